@@ -1,14 +1,17 @@
 package anthropic
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+	"github.com/i2y/bucephalus/provider/sse"
 )
 
 const (
@@ -20,28 +23,34 @@ const (
 
 // client wraps the HTTP client for Anthropic API calls.
 type client struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey           string
+	baseURL          string
+	httpClient       *http.Client
+	maxResponseBytes int64
+	betas            []string // Sent in the anthropic-beta header
 }
 
 // newClient creates a new Anthropic client.
-func newClient(apiKey, baseURL string, httpClient *http.Client) *client {
+func newClient(apiKey, baseURL string, httpClient *http.Client, maxResponseBytes int64) *client {
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
+	if maxResponseBytes == 0 {
+		maxResponseBytes = provider.DefaultMaxResponseBytes
+	}
 	return &client{
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		httpClient: httpClient,
+		apiKey:           apiKey,
+		baseURL:          baseURL,
+		httpClient:       httpClient,
+		maxResponseBytes: maxResponseBytes,
 	}
 }
 
-// messages sends a messages request.
-func (c *client) messages(ctx context.Context, req *messagesRequest) (*messagesResponse, error) {
+// messages sends a messages request with the additional headers.
+func (c *client) messages(ctx context.Context, req *messagesRequest, headers http.Header) (*messagesResponse, *provider.HTTPResponse, error) {
 	// Ensure max_tokens is set
 	if req.MaxTokens == 0 {
 		req.MaxTokens = defaultMaxTokens
@@ -49,42 +58,43 @@ func (c *client) messages(ctx context.Context, req *messagesRequest) (*messagesR
 
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return nil, nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST",
 		c.baseURL+"/v1/messages", bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	c.setHeaders(httpReq, req.OutputFormat != nil)
+	c.setHeaders(httpReq, req.OutputFormat != nil, headers)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
+		return nil, nil, fmt.Errorf("sending request: %w", err)
 	}
 	defer func() { _ = httpResp.Body.Close() }()
 
-	respBody, err := io.ReadAll(httpResp.Body)
+	respBody, err := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, nil, fmt.Errorf("reading response: %w", err)
 	}
 
+	raw := &provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody}
 	if httpResp.StatusCode != http.StatusOK {
-		return nil, c.parseError(httpResp.StatusCode, respBody)
+		return nil, raw, c.parseError(raw)
 	}
 
 	var resp messagesResponse
 	if err := json.Unmarshal(respBody, &resp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+		return nil, raw, fmt.Errorf("parsing response: %w", err)
 	}
 
-	return &resp, nil
+	return &resp, raw, nil
 }
 
-// messagesStream sends a streaming messages request.
-func (c *client) messagesStream(ctx context.Context, req *messagesRequest) (*streamReader, error) {
+// messagesStream sends a streaming messages request with the additional headers.
+func (c *client) messagesStream(ctx context.Context, req *messagesRequest, headers http.Header) (*streamReader, error) {
 	req.Stream = true
 	if req.MaxTokens == 0 {
 		req.MaxTokens = defaultMaxTokens
@@ -101,7 +111,7 @@ func (c *client) messagesStream(ctx context.Context, req *messagesRequest) (*str
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	c.setHeaders(httpReq, req.OutputFormat != nil)
+	c.setHeaders(httpReq, req.OutputFormat != nil, headers)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -110,82 +120,130 @@ func (c *client) messagesStream(ctx context.Context, req *messagesRequest) (*str
 
 	if httpResp.StatusCode != http.StatusOK {
 		defer func() { _ = httpResp.Body.Close() }()
-		respBody, _ := io.ReadAll(httpResp.Body)
-		return nil, c.parseError(httpResp.StatusCode, respBody)
+		respBody, _ := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
+		return nil, c.parseError(&provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody})
 	}
 
 	return &streamReader{
-		reader: bufio.NewReader(httpResp.Body),
+		events: sse.NewReader(httpResp.Body),
 		closer: httpResp.Body,
+		raw:    &provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header},
 	}, nil
 }
 
-func (c *client) setHeaders(req *http.Request, useStructuredOutput bool) {
+// setHeaders sets the API headers on the request, followed by the additional
+// headers, which replace the API headers of the same name. The betas of the
+// client, the request, and the additional headers are combined into one
+// anthropic-beta header.
+func (c *client) setHeaders(req *http.Request, useStructuredOutput bool, headers http.Header) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", c.apiKey)
 	req.Header.Set("anthropic-version", apiVersion)
+
+	betas := slices.Clone(c.betas)
 	if useStructuredOutput {
-		req.Header.Set("anthropic-beta", structuredOutputsBeta)
+		betas = append(betas, structuredOutputsBeta)
+	}
+	for key, values := range headers {
+		key = http.CanonicalHeaderKey(key)
+		if key == "Anthropic-Beta" {
+			for _, v := range values {
+				for beta := range strings.SplitSeq(v, ",") {
+					betas = append(betas, strings.TrimSpace(beta))
+				}
+			}
+			continue
+		}
+		req.Header[key] = values
+	}
+	betas = slices.DeleteFunc(betas, func(beta string) bool { return beta == "" })
+	if len(betas) > 0 {
+		slices.Sort(betas)
+		req.Header.Set("anthropic-beta", strings.Join(slices.Compact(betas), ","))
 	}
 }
 
-func (c *client) parseError(statusCode int, body []byte) error {
+func (c *client) parseError(raw *provider.HTTPResponse) error {
 	var errResp errorResponse
-	if err := json.Unmarshal(body, &errResp); err != nil {
+	if err := json.Unmarshal(raw.Body, &errResp); err != nil {
 		return &APIError{
-			StatusCode: statusCode,
-			Message:    string(body),
+			StatusCode: raw.StatusCode,
+			Message:    string(raw.Body),
+			Raw:        raw,
 		}
 	}
 
 	return &APIError{
-		StatusCode: statusCode,
+		StatusCode: raw.StatusCode,
 		Type:       errResp.Error.Type,
 		Message:    errResp.Error.Message,
+		Raw:        raw,
 	}
 }
 
 // streamReader reads SSE events from an Anthropic stream.
 type streamReader struct {
-	reader *bufio.Reader
+	events *sse.Reader
 	closer io.Closer
+	raw    *provider.HTTPResponse
 }
 
-// ReadEvent reads the next event from the stream.
+// ReadEvent reads the next event from the stream. An error event, such as
+// an overload in the middle of the stream, is returned as an *APIError.
 func (s *streamReader) ReadEvent() (*streamEvent, error) {
 	for {
-		line, err := s.reader.ReadString('\n')
+		event, err := s.events.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		line = strings.TrimSpace(line)
-		if line == "" {
+		data := strings.TrimSpace(event.Data)
+		if data == "" {
 			continue
 		}
 
-		if strings.HasPrefix(line, "event:") {
-			// Read the data line
-			dataLine, err := s.reader.ReadString('\n')
-			if err != nil {
-				return nil, err
+		if event.Type == "error" {
+			var errResp errorResponse
+			if err := json.Unmarshal([]byte(data), &errResp); err != nil {
+				return nil, &APIError{StatusCode: http.StatusInternalServerError, Message: data, Raw: s.raw}
 			}
-
-			dataLine = strings.TrimSpace(dataLine)
-			if !strings.HasPrefix(dataLine, "data:") {
-				continue
+			return nil, &APIError{
+				StatusCode: errorTypeStatus(errResp.Error.Type),
+				Type:       errResp.Error.Type,
+				Message:    errResp.Error.Message,
+				Raw:        s.raw,
 			}
+		}
 
-			data := strings.TrimPrefix(dataLine, "data:")
-			data = strings.TrimSpace(data)
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil, fmt.Errorf("parsing event: %w", err)
+		}
 
-			var event streamEvent
-			if err := json.Unmarshal([]byte(data), &event); err != nil {
-				return nil, fmt.Errorf("parsing event: %w", err)
-			}
+		return &ev, nil
+	}
+}
 
-			return &event, nil
-		}
+// errorTypeStatus returns the HTTP status code of the errors of the type, for
+// errors reported in a stream after its 200 OK status.
+func errorTypeStatus(errorType string) int {
+	switch errorType {
+	case "invalid_request_error":
+		return http.StatusBadRequest
+	case "authentication_error":
+		return http.StatusUnauthorized
+	case "permission_error":
+		return http.StatusForbidden
+	case "not_found_error":
+		return http.StatusNotFound
+	case "request_too_large":
+		return http.StatusRequestEntityTooLarge
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "overloaded_error":
+		return 529
+	default:
+		return http.StatusInternalServerError
 	}
 }
 
@@ -199,6 +257,17 @@ type APIError struct {
 	StatusCode int
 	Type       string
 	Message    string
+	Raw        *provider.HTTPResponse // The HTTP response, with its headers and body
+}
+
+// HTTPStatus returns the HTTP status code of the error.
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// RawResponse returns the HTTP response of the error.
+func (e *APIError) RawResponse() *provider.HTTPResponse {
+	return e.Raw
 }
 
 func (e *APIError) Error() string {
@@ -0,0 +1,91 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// modelsResponse is a page of the models endpoint.
+type modelsResponse struct {
+	Data []struct {
+		ID          string    `json:"id"`
+		DisplayName string    `json:"display_name"`
+		CreatedAt   time.Time `json:"created_at"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// ListModels implements provider.ModelLister.
+func (p *Provider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	var models []provider.ModelInfo
+	afterID := ""
+	for {
+		resp, err := p.client.listModels(ctx, afterID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range resp.Data {
+			models = append(models, provider.ModelInfo{
+				ID:          m.ID,
+				DisplayName: m.DisplayName,
+				Created:     m.CreatedAt,
+			})
+		}
+		if !resp.HasMore || resp.LastID == "" {
+			break
+		}
+		afterID = resp.LastID
+	}
+	slices.SortFunc(models, func(a, b provider.ModelInfo) int { return strings.Compare(a.ID, b.ID) })
+	return models, nil
+}
+
+// Ping implements provider.HealthChecker by listing the models, which
+// checks the API key without generating tokens.
+func (p *Provider) Ping(ctx context.Context) error {
+	_, err := p.client.listModels(ctx, "")
+	return err
+}
+
+// listModels lists a page of the available models, starting after afterID.
+func (c *client) listModels(ctx context.Context, afterID string) (*modelsResponse, error) {
+	query := url.Values{"limit": {"1000"}}
+	if afterID != "" {
+		query.Set("after_id", afterID)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/v1/models?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	c.setHeaders(httpReq, false, nil)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	respBody, err := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, c.parseError(&provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody})
+	}
+
+	var resp modelsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return &resp, nil
+}
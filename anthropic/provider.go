@@ -7,13 +7,29 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/i2y/bucephalus/provider"
 )
 
 func init() {
-	provider.Register("anthropic", func() (provider.Provider, error) {
-		return New()
+	Register("anthropic")
+}
+
+// Register registers an Anthropic provider configured with opts in the provider
+// registry under name, so it can be selected with llm.WithProvider(name).
+// Register the provider under several names to use several configurations.
+//
+// Example:
+//
+//	anthropic.Register("anthropic-proxy", anthropic.WithBaseURL("https://llm-proxy.internal"))
+//	resp, err := llm.Call(ctx, "Hello", llm.WithProvider("anthropic-proxy"), ...)
+func Register(name string, opts ...Option) {
+	provider.Register(name, func() (provider.Provider, error) {
+		return New(opts...)
 	})
 }
 
@@ -26,9 +42,12 @@ type Provider struct {
 type Option func(*providerConfig)
 
 type providerConfig struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey           string
+	baseURL          string
+	httpClient       *http.Client
+	http             provider.HTTPOptions
+	maxResponseBytes int64
+	betas            []string
 }
 
 // WithAPIKey sets the API key.
@@ -45,13 +64,49 @@ func WithBaseURL(url string) Option {
 	}
 }
 
-// WithHTTPClient sets a custom HTTP client.
+// WithHTTPClient sets a custom HTTP client. It replaces the default client,
+// so WithProxy and WithTimeout have no effect.
 func WithHTTPClient(client *http.Client) Option {
 	return func(c *providerConfig) {
 		c.httpClient = client
 	}
 }
 
+// WithProxy sends requests through the proxy at proxyURL, e.g.
+// "http://proxy.internal:3128". By default, the proxy is taken from the
+// HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) Option {
+	return func(c *providerConfig) {
+		c.http.Proxy = proxyURL
+	}
+}
+
+// WithTimeout limits the time of a whole request, including reading a
+// streamed response (default: no limit).
+func WithTimeout(d time.Duration) Option {
+	return func(c *providerConfig) {
+		c.http.Timeout = d
+	}
+}
+
+// WithMaxResponseBytes limits the size of response bodies (default:
+// provider.DefaultMaxResponseBytes). Streamed responses are not limited.
+// A negative n disables the limit.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *providerConfig) {
+		c.maxResponseBytes = n
+	}
+}
+
+// WithBetas opts in to beta features by sending their names in the
+// anthropic-beta header of every request, e.g. "context-1m-2025-08-07".
+// Per-call anthropic-beta headers (llm.WithHeader) are added to these.
+func WithBetas(betas ...string) Option {
+	return func(c *providerConfig) {
+		c.betas = append(c.betas, betas...)
+	}
+}
+
 // New creates a new Anthropic provider.
 func New(opts ...Option) (*Provider, error) {
 	cfg := &providerConfig{}
@@ -70,9 +125,17 @@ func New(opts ...Option) (*Provider, error) {
 		}
 	}
 
-	return &Provider{
-		client: newClient(cfg.apiKey, cfg.baseURL, cfg.httpClient),
-	}, nil
+	httpClient := cfg.httpClient
+	if httpClient == nil {
+		var err error
+		if httpClient, err = provider.NewHTTPClient(cfg.http); err != nil {
+			return nil, err
+		}
+	}
+
+	c := newClient(cfg.apiKey, cfg.baseURL, httpClient, cfg.maxResponseBytes)
+	c.betas = cfg.betas
+	return &Provider{client: c}, nil
 }
 
 // Name returns the provider identifier.
@@ -80,30 +143,39 @@ func (p *Provider) Name() string {
 	return "anthropic"
 }
 
+// Close closes the idle connections of the provider's HTTP client.
+// provider.Close calls it for registered providers.
+func (p *Provider) Close() error {
+	p.client.httpClient.CloseIdleConnections()
+	return nil
+}
+
 // Call implements provider.Provider.
 func (p *Provider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
 	apiReq := p.buildRequest(req)
 
-	apiResp, err := p.client.messages(ctx, apiReq)
+	apiResp, raw, err := p.client.messages(ctx, apiReq, req.Headers)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.convertResponse(apiResp), nil
+	resp := p.convertResponse(apiResp)
+	resp.HTTP = raw
+	return resp, nil
 }
 
 // CallStream implements provider.StreamingProvider.
 func (p *Provider) CallStream(ctx context.Context, req *provider.Request) (provider.ResponseStream, error) {
 	apiReq := p.buildRequest(req)
 
-	stream, err := p.client.messagesStream(ctx, apiReq)
+	stream, err := p.client.messagesStream(ctx, apiReq, req.Headers)
 	if err != nil {
 		return nil, err
 	}
 
 	return &anthropicStream{
-		reader:      stream,
-		accumulated: &provider.Response{},
+		reader: stream,
+		acc:    provider.NewAccumulator(stream.raw),
 	}, nil
 }
 
@@ -121,11 +193,16 @@ func (p *Provider) buildRequest(req *provider.Request) *messagesRequest {
 	if req.MaxTokens != nil {
 		apiReq.MaxTokens = *req.MaxTokens
 	}
+	if req.User != "" {
+		apiReq.Metadata = &metadata{UserID: req.User}
+	}
 
 	for _, msg := range req.Messages {
-		// Extract system message
+		// Each system message becomes a block of the system prompt
 		if msg.Role == provider.RoleSystem {
-			apiReq.System = msg.Content
+			if msg.Content != "" {
+				apiReq.System = append(apiReq.System, systemBlock{Type: "text", Text: msg.Content})
+			}
 			continue
 		}
 
@@ -177,6 +254,10 @@ func (p *Provider) buildRequest(req *provider.Request) *messagesRequest {
 		}
 	}
 
+	if !req.RawMessages {
+		apiReq.Messages = sanitizeMessages(apiReq.Messages)
+	}
+
 	// Handle tools
 	for _, tool := range req.Tools {
 		apiReq.Tools = append(apiReq.Tools, toolDef{
@@ -186,17 +267,90 @@ func (p *Provider) buildRequest(req *provider.Request) *messagesRequest {
 		})
 	}
 
+	if len(apiReq.Tools) > 0 {
+		apiReq.ToolChoice = convertToolChoice(req.ToolChoice)
+	}
+
 	// Handle JSON Schema for structured output
 	if req.JSONSchema != nil {
+		// Structured output does not support recursive schemas
+		schema := provider.InlineRefs(req.JSONSchema.Schema, provider.DefaultRefDepth)
 		apiReq.OutputFormat = &outputFormat{
 			Type:   "json_schema",
-			Schema: req.JSONSchema.Schema,
+			Schema: provider.ApplyAdditionalProperties(schema, req.JSONSchema.AdditionalProperties),
 		}
 	}
 
 	return apiReq
 }
 
+// sanitizeMessages normalizes messages to what the API accepts: blank text
+// blocks and the messages left empty are dropped, and consecutive messages
+// of the same role, such as the results of parallel tool calls, are merged
+// into one with the tool results first. Trailing whitespace of a final
+// assistant message (a prefill) is trimmed.
+func sanitizeMessages(msgs []message) []message {
+	out := make([]message, 0, len(msgs))
+	for _, msg := range msgs {
+		parts := make([]contentPart, 0, len(msg.Content))
+		for _, part := range msg.Content {
+			if part.Type == "text" && strings.TrimSpace(part.Text) == "" {
+				continue
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].Role == msg.Role {
+			out[n-1].Content = append(out[n-1].Content, parts...)
+			continue
+		}
+		out = append(out, message{Role: msg.Role, Content: parts})
+	}
+
+	for i := range out {
+		if out[i].Role == "user" {
+			slices.SortStableFunc(out[i].Content, func(a, b contentPart) int {
+				return boolInt(b.Type == "tool_result") - boolInt(a.Type == "tool_result")
+			})
+		}
+	}
+	if n := len(out); n > 0 && out[n-1].Role == "assistant" {
+		last := &out[n-1].Content[len(out[n-1].Content)-1]
+		if last.Type == "text" {
+			last.Text = strings.TrimRightFunc(last.Text, unicode.IsSpace)
+		}
+	}
+	return out
+}
+
+// boolInt returns 1 if b is true and 0 otherwise.
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// convertToolChoice converts a provider.ToolChoice to an Anthropic tool choice.
+func convertToolChoice(choice *provider.ToolChoice) *toolChoice {
+	if choice == nil {
+		return nil
+	}
+	switch choice.Mode {
+	case provider.ToolChoiceRequired:
+		if len(choice.Tools) == 1 {
+			return &toolChoice{Type: "tool", Name: choice.Tools[0]}
+		}
+		return &toolChoice{Type: "any"}
+	case provider.ToolChoiceNone:
+		return &toolChoice{Type: "none"}
+	default:
+		return &toolChoice{Type: "auto"}
+	}
+}
+
 // convertResponse converts an Anthropic API response to a provider.Response.
 func (p *Provider) convertResponse(resp *messagesResponse) *provider.Response {
 	result := &provider.Response{
@@ -206,6 +360,7 @@ func (p *Provider) convertResponse(resp *messagesResponse) *provider.Response {
 			CompletionTokens: resp.Usage.OutputTokens,
 			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
 		},
+		ContentFilter: contentFilter(resp.StopReason),
 	}
 
 	for _, block := range resp.Content {
@@ -242,23 +397,36 @@ func convertStopReason(reason string) provider.FinishReason {
 		return provider.FinishReasonToolCalls
 	case "max_tokens":
 		return provider.FinishReasonLength
+	case "refusal":
+		return provider.FinishReasonContentFiltered
 	default:
 		return provider.FinishReasonStop
 	}
 }
 
+// contentFilter returns the details of a response that the model refused to
+// give for safety reasons (stop reason "refusal"), or nil.
+func contentFilter(stopReason string) *provider.ContentFilter {
+	if stopReason != "refusal" {
+		return nil
+	}
+	return &provider.ContentFilter{Reason: stopReason}
+}
+
 // anthropicStream implements provider.ResponseStream for Anthropic.
 type anthropicStream struct {
-	reader      *streamReader
-	accumulated *provider.Response
-	err         error
-	current     *provider.StreamChunk
-	done        bool
+	reader    *streamReader
+	acc       *provider.Accumulator
+	err       error
+	chunk     provider.StreamChunk // Reused for every chunk
+	toolDelta provider.ToolCallDelta
+	current   *provider.StreamChunk
+	done      bool
 
 	// Track current tool call for streaming
-	currentToolID   string
-	currentToolName string
-	currentToolArgs string
+	currentToolIndex int
+	currentToolID    string
+	currentToolName  string
 }
 
 func (s *anthropicStream) Next() bool {
@@ -276,57 +444,53 @@ func (s *anthropicStream) Next() bool {
 		return false
 	}
 
-	s.current = &provider.StreamChunk{}
+	s.chunk = provider.StreamChunk{}
+	s.current = &s.chunk
 
 	switch event.Type {
 	case "content_block_start":
 		if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+			s.currentToolIndex = event.Index
 			s.currentToolID = event.ContentBlock.ID
 			s.currentToolName = event.ContentBlock.Name
-			s.currentToolArgs = ""
+			s.acc.AddToolCallDelta(event.Index, event.ContentBlock.ID, event.ContentBlock.Name, "")
 		}
 
 	case "content_block_delta":
 		if event.Delta != nil {
 			if event.Delta.Text != "" {
 				s.current.Delta = event.Delta.Text
-				s.accumulated.Content += event.Delta.Text
+				s.acc.AddContent(event.Delta.Text)
 			}
 			if event.Delta.PartialJSON != "" {
-				s.currentToolArgs += event.Delta.PartialJSON
-				s.current.ToolCallDelta = &provider.ToolCallDelta{
+				s.acc.AddToolCallDelta(s.currentToolIndex, "", "", event.Delta.PartialJSON)
+				s.toolDelta = provider.ToolCallDelta{
 					ID:             s.currentToolID,
 					Name:           s.currentToolName,
 					ArgumentsDelta: event.Delta.PartialJSON,
 				}
+				s.current.ToolCallDelta = &s.toolDelta
 			}
 		}
 
 	case "content_block_stop":
-		if s.currentToolID != "" {
-			s.accumulated.ToolCalls = append(s.accumulated.ToolCalls, provider.ToolCall{
-				ID:        s.currentToolID,
-				Name:      s.currentToolName,
-				Arguments: s.currentToolArgs,
-			})
-			s.currentToolID = ""
-			s.currentToolName = ""
-			s.currentToolArgs = ""
-		}
+		s.currentToolID = ""
+		s.currentToolName = ""
 
 	case "message_delta":
 		if event.Delta != nil && event.Delta.StopReason != "" {
 			s.current.FinishReason = convertStopReason(event.Delta.StopReason)
-			s.accumulated.FinishReason = s.current.FinishReason
+			s.acc.FinishReason = s.current.FinishReason
+			s.acc.ContentFilter = contentFilter(event.Delta.StopReason)
 		}
 		if event.Usage != nil {
-			s.accumulated.Usage.CompletionTokens = event.Usage.OutputTokens
-			s.accumulated.Usage.TotalTokens = s.accumulated.Usage.PromptTokens + event.Usage.OutputTokens
+			s.acc.Usage.CompletionTokens = event.Usage.OutputTokens
+			s.acc.Usage.TotalTokens = s.acc.Usage.PromptTokens + event.Usage.OutputTokens
 		}
 
 	case "message_start":
 		if event.Message != nil {
-			s.accumulated.Usage.PromptTokens = event.Message.Usage.InputTokens
+			s.acc.Usage.PromptTokens = event.Message.Usage.InputTokens
 		}
 
 	case "message_stop":
@@ -350,5 +514,5 @@ func (s *anthropicStream) Close() error {
 }
 
 func (s *anthropicStream) Accumulated() *provider.Response {
-	return s.accumulated
+	return s.acc.Response()
 }
@@ -0,0 +1,206 @@
+package anthropic
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+	"github.com/i2y/bucephalus/provider/sse"
+)
+
+func TestBuildRequest_SystemBlocks(t *testing.T) {
+	p := &Provider{}
+	apiReq := p.buildRequest(&provider.Request{
+		Messages: []provider.Message{
+			{Role: provider.RoleSystem, Content: "You are terse."},
+			{Role: provider.RoleSystem, Content: "Answer in French."},
+			{Role: provider.RoleUser, Content: "Hello"},
+		},
+	})
+
+	assert.Equal(t, []systemBlock{
+		{Type: "text", Text: "You are terse."},
+		{Type: "text", Text: "Answer in French."},
+	}, apiReq.System)
+	assert.Len(t, apiReq.Messages, 1)
+}
+
+func TestBuildRequest_ToolChoice(t *testing.T) {
+	tools := []provider.ToolDef{{Name: "get_weather"}, {Name: "get_time"}}
+	tests := []struct {
+		name   string
+		choice *provider.ToolChoice
+		want   *toolChoice
+	}{
+		{"unset", nil, nil},
+		{"auto", &provider.ToolChoice{Mode: provider.ToolChoiceAuto}, &toolChoice{Type: "auto"}},
+		{"required", &provider.ToolChoice{Mode: provider.ToolChoiceRequired}, &toolChoice{Type: "any"}},
+		{"required tool", &provider.ToolChoice{Mode: provider.ToolChoiceRequired, Tools: []string{"get_time"}}, &toolChoice{Type: "tool", Name: "get_time"}},
+		{"none", &provider.ToolChoice{Mode: provider.ToolChoiceNone}, &toolChoice{Type: "none"}},
+	}
+
+	p := &Provider{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiReq := p.buildRequest(&provider.Request{Tools: tools, ToolChoice: tt.choice})
+			assert.Equal(t, tt.want, apiReq.ToolChoice)
+		})
+	}
+}
+
+func TestSetHeaders_Betas(t *testing.T) {
+	c := &client{apiKey: "key", betas: []string{"context-1m-2025-08-07"}}
+	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", nil)
+
+	c.setHeaders(req, true, http.Header{
+		"anthropic-beta": {"files-api-2025-04-14, context-1m-2025-08-07"},
+		"X-Trace-Id":     {"abc"},
+	})
+
+	assert.Equal(t, "context-1m-2025-08-07,files-api-2025-04-14,"+structuredOutputsBeta, req.Header.Get("anthropic-beta"))
+	assert.Equal(t, "abc", req.Header.Get("X-Trace-Id"))
+	assert.Equal(t, "key", req.Header.Get("x-api-key"))
+}
+
+func TestStreamReader(t *testing.T) {
+	stream := "event: message_start\n" +
+		": keep-alive\n" +
+		"data: {\"type\":\"message_start\",\n" +
+		"data: \"message\":{\"id\":\"msg_1\"}}\n\n" +
+		"event: ping\ndata: {\"type\":\"ping\"}\n\n" +
+		"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+	r := &streamReader{events: sse.NewReader(strings.NewReader(stream)), raw: &provider.HTTPResponse{StatusCode: 200}}
+
+	event, err := r.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, "message_start", event.Type)
+	assert.Equal(t, "msg_1", event.Message.ID)
+
+	event, err = r.ReadEvent()
+	require.NoError(t, err)
+	assert.Equal(t, "ping", event.Type)
+
+	_, err = r.ReadEvent()
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "overloaded_error", apiErr.Type)
+	assert.True(t, provider.IsTransient(err))
+}
+
+// streamOf returns an Anthropic stream of events.
+func streamOf(events ...string) *anthropicStream {
+	var body strings.Builder
+	for _, event := range events {
+		body.WriteString("data: " + event + "\n\n")
+	}
+	reader := &streamReader{events: sse.NewReader(strings.NewReader(body.String())), raw: &provider.HTTPResponse{StatusCode: 200}}
+	return &anthropicStream{reader: reader, acc: provider.NewAccumulator(reader.raw)}
+}
+
+func TestStream_Accumulates(t *testing.T) {
+	stream := streamOf(
+		`{"type":"message_start","message":{"usage":{"input_tokens":10}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Tokyo\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+		`{"type":"message_stop"}`,
+	)
+
+	var deltas []string
+	for stream.Next() {
+		if d := stream.Current().ToolCallDelta; d != nil {
+			assert.Equal(t, "toolu_1", d.ID)
+			deltas = append(deltas, d.ArgumentsDelta)
+		}
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []string{`{"city":`, `"Tokyo"}`}, deltas)
+
+	resp := stream.Accumulated()
+	assert.Equal(t, "Let me check.", resp.Content)
+	assert.Equal(t, []provider.ToolCall{{ID: "toolu_1", Name: "weather", Arguments: `{"city":"Tokyo"}`}}, resp.ToolCalls)
+	assert.Equal(t, provider.FinishReasonToolCalls, resp.FinishReason)
+	assert.Equal(t, provider.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, resp.Usage)
+}
+
+// BenchmarkStream measures streaming a long generation end to end, from
+// the event stream to the accumulated response.
+func BenchmarkStream(b *testing.B) {
+	events := []string{`{"type":"message_start","message":{"usage":{"input_tokens":10}}}`}
+	for range 10000 {
+		events = append(events, `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"token "}}`)
+	}
+	events = append(events, `{"type":"message_stop"}`)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		stream := streamOf(events...)
+		for stream.Next() {
+		}
+		_ = stream.Accumulated()
+	}
+}
+
+func TestBuildRequest_User(t *testing.T) {
+	p := &Provider{}
+	assert.Nil(t, p.buildRequest(&provider.Request{}).Metadata)
+	assert.Equal(t, &metadata{UserID: "u_1"}, p.buildRequest(&provider.Request{User: "u_1"}).Metadata)
+}
+
+func TestBuildRequest_SanitizesMessages(t *testing.T) {
+	p := &Provider{}
+	msgs := []provider.Message{
+		{Role: provider.RoleUser, Content: "Weather in Paris and Rome?"},
+		{Role: provider.RoleUser, Content: "In Celsius."},
+		{Role: provider.RoleAssistant, Content: "  ", ToolCalls: []provider.ToolCall{
+			{ID: "1", Name: "weather", Arguments: `{"city":"Paris"}`},
+			{ID: "2", Name: "weather", Arguments: `{"city":"Rome"}`},
+		}},
+		{Role: provider.RoleTool, ToolID: "1", Content: "20"},
+		{Role: provider.RoleTool, ToolID: "2", Content: "25"},
+		{Role: provider.RoleUser, Content: "And tomorrow?"},
+		{Role: provider.RoleAssistant, Content: ""},
+		{Role: provider.RoleAssistant, Content: "Tomorrow it will be \n"},
+	}
+	apiReq := p.buildRequest(&provider.Request{Messages: msgs})
+
+	require.Len(t, apiReq.Messages, 4)
+	assert.Equal(t, []string{"user", "assistant", "user", "assistant"},
+		[]string{apiReq.Messages[0].Role, apiReq.Messages[1].Role, apiReq.Messages[2].Role, apiReq.Messages[3].Role})
+	assert.Len(t, apiReq.Messages[0].Content, 2)
+	assert.Len(t, apiReq.Messages[1].Content, 2) // The blank text is dropped
+	results := apiReq.Messages[2].Content
+	require.Len(t, results, 3)
+	assert.Equal(t, []string{"tool_result", "tool_result", "text"}, []string{results[0].Type, results[1].Type, results[2].Type})
+	assert.Equal(t, "Tomorrow it will be", apiReq.Messages[3].Content[0].Text)
+
+	apiReq = p.buildRequest(&provider.Request{Messages: msgs, RawMessages: true})
+	assert.Len(t, apiReq.Messages, 7)
+}
+
+func TestRefusal(t *testing.T) {
+	p := &Provider{}
+	resp := p.convertResponse(&messagesResponse{StopReason: "refusal"})
+	assert.Equal(t, provider.FinishReasonContentFiltered, resp.FinishReason)
+	assert.Equal(t, &provider.ContentFilter{Reason: "refusal"}, resp.ContentFilter)
+
+	stream := streamOf(
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"I can"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"refusal"},"usage":{"output_tokens":2}}`,
+	)
+	for stream.Next() {
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, provider.FinishReasonContentFiltered, stream.Accumulated().FinishReason)
+	assert.Equal(t, "refusal", stream.Accumulated().ContentFilter.Reason)
+}
@@ -6,15 +6,34 @@ import "encoding/json"
 type messagesRequest struct {
 	Model         string        `json:"model"`
 	Messages      []message     `json:"messages"`
-	System        string        `json:"system,omitempty"`
+	System        []systemBlock `json:"system,omitempty"`
 	MaxTokens     int           `json:"max_tokens"`
 	Temperature   *float64      `json:"temperature,omitempty"`
 	TopP          *float64      `json:"top_p,omitempty"`
 	TopK          *int          `json:"top_k,omitempty"`
 	StopSequences []string      `json:"stop_sequences,omitempty"`
 	Tools         []toolDef     `json:"tools,omitempty"`
+	ToolChoice    *toolChoice   `json:"tool_choice,omitempty"`
 	Stream        bool          `json:"stream,omitempty"`
 	OutputFormat  *outputFormat `json:"output_format,omitempty"`
+	Metadata      *metadata     `json:"metadata,omitempty"`
+}
+
+// metadata describes the request.
+type metadata struct {
+	UserID string `json:"user_id,omitempty"` // Opaque ID of the end user
+}
+
+// systemBlock is a text block of the system prompt.
+type systemBlock struct {
+	Type string `json:"type"` // "text"
+	Text string `json:"text"`
+}
+
+// toolChoice controls how the model uses tools.
+type toolChoice struct {
+	Type string `json:"type"`           // "auto", "any", "tool", or "none"
+	Name string `json:"name,omitempty"` // For type "tool"
 }
 
 // outputFormat specifies the output format for structured output.
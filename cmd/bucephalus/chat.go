@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/i2y/bucephalus/config"
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/plugin"
+	"github.com/i2y/bucephalus/tools"
+)
+
+// defaultTools are the tools enabled when neither -tools nor the
+// configuration file enables any.
+const defaultTools = "readonly"
+
+// chatFlags are the flags of the chat command.
+type chatFlags struct {
+	config   string
+	provider string
+	model    string
+	plugin   string
+	agent    string
+	tools    string
+	system   string
+	session  string
+	maxTurns int
+	yes      bool
+}
+
+// chat is an interactive chat on a terminal.
+type chat struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	session *plugin.ChatSession
+	file    string          // Session file saved after every reply (optional)
+	yes     bool            // Run all tools without asking
+	allowed map[string]bool // Tools the user allowed for the rest of the chat
+	done    bool            // Set by /exit
+}
+
+// runChat runs the chat command.
+func runChat(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var f chatFlags
+	fs := flag.NewFlagSet("chat", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&f.config, "config", "", "Configuration file (YAML, JSON, or TOML)")
+	fs.StringVar(&f.provider, "provider", "", "Provider name (default: from the configuration, or the registry default)")
+	fs.StringVar(&f.model, "model", "", "Model name (default: from the configuration)")
+	fs.StringVar(&f.plugin, "plugin", "", "Plugin directory whose slash commands and agents are available")
+	fs.StringVar(&f.agent, "agent", "", "Plugin agent to chat with (default: a general assistant)")
+	fs.StringVar(&f.tools, "tools", "", "Comma-separated built-in tools or groups: read, write, glob, grep, bash, bash_output, task_start, task_status, task_output, web_fetch, web_search, wikipedia, file, web, knowledge, readonly, system, task, all, none (default: "+defaultTools+")")
+	fs.StringVar(&f.system, "system", "", "System prompt of the general assistant")
+	fs.StringVar(&f.session, "session", "", "Session file: loaded at start if it exists, saved after every reply")
+	fs.IntVar(&f.maxTurns, "max-turns", 0, "Maximum LLM calls per message (default: from the configuration, or 10)")
+	fs.BoolVar(&f.yes, "yes", false, "Run tools without asking for permission")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := newChat(f, stdin, stdout)
+	if err != nil {
+		return err
+	}
+	return c.loop(context.Background(), stderr)
+}
+
+// splitList splits a comma-separated flag value, trimming spaces and
+// dropping empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// newChat creates a chat configured by f.
+func newChat(f chatFlags, stdin io.Reader, stdout io.Writer) (*chat, error) {
+	cfg := &config.Config{}
+	if f.config != "" {
+		var err error
+		if cfg, err = config.Load(f.config); err != nil {
+			return nil, err
+		}
+	}
+	if f.provider != "" {
+		cfg.Defaults.Provider = f.provider
+	}
+	if f.model != "" {
+		cfg.Defaults.Model = f.model
+	}
+	switch {
+	case f.tools == "none":
+		cfg.Tools = nil
+	case f.tools != "":
+		cfg.Tools = splitList(f.tools)
+	case len(cfg.Tools) == 0:
+		cfg.Tools = []string{defaultTools}
+	}
+	if f.plugin != "" {
+		cfg.Plugins = []string{f.plugin}
+	}
+	if f.maxTurns > 0 {
+		cfg.Budget.MaxTurns = f.maxTurns
+	}
+	if cfg.Defaults.Model == "" {
+		return nil, errors.New("no model: set -model or defaults.model in the configuration")
+	}
+
+	setup, err := cfg.Bootstrap()
+	if err != nil {
+		return nil, err
+	}
+
+	var p *plugin.Plugin
+	if len(setup.Plugins) > 0 {
+		p = setup.Plugins[0]
+	}
+	agent, err := chatAgent(p, f, setup.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &chat{
+		in:      bufio.NewScanner(stdin),
+		out:     stdout,
+		file:    f.session,
+		yes:     f.yes,
+		allowed: make(map[string]bool),
+	}
+	for _, tool := range tools.ReadOnlyTools() {
+		c.allowed[tool.Name()] = true
+	}
+
+	opts := append(setup.AgentOptions(), plugin.WithAgentHooks(plugin.Hooks{OnToolCall: c.approve}))
+	if p != nil {
+		opts = append(opts, plugin.WithAgentPlugin(p))
+	}
+	runner := agent.NewRunner(opts...)
+
+	if c.file != "" {
+		if err := loadSession(runner, c.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	c.session = plugin.NewChatSession(p, runner,
+		plugin.WithChatEvents(c.printEvent),
+		plugin.WithChatCommand("save", "Save the conversation to a file: /save [file]", c.save),
+		plugin.WithChatCommand("load", "Load a conversation from a file: /load [file]", c.load),
+		plugin.WithChatCommand("tools", "List the available tools", c.listTools),
+		plugin.WithChatCommand("exit", "Exit the chat", c.exit),
+	)
+	return c, nil
+}
+
+// chatAgent returns the plugin agent named by f.agent, or a general
+// assistant that can use all enabled tools.
+func chatAgent(p *plugin.Plugin, f chatFlags, enabled []llm.Tool) (*plugin.Agent, error) {
+	if f.agent != "" {
+		if p == nil {
+			return nil, errors.New("-agent requires -plugin or plugins in the configuration")
+		}
+		agent := p.GetAgent(f.agent)
+		if agent == nil {
+			return nil, fmt.Errorf("plugin %s has no agent %q", p.Name, f.agent)
+		}
+		return agent, nil
+	}
+
+	agent := &plugin.Agent{Name: "assistant", Content: f.system}
+	for _, tool := range enabled {
+		agent.Tools = append(agent.Tools, tool.Name())
+	}
+	return agent, nil
+}
+
+// loop reads and handles input until /exit or the end of the input.
+// Errors of single messages are printed to errOut and do not end the chat.
+func (c *chat) loop(ctx context.Context, errOut io.Writer) error {
+	fmt.Fprintln(c.out, `Type a message, or /help for commands.`)
+	for !c.done {
+		fmt.Fprint(c.out, "> ")
+		if !c.in.Scan() {
+			fmt.Fprintln(c.out)
+			return c.in.Err()
+		}
+		line := strings.TrimSpace(c.in.Text())
+		if line == "" {
+			continue
+		}
+
+		// Ctrl-C interrupts the current reply, not the chat
+		runCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		reply, err := c.session.Send(runCtx, line)
+		stop()
+		if err != nil {
+			fmt.Fprintln(errOut, "error:", err)
+			continue
+		}
+		if reply.Response == nil {
+			fmt.Fprint(c.out, reply.Text) // Built-in command output
+		}
+		fmt.Fprintln(c.out)
+
+		if reply.Response != nil && c.file != "" {
+			if err := saveSession(c.session.Runner(), c.file); err != nil {
+				fmt.Fprintln(errOut, "error:", err)
+			}
+		}
+	}
+	return nil
+}
+
+// printEvent prints streamed text and tool activity.
+func (c *chat) printEvent(ev plugin.AgentEvent) {
+	switch ev.Type {
+	case plugin.AgentEventTextDelta:
+		fmt.Fprint(c.out, ev.Delta)
+	case plugin.AgentEventToolCallStarted:
+		fmt.Fprintf(c.out, "\n[%s %s]\n", ev.ToolCall.Name, ev.ToolCall.Arguments)
+	}
+}
+
+// approve asks the user whether the tool call may run. Read-only tools and
+// tools the user allowed for the rest of the chat run without asking.
+func (c *chat) approve(ctx context.Context, call llm.ToolCall) error {
+	if c.yes || c.allowed[call.Name] {
+		return nil
+	}
+	fmt.Fprintf(c.out, "\nAllow %s %s? [y]es, [n]o, [a]lways: ", call.Name, call.Arguments)
+	if !c.in.Scan() {
+		return errors.New("denied: no input")
+	}
+	switch strings.ToLower(strings.TrimSpace(c.in.Text())) {
+	case "y", "yes":
+		return nil
+	case "a", "always":
+		c.allowed[call.Name] = true
+		return nil
+	default:
+		return errors.New("denied by the user")
+	}
+}
+
+// save handles /save.
+func (c *chat) save(ctx context.Context, s *plugin.ChatSession, args string) (string, error) {
+	path, err := c.sessionPath(args)
+	if err != nil {
+		return "", err
+	}
+	if err := saveSession(s.Runner(), path); err != nil {
+		return "", err
+	}
+	return "Saved to " + path + ".", nil
+}
+
+// load handles /load.
+func (c *chat) load(ctx context.Context, s *plugin.ChatSession, args string) (string, error) {
+	path, err := c.sessionPath(args)
+	if err != nil {
+		return "", err
+	}
+	if err := loadSession(s.Runner(), path); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Loaded %d messages from %s.", s.Runner().Context().HistoryLen(), path), nil
+}
+
+// sessionPath returns the file given as args, or the session file.
+func (c *chat) sessionPath(args string) (string, error) {
+	if path := strings.TrimSpace(args); path != "" {
+		return path, nil
+	}
+	if c.file == "" {
+		return "", errors.New("no file given and no -session file set")
+	}
+	return c.file, nil
+}
+
+// listTools handles /tools.
+func (c *chat) listTools(ctx context.Context, s *plugin.ChatSession, args string) (string, error) {
+	filtered := s.Runner().FilteredTools()
+	if len(filtered) == 0 {
+		return "No tools.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Tools:\n")
+	for _, tool := range filtered {
+		fmt.Fprintf(&sb, "  %s - %s\n", tool.Name(), tool.Description())
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// exit handles /exit.
+func (c *chat) exit(ctx context.Context, s *plugin.ChatSession, args string) (string, error) {
+	c.done = true
+	return "Bye.", nil
+}
+
+// saveSession writes the runner's context to path as JSON.
+func saveSession(runner *plugin.AgentRunner, path string) error {
+	data, err := json.MarshalIndent(runner.Context(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling session: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing session: %w", err)
+	}
+	return nil
+}
+
+// loadSession replaces the runner's context with the one saved at path.
+func loadSession(runner *plugin.AgentRunner, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading session: %w", err)
+	}
+	loaded := plugin.NewAgentContext()
+	if err := json.Unmarshal(data, loaded); err != nil {
+		return fmt.Errorf("parsing session: %w", err)
+	}
+
+	agentCtx := runner.Context()
+	agentCtx.Clear()
+	agentCtx.AddMessages(loaded.History()...)
+	for _, key := range loaded.StateKeys() {
+		value, _ := loaded.GetState(key)
+		agentCtx.SetState(key, value)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// scriptedProvider returns canned responses in order and streams each as a
+// single chunk.
+type scriptedProvider struct {
+	responses []*provider.Response
+	requests  []*provider.Request
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	p.requests = append(p.requests, req)
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func (p *scriptedProvider) CallStream(ctx context.Context, req *provider.Request) (provider.ResponseStream, error) {
+	resp, err := p.Call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptedStream{resp: resp}, nil
+}
+
+type scriptedStream struct {
+	resp *provider.Response
+	done bool
+}
+
+func (s *scriptedStream) Next() bool {
+	if s.done || s.resp.Content == "" {
+		return false
+	}
+	s.done = true
+	return true
+}
+
+func (s *scriptedStream) Current() *provider.StreamChunk {
+	return &provider.StreamChunk{Delta: s.resp.Content}
+}
+func (s *scriptedStream) Err() error                      { return nil }
+func (s *scriptedStream) Close() error                    { return nil }
+func (s *scriptedStream) Accumulated() *provider.Response { return s.resp }
+
+// useScripted makes a scripted provider the registry default.
+func useScripted(t *testing.T, responses ...*provider.Response) *scriptedProvider {
+	t.Helper()
+	p := &scriptedProvider{responses: responses}
+	name := "scripted-" + t.Name()
+	provider.RegisterInstance(name, p)
+	defaultName := provider.DefaultName()
+	provider.SetDefault(name)
+	t.Cleanup(func() { provider.SetDefault(defaultName) })
+	return p
+}
+
+func TestChat(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out.txt")
+	useScripted(t,
+		&provider.Response{
+			ToolCalls: []provider.ToolCall{{
+				ID: "call_1", Name: "write",
+				Arguments: `{"path":"` + filepath.ToSlash(target) + `","content":"hi"}`,
+			}},
+			FinishReason: provider.FinishReasonToolCalls,
+		},
+		&provider.Response{Content: "I was not allowed to write.", FinishReason: provider.FinishReasonStop},
+	)
+
+	session := filepath.Join(dir, "session.json")
+	input := strings.Join([]string{
+		"Write hi to out.txt",
+		"n", // Deny the write
+		"/tools",
+		"/exit",
+	}, "\n")
+	var out, errOut bytes.Buffer
+	err := run([]string{"chat", "-model", "test-model", "-tools", "file", "-session", session},
+		strings.NewReader(input), &out, &errOut)
+	require.NoError(t, err)
+	assert.Empty(t, errOut.String())
+
+	assert.Contains(t, out.String(), "Allow write")
+	assert.Contains(t, out.String(), "I was not allowed to write.")
+	assert.Contains(t, out.String(), "  grep - ")
+	assert.NoFileExists(t, target)
+
+	// The session was saved after the reply and is loaded by the next chat
+	data, err := os.ReadFile(session)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "vetoed: denied by the user")
+
+	useScripted(t, &provider.Response{Content: "Welcome back.", FinishReason: provider.FinishReasonStop})
+	c, err := newChat(chatFlags{model: "test-model", session: session}, strings.NewReader(""), &out)
+	require.NoError(t, err)
+	assert.Equal(t, 4, c.session.Runner().Context().HistoryLen())
+}
+
+func TestChat_ToolsList(t *testing.T) {
+	useScripted(t)
+	var out bytes.Buffer
+	c, err := newChat(chatFlags{model: "test-model", tools: "read, grep,,"}, strings.NewReader(""), &out)
+	require.NoError(t, err)
+
+	var names []string
+	for _, tool := range c.session.Runner().FilteredTools() {
+		names = append(names, tool.Name())
+	}
+	assert.ElementsMatch(t, []string{"read", "grep"}, names)
+}
+
+func TestChat_Errors(t *testing.T) {
+	var out bytes.Buffer
+	assert.ErrorContains(t, run(nil, nil, &out, &out), "no command")
+	assert.ErrorContains(t, run([]string{"serve"}, nil, &out, &out), `unknown command "serve"`)
+	assert.ErrorContains(t, run([]string{"chat"}, nil, &out, &out), "no model")
+	assert.ErrorContains(t, run([]string{"chat", "-model", "m", "-agent", "reviewer"}, nil, &out, &out), "-agent requires")
+	assert.ErrorContains(t, run([]string{"chat", "-model", "m", "-tools", "telnet"}, nil, &out, &out), `unknown tool "telnet"`)
+}
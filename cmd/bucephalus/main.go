@@ -0,0 +1,58 @@
+// Command bucephalus is an interactive chat client for Bucephalus agents,
+// both a reference application and a tool for debugging plugins, prompts,
+// and providers.
+//
+// Usage:
+//
+//	bucephalus chat [flags]
+//
+// Example:
+//
+//	bucephalus chat -provider anthropic -model claude-sonnet-4-5-20250929 -tools file,bash
+//	bucephalus chat -config bucephalus.yaml -plugin ./plugins/reviewer -session review.json
+//
+// In the chat, lines starting with / are slash commands: /help lists the
+// built-in commands and those of the loaded plugin. Tools that can modify
+// the system ask for permission before they run, unless -yes is set.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "bucephalus:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command line args.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		usage(stderr)
+		return fmt.Errorf("no command")
+	}
+	switch args[0] {
+	case "chat":
+		return runChat(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return nil
+	default:
+		usage(stderr)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// usage prints the commands.
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: bucephalus <command> [flags]
+
+Commands:
+  chat    Chat with an agent (run "bucephalus chat -h" for flags)
+  help    Show this help
+`)
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/i2y/bucephalus/anthropic"
+	"github.com/i2y/bucephalus/gemini"
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/openai"
+	"github.com/i2y/bucephalus/plugin"
+	"github.com/i2y/bucephalus/provider"
+	"github.com/i2y/bucephalus/tools"
+)
+
+// Setup is the result of Bootstrap: the tools, plugins, and quota built from
+// a configuration, and the options that apply its defaults.
+type Setup struct {
+	Config  *Config
+	Tools   []llm.Tool       // Enabled built-in tools
+	Plugins []*plugin.Plugin // Loaded plugins, in configuration order
+	Quota   *llm.Quota       // Shared quota of all calls, or nil
+}
+
+// Bootstrap registers the configured providers in the provider registry,
+// makes the default provider the registry default, and builds the tools,
+// plugins, and quota of the configuration.
+//
+// Example:
+//
+//	cfg, err := config.Load("bucephalus.yaml")
+//	if err != nil {
+//	    return err
+//	}
+//	setup, err := cfg.Bootstrap()
+//	if err != nil {
+//	    return err
+//	}
+//	resp, err := llm.Call(ctx, "Hello", setup.LLMOptions()...)
+//	runner := agent.NewRunner(setup.AgentOptions()...)
+func (c *Config) Bootstrap() (*Setup, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	toolList, err := resolveTools(c.Tools)
+	if err != nil {
+		return nil, err
+	}
+	plugins := make([]*plugin.Plugin, 0, len(c.Plugins))
+	for _, path := range c.Plugins {
+		p, err := plugin.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading plugin %s: %w", path, err)
+		}
+		plugins = append(plugins, p)
+	}
+
+	for name, p := range c.Providers {
+		p.register(name)
+	}
+	if c.Defaults.Provider != "" {
+		provider.SetDefault(c.Defaults.Provider)
+	}
+
+	s := &Setup{Config: c, Tools: toolList, Plugins: plugins}
+	if b := c.Budget; b.RequestsPerMinute > 0 || b.TokensPerMinute > 0 {
+		s.Quota = llm.NewQuota(
+			llm.WithRequestsPerMinute(b.RequestsPerMinute),
+			llm.WithTokensPerMinute(b.TokensPerMinute),
+		)
+	}
+	return s, nil
+}
+
+// LoadAndBootstrap loads the configuration file at path with Load and
+// bootstraps it.
+func LoadAndBootstrap(path string) (*Setup, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Bootstrap()
+}
+
+// register registers the provider under name.
+func (p ProviderConfig) register(name string) {
+	switch p.providerType(name) {
+	case ProviderOpenAI:
+		var opts []openai.Option
+		if p.APIKey != "" {
+			opts = append(opts, openai.WithAPIKey(p.APIKey))
+		}
+		if p.BaseURL != "" {
+			opts = append(opts, openai.WithBaseURL(p.BaseURL))
+		}
+		if p.Proxy != "" {
+			opts = append(opts, openai.WithProxy(p.Proxy))
+		}
+		if p.Timeout > 0 {
+			opts = append(opts, openai.WithTimeout(p.Timeout))
+		}
+		if p.MaxResponseBytes != 0 {
+			opts = append(opts, openai.WithMaxResponseBytes(p.MaxResponseBytes))
+		}
+		openai.Register(name, opts...)
+	case ProviderAnthropic:
+		var opts []anthropic.Option
+		if p.APIKey != "" {
+			opts = append(opts, anthropic.WithAPIKey(p.APIKey))
+		}
+		if p.BaseURL != "" {
+			opts = append(opts, anthropic.WithBaseURL(p.BaseURL))
+		}
+		if p.Proxy != "" {
+			opts = append(opts, anthropic.WithProxy(p.Proxy))
+		}
+		if p.Timeout > 0 {
+			opts = append(opts, anthropic.WithTimeout(p.Timeout))
+		}
+		if p.MaxResponseBytes != 0 {
+			opts = append(opts, anthropic.WithMaxResponseBytes(p.MaxResponseBytes))
+		}
+		anthropic.Register(name, opts...)
+	case ProviderGemini:
+		var opts []gemini.Option
+		if p.APIKey != "" {
+			opts = append(opts, gemini.WithAPIKey(p.APIKey))
+		}
+		if p.BaseURL != "" {
+			opts = append(opts, gemini.WithBaseURL(p.BaseURL))
+		}
+		if p.Proxy != "" {
+			opts = append(opts, gemini.WithProxy(p.Proxy))
+		}
+		if p.Timeout > 0 {
+			opts = append(opts, gemini.WithTimeout(p.Timeout))
+		}
+		if p.MaxResponseBytes != 0 {
+			opts = append(opts, gemini.WithMaxResponseBytes(p.MaxResponseBytes))
+		}
+		gemini.Register(name, opts...)
+	}
+}
+
+// toolGroups are the tool group names accepted in Config.Tools.
+var toolGroups = map[string]func() []llm.Tool{
+	"all":       tools.AllTools,
+	"file":      tools.FileTools,
+	"web":       tools.WebTools,
+	"knowledge": tools.KnowledgeTools,
+	"readonly":  tools.ReadOnlyTools,
+	"system":    tools.SystemTools,
+	"task":      tools.TaskTools,
+}
+
+// resolveTools returns the built-in tools named by names, which are tool
+// names ("read", "bash", ...) or group names ("file", "web", ...).
+func resolveTools(names []string) ([]llm.Tool, error) {
+	byName := make(map[string]llm.Tool)
+	for _, tool := range tools.AllTools() {
+		byName[tool.Name()] = tool
+	}
+
+	var list []llm.Tool
+	seen := make(map[string]bool)
+	add := func(tool llm.Tool) {
+		if !seen[tool.Name()] {
+			seen[tool.Name()] = true
+			list = append(list, tool)
+		}
+	}
+	for _, name := range names {
+		if group, ok := toolGroups[name]; ok {
+			for _, tool := range group() {
+				add(tool)
+			}
+			continue
+		}
+		tool, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+		add(tool)
+	}
+	return list, nil
+}
+
+// LLMOptions returns the options that apply the default provider, model,
+// sampling, and quota of the configuration to a call.
+func (s *Setup) LLMOptions() []llm.Option {
+	d := s.Config.Defaults
+	var opts []llm.Option
+	if d.Provider != "" {
+		opts = append(opts, llm.WithProvider(d.Provider))
+	}
+	if d.Model != "" {
+		opts = append(opts, llm.WithModel(d.Model))
+	}
+	if d.Temperature != nil {
+		opts = append(opts, llm.WithTemperature(*d.Temperature))
+	}
+	if d.MaxTokens != nil {
+		opts = append(opts, llm.WithMaxTokens(*d.MaxTokens))
+	}
+	if s.Quota != nil {
+		opts = append(opts, llm.WithQuota(s.Quota))
+	}
+	return opts
+}
+
+// AgentOptions returns the options that apply the defaults, tools, budget,
+// and quota of the configuration to an agent runner. As with
+// plugin.WithAgentTools, the agent uses the enabled tools listed in its
+// Tools field.
+func (s *Setup) AgentOptions() []plugin.AgentOption {
+	d, b := s.Config.Defaults, s.Config.Budget
+	var opts []plugin.AgentOption
+	if d.Provider != "" {
+		opts = append(opts, plugin.WithAgentProvider(d.Provider))
+	}
+	if d.Model != "" {
+		opts = append(opts, plugin.WithAgentModel(d.Model))
+	}
+	if d.Temperature != nil {
+		opts = append(opts, plugin.WithAgentTemperature(*d.Temperature))
+	}
+	if d.MaxTokens != nil {
+		opts = append(opts, plugin.WithAgentMaxTokens(*d.MaxTokens))
+	}
+	if len(s.Tools) > 0 {
+		opts = append(opts, plugin.WithAgentTools(s.Tools...))
+	}
+	if b.MaxTurns > 0 {
+		opts = append(opts, plugin.WithAgentMaxTurns(b.MaxTurns))
+	}
+	if b.TokenBudget > 0 {
+		opts = append(opts, plugin.WithAgentTokenBudget(b.TokenBudget))
+	}
+	if b.Deadline > 0 {
+		opts = append(opts, plugin.WithAgentDeadline(b.Deadline))
+	}
+	if s.Quota != nil {
+		opts = append(opts, plugin.WithAgentQuota(s.Quota))
+	}
+	return opts
+}
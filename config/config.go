@@ -0,0 +1,300 @@
+// Package config loads Bucephalus settings — providers, default models, tools,
+// budgets, and plugin paths — from a YAML, JSON, or TOML file and the
+// environment, and bootstraps them, so services do not wire them by hand.
+//
+// Example config.yaml:
+//
+//	providers:
+//	  openai:
+//	    api_key: ${OPENAI_API_KEY}
+//	  openai-eu:
+//	    type: openai
+//	    base_url: https://eu.example.com/v1
+//	    timeout: 60s
+//	defaults:
+//	  provider: openai
+//	  model: gpt-4o
+//	  temperature: 0.2
+//	tools: [file, web_fetch]
+//	budget:
+//	  requests_per_minute: 60
+//	  tokens_per_minute: 100000
+//	  max_turns: 20
+//	  deadline: 5m
+//	plugins:
+//	  - ./plugins/reviewer
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables that override the
+// configuration file (see Config.ApplyEnv).
+const EnvPrefix = "BUCEPHALUS_"
+
+// Provider types of ProviderConfig.Type.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderGemini    = "gemini"
+)
+
+// Config is the configuration of a service using Bucephalus.
+type Config struct {
+	Providers map[string]ProviderConfig `yaml:"providers"` // Providers to register, by name
+	Defaults  Defaults                  `yaml:"defaults"`  // Default provider, model, and sampling
+	Tools     []string                  `yaml:"tools"`     // Built-in tools or tool groups to enable
+	Budget    Budget                    `yaml:"budget"`    // Rate and agent run limits
+	Plugins   []string                  `yaml:"plugins"`   // Plugin directories to load
+}
+
+// ProviderConfig configures a provider registered under its name in
+// Config.Providers. Empty fields keep the provider's defaults, e.g. the API
+// key is read from the provider's usual environment variable.
+type ProviderConfig struct {
+	Type             string        `yaml:"type"`               // ProviderOpenAI, ProviderAnthropic, or ProviderGemini (default: the name)
+	APIKey           string        `yaml:"api_key"`            // API key
+	BaseURL          string        `yaml:"base_url"`           // Custom endpoint
+	Proxy            string        `yaml:"proxy"`              // Proxy URL
+	Timeout          time.Duration `yaml:"timeout"`            // Limit of a whole request
+	MaxResponseBytes int64         `yaml:"max_response_bytes"` // Limit of response bodies
+}
+
+// Defaults are the provider, model, and sampling settings used by calls and
+// agents unless they set their own.
+type Defaults struct {
+	Provider    string   `yaml:"provider"`
+	Model       string   `yaml:"model"`
+	Temperature *float64 `yaml:"temperature"`
+	MaxTokens   *int     `yaml:"max_tokens"`
+}
+
+// Budget limits the rate of calls and the size of agent runs. Zero values
+// are not enforced.
+type Budget struct {
+	RequestsPerMinute int           `yaml:"requests_per_minute"` // Shared quota of all calls
+	TokensPerMinute   int           `yaml:"tokens_per_minute"`   // Shared quota of all calls
+	MaxTurns          int           `yaml:"max_turns"`           // LLM calls per agent run
+	TokenBudget       int           `yaml:"token_budget"`        // Tokens per agent run
+	Deadline          time.Duration `yaml:"deadline"`            // Wall-clock time per agent run
+}
+
+// Load loads the configuration file at path, expands ${VAR} references to
+// environment variables in its strings, and applies the overrides of
+// ApplyEnv. The format is chosen by the extension: .yaml/.yml, .json, or
+// .toml. Relative plugin paths are resolved against the file's directory.
+//
+// Example:
+//
+//	cfg, err := config.Load("bucephalus.yaml")
+//	if err != nil {
+//	    return err
+//	}
+//	setup, err := cfg.Bootstrap()
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	cfg, err := Parse(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.expand(os.Getenv)
+	for i, p := range cfg.Plugins {
+		if !filepath.IsAbs(p) {
+			cfg.Plugins[i] = filepath.Join(filepath.Dir(path), p)
+		}
+	}
+	if err := cfg.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Parse parses a configuration in format "yaml", "yml", "json", or "toml".
+// Unknown settings are errors. Unlike Load, it does not expand environment
+// variables or apply overrides.
+func Parse(data []byte, format string) (*Config, error) {
+	format = strings.ToLower(format)
+	switch format {
+	case "yaml", "yml":
+	case "json", "toml":
+		// Convert to YAML, which shares the types and decodes durations
+		// from strings like "30s"
+		var tree any
+		var err error
+		if format == "json" {
+			err = json.Unmarshal(data, &tree)
+		} else {
+			var table map[string]any
+			err = toml.Unmarshal(data, &table)
+			tree = table
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing config: %w", err)
+		}
+		if data, err = yaml.Marshal(tree); err != nil {
+			return nil, fmt.Errorf("parsing config: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that the provider types are known and that the default
+// provider is configured or built in.
+func (c *Config) Validate() error {
+	for name, p := range c.Providers {
+		switch p.providerType(name) {
+		case ProviderOpenAI, ProviderAnthropic, ProviderGemini:
+		default:
+			return fmt.Errorf("provider %q: unknown type %q", name, p.providerType(name))
+		}
+	}
+	if d := c.Defaults.Provider; d != "" {
+		if _, ok := c.Providers[d]; !ok && !isBuiltin(d) {
+			return fmt.Errorf("default provider %q is not configured", d)
+		}
+	}
+	return nil
+}
+
+// providerType returns the type of the provider registered under name.
+func (p ProviderConfig) providerType(name string) string {
+	if p.Type != "" {
+		return p.Type
+	}
+	return name
+}
+
+// isBuiltin reports whether name is a provider registered by its package.
+func isBuiltin(name string) bool {
+	return name == ProviderOpenAI || name == ProviderAnthropic || name == ProviderGemini
+}
+
+// expand expands ${VAR} references in the string settings using mapping.
+func (c *Config) expand(mapping func(string) string) {
+	for name, p := range c.Providers {
+		p.Type = os.Expand(p.Type, mapping)
+		p.APIKey = os.Expand(p.APIKey, mapping)
+		p.BaseURL = os.Expand(p.BaseURL, mapping)
+		p.Proxy = os.Expand(p.Proxy, mapping)
+		c.Providers[name] = p
+	}
+	c.Defaults.Provider = os.Expand(c.Defaults.Provider, mapping)
+	c.Defaults.Model = os.Expand(c.Defaults.Model, mapping)
+	for i, p := range c.Plugins {
+		c.Plugins[i] = os.Expand(p, mapping)
+	}
+}
+
+// ApplyEnv overrides settings with the environment variables returned by
+// getenv, which is usually os.Getenv:
+//
+//	BUCEPHALUS_PROVIDER, BUCEPHALUS_MODEL         Defaults.Provider, Defaults.Model
+//	BUCEPHALUS_TEMPERATURE, BUCEPHALUS_MAX_TOKENS Defaults.Temperature, Defaults.MaxTokens
+//	BUCEPHALUS_TOOLS                              Tools, comma-separated
+//	BUCEPHALUS_PLUGINS                            Plugins, separated by os.PathListSeparator
+//	BUCEPHALUS_REQUESTS_PER_MINUTE, BUCEPHALUS_TOKENS_PER_MINUTE,
+//	BUCEPHALUS_MAX_TURNS, BUCEPHALUS_TOKEN_BUDGET, BUCEPHALUS_DEADLINE   Budget
+//	BUCEPHALUS_<NAME>_API_KEY, BUCEPHALUS_<NAME>_BASE_URL               Providers[name]
+//
+// <NAME> is the provider name in upper case with "-" replaced by "_".
+// Empty variables are ignored.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	env := func(key string) string { return getenv(EnvPrefix + key) }
+
+	setString(&c.Defaults.Provider, env("PROVIDER"))
+	setString(&c.Defaults.Model, env("MODEL"))
+	if v := env("TEMPERATURE"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%sTEMPERATURE: %w", EnvPrefix, err)
+		}
+		c.Defaults.Temperature = &t
+	}
+	if v := env("MAX_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sMAX_TOKENS: %w", EnvPrefix, err)
+		}
+		c.Defaults.MaxTokens = &n
+	}
+	if v := env("TOOLS"); v != "" {
+		c.Tools = splitList(v, ",")
+	}
+	if v := env("PLUGINS"); v != "" {
+		c.Plugins = splitList(v, string(os.PathListSeparator))
+	}
+
+	for key, n := range map[string]*int{
+		"REQUESTS_PER_MINUTE": &c.Budget.RequestsPerMinute,
+		"TOKENS_PER_MINUTE":   &c.Budget.TokensPerMinute,
+		"MAX_TURNS":           &c.Budget.MaxTurns,
+		"TOKEN_BUDGET":        &c.Budget.TokenBudget,
+	} {
+		if v := env(key); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", EnvPrefix, key, err)
+			}
+			*n = i
+		}
+	}
+	if v := env("DEADLINE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDEADLINE: %w", EnvPrefix, err)
+		}
+		c.Budget.Deadline = d
+	}
+
+	for name, p := range c.Providers {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		setString(&p.APIKey, env(key+"_API_KEY"))
+		setString(&p.BaseURL, env(key+"_BASE_URL"))
+		c.Providers[name] = p
+	}
+	return nil
+}
+
+// setString sets *s to v unless v is empty.
+func setString(s *string, v string) {
+	if v != "" {
+		*s = v
+	}
+}
+
+// splitList splits s at sep, trimming spaces and dropping empty elements.
+func splitList(s, sep string) []string {
+	var list []string
+	for _, v := range strings.Split(s, sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+const testYAML = `
+providers:
+  openai-test:
+    type: openai
+    api_key: ${TEST_CONFIG_KEY}
+    base_url: https://eu.example.com/v1
+    timeout: 30s
+defaults:
+  provider: openai-test
+  model: gpt-4o
+  temperature: 0.2
+tools: [file, bash]
+budget:
+  requests_per_minute: 60
+  max_turns: 5
+  deadline: 2m
+plugins: [plugins/reviewer]
+`
+
+func TestLoad(t *testing.T) {
+	t.Setenv("TEST_CONFIG_KEY", "secret")
+	t.Setenv("BUCEPHALUS_MODEL", "gpt-4o-mini")
+	t.Setenv("BUCEPHALUS_OPENAI_TEST_BASE_URL", "https://us.example.com/v1")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bucephalus.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testYAML), 0o644))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	p := cfg.Providers["openai-test"]
+	assert.Equal(t, "secret", p.APIKey)
+	assert.Equal(t, "https://us.example.com/v1", p.BaseURL) // Overridden by the environment
+	assert.Equal(t, 30*time.Second, p.Timeout)
+	assert.Equal(t, "openai-test", cfg.Defaults.Provider)
+	assert.Equal(t, "gpt-4o-mini", cfg.Defaults.Model)
+	assert.Equal(t, 0.2, *cfg.Defaults.Temperature)
+	assert.Nil(t, cfg.Defaults.MaxTokens)
+	assert.Equal(t, []string{"file", "bash"}, cfg.Tools)
+	assert.Equal(t, Budget{RequestsPerMinute: 60, MaxTurns: 5, Deadline: 2 * time.Minute}, cfg.Budget)
+	assert.Equal(t, []string{filepath.Join(dir, "plugins", "reviewer")}, cfg.Plugins)
+}
+
+func TestParse_Formats(t *testing.T) {
+	want, err := Parse([]byte(testYAML), "yaml")
+	require.NoError(t, err)
+
+	fromJSON, err := Parse([]byte(`{
+		"providers": {"openai-test": {"type": "openai", "api_key": "${TEST_CONFIG_KEY}", "base_url": "https://eu.example.com/v1", "timeout": "30s"}},
+		"defaults": {"provider": "openai-test", "model": "gpt-4o", "temperature": 0.2},
+		"tools": ["file", "bash"],
+		"budget": {"requests_per_minute": 60, "max_turns": 5, "deadline": "2m"},
+		"plugins": ["plugins/reviewer"]
+	}`), "json")
+	require.NoError(t, err)
+	assert.Equal(t, want, fromJSON)
+
+	fromTOML, err := Parse([]byte(`
+# Bucephalus settings
+tools = ["file", "bash"]
+plugins = [
+  "plugins/reviewer", # Code review agents
+]
+
+[providers.openai-test]
+type = "openai"
+api_key = "${TEST_CONFIG_KEY}"
+base_url = 'https://eu.example.com/v1'
+timeout = "30s"
+
+[defaults]
+provider = "openai-test"
+model = "gpt-4o"
+temperature = 0.2
+
+[budget]
+requests_per_minute = 60
+max_turns = 5
+deadline = "2m"
+`), "toml")
+	require.NoError(t, err)
+	assert.Equal(t, want, fromTOML)
+
+	// Inline tables and multi-line strings
+	fromTOML, err = Parse([]byte(`
+providers.openai-test = {type = "openai", timeout = "30s"}
+defaults = {provider = "openai-test", model = """
+gpt-4o"""}
+`), "toml")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", fromTOML.Providers["openai-test"].Type)
+	assert.Equal(t, "gpt-4o", fromTOML.Defaults.Model)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("defaults:\n  modle: gpt-4o\n"), "yaml")
+	assert.ErrorContains(t, err, "modle")
+
+	_, err = Parse([]byte(`[defaults]`+"\nmodel = {name = \"gpt-4o\"}\n"), "toml")
+	assert.ErrorContains(t, err, "parsing config")
+
+	_, err = Parse([]byte(`model = "gpt-4o`), "toml")
+	assert.ErrorContains(t, err, "line 1")
+
+	_, err = Parse([]byte("[budget]\nmax_turns = 010\n"), "toml")
+	assert.ErrorContains(t, err, "leading zero")
+
+	_, err = Parse(nil, "ini")
+	assert.ErrorContains(t, err, "unsupported config format")
+
+	cfg, err := Parse([]byte("providers:\n  local:\n    base_url: http://localhost\n"), "yaml")
+	require.NoError(t, err)
+	assert.ErrorContains(t, cfg.Validate(), `provider "local": unknown type "local"`)
+
+	cfg, err = Parse([]byte("defaults:\n  provider: mistral\n"), "yaml")
+	require.NoError(t, err)
+	assert.ErrorContains(t, cfg.Validate(), `default provider "mistral" is not configured`)
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := &Config{}
+	env := map[string]string{
+		"BUCEPHALUS_PROVIDER":     "anthropic",
+		"BUCEPHALUS_MAX_TOKENS":   "1024",
+		"BUCEPHALUS_TOOLS":        "read, grep",
+		"BUCEPHALUS_TOKEN_BUDGET": "50000",
+		"BUCEPHALUS_DEADLINE":     "90s",
+	}
+	require.NoError(t, cfg.ApplyEnv(func(key string) string { return env[key] }))
+	assert.Equal(t, "anthropic", cfg.Defaults.Provider)
+	assert.Equal(t, 1024, *cfg.Defaults.MaxTokens)
+	assert.Equal(t, []string{"read", "grep"}, cfg.Tools)
+	assert.Equal(t, 50000, cfg.Budget.TokenBudget)
+	assert.Equal(t, 90*time.Second, cfg.Budget.Deadline)
+
+	env["BUCEPHALUS_MAX_TURNS"] = "many"
+	assert.ErrorContains(t, cfg.ApplyEnv(func(key string) string { return env[key] }), "BUCEPHALUS_MAX_TURNS")
+}
+
+func TestBootstrap(t *testing.T) {
+	defaultName := provider.DefaultName()
+	t.Cleanup(func() {
+		provider.SetDefault(defaultName)
+		provider.Reset("openai-test")
+	})
+
+	cfg, err := Parse([]byte(testYAML), "yaml")
+	require.NoError(t, err)
+	cfg.Providers["openai-test"] = ProviderConfig{Type: ProviderOpenAI, APIKey: "secret"}
+	cfg.Plugins = nil
+
+	setup, err := cfg.Bootstrap()
+	require.NoError(t, err)
+
+	p, err := provider.Get("openai-test")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", p.Name())
+	assert.Equal(t, "openai-test", provider.DefaultName())
+
+	var names []string
+	for _, tool := range setup.Tools {
+		names = append(names, tool.Name())
+	}
+	assert.Equal(t, []string{"read", "write", "glob", "grep", "bash"}, names)
+	assert.NotNil(t, setup.Quota)
+	assert.Len(t, setup.LLMOptions(), 4)   // Provider, model, temperature, quota
+	assert.Len(t, setup.AgentOptions(), 7) // Plus tools, max turns, deadline
+
+	cfg.Tools = []string{"telnet"}
+	_, err = cfg.Bootstrap()
+	assert.ErrorContains(t, err, `unknown tool "telnet"`)
+}
@@ -0,0 +1,346 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errNoJudge is returned by the batch judge helpers if no judge is configured.
+var errNoJudge = errors.New("no judge configured (use WithJudge)")
+
+// Sample is a response to grade.
+type Sample struct {
+	Input    string `json:"input"`
+	Response string `json:"response"`
+}
+
+// SampleResult is the grade of a single sample.
+type SampleResult struct {
+	Sample
+	Grade  *Grade `json:"grade,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Passed bool   `json:"passed"`
+}
+
+// GradeReport is the result of grading a batch of samples against a rubric.
+// The statistics cover the samples that were graded without an error.
+type GradeReport struct {
+	Results  []SampleResult `json:"results"`
+	Graded   int            `json:"graded"`
+	Errors   int            `json:"errors"`
+	Passed   int            `json:"passed"` // Samples scoring at least the pass threshold
+	Mean     float64        `json:"mean"`
+	StdDev   float64        `json:"std_dev"`
+	Min      float64        `json:"min"`
+	Max      float64        `json:"max"`
+	PassRate float64        `json:"pass_rate"`
+	Duration time.Duration  `json:"duration"`
+}
+
+// GradeSamples grades every sample against rubric with the judge model set by
+// WithJudge, which is required. WithConcurrency and WithPassThreshold apply.
+// Failures to grade individual samples are recorded in the report;
+// GradeSamples only returns an error if no judge is set or ctx is done.
+//
+// Example:
+//
+//	report, err := eval.GradeSamples(ctx, "Answers accurately and cites a source.", samples,
+//	    eval.WithJudge(llm.WithProvider("openai"), llm.WithModel("gpt-4o")),
+//	    eval.WithConcurrency(4),
+//	)
+//	fmt.Printf("mean %.2f, pass rate %.0f%%\n", report.Mean, report.PassRate*100)
+func GradeSamples(ctx context.Context, rubric string, samples []Sample, opts ...Option) (*GradeReport, error) {
+	cfg := newConfig(opts)
+	if len(cfg.judgeOpts) == 0 {
+		return nil, errNoJudge
+	}
+
+	start := time.Now()
+	report := &GradeReport{Results: make([]SampleResult, len(samples))}
+	err := forEach(ctx, len(samples), cfg.concurrency, func(i int) {
+		res := SampleResult{Sample: samples[i]}
+		grade, err := GradeResponse(ctx, rubric, samples[i].Input, samples[i].Response, cfg.judgeOpts...)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Grade = &grade
+			res.Passed = grade.Score >= cfg.threshold
+		}
+		report.Results[i] = res
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var scores []float64
+	for _, res := range report.Results {
+		if res.Grade == nil {
+			report.Errors++
+			continue
+		}
+		scores = append(scores, res.Grade.Score)
+		if res.Passed {
+			report.Passed++
+		}
+	}
+	report.Graded = len(scores)
+	if report.Graded > 0 {
+		report.Mean, report.StdDev, report.Min, report.Max = stats(scores)
+		report.PassRate = float64(report.Passed) / float64(report.Graded)
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// Pair is a pair of responses to the same input to compare.
+type Pair struct {
+	Input string `json:"input"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+// PairResult is the verdict on a single pair.
+type PairResult struct {
+	Pair
+	Winner    string `json:"winner,omitempty"` // WinnerA, WinnerB, or WinnerTie; empty on error
+	Reasoning string `json:"reasoning,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PairwiseReport is the result of comparing a batch of pairs.
+// The win rates cover the pairs that were judged without an error, counting
+// ties as half a win for each side.
+type PairwiseReport struct {
+	Results  []PairResult  `json:"results"`
+	WinsA    int           `json:"wins_a"`
+	WinsB    int           `json:"wins_b"`
+	Ties     int           `json:"ties"`
+	Errors   int           `json:"errors"`
+	WinRateA float64       `json:"win_rate_a"`
+	WinRateB float64       `json:"win_rate_b"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Winner returns WinnerA or WinnerB if that side won more pairs, or WinnerTie.
+func (r *PairwiseReport) Winner() string {
+	switch {
+	case r.WinsA > r.WinsB:
+		return WinnerA
+	case r.WinsB > r.WinsA:
+		return WinnerB
+	default:
+		return WinnerTie
+	}
+}
+
+// String returns a human-readable summary of the report.
+func (r *PairwiseReport) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("A %d, B %d, ties %d, errors %d: win rate A %.1f%%, B %.1f%%, %s\n",
+		r.WinsA, r.WinsB, r.Ties, r.Errors, r.WinRateA*100, r.WinRateB*100, r.Duration.Round(time.Millisecond)))
+	for i, res := range r.Results {
+		if res.Error != "" {
+			sb.WriteString(fmt.Sprintf("%d  error: %s\n", i+1, res.Error))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%d  %s: %s\n", i+1, res.Winner, res.Reasoning))
+	}
+	return sb.String()
+}
+
+// ComparePairs asks the judge model set by WithJudge, which is required,
+// which response of every pair better meets criteria. WithConcurrency and
+// WithPositionSwap apply. Failures to judge individual pairs are recorded in
+// the report; ComparePairs only returns an error if no judge is set or ctx
+// is done.
+//
+// Example:
+//
+//	report, err := eval.ComparePairs(ctx, "Accurate, concise, and friendly.", pairs,
+//	    eval.WithJudge(llm.WithProvider("openai"), llm.WithModel("gpt-4o")),
+//	    eval.WithPositionSwap(),
+//	)
+//	if report.Winner() == eval.WinnerB {
+//	    prompt = candidatePrompt
+//	}
+func ComparePairs(ctx context.Context, criteria string, pairs []Pair, opts ...Option) (*PairwiseReport, error) {
+	cfg := newConfig(opts)
+	if len(cfg.judgeOpts) == 0 {
+		return nil, errNoJudge
+	}
+
+	start := time.Now()
+	report := &PairwiseReport{Results: make([]PairResult, len(pairs))}
+	err := forEach(ctx, len(pairs), cfg.concurrency, func(i int) {
+		report.Results[i] = comparePair(ctx, criteria, pairs[i], cfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range report.Results {
+		switch res.Winner {
+		case WinnerA:
+			report.WinsA++
+		case WinnerB:
+			report.WinsB++
+		case WinnerTie:
+			report.Ties++
+		default:
+			report.Errors++
+		}
+	}
+	if judged := report.WinsA + report.WinsB + report.Ties; judged > 0 {
+		report.WinRateA = (float64(report.WinsA) + float64(report.Ties)/2) / float64(judged)
+		report.WinRateB = (float64(report.WinsB) + float64(report.Ties)/2) / float64(judged)
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// CompareTargets runs every input against targets a and b, such as two
+// models or prompts, and compares their outputs with ComparePairs. Inputs
+// that fail on either target are recorded as errors.
+//
+// Example:
+//
+//	report, err := eval.CompareTargets(ctx, "Accurate and concise.", inputs,
+//	    eval.AgentTarget(p, agent, plugin.WithAgentModel("gpt-4o")),
+//	    eval.AgentTarget(p, agent, plugin.WithAgentModel("gpt-4o-mini")),
+//	    eval.WithJudge(llm.WithProvider("anthropic"), llm.WithModel("claude-sonnet-4-5-20250929")),
+//	)
+func CompareTargets(ctx context.Context, criteria string, inputs []string, a, b Target, opts ...Option) (*PairwiseReport, error) {
+	cfg := newConfig(opts)
+	if len(cfg.judgeOpts) == 0 {
+		return nil, errNoJudge
+	}
+
+	start := time.Now()
+	pairs := make([]Pair, len(inputs))
+	failures := make([]error, len(inputs))
+	err := forEach(ctx, len(inputs), cfg.concurrency, func(i int) {
+		pairs[i].Input = inputs[i]
+		outA, errA := a(ctx, inputs[i])
+		outB, errB := b(ctx, inputs[i])
+		pairs[i].A, pairs[i].B = outA.Text, outB.Text
+		switch {
+		case errA != nil:
+			failures[i] = fmt.Errorf("target A: %w", errA)
+		case errB != nil:
+			failures[i] = fmt.Errorf("target B: %w", errB)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var judged []Pair
+	var index []int
+	for i, pair := range pairs {
+		if failures[i] == nil {
+			judged = append(judged, pair)
+			index = append(index, i)
+		}
+	}
+	report, err := ComparePairs(ctx, criteria, judged, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]PairResult, len(pairs))
+	for j, i := range index {
+		results[i] = report.Results[j]
+	}
+	for i, failure := range failures {
+		if failure != nil {
+			results[i] = PairResult{Pair: pairs[i], Error: failure.Error()}
+			report.Errors++
+		}
+	}
+	report.Results = results
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// comparePair judges a single pair, in both orders if position swapping is enabled.
+func comparePair(ctx context.Context, criteria string, pair Pair, cfg *config) PairResult {
+	res := PairResult{Pair: pair}
+	verdict, err := CompareResponses(ctx, criteria, pair.Input, pair.A, pair.B, cfg.judgeOpts...)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Winner, res.Reasoning = verdict.Winner, verdict.Reasoning
+	if !cfg.swap {
+		return res
+	}
+
+	swapped, err := CompareResponses(ctx, criteria, pair.Input, pair.B, pair.A, cfg.judgeOpts...)
+	if err != nil {
+		return PairResult{Pair: pair, Error: err.Error()}
+	}
+	if flip(swapped.Winner) != verdict.Winner {
+		// The judge preferred a position rather than a response
+		res.Winner = WinnerTie
+		res.Reasoning = fmt.Sprintf("inconsistent verdicts when the order was swapped: %s / %s",
+			verdict.Reasoning, swapped.Reasoning)
+	}
+	return res
+}
+
+// flip returns the winner of a verdict with the responses swapped.
+func flip(winner string) string {
+	switch winner {
+	case WinnerA:
+		return WinnerB
+	case WinnerB:
+		return WinnerA
+	default:
+		return winner
+	}
+}
+
+// stats returns the mean, population standard deviation, minimum, and
+// maximum of a non-empty slice.
+func stats(values []float64) (mean, stdDev, lo, hi float64) {
+	lo, hi = values[0], values[0]
+	for _, v := range values {
+		mean += v
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		stdDev += (v - mean) * (v - mean)
+	}
+	stdDev = math.Sqrt(stdDev / float64(len(values)))
+	return mean, stdDev, lo, hi
+}
+
+// forEach calls fn for 0 to n-1 with at most concurrency calls at the same
+// time. It returns ctx.Err() if ctx is done before all calls complete.
+func forEach(ctx context.Context, n, concurrency int, fn func(i int)) error {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
@@ -0,0 +1,118 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+var responseTag = regexp.MustCompile(`(?s)<response_(a|b)>\n(.*?)\n</response_`)
+
+// lengthJudge prefers the longer response, or A when both are equally long.
+func lengthJudge(req *provider.Request) *provider.Response {
+	responses := map[string]string{}
+	for _, m := range responseTag.FindAllStringSubmatch(req.Messages[len(req.Messages)-1].Content, -1) {
+		responses[m[1]] = m[2]
+	}
+	if len(responses["b"]) > len(responses["a"]) {
+		return text(`{"winner": "B", "reasoning": "more detailed"}`)
+	}
+	return text(`{"winner": "A", "reasoning": "first is fine"}`)
+}
+
+func TestComparePairs(t *testing.T) {
+	judge := WithJudge(llm.WithProvider(registerFunc(t, lengthJudge)), llm.WithModel("judge"))
+	pairs := []Pair{
+		{Input: "q1", A: "short", B: "much longer"},
+		{Input: "q2", A: "much longer", B: "short"},
+		{Input: "q3", A: "same", B: "same"},
+	}
+	ctx := context.Background()
+
+	report, err := ComparePairs(ctx, "detailed", pairs, judge, WithConcurrency(2))
+	require.NoError(t, err)
+	assert.Equal(t, WinnerB, report.Results[0].Winner)
+	assert.Equal(t, WinnerA, report.Results[1].Winner)
+	assert.Equal(t, WinnerA, report.Results[2].Winner)
+	assert.Equal(t, 2, report.WinsA)
+	assert.Equal(t, 1, report.WinsB)
+	assert.Equal(t, WinnerA, report.Winner())
+
+	// The judge's preference for position A is detected by swapping
+	report, err = ComparePairs(ctx, "detailed", pairs, judge, WithPositionSwap())
+	require.NoError(t, err)
+	assert.Equal(t, WinnerB, report.Results[0].Winner)
+	assert.Equal(t, WinnerA, report.Results[1].Winner)
+	assert.Equal(t, WinnerTie, report.Results[2].Winner)
+	assert.Contains(t, report.Results[2].Reasoning, "inconsistent verdicts")
+	assert.InDelta(t, 0.5, report.WinRateA, 1e-9)
+	assert.InDelta(t, 0.5, report.WinRateB, 1e-9)
+	assert.Equal(t, WinnerTie, report.Winner())
+	assert.Contains(t, report.String(), "A 1, B 1, ties 1, errors 0")
+
+	_, err = ComparePairs(ctx, "detailed", pairs)
+	assert.ErrorIs(t, err, errNoJudge)
+}
+
+func TestCompareTargets(t *testing.T) {
+	judge := WithJudge(llm.WithProvider(registerFunc(t, lengthJudge)), llm.WithModel("judge"))
+	terse := func(ctx context.Context, input string) (Output, error) {
+		return Output{Text: "ok"}, nil
+	}
+	verbose := func(ctx context.Context, input string) (Output, error) {
+		if input == "fail" {
+			return Output{}, errors.New("rate limited")
+		}
+		return Output{Text: "here is a detailed answer to " + input}, nil
+	}
+
+	report, err := CompareTargets(context.Background(), "detailed", []string{"q1", "fail", "q2"}, terse, verbose, judge)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 3)
+	assert.Equal(t, 2, report.WinsB)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, "target B: rate limited", report.Results[1].Error)
+	assert.Equal(t, "q2", report.Results[2].Input)
+	assert.InDelta(t, 1.0, report.WinRateB, 1e-9)
+}
+
+func TestGradeSamples(t *testing.T) {
+	judge := registerFunc(t, func(req *provider.Request) *provider.Response {
+		prompt := req.Messages[len(req.Messages)-1].Content
+		switch {
+		case strings.Contains(prompt, "Paris"):
+			return text(`{"score": 1, "reasoning": "correct"}`)
+		case strings.Contains(prompt, "Lyon"):
+			return text(`{"score": 0.2, "reasoning": "wrong"}`)
+		default:
+			return text(`not json`)
+		}
+	})
+	samples := []Sample{
+		{Input: "Capital of France?", Response: "Paris"},
+		{Input: "Capital of France?", Response: "Lyon"},
+		{Input: "Capital of France?", Response: "???"},
+	}
+
+	report, err := GradeSamples(context.Background(), "Names the capital.", samples,
+		WithJudge(llm.WithProvider(judge), llm.WithModel("judge")), WithConcurrency(3))
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Graded)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, 1, report.Passed)
+	assert.InDelta(t, 0.6, report.Mean, 1e-9)
+	assert.InDelta(t, 0.4, report.StdDev, 1e-9)
+	assert.InDelta(t, 0.2, report.Min, 1e-9)
+	assert.InDelta(t, 1.0, report.Max, 1e-9)
+	assert.InDelta(t, 0.5, report.PassRate, 1e-9)
+	assert.True(t, report.Results[0].Passed)
+	assert.NotEmpty(t, report.Results[2].Error)
+}
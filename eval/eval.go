@@ -0,0 +1,343 @@
+// Package eval provides a harness for evaluating plugin agents.
+//
+// A Suite defines test cases (an input, deterministic expectations, and an
+// optional rubric graded by a judge model). Run executes the cases against a
+// Target, such as a plugin agent, and returns a scored Report. This enables
+// regression testing of agent prompts, commands, and skills.
+//
+// GradeSamples, ComparePairs, and CompareTargets use a judge model to score
+// batches of responses against a rubric or compare them pairwise, returning
+// aggregated statistics for choosing between models or prompts.
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/plugin"
+)
+
+// Suite is a named set of evaluation cases.
+type Suite struct {
+	Name  string `yaml:"name"`
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is a single evaluation case.
+// All expectations must hold for the case to pass.
+type Case struct {
+	Name        string   `yaml:"name"`
+	Input       string   `yaml:"input"`        // User input; slash commands are expanded by AgentTarget
+	Contains    []string `yaml:"contains"`     // Substrings the output must contain
+	NotContains []string `yaml:"not_contains"` // Substrings the output must not contain
+	Matches     []string `yaml:"matches"`      // Regular expressions the output must match
+	Tools       []string `yaml:"tools"`        // Tools that must be called during the run
+	Rubric      string   `yaml:"rubric"`       // Criteria graded by the judge model (optional)
+	Checks      []Check  `yaml:"-"`            // Additional custom checks
+}
+
+// Check is a custom expectation on the output of a case.
+type Check struct {
+	Name string
+	Func func(out Output) error // Returns an error describing the failure
+}
+
+// Output is the result of running a case input against a target.
+type Output struct {
+	Text      string
+	ToolCalls []llm.ToolCall // Tool calls executed during the run
+	Trace     *plugin.Trace  // Trace of the run, if available
+}
+
+// Target produces the output for a case input.
+type Target func(ctx context.Context, input string) (Output, error)
+
+// AgentTarget returns a Target that runs each input with a fresh runner for
+// agent. Inputs are sent through a plugin.ChatSession, so slash commands of p
+// are expanded. opts configure the runner (provider, model, tools, ...).
+func AgentTarget(p *plugin.Plugin, agent *plugin.Agent, opts ...plugin.AgentOption) Target {
+	return func(ctx context.Context, input string) (Output, error) {
+		runner := agent.NewRunner(opts...)
+		session := plugin.NewChatSession(p, runner)
+
+		reply, err := session.Send(ctx, input)
+		out := Output{Trace: runner.LastTrace()}
+		if out.Trace != nil {
+			for _, turn := range out.Trace.Turns {
+				for _, tc := range turn.ToolCalls {
+					out.ToolCalls = append(out.ToolCalls, llm.ToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments})
+				}
+			}
+		}
+		if err != nil {
+			return out, err
+		}
+		out.Text = reply.Text
+		return out, nil
+	}
+}
+
+// Option configures Run.
+type Option func(*config)
+
+// config holds the configuration of an evaluation run.
+type config struct {
+	judgeOpts   []llm.Option
+	threshold   float64
+	concurrency int
+	swap        bool
+}
+
+// newConfig returns the configuration set by opts.
+func newConfig(opts []Option) *config {
+	cfg := &config{threshold: 0.7, concurrency: 1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = 1
+	}
+	return cfg
+}
+
+// WithJudge sets the llm.Options (provider, model, ...) of the judge model
+// used to grade case rubrics and by the batch judge helpers. Cases with a
+// rubric fail if no judge is set.
+func WithJudge(opts ...llm.Option) Option {
+	return func(c *config) {
+		c.judgeOpts = append(c.judgeOpts, opts...)
+	}
+}
+
+// WithPassThreshold sets the minimum judge score for a rubric to pass (default: 0.7).
+func WithPassThreshold(score float64) Option {
+	return func(c *config) {
+		c.threshold = score
+	}
+}
+
+// WithConcurrency sets the number of cases run at the same time (default: 1).
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithPositionSwap makes ComparePairs and CompareTargets judge every pair a
+// second time with the responses swapped, counting inconsistent verdicts as
+// ties. This cancels out the judge's bias toward a position at twice the cost.
+func WithPositionSwap() Option {
+	return func(c *config) {
+		c.swap = true
+	}
+}
+
+// Report is the scored result of running a suite.
+type Report struct {
+	Suite    string        `json:"suite"`
+	Results  []CaseResult  `json:"results"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Score    float64       `json:"score"` // Mean case score
+	Duration time.Duration `json:"duration"`
+}
+
+// CaseResult is the result of a single case.
+type CaseResult struct {
+	Name     string        `json:"name"`
+	Input    string        `json:"input"`
+	Output   string        `json:"output"`
+	Error    string        `json:"error,omitempty"`
+	Checks   []CheckResult `json:"checks"`
+	Grade    *Grade        `json:"grade,omitempty"`
+	Score    float64       `json:"score"` // Mean of check results (0 or 1) and the judge score
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+}
+
+// CheckResult is the result of a single expectation.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Run runs every case of suite against target and returns the report.
+// Failures of individual cases are recorded in the report; Run only
+// returns an error if ctx is done.
+func Run(ctx context.Context, suite *Suite, target Target, opts ...Option) (*Report, error) {
+	cfg := newConfig(opts)
+
+	start := time.Now()
+	report := &Report{Suite: suite.Name, Results: make([]CaseResult, len(suite.Cases))}
+	err := forEach(ctx, len(suite.Cases), cfg.concurrency, func(i int) {
+		report.Results[i] = runCase(ctx, &suite.Cases[i], target, cfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range report.Results {
+		if res.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Score += res.Score
+	}
+	if len(report.Results) > 0 {
+		report.Score /= float64(len(report.Results))
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// runCase runs a single case and scores its output.
+func runCase(ctx context.Context, c *Case, target Target, cfg *config) CaseResult {
+	start := time.Now()
+	res := CaseResult{Name: c.Name, Input: c.Input}
+
+	out, err := target(ctx, c.Input)
+	res.Output = out.Text
+	if err != nil {
+		res.Error = err.Error()
+		res.Duration = time.Since(start)
+		return res
+	}
+
+	res.Checks = runChecks(c, out)
+
+	if c.Rubric != "" {
+		res.Grade = gradeCase(ctx, c, out, cfg)
+	}
+
+	res.Score, res.Passed = score(res.Checks, res.Grade, cfg.threshold)
+	res.Duration = time.Since(start)
+	return res
+}
+
+// runChecks evaluates the deterministic expectations of a case.
+func runChecks(c *Case, out Output) []CheckResult {
+	var results []CheckResult
+	add := func(name string, err error) {
+		r := CheckResult{Name: name, Passed: err == nil}
+		if err != nil {
+			r.Message = err.Error()
+		}
+		results = append(results, r)
+	}
+
+	for _, s := range c.Contains {
+		var err error
+		if !strings.Contains(out.Text, s) {
+			err = fmt.Errorf("output does not contain %q", s)
+		}
+		add(fmt.Sprintf("contains %q", s), err)
+	}
+	for _, s := range c.NotContains {
+		var err error
+		if strings.Contains(out.Text, s) {
+			err = fmt.Errorf("output contains %q", s)
+		}
+		add(fmt.Sprintf("not contains %q", s), err)
+	}
+	for _, pattern := range c.Matches {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(out.Text) {
+			err = fmt.Errorf("output does not match %q", pattern)
+		}
+		add(fmt.Sprintf("matches %q", pattern), err)
+	}
+	for _, name := range c.Tools {
+		var err error
+		if !slices.ContainsFunc(out.ToolCalls, func(tc llm.ToolCall) bool { return tc.Name == name }) {
+			err = fmt.Errorf("tool %q was not called", name)
+		}
+		add(fmt.Sprintf("calls tool %q", name), err)
+	}
+	for _, check := range c.Checks {
+		add(check.Name, check.Func(out))
+	}
+	return results
+}
+
+// gradeCase grades the output against the case rubric with the judge model.
+func gradeCase(ctx context.Context, c *Case, out Output, cfg *config) *Grade {
+	if len(cfg.judgeOpts) == 0 {
+		return &Grade{Reasoning: "no judge configured (use WithJudge)"}
+	}
+	grade, err := GradeResponse(ctx, c.Rubric, c.Input, out.Text, cfg.judgeOpts...)
+	if err != nil {
+		return &Grade{Reasoning: fmt.Sprintf("judge failed: %v", err)}
+	}
+	return &grade
+}
+
+// score computes the score of a case and whether it passed.
+func score(checks []CheckResult, grade *Grade, threshold float64) (float64, bool) {
+	total, n := 0.0, 0
+	passed := true
+	for _, c := range checks {
+		n++
+		if c.Passed {
+			total++
+		} else {
+			passed = false
+		}
+	}
+	if grade != nil {
+		n++
+		total += grade.Score
+		if grade.Score < threshold {
+			passed = false
+		}
+	}
+	if n == 0 {
+		return 1, true
+	}
+	return total / float64(n), passed
+}
+
+// JSON returns the report as indented JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// String returns a human-readable summary of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+	total := len(r.Results)
+	rate := 0.0
+	if total > 0 {
+		rate = float64(r.Passed) / float64(total) * 100
+	}
+	sb.WriteString(fmt.Sprintf("Suite %s: %d/%d passed (%.1f%%), score %.2f, %s\n",
+		r.Suite, r.Passed, total, rate, r.Score, r.Duration.Round(time.Millisecond)))
+
+	for _, res := range r.Results {
+		status := "PASS"
+		if !res.Passed {
+			status = "FAIL"
+		}
+		sb.WriteString(fmt.Sprintf("%s  %s  score %.2f  (%s)\n",
+			status, res.Name, res.Score, res.Duration.Round(time.Millisecond)))
+		if res.Error != "" {
+			sb.WriteString(fmt.Sprintf("      error: %s\n", res.Error))
+		}
+		for _, c := range res.Checks {
+			if !c.Passed {
+				sb.WriteString(fmt.Sprintf("      - %s: %s\n", c.Name, c.Message))
+			}
+		}
+		if res.Grade != nil && !res.Passed {
+			sb.WriteString(fmt.Sprintf("      - judge %.2f: %s\n", res.Grade.Score, res.Grade.Reasoning))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,168 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/llm/llmtest"
+	"github.com/i2y/bucephalus/plugin"
+	"github.com/i2y/bucephalus/provider"
+)
+
+// registerFunc registers fn as a provider and returns its name.
+func registerFunc(t *testing.T, fn func(req *provider.Request) *provider.Response) string {
+	t.Helper()
+	return llmtest.RegisterFunc(t, func(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+		return fn(req), nil
+	})
+}
+
+func text(s string) *provider.Response {
+	return &provider.Response{Content: s, FinishReason: provider.FinishReasonStop}
+}
+
+func TestRun_AgentTarget(t *testing.T) {
+	agentName := registerFunc(t, func(req *provider.Request) *provider.Response {
+		last := req.Messages[len(req.Messages)-1]
+		switch {
+		case last.Role == provider.RoleTool:
+			return text("The weather is sunny.")
+		case strings.Contains(last.Content, "weather"):
+			return &provider.Response{
+				ToolCalls:    []provider.ToolCall{{ID: "call_1", Name: "weather", Arguments: `{}`}},
+				FinishReason: provider.FinishReasonToolCalls,
+			}
+		default:
+			return text("I don't know.")
+		}
+	})
+	judgeName := registerFunc(t, func(req *provider.Request) *provider.Response {
+		if strings.Contains(req.Messages[len(req.Messages)-1].Content, "sunny") {
+			return text(`{"score": 0.9, "reasoning": "accurate"}`)
+		}
+		return text(`{"score": 0.2, "reasoning": "unhelpful"}`)
+	})
+
+	weather := llm.MustNewTool("weather", "Get the weather",
+		func(ctx context.Context, in struct{}) (string, error) {
+			return "sunny", nil
+		})
+
+	agent := &plugin.Agent{Name: "assistant", Tools: []string{"weather"}}
+	target := AgentTarget(nil, agent,
+		plugin.WithAgentProvider(agentName),
+		plugin.WithAgentModel("test-model"),
+		plugin.WithAgentTools(weather),
+	)
+
+	suite := &Suite{Name: "weather", Cases: []Case{
+		{
+			Name:     "uses tool",
+			Input:    "What's the weather?",
+			Contains: []string{"sunny"},
+			Tools:    []string{"weather"},
+			Rubric:   "Reports the current weather.",
+		},
+		{
+			Name:   "unknown",
+			Input:  "What's the time?",
+			Rubric: "Reports the current time.",
+			Checks: []Check{{Name: "not empty", Func: func(out Output) error {
+				if out.Text == "" {
+					return errors.New("empty output")
+				}
+				return nil
+			}}},
+		},
+	}}
+
+	report, err := Run(context.Background(), suite, target,
+		WithJudge(llm.WithProvider(judgeName), llm.WithModel("judge-model")),
+		WithConcurrency(2),
+	)
+	require.NoError(t, err)
+
+	require.Len(t, report.Results, 2)
+	first := report.Results[0]
+	assert.True(t, first.Passed)
+	assert.Len(t, first.Checks, 2)
+	require.NotNil(t, first.Grade)
+	assert.InDelta(t, 0.9, first.Grade.Score, 1e-9)
+	assert.InDelta(t, (1+1+0.9)/3, first.Score, 1e-9)
+
+	second := report.Results[1]
+	assert.False(t, second.Passed)
+	assert.InDelta(t, (1+0.2)/2, second.Score, 1e-9)
+
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Contains(t, report.String(), "FAIL  unknown")
+	assert.Contains(t, report.String(), "judge 0.20: unhelpful")
+
+	data, err := report.JSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"suite": "weather"`)
+}
+
+func TestRun_FailedChecks(t *testing.T) {
+	target := func(ctx context.Context, input string) (Output, error) {
+		if input == "boom" {
+			return Output{}, errors.New("target failed")
+		}
+		return Output{Text: "hello world"}, nil
+	}
+
+	suite := &Suite{Name: "checks", Cases: []Case{
+		{Name: "match", Input: "hi", Matches: []string{`^hello`}, NotContains: []string{"world"}},
+		{Name: "error", Input: "boom"},
+		{Name: "rubric without judge", Input: "hi", Rubric: "Says hello."},
+	}}
+
+	report, err := Run(context.Background(), suite, target)
+	require.NoError(t, err)
+
+	assert.False(t, report.Results[0].Passed)
+	assert.Equal(t, `output contains "world"`, report.Results[0].Checks[0].Message)
+	assert.True(t, report.Results[0].Checks[1].Passed)
+
+	assert.False(t, report.Results[1].Passed)
+	assert.Equal(t, "target failed", report.Results[1].Error)
+
+	assert.False(t, report.Results[2].Passed)
+	assert.Contains(t, report.Results[2].Grade.Reasoning, "no judge configured")
+	assert.Equal(t, 0, report.Passed)
+}
+
+func TestLoadSuite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suite.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+name: support
+cases:
+  - name: refund
+    input: "Can I get a refund?"
+    contains: ["30 days"]
+    not_contains: ["sorry"]
+    tools: ["search_docs"]
+    rubric: "Explains the refund policy."
+  - input: "/review main.go"
+`), 0o644))
+
+	suite, err := LoadSuite(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "support", suite.Name)
+	require.Len(t, suite.Cases, 2)
+	assert.Equal(t, []string{"30 days"}, suite.Cases[0].Contains)
+	assert.Equal(t, []string{"sorry"}, suite.Cases[0].NotContains)
+	assert.Equal(t, []string{"search_docs"}, suite.Cases[0].Tools)
+	assert.Equal(t, "Explains the refund policy.", suite.Cases[0].Rubric)
+	assert.Equal(t, "case 2", suite.Cases[1].Name)
+}
@@ -0,0 +1,97 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// Grade is a judge model's assessment of a response.
+type Grade struct {
+	Score     float64 `json:"score" jsonschema:"required,minimum=0,maximum=1,description=How well the response meets the rubric from 0 (not at all) to 1 (fully)"`
+	Reasoning string  `json:"reasoning" jsonschema:"required,description=Brief justification of the score"`
+}
+
+// judgeInstructions is the system message of the judge model.
+const judgeInstructions = "You are an impartial evaluator. Grade the response to the input strictly " +
+	"against the rubric. Do not reward length or style unless the rubric asks for it."
+
+// GradeResponse asks a judge model to grade response (to input) against rubric.
+// opts configure the judge call and must include the provider and model.
+//
+// Example:
+//
+//	grade, err := eval.GradeResponse(ctx,
+//	    "The answer names the capital of France and nothing else.",
+//	    "What is the capital of France?", resp.Text(),
+//	    llm.WithProvider("openai"), llm.WithModel("gpt-4o"),
+//	)
+func GradeResponse(ctx context.Context, rubric, input, response string, opts ...llm.Option) (Grade, error) {
+	prompt := fmt.Sprintf("<rubric>\n%s\n</rubric>\n\n<input>\n%s\n</input>\n\n<response>\n%s\n</response>",
+		rubric, input, response)
+
+	callOpts := make([]llm.Option, 0, len(opts)+1)
+	callOpts = append(callOpts, opts...)
+	callOpts = append(callOpts, llm.WithSystemMessage(judgeInstructions))
+
+	resp, err := llm.CallParse[Grade](ctx, prompt, callOpts...)
+	if err != nil {
+		return Grade{}, fmt.Errorf("grading response: %w", err)
+	}
+	grade, err := resp.Parsed()
+	if err != nil {
+		return Grade{}, fmt.Errorf("grading response: %w", err)
+	}
+	grade.Score = min(max(grade.Score, 0), 1)
+	return grade, nil
+}
+
+// Verdict is a judge model's preference between two responses.
+type Verdict struct {
+	Winner    string `json:"winner" jsonschema:"required,enum=A,enum=B,enum=tie,description=The better response or tie if they are equally good"`
+	Reasoning string `json:"reasoning" jsonschema:"required,description=Brief justification of the preference"`
+}
+
+// Winners of a Verdict.
+const (
+	WinnerA   = "A"
+	WinnerB   = "B"
+	WinnerTie = "tie"
+)
+
+// compareInstructions is the system message of the judge model for pairwise comparisons.
+const compareInstructions = "You are an impartial evaluator. Compare two responses to the input strictly " +
+	"against the criteria and choose the better one, or tie if neither is better. Do not let the order " +
+	"of the responses, their length, or their style influence you unless the criteria ask for it."
+
+// CompareResponses asks a judge model which of responses a and b (to input)
+// better meets criteria. opts configure the judge call and must include the
+// provider and model. Judges tend to favor one position; use ComparePairs
+// with WithPositionSwap to cancel out this bias.
+//
+// Example:
+//
+//	verdict, err := eval.CompareResponses(ctx,
+//	    "Accurate, concise, and friendly.",
+//	    question, respA.Text(), respB.Text(),
+//	    llm.WithProvider("openai"), llm.WithModel("gpt-4o"),
+//	)
+func CompareResponses(ctx context.Context, criteria, input, a, b string, opts ...llm.Option) (Verdict, error) {
+	prompt := fmt.Sprintf("<criteria>\n%s\n</criteria>\n\n<input>\n%s\n</input>\n\n<response_a>\n%s\n</response_a>\n\n<response_b>\n%s\n</response_b>",
+		criteria, input, a, b)
+
+	callOpts := make([]llm.Option, 0, len(opts)+1)
+	callOpts = append(callOpts, opts...)
+	callOpts = append(callOpts, llm.WithSystemMessage(compareInstructions))
+
+	resp, err := llm.CallParse[Verdict](ctx, prompt, callOpts...)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("comparing responses: %w", err)
+	}
+	verdict, err := resp.Parsed()
+	if err != nil {
+		return Verdict{}, fmt.Errorf("comparing responses: %w", err)
+	}
+	return verdict, nil
+}
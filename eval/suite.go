@@ -0,0 +1,40 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadSuite loads a suite from a YAML file.
+//
+// Format:
+//
+//	name: support-agent
+//	cases:
+//	  - name: refund policy
+//	    input: "Can I get a refund after 30 days?"
+//	    contains: ["30 days"]
+//	    tools: ["search_docs"]
+//	    rubric: "Explains the refund policy politely and accurately."
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading suite: %w", err)
+	}
+
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("parsing suite: %w", err)
+	}
+	if suite.Name == "" {
+		suite.Name = path
+	}
+	for i, c := range suite.Cases {
+		if c.Name == "" {
+			suite.Cases[i].Name = fmt.Sprintf("case %d", i+1)
+		}
+	}
+	return &suite, nil
+}
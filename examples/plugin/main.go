@@ -274,7 +274,7 @@ func demoAgentContext(ctx context.Context, p *plugin.Plugin) {
 		plugin.WithAgentMaxTokens(150),
 		// NEW: Pass additional llm.Options at runner creation
 		plugin.WithAgentLLMOptions(
-			llm.WithSystemMessage(p.SkillsIndexSystemMessage()), // Add skills index
+			p.SkillsIndexOption(), // Add skills index below the agent's system message
 		),
 	)
 
@@ -49,5 +49,15 @@ func run() error {
 	fmt.Printf("\nFull response:\n%s\n", resp.Text())
 	fmt.Printf("\nUsage: %d total tokens\n", resp.Usage().TotalTokens)
 
+	// CallStreamTo writes the deltas to a writer, such as os.Stdout
+	fmt.Println("\nStreaming to stdout...")
+	if _, err := llm.CallStreamTo(ctx, os.Stdout, "Write a short haiku about Go",
+		llm.WithProvider("openai"),
+		llm.WithModel("o4-mini"),
+	); err != nil {
+		return fmt.Errorf("stream error: %w", err)
+	}
+	fmt.Println()
+
 	return nil
 }
@@ -0,0 +1,464 @@
+// Package flow composes multi-step LLM pipelines as directed acyclic graphs.
+//
+// A Flow is a set of named steps: Go functions (Func), LLM calls (Call,
+// CallParse), tool calls (Tool), and agent runs (Agent). Each step returns a
+// typed Ref to its output, which later steps read from the Results and
+// depend on with After. Steps whose dependencies are done run in parallel,
+// each with its own retry policy. The outputs of completed steps form a
+// JSON State, which a failed or interrupted run can be resumed from.
+//
+// Example:
+//
+//	f := flow.New("article")
+//	topic := flow.Input[string](f)
+//	outline := flow.Call(f, "outline", func(r *flow.Results) string {
+//	    return "Outline an article about " + topic.Get(r)
+//	}, flow.After(topic))
+//	facts := flow.Func(f, "facts", func(ctx context.Context, r *flow.Results) ([]string, error) {
+//	    return searchFacts(ctx, topic.Get(r))
+//	}, flow.After(topic), flow.Retry(2, time.Second))
+//	draft := flow.Call(f, "draft", func(r *flow.Results) string {
+//	    return fmt.Sprintf("Write the article.\n\nOutline:\n%s\n\nFacts:\n%s",
+//	        outline.Get(r), strings.Join(facts.Get(r), "\n"))
+//	}, flow.After(outline, facts))
+//
+//	results, err := f.Run(ctx, "Go generics", flow.WithLLMOptions(
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	))
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(draft.Get(results))
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// InputStep is the name of the step that holds the input of a run.
+const InputStep = "input"
+
+// Flow is a directed acyclic graph of steps. Steps can only depend on steps
+// added before them, so a flow has no cycles. A Flow is safe for concurrent
+// runs once all its steps are added.
+type Flow struct {
+	name  string
+	steps map[string]*step
+	order []string // In the order the steps were added
+}
+
+// step is a node of a flow.
+type step struct {
+	name   string
+	deps   []string
+	run    func(ctx context.Context, r *Results, llmOpts []llm.Option) (any, error)
+	decode func(data []byte) (any, error)
+	retry  retryPolicy
+	llm    []llm.Option
+}
+
+// retryPolicy is the retry policy of a step.
+type retryPolicy struct {
+	retries int
+	backoff time.Duration
+}
+
+// New creates an empty flow.
+func New(name string) *Flow {
+	return &Flow{name: name, steps: make(map[string]*step)}
+}
+
+// Name returns the name of the flow.
+func (f *Flow) Name() string {
+	return f.name
+}
+
+// Steps returns the names of the steps, in the order they were added.
+func (f *Flow) Steps() []string {
+	return append([]string(nil), f.order...)
+}
+
+// Ref is a typed reference to the output of a step.
+type Ref[T any] struct {
+	flow *Flow
+	name string
+}
+
+// Name returns the name of the step.
+func (r Ref[T]) Name() string {
+	return r.name
+}
+
+// Get returns the output of the step in r, or the zero value if the step
+// has not completed. Steps can get the outputs of the steps they depend on.
+func (r Ref[T]) Get(results *Results) T {
+	v, _ := results.value(r.name).(T)
+	return v
+}
+
+// ref returns the flow and the name of the step.
+func (r Ref[T]) ref() (*Flow, string) {
+	return r.flow, r.name
+}
+
+// Dep is a step that another step depends on. Refs are Deps.
+type Dep interface {
+	ref() (*Flow, string)
+}
+
+// StepOption configures a step.
+type StepOption func(*step)
+
+// After makes the step depend on deps: it starts once they are all done.
+func After(deps ...Dep) StepOption {
+	return func(s *step) {
+		for _, d := range deps {
+			_, name := d.ref()
+			s.deps = append(s.deps, name)
+		}
+	}
+}
+
+// Retry retries the step up to retries times when it fails, waiting backoff
+// before the first retry and doubling the wait for each next one.
+func Retry(retries int, backoff time.Duration) StepOption {
+	return func(s *step) {
+		s.retry = retryPolicy{retries: retries, backoff: backoff}
+	}
+}
+
+// LLMOptions sets the llm.Options of an LLM call step (Call, CallParse),
+// applied after those of WithLLMOptions.
+func LLMOptions(opts ...llm.Option) StepOption {
+	return func(s *step) {
+		s.llm = append(s.llm, opts...)
+	}
+}
+
+// Input adds the step that holds the input of a run (see Flow.Run), named
+// InputStep. A flow has at most one input.
+func Input[T any](f *Flow) Ref[T] {
+	return add(f, InputStep, func(ctx context.Context, r *Results) (T, error) {
+		var zero T
+		return zero, errors.New("the run has no input")
+	})
+}
+
+// Func adds a step that runs fn.
+//
+// Example:
+//
+//	words := flow.Func(f, "count", func(ctx context.Context, r *flow.Results) (int, error) {
+//	    return len(strings.Fields(draft.Get(r))), nil
+//	}, flow.After(draft))
+func Func[T any](f *Flow, name string, fn func(ctx context.Context, r *Results) (T, error), opts ...StepOption) Ref[T] {
+	return add(f, name, fn, opts...)
+}
+
+// add adds a step with a typed function to f. It panics if the name is
+// taken or a dependency is not a step of f, as both are programming errors.
+func add[T any](f *Flow, name string, fn func(ctx context.Context, r *Results) (T, error), opts ...StepOption) Ref[T] {
+	return addStep[T](f, name, func(ctx context.Context, r *Results, _ []llm.Option) (any, error) {
+		return fn(ctx, r)
+	}, opts...)
+}
+
+// addStep adds a step to f; see add.
+func addStep[T any](f *Flow, name string, run func(ctx context.Context, r *Results, llmOpts []llm.Option) (any, error), opts ...StepOption) Ref[T] {
+	if _, ok := f.steps[name]; ok || name == "" {
+		panic(fmt.Sprintf("flow %q: invalid or duplicate step name %q", f.name, name))
+	}
+	s := &step{
+		name: name,
+		run:  run,
+		decode: func(data []byte) (any, error) {
+			var v T
+			err := json.Unmarshal(data, &v)
+			return v, err
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, dep := range s.deps {
+		if _, ok := f.steps[dep]; !ok {
+			panic(fmt.Sprintf("flow %q: step %q depends on %q, which is not a step of the flow", f.name, name, dep))
+		}
+	}
+	f.steps[name] = s
+	f.order = append(f.order, name)
+	return Ref[T]{flow: f, name: name}
+}
+
+// State is the execution state of a run: the JSON outputs of its completed
+// steps. Pass it to WithState to resume the run.
+type State struct {
+	Flow    string                     `json:"flow"`
+	Outputs map[string]json.RawMessage `json:"outputs"`
+}
+
+// Results holds the outputs of the completed steps of a run.
+type Results struct {
+	mu      sync.Mutex
+	flow    string
+	values  map[string]any
+	outputs map[string]json.RawMessage
+}
+
+// value returns the output of the named step, or nil.
+func (r *Results) value(name string) any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.values[name]
+}
+
+// set records the output of the named step.
+func (r *Results) set(name string, value any, data json.RawMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[name] = value
+	r.outputs[name] = data
+}
+
+// Done reports whether the named step has completed.
+func (r *Results) Done(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.outputs[name]
+	return ok
+}
+
+// State returns the execution state of the run, for resuming it.
+func (r *Results) State() State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return State{Flow: r.flow, Outputs: maps.Clone(r.outputs)}
+}
+
+// StepError is returned by Run when a step fails after its retries.
+type StepError struct {
+	Step     string
+	Attempts int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %q failed after %d attempt(s): %v", e.Step, e.Attempts, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// RunOption configures a run.
+type RunOption func(*runConfig)
+
+// runConfig holds the configuration of a run.
+type runConfig struct {
+	concurrency int
+	state       *State
+	checkpoint  func(ctx context.Context, state State) error
+	llm         []llm.Option
+}
+
+// WithConcurrency limits the number of steps running at the same time.
+// By default, all the steps whose dependencies are done run in parallel.
+func WithConcurrency(n int) RunOption {
+	return func(c *runConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithState resumes a run from state: the steps with an output in state are
+// not run again. The input of the run may be nil to reuse that of state.
+//
+// Example:
+//
+//	results, err := f.Run(ctx, input, flow.WithCheckpoint(save))
+//	if err != nil {
+//	    // Later, after fixing the cause
+//	    results, err = f.Run(ctx, nil, flow.WithState(results.State()))
+//	}
+func WithState(state State) RunOption {
+	return func(c *runConfig) {
+		c.state = &state
+	}
+}
+
+// WithCheckpoint calls fn with the state of the run after each step
+// completes, e.g. to persist it for resuming after a crash. An error of fn
+// fails the run. fn is not called concurrently.
+func WithCheckpoint(fn func(ctx context.Context, state State) error) RunOption {
+	return func(c *runConfig) {
+		c.checkpoint = fn
+	}
+}
+
+// WithLLMOptions sets the llm.Options of all LLM call steps of the run,
+// such as the provider and the model.
+func WithLLMOptions(opts ...llm.Option) RunOption {
+	return func(c *runConfig) {
+		c.llm = append(c.llm, opts...)
+	}
+}
+
+// stepResult is the outcome of a step.
+type stepResult struct {
+	name     string
+	value    any
+	data     json.RawMessage
+	attempts int
+	err      error
+}
+
+// Run runs the flow with input, the output of the Input step if any, and
+// returns the outputs of its steps. Each step starts once its dependencies
+// are done. When a step fails after its retries, no more steps start; the
+// running ones finish and their outputs are kept, and Run returns the
+// results so far with a *StepError. Outputs must be JSON-serializable, so
+// that Results.State can resume the run.
+func (f *Flow) Run(ctx context.Context, input any, opts ...RunOption) (*Results, error) {
+	cfg := &runConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	results := &Results{
+		flow:    f.name,
+		values:  make(map[string]any),
+		outputs: make(map[string]json.RawMessage),
+	}
+	if cfg.state != nil {
+		if cfg.state.Flow != f.name {
+			return nil, fmt.Errorf("resuming flow %q: the state is of flow %q", f.name, cfg.state.Flow)
+		}
+		for name, data := range cfg.state.Outputs {
+			s, ok := f.steps[name]
+			if !ok {
+				return nil, fmt.Errorf("resuming flow %q: unknown step %q", f.name, name)
+			}
+			value, err := s.decode(data)
+			if err != nil {
+				return nil, fmt.Errorf("resuming flow %q: decoding the output of step %q: %w", f.name, name, err)
+			}
+			results.set(name, value, data)
+		}
+	}
+	if s, ok := f.steps[InputStep]; ok && input != nil {
+		if err := setInput(results, s, input); err != nil {
+			return nil, fmt.Errorf("running flow %q: %w", f.name, err)
+		}
+	}
+
+	started := make(map[string]bool)
+	done := make(chan stepResult)
+	running := 0
+	var runErr error
+	for {
+		for _, name := range f.order {
+			if runErr != nil || ctx.Err() != nil || (cfg.concurrency > 0 && running >= cfg.concurrency) {
+				break
+			}
+			s := f.steps[name]
+			if started[name] || results.Done(name) || !depsDone(results, s) {
+				continue
+			}
+			started[name] = true
+			running++
+			go func() { done <- f.runStep(ctx, s, results, cfg) }()
+		}
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+		if res.err != nil {
+			if runErr == nil {
+				runErr = &StepError{Step: res.name, Attempts: res.attempts, Err: res.err}
+			}
+			continue
+		}
+		results.set(res.name, res.value, res.data)
+		if cfg.checkpoint != nil && runErr == nil {
+			if err := cfg.checkpoint(ctx, results.State()); err != nil {
+				runErr = fmt.Errorf("checkpoint after step %q: %w", res.name, err)
+			}
+		}
+	}
+
+	if runErr != nil {
+		return results, fmt.Errorf("running flow %q: %w", f.name, runErr)
+	}
+	for _, name := range f.order {
+		if !results.Done(name) {
+			if err := ctx.Err(); err != nil {
+				return results, fmt.Errorf("running flow %q: %w", f.name, err)
+			}
+			return results, fmt.Errorf("running flow %q: step %q did not run", f.name, name)
+		}
+	}
+	return results, nil
+}
+
+// setInput records input as the output of the input step s.
+func setInput(results *Results, s *step, input any) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("encoding the input: %w", err)
+	}
+	// Decoding checks that input has the type of the step
+	value, err := s.decode(data)
+	if err != nil {
+		return fmt.Errorf("the input does not match the type of the input step: %w", err)
+	}
+	results.set(s.name, value, data)
+	return nil
+}
+
+// depsDone reports whether the dependencies of s are done.
+func depsDone(results *Results, s *step) bool {
+	for _, dep := range s.deps {
+		if !results.Done(dep) {
+			return false
+		}
+	}
+	return true
+}
+
+// runStep runs s with its retry policy and encodes its output.
+func (f *Flow) runStep(ctx context.Context, s *step, results *Results, cfg *runConfig) stepResult {
+	res := stepResult{name: s.name}
+	llmOpts := append(slices.Clip(cfg.llm), s.llm...)
+	backoff := s.retry.backoff
+	for {
+		res.attempts++
+		res.value, res.err = s.run(ctx, results, llmOpts)
+		if res.err == nil {
+			res.data, res.err = json.Marshal(res.value)
+			if res.err != nil {
+				res.err = fmt.Errorf("encoding the output: %w", res.err)
+			}
+			return res
+		}
+		if res.attempts > s.retry.retries || ctx.Err() != nil {
+			return res
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return res
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,294 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/llm/llmtest"
+	"github.com/i2y/bucephalus/provider"
+)
+
+// registerFunc registers fn as a provider and returns the llm.Options to
+// call it.
+func registerFunc(t *testing.T, fn func(req *provider.Request) *provider.Response) []llm.Option {
+	t.Helper()
+	name := llmtest.RegisterFunc(t, func(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+		return fn(req), nil
+	})
+	return []llm.Option{llm.WithProvider(name), llm.WithModel("test-model")}
+}
+
+func TestRun_ParallelBranches(t *testing.T) {
+	f := New("parallel")
+	n := Input[int](f)
+
+	var running, maxRunning atomic.Int32
+	branch := func(factor int) func(ctx context.Context, r *Results) (int, error) {
+		return func(ctx context.Context, r *Results) (int, error) {
+			cur := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := maxRunning.Load()
+				if cur <= old || maxRunning.CompareAndSwap(old, cur) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return n.Get(r) * factor, nil
+		}
+	}
+	double := Func(f, "double", branch(2), After(n))
+	triple := Func(f, "triple", branch(3), After(n))
+	sum := Func(f, "sum", func(ctx context.Context, r *Results) (map[string]int, error) {
+		return map[string]int{"sum": double.Get(r) + triple.Get(r)}, nil
+	}, After(double, triple))
+
+	results, err := f.Run(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, 10, double.Get(results))
+	assert.Equal(t, 15, triple.Get(results))
+	assert.Equal(t, map[string]int{"sum": 25}, sum.Get(results))
+	assert.Equal(t, int32(2), maxRunning.Load())
+	assert.Equal(t, []string{"input", "double", "triple", "sum"}, f.Steps())
+
+	// With a concurrency of 1, the branches run one at a time
+	maxRunning.Store(0)
+	_, err = f.Run(context.Background(), 5, WithConcurrency(1))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+func TestRun_Input(t *testing.T) {
+	f := New("input")
+	in := Input[string](f)
+	upper := Func(f, "upper", func(ctx context.Context, r *Results) (string, error) {
+		return strings.ToUpper(in.Get(r)), nil
+	}, After(in))
+
+	results, err := f.Run(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", upper.Get(results))
+
+	_, err = f.Run(context.Background(), 42)
+	assert.ErrorContains(t, err, "does not match the type of the input step")
+
+	_, err = f.Run(context.Background(), nil)
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, InputStep, stepErr.Step)
+}
+
+func TestRun_Retry(t *testing.T) {
+	f := New("retry")
+	var attempts atomic.Int32
+	flaky := Func(f, "flaky", func(ctx context.Context, r *Results) (string, error) {
+		if attempts.Add(1) < 3 {
+			return "", errors.New("unavailable")
+		}
+		return "ok", nil
+	}, Retry(2, time.Millisecond))
+
+	results, err := f.Run(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", flaky.Get(results))
+	assert.Equal(t, int32(3), attempts.Load())
+
+	attempts.Store(-10)
+	_, err = f.Run(context.Background(), nil)
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "flaky", stepErr.Step)
+	assert.Equal(t, 3, stepErr.Attempts)
+	assert.EqualError(t, stepErr.Err, "unavailable")
+}
+
+func TestRun_Resume(t *testing.T) {
+	type Outline struct {
+		Sections []string `json:"sections"`
+	}
+
+	var outlineRuns, draftRuns atomic.Int32
+	fail := true
+	f := New("article")
+	topic := Input[string](f)
+	outline := Func(f, "outline", func(ctx context.Context, r *Results) (Outline, error) {
+		outlineRuns.Add(1)
+		return Outline{Sections: []string{"Intro: " + topic.Get(r), "End"}}, nil
+	}, After(topic))
+	draft := Func(f, "draft", func(ctx context.Context, r *Results) (string, error) {
+		draftRuns.Add(1)
+		if fail {
+			return "", errors.New("model overloaded")
+		}
+		return strings.Join(outline.Get(r).Sections, "\n"), nil
+	}, After(outline))
+
+	var checkpoints []State
+	results, err := f.Run(context.Background(), "Go", WithCheckpoint(func(ctx context.Context, state State) error {
+		checkpoints = append(checkpoints, state)
+		return nil
+	}))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `step "draft" failed after 1 attempt(s): model overloaded`)
+	assert.True(t, results.Done("outline"))
+	assert.False(t, results.Done("draft"))
+	require.Len(t, checkpoints, 1)
+	assert.Equal(t, results.State(), checkpoints[0])
+
+	// The state survives a JSON round trip with typed outputs
+	data, err := json.Marshal(results.State())
+	require.NoError(t, err)
+	var state State
+	require.NoError(t, json.Unmarshal(data, &state))
+
+	fail = false
+	results, err = f.Run(context.Background(), nil, WithState(state))
+	require.NoError(t, err)
+	assert.Equal(t, "Intro: Go\nEnd", draft.Get(results))
+	assert.Equal(t, Outline{Sections: []string{"Intro: Go", "End"}}, outline.Get(results))
+	assert.Equal(t, int32(1), outlineRuns.Load())
+	assert.Equal(t, int32(2), draftRuns.Load())
+
+	_, err = New("other").Run(context.Background(), nil, WithState(state))
+	assert.ErrorContains(t, err, `the state is of flow "article"`)
+}
+
+func TestRun_FailureKeepsRunningSteps(t *testing.T) {
+	f := New("failure")
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, name)
+	}
+	Func(f, "fail", func(ctx context.Context, r *Results) (int, error) {
+		return 0, errors.New("boom")
+	})
+	slow := Func(f, "slow", func(ctx context.Context, r *Results) (int, error) {
+		time.Sleep(20 * time.Millisecond)
+		record("slow")
+		return 1, nil
+	})
+	Func(f, "after", func(ctx context.Context, r *Results) (int, error) {
+		record("after")
+		return 2, nil
+	}, After(slow))
+
+	results, err := f.Run(context.Background(), nil)
+	require.Error(t, err)
+	assert.True(t, results.Done("slow"))
+	assert.False(t, results.Done("after"))
+	assert.Equal(t, []string{"slow"}, ran)
+}
+
+func TestRun_CheckpointError(t *testing.T) {
+	f := New("checkpoint")
+	Func(f, "a", func(ctx context.Context, r *Results) (int, error) { return 1, nil })
+
+	_, err := f.Run(context.Background(), nil, WithCheckpoint(func(ctx context.Context, state State) error {
+		return errors.New("disk full")
+	}))
+	assert.ErrorContains(t, err, `checkpoint after step "a": disk full`)
+}
+
+func TestRun_Canceled(t *testing.T) {
+	f := New("canceled")
+	ctx, cancel := context.WithCancel(context.Background())
+	a := Func(f, "a", func(ctx context.Context, r *Results) (int, error) {
+		cancel()
+		return 1, nil
+	})
+	Func(f, "b", func(ctx context.Context, r *Results) (int, error) { return 2, nil }, After(a))
+
+	results, err := f.Run(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, results.Done("a"))
+	assert.False(t, results.Done("b"))
+}
+
+func TestNew_InvalidSteps(t *testing.T) {
+	f := New("invalid")
+	a := Func(f, "a", func(ctx context.Context, r *Results) (int, error) { return 1, nil })
+	assert.Panics(t, func() {
+		Func(f, "a", func(ctx context.Context, r *Results) (int, error) { return 1, nil })
+	})
+
+	other := New("other")
+	assert.Panics(t, func() {
+		Func(other, "b", func(ctx context.Context, r *Results) (int, error) { return 1, nil }, After(a))
+	})
+}
+
+func TestCall(t *testing.T) {
+	type Review struct {
+		Score int `json:"score"`
+	}
+
+	var mu sync.Mutex
+	var models []string
+	llmOpts := registerFunc(t, func(req *provider.Request) *provider.Response {
+		mu.Lock()
+		models = append(models, req.Model)
+		mu.Unlock()
+		prompt := req.Messages[len(req.Messages)-1].Content
+		if req.JSONSchema != nil {
+			return &provider.Response{Content: `{"score": 8}`, FinishReason: provider.FinishReasonStop}
+		}
+		return &provider.Response{Content: "draft about " + strings.TrimPrefix(prompt, "Write about "), FinishReason: provider.FinishReasonStop}
+	})
+
+	f := New("llm")
+	topic := Input[string](f)
+	draft := Call(f, "draft", func(r *Results) string {
+		return "Write about " + topic.Get(r)
+	}, After(topic))
+	review := CallParse[Review](f, "review", func(r *Results) string {
+		return "Review: " + draft.Get(r)
+	}, After(draft), LLMOptions(llm.WithModel("judge-model")))
+
+	results, err := f.Run(context.Background(), "Go", WithLLMOptions(llmOpts...))
+	require.NoError(t, err)
+	assert.Equal(t, "draft about Go", draft.Get(results))
+	assert.Equal(t, Review{Score: 8}, review.Get(results))
+	assert.Equal(t, []string{"test-model", "judge-model"}, models)
+}
+
+func TestTool(t *testing.T) {
+	type AddArgs struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	type Sum struct {
+		Sum int `json:"sum"`
+	}
+	add := llm.MustNewTool("add", "Adds two numbers", func(ctx context.Context, args AddArgs) (Sum, error) {
+		if args.A < 0 {
+			return Sum{}, errors.New("negative")
+		}
+		return Sum{Sum: args.A + args.B}, nil
+	})
+
+	f := New("tool")
+	n := Input[int](f)
+	sum := Tool(f, "add", add, func(r *Results) any {
+		return AddArgs{A: n.Get(r), B: 2}
+	}, After(n))
+
+	results, err := f.Run(context.Background(), 3)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sum": 5}`, sum.Get(results))
+
+	_, err = f.Run(context.Background(), -1)
+	assert.ErrorContains(t, err, `tool "add": negative`)
+}
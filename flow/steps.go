@@ -0,0 +1,105 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/plugin"
+)
+
+// Call adds a step that calls the LLM with the prompt built by prompt, with
+// the options of WithLLMOptions and LLMOptions, and outputs the text of the
+// response.
+//
+// Example:
+//
+//	summary := flow.Call(f, "summary", func(r *flow.Results) string {
+//	    return "Summarize:\n" + doc.Get(r)
+//	}, flow.After(doc), flow.LLMOptions(llm.WithModel("gpt-4o-mini")))
+func Call(f *Flow, name string, prompt func(r *Results) string, opts ...StepOption) Ref[string] {
+	return addStep[string](f, name, func(ctx context.Context, r *Results, llmOpts []llm.Option) (any, error) {
+		resp, err := llm.Call(ctx, prompt(r), llmOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Text(), nil
+	}, opts...)
+}
+
+// CallParse adds a step that calls the LLM like Call and outputs the
+// response parsed into T, as with llm.CallParse.
+//
+// Example:
+//
+//	review := flow.CallParse[Review](f, "review", func(r *flow.Results) string {
+//	    return "Review this draft:\n" + draft.Get(r)
+//	}, flow.After(draft), flow.Retry(2, time.Second))
+func CallParse[T any](f *Flow, name string, prompt func(r *Results) string, opts ...StepOption) Ref[T] {
+	return addStep[T](f, name, func(ctx context.Context, r *Results, llmOpts []llm.Option) (any, error) {
+		resp, err := llm.CallParse[T](ctx, prompt(r), llmOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Parsed()
+	}, opts...)
+}
+
+// Tool adds a step that executes tool with the arguments built by args,
+// which are marshaled to JSON, and outputs its result as the model would
+// see it: strings as is, llm.ToolResultContent as its content, and other
+// values as JSON. Unlike llm.ExecuteToolCalls, an error of the tool fails
+// the step, so that it can be retried.
+//
+// Example:
+//
+//	page := flow.Tool(f, "fetch", fetchTool, func(r *flow.Results) any {
+//	    return FetchArgs{URL: url.Get(r)}
+//	}, flow.After(url), flow.Retry(3, time.Second))
+func Tool(f *Flow, name string, tool llm.Tool, args func(r *Results) any, opts ...StepOption) Ref[string] {
+	return add(f, name, func(ctx context.Context, r *Results) (string, error) {
+		data, err := json.Marshal(args(r))
+		if err != nil {
+			return "", fmt.Errorf("encoding the arguments of tool %q: %w", tool.Name(), err)
+		}
+		result, err := tool.Execute(ctx, data)
+		if err != nil {
+			return "", fmt.Errorf("tool %q: %w", tool.Name(), err)
+		}
+		switch v := result.(type) {
+		case string:
+			return v, nil
+		case llm.ToolResultContent:
+			return v.ToolContent(), nil
+		default:
+			out, err := json.Marshal(v)
+			if err != nil {
+				return "", fmt.Errorf("encoding the result of tool %q: %w", tool.Name(), err)
+			}
+			return string(out), nil
+		}
+	}, opts...)
+}
+
+// Agent adds a step that runs runner with the task built by task, and
+// outputs the final text of the run. A runner keeps its conversation across
+// runs, so use a runner per step unless the steps are meant to share one,
+// and make such steps depend on each other, as a runner does not support
+// concurrent runs.
+//
+// Example:
+//
+//	researcher := p.GetAgent("researcher").NewRunner(plugin.WithAgentProvider("anthropic"))
+//	notes := flow.Agent(f, "research", researcher, func(r *flow.Results) string {
+//	    return "Research " + topic.Get(r)
+//	}, flow.After(topic))
+func Agent(f *Flow, name string, runner *plugin.AgentRunner, task func(r *Results) string, opts ...StepOption) Ref[string] {
+	return add(f, name, func(ctx context.Context, r *Results) (string, error) {
+		resp, err := runner.Run(ctx, task(r))
+		if err != nil {
+			return "", err
+		}
+		return resp.Text(), nil
+	}, opts...)
+}
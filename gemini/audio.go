@@ -0,0 +1,155 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// Default audio models and voice.
+const (
+	DefaultTranscriptionModel = "gemini-2.5-flash"
+	DefaultSpeechModel        = "gemini-2.5-flash-preview-tts"
+	DefaultVoice              = "Kore"
+)
+
+// defaultSampleRate is the sample rate of generated speech if the response
+// does not specify it.
+const defaultSampleRate = 24000
+
+// Transcribe implements provider.Transcriber by sending the audio to a
+// multimodal model with transcription instructions.
+func (p *Provider) Transcribe(ctx context.Context, req *provider.TranscriptionRequest) (*provider.Transcription, error) {
+	model := req.Model
+	if model == "" {
+		model = DefaultTranscriptionModel
+	}
+
+	instructions := "Generate a verbatim transcript of the speech in this audio. Respond with the transcript only."
+	if req.Language != "" {
+		instructions += " The speech is in the language with ISO-639-1 code " + req.Language + "."
+	}
+	if req.Prompt != "" {
+		instructions += "\n\nContext:\n" + req.Prompt
+	}
+	apiReq := &generateContentRequest{Contents: []content{{
+		Role: "user",
+		Parts: []part{
+			{Text: instructions},
+			{InlineData: &inlineData{MimeType: req.MIMEType, Data: req.Audio}},
+		},
+	}}}
+
+	apiResp, raw, err := p.client.generateContent(ctx, model, apiReq, req.Headers)
+	if err != nil {
+		return nil, err
+	}
+	resp := p.convertResponse(apiResp)
+	return &provider.Transcription{Text: strings.TrimSpace(resp.Content), Usage: resp.Usage, HTTP: raw}, nil
+}
+
+// Speak implements provider.SpeechSynthesizer with a text-to-speech model.
+// Formats are "wav" (default) and "pcm" (16-bit mono little-endian samples).
+// Gemini has no speed setting; describe the pace in the instructions instead.
+func (p *Provider) Speak(ctx context.Context, req *provider.SpeechRequest) (*provider.Speech, error) {
+	model, voice, format := req.Model, req.Voice, req.Format
+	if model == "" {
+		model = DefaultSpeechModel
+	}
+	if voice == "" {
+		voice = DefaultVoice
+	}
+	if format == "" {
+		format = "wav"
+	}
+	if format != "wav" && format != "pcm" {
+		return nil, fmt.Errorf("unsupported speech format %q", req.Format)
+	}
+	if req.Speed != 0 {
+		return nil, errors.New("gemini does not support speech speed; describe the pace in the instructions")
+	}
+
+	text := req.Text
+	if req.Instructions != "" {
+		text = req.Instructions + ": " + text
+	}
+	apiReq := &generateContentRequest{
+		Contents: []content{{Role: "user", Parts: []part{{Text: text}}}},
+		GenerationConfig: &generationConfig{
+			ResponseModalities: []string{"AUDIO"},
+			SpeechConfig: &speechConfig{VoiceConfig: &voiceConfig{
+				PrebuiltVoiceConfig: &prebuiltVoiceConfig{VoiceName: voice},
+			}},
+		},
+	}
+
+	apiResp, raw, err := p.client.generateContent(ctx, model, apiReq, req.Headers)
+	if err != nil {
+		return nil, err
+	}
+	raw.Body = nil // Keep the raw response small; the audio is in Speech.Audio
+
+	var audio *inlineData
+	if len(apiResp.Candidates) > 0 && apiResp.Candidates[0].Content != nil {
+		for _, part := range apiResp.Candidates[0].Content.Parts {
+			if part.InlineData != nil {
+				audio = part.InlineData
+				break
+			}
+		}
+	}
+	if audio == nil {
+		return nil, errors.New("gemini response contains no audio")
+	}
+
+	speech := &provider.Speech{Audio: audio.Data, MIMEType: audio.MimeType, Usage: p.convertResponse(apiResp).Usage, HTTP: raw}
+	if format == "wav" {
+		speech.Audio = wavFile(audio.Data, sampleRate(audio.MimeType))
+		speech.MIMEType = "audio/wav"
+	}
+	return speech, nil
+}
+
+// sampleRate returns the rate parameter of a PCM MIME type such as
+// "audio/L16;codec=pcm;rate=24000".
+func sampleRate(mimeType string) int {
+	for _, param := range strings.Split(mimeType, ";") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(param), "rate="); ok {
+			if rate, err := strconv.Atoi(value); err == nil && rate > 0 {
+				return rate
+			}
+		}
+	}
+	return defaultSampleRate
+}
+
+// wavFile wraps 16-bit mono little-endian PCM samples in a WAV header.
+func wavFile(pcm []byte, rate int) []byte {
+	const channels, bitsPerSample = 1, 16
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []any{
+		uint32(16),       // Format chunk size
+		uint16(1),        // PCM
+		uint16(channels), // Channels
+		uint32(rate),     // Sample rate
+		uint32(rate * channels * bitsPerSample / 8), // Byte rate
+		uint16(channels * bitsPerSample / 8),        // Block align
+		uint16(bitsPerSample),                       // Bits per sample
+	} {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
@@ -0,0 +1,76 @@
+package gemini
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// audioServer serves resp to generateContent requests and records them.
+func audioServer(t *testing.T, resp *generateContentResponse) (*Provider, *generateContentRequest, *string) {
+	t.Helper()
+	var got generateContentRequest
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &got))
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	p, err := New(WithAPIKey("key"), WithBaseURL(server.URL))
+	require.NoError(t, err)
+	return p, &got, &path
+}
+
+func TestTranscribe(t *testing.T) {
+	p, got, path := audioServer(t, &generateContentResponse{Candidates: []candidate{{
+		Content: &content{Parts: []part{{Text: " Hello there. \n"}}},
+	}}})
+
+	transcript, err := p.Transcribe(context.Background(), &provider.TranscriptionRequest{
+		Audio: []byte("audio"), MIMEType: "audio/mpeg", Language: "en",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there.", transcript.Text)
+	assert.Equal(t, "/v1beta/models/"+DefaultTranscriptionModel+":generateContent", *path)
+	parts := got.Contents[0].Parts
+	require.Len(t, parts, 2)
+	assert.Contains(t, parts[0].Text, "ISO-639-1 code en")
+	assert.Equal(t, &inlineData{MimeType: "audio/mpeg", Data: []byte("audio")}, parts[1].InlineData)
+}
+
+func TestSpeak(t *testing.T) {
+	pcm := []byte{1, 0, 2, 0}
+	p, got, _ := audioServer(t, &generateContentResponse{Candidates: []candidate{{
+		Content: &content{Parts: []part{{InlineData: &inlineData{MimeType: "audio/L16;codec=pcm;rate=16000", Data: pcm}}}},
+	}}})
+
+	speech, err := p.Speak(context.Background(), &provider.SpeechRequest{Text: "Hi", Voice: "Puck", Instructions: "Say cheerfully"})
+	require.NoError(t, err)
+	assert.Equal(t, "Say cheerfully: Hi", got.Contents[0].Parts[0].Text)
+	assert.Equal(t, []string{"AUDIO"}, got.GenerationConfig.ResponseModalities)
+	assert.Equal(t, "Puck", got.GenerationConfig.SpeechConfig.VoiceConfig.PrebuiltVoiceConfig.VoiceName)
+
+	assert.Equal(t, "audio/wav", speech.MIMEType)
+	require.Len(t, speech.Audio, 44+len(pcm))
+	assert.Equal(t, "RIFF", string(speech.Audio[:4]))
+	assert.Equal(t, uint32(16000), binary.LittleEndian.Uint32(speech.Audio[24:28]))
+	assert.Equal(t, pcm, speech.Audio[44:])
+
+	speech, err = p.Speak(context.Background(), &provider.SpeechRequest{Text: "Hi", Format: "pcm"})
+	require.NoError(t, err)
+	assert.Equal(t, pcm, speech.Audio)
+
+	_, err = p.Speak(context.Background(), &provider.SpeechRequest{Text: "Hi", Format: "mp3"})
+	assert.ErrorContains(t, err, `unsupported speech format "mp3"`)
+}
@@ -1,7 +1,6 @@
 package gemini
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,6 +8,9 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+	"github.com/i2y/bucephalus/provider/sse"
 )
 
 const (
@@ -18,66 +20,73 @@ const (
 
 // client wraps the HTTP client for Gemini API calls.
 type client struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey           string
+	baseURL          string
+	httpClient       *http.Client
+	maxResponseBytes int64
 }
 
 // newClient creates a new Gemini client.
-func newClient(apiKey, baseURL string, httpClient *http.Client) *client {
+func newClient(apiKey, baseURL string, httpClient *http.Client, maxResponseBytes int64) *client {
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
+	if maxResponseBytes == 0 {
+		maxResponseBytes = provider.DefaultMaxResponseBytes
+	}
 	return &client{
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		httpClient: httpClient,
+		apiKey:           apiKey,
+		baseURL:          baseURL,
+		httpClient:       httpClient,
+		maxResponseBytes: maxResponseBytes,
 	}
 }
 
-// generateContent sends a generateContent request.
-func (c *client) generateContent(ctx context.Context, model string, req *generateContentRequest) (*generateContentResponse, error) {
+// generateContent sends a generateContent request with the additional headers.
+func (c *client) generateContent(ctx context.Context, model string, req *generateContentRequest, headers http.Header) (*generateContentResponse, *provider.HTTPResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return nil, nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
 	url := fmt.Sprintf("%s/%s/models/%s:generateContent", c.baseURL, apiVersion, model)
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	c.setHeaders(httpReq)
+	c.setHeaders(httpReq, headers)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
+		return nil, nil, fmt.Errorf("sending request: %w", err)
 	}
 	defer func() { _ = httpResp.Body.Close() }()
 
-	respBody, err := io.ReadAll(httpResp.Body)
+	respBody, err := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, nil, fmt.Errorf("reading response: %w", err)
 	}
 
+	raw := &provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody}
 	if httpResp.StatusCode != http.StatusOK {
-		return nil, c.parseError(httpResp.StatusCode, respBody)
+		return nil, raw, c.parseError(raw)
 	}
 
 	var resp generateContentResponse
 	if err := json.Unmarshal(respBody, &resp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+		return nil, raw, fmt.Errorf("parsing response: %w", err)
 	}
 
-	return &resp, nil
+	return &resp, raw, nil
 }
 
-// streamGenerateContent sends a streaming generateContent request.
-func (c *client) streamGenerateContent(ctx context.Context, model string, req *generateContentRequest) (*streamReader, error) {
+// streamGenerateContent sends a streaming generateContent request with the
+// additional headers.
+func (c *client) streamGenerateContent(ctx context.Context, model string, req *generateContentRequest, headers http.Header) (*streamReader, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling request: %w", err)
@@ -89,7 +98,7 @@ func (c *client) streamGenerateContent(ctx context.Context, model string, req *g
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	c.setHeaders(httpReq)
+	c.setHeaders(httpReq, headers)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -98,73 +107,72 @@ func (c *client) streamGenerateContent(ctx context.Context, model string, req *g
 
 	if httpResp.StatusCode != http.StatusOK {
 		defer func() { _ = httpResp.Body.Close() }()
-		respBody, _ := io.ReadAll(httpResp.Body)
-		return nil, c.parseError(httpResp.StatusCode, respBody)
+		respBody, _ := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
+		return nil, c.parseError(&provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody})
 	}
 
 	return &streamReader{
-		reader: bufio.NewReader(httpResp.Body),
+		events: sse.NewReader(httpResp.Body),
 		closer: httpResp.Body,
+		raw:    &provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header},
 	}, nil
 }
 
-func (c *client) setHeaders(req *http.Request) {
+// setHeaders sets the API headers on the request, followed by the additional
+// headers, which replace the API headers of the same name.
+func (c *client) setHeaders(req *http.Request, headers http.Header) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-goog-api-key", c.apiKey)
+	for key, values := range headers {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
 }
 
-func (c *client) parseError(statusCode int, body []byte) error {
+func (c *client) parseError(raw *provider.HTTPResponse) error {
 	var errResp errorResponse
-	if err := json.Unmarshal(body, &errResp); err != nil {
+	if err := json.Unmarshal(raw.Body, &errResp); err != nil {
 		return &APIError{
-			StatusCode: statusCode,
-			Message:    string(body),
+			StatusCode: raw.StatusCode,
+			Message:    string(raw.Body),
+			Raw:        raw,
 		}
 	}
 
 	return &APIError{
-		StatusCode: statusCode,
+		StatusCode: raw.StatusCode,
 		Code:       errResp.Error.Code,
 		Status:     errResp.Error.Status,
 		Message:    errResp.Error.Message,
+		Raw:        raw,
 	}
 }
 
 // streamReader reads SSE events from a Gemini stream.
 type streamReader struct {
-	reader *bufio.Reader
+	events *sse.Reader
 	closer io.Closer
+	raw    *provider.HTTPResponse
 }
 
 // ReadChunk reads the next chunk from the stream.
 func (s *streamReader) ReadChunk() (*streamChunk, error) {
 	for {
-		line, err := s.reader.ReadString('\n')
+		event, err := s.events.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		line = strings.TrimSpace(line)
-		if line == "" {
+		data := strings.TrimSpace(event.Data)
+		if data == "" {
 			continue
 		}
 
-		// Gemini streaming format: "data: {json}"
-		if strings.HasPrefix(line, "data:") {
-			data := strings.TrimPrefix(line, "data:")
-			data = strings.TrimSpace(data)
-
-			if data == "" {
-				continue
-			}
-
-			var chunk streamChunk
-			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-				return nil, fmt.Errorf("parsing chunk: %w", err)
-			}
-
-			return &chunk, nil
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("parsing chunk: %w", err)
 		}
+
+		return &chunk, nil
 	}
 }
 
@@ -179,6 +187,17 @@ type APIError struct {
 	Code       int
 	Status     string
 	Message    string
+	Raw        *provider.HTTPResponse // The HTTP response, with its headers and body
+}
+
+// HTTPStatus returns the HTTP status code of the error.
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// RawResponse returns the HTTP response of the error.
+func (e *APIError) RawResponse() *provider.HTTPResponse {
+	return e.Raw
 }
 
 func (e *APIError) Error() string {
@@ -0,0 +1,97 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// modelsResponse is a page of the models endpoint.
+type modelsResponse struct {
+	Models []struct {
+		Name                       string   `json:"name"`
+		DisplayName                string   `json:"displayName"`
+		InputTokenLimit            int      `json:"inputTokenLimit"`
+		OutputTokenLimit           int      `json:"outputTokenLimit"`
+		SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	} `json:"models"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// ListModels implements provider.ModelLister. Only models that support
+// generateContent are listed.
+func (p *Provider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	var models []provider.ModelInfo
+	pageToken := ""
+	for {
+		resp, err := p.client.listModels(ctx, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range resp.Models {
+			if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+				continue
+			}
+			models = append(models, provider.ModelInfo{
+				ID:               strings.TrimPrefix(m.Name, "models/"),
+				DisplayName:      m.DisplayName,
+				InputTokenLimit:  m.InputTokenLimit,
+				OutputTokenLimit: m.OutputTokenLimit,
+			})
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+	slices.SortFunc(models, func(a, b provider.ModelInfo) int { return strings.Compare(a.ID, b.ID) })
+	return models, nil
+}
+
+// Ping implements provider.HealthChecker by listing the models, which
+// checks the API key without generating tokens.
+func (p *Provider) Ping(ctx context.Context) error {
+	_, err := p.client.listModels(ctx, "")
+	return err
+}
+
+// listModels lists a page of the available models.
+func (c *client) listModels(ctx context.Context, pageToken string) (*modelsResponse, error) {
+	query := url.Values{"pageSize": {"1000"}}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	reqURL := fmt.Sprintf("%s/%s/models?%s", c.baseURL, apiVersion, query.Encode())
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	c.setHeaders(httpReq, nil)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	respBody, err := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, c.parseError(&provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody})
+	}
+
+	var resp modelsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return &resp, nil
+}
@@ -3,17 +3,33 @@ package gemini
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/i2y/bucephalus/provider"
 )
 
 func init() {
-	provider.Register("gemini", func() (provider.Provider, error) {
-		return New()
+	Register("gemini")
+}
+
+// Register registers a Gemini provider configured with opts in the provider
+// registry under name, so it can be selected with llm.WithProvider(name).
+// Register the provider under several names to use several configurations.
+//
+// Example:
+//
+//	gemini.Register("gemini-staging", gemini.WithAPIKey(os.Getenv("GEMINI_STAGING_KEY")))
+//	resp, err := llm.Call(ctx, "Hello", llm.WithProvider("gemini-staging"), ...)
+func Register(name string, opts ...Option) {
+	provider.Register(name, func() (provider.Provider, error) {
+		return New(opts...)
 	})
 }
 
@@ -26,9 +42,11 @@ type Provider struct {
 type Option func(*providerConfig)
 
 type providerConfig struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey           string
+	baseURL          string
+	httpClient       *http.Client
+	http             provider.HTTPOptions
+	maxResponseBytes int64
 }
 
 // WithAPIKey sets the API key.
@@ -45,13 +63,40 @@ func WithBaseURL(url string) Option {
 	}
 }
 
-// WithHTTPClient sets a custom HTTP client.
+// WithHTTPClient sets a custom HTTP client. It replaces the default client,
+// so WithProxy and WithTimeout have no effect.
 func WithHTTPClient(client *http.Client) Option {
 	return func(c *providerConfig) {
 		c.httpClient = client
 	}
 }
 
+// WithProxy sends requests through the proxy at proxyURL, e.g.
+// "http://proxy.internal:3128". By default, the proxy is taken from the
+// HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) Option {
+	return func(c *providerConfig) {
+		c.http.Proxy = proxyURL
+	}
+}
+
+// WithTimeout limits the time of a whole request, including reading a
+// streamed response (default: no limit).
+func WithTimeout(d time.Duration) Option {
+	return func(c *providerConfig) {
+		c.http.Timeout = d
+	}
+}
+
+// WithMaxResponseBytes limits the size of response bodies (default:
+// provider.DefaultMaxResponseBytes). Streamed responses are not limited.
+// A negative n disables the limit.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *providerConfig) {
+		c.maxResponseBytes = n
+	}
+}
+
 // New creates a new Gemini provider.
 func New(opts ...Option) (*Provider, error) {
 	cfg := &providerConfig{}
@@ -70,8 +115,16 @@ func New(opts ...Option) (*Provider, error) {
 		}
 	}
 
+	httpClient := cfg.httpClient
+	if httpClient == nil {
+		var err error
+		if httpClient, err = provider.NewHTTPClient(cfg.http); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Provider{
-		client: newClient(cfg.apiKey, cfg.baseURL, cfg.httpClient),
+		client: newClient(cfg.apiKey, cfg.baseURL, httpClient, cfg.maxResponseBytes),
 	}, nil
 }
 
@@ -80,30 +133,39 @@ func (p *Provider) Name() string {
 	return "gemini"
 }
 
+// Close closes the idle connections of the provider's HTTP client.
+// provider.Close calls it for registered providers.
+func (p *Provider) Close() error {
+	p.client.httpClient.CloseIdleConnections()
+	return nil
+}
+
 // Call implements provider.Provider.
 func (p *Provider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
 	apiReq := p.buildRequest(req)
 
-	apiResp, err := p.client.generateContent(ctx, req.Model, apiReq)
+	apiResp, raw, err := p.client.generateContent(ctx, req.Model, apiReq, req.Headers)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.convertResponse(apiResp), nil
+	resp := p.convertResponse(apiResp)
+	resp.HTTP = raw
+	return resp, nil
 }
 
 // CallStream implements provider.StreamingProvider.
 func (p *Provider) CallStream(ctx context.Context, req *provider.Request) (provider.ResponseStream, error) {
 	apiReq := p.buildRequest(req)
 
-	stream, err := p.client.streamGenerateContent(ctx, req.Model, apiReq)
+	stream, err := p.client.streamGenerateContent(ctx, req.Model, apiReq, req.Headers)
 	if err != nil {
 		return nil, err
 	}
 
 	return &geminiStream{
-		reader:      stream,
-		accumulated: &provider.Response{},
+		reader: stream,
+		acc:    provider.NewAccumulator(stream.raw),
 	}, nil
 }
 
@@ -124,12 +186,22 @@ func (p *Provider) buildRequest(req *provider.Request) *generateContentRequest {
 		}
 	}
 
+	// Tool results carry only the call ID, so find the function names of
+	// the calls in the history
+	toolNames := make(map[string]string)
+	for _, msg := range req.Messages {
+		for _, tc := range msg.ToolCalls {
+			toolNames[tc.ID] = tc.Name
+		}
+	}
+
 	for _, msg := range req.Messages {
-		// Extract system message
+		// Each system message becomes a part of the system instruction
 		if msg.Role == provider.RoleSystem {
-			apiReq.SystemInstruction = &content{
-				Parts: []part{{Text: msg.Content}},
+			if apiReq.SystemInstruction == nil {
+				apiReq.SystemInstruction = &content{}
 			}
+			apiReq.SystemInstruction.Parts = append(apiReq.SystemInstruction.Parts, part{Text: msg.Content})
 			continue
 		}
 
@@ -154,10 +226,15 @@ func (p *Provider) buildRequest(req *provider.Request) *generateContentRequest {
 				}
 			}
 
+			name, ok := toolNames[msg.ToolID]
+			if !ok {
+				name = msg.ToolID // Call IDs used to be the function names
+			}
 			apiContent.Role = "user"
 			apiContent.Parts = append(apiContent.Parts, part{
 				FunctionResponse: &functionResponse{
-					Name:     msg.ToolID,
+					ID:       apiCallID(msg.ToolID),
+					Name:     name,
 					Response: responseData,
 				},
 			})
@@ -177,6 +254,7 @@ func (p *Provider) buildRequest(req *provider.Request) *generateContentRequest {
 				}
 				apiContent.Parts = append(apiContent.Parts, part{
 					FunctionCall: &functionCall{
+						ID:   apiCallID(tc.ID),
 						Name: tc.Name,
 						Args: args,
 					},
@@ -196,6 +274,10 @@ func (p *Provider) buildRequest(req *provider.Request) *generateContentRequest {
 		}
 	}
 
+	if !req.RawMessages {
+		apiReq.Contents = sanitizeContents(apiReq.Contents)
+	}
+
 	// Handle tools
 	if len(req.Tools) > 0 {
 		funcDecls := make([]functionDeclaration, 0, len(req.Tools))
@@ -209,6 +291,7 @@ func (p *Provider) buildRequest(req *provider.Request) *generateContentRequest {
 			})
 		}
 		apiReq.Tools = []tool{{FunctionDeclarations: funcDecls}}
+		apiReq.ToolConfig = convertToolChoice(req.ToolChoice)
 	}
 
 	// Handle JSON Schema for structured output
@@ -228,6 +311,32 @@ func (p *Provider) buildRequest(req *provider.Request) *generateContentRequest {
 	return apiReq
 }
 
+// sanitizeContents normalizes contents to what the API accepts: blank text
+// parts and the contents left empty are dropped, and consecutive contents of
+// the same role, such as the responses to parallel function calls, are
+// merged so that user and model turns alternate.
+func sanitizeContents(contents []content) []content {
+	out := make([]content, 0, len(contents))
+	for _, c := range contents {
+		parts := make([]part, 0, len(c.Parts))
+		for _, p := range c.Parts {
+			if p.FunctionCall == nil && p.FunctionResponse == nil && p.InlineData == nil && strings.TrimSpace(p.Text) == "" {
+				continue
+			}
+			parts = append(parts, p)
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].Role == c.Role {
+			out[n-1].Parts = append(out[n-1].Parts, parts...)
+			continue
+		}
+		out = append(out, content{Role: c.Role, Parts: parts})
+	}
+	return out
+}
+
 // convertResponse converts a Gemini API response to a provider.Response.
 func (p *Provider) convertResponse(resp *generateContentResponse) *provider.Response {
 	result := &provider.Response{}
@@ -240,22 +349,25 @@ func (p *Provider) convertResponse(resp *generateContentResponse) *provider.Resp
 		}
 	}
 
-	if len(resp.Candidates) == 0 {
-		return result
+	var first *candidate
+	if len(resp.Candidates) > 0 {
+		first = &resp.Candidates[0]
+		result.FinishReason = convertFinishReason(first.FinishReason)
+	}
+	if filter := contentFilter(resp.PromptFeedback, first); filter != nil {
+		result.FinishReason = provider.FinishReasonContentFiltered
+		result.ContentFilter = filter
 	}
 
-	candidate := resp.Candidates[0]
-	result.FinishReason = convertFinishReason(candidate.FinishReason)
-
-	if candidate.Content != nil {
-		for _, part := range candidate.Content.Parts {
+	if first != nil && first.Content != nil {
+		for _, part := range first.Content.Parts {
 			if part.Text != "" {
 				result.Content += part.Text
 			}
 			if part.FunctionCall != nil {
 				argsJSON, _ := json.Marshal(part.FunctionCall.Args)
 				result.ToolCalls = append(result.ToolCalls, provider.ToolCall{
-					ID:        part.FunctionCall.Name, // Gemini uses name as ID
+					ID:        callID(part.FunctionCall),
 					Name:      part.FunctionCall.Name,
 					Arguments: string(argsJSON),
 				})
@@ -266,36 +378,45 @@ func (p *Provider) convertResponse(resp *generateContentResponse) *provider.Resp
 	return result
 }
 
-// cleanSchemaForGemini removes fields not supported by Gemini API from JSON schema.
-func cleanSchemaForGemini(schema json.RawMessage) json.RawMessage {
-	if schema == nil {
-		return nil
-	}
+// generatedIDPrefix marks the tool call IDs generated for function calls
+// without an ID, which are not sent back to the API.
+const generatedIDPrefix = "gemini_call_"
 
-	var schemaMap map[string]any
-	if err := json.Unmarshal(schema, &schemaMap); err != nil {
-		return schema
+// callID returns the ID of a function call: the call's own ID, or a
+// generated unique ID if the model did not provide one, so that calls of the
+// same function in one turn can be told apart.
+func callID(fc *functionCall) string {
+	if fc.ID != "" {
+		return fc.ID
 	}
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return generatedIDPrefix + hex.EncodeToString(b[:])
+}
 
-	// Remove unsupported fields
-	delete(schemaMap, "$schema")
-	delete(schemaMap, "additionalProperties")
-
-	// Recursively clean nested properties
-	if props, ok := schemaMap["properties"].(map[string]any); ok {
-		for key, val := range props {
-			if propMap, ok := val.(map[string]any); ok {
-				delete(propMap, "additionalProperties")
-				props[key] = propMap
-			}
-		}
+// apiCallID returns the ID of a tool call to send to the API, or "" for
+// generated IDs.
+func apiCallID(id string) string {
+	if strings.HasPrefix(id, generatedIDPrefix) {
+		return ""
 	}
+	return id
+}
 
-	cleaned, err := json.Marshal(schemaMap)
-	if err != nil {
-		return schema
+// convertToolChoice converts a provider.ToolChoice to a Gemini tool config.
+func convertToolChoice(choice *provider.ToolChoice) *toolConfig {
+	if choice == nil {
+		return nil
 	}
-	return cleaned
+	config := &functionCallingConfig{Mode: "AUTO"}
+	switch choice.Mode {
+	case provider.ToolChoiceRequired:
+		config.Mode = "ANY"
+		config.AllowedFunctionNames = choice.Tools
+	case provider.ToolChoiceNone:
+		config.Mode = "NONE"
+	}
+	return &toolConfig{FunctionCallingConfig: config}
 }
 
 func convertRole(role provider.Role) string {
@@ -317,18 +438,62 @@ func convertFinishReason(reason string) provider.FinishReason {
 		return provider.FinishReasonLength
 	case "TOOL_USE", "FUNCTION_CALL":
 		return provider.FinishReasonToolCalls
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return provider.FinishReasonContentFiltered
 	default:
 		return provider.FinishReasonStop
 	}
 }
 
+// contentFilter returns the details of a blocked prompt or of a candidate
+// stopped by the safety filters, or nil.
+func contentFilter(feedback *promptFeedback, c *candidate) *provider.ContentFilter {
+	if feedback != nil && feedback.BlockReason != "" {
+		return &provider.ContentFilter{
+			Reason:     feedback.BlockReason,
+			Categories: blockedCategories(feedback.SafetyRatings),
+			Message:    feedback.BlockReasonMessage,
+		}
+	}
+	if c != nil && convertFinishReason(c.FinishReason) == provider.FinishReasonContentFiltered {
+		return &provider.ContentFilter{
+			Reason:     c.FinishReason,
+			Categories: blockedCategories(c.SafetyRatings),
+			Message:    c.FinishMessage,
+		}
+	}
+	return nil
+}
+
+// blockedCategories returns the categories of the ratings that caused a
+// block, or of those rated with a high probability if none is marked.
+func blockedCategories(ratings []safetyRating) []string {
+	var categories []string
+	for _, r := range ratings {
+		if r.Blocked {
+			categories = append(categories, r.Category)
+		}
+	}
+	if len(categories) > 0 {
+		return categories
+	}
+	for _, r := range ratings {
+		if r.Probability == "HIGH" {
+			categories = append(categories, r.Category)
+		}
+	}
+	return categories
+}
+
 // geminiStream implements provider.ResponseStream for Gemini.
 type geminiStream struct {
-	reader      *streamReader
-	accumulated *provider.Response
-	err         error
-	current     *provider.StreamChunk
-	done        bool
+	reader    *streamReader
+	acc       *provider.Accumulator
+	err       error
+	chunk     provider.StreamChunk // Reused for every chunk
+	toolDelta provider.ToolCallDelta
+	current   *provider.StreamChunk
+	done      bool
 }
 
 func (s *geminiStream) Next() bool {
@@ -346,36 +511,48 @@ func (s *geminiStream) Next() bool {
 		return false
 	}
 
-	s.current = &provider.StreamChunk{}
+	s.chunk = provider.StreamChunk{}
+	s.current = &s.chunk
 
 	if chunk.UsageMetadata != nil {
-		s.accumulated.Usage = provider.Usage{
+		s.acc.Usage = provider.Usage{
 			PromptTokens:     chunk.UsageMetadata.PromptTokenCount,
 			CompletionTokens: chunk.UsageMetadata.CandidatesTokenCount,
 			TotalTokens:      chunk.UsageMetadata.TotalTokenCount,
 		}
 	}
 
+	if filter := contentFilter(chunk.PromptFeedback, nil); filter != nil {
+		s.current.FinishReason = provider.FinishReasonContentFiltered
+		s.acc.FinishReason = s.current.FinishReason
+		s.acc.ContentFilter = filter
+	}
+
 	if len(chunk.Candidates) > 0 {
 		candidate := chunk.Candidates[0]
 		s.current.FinishReason = convertFinishReason(candidate.FinishReason)
-		s.accumulated.FinishReason = s.current.FinishReason
+		s.acc.FinishReason = s.current.FinishReason
+		if filter := contentFilter(nil, &candidate); filter != nil {
+			s.acc.ContentFilter = filter
+		}
 
 		if candidate.Content != nil {
 			for _, part := range candidate.Content.Parts {
 				if part.Text != "" {
 					s.current.Delta = part.Text
-					s.accumulated.Content += part.Text
+					s.acc.AddContent(part.Text)
 				}
 				if part.FunctionCall != nil {
 					argsJSON, _ := json.Marshal(part.FunctionCall.Args)
-					s.current.ToolCallDelta = &provider.ToolCallDelta{
-						ID:             part.FunctionCall.Name,
+					id := callID(part.FunctionCall)
+					s.toolDelta = provider.ToolCallDelta{
+						ID:             id,
 						Name:           part.FunctionCall.Name,
 						ArgumentsDelta: string(argsJSON),
 					}
-					s.accumulated.ToolCalls = append(s.accumulated.ToolCalls, provider.ToolCall{
-						ID:        part.FunctionCall.Name,
+					s.current.ToolCallDelta = &s.toolDelta
+					s.acc.AddToolCall(provider.ToolCall{
+						ID:        id,
 						Name:      part.FunctionCall.Name,
 						Arguments: string(argsJSON),
 					})
@@ -400,5 +577,5 @@ func (s *geminiStream) Close() error {
 }
 
 func (s *geminiStream) Accumulated() *provider.Response {
-	return s.accumulated
+	return s.acc.Response()
 }
@@ -0,0 +1,128 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+func TestToolCallIDs(t *testing.T) {
+	p := &Provider{}
+	resp := p.convertResponse(&generateContentResponse{Candidates: []candidate{{
+		Content: &content{Parts: []part{
+			{FunctionCall: &functionCall{Name: "get_weather", Args: map[string]any{"city": "Paris"}}},
+			{FunctionCall: &functionCall{Name: "get_weather", Args: map[string]any{"city": "Rome"}}},
+			{FunctionCall: &functionCall{ID: "abc", Name: "get_time"}},
+		}},
+	}}})
+
+	require.Len(t, resp.ToolCalls, 3)
+	paris, rome, clock := resp.ToolCalls[0], resp.ToolCalls[1], resp.ToolCalls[2]
+	assert.NotEqual(t, paris.ID, rome.ID)
+	assert.Equal(t, "abc", clock.ID)
+
+	// Tool results are sent back with the function names, and only API IDs
+	apiReq := p.buildRequest(&provider.Request{Messages: []provider.Message{
+		{Role: provider.RoleUser, Content: "Weather in Paris and Rome, and the time?"},
+		{Role: provider.RoleAssistant, ToolCalls: resp.ToolCalls},
+		{Role: provider.RoleTool, ToolID: rome.ID, Content: `{"temp":20}`},
+		{Role: provider.RoleTool, ToolID: clock.ID, Content: "12:00"},
+	}})
+
+	require.Len(t, apiReq.Contents, 3) // The results are merged into one turn
+	calls := apiReq.Contents[1].Parts
+	assert.Empty(t, calls[0].FunctionCall.ID)
+	assert.Equal(t, "abc", calls[2].FunctionCall.ID)
+
+	weather := apiReq.Contents[2].Parts[0].FunctionResponse
+	assert.Equal(t, "get_weather", weather.Name)
+	assert.Empty(t, weather.ID)
+	clockResult := apiReq.Contents[2].Parts[1].FunctionResponse
+	assert.Equal(t, "get_time", clockResult.Name)
+	assert.Equal(t, "abc", clockResult.ID)
+}
+
+func TestToolConfig(t *testing.T) {
+	p := &Provider{}
+	apiReq := p.buildRequest(&provider.Request{
+		Tools:      []provider.ToolDef{{Name: "get_weather"}, {Name: "get_time"}},
+		ToolChoice: &provider.ToolChoice{Mode: provider.ToolChoiceRequired, Tools: []string{"get_time"}},
+	})
+	require.NotNil(t, apiReq.ToolConfig)
+	assert.Equal(t, &functionCallingConfig{Mode: "ANY", AllowedFunctionNames: []string{"get_time"}}, apiReq.ToolConfig.FunctionCallingConfig)
+
+	apiReq = p.buildRequest(&provider.Request{
+		Tools:      []provider.ToolDef{{Name: "get_weather"}},
+		ToolChoice: &provider.ToolChoice{Mode: provider.ToolChoiceNone},
+	})
+	assert.Equal(t, "NONE", apiReq.ToolConfig.FunctionCallingConfig.Mode)
+}
+
+func TestBuildRequest_SanitizesContents(t *testing.T) {
+	p := &Provider{}
+	msgs := []provider.Message{
+		{Role: provider.RoleUser, Content: "Weather in Paris and Rome?"},
+		{Role: provider.RoleAssistant, ToolCalls: []provider.ToolCall{
+			{ID: "1", Name: "weather", Arguments: `{"city":"Paris"}`},
+			{ID: "2", Name: "weather", Arguments: `{"city":"Rome"}`},
+		}},
+		{Role: provider.RoleTool, ToolID: "1", Content: "20"},
+		{Role: provider.RoleTool, ToolID: "2", Content: "25"},
+		{Role: provider.RoleAssistant, Content: " "},
+		{Role: provider.RoleUser, Content: "Thanks"},
+	}
+	apiReq := p.buildRequest(&provider.Request{Messages: msgs})
+
+	require.Len(t, apiReq.Contents, 3)
+	assert.Equal(t, "model", apiReq.Contents[1].Role)
+	responses := apiReq.Contents[2]
+	assert.Equal(t, "user", responses.Role)
+	require.Len(t, responses.Parts, 3)
+	assert.NotNil(t, responses.Parts[0].FunctionResponse)
+	assert.NotNil(t, responses.Parts[1].FunctionResponse)
+	assert.Equal(t, "Thanks", responses.Parts[2].Text)
+
+	apiReq = p.buildRequest(&provider.Request{Messages: msgs, RawMessages: true})
+	assert.Len(t, apiReq.Contents, 6)
+}
+
+func TestBuildRequest_SystemInstruction(t *testing.T) {
+	p := &Provider{}
+	apiReq := p.buildRequest(&provider.Request{Messages: []provider.Message{
+		{Role: provider.RoleSystem, Content: "You are a librarian."},
+		{Role: provider.RoleSystem, Content: "Answer in French."},
+		{Role: provider.RoleUser, Content: "Hello"},
+	}})
+	require.NotNil(t, apiReq.SystemInstruction)
+	assert.Equal(t, []part{{Text: "You are a librarian."}, {Text: "Answer in French."}}, apiReq.SystemInstruction.Parts)
+}
+
+func TestConvertResponse_ContentFilter(t *testing.T) {
+	p := &Provider{}
+	resp := p.convertResponse(&generateContentResponse{Candidates: []candidate{{
+		Content:      &content{Parts: []part{{Text: "Partial"}}},
+		FinishReason: "SAFETY",
+		SafetyRatings: []safetyRating{
+			{Category: "HARM_CATEGORY_HARASSMENT", Probability: "LOW"},
+			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Probability: "HIGH", Blocked: true},
+		},
+	}}})
+	assert.Equal(t, provider.FinishReasonContentFiltered, resp.FinishReason)
+	assert.Equal(t, &provider.ContentFilter{Reason: "SAFETY", Categories: []string{"HARM_CATEGORY_DANGEROUS_CONTENT"}}, resp.ContentFilter)
+	assert.Equal(t, "Partial", resp.Content)
+
+	// Blocked prompts have no candidates
+	resp = p.convertResponse(&generateContentResponse{PromptFeedback: &promptFeedback{
+		BlockReason:   "PROHIBITED_CONTENT",
+		SafetyRatings: []safetyRating{{Category: "HARM_CATEGORY_HATE_SPEECH", Probability: "HIGH"}},
+	}})
+	assert.Equal(t, provider.FinishReasonContentFiltered, resp.FinishReason)
+	assert.Equal(t, &provider.ContentFilter{Reason: "PROHIBITED_CONTENT", Categories: []string{"HARM_CATEGORY_HATE_SPEECH"}}, resp.ContentFilter)
+
+	resp = p.convertResponse(&generateContentResponse{Candidates: []candidate{{FinishReason: "STOP"}}})
+	assert.Equal(t, provider.FinishReasonStop, resp.FinishReason)
+	assert.Nil(t, resp.ContentFilter)
+}
@@ -0,0 +1,180 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// supportedSchemaKeys are the schema keywords accepted by Gemini, which
+// supports a subset of the OpenAPI 3.0 schema object.
+var supportedSchemaKeys = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"title":            true,
+	"description":      true,
+	"nullable":         true,
+	"enum":             true,
+	"items":            true,
+	"minItems":         true,
+	"maxItems":         true,
+	"properties":       true,
+	"required":         true,
+	"minProperties":    true,
+	"maxProperties":    true,
+	"minLength":        true,
+	"maxLength":        true,
+	"pattern":          true,
+	"minimum":          true,
+	"maximum":          true,
+	"anyOf":            true,
+	"propertyOrdering": true,
+	"default":          true,
+	"example":          true,
+}
+
+// supportedFormats are the formats Gemini accepts, by type.
+var supportedFormats = map[string][]string{
+	"string":  {"enum", "date-time"},
+	"integer": {"int32", "int64"},
+	"number":  {"float", "double"},
+}
+
+// cleanSchemaForGemini converts a JSON schema into a form accepted by Gemini.
+// References are inlined, unsupported keywords ($schema,
+// additionalProperties, ...) are removed,
+// oneOf becomes anyOf, const becomes a single-value enum, nullable types
+// become nullable, and constraints Gemini cannot express (unsupported
+// formats, non-string enums, exclusive bounds) are moved into the
+// description so the model still sees them.
+func cleanSchemaForGemini(schema json.RawMessage) json.RawMessage {
+	if schema == nil {
+		return nil
+	}
+
+	// Gemini does not support references
+	schema = provider.InlineRefs(schema, provider.DefaultRefDepth)
+
+	var schemaMap map[string]any
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		return schema
+	}
+
+	sanitizeSchema(schemaMap)
+
+	cleaned, err := json.Marshal(schemaMap)
+	if err != nil {
+		return schema
+	}
+	return cleaned
+}
+
+// sanitizeSchema converts schema in place; see cleanSchemaForGemini.
+func sanitizeSchema(schema map[string]any) {
+	var notes []string
+
+	if oneOf, ok := schema["oneOf"]; ok {
+		schema["anyOf"] = oneOf
+	}
+	if c, ok := schema["const"]; ok {
+		schema["enum"] = []any{c}
+	}
+
+	// ["string", "null"] and anyOf [X, {"type": "null"}] become nullable
+	if types, ok := schema["type"].([]any); ok {
+		var nonNull []any
+		for _, t := range types {
+			if t == "null" {
+				schema["nullable"] = true
+			} else {
+				nonNull = append(nonNull, t)
+			}
+		}
+		if len(nonNull) == 1 {
+			schema["type"] = nonNull[0]
+		} else {
+			delete(schema, "type")
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		alts := slices.DeleteFunc(slices.Clone(anyOf), func(alt any) bool {
+			m, ok := alt.(map[string]any)
+			return ok && m["type"] == "null"
+		})
+		if len(alts) < len(anyOf) {
+			schema["nullable"] = true
+		}
+		if len(alts) == 1 {
+			if m, ok := alts[0].(map[string]any); ok {
+				delete(schema, "anyOf")
+				for k, v := range m {
+					if _, exists := schema[k]; !exists || k == "type" {
+						schema[k] = v
+					}
+				}
+			}
+		} else {
+			schema["anyOf"] = alts
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	if format, ok := schema["format"].(string); ok && !slices.Contains(supportedFormats[typ], format) {
+		notes = append(notes, "format: "+format)
+		delete(schema, "format")
+	}
+	if enum, ok := schema["enum"].([]any); ok && typ != "string" {
+		// Gemini only supports string enums
+		values := make([]string, len(enum))
+		for i, v := range enum {
+			values[i] = fmt.Sprint(v)
+		}
+		notes = append(notes, "one of: "+strings.Join(values, ", "))
+		delete(schema, "enum")
+	}
+	if min, ok := schema["exclusiveMinimum"]; ok {
+		notes = append(notes, fmt.Sprintf("greater than %v", min))
+	}
+	if max, ok := schema["exclusiveMaximum"]; ok {
+		notes = append(notes, fmt.Sprintf("less than %v", max))
+	}
+	if mult, ok := schema["multipleOf"]; ok {
+		notes = append(notes, fmt.Sprintf("multiple of %v", mult))
+	}
+
+	if len(notes) > 0 {
+		desc, _ := schema["description"].(string)
+		if desc != "" {
+			desc += " "
+		}
+		schema["description"] = desc + "(" + strings.Join(notes, "; ") + ")"
+	}
+
+	for key := range schema {
+		if !supportedSchemaKeys[key] {
+			delete(schema, key)
+		}
+	}
+
+	// Recursively sanitize subschemas
+	if props, ok := schema["properties"].(map[string]any); ok {
+		for _, val := range props {
+			if propMap, ok := val.(map[string]any); ok {
+				sanitizeSchema(propMap)
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		sanitizeSchema(items)
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		for _, alt := range anyOf {
+			if altMap, ok := alt.(map[string]any); ok {
+				sanitizeSchema(altMap)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/schema"
+)
+
+type testEvent struct {
+	Name     string            `json:"name"`
+	Website  string            `json:"website" jsonschema:"format=uri,description=Event website"`
+	Priority int               `json:"priority" jsonschema:"enum=1,enum=2,enum=3"`
+	Price    float64           `json:"price" jsonschema:"exclusiveMinimum=0"`
+	Note     *string           `json:"note" jsonschema:"type=string"`
+	Tags     []testTag         `json:"tags"`
+	Extra    map[string]string `json:"extra,omitempty"`
+}
+
+type testTag struct {
+	Label string `json:"label"`
+}
+
+func TestCleanSchemaForGemini(t *testing.T) {
+	cleaned := cleanSchemaForGemini(schema.MustGenerate[testEvent]())
+
+	var s map[string]any
+	require.NoError(t, json.Unmarshal(cleaned, &s))
+
+	assert.NotContains(t, s, "$schema")
+	assert.NotContains(t, s, "$id")
+	assert.NotContains(t, s, "additionalProperties")
+
+	props := s["properties"].(map[string]any)
+	website := props["website"].(map[string]any)
+	assert.NotContains(t, website, "format")
+	assert.Equal(t, "Event website (format: uri)", website["description"])
+
+	priority := props["priority"].(map[string]any)
+	assert.NotContains(t, priority, "enum")
+	assert.Equal(t, "(one of: 1, 2, 3)", priority["description"])
+
+	price := props["price"].(map[string]any)
+	assert.NotContains(t, price, "exclusiveMinimum")
+	assert.Equal(t, "(greater than 0)", price["description"])
+
+	tag := props["tags"].(map[string]any)["items"].(map[string]any)
+	assert.NotContains(t, tag, "additionalProperties")
+	assert.Contains(t, tag["properties"], "label")
+}
+
+func TestCleanSchemaForGemini_Alternatives(t *testing.T) {
+	cleaned := cleanSchemaForGemini(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"a": {"type": ["string", "null"]},
+			"b": {"oneOf": [{"type": "integer"}, {"type": "null"}]},
+			"c": {"oneOf": [{"type": "object", "properties": {"x": {"type": "string"}}}, {"type": "object", "properties": {"y": {"type": "string"}}}]},
+			"d": {"type": "string", "const": "x"}
+		}
+	}`))
+
+	var s struct {
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(cleaned, &s))
+
+	assert.Equal(t, map[string]any{"type": "string", "nullable": true}, s.Properties["a"])
+	assert.Equal(t, map[string]any{"type": "integer", "nullable": true}, s.Properties["b"])
+	assert.Len(t, s.Properties["c"]["anyOf"], 2)
+	assert.NotContains(t, s.Properties["c"], "oneOf")
+	assert.Equal(t, map[string]any{"type": "string", "enum": []any{"x"}}, s.Properties["d"])
+}
+
+type testCategory struct {
+	Name          string         `json:"name"`
+	Subcategories []testCategory `json:"subcategories"`
+}
+
+func TestCleanSchemaForGemini_Recursive(t *testing.T) {
+	cleaned := cleanSchemaForGemini(schema.MustGenerate[testCategory]())
+	assert.NotContains(t, string(cleaned), "$ref")
+	assert.NotContains(t, string(cleaned), "$defs")
+	assert.Contains(t, string(cleaned), "subcategories")
+}
@@ -8,6 +8,7 @@ type generateContentRequest struct {
 	SystemInstruction *content          `json:"systemInstruction,omitempty"`
 	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
 	Tools             []tool            `json:"tools,omitempty"`
+	ToolConfig        *toolConfig       `json:"toolConfig,omitempty"`
 }
 
 // content represents a content object in the conversation.
@@ -21,16 +22,25 @@ type part struct {
 	Text             string            `json:"text,omitempty"`
 	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
 	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+	InlineData       *inlineData       `json:"inlineData,omitempty"`
+}
+
+// inlineData represents media embedded in a part, such as audio.
+type inlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"` // Base64-encoded in JSON
 }
 
 // functionCall represents a function call from the model.
 type functionCall struct {
+	ID   string         `json:"id,omitempty"`
 	Name string         `json:"name"`
 	Args map[string]any `json:"args,omitempty"`
 }
 
 // functionResponse represents a function response to send back.
 type functionResponse struct {
+	ID       string `json:"id,omitempty"`
 	Name     string `json:"name"`
 	Response any    `json:"response"`
 }
@@ -44,6 +54,24 @@ type generationConfig struct {
 	StopSequences    []string `json:"stopSequences,omitempty"`
 	ResponseSchema   any      `json:"responseSchema,omitempty"`
 	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+
+	ResponseModalities []string      `json:"responseModalities,omitempty"`
+	SpeechConfig       *speechConfig `json:"speechConfig,omitempty"`
+}
+
+// speechConfig configures speech generation.
+type speechConfig struct {
+	VoiceConfig *voiceConfig `json:"voiceConfig,omitempty"`
+}
+
+// voiceConfig selects the voice of generated speech.
+type voiceConfig struct {
+	PrebuiltVoiceConfig *prebuiltVoiceConfig `json:"prebuiltVoiceConfig,omitempty"`
+}
+
+// prebuiltVoiceConfig names a prebuilt voice.
+type prebuiltVoiceConfig struct {
+	VoiceName string `json:"voiceName"`
 }
 
 // tool represents a tool definition.
@@ -58,18 +86,45 @@ type functionDeclaration struct {
 	Parameters  json.RawMessage `json:"parameters,omitempty"`
 }
 
+// toolConfig configures how the model uses tools.
+type toolConfig struct {
+	FunctionCallingConfig *functionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+// functionCallingConfig controls function calling.
+type functionCallingConfig struct {
+	Mode                 string   `json:"mode"` // "AUTO", "ANY", or "NONE"
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
 // generateContentResponse represents a Gemini generateContent API response.
 type generateContentResponse struct {
-	Candidates    []candidate    `json:"candidates,omitempty"`
-	UsageMetadata *usageMetadata `json:"usageMetadata,omitempty"`
+	Candidates     []candidate     `json:"candidates,omitempty"`
+	PromptFeedback *promptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *usageMetadata  `json:"usageMetadata,omitempty"`
 }
 
 // candidate represents a response candidate.
 type candidate struct {
-	Content       *content `json:"content,omitempty"`
-	FinishReason  string   `json:"finishReason,omitempty"`
-	Index         int      `json:"index,omitempty"`
-	SafetyRatings []any    `json:"safetyRatings,omitempty"`
+	Content       *content       `json:"content,omitempty"`
+	FinishReason  string         `json:"finishReason,omitempty"`
+	FinishMessage string         `json:"finishMessage,omitempty"`
+	Index         int            `json:"index,omitempty"`
+	SafetyRatings []safetyRating `json:"safetyRatings,omitempty"`
+}
+
+// promptFeedback reports whether the prompt was blocked.
+type promptFeedback struct {
+	BlockReason        string         `json:"blockReason,omitempty"`
+	BlockReasonMessage string         `json:"blockReasonMessage,omitempty"`
+	SafetyRatings      []safetyRating `json:"safetyRatings,omitempty"`
+}
+
+// safetyRating represents the rating of a content for a harm category.
+type safetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability,omitempty"`
+	Blocked     bool   `json:"blocked,omitempty"`
 }
 
 // usageMetadata represents token usage information.
@@ -83,8 +138,9 @@ type usageMetadata struct {
 
 // streamChunk represents a chunk in the streaming response.
 type streamChunk struct {
-	Candidates    []candidate    `json:"candidates,omitempty"`
-	UsageMetadata *usageMetadata `json:"usageMetadata,omitempty"`
+	Candidates     []candidate     `json:"candidates,omitempty"`
+	PromptFeedback *promptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *usageMetadata  `json:"usageMetadata,omitempty"`
 }
 
 // Error types
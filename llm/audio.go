@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// Transcription is the result of Transcribe.
+type Transcription struct {
+	Text  string
+	Usage Usage // Token usage, if the provider reports it
+}
+
+// Speech is the result of Speak.
+type Speech struct {
+	Audio    []byte
+	MIMEType string // e.g. "audio/mpeg" or "audio/wav"
+	Usage    Usage  // Token usage, if the provider reports it
+}
+
+// TranscribeOption configures Transcribe. See WithTranscribeOptions.
+type TranscribeOption func(*provider.TranscriptionRequest)
+
+// TranscribeLanguage sets the ISO-639-1 code of the spoken language, e.g.
+// "en", which improves accuracy and latency (default: detected).
+func TranscribeLanguage(language string) TranscribeOption {
+	return func(r *provider.TranscriptionRequest) {
+		r.Language = language
+	}
+}
+
+// TranscribePrompt sets context for the transcription, such as the topic,
+// names, and vocabulary, or the transcript of the previous segment.
+func TranscribePrompt(prompt string) TranscribeOption {
+	return func(r *provider.TranscriptionRequest) {
+		r.Prompt = prompt
+	}
+}
+
+// TranscribeMIMEType sets the MIME type of the audio, e.g. "audio/mpeg"
+// (default: detected from the content).
+func TranscribeMIMEType(mimeType string) TranscribeOption {
+	return func(r *provider.TranscriptionRequest) {
+		r.MIMEType = mimeType
+	}
+}
+
+// WithTranscribeOptions configures Transcribe.
+//
+// Example:
+//
+//	transcript, err := llm.Transcribe(ctx, audio,
+//	    llm.WithProvider("openai"),
+//	    llm.WithTranscribeOptions(llm.TranscribeLanguage("ja"), llm.TranscribeMIMEType("audio/webm")),
+//	)
+func WithTranscribeOptions(opts ...TranscribeOption) Option {
+	return func(c *callConfig) {
+		c.transcribeOptions = append(c.transcribeOptions, opts...)
+	}
+}
+
+// SpeakOption configures Speak. See WithSpeakOptions.
+type SpeakOption func(*provider.SpeechRequest)
+
+// SpeakVoice sets the provider-specific voice, e.g. "alloy" for OpenAI or
+// "Kore" for Gemini (default: the provider's default voice).
+func SpeakVoice(voice string) SpeakOption {
+	return func(r *provider.SpeechRequest) {
+		r.Voice = voice
+	}
+}
+
+// SpeakFormat sets the audio format, e.g. "mp3" or "wav" (default: "mp3" for
+// OpenAI and "wav" for Gemini).
+func SpeakFormat(format string) SpeakOption {
+	return func(r *provider.SpeechRequest) {
+		r.Format = format
+	}
+}
+
+// SpeakSpeed sets the speaking rate, where 1 is normal.
+func SpeakSpeed(speed float64) SpeakOption {
+	return func(r *provider.SpeechRequest) {
+		r.Speed = speed
+	}
+}
+
+// SpeakInstructions sets how to speak, e.g. "Speak calmly, with a British
+// accent." Not all models support instructions.
+func SpeakInstructions(instructions string) SpeakOption {
+	return func(r *provider.SpeechRequest) {
+		r.Instructions = instructions
+	}
+}
+
+// WithSpeakOptions configures Speak.
+//
+// Example:
+//
+//	speech, err := llm.Speak(ctx, "Your order has shipped.",
+//	    llm.WithProvider("openai"),
+//	    llm.WithSpeakOptions(llm.SpeakVoice("nova"), llm.SpeakFormat("wav")),
+//	)
+func WithSpeakOptions(opts ...SpeakOption) Option {
+	return func(c *callConfig) {
+		c.speakOptions = append(c.speakOptions, opts...)
+	}
+}
+
+// Transcribe converts speech to text with the provider set by WithProvider,
+// which must implement provider.Transcriber (OpenAI and Gemini do). The
+// model set by WithModel is optional; providers have a default
+// transcription model.
+//
+// Example:
+//
+//	audio, err := os.ReadFile("question.mp3")
+//	if err != nil {
+//	    return err
+//	}
+//	transcript, err := llm.Transcribe(ctx, audio, llm.WithProvider("openai"), llm.WithModel("whisper-1"))
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(transcript.Text)
+func Transcribe(ctx context.Context, audio []byte, opts ...Option) (Transcription, error) {
+	cfg := newCallConfig()
+	cfg.apply(opts...)
+	if cfg.providerName == "" {
+		return Transcription{}, ErrProviderRequired
+	}
+	if len(audio) == 0 {
+		return Transcription{}, errors.New("audio is empty")
+	}
+
+	req := &provider.TranscriptionRequest{Model: cfg.model, Audio: audio, Headers: cfg.headers}
+	for _, opt := range cfg.transcribeOptions {
+		opt(req)
+	}
+	if req.MIMEType == "" {
+		req.MIMEType = detectAudioType(audio)
+		if req.MIMEType == "" {
+			return Transcription{}, errors.New("cannot detect the audio type: use TranscribeMIMEType")
+		}
+	}
+
+	p, err := provider.Get(cfg.providerName)
+	if err != nil {
+		return Transcription{}, fmt.Errorf("getting provider: %w", err)
+	}
+	transcriber, ok := p.(provider.Transcriber)
+	if !ok {
+		return Transcription{}, fmt.Errorf("provider %q does not support transcription", cfg.providerName)
+	}
+	resp, err := transcriber.Transcribe(ctx, req)
+	if err != nil {
+		return Transcription{}, fmt.Errorf("transcribing: %w", err)
+	}
+	return Transcription{Text: resp.Text, Usage: Usage(resp.Usage)}, nil
+}
+
+// Speak converts text to speech with the provider set by WithProvider, which
+// must implement provider.SpeechSynthesizer (OpenAI and Gemini do). The
+// model set by WithModel is optional; providers have a default speech model.
+//
+// Example:
+//
+//	speech, err := llm.Speak(ctx, "Welcome back!",
+//	    llm.WithProvider("gemini"),
+//	    llm.WithSpeakOptions(llm.SpeakVoice("Puck"), llm.SpeakInstructions("Say cheerfully")),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	err = os.WriteFile("welcome.wav", speech.Audio, 0o644)
+func Speak(ctx context.Context, text string, opts ...Option) (Speech, error) {
+	cfg := newCallConfig()
+	cfg.apply(opts...)
+	if cfg.providerName == "" {
+		return Speech{}, ErrProviderRequired
+	}
+	if strings.TrimSpace(text) == "" {
+		return Speech{}, errors.New("text is empty")
+	}
+
+	req := &provider.SpeechRequest{Model: cfg.model, Text: text, Headers: cfg.headers}
+	for _, opt := range cfg.speakOptions {
+		opt(req)
+	}
+
+	p, err := provider.Get(cfg.providerName)
+	if err != nil {
+		return Speech{}, fmt.Errorf("getting provider: %w", err)
+	}
+	synthesizer, ok := p.(provider.SpeechSynthesizer)
+	if !ok {
+		return Speech{}, fmt.Errorf("provider %q does not support speech synthesis", cfg.providerName)
+	}
+	resp, err := synthesizer.Speak(ctx, req)
+	if err != nil {
+		return Speech{}, fmt.Errorf("synthesizing speech: %w", err)
+	}
+	return Speech{Audio: resp.Audio, MIMEType: resp.MIMEType, Usage: Usage(resp.Usage)}, nil
+}
+
+// detectAudioType returns the MIME type of audio, or "" if it is not a
+// known audio or video format.
+func detectAudioType(audio []byte) string {
+	switch mimeType := http.DetectContentType(audio); mimeType {
+	case "audio/mpeg", "audio/aiff", "audio/basic", "audio/midi", "video/mp4", "video/webm":
+		return mimeType
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	}
+	switch {
+	case len(audio) >= 4 && string(audio[:4]) == "fLaC":
+		return "audio/flac"
+	case len(audio) >= 2 && audio[0] == 0xFF && audio[1]&0xE0 == 0xE0:
+		return "audio/mpeg" // MPEG frame without an ID3 tag
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// audioProvider records transcription and speech requests.
+type audioProvider struct {
+	contentProvider
+	transcription *provider.TranscriptionRequest
+	speech        *provider.SpeechRequest
+}
+
+func (p *audioProvider) Transcribe(_ context.Context, req *provider.TranscriptionRequest) (*provider.Transcription, error) {
+	p.transcription = req
+	return &provider.Transcription{Text: "hello world", Usage: provider.Usage{TotalTokens: 7}}, nil
+}
+
+func (p *audioProvider) Speak(_ context.Context, req *provider.SpeechRequest) (*provider.Speech, error) {
+	p.speech = req
+	return &provider.Speech{Audio: []byte("RIFF"), MIMEType: "audio/wav"}, nil
+}
+
+func TestTranscribe(t *testing.T) {
+	p := &audioProvider{}
+	provider.RegisterInstance("audio-test", p)
+	t.Cleanup(func() { provider.Reset("audio-test") })
+
+	wav := append([]byte("RIFF\x00\x00\x00\x00WAVEfmt "), make([]byte, 32)...)
+	transcript, err := Transcribe(context.Background(), wav, WithProvider("audio-test"),
+		WithTranscribeOptions(TranscribeLanguage("en"), TranscribePrompt("Greetings.")))
+	require.NoError(t, err)
+	assert.Equal(t, Transcription{Text: "hello world", Usage: Usage{TotalTokens: 7}}, transcript)
+	assert.Equal(t, "audio/wav", p.transcription.MIMEType)
+	assert.Equal(t, "en", p.transcription.Language)
+	assert.Equal(t, "Greetings.", p.transcription.Prompt)
+	assert.Empty(t, p.transcription.Model)
+
+	_, err = Transcribe(context.Background(), []byte("not audio"), WithProvider("audio-test"))
+	assert.ErrorContains(t, err, "TranscribeMIMEType")
+
+	_, err = Transcribe(context.Background(), []byte("not audio"), WithProvider("audio-test"), WithModel("whisper-1"),
+		WithTranscribeOptions(TranscribeMIMEType("audio/webm")))
+	require.NoError(t, err)
+	assert.Equal(t, "audio/webm", p.transcription.MIMEType)
+	assert.Equal(t, "whisper-1", p.transcription.Model)
+}
+
+func TestSpeak(t *testing.T) {
+	p := &audioProvider{}
+	provider.RegisterInstance("audio-test", p)
+	t.Cleanup(func() { provider.Reset("audio-test") })
+
+	speech, err := Speak(context.Background(), "Hello", WithProvider("audio-test"),
+		WithSpeakOptions(SpeakVoice("nova"), SpeakFormat("wav"), SpeakSpeed(1.5), SpeakInstructions("Cheerfully.")))
+	require.NoError(t, err)
+	assert.Equal(t, Speech{Audio: []byte("RIFF"), MIMEType: "audio/wav"}, speech)
+	assert.Equal(t, &provider.SpeechRequest{Text: "Hello", Voice: "nova", Format: "wav", Speed: 1.5, Instructions: "Cheerfully."}, p.speech)
+
+	_, err = Speak(context.Background(), " ", WithProvider("audio-test"))
+	assert.ErrorContains(t, err, "text is empty")
+}
+
+func TestAudio_Unsupported(t *testing.T) {
+	opts := registerContent(t, "")
+	_, err := Speak(context.Background(), "Hello", opts...)
+	assert.ErrorContains(t, err, "does not support speech synthesis")
+	_, err = Transcribe(context.Background(), []byte("fLaC...."), opts...)
+	assert.ErrorContains(t, err, "does not support transcription")
+
+	_, err = Speak(context.Background(), "Hello")
+	assert.ErrorIs(t, err, ErrProviderRequired)
+}
@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrNoJSON is returned when a response contains no valid JSON.
+var ErrNoJSON = errors.New("no JSON found in response")
+
+// CodeBlock is a fenced code block of a Markdown text.
+type CodeBlock struct {
+	Language string // The first word of the info string, e.g. "go"; empty if none
+	Code     string // The content, without the fences and the trailing newline
+}
+
+// ExtractCodeBlocks returns the fenced code blocks (``` or ~~~) of a
+// Markdown text, in order. A block left open at the end of the text, as in
+// a truncated response, extends to the end.
+//
+// Example:
+//
+//	for _, block := range llm.ExtractCodeBlocks(resp.Text()) {
+//	    if block.Language == "go" {
+//	        fmt.Println(block.Code)
+//	    }
+//	}
+func ExtractCodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+	var (
+		open  *CodeBlock
+		fence string
+		code  strings.Builder
+	)
+	for _, line := range strings.Split(text, "\n") {
+		m := fenceLine.FindStringSubmatch(line)
+		if open == nil {
+			if m == nil {
+				continue
+			}
+			info := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), m[1][:1]))
+			if m[1][0] == '`' && strings.Contains(info, "`") {
+				continue // Not a fence: backticks in the info string are inline code
+			}
+			language, _, _ := strings.Cut(info, " ")
+			open, fence = &CodeBlock{Language: language}, m[1]
+			code.Reset()
+			continue
+		}
+		if m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) &&
+			strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), fence[:1])) == "" {
+			open.Code = strings.TrimSuffix(code.String(), "\n")
+			blocks = append(blocks, *open)
+			open = nil
+			continue
+		}
+		code.WriteString(line)
+		code.WriteString("\n")
+	}
+	if open != nil {
+		open.Code = strings.TrimRight(code.String(), "\n")
+		blocks = append(blocks, *open)
+	}
+	return blocks
+}
+
+// ExtractJSON returns the JSON value in text, which models often wrap in a
+// ```json fence or surround with prose. It returns, in order of preference:
+// text itself if it is valid JSON, the first fenced code block that is valid
+// JSON (blocks labeled "json" first), or the first valid JSON object or
+// array embedded in text. It returns ErrNoJSON if there is none.
+//
+// Example:
+//
+//	raw, err := llm.ExtractJSON("Here you go:\n```json\n{\"ok\": true}\n```")
+//	// raw is {"ok": true}
+func ExtractJSON(text string) (json.RawMessage, error) {
+	if trimmed := strings.TrimSpace(text); json.Valid([]byte(trimmed)) && trimmed != "" {
+		return json.RawMessage(trimmed), nil
+	}
+
+	blocks := ExtractCodeBlocks(text)
+	for _, preferJSON := range []bool{true, false} {
+		for _, block := range blocks {
+			if strings.EqualFold(block.Language, "json") != preferJSON {
+				continue
+			}
+			if code := strings.TrimSpace(block.Code); code != "" && json.Valid([]byte(code)) {
+				return json.RawMessage(code), nil
+			}
+		}
+	}
+
+	for start := 0; start < len(text); start++ {
+		if text[start] != '{' && text[start] != '[' {
+			continue
+		}
+		if end := matchingBracket(text, start); end > 0 && json.Valid([]byte(text[start:end])) {
+			return json.RawMessage(text[start:end]), nil
+		}
+	}
+	return nil, ErrNoJSON
+}
+
+// matchingBracket returns the index after the bracket that closes the one at
+// text[start], ignoring brackets in JSON strings, or -1 if it is not closed.
+func matchingBracket(text string, start int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// JSON returns the JSON value of the response text, stripping Markdown
+// fences and surrounding prose (see ExtractJSON). Use it with models or
+// providers without native structured output.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, "List three colors as a JSON array.", opts...)
+//	if err != nil {
+//	    return err
+//	}
+//	raw, err := resp.JSON()
+//	if err != nil {
+//	    return err
+//	}
+//	var colors []string
+//	err = json.Unmarshal(raw, &colors)
+func (r Response[T]) JSON() (json.RawMessage, error) {
+	return ExtractJSON(r.Text())
+}
+
+// CodeBlocks returns the fenced code blocks of the response text (see
+// ExtractCodeBlocks).
+func (r Response[T]) CodeBlocks() []CodeBlock {
+	return ExtractCodeBlocks(r.Text())
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCodeBlocks(t *testing.T) {
+	text := "Intro\n\n```go title=\"main.go\"\nfunc main() {}\n```\n\nSee `x` and ``` inline ``` too.\n\n" +
+		"~~~~\n```\nnested\n```\n~~~~\n\n```python\nprint(1)\n"
+	assert.Equal(t, []CodeBlock{
+		{Language: "go", Code: "func main() {}"},
+		{Code: "```\nnested\n```"},
+		{Language: "python", Code: "print(1)"}, // Unclosed at the end
+	}, ExtractCodeBlocks(text))
+	assert.Empty(t, ExtractCodeBlocks("No code here."))
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"plain", ` {"a": 1} `, `{"a": 1}`},
+		{"fenced", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"prefers json blocks", "```\n[1]\n```\n\n```JSON\n[2]\n```", `[2]`},
+		{"unlabeled block", "Result:\n```\n[1, 2]\n```", `[1, 2]`},
+		{"embedded", `The answer is {"text": "a } in a string", "n": [1]}. Done.`, `{"text": "a } in a string", "n": [1]}`},
+		{"skips invalid candidates", `Use {braces} like this: {"ok": true}`, `{"ok": true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := ExtractJSON(tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(raw))
+		})
+	}
+
+	_, err := ExtractJSON("There is no JSON {here")
+	assert.ErrorIs(t, err, ErrNoJSON)
+	_, err = ExtractJSON("")
+	assert.ErrorIs(t, err, ErrNoJSON)
+}
+
+func TestResponse_JSONAndCodeBlocks(t *testing.T) {
+	resp, err := Call(context.Background(), "Hello",
+		registerContent(t, "Sure!\n```json\n{\"colors\": [\"red\"]}\n```\n")...)
+	require.NoError(t, err)
+
+	raw, err := resp.JSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"colors": ["red"]}`, string(raw))
+	assert.Equal(t, []CodeBlock{{Language: "json", Code: `{"colors": ["red"]}`}}, resp.CodeBlocks())
+}
@@ -3,6 +3,9 @@ package llm
 import (
 	"errors"
 	"fmt"
+
+	"github.com/i2y/bucephalus/provider"
+	"github.com/i2y/bucephalus/schema"
 )
 
 // Common errors.
@@ -23,6 +26,7 @@ type ProviderError struct {
 	StatusCode int
 	Message    string
 	Cause      error
+	Raw        *provider.HTTPResponse // The HTTP response, if any
 }
 
 func (e *ProviderError) Error() string {
@@ -34,15 +38,28 @@ func (e *ProviderError) Error() string {
 		e.Provider, e.StatusCode, e.Message)
 }
 
+// HTTPStatus returns the HTTP status code of the error.
+func (e *ProviderError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// RawResponse returns the HTTP response of the error.
+func (e *ProviderError) RawResponse() *provider.HTTPResponse {
+	return e.Raw
+}
+
 func (e *ProviderError) Unwrap() error {
 	return e.Cause
 }
 
 // ParseError represents a failure to parse the LLM response.
+// Responses that parse but do not match the schema of the target type have
+// the mismatches listed in Violations.
 type ParseError struct {
-	Content string
-	Target  string
-	Cause   error
+	Content    string
+	Target     string
+	Cause      error
+	Violations []schema.Violation
 }
 
 func (e *ParseError) Error() string {
@@ -53,10 +70,21 @@ func (e *ParseError) Unwrap() error {
 	return e.Cause
 }
 
-// ToolError represents an error during tool execution.
+// ToolError represents an error during tool execution. Tools return it to
+// tell the model what went wrong and how to recover; see ToolErrorResult.
 type ToolError struct {
 	ToolName string
 	Cause    error
+
+	// Type is the type of the error, e.g. ToolErrorNotFound. If empty, it
+	// is derived from Cause.
+	Type string
+
+	// Retryable reports whether the same call may succeed if retried.
+	Retryable bool
+
+	// Suggestion tells the model how to recover, if set.
+	Suggestion string
 }
 
 func (e *ToolError) Error() string {
@@ -67,6 +95,23 @@ func (e *ToolError) Unwrap() error {
 	return e.Cause
 }
 
+// ToolCallsCanceledError is returned by ExecuteToolCalls when its context is
+// done before all tool calls have run.
+type ToolCallsCanceledError struct {
+	Completed int        // The number of tool calls that ran
+	Pending   []ToolCall // The tool calls that were not started
+	Cause     error      // The context's error
+}
+
+func (e *ToolCallsCanceledError) Error() string {
+	return fmt.Sprintf("tool calls canceled after %d of %d: %v",
+		e.Completed, e.Completed+len(e.Pending), e.Cause)
+}
+
+func (e *ToolCallsCanceledError) Unwrap() error {
+	return e.Cause
+}
+
 // ToolNotFoundError is returned when a tool is not found.
 type ToolNotFoundError struct {
 	Name string
@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// Variant is an arm of an Experiment: a share of the calls and the options
+// they are made with.
+type Variant struct {
+	// Name identifies the variant in responses, outcomes, and stats.
+	Name string
+
+	// Weight is the relative share of calls routed to the variant. Variants
+	// with a zero weight get none.
+	Weight float64
+
+	// Options are applied after the options of the call, e.g. WithModel,
+	// WithSystemMessage, or WithTemperature.
+	Options []Option
+}
+
+// ExperimentOutcome is the outcome of a call of an experiment, or a metric
+// recorded with Experiment.Record.
+type ExperimentOutcome struct {
+	Experiment string
+	Variant    string
+	User       string // The user set by WithUser, if any
+
+	// Metric and Value are set for outcomes recorded with Record; Metric is
+	// empty for calls.
+	Metric string
+	Value  float64
+
+	// Duration, Usage, and Err are set for calls.
+	Duration time.Duration
+	Usage    Usage
+	Err      error
+}
+
+// VariantStats are the aggregated outcomes of a variant.
+type VariantStats struct {
+	Calls    int
+	Errors   int
+	Usage    Usage
+	Duration time.Duration            // Total duration of the calls
+	Metrics  map[string]MetricSummary // By metric name, from Record
+}
+
+// MeanDuration returns the mean duration of the calls of the variant.
+func (s VariantStats) MeanDuration() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Duration / time.Duration(s.Calls)
+}
+
+// MetricSummary aggregates the values of a metric recorded with
+// Experiment.Record.
+type MetricSummary struct {
+	Count int
+	Sum   float64
+}
+
+// Mean returns the mean of the recorded values.
+func (m MetricSummary) Mean() float64 {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.Sum / float64(m.Count)
+}
+
+// ExperimentOption configures an Experiment.
+type ExperimentOption func(*Experiment)
+
+// ExperimentOnOutcome calls fn with the outcome of every call of the
+// experiment and every metric recorded with Record, e.g. to export them to
+// an analytics system. fn is called synchronously and must be safe for
+// concurrent use.
+func ExperimentOnOutcome(fn func(ExperimentOutcome)) ExperimentOption {
+	return func(e *Experiment) {
+		e.onOutcome = fn
+	}
+}
+
+// Experiment routes calls to weighted variants of prompts and models, tags
+// their responses with the variant (Response.Variant), and aggregates the
+// outcomes of each variant. Calls with a user (WithUser) are assigned a
+// variant by a hash of the user ID, so that a user always gets the same
+// variant; other calls are assigned at random.
+//
+// Example:
+//
+//	exp, err := llm.NewExperiment("support-prompt", []llm.Variant{
+//	    {Name: "control", Weight: 90},
+//	    {Name: "concise", Weight: 10, Options: []llm.Option{
+//	        llm.WithSystemMessage("You are a support agent. Answer in two sentences."),
+//	    }},
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	resp, err := llm.Call(ctx, question, append(opts, llm.WithUser(userID), llm.WithExperiment(exp))...)
+//	...
+//	exp.Record(resp.Variant(), "thumbs_up", 1) // When the user rates the answer
+type Experiment struct {
+	name      string
+	variants  []Variant
+	total     float64
+	onOutcome func(ExperimentOutcome)
+
+	mu    sync.Mutex
+	stats map[string]*VariantStats
+}
+
+// NewExperiment creates an experiment with variants, which must have unique
+// non-empty names, non-negative weights, and a positive total weight.
+func NewExperiment(name string, variants []Variant, opts ...ExperimentOption) (*Experiment, error) {
+	e := &Experiment{name: name, variants: variants, stats: make(map[string]*VariantStats)}
+	for _, v := range variants {
+		if v.Name == "" {
+			return nil, fmt.Errorf("experiment %q: variant without a name", name)
+		}
+		if v.Weight < 0 {
+			return nil, fmt.Errorf("experiment %q: variant %q has a negative weight", name, v.Name)
+		}
+		if _, ok := e.stats[v.Name]; ok {
+			return nil, fmt.Errorf("experiment %q: duplicate variant %q", name, v.Name)
+		}
+		e.stats[v.Name] = &VariantStats{}
+		e.total += v.Weight
+	}
+	if e.total <= 0 {
+		return nil, fmt.Errorf("experiment %q: no variant has a positive weight", name)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Name returns the name of the experiment.
+func (e *Experiment) Name() string {
+	return e.name
+}
+
+// Choose returns the variant for key: the same variant for the same key,
+// or a random one if key is empty. Calls with WithExperiment choose with
+// the user ID as the key.
+func (e *Experiment) Choose(key string) Variant {
+	var x float64
+	if key == "" {
+		x = rand.Float64()
+	} else {
+		sum := sha256.Sum256([]byte(e.name + "\x00" + key))
+		x = float64(binary.BigEndian.Uint64(sum[:])>>11) / (1 << 53)
+	}
+	x *= e.total
+	for _, v := range e.variants {
+		if x < v.Weight {
+			return v
+		}
+		x -= v.Weight
+	}
+	// Rounding: the last variant with a positive weight
+	for i := len(e.variants) - 1; ; i-- {
+		if e.variants[i].Weight > 0 {
+			return e.variants[i]
+		}
+	}
+}
+
+// Record records a metric of the outcome of a variant, such as a user
+// rating or a conversion, for its stats and the ExperimentOnOutcome
+// callback.
+func (e *Experiment) Record(variant, metric string, value float64) {
+	e.record(ExperimentOutcome{Experiment: e.name, Variant: variant, Metric: metric, Value: value})
+}
+
+// Stats returns the aggregated outcomes of each variant, by name.
+func (e *Experiment) Stats() map[string]VariantStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats := make(map[string]VariantStats, len(e.stats))
+	for name, s := range e.stats {
+		stats[name] = VariantStats{
+			Calls:    s.Calls,
+			Errors:   s.Errors,
+			Usage:    s.Usage,
+			Duration: s.Duration,
+			Metrics:  maps.Clone(s.Metrics),
+		}
+	}
+	return stats
+}
+
+// record aggregates an outcome and passes it to the callback.
+func (e *Experiment) record(o ExperimentOutcome) {
+	e.mu.Lock()
+	s, ok := e.stats[o.Variant]
+	if ok {
+		if o.Metric != "" {
+			if s.Metrics == nil {
+				s.Metrics = make(map[string]MetricSummary)
+			}
+			m := s.Metrics[o.Metric]
+			m.Count++
+			m.Sum += o.Value
+			s.Metrics[o.Metric] = m
+		} else {
+			s.Calls++
+			if o.Err != nil {
+				s.Errors++
+			}
+			s.Usage = addUsage(s.Usage, o.Usage)
+			s.Duration += o.Duration
+		}
+	}
+	e.mu.Unlock()
+
+	if ok && e.onOutcome != nil {
+		e.onOutcome(o)
+	}
+}
+
+// WithExperiment routes the call to a variant of e, whose options are
+// applied after the call's options. The response reports the variant with
+// Response.Variant, and the outcome of the call is recorded in e.
+func WithExperiment(e *Experiment) Option {
+	return func(c *callConfig) {
+		c.experiment = e
+	}
+}
+
+// applyExperiment chooses the variant of the call's experiment, if any and
+// not already chosen, and applies its options.
+func (c *callConfig) applyExperiment() {
+	if c.experiment == nil || c.variant != "" {
+		return
+	}
+	v := c.experiment.Choose(c.user)
+	c.variant = v.Name
+	for _, opt := range v.Options {
+		opt(c)
+	}
+}
+
+// recordExperiment records the outcome of a provider call in the call's
+// experiment, if any.
+func (c *callConfig) recordExperiment(resp *provider.Response, err error, duration time.Duration) {
+	if c.experiment == nil {
+		return
+	}
+	c.experiment.record(ExperimentOutcome{
+		Experiment: c.experiment.name,
+		Variant:    c.variant,
+		User:       c.user,
+		Duration:   duration,
+		Usage:      responseUsage(resp),
+		Err:        err,
+	})
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+func TestNewExperiment_Validates(t *testing.T) {
+	_, err := NewExperiment("e", []Variant{{Name: "a", Weight: 1}, {Name: "a", Weight: 1}})
+	assert.ErrorContains(t, err, `duplicate variant "a"`)
+	_, err = NewExperiment("e", []Variant{{Name: "a", Weight: -1}, {Name: "b", Weight: 2}})
+	assert.ErrorContains(t, err, "negative weight")
+	_, err = NewExperiment("e", []Variant{{Name: "a"}})
+	assert.ErrorContains(t, err, "no variant has a positive weight")
+	_, err = NewExperiment("e", []Variant{{Weight: 1}})
+	assert.ErrorContains(t, err, "variant without a name")
+}
+
+func TestExperiment_Choose(t *testing.T) {
+	exp, err := NewExperiment("e", []Variant{{Name: "off", Weight: 0}, {Name: "a", Weight: 3}, {Name: "b", Weight: 1}})
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := range 4000 {
+		v := exp.Choose(fmt.Sprintf("user-%d", i))
+		counts[v.Name]++
+		assert.Equal(t, v.Name, exp.Choose(fmt.Sprintf("user-%d", i)).Name) // Sticky
+	}
+	assert.Zero(t, counts["off"])
+	assert.InDelta(t, 3000, counts["a"], 150)
+	assert.InDelta(t, 1000, counts["b"], 150)
+}
+
+func TestWithExperiment(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		outcomes []ExperimentOutcome
+	)
+	exp, err := NewExperiment("prompt", []Variant{
+		{Name: "control", Weight: 1},
+		{Name: "upper", Weight: 1, Options: []Option{WithSystemMessage("Shout.")}},
+	}, ExperimentOnOutcome(func(o ExperimentOutcome) {
+		mu.Lock()
+		defer mu.Unlock()
+		outcomes = append(outcomes, o)
+	}))
+	require.NoError(t, err)
+
+	mock, opts := registerPrompt(t, func(prompt string) string { return prompt })
+	var user string
+	for i := 0; user == "" || exp.Choose(user).Name != "upper"; i++ {
+		user = fmt.Sprintf("user-%d", i)
+	}
+	resp, err := Call(context.Background(), "hi", append(opts, WithUser(user), WithExperiment(exp))...)
+	require.NoError(t, err)
+	assert.Equal(t, "upper", resp.Variant())
+	assert.Equal(t, SystemMessage("Shout."), resp.Messages()[0])
+	assert.EqualValues(t, 1, mock.calls.Load())
+
+	exp.Record(resp.Variant(), "thumbs_up", 1)
+	exp.Record(resp.Variant(), "thumbs_up", 0)
+	exp.Record("unknown", "thumbs_up", 1) // Ignored
+
+	stats := exp.Stats()
+	upper := stats["upper"]
+	assert.Equal(t, 1, upper.Calls)
+	assert.Equal(t, 15, upper.Usage.TotalTokens)
+	assert.Equal(t, MetricSummary{Count: 2, Sum: 1}, upper.Metrics["thumbs_up"])
+	assert.InDelta(t, 0.5, upper.Metrics["thumbs_up"].Mean(), 1e-9)
+	assert.Zero(t, stats["control"].Calls)
+
+	require.Len(t, outcomes, 3)
+	assert.Equal(t, "prompt", outcomes[0].Experiment)
+	assert.Equal(t, user, outcomes[0].User)
+	assert.Equal(t, "thumbs_up", outcomes[2].Metric)
+
+	// Failed calls are counted as errors
+	provider.Register("error", func() (provider.Provider, error) { return errorProvider{}, nil })
+	_, err = Call(context.Background(), "hi", WithProvider("error"), WithModel("m"), WithUser(user), WithExperiment(exp))
+	require.Error(t, err)
+	assert.Equal(t, 1, exp.Stats()["upper"].Errors)
+	assert.Equal(t, 2, exp.Stats()["upper"].Calls)
+}
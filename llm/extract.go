@@ -0,0 +1,452 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/i2y/bucephalus/schema"
+)
+
+// FieldConfidence is a model's confidence in an extracted field.
+type FieldConfidence struct {
+	Field         string  `json:"field" jsonschema:"required,description=Path of the field with dots between names such as total or customer.name"`
+	Confidence    float64 `json:"confidence" jsonschema:"required,minimum=0,maximum=1,description=Confidence that the value is correct from 0 (a guess) to 1 (stated verbatim)"`
+	Justification string  `json:"justification" jsonschema:"required,description=Quote from the document or reasoning supporting the value"`
+}
+
+// Extraction is the result of Extract.
+type Extraction[T any] struct {
+	Value T // Merged value extracted from all chunks
+
+	// Confidence maps field paths (e.g. "customer.name") to the model's
+	// confidence in them. It is only set with ExtractConfidence.
+	Confidence map[string]FieldConfidence
+
+	Chunks []T   // Values extracted from each chunk, in document order
+	Usage  Usage // Total usage of all calls
+}
+
+// ExtractOption configures Extract. See WithExtractOptions.
+type ExtractOption func(*extractConfig)
+
+// extractConfig holds the configuration of Extract.
+type extractConfig struct {
+	chunkTokens int
+	overlap     int
+	confidence  bool
+	concurrency int
+}
+
+// Defaults of Extract.
+const (
+	defaultExtractChunkTokens = 4000
+	defaultExtractConcurrency = 4
+)
+
+// ExtractChunkTokens sets the maximum estimated tokens of the document
+// chunks extracted from separately (default: 4000).
+func ExtractChunkTokens(n int) ExtractOption {
+	return func(c *extractConfig) {
+		c.chunkTokens = n
+	}
+}
+
+// ExtractChunkOverlap sets the estimated tokens repeated from the end of a
+// chunk at the start of the next one, so that values split across chunks
+// are seen whole (default: none).
+func ExtractChunkOverlap(n int) ExtractOption {
+	return func(c *extractConfig) {
+		c.overlap = n
+	}
+}
+
+// ExtractConfidence makes the model report its confidence in each field it
+// extracts, with a justification, in Extraction.Confidence. When chunks
+// disagree on a value, the most confident one is kept.
+func ExtractConfidence() ExtractOption {
+	return func(c *extractConfig) {
+		c.confidence = true
+	}
+}
+
+// ExtractConcurrency sets the number of chunks extracted from at the same
+// time (default: 4).
+func ExtractConcurrency(n int) ExtractOption {
+	return func(c *extractConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithExtractOptions configures Extract.
+//
+// Example:
+//
+//	invoice, err := llm.Extract[Invoice](ctx, text,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithExtractOptions(llm.ExtractChunkTokens(2000), llm.ExtractConfidence()),
+//	)
+func WithExtractOptions(opts ...ExtractOption) Option {
+	return func(c *callConfig) {
+		c.extractOptions = append(c.extractOptions, opts...)
+	}
+}
+
+// extractionWithConfidence is the structured output of a chunk with ExtractConfidence.
+type extractionWithConfidence[T any] struct {
+	Data   T                 `json:"data" jsonschema:"required"`
+	Fields []FieldConfidence `json:"fields" jsonschema:"required,description=Confidence in each extracted field"`
+}
+
+// Extract extracts a T from document with structured output. Documents
+// longer than the chunk size (see ExtractChunkTokens) are split at
+// paragraphs, T is extracted from each chunk, and the results are merged in
+// document order: objects are merged field by field, arrays are
+// concatenated without duplicates, and the first non-zero value of other
+// fields is kept (or the most confident one, with ExtractConfidence). Zero
+// values are treated as missing, so fields absent from a chunk do not
+// overwrite those found in others.
+//
+// Example:
+//
+//	type Invoice struct {
+//	    Number string     `json:"number" jsonschema:"required"`
+//	    Total  float64    `json:"total" jsonschema:"required"`
+//	    Items  []LineItem `json:"items" jsonschema:"required"`
+//	}
+//
+//	result, err := llm.Extract[Invoice](ctx, pdfText,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithExtractOptions(llm.ExtractConfidence()),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(result.Value.Total, result.Confidence["total"].Confidence)
+func Extract[T any](ctx context.Context, document string, opts ...Option) (Extraction[T], error) {
+	cfg := newCallConfig()
+	cfg.apply(opts...)
+	ec := &extractConfig{chunkTokens: defaultExtractChunkTokens, concurrency: defaultExtractConcurrency}
+	for _, opt := range cfg.extractOptions {
+		opt(ec)
+	}
+	if ec.chunkTokens <= 0 {
+		ec.chunkTokens = defaultExtractChunkTokens
+	}
+	if ec.concurrency <= 0 {
+		ec.concurrency = 1
+	}
+
+	var confidenceSchema json.RawMessage
+	if ec.confidence {
+		s, err := schema.Generate[extractionWithConfidence[T]]()
+		if err != nil {
+			return Extraction[T]{}, fmt.Errorf("generating schema: %w", err)
+		}
+		if confidenceSchema, err = withTitle(s, "extraction"); err != nil {
+			return Extraction[T]{}, err
+		}
+	}
+
+	chunks := splitDocument(document, ec.chunkTokens, ec.overlap)
+	results := make([]extractionWithConfidence[T], len(chunks))
+	usages := make([]Usage, len(chunks))
+
+	errs := forEachChunk(ctx, len(chunks), ec.concurrency, func(i int) error {
+		prompt := extractPrompt(chunks[i], i, len(chunks), ec.confidence)
+		if ec.confidence {
+			var err error
+			results[i], usages[i], err = extractWithConfidence[T](ctx, prompt, confidenceSchema, opts)
+			return err
+		}
+		resp, err := CallParse[T](ctx, prompt, opts...)
+		if err != nil {
+			return err
+		}
+		usages[i] = resp.Usage()
+		results[i].Data, err = resp.Parsed()
+		return err
+	})
+
+	var out Extraction[T]
+	for i, err := range errs {
+		if err != nil {
+			return Extraction[T]{}, fmt.Errorf("extracting chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		out.Usage = addUsage(out.Usage, usages[i])
+		out.Chunks = append(out.Chunks, results[i].Data)
+	}
+
+	value, confidence, err := mergeExtractions(results, ec.confidence)
+	if err != nil {
+		return Extraction[T]{}, err
+	}
+	out.Value, out.Confidence = value, confidence
+	return out, nil
+}
+
+// extractWithConfidence extracts a T and field confidences from prompt.
+func extractWithConfidence[T any](ctx context.Context, prompt string, jsonSchema json.RawMessage, opts []Option) (extractionWithConfidence[T], Usage, error) {
+	var result extractionWithConfidence[T]
+	resp, err := CallParseDynamic(ctx, prompt, jsonSchema, opts...)
+	if err != nil {
+		return result, Usage{}, err
+	}
+	parsed, err := resp.Parsed()
+	if err != nil {
+		return result, resp.Usage(), err
+	}
+	data, err := json.Marshal(parsed)
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err != nil {
+		return result, resp.Usage(), fmt.Errorf("decoding extraction: %w", err)
+	}
+	return result, resp.Usage(), nil
+}
+
+// extractPrompt returns the prompt to extract from chunk i of n.
+func extractPrompt(chunk string, i, n int, confidence bool) string {
+	var sb strings.Builder
+	sb.WriteString("Extract the information described by the response schema from the document below. ")
+	sb.WriteString("Use only what the document states; leave fields empty when the document does not mention them.")
+	if n > 1 {
+		fmt.Fprintf(&sb, " The document is part %d of %d of a longer document; other parts are processed separately.", i+1, n)
+	}
+	if confidence {
+		sb.WriteString(" Put the extracted values in data, and list every field you filled in fields with your confidence and a justification.")
+	}
+	sb.WriteString("\n\n<document>\n")
+	sb.WriteString(chunk)
+	sb.WriteString("\n</document>")
+	return sb.String()
+}
+
+// withTitle returns jsonSchema with its title set to title.
+func withTitle(jsonSchema json.RawMessage, title string) (json.RawMessage, error) {
+	var s map[string]any
+	if err := json.Unmarshal(jsonSchema, &s); err != nil {
+		return nil, fmt.Errorf("generating schema: %w", err)
+	}
+	s["title"] = title
+	return json.Marshal(s)
+}
+
+// mergeExtractions merges the values extracted from chunks in order and
+// returns the merged value and the highest confidence of each field.
+func mergeExtractions[T any](results []extractionWithConfidence[T], confidence bool) (T, map[string]FieldConfidence, error) {
+	var zero T
+	var merged any
+	var best map[string]FieldConfidence
+	if confidence {
+		best = make(map[string]FieldConfidence)
+	}
+
+	for _, result := range results {
+		data, err := json.Marshal(result.Data)
+		if err != nil {
+			return zero, nil, fmt.Errorf("merging extractions: %w", err)
+		}
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			return zero, nil, fmt.Errorf("merging extractions: %w", err)
+		}
+
+		chunkConfidence := make(map[string]FieldConfidence, len(result.Fields))
+		for _, field := range result.Fields {
+			chunkConfidence[field.Field] = field
+		}
+		preferNew := func(path string) bool {
+			field, ok := chunkConfidence[path]
+			return ok && field.Confidence > best[path].Confidence
+		}
+		merged = mergeJSON(merged, value, "", preferNew)
+
+		for path, field := range chunkConfidence {
+			if current, ok := best[path]; confidence && (!ok || field.Confidence > current.Confidence) {
+				best[path] = field
+			}
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return zero, nil, fmt.Errorf("merging extractions: %w", err)
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return zero, nil, fmt.Errorf("merging extractions: %w", err)
+	}
+	return value, best, nil
+}
+
+// mergeJSON merges the decoded JSON value b into a. Objects are merged by
+// key and arrays concatenated without duplicates; for other values, b
+// replaces a if a is empty or preferB reports that b's value at path is
+// more confident.
+func mergeJSON(a, b any, path string, preferB func(path string) bool) any {
+	if isEmptyJSON(b) {
+		return a
+	}
+	if isEmptyJSON(a) {
+		return b
+	}
+
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			for key, value := range b {
+				a[key] = mergeJSON(a[key], value, joinPath(path, key), preferB)
+			}
+			return a
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			for _, item := range b {
+				if !containsJSON(a, item) {
+					a = append(a, item)
+				}
+			}
+			return a
+		}
+	}
+	if preferB(path) {
+		return b
+	}
+	return a
+}
+
+// isEmptyJSON reports whether v is a zero decoded JSON value.
+func isEmptyJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		for _, value := range v {
+			if !isEmptyJSON(value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// containsJSON reports whether items contains v.
+func containsJSON(items []any, v any) bool {
+	for _, item := range items {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath appends key to a dotted field path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// splitDocument splits text into chunks of at most maxTokens estimated
+// tokens at paragraph breaks, repeating about overlap tokens of the previous
+// chunk. Paragraphs longer than maxTokens are split at whitespace.
+func splitDocument(text string, maxTokens, overlap int) []string {
+	if EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	var parts []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		if EstimateTokens(paragraph) <= maxTokens {
+			parts = append(parts, paragraph)
+			continue
+		}
+		var sb strings.Builder
+		for _, word := range strings.Fields(paragraph) {
+			if sb.Len() > 0 && EstimateTokens(sb.String()+" "+word) > maxTokens {
+				parts = append(parts, sb.String())
+				sb.Reset()
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(word)
+		}
+		if sb.Len() > 0 {
+			parts = append(parts, sb.String())
+		}
+	}
+
+	var chunks []string
+	for start := 0; start < len(parts); {
+		end := start + 1
+		for end < len(parts) && EstimateTokens(strings.Join(parts[start:end+1], "\n\n")) <= maxTokens {
+			end++
+		}
+		chunks = append(chunks, strings.Join(parts[start:end], "\n\n"))
+		if end == len(parts) {
+			break
+		}
+		next := end
+		for next > start+1 && EstimateTokens(strings.Join(parts[next-1:end], "\n\n")) <= overlap &&
+			EstimateTokens(strings.Join(parts[next-1:end+1], "\n\n")) <= maxTokens {
+			next--
+		}
+		start = next
+	}
+	return chunks
+}
+
+// forEachChunk calls fn for 0 to n-1 with at most concurrency calls at the
+// same time and returns the error of each call. Calls not started before ctx
+// is done return ctx.Err().
+func forEachChunk(ctx context.Context, n, concurrency int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// addUsage returns the sum of a and b.
+func addUsage(a, b Usage) Usage {
+	return Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+type lineItem struct {
+	Name string `json:"name" jsonschema:"required"`
+}
+
+type invoice struct {
+	Number string     `json:"number" jsonschema:"required"`
+	Total  float64    `json:"total" jsonschema:"required"`
+	Items  []lineItem `json:"items" jsonschema:"required"`
+}
+
+// promptProvider answers each prompt with a function of it.
+type promptProvider struct {
+	fn    func(prompt string) string
+	calls atomic.Int64
+}
+
+func (p *promptProvider) Name() string { return "prompt" }
+
+func (p *promptProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	p.calls.Add(1)
+	return &provider.Response{
+		Content:      p.fn(req.Messages[len(req.Messages)-1].Content),
+		FinishReason: provider.FinishReasonStop,
+		Usage:        provider.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+// registerPrompt registers a promptProvider and returns it with options to use it.
+func registerPrompt(t *testing.T, fn func(prompt string) string) (*promptProvider, []Option) {
+	t.Helper()
+	p := &promptProvider{fn: fn}
+	name := "prompt-" + t.Name()
+	provider.RegisterInstance(name, p)
+	t.Cleanup(func() { provider.Reset(name) })
+	return p, []Option{WithProvider(name), WithModel("m")}
+}
+
+const invoiceDocument = "Invoice INV-1 issued to ACME.\n\nItems: one pen.\n\nOne pen and one ink. Total: 12.50."
+
+func TestExtract_MergesChunks(t *testing.T) {
+	p, opts := registerPrompt(t, func(prompt string) string {
+		switch {
+		case strings.Contains(prompt, "INV-1"):
+			return `{"number": "INV-1", "total": 0, "items": []}`
+		case strings.Contains(prompt, "ink"):
+			return `{"number": "", "total": 12.5, "items": [{"name": "pen"}, {"name": "ink"}]}`
+		default:
+			return `{"number": "", "total": 0, "items": [{"name": "pen"}]}`
+		}
+	})
+
+	result, err := Extract[invoice](context.Background(), invoiceDocument,
+		append(opts, WithExtractOptions(ExtractChunkTokens(10)))...)
+	require.NoError(t, err)
+	assert.Equal(t, invoice{Number: "INV-1", Total: 12.5, Items: []lineItem{{"pen"}, {"ink"}}}, result.Value)
+	assert.Len(t, result.Chunks, 3)
+	assert.Equal(t, int64(3), p.calls.Load())
+	assert.Equal(t, 45, result.Usage.TotalTokens)
+	assert.Nil(t, result.Confidence)
+
+	// Short documents are extracted with a single call
+	result, err = Extract[invoice](context.Background(), invoiceDocument, opts...)
+	require.NoError(t, err)
+	assert.Len(t, result.Chunks, 1)
+	assert.Equal(t, "INV-1", result.Value.Number)
+}
+
+func TestExtract_Confidence(t *testing.T) {
+	_, opts := registerPrompt(t, func(prompt string) string {
+		if strings.Contains(prompt, "INV-1") {
+			return `{"data": {"number": "INV-1", "total": 10, "items": []},
+				"fields": [{"field": "number", "confidence": 0.9, "justification": "stated"},
+				           {"field": "total", "confidence": 0.3, "justification": "guessed"}]}`
+		}
+		return `{"data": {"number": "", "total": 12.5, "items": []},
+			"fields": [{"field": "total", "confidence": 0.8, "justification": "Total: 12.50"}]}`
+	})
+
+	result, err := Extract[invoice](context.Background(), invoiceDocument,
+		append(opts, WithExtractOptions(ExtractChunkTokens(10), ExtractConfidence(), ExtractConcurrency(1)))...)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-1", result.Value.Number)
+	assert.Equal(t, 12.5, result.Value.Total) // The more confident value wins
+	assert.Equal(t, 0.9, result.Confidence["number"].Confidence)
+	assert.Equal(t, FieldConfidence{Field: "total", Confidence: 0.8, Justification: "Total: 12.50"}, result.Confidence["total"])
+}
+
+func TestExtract_ChunkError(t *testing.T) {
+	_, opts := registerPrompt(t, func(prompt string) string {
+		if strings.Contains(prompt, "ink") {
+			return `not json`
+		}
+		return `{"number": "INV-1", "total": 0, "items": []}`
+	})
+	_, err := Extract[invoice](context.Background(), invoiceDocument,
+		append(opts, WithExtractOptions(ExtractChunkTokens(10)))...)
+	assert.ErrorContains(t, err, "extracting chunk 3 of 3")
+}
+
+func TestSplitDocument(t *testing.T) {
+	text := "aaaa aaaa\n\nbbbb bbbb\n\ncccc cccc dddd dddd eeee eeee"
+	assert.Equal(t, []string{text}, splitDocument(text, 100, 0))
+	assert.Equal(t, []string{
+		"aaaa aaaa\n\nbbbb bbbb",
+		"cccc cccc dddd dddd eeee",
+		"eeee",
+	}, splitDocument(text, 6, 0))
+	assert.Equal(t, []string{
+		"aaaa aaaa\n\nbbbb bbbb",
+		"bbbb bbbb\n\ncccc cccc", // Overlaps by a paragraph
+		"dddd dddd eeee eeee",    // Too long to overlap
+	}, splitDocument("aaaa aaaa\n\nbbbb bbbb\n\ncccc cccc\n\ndddd dddd eeee eeee", 6, 3))
+}
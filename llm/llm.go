@@ -4,8 +4,11 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/i2y/bucephalus/provider"
 	"github.com/i2y/bucephalus/schema"
@@ -41,18 +44,14 @@ func Call(ctx context.Context, prompt string, opts ...Option) (Response[string],
 
 	req := cfg.buildRequest(prompt)
 
-	resp, err := p.Call(ctx, req)
+	resp, err := cfg.call(ctx, p, req)
 	if err != nil {
 		return Response[string]{}, fmt.Errorf("calling provider: %w", err)
 	}
 
 	// Build message history for Resume support
 	messages := buildMessagesFromRequest(req, resp)
-	config := &responseConfig{
-		providerName: cfg.providerName,
-		model:        cfg.model,
-		tools:        cfg.tools,
-	}
+	config := cfg.responseConfig()
 
 	return newResponseWithHistory(resp, resp.Content, nil, messages, config), nil
 }
@@ -100,11 +99,7 @@ func CallParse[T any](ctx context.Context, prompt string, opts ...Option) (Respo
 		typeName = "response"
 	}
 
-	cfg.jsonSchema = &provider.JSONSchema{
-		Name:   typeName,
-		Strict: true,
-		Schema: jsonSchema,
-	}
+	cfg.setJSONSchema(typeName, jsonSchema)
 
 	p, err := provider.Get(cfg.providerName)
 	if err != nil {
@@ -113,30 +108,96 @@ func CallParse[T any](ctx context.Context, prompt string, opts ...Option) (Respo
 
 	req := cfg.buildRequest(prompt)
 
-	resp, err := p.Call(ctx, req)
+	resp, err := cfg.call(ctx, p, req)
 	if err != nil {
-		return Response[T]{}, fmt.Errorf("calling provider: %w", err)
+		return Response[T]{}, fmt.Errorf("calling provider: %w", explainSchemaError(err, jsonSchema))
 	}
 
-	// Parse the response into T
+	// Parse the response into T and validate it against the schema
 	var parsed T
-	parseErr := json.Unmarshal([]byte(resp.Content), &parsed)
-	if parseErr != nil {
-		parseErr = &ParseError{
-			Content: resp.Content,
-			Target:  typeName,
-			Cause:   parseErr,
-		}
-	}
+	parseErr := parseResponse(resp.Content, &parsed, jsonSchema, typeName)
 
 	// Build message history for Resume support
 	messages := buildMessagesFromRequest(req, resp)
-	config := &responseConfig{
-		providerName: cfg.providerName,
-		model:        cfg.model,
-		tools:        cfg.tools,
+	config := cfg.responseConfig()
+
+	return newResponseWithHistory(resp, parsed, parseErr, messages, config), nil
+}
+
+// CallParseDynamic makes an LLM call with structured output described by a
+// JSON schema known only at runtime, such as an extraction template defined
+// by users, and parses the response into a map. The schema must describe an
+// object; its title, if any, names it for the provider. Responses are
+// validated against the schema like those of CallParse.
+//
+// Example:
+//
+//	invoiceSchema := json.RawMessage(`{
+//	    "type": "object",
+//	    "properties": {
+//	        "number": {"type": "string"},
+//	        "total": {"type": "number"}
+//	    },
+//	    "required": ["number", "total"]
+//	}`)
+//
+//	resp, err := llm.CallParseDynamic(ctx, "Extract the invoice: "+text, invoiceSchema,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("o4-mini"),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	fields := resp.MustParse()
+//	fmt.Println(fields["number"], fields["total"])
+func CallParseDynamic(ctx context.Context, prompt string, jsonSchema json.RawMessage, opts ...Option) (Response[map[string]any], error) {
+	cfg := newCallConfig()
+	cfg.apply(opts...)
+
+	if cfg.providerName == "" {
+		return Response[map[string]any]{}, ErrProviderRequired
+	}
+	if cfg.model == "" {
+		return Response[map[string]any]{}, ErrModelRequired
+	}
+
+	var header struct {
+		Type  any    `json:"type"`
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal(jsonSchema, &header); err != nil {
+		return Response[map[string]any]{}, fmt.Errorf("parsing schema: %w", err)
+	}
+	if header.Type != "object" {
+		return Response[map[string]any]{}, fmt.Errorf("schema must describe an object, got type %v", header.Type)
+	}
+	typeName := header.Title
+	if typeName == "" {
+		typeName = "response"
+	}
+
+	cfg.setJSONSchema(typeName, jsonSchema)
+
+	p, err := provider.Get(cfg.providerName)
+	if err != nil {
+		return Response[map[string]any]{}, fmt.Errorf("getting provider: %w", err)
+	}
+
+	req := cfg.buildRequest(prompt)
+
+	resp, err := cfg.call(ctx, p, req)
+	if err != nil {
+		return Response[map[string]any]{}, fmt.Errorf("calling provider: %w", explainSchemaError(err, jsonSchema))
 	}
 
+	// Parse the response into a map and validate it against the schema
+	var parsed map[string]any
+	parseErr := parseResponse(resp.Content, &parsed, jsonSchema, typeName)
+
+	// Build message history for Resume support
+	messages := buildMessagesFromRequest(req, resp)
+	config := cfg.responseConfig()
+
 	return newResponseWithHistory(resp, parsed, parseErr, messages, config), nil
 }
 
@@ -174,18 +235,14 @@ func CallMessages(ctx context.Context, messages []Message, opts ...Option) (Resp
 
 	req := cfg.buildRequestFromMessages(messages)
 
-	resp, err := p.Call(ctx, req)
+	resp, err := cfg.call(ctx, p, req)
 	if err != nil {
 		return Response[string]{}, fmt.Errorf("calling provider: %w", err)
 	}
 
 	// Build message history for Resume support
 	historyMessages := buildMessagesFromRequest(req, resp)
-	config := &responseConfig{
-		providerName: cfg.providerName,
-		model:        cfg.model,
-		tools:        cfg.tools,
-	}
+	config := cfg.responseConfig()
 
 	return newResponseWithHistory(resp, resp.Content, nil, historyMessages, config), nil
 }
@@ -215,11 +272,7 @@ func CallMessagesParse[T any](ctx context.Context, messages []Message, opts ...O
 		typeName = "response"
 	}
 
-	cfg.jsonSchema = &provider.JSONSchema{
-		Name:   typeName,
-		Strict: true,
-		Schema: jsonSchema,
-	}
+	cfg.setJSONSchema(typeName, jsonSchema)
 
 	p, err := provider.Get(cfg.providerName)
 	if err != nil {
@@ -228,32 +281,50 @@ func CallMessagesParse[T any](ctx context.Context, messages []Message, opts ...O
 
 	req := cfg.buildRequestFromMessages(messages)
 
-	resp, err := p.Call(ctx, req)
+	resp, err := cfg.call(ctx, p, req)
 	if err != nil {
-		return Response[T]{}, fmt.Errorf("calling provider: %w", err)
+		return Response[T]{}, fmt.Errorf("calling provider: %w", explainSchemaError(err, jsonSchema))
 	}
 
+	// Parse the response into T and validate it against the schema
 	var parsed T
-	parseErr := json.Unmarshal([]byte(resp.Content), &parsed)
-	if parseErr != nil {
-		parseErr = &ParseError{
-			Content: resp.Content,
-			Target:  typeName,
-			Cause:   parseErr,
-		}
-	}
+	parseErr := parseResponse(resp.Content, &parsed, jsonSchema, typeName)
 
 	// Build message history for Resume support
 	historyMessages := buildMessagesFromRequest(req, resp)
-	config := &responseConfig{
-		providerName: cfg.providerName,
-		model:        cfg.model,
-		tools:        cfg.tools,
-	}
+	config := cfg.responseConfig()
 
 	return newResponseWithHistory(resp, parsed, parseErr, historyMessages, config), nil
 }
 
+// explainSchemaError adds the limits jsonSchema exceeds, if any, to err, a
+// failed call with it. Providers often reject oversized schemas with
+// unspecific errors.
+func explainSchemaError(err error, jsonSchema json.RawMessage) error {
+	report, measureErr := schema.Measure(jsonSchema, schema.DefaultLimits)
+	if measureErr != nil || report.OK() {
+		return err
+	}
+	return fmt.Errorf("%w (schema exceeds provider limits: %s)", err, strings.Join(report.Warnings, "; "))
+}
+
+// parseResponse unmarshals content into target and validates it against
+// jsonSchema, returning a *ParseError on failure.
+func parseResponse(content string, target any, jsonSchema json.RawMessage, typeName string) error {
+	if err := json.Unmarshal([]byte(content), target); err != nil {
+		return &ParseError{Content: content, Target: typeName, Cause: err}
+	}
+	if err := schema.Validate(jsonSchema, []byte(content)); err != nil {
+		parseErr := &ParseError{Content: content, Target: typeName, Cause: err}
+		var validationErr *schema.ValidationError
+		if errors.As(err, &validationErr) {
+			parseErr.Violations = validationErr.Violations
+		}
+		return parseErr
+	}
+	return nil
+}
+
 // buildMessagesFromRequest creates the full message history from request and response.
 func buildMessagesFromRequest(req *provider.Request, resp *provider.Response) []Message {
 	// Copy request messages
@@ -278,3 +349,76 @@ func buildMessagesFromRequest(req *provider.Request, resp *provider.Response) []
 
 	return messages
 }
+
+// call sends req to p, logs it, and records its metrics, waiting for and
+// recording usage in the call's rate limit and quota, if any.
+func (c *callConfig) call(ctx context.Context, p provider.Provider, req *provider.Request) (*provider.Response, error) {
+	if err := c.setLogitBias(req); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	c.logRequest(ctx, "llm request", req)
+	resp, err := c.callWithLimits(ctx, p, req)
+	duration := time.Since(start)
+	c.logResponse(ctx, "llm response", resp, err, duration)
+	c.recordRequest(resp, err, false, duration, 0)
+	if err != nil {
+		if retry, terr := c.truncate(ctx, req, err); terr != nil {
+			return nil, fmt.Errorf("%w (%v)", err, terr)
+		} else if retry {
+			return c.call(ctx, p, req)
+		}
+	}
+	return resp, err
+}
+
+// callWithLimits sends req to p, waiting for and recording usage in the
+// call's rate limit and quota, if any.
+func (c *callConfig) callWithLimits(ctx context.Context, p provider.Provider, req *provider.Request) (*provider.Response, error) {
+	release, err := c.acquire(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Call(ctx, req)
+	if err != nil {
+		release(Usage{})
+		return nil, err
+	}
+	release(responseUsage(resp))
+	return resp, nil
+}
+
+// acquire waits for the call's rate limit and quota, if any, and returns a
+// function that records the usage of the call in them. The function can be
+// called more than once; only the first call counts.
+func (c *callConfig) acquire(ctx context.Context, req *provider.Request) (func(Usage), error) {
+	tokens := EstimateMessagesTokens(req.Messages)
+	var (
+		limit       *rateReservation
+		reservation *QuotaReservation
+		err         error
+	)
+	if c.rateLimit != nil {
+		limit, err = rateLimiterFor(c.providerName, c.model, *c.rateLimit).acquire(ctx, tokens)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.quota != nil {
+		reservation, err = c.quota.Acquire(ctx, c.model, tokens)
+		if err != nil {
+			if limit != nil {
+				limit.release(Usage{})
+			}
+			return nil, err
+		}
+	}
+	return func(usage Usage) {
+		if limit != nil {
+			limit.release(usage)
+		}
+		if reservation != nil {
+			reservation.Release(usage)
+		}
+	}, nil
+}
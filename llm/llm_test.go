@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// contentProvider returns a fixed response content.
+type contentProvider struct {
+	content string
+}
+
+func (p contentProvider) Name() string { return "content" }
+
+func (p contentProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	return &provider.Response{Content: p.content, FinishReason: provider.FinishReasonStop}, nil
+}
+
+// registerContent registers a provider returning content and returns options to use it.
+func registerContent(t *testing.T, content string) []Option {
+	t.Helper()
+	name := "content-" + t.Name()
+	provider.Register(name, func() (provider.Provider, error) {
+		return contentProvider{content: content}, nil
+	})
+	return []Option{WithProvider(name), WithModel("m")}
+}
+
+// errorProvider fails every call.
+type errorProvider struct{}
+
+func (errorProvider) Name() string { return "error" }
+
+func (errorProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	return nil, errors.New("invalid schema")
+}
+
+type rating struct {
+	Stars   int    `json:"stars" jsonschema:"minimum=1,maximum=5"`
+	Comment string `json:"comment"`
+}
+
+func TestCallParse_Validates(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		resp, err := CallParse[rating](context.Background(), "Rate it", registerContent(t, `{"stars":4,"comment":"good"}`)...)
+		require.NoError(t, err)
+		parsed, err := resp.Parsed()
+		require.NoError(t, err)
+		assert.Equal(t, rating{Stars: 4, Comment: "good"}, parsed)
+	})
+
+	t.Run("schema violations", func(t *testing.T) {
+		resp, err := CallParse[rating](context.Background(), "Rate it", registerContent(t, `{"stars":9}`)...)
+		require.NoError(t, err)
+
+		parsed, err := resp.Parsed()
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr))
+		assert.Equal(t, "rating", parseErr.Target)
+		require.Len(t, parseErr.Violations, 2)
+		assert.Equal(t, `$: missing required property "comment"`, parseErr.Violations[0].String())
+		assert.Equal(t, "$.stars: must be <= 5", parseErr.Violations[1].String())
+		assert.Equal(t, 9, parsed.Stars, "the unmarshaled value is still returned")
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		resp, err := CallParse[rating](context.Background(), "Rate it", registerContent(t, `not json`)...)
+		require.NoError(t, err)
+		_, err = resp.Parsed()
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr))
+		assert.Empty(t, parseErr.Violations)
+	})
+}
+
+func TestCallParseDynamic(t *testing.T) {
+	invoiceSchema := json.RawMessage(`{
+		"title": "invoice",
+		"type": "object",
+		"properties": {
+			"number": {"type": "string"},
+			"total": {"type": "number", "minimum": 0}
+		},
+		"required": ["number", "total"]
+	}`)
+
+	t.Run("valid", func(t *testing.T) {
+		resp, err := CallParseDynamic(context.Background(), "Extract", invoiceSchema, registerContent(t, `{"number":"A-1","total":12.5}`)...)
+		require.NoError(t, err)
+		parsed, err := resp.Parsed()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"number": "A-1", "total": 12.5}, parsed)
+	})
+
+	t.Run("schema violations", func(t *testing.T) {
+		resp, err := CallParseDynamic(context.Background(), "Extract", invoiceSchema, registerContent(t, `{"total":-1}`)...)
+		require.NoError(t, err)
+		_, err = resp.Parsed()
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr))
+		assert.Equal(t, "invoice", parseErr.Target)
+		assert.Len(t, parseErr.Violations, 2)
+	})
+
+	t.Run("non-object schema", func(t *testing.T) {
+		_, err := CallParseDynamic(context.Background(), "Extract", json.RawMessage(`{"type":"string"}`), registerContent(t, `"x"`)...)
+		assert.ErrorContains(t, err, "schema must describe an object")
+	})
+}
+
+func TestCallParseDynamic_ExplainsOversizedSchema(t *testing.T) {
+	provider.Register("error", func() (provider.Provider, error) { return errorProvider{}, nil })
+
+	// 12 levels of nested objects
+	nested := `{"type": "string"}`
+	for range 12 {
+		nested = `{"type": "object", "properties": {"child": ` + nested + `}}`
+	}
+
+	_, err := CallParseDynamic(context.Background(), "Extract", json.RawMessage(nested), WithProvider("error"), WithModel("m"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid schema")
+	assert.Contains(t, err.Error(), "schema exceeds provider limits: depth 12 exceeds 10")
+}
+
+// schemaProvider records the structured output schema of the last call.
+type schemaProvider struct {
+	schema **provider.JSONSchema
+}
+
+func (p schemaProvider) Name() string { return "schema" }
+
+func (p schemaProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	*p.schema = req.JSONSchema
+	return &provider.Response{Content: `{"stars":3,"comment":"ok"}`, FinishReason: provider.FinishReasonStop}, nil
+}
+
+func TestWithSchemaOptions(t *testing.T) {
+	var sent *provider.JSONSchema
+	provider.Register("schema", func() (provider.Provider, error) { return schemaProvider{schema: &sent}, nil })
+	opts := []Option{WithProvider("schema"), WithModel("m")}
+
+	_, err := CallParse[rating](context.Background(), "Rate it", opts...)
+	require.NoError(t, err)
+	assert.True(t, sent.Strict)
+	assert.Equal(t, provider.AdditionalPropertiesAuto, sent.AdditionalProperties)
+
+	_, err = CallParse[rating](context.Background(), "Rate it", append(opts,
+		WithSchemaOptions(SchemaStrict(false), SchemaAdditionalProperties(provider.AdditionalPropertiesOmit)),
+	)...)
+	require.NoError(t, err)
+	assert.Equal(t, "rating", sent.Name)
+	assert.False(t, sent.Strict)
+	assert.Equal(t, provider.AdditionalPropertiesOmit, sent.AdditionalProperties)
+}
+
+// headerProvider records the HTTP headers of the last call and responds with
+// a raw HTTP response.
+type headerProvider struct {
+	headers *http.Header
+}
+
+func (p headerProvider) Name() string { return "header" }
+
+func (p headerProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	*p.headers = req.Headers
+	return &provider.Response{
+		Content: "ok",
+		HTTP:    &provider.HTTPResponse{StatusCode: 200, Header: http.Header{"X-Request-Id": {"req_1"}}},
+	}, nil
+}
+
+func TestWithHeader(t *testing.T) {
+	var sent http.Header
+	provider.Register("header", func() (provider.Provider, error) { return headerProvider{headers: &sent}, nil })
+	t.Cleanup(func() { provider.Reset("header") })
+
+	resp, err := Call(context.Background(), "Hello",
+		WithProvider("header"),
+		WithModel("m"),
+		WithHeader("OpenAI-Project", "proj_1"),
+		WithHeader("x-trace-id", "a"),
+		WithHeader("x-trace-id", "b"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "proj_1", sent.Get("Openai-Project"))
+	assert.Equal(t, []string{"a", "b"}, sent.Values("X-Trace-Id"))
+	assert.Equal(t, "req_1", resp.HTTPResponse().RequestID())
+}
+
+func TestDefaultProvider(t *testing.T) {
+	provider.RegisterInstance("default", contentProvider{content: "hi"})
+	_, err := Call(context.Background(), "Hello", WithModel("m"))
+	require.ErrorIs(t, err, ErrProviderRequired)
+
+	provider.SetDefault("default")
+	t.Cleanup(func() { provider.SetDefault("") })
+
+	resp, err := Call(context.Background(), "Hello", WithModel("m"))
+	require.NoError(t, err)
+	assert.Equal(t, "hi", resp.Text())
+}
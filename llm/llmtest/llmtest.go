@@ -0,0 +1,170 @@
+// Package llmtest provides approval testing of prompt assembly.
+//
+// A Recorder is a provider that records the requests built by calls and
+// agents, and the responses to them. Snapshot compares them, normalized and
+// redacted, with golden files, so changes to prompts, tool definitions, and
+// options show up as test failures with a diff until the golden files are
+// updated:
+//
+//	BUCEPHALUS_UPDATE_GOLDEN=1 go test ./...
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// Exchange is a request sent to a Recorder and its outcome.
+type Exchange struct {
+	Request  *provider.Request
+	Response *provider.Response // Nil if the call failed
+	Err      error
+}
+
+// Recorder is a provider that records the requests it receives and the
+// responses of the provider it wraps. It is safe for concurrent use.
+type Recorder struct {
+	inner provider.Provider
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// NewRecorder returns a recorder that forwards requests to inner. If inner
+// is nil, every request gets an empty response that ends with
+// FinishReasonStop, which suffices to snapshot single-turn requests.
+//
+// Example:
+//
+//	rec := llmtest.NewRecorder(nil)
+//	provider.RegisterInstance("recorder", rec)
+//	_, err := llm.Call(ctx, "Summarize the ticket.", llm.WithProvider("recorder"), llm.WithModel("gpt-4o"))
+//	llmtest.Snapshot(t, "summarize", rec.Requests())
+func NewRecorder(inner provider.Provider) *Recorder {
+	return &Recorder{inner: inner}
+}
+
+// Name implements provider.Provider.
+func (r *Recorder) Name() string {
+	if r.inner != nil {
+		return r.inner.Name()
+	}
+	return "recorder"
+}
+
+// Call implements provider.Provider.
+func (r *Recorder) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	var resp *provider.Response
+	var err error
+	if r.inner != nil {
+		resp, err = r.inner.Call(ctx, req)
+	} else {
+		resp = &provider.Response{FinishReason: provider.FinishReasonStop}
+	}
+	r.record(Exchange{Request: req, Response: resp, Err: err})
+	return resp, err
+}
+
+// CallStream implements provider.StreamingProvider. The exchange is recorded
+// when the stream is closed, with the response accumulated so far. It fails
+// if the wrapped provider does not support streaming.
+func (r *Recorder) CallStream(ctx context.Context, req *provider.Request) (provider.ResponseStream, error) {
+	streamer, ok := r.inner.(provider.StreamingProvider)
+	if !ok {
+		err := fmt.Errorf("provider %q does not support streaming", r.Name())
+		r.record(Exchange{Request: req, Err: err})
+		return nil, err
+	}
+	stream, err := streamer.CallStream(ctx, req)
+	if err != nil {
+		r.record(Exchange{Request: req, Err: err})
+		return nil, err
+	}
+	return &recordingStream{ResponseStream: stream, recorder: r, req: req}, nil
+}
+
+// Exchanges returns the recorded exchanges in the order they completed.
+func (r *Recorder) Exchanges() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exchange(nil), r.exchanges...)
+}
+
+// Requests returns the recorded requests.
+func (r *Recorder) Requests() []*provider.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reqs := make([]*provider.Request, len(r.exchanges))
+	for i, ex := range r.exchanges {
+		reqs[i] = ex.Request
+	}
+	return reqs
+}
+
+// Reset discards the recorded exchanges.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = nil
+}
+
+// record appends an exchange.
+func (r *Recorder) record(ex Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = append(r.exchanges, ex)
+}
+
+// recordingStream records its exchange when closed.
+type recordingStream struct {
+	provider.ResponseStream
+	recorder *Recorder
+	req      *provider.Request
+	once     sync.Once
+}
+
+// Close implements provider.ResponseStream.
+func (s *recordingStream) Close() error {
+	s.once.Do(func() {
+		s.recorder.record(Exchange{Request: s.req, Response: s.Accumulated(), Err: s.Err()})
+	})
+	return s.ResponseStream.Close()
+}
+
+// ProviderFunc is a provider that answers each request with the result of
+// the function, for tests that script the model's behavior.
+type ProviderFunc func(ctx context.Context, req *provider.Request) (*provider.Response, error)
+
+// Name implements provider.Provider.
+func (f ProviderFunc) Name() string {
+	return "func"
+}
+
+// Call implements provider.Provider.
+func (f ProviderFunc) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	return f(ctx, req)
+}
+
+var funcCount atomic.Int64
+
+// RegisterFunc registers fn as a provider under a unique name and returns
+// the name. The provider is reset when the test finishes.
+//
+// Example:
+//
+//	name := llmtest.RegisterFunc(t, func(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+//	    return &provider.Response{Content: "yes", FinishReason: provider.FinishReasonStop}, nil
+//	})
+//	resp, err := llm.Call(ctx, "Is it sunny?", llm.WithProvider(name), llm.WithModel("test-model"))
+func RegisterFunc(t testing.TB, fn ProviderFunc) string {
+	t.Helper()
+	name := fmt.Sprintf("llmtest-func-%d", funcCount.Add(1))
+	provider.RegisterInstance(name, fn)
+	t.Cleanup(func() { provider.Reset(name) })
+	return name
+}
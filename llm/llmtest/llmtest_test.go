@@ -0,0 +1,206 @@
+package llmtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+// fakeT records the failures of a snapshot.
+type fakeT struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	t.fatal = true
+}
+
+// toolProvider calls a tool once, then answers.
+type toolProvider struct{}
+
+func (toolProvider) Name() string { return "tool" }
+
+func (toolProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	if req.Messages[len(req.Messages)-1].Role == provider.RoleTool {
+		return &provider.Response{Content: "Sent.", FinishReason: provider.FinishReasonStop}, nil
+	}
+	return &provider.Response{
+		ToolCalls:    []provider.ToolCall{{ID: "call_x7Yq", Name: "send_email", Arguments: `{"to":"jane@example.com","body":"Hi"}`}},
+		FinishReason: provider.FinishReasonToolCalls,
+		Usage:        provider.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+type emailInput struct {
+	To   string `json:"to" jsonschema:"required"`
+	Body string `json:"body" jsonschema:"required"`
+}
+
+func TestSnapshot_Recorder(t *testing.T) {
+	rec := NewRecorder(toolProvider{})
+	provider.RegisterInstance("llmtest-tool", rec)
+	t.Cleanup(func() { provider.Reset("llmtest-tool") })
+
+	send := llm.MustNewTool("send_email", "Send an email",
+		func(ctx context.Context, in emailInput) (string, error) { return "ok", nil })
+	archive := llm.MustNewTool("archive", "Archive the thread",
+		func(ctx context.Context, in struct{}) (string, error) { return "ok", nil })
+
+	_, err := llm.Call(context.Background(), "Email jane@example.com that the build is fixed.",
+		llm.WithProvider("llmtest-tool"),
+		llm.WithModel("m"),
+		llm.WithSystemMessage("You send emails."),
+		llm.WithTools(send, archive),
+		llm.WithTemperature(0),
+	)
+	require.NoError(t, err)
+	require.Len(t, rec.Requests(), 1)
+
+	dir := t.TempDir()
+	t.Setenv(UpdateEnv, "1")
+	Snapshot(t, "email", rec, WithGoldenDir(dir))
+
+	golden, err := os.ReadFile(filepath.Join(dir, "email.golden"))
+	require.NoError(t, err)
+	assert.Contains(t, string(golden), `"id": "call_1"`)
+	assert.Contains(t, string(golden), `"to": "[REDACTED:email]"`)
+	assert.NotContains(t, string(golden), "jane@example.com")
+	assert.Regexp(t, `(?s)"name": "archive".*"name": "send_email"`, string(golden))
+
+	// Unchanged requests match the golden file
+	t.Setenv(UpdateEnv, "")
+	ft := &fakeT{TB: t}
+	Snapshot(ft, "email", rec, WithGoldenDir(dir))
+	assert.Empty(t, ft.errors)
+
+	// A changed prompt fails with a diff
+	rec.Reset()
+	_, err = llm.Call(context.Background(), "Email jane@example.com that the build is fixed.",
+		llm.WithProvider("llmtest-tool"),
+		llm.WithModel("m"),
+		llm.WithSystemMessage("You send short emails."),
+		llm.WithTools(send, archive),
+		llm.WithTemperature(0),
+	)
+	require.NoError(t, err)
+	ft = &fakeT{TB: t}
+	Snapshot(ft, "email", rec, WithGoldenDir(dir))
+	require.Len(t, ft.errors, 1)
+	assert.False(t, ft.fatal)
+	assert.Contains(t, ft.errors[0], `-           "content": "You send emails."`)
+	assert.Contains(t, ft.errors[0], `+           "content": "You send short emails."`)
+	assert.Contains(t, ft.errors[0], UpdateEnv)
+}
+
+func TestSnapshot_Normalization(t *testing.T) {
+	req := &provider.Request{
+		Model: "m",
+		Messages: []provider.Message{
+			{Role: provider.RoleUser, Content: "Today is 2026-10-16."},
+			{Role: provider.RoleAssistant, ToolCalls: []provider.ToolCall{{ID: "abc", Name: "lookup", Arguments: `{"b":1,"a":2}`}}},
+			{Role: provider.RoleTool, ToolID: "abc", Content: "found"},
+		},
+		Headers: http.Header{"Authorization": {"Bearer secret"}, "X-Team": {"search"}},
+	}
+	dir := t.TempDir()
+	opts := []Option{
+		WithGoldenDir(dir),
+		WithReplacement(regexp.MustCompile(`\d{4}-\d{2}-\d{2}`), "<date>"),
+	}
+	t.Setenv(UpdateEnv, "1")
+	Snapshot(t, "normalized", req, opts...)
+
+	golden, err := os.ReadFile(filepath.Join(dir, "normalized.golden"))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "model": "m",
+  "messages": [
+    {
+      "role": "user",
+      "content": "Today is <date>."
+    },
+    {
+      "role": "assistant",
+      "tool_calls": [
+        {
+          "id": "call_1",
+          "name": "lookup",
+          "arguments": {
+            "a": 2,
+            "b": 1
+          }
+        }
+      ]
+    },
+    {
+      "role": "tool",
+      "content": "found",
+      "tool_id": "call_1"
+    }
+  ],
+  "headers": {
+    "Authorization": "[REDACTED]",
+    "X-Team": "search"
+  }
+}
+`, string(golden))
+}
+
+func TestSnapshot_MissingGolden(t *testing.T) {
+	t.Setenv(UpdateEnv, "")
+	ft := &fakeT{TB: t}
+	Snapshot(ft, "missing", &provider.Response{Content: "hi"}, WithGoldenDir(t.TempDir()))
+	require.Len(t, ft.errors, 1)
+	assert.True(t, ft.fatal)
+	assert.Contains(t, ft.errors[0], "does not exist")
+}
+
+func TestRecorder_Errors(t *testing.T) {
+	rec := NewRecorder(nil)
+	resp, err := rec.Call(context.Background(), &provider.Request{Model: "m"})
+	require.NoError(t, err)
+	assert.Equal(t, provider.FinishReasonStop, resp.FinishReason)
+
+	_, err = rec.CallStream(context.Background(), &provider.Request{Model: "m"})
+	assert.ErrorContains(t, err, "does not support streaming")
+
+	exchanges := rec.Exchanges()
+	require.Len(t, exchanges, 2)
+	assert.NoError(t, exchanges[0].Err)
+	assert.Error(t, exchanges[1].Err)
+	assert.Nil(t, exchanges[1].Response)
+}
+
+func TestDiff(t *testing.T) {
+	want := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	got := "a\nb\nc\nd\nE\nf\ng\nh\ni\n"
+	assert.Equal(t, "  ...\n  b\n  c\n  d\n- e\n+ E\n  f\n  g\n  h\n  ...\n", diff(want, got))
+}
+
+func TestRegisterFunc(t *testing.T) {
+	name := RegisterFunc(t, func(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+		return &provider.Response{Content: "echo: " + req.Messages[len(req.Messages)-1].Content, FinishReason: provider.FinishReasonStop}, nil
+	})
+	resp, err := llm.Call(context.Background(), "hi", llm.WithProvider(name), llm.WithModel("test-model"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo: hi", resp.Text())
+}
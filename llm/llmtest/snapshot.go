@@ -0,0 +1,460 @@
+package llmtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+// UpdateEnv is the environment variable that makes Snapshot write golden
+// files instead of comparing with them when set to a non-empty value.
+const UpdateEnv = "BUCEPHALUS_UPDATE_GOLDEN"
+
+// sensitiveHeaders are the request headers whose values are always redacted.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Cookie"}
+
+// Option configures Snapshot.
+type Option func(*snapshotter)
+
+// snapshotter holds the configuration of a snapshot.
+type snapshotter struct {
+	dir          string
+	redactor     *llm.Redactor
+	replacements []replacement
+
+	ids map[string]string // Original tool call IDs to stable ones
+}
+
+// replacement replaces the matches of a pattern in a snapshot.
+type replacement struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// WithGoldenDir sets the directory of the golden files (default: "testdata/golden").
+func WithGoldenDir(dir string) Option {
+	return func(s *snapshotter) {
+		s.dir = dir
+	}
+}
+
+// WithRedactor sets the redactor applied to message contents, tool call
+// arguments, and header values (default: llm.NewRedactor()). Pass nil to
+// redact only sensitive headers.
+func WithRedactor(r *llm.Redactor) Option {
+	return func(s *snapshotter) {
+		s.redactor = r
+	}
+}
+
+// WithReplacement replaces the matches of pattern in the snapshot, with $1
+// etc. expanded as in regexp.Regexp.ReplaceAllString, to mask values that
+// change between runs such as dates.
+//
+// Example:
+//
+//	llmtest.WithReplacement(regexp.MustCompile(`\d{4}-\d{2}-\d{2}`), "<date>")
+func WithReplacement(pattern *regexp.Regexp, repl string) Option {
+	return func(s *snapshotter) {
+		s.replacements = append(s.replacements, replacement{pattern: pattern, replacement: repl})
+	}
+}
+
+// Snapshot compares v, as normalized JSON, with the golden file
+// <dir>/<name>.golden and fails t with a diff if they differ. If UpdateEnv
+// is set, it writes the golden file instead.
+//
+// Requests, responses, and exchanges (*provider.Request, *provider.Response,
+// Exchange, slices of them, or a *Recorder for all its exchanges) are
+// normalized so that snapshots are stable: JSON objects have sorted keys,
+// tool call arguments and schemas are decoded, tools are sorted by name,
+// tool call IDs are renumbered in order of appearance, raw HTTP data is
+// dropped, and contents, arguments, and headers are redacted. Other values
+// are snapshotted as their JSON encoding.
+//
+// Example:
+//
+//	func TestSummarizePrompt(t *testing.T) {
+//	    rec := llmtest.NewRecorder(nil)
+//	    provider.RegisterInstance("recorder", rec)
+//	    _, err := summarize(ctx, ticket, llm.WithProvider("recorder"))
+//	    require.NoError(t, err)
+//	    llmtest.Snapshot(t, "summarize", rec)
+//	}
+func Snapshot(t testing.TB, name string, v any, opts ...Option) {
+	t.Helper()
+	s := &snapshotter{dir: filepath.Join("testdata", "golden"), redactor: llm.NewRedactor()}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	got, err := s.marshal(v)
+	if err != nil {
+		t.Fatalf("llmtest: snapshot %s: %v", name, err)
+		return
+	}
+
+	path := filepath.Join(s.dir, name+".golden")
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("llmtest: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("llmtest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("llmtest: golden file %s does not exist; run the test with %s=1 to create it", path, UpdateEnv)
+		return
+	}
+	if err != nil {
+		t.Fatalf("llmtest: %v", err)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("llmtest: snapshot %s differs from %s (-want +got):\n%s\nRun the test with %s=1 to update it.",
+			name, path, diff(string(want), string(got)), UpdateEnv)
+	}
+}
+
+// marshal returns the normalized JSON of v.
+func (s *snapshotter) marshal(v any) ([]byte, error) {
+	s.ids = make(map[string]string)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s.normalize(v)); err != nil {
+		return nil, err
+	}
+
+	data := buf.String()
+	for _, r := range s.replacements {
+		data = r.pattern.ReplaceAllString(data, r.replacement)
+	}
+	return []byte(data), nil
+}
+
+// normalize returns the stable form of v.
+func (s *snapshotter) normalize(v any) any {
+	switch v := v.(type) {
+	case *Recorder:
+		return s.normalize(v.Exchanges())
+	case []Exchange:
+		out := make([]any, len(v))
+		for i, ex := range v {
+			out[i] = s.exchange(ex)
+		}
+		return out
+	case Exchange:
+		return s.exchange(v)
+	case []*provider.Request:
+		out := make([]any, len(v))
+		for i, req := range v {
+			out[i] = s.request(req)
+		}
+		return out
+	case *provider.Request:
+		return s.request(v)
+	case provider.Request:
+		return s.request(&v)
+	case []*provider.Response:
+		out := make([]any, len(v))
+		for i, resp := range v {
+			out[i] = s.response(resp)
+		}
+		return out
+	case *provider.Response:
+		return s.response(v)
+	case provider.Response:
+		return s.response(&v)
+	default:
+		return v
+	}
+}
+
+// exchangeSnapshot is the stable form of an Exchange.
+type exchangeSnapshot struct {
+	Request  *requestSnapshot  `json:"request"`
+	Response *responseSnapshot `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// exchange returns the stable form of ex.
+func (s *snapshotter) exchange(ex Exchange) *exchangeSnapshot {
+	out := &exchangeSnapshot{Request: s.request(ex.Request), Response: s.response(ex.Response)}
+	if ex.Err != nil {
+		out.Error = s.redactor.String(ex.Err.Error())
+	}
+	return out
+}
+
+// requestSnapshot is the stable form of a provider.Request.
+type requestSnapshot struct {
+	Model         string            `json:"model"`
+	Messages      []messageSnapshot `json:"messages"`
+	Tools         []toolSnapshot    `json:"tools,omitempty"`
+	ToolChoice    *toolChoice       `json:"tool_choice,omitempty"`
+	Temperature   *float64          `json:"temperature,omitempty"`
+	MaxTokens     *int              `json:"max_tokens,omitempty"`
+	TopP          *float64          `json:"top_p,omitempty"`
+	TopK          *int              `json:"top_k,omitempty"`
+	Seed          *int              `json:"seed,omitempty"`
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	JSONSchema    *jsonSchema       `json:"json_schema,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+}
+
+// messageSnapshot is the stable form of a provider.Message.
+type messageSnapshot struct {
+	Role      provider.Role      `json:"role"`
+	Content   string             `json:"content,omitempty"`
+	ToolCalls []toolCallSnapshot `json:"tool_calls,omitempty"`
+	ToolID    string             `json:"tool_id,omitempty"`
+}
+
+// toolCallSnapshot is the stable form of a provider.ToolCall.
+type toolCallSnapshot struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments any    `json:"arguments"`
+}
+
+// toolSnapshot is the stable form of a provider.ToolDef.
+type toolSnapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// toolChoice is the stable form of a provider.ToolChoice.
+type toolChoice struct {
+	Mode  provider.ToolChoiceMode `json:"mode"`
+	Tools []string                `json:"tools,omitempty"`
+}
+
+// jsonSchema is the stable form of a provider.JSONSchema.
+type jsonSchema struct {
+	Name                 string                        `json:"name"`
+	Strict               bool                          `json:"strict,omitempty"`
+	AdditionalProperties provider.AdditionalProperties `json:"additional_properties,omitempty"`
+	Schema               any                           `json:"schema"`
+}
+
+// request returns the stable form of req.
+func (s *snapshotter) request(req *provider.Request) *requestSnapshot {
+	if req == nil {
+		return nil
+	}
+	out := &requestSnapshot{
+		Model:         req.Model,
+		Messages:      make([]messageSnapshot, len(req.Messages)),
+		Temperature:   req.Temperature,
+		MaxTokens:     req.MaxTokens,
+		TopP:          req.TopP,
+		TopK:          req.TopK,
+		Seed:          req.Seed,
+		StopSequences: req.StopSequences,
+		Headers:       s.headers(req.Headers),
+	}
+	for i, m := range req.Messages {
+		out.Messages[i] = messageSnapshot{
+			Role:      m.Role,
+			Content:   s.redactor.String(m.Content),
+			ToolCalls: s.toolCalls(m.ToolCalls),
+			ToolID:    s.id(m.ToolID),
+		}
+	}
+	for _, tool := range req.Tools {
+		out.Tools = append(out.Tools, toolSnapshot{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  decodeJSON(string(tool.Parameters)),
+		})
+	}
+	slices.SortStableFunc(out.Tools, func(a, b toolSnapshot) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	if req.ToolChoice != nil {
+		out.ToolChoice = &toolChoice{Mode: req.ToolChoice.Mode, Tools: req.ToolChoice.Tools}
+	}
+	if req.JSONSchema != nil {
+		out.JSONSchema = &jsonSchema{
+			Name:                 req.JSONSchema.Name,
+			Strict:               req.JSONSchema.Strict,
+			AdditionalProperties: req.JSONSchema.AdditionalProperties,
+			Schema:               decodeJSON(string(req.JSONSchema.Schema)),
+		}
+	}
+	return out
+}
+
+// responseSnapshot is the stable form of a provider.Response.
+type responseSnapshot struct {
+	Content      string                `json:"content,omitempty"`
+	ToolCalls    []toolCallSnapshot    `json:"tool_calls,omitempty"`
+	FinishReason provider.FinishReason `json:"finish_reason,omitempty"`
+	Usage        *usage                `json:"usage,omitempty"`
+}
+
+// usage is the stable form of a provider.Usage.
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// response returns the stable form of resp.
+func (s *snapshotter) response(resp *provider.Response) *responseSnapshot {
+	if resp == nil {
+		return nil
+	}
+	out := &responseSnapshot{
+		Content:      s.redactor.String(resp.Content),
+		ToolCalls:    s.toolCalls(resp.ToolCalls),
+		FinishReason: resp.FinishReason,
+	}
+	if resp.Usage != (provider.Usage{}) {
+		out.Usage = &usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		}
+	}
+	return out
+}
+
+// toolCalls returns the stable form of calls.
+func (s *snapshotter) toolCalls(calls []provider.ToolCall) []toolCallSnapshot {
+	var out []toolCallSnapshot
+	for _, tc := range calls {
+		out = append(out, toolCallSnapshot{
+			ID:        s.id(tc.ID),
+			Name:      tc.Name,
+			Arguments: decodeJSON(s.redactor.String(tc.Arguments)),
+		})
+	}
+	return out
+}
+
+// id returns the stable form of a tool call ID: "call_<n>" for the nth
+// distinct ID in the snapshot.
+func (s *snapshotter) id(id string) string {
+	if id == "" {
+		return ""
+	}
+	stable, ok := s.ids[id]
+	if !ok {
+		stable = fmt.Sprintf("call_%d", len(s.ids)+1)
+		s.ids[id] = stable
+	}
+	return stable
+}
+
+// headers returns the redacted headers, with multiple values joined by ", ".
+func (s *snapshotter) headers(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for key, values := range h {
+		value := strings.Join(values, ", ")
+		if slices.ContainsFunc(sensitiveHeaders, func(name string) bool { return strings.EqualFold(name, key) }) {
+			value = "[REDACTED]"
+		}
+		out[http.CanonicalHeaderKey(key)] = s.redactor.String(value)
+	}
+	return out
+}
+
+// decodeJSON returns s decoded if it is JSON, or s otherwise. Maps are
+// encoded with sorted keys, so decoding makes the key order stable.
+func decodeJSON(s string) any {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return s
+	}
+	return v
+}
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+// diff returns a line diff of want and got, with removed lines prefixed by
+// "-", added lines by "+", and unchanged lines near changes by " ".
+func diff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+
+	// Show only the changes and the unchanged lines around them
+	show := make([]bool, len(lines))
+	for k, line := range lines {
+		if line[0] != ' ' {
+			for c := max(k-diffContext, 0); c <= min(k+diffContext, len(lines)-1); c++ {
+				show[c] = true
+			}
+		}
+	}
+	var sb strings.Builder
+	for k, line := range lines {
+		if show[k] {
+			sb.WriteString(line + "\n")
+		} else if k == 0 || show[k-1] {
+			sb.WriteString("  ...\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// defaultLogger is the logger set by SetLogger.
+var defaultLogger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger of all calls that do not set one with
+// WithLogger. By default, nothing is logged; pass nil to log nothing again.
+//
+// Calls log at these levels, so the logger's handler selects the detail:
+//
+//   - Debug: requests (provider, model, user, and the number of messages
+//     and tools, but not their content), tool executions, and stream starts
+//   - Info: responses and finished streams, with durations, token usage,
+//     and provider request IDs
+//   - Warn: transient errors (rate limits, overload, timeouts) and model
+//     fallbacks
+//   - Error: other errors
+//
+// Example:
+//
+//	llm.SetLogger(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+func SetLogger(logger *slog.Logger) {
+	defaultLogger.Store(logger)
+}
+
+// Logger returns the logger set by SetLogger, or nil if there is none.
+func Logger() *slog.Logger {
+	return defaultLogger.Load()
+}
+
+// WithLogger logs the call to logger instead of the logger set by SetLogger.
+// See SetLogger for what is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *callConfig) {
+		c.logger = logger
+	}
+}
+
+// log returns the logger of the call, or nil if nothing is logged.
+func (c *callConfig) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return defaultLogger.Load()
+}
+
+// logRequest logs a request to the provider, without its content.
+func (c *callConfig) logRequest(ctx context.Context, msg string, req *provider.Request) {
+	logger := c.log()
+	if logger == nil {
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, msg, append(c.logAttrs(),
+		slog.Int("messages", len(req.Messages)),
+		slog.Int("tools", len(req.Tools)),
+		slog.Bool("structured", req.JSONSchema != nil),
+	)...)
+}
+
+// logAttrs returns the attributes that identify the call in its log records:
+// the provider, the model, and the user set by WithUser, if any.
+func (c *callConfig) logAttrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("provider", c.providerName),
+		slog.String("model", c.model),
+	}
+	if c.user != "" {
+		attrs = append(attrs, slog.String("user", c.user))
+	}
+	return attrs
+}
+
+// logResponse logs the response of a provider, or its error.
+func (c *callConfig) logResponse(ctx context.Context, msg string, resp *provider.Response, err error, duration time.Duration) {
+	logger := c.log()
+	if logger == nil {
+		return
+	}
+	attrs := append(c.logAttrs(), slog.Duration("duration", duration))
+	if err != nil {
+		logError(ctx, logger, msg+" failed", err, attrs...)
+		return
+	}
+	if resp != nil {
+		attrs = append(attrs,
+			slog.Int("prompt_tokens", resp.Usage.PromptTokens),
+			slog.Int("completion_tokens", resp.Usage.CompletionTokens),
+			slog.String("finish_reason", string(resp.FinishReason)),
+			slog.Int("tool_calls", len(resp.ToolCalls)),
+		)
+		if id := resp.HTTP.RequestID(); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if resp.ContentFilter != nil {
+			attrs = append(attrs, slog.String("content_filter", resp.ContentFilter.Reason))
+		}
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, msg, attrs...)
+}
+
+// logToolCall logs the execution of a tool call, without its arguments.
+func logToolCall(ctx context.Context, tc ToolCall, duration time.Duration, err error) {
+	logger := defaultLogger.Load()
+	if logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("tool", tc.Name),
+		slog.String("tool_call_id", tc.ID),
+		slog.Duration("duration", duration),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", Redact(err.Error())))
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "llm tool call", attrs...)
+}
+
+// logError logs err, redacted with the redactor set by SetRedactor, at Warn
+// if it is transient and at Error otherwise, with its HTTP status and
+// provider request ID if it has them.
+func logError(ctx context.Context, logger *slog.Logger, msg string, err error, attrs ...slog.Attr) {
+	level := slog.LevelError
+	if provider.IsTransient(err) {
+		level = slog.LevelWarn
+	}
+	attrs = append(attrs, slog.String("error", Redact(err.Error())))
+	var statusErr provider.StatusError
+	if errors.As(err, &statusErr) {
+		attrs = append(attrs, slog.Int("status", statusErr.HTTPStatus()))
+	}
+	if id := provider.RawResponse(err).RequestID(); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// logRecords returns the records logged as JSON to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	opts := append(registerContent(t, "secret answer"), WithLogger(logger))
+	_, err := Call(context.Background(), "secret question", opts...)
+	require.NoError(t, err)
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "llm request", records[0]["msg"])
+	assert.Equal(t, "DEBUG", records[0]["level"])
+	assert.Equal(t, float64(1), records[0]["messages"])
+	assert.Equal(t, "llm response", records[1]["msg"])
+	assert.Equal(t, "INFO", records[1]["level"])
+	assert.Equal(t, "stop", records[1]["finish_reason"])
+	assert.Contains(t, records[1], "duration")
+
+	// Contents are not logged
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestSetLogger_Error(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	provider.Register("error", func() (provider.Provider, error) { return errorProvider{}, nil })
+	_, err := Call(context.Background(), "Hello", WithProvider("error"), WithModel("m"))
+	require.Error(t, err)
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 1) // The request is logged at Debug
+	assert.Equal(t, "llm response failed", records[0]["msg"])
+	assert.Equal(t, "ERROR", records[0]["level"]) // Local errors are not transient
+	assert.Equal(t, "invalid schema", records[0]["error"])
+}
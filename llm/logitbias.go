@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// bannedTokenBias is the bias that prevents a token from being generated.
+const bannedTokenBias = -100
+
+// Tokenizer converts text to the token IDs of a model's vocabulary.
+type Tokenizer interface {
+	Encode(text string) []int
+}
+
+// TokenizerFunc is a function that implements Tokenizer.
+type TokenizerFunc func(text string) []int
+
+// Encode implements Tokenizer.
+func (f TokenizerFunc) Encode(text string) []int {
+	return f(text)
+}
+
+// tokenizers holds the tokenizers registered with RegisterTokenizer, by
+// model prefix.
+var tokenizers sync.Map
+
+// RegisterTokenizer registers the tokenizer of the models whose names start
+// with modelPrefix, for WithBannedWords. The longest matching prefix wins.
+// Bucephalus has no built-in tokenizers; wrap a BPE library such as
+// tiktoken-go.
+//
+// Example:
+//
+//	enc, _ := tiktoken.GetEncoding("o200k_base")
+//	llm.RegisterTokenizer("gpt-4o", llm.TokenizerFunc(func(text string) []int {
+//	    return enc.Encode(text, nil, nil)
+//	}))
+func RegisterTokenizer(modelPrefix string, t Tokenizer) {
+	tokenizers.Store(modelPrefix, t)
+}
+
+// tokenizerFor returns the tokenizer registered for model, if any.
+func tokenizerFor(model string) (Tokenizer, bool) {
+	var (
+		best    Tokenizer
+		bestLen = -1
+	)
+	tokenizers.Range(func(key, value any) bool {
+		prefix := key.(string)
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = value.(Tokenizer), len(prefix)
+		}
+		return true
+	})
+	return best, best != nil
+}
+
+// WithLogitBias adjusts the likelihood of tokens, given by their IDs in the
+// model's vocabulary (as decimal strings), from -100 (banned) to 100
+// (exclusively selected). Biases are sent to providers that support them
+// (OpenAI and OpenAI-compatible servers) and ignored by the others.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, "Describe the product.",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithLogitBias(map[string]float64{"1734": -100}),
+//	)
+func WithLogitBias(bias map[string]float64) Option {
+	return func(c *callConfig) {
+		if c.logitBias == nil {
+			c.logitBias = make(map[string]float64, len(bias))
+		}
+		for token, value := range bias {
+			c.logitBias[token] = value
+		}
+	}
+}
+
+// WithBannedWords bans words from the output with logit biases (see
+// WithLogitBias). The words are tokenized with the tokenizer of the model
+// registered with RegisterTokenizer, with and without a leading space and
+// capitalized; the call fails if the model has no tokenizer. Words that are
+// several tokens long are banned by their first token, which also bans
+// other words starting with that token, so prefer single-token words.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, "Write a product description.",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithBannedWords("revolutionary", "synergy"),
+//	)
+func WithBannedWords(words ...string) Option {
+	return func(c *callConfig) {
+		c.bannedWords = append(c.bannedWords, words...)
+	}
+}
+
+// setLogitBias sets the logit biases of req from WithLogitBias and
+// WithBannedWords. Explicit biases take precedence over banned words.
+func (c *callConfig) setLogitBias(req *provider.Request) error {
+	if len(c.logitBias) == 0 && len(c.bannedWords) == 0 {
+		return nil
+	}
+	bias := make(map[string]float64, len(c.logitBias))
+	if len(c.bannedWords) > 0 {
+		tokenizer, ok := tokenizerFor(c.model)
+		if !ok {
+			return fmt.Errorf("banning words: no tokenizer registered for model %q: use RegisterTokenizer", c.model)
+		}
+		for _, word := range c.bannedWords {
+			for _, variant := range wordVariants(word) {
+				if tokens := tokenizer.Encode(variant); len(tokens) > 0 {
+					bias[strconv.Itoa(tokens[0])] = bannedTokenBias
+				}
+			}
+		}
+	}
+	for token, value := range c.logitBias {
+		bias[token] = value
+	}
+	req.LogitBias = bias
+	return nil
+}
+
+// wordVariants returns the forms of word that a model may generate: as is
+// and capitalized, at the start of the text and after a space.
+func wordVariants(word string) []string {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return nil
+	}
+	variants := []string{word, " " + word}
+	r, size := utf8.DecodeRuneInString(word)
+	if upper := unicode.ToUpper(r); upper != r {
+		capitalized := string(upper) + word[size:]
+		variants = append(variants, capitalized, " "+capitalized)
+	}
+	return variants
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// biasProvider records the logit biases of the last call.
+type biasProvider struct {
+	bias *map[string]float64
+}
+
+func (p biasProvider) Name() string { return "bias" }
+
+func (p biasProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	*p.bias = req.LogitBias
+	return &provider.Response{Content: "ok"}, nil
+}
+
+// letterTokenizer encodes each byte as a token whose ID is the byte value.
+var letterTokenizer = TokenizerFunc(func(text string) []int {
+	tokens := make([]int, len(text))
+	for i := range len(text) {
+		tokens[i] = int(text[i])
+	}
+	return tokens
+})
+
+func TestWithBannedWords(t *testing.T) {
+	var sent map[string]float64
+	provider.RegisterInstance("bias", biasProvider{bias: &sent})
+	t.Cleanup(func() { provider.Reset("bias") })
+	RegisterTokenizer("letters", letterTokenizer)
+	RegisterTokenizer("letters-upper", TokenizerFunc(func(text string) []int {
+		return letterTokenizer(strings.ToUpper(text))
+	}))
+
+	_, err := Call(context.Background(), "Hello", WithProvider("bias"), WithModel("letters-1"),
+		WithBannedWords("xyz"), WithLogitBias(map[string]float64{"32": 5, "1000": -50}))
+	require.NoError(t, err)
+	// "xyz", " xyz", "Xyz", " Xyz" start with tokens "x", " ", and "X"; the
+	// explicit bias of " " wins
+	assert.Equal(t, map[string]float64{"120": -100, "88": -100, "32": 5, "1000": -50}, sent)
+
+	// The longest registered prefix selects the tokenizer
+	_, err = Call(context.Background(), "Hello", WithProvider("bias"), WithModel("letters-upper-2"), WithBannedWords("x"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"88": -100, "32": -100}, sent)
+
+	_, err = Call(context.Background(), "Hello", WithProvider("bias"), WithModel("unknown"), WithBannedWords("x"))
+	assert.ErrorContains(t, err, `no tokenizer registered for model "unknown"`)
+
+	_, err = Call(context.Background(), "Hello", WithProvider("bias"), WithModel("unknown"))
+	require.NoError(t, err)
+	assert.Nil(t, sent)
+}
+
+func TestWordVariants(t *testing.T) {
+	assert.Equal(t, []string{"été", " été", "Été", " Été"}, wordVariants(" été "))
+	assert.Equal(t, []string{"42", " 42"}, wordVariants("42"))
+	assert.Empty(t, wordVariants(" "))
+}
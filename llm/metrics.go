@@ -0,0 +1,404 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// MetricsRecorder receives metrics of LLM calls, tool executions, and agent
+// turns, for dashboards of LLM usage. Implementations must be safe for
+// concurrent use. MetricSet is the default implementation; implement the
+// interface to forward the metrics to another system.
+type MetricsRecorder interface {
+	// RecordRequest records a finished provider call or stream.
+	RecordRequest(m RequestMetrics)
+	// RecordToolExecution records a tool execution.
+	RecordToolExecution(tool string, duration time.Duration, err error)
+	// RecordAgentTurn records an LLM call of an agent.
+	RecordAgentTurn(agent string)
+}
+
+// RequestMetrics are the metrics of a provider call.
+type RequestMetrics struct {
+	Provider string
+	Model    string
+	User     string // The user set by WithUser, if any
+	Status   string // "ok", the HTTP status code of the error (e.g. "429"), "canceled", or "error"
+	Stream   bool
+	Duration time.Duration
+	Usage    Usage
+
+	// TimeToFirstToken is the time until the first chunk of a stream, or
+	// zero for calls that are not streamed.
+	TimeToFirstToken time.Duration
+}
+
+// metricsRecorder holds the recorder set by SetMetrics.
+type metricsRecorder struct{ MetricsRecorder }
+
+var defaultMetrics atomic.Pointer[metricsRecorder]
+
+// SetMetrics sets the recorder of the metrics of all calls, tool executions,
+// and agent turns. By default, no metrics are recorded; pass nil to stop
+// recording.
+//
+// Example:
+//
+//	metrics := llm.NewMetricSet()
+//	llm.SetMetrics(metrics)
+//	metrics.Publish("llm")                     // expvar, at /debug/vars
+//	http.Handle("/metrics", metrics)            // Prometheus text format
+func SetMetrics(m MetricsRecorder) {
+	if m == nil {
+		defaultMetrics.Store(nil)
+		return
+	}
+	defaultMetrics.Store(&metricsRecorder{m})
+}
+
+// Metrics returns the recorder set by SetMetrics, or nil if there is none.
+func Metrics() MetricsRecorder {
+	if m := defaultMetrics.Load(); m != nil {
+		return m.MetricsRecorder
+	}
+	return nil
+}
+
+// recordRequest records the metrics of a provider call, if metrics are
+// recorded, and its outcome in the call's experiment, if any.
+func (c *callConfig) recordRequest(resp *provider.Response, err error, stream bool, duration, ttft time.Duration) {
+	c.recordExperiment(resp, err, duration)
+	m := Metrics()
+	if m == nil {
+		return
+	}
+	m.RecordRequest(RequestMetrics{
+		Provider:         c.providerName,
+		Model:            c.model,
+		User:             c.user,
+		Status:           errorStatus(err),
+		Stream:           stream,
+		Duration:         duration,
+		TimeToFirstToken: ttft,
+		Usage:            responseUsage(resp),
+	})
+}
+
+// errorStatus returns the status label of a call that returned err.
+func errorStatus(err error) string {
+	var statusErr provider.StatusError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &statusErr) && statusErr.HTTPStatus() != 0:
+		return strconv.Itoa(statusErr.HTTPStatus())
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the duration
+// histograms of a MetricSet.
+var DefaultDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// MetricSet is a MetricsRecorder that keeps counters and histograms in
+// memory and exposes them with expvar (Publish) and in the Prometheus text
+// format (ServeHTTP, WritePrometheus):
+//
+//   - llm_requests_total{provider,model,status}
+//   - llm_tokens_total{provider,model,type} (type "prompt" or "completion")
+//   - llm_request_duration_seconds{provider,model} (histogram)
+//   - llm_time_to_first_token_seconds{provider,model} (histogram, streams)
+//   - llm_tool_duration_seconds{tool,status} (histogram)
+//   - llm_agent_turns_total{agent}
+type MetricSet struct {
+	mu         sync.Mutex
+	requests   *counterVec
+	tokens     *counterVec
+	latency    *histogramVec
+	firstToken *histogramVec
+	tools      *histogramVec
+	turns      *counterVec
+	users      map[string]Usage
+}
+
+// NewMetricSet returns an empty MetricSet.
+func NewMetricSet() *MetricSet {
+	return &MetricSet{
+		requests:   newCounterVec("llm_requests_total", "LLM requests by provider, model, and status.", "provider", "model", "status"),
+		tokens:     newCounterVec("llm_tokens_total", "LLM tokens by provider, model, and type.", "provider", "model", "type"),
+		latency:    newHistogramVec("llm_request_duration_seconds", "LLM request duration.", "provider", "model"),
+		firstToken: newHistogramVec("llm_time_to_first_token_seconds", "Time to the first chunk of LLM streams.", "provider", "model"),
+		tools:      newHistogramVec("llm_tool_duration_seconds", "Tool execution duration by tool and status.", "tool", "status"),
+		turns:      newCounterVec("llm_agent_turns_total", "Agent turns by agent.", "agent"),
+		users:      make(map[string]Usage),
+	}
+}
+
+// RecordRequest implements MetricsRecorder.
+func (s *MetricSet) RecordRequest(m RequestMetrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests.add(1, m.Provider, m.Model, m.Status)
+	if m.Usage.PromptTokens > 0 {
+		s.tokens.add(float64(m.Usage.PromptTokens), m.Provider, m.Model, "prompt")
+	}
+	if m.Usage.CompletionTokens > 0 {
+		s.tokens.add(float64(m.Usage.CompletionTokens), m.Provider, m.Model, "completion")
+	}
+	s.latency.observe(m.Duration.Seconds(), m.Provider, m.Model)
+	if m.TimeToFirstToken > 0 {
+		s.firstToken.observe(m.TimeToFirstToken.Seconds(), m.Provider, m.Model)
+	}
+	if m.User != "" {
+		s.users[m.User] = addUsage(s.users[m.User], m.Usage)
+	}
+}
+
+// UsageByUser returns the token usage of the calls made with WithUser,
+// by user. Users are not metric labels, to keep the number of series of the
+// exported metrics bounded.
+//
+// Example:
+//
+//	for user, usage := range metrics.UsageByUser() {
+//	    fmt.Println(user, usage.TotalTokens)
+//	}
+func (s *MetricSet) UsageByUser() map[string]Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.users)
+}
+
+// RecordToolExecution implements MetricsRecorder.
+func (s *MetricSet) RecordToolExecution(tool string, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	s.tools.observe(duration.Seconds(), tool, status)
+}
+
+// RecordAgentTurn implements MetricsRecorder.
+func (s *MetricSet) RecordAgentTurn(agent string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns.add(1, agent)
+}
+
+// Publish publishes the metrics with expvar under name, so they are served
+// as JSON at /debug/vars. It panics if name is already published.
+func (s *MetricSet) Publish(name string) {
+	expvar.Publish(name, expvar.Func(s.snapshot))
+}
+
+// snapshot returns the metrics as a map from metric name to series.
+func (s *MetricSet) snapshot() any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]any)
+	for _, c := range s.counters() {
+		out[c.name] = c.snapshot()
+	}
+	for _, h := range s.histograms() {
+		out[h.name] = h.snapshot()
+	}
+	return out
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (s *MetricSet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = s.WritePrometheus(w)
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text format.
+func (s *MetricSet) WritePrometheus(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var b strings.Builder
+	for _, c := range s.counters() {
+		c.writePrometheus(&b)
+	}
+	for _, h := range s.histograms() {
+		h.writePrometheus(&b)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (s *MetricSet) counters() []*counterVec {
+	return []*counterVec{s.requests, s.tokens, s.turns}
+}
+
+func (s *MetricSet) histograms() []*histogramVec {
+	return []*histogramVec{s.latency, s.firstToken, s.tools}
+}
+
+// series is a labeled value of a metric.
+type series struct {
+	labels []string
+	value  float64
+}
+
+// counterVec is a counter metric with labels.
+type counterVec struct {
+	name, help string
+	labelNames []string
+	series     map[string]*series
+}
+
+func newCounterVec(name, help string, labelNames ...string) *counterVec {
+	return &counterVec{name: name, help: help, labelNames: labelNames, series: make(map[string]*series)}
+}
+
+func (c *counterVec) add(v float64, labels ...string) {
+	key := strings.Join(labels, "\x00")
+	s, ok := c.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+func (c *counterVec) snapshot() []map[string]any {
+	out := make([]map[string]any, 0, len(c.series))
+	for _, key := range sortedSeriesKeys(c.series) {
+		s := c.series[key]
+		m := labelMap(c.labelNames, s.labels)
+		m["value"] = s.value
+		out = append(out, m)
+	}
+	return out
+}
+
+func (c *counterVec) writePrometheus(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedSeriesKeys(c.series) {
+		s := c.series[key]
+		fmt.Fprintf(b, "%s%s %s\n", c.name, formatLabels(c.labelNames, s.labels), formatFloat(s.value))
+	}
+}
+
+// histogram is a labeled distribution of a metric.
+type histogram struct {
+	labels []string
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// histogramVec is a histogram metric with labels.
+type histogramVec struct {
+	name, help string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*histogram
+}
+
+func newHistogramVec(name, help string, labelNames ...string) *histogramVec {
+	return &histogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    DefaultDurationBuckets,
+		series:     make(map[string]*histogram),
+	}
+}
+
+func (h *histogramVec) observe(v float64, labels ...string) {
+	key := strings.Join(labels, "\x00")
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogramVec) snapshot() []map[string]any {
+	out := make([]map[string]any, 0, len(h.series))
+	for _, key := range sortedSeriesKeys(h.series) {
+		s := h.series[key]
+		m := labelMap(h.labelNames, s.labels)
+		m["count"] = s.count
+		m["sum"] = s.sum
+		buckets := make(map[string]uint64, len(h.buckets))
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			buckets[formatFloat(le)] = cumulative
+		}
+		m["buckets"] = buckets
+		out = append(out, m)
+	}
+	return out
+}
+
+func (h *histogramVec) writePrometheus(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedSeriesKeys(h.series) {
+		s := h.series[key]
+		names := append(slices.Clone(h.labelNames), "le")
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, formatLabels(names, append(slices.Clone(s.labels), formatFloat(le))), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, formatLabels(names, append(slices.Clone(s.labels), "+Inf")), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", h.name, formatLabels(h.labelNames, s.labels), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, formatLabels(h.labelNames, s.labels), s.count)
+	}
+}
+
+func sortedSeriesKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func labelMap(names, values []string) map[string]any {
+	m := make(map[string]any, len(names)+1)
+	for i, name := range names {
+		m[name] = values[i]
+	}
+	return m
+}
+
+// formatLabels formats labels as {name="value",...} with escaped values.
+func formatLabels(names, values []string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + strconv.Quote(values[i])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
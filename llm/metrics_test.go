@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+func TestSetMetrics(t *testing.T) {
+	metrics := NewMetricSet()
+	SetMetrics(metrics)
+	t.Cleanup(func() { SetMetrics(nil) })
+
+	_, err := Call(context.Background(), "Hello", registerContent(t, "Hi")...)
+	require.NoError(t, err)
+
+	provider.Register("error", func() (provider.Provider, error) { return errorProvider{}, nil })
+	_, err = Call(context.Background(), "Hello", WithProvider("error"), WithModel("m"))
+	require.Error(t, err)
+
+	type input struct{}
+	tool, err := NewTool("fail", "Fails", func(ctx context.Context, in input) (string, error) {
+		return "", errors.New("failed")
+	})
+	require.NoError(t, err)
+	registry := NewToolRegistry()
+	registry.Register(tool)
+	_, err = ExecuteToolCalls(context.Background(), []ToolCall{{ID: "1", Name: "fail", Arguments: "{}"}}, registry)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE llm_requests_total counter\n")
+	assert.Contains(t, body, `llm_requests_total{provider="content-TestSetMetrics",model="m",status="ok"} 1`)
+	assert.Contains(t, body, `llm_requests_total{provider="error",model="m",status="error"} 1`)
+	assert.Contains(t, body, `llm_request_duration_seconds_count{provider="error",model="m"} 1`)
+	assert.Contains(t, body, `llm_tool_duration_seconds_bucket{tool="fail",status="error",le="+Inf"} 1`)
+}
+
+func TestMetricSet(t *testing.T) {
+	metrics := NewMetricSet()
+	metrics.RecordRequest(RequestMetrics{
+		Provider:         "openai",
+		Model:            "gpt-4o",
+		Status:           "ok",
+		Stream:           true,
+		Duration:         3 * time.Second,
+		TimeToFirstToken: 300 * time.Millisecond,
+		Usage:            Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	})
+	metrics.RecordRequest(RequestMetrics{Provider: "openai", Model: "gpt-4o", Status: "429", Duration: 200 * time.Millisecond})
+	metrics.RecordAgentTurn("reviewer")
+	metrics.RecordAgentTurn("reviewer")
+
+	var b bytes.Buffer
+	require.NoError(t, metrics.WritePrometheus(&b))
+	body := b.String()
+	assert.Contains(t, body, `llm_requests_total{provider="openai",model="gpt-4o",status="429"} 1`)
+	assert.Contains(t, body, `llm_tokens_total{provider="openai",model="gpt-4o",type="prompt"} 10`)
+	assert.Contains(t, body, `llm_tokens_total{provider="openai",model="gpt-4o",type="completion"} 5`)
+	assert.Contains(t, body, `llm_request_duration_seconds_bucket{provider="openai",model="gpt-4o",le="0.25"} 1`)
+	assert.Contains(t, body, `llm_request_duration_seconds_bucket{provider="openai",model="gpt-4o",le="5"} 2`)
+	assert.Contains(t, body, `llm_request_duration_seconds_sum{provider="openai",model="gpt-4o"} 3.2`)
+	assert.Contains(t, body, `llm_time_to_first_token_seconds_count{provider="openai",model="gpt-4o"} 1`)
+	assert.Contains(t, body, `llm_agent_turns_total{agent="reviewer"} 2`)
+
+	snapshot := metrics.snapshot().(map[string]any)
+	turns := snapshot["llm_agent_turns_total"].([]map[string]any)
+	require.Len(t, turns, 1)
+	assert.Equal(t, 2.0, turns[0]["value"])
+}
+
+func TestErrorStatus(t *testing.T) {
+	assert.Equal(t, "ok", errorStatus(nil))
+	assert.Equal(t, "error", errorStatus(errors.New("boom")))
+	assert.Equal(t, "canceled", errorStatus(context.Canceled))
+	assert.Equal(t, "429", errorStatus(&ProviderError{Provider: "openai", StatusCode: 429}))
+}
+
+// userProvider records the user of the last call.
+type userProvider struct {
+	user *string
+}
+
+func (p userProvider) Name() string { return "user" }
+
+func (p userProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	*p.user = req.User
+	return &provider.Response{Content: "ok", Usage: provider.Usage{PromptTokens: 4, CompletionTokens: 1, TotalTokens: 5}}, nil
+}
+
+func TestWithUser(t *testing.T) {
+	var sent string
+	provider.RegisterInstance("user", userProvider{user: &sent})
+	t.Cleanup(func() { provider.Reset("user") })
+	metrics := NewMetricSet()
+	SetMetrics(metrics)
+	t.Cleanup(func() { SetMetrics(nil) })
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	for range 2 {
+		_, err := Call(context.Background(), "Hello", WithProvider("user"), WithModel("m"), WithUser("u_1"), WithLogger(logger))
+		require.NoError(t, err)
+	}
+	_, err := Call(context.Background(), "Hello", WithProvider("user"), WithModel("m"))
+	require.NoError(t, err)
+
+	assert.Empty(t, sent)
+	assert.Equal(t, map[string]Usage{"u_1": {PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10}}, metrics.UsageByUser())
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "u_1", records[0]["user"])
+
+	_, err = Call(context.Background(), "Hello", WithProvider("user"), WithModel("m"), WithUser("u_2"))
+	require.NoError(t, err)
+	assert.Equal(t, "u_2", sent)
+
+	// Users are not metric labels
+	var out bytes.Buffer
+	require.NoError(t, metrics.WritePrometheus(&out))
+	assert.NotContains(t, out.String(), "u_1")
+}
@@ -2,7 +2,7 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 
 	"github.com/i2y/bucephalus/provider"
 	"github.com/i2y/bucephalus/schema"
@@ -55,6 +55,65 @@ func (m *Model) CallMessages(ctx context.Context, messages []Message, opts ...Op
 	return CallMessages(ctx, messages, allOpts...)
 }
 
+// ListModels returns the models available from the named provider, for
+// model pickers or to check configured model names at startup. The provider
+// must implement provider.ModelLister.
+//
+// Example:
+//
+//	models, err := llm.ListModels(ctx, "anthropic")
+//	if err != nil {
+//	    return err
+//	}
+//	for _, m := range models {
+//	    fmt.Println(m.ID, m.DisplayName)
+//	}
+func ListModels(ctx context.Context, providerName string) ([]provider.ModelInfo, error) {
+	p, err := provider.Get(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("getting provider: %w", err)
+	}
+	lister, ok := p.(provider.ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("provider %q does not support listing models", providerName)
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing models: %w", err)
+	}
+	return models, nil
+}
+
+// CheckProvider checks that the named provider is configured and reachable,
+// e.g. that its API key is set and accepted, at startup or in readiness
+// probes. It uses the provider's provider.HealthChecker, or lists its models
+// if it is a provider.ModelLister, and otherwise only checks that it can be
+// created.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+//	defer cancel()
+//	if err := llm.CheckProvider(ctx, "openai"); err != nil {
+//	    log.Fatalf("openai unavailable: %v", err)
+//	}
+func CheckProvider(ctx context.Context, providerName string) error {
+	p, err := provider.Get(providerName)
+	if err != nil {
+		return fmt.Errorf("getting provider: %w", err)
+	}
+	switch p := p.(type) {
+	case provider.HealthChecker:
+		err = p.Ping(ctx)
+	case provider.ModelLister:
+		_, err = p.ListModels(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("checking provider %q: %w", providerName, err)
+	}
+	return nil
+}
+
 // mergeOptions combines base options with per-call options.
 func (m *Model) mergeOptions(opts []Option) []Option {
 	allOpts := make([]Option, 0, len(m.baseOpts)+len(opts)+2)
@@ -83,11 +142,7 @@ func callParseReflect(ctx context.Context, prompt string, target any, opts ...Op
 		return err
 	}
 
-	cfg.jsonSchema = &provider.JSONSchema{
-		Name:   "response",
-		Strict: true,
-		Schema: jsonSchema,
-	}
+	cfg.setJSONSchema("response", jsonSchema)
 
 	p, err := provider.Get(cfg.providerName)
 	if err != nil {
@@ -96,10 +151,10 @@ func callParseReflect(ctx context.Context, prompt string, target any, opts ...Op
 
 	req := cfg.buildRequest(prompt)
 
-	resp, err := p.Call(ctx, req)
+	resp, err := cfg.call(ctx, p, req)
 	if err != nil {
-		return err
+		return explainSchemaError(err, jsonSchema)
 	}
 
-	return json.Unmarshal([]byte(resp.Content), target)
+	return parseResponse(resp.Content, target, jsonSchema, "response")
 }
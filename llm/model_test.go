@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// listingProvider lists a fixed set of models.
+type listingProvider struct {
+	contentProvider
+}
+
+func (listingProvider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	return []provider.ModelInfo{{ID: "small"}, {ID: "large", InputTokenLimit: 200000}}, nil
+}
+
+func TestListModels(t *testing.T) {
+	provider.Register("listing", func() (provider.Provider, error) { return listingProvider{}, nil })
+	provider.Register("not-listing", func() (provider.Provider, error) { return contentProvider{}, nil })
+	ctx := context.Background()
+
+	models, err := ListModels(ctx, "listing")
+	require.NoError(t, err)
+	assert.Equal(t, []provider.ModelInfo{{ID: "small"}, {ID: "large", InputTokenLimit: 200000}}, models)
+
+	_, err = ListModels(ctx, "not-listing")
+	assert.ErrorContains(t, err, `provider "not-listing" does not support listing models`)
+
+	_, err = ListModels(ctx, "unknown")
+	assert.ErrorContains(t, err, "unknown provider")
+}
+
+// pingingProvider fails its health check with err.
+type pingingProvider struct {
+	contentProvider
+	err error
+}
+
+func (p pingingProvider) Ping(ctx context.Context) error { return p.err }
+
+func TestCheckProvider(t *testing.T) {
+	provider.RegisterInstance("healthy", pingingProvider{})
+	provider.RegisterInstance("unhealthy", pingingProvider{err: errors.New("invalid API key")})
+	provider.RegisterInstance("listing", listingProvider{})
+	provider.Register("unconfigured", func() (provider.Provider, error) { return nil, errors.New("API key required") })
+	ctx := context.Background()
+
+	assert.NoError(t, CheckProvider(ctx, "healthy"))
+	assert.NoError(t, CheckProvider(ctx, "listing"))
+	assert.EqualError(t, CheckProvider(ctx, "unhealthy"), `checking provider "unhealthy": invalid API key`)
+	assert.ErrorContains(t, CheckProvider(ctx, "unconfigured"), "API key required")
+}
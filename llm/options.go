@@ -2,6 +2,8 @@ package llm
 
 import (
 	"encoding/json"
+	"log/slog"
+	"net/http"
 
 	"github.com/i2y/bucephalus/provider"
 )
@@ -11,31 +13,55 @@ type Option func(*callConfig)
 
 // callConfig holds all configuration for a call.
 type callConfig struct {
-	providerName  string
-	model         string
-	temperature   *float64
-	maxTokens     *int
-	topP          *float64
-	topK          *int
-	seed          *int
-	stopSequences []string
-	systemMessage string
-	tools         []Tool
-	messages      []Message
-	jsonSchema    *provider.JSONSchema
+	providerName      string
+	model             string
+	temperature       *float64
+	maxTokens         *int
+	topP              *float64
+	topK              *int
+	seed              *int
+	stopSequences     []string
+	systemMessage     string
+	systemSections    []SystemSection
+	systemMaxTokens   int
+	tools             []Tool
+	messages          []Message
+	jsonSchema        *provider.JSONSchema
+	schemaOptions     []SchemaOption
+	extractOptions    []ExtractOption
+	summarizeOptions  []SummarizeOption
+	translateOptions  []TranslateOption
+	routeOptions      []RouteOption
+	transcribeOptions []TranscribeOption
+	speakOptions      []SpeakOption
+	quota             *Quota
+	rateLimit         *rateLimit
+	toolChoice        *provider.ToolChoice
+	headers           http.Header
+	user              string
+	logitBias         map[string]float64
+	bannedWords       []string
+	rawMessages       bool
+	experiment        *Experiment
+	variant           string // The variant of experiment chosen for the call
+	logger            *slog.Logger
+	autoTruncate      *truncateConfig
+	truncation        *Truncation // How the messages of the call were truncated, if they were
 }
 
 func newCallConfig() *callConfig {
-	return &callConfig{}
+	return &callConfig{providerName: provider.DefaultName()}
 }
 
 func (c *callConfig) apply(opts ...Option) {
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.applyExperiment()
 }
 
 // WithProvider sets the LLM provider (e.g., "openai", "anthropic").
+// It defaults to the provider set by provider.SetDefault.
 func WithProvider(name string) Option {
 	return func(c *callConfig) {
 		c.providerName = name
@@ -96,7 +122,9 @@ func WithStopSequences(seqs ...string) Option {
 	}
 }
 
-// WithSystemMessage sets a system message.
+// WithSystemMessage sets the base system message, replacing the one set by
+// a previous WithSystemMessage. Use WithSystemSection or WithSystemPrompt to
+// layer more instructions over it.
 func WithSystemMessage(msg string) Option {
 	return func(c *callConfig) {
 		c.systemMessage = msg
@@ -110,6 +138,34 @@ func WithTools(tools ...Tool) Option {
 	}
 }
 
+// ToolChoiceMode is the tool calling mode set by WithToolChoice.
+type ToolChoiceMode = provider.ToolChoiceMode
+
+// Tool calling modes.
+const (
+	ToolChoiceAuto     = provider.ToolChoiceAuto
+	ToolChoiceRequired = provider.ToolChoiceRequired
+	ToolChoiceNone     = provider.ToolChoiceNone
+)
+
+// WithToolChoice controls whether the model calls tools (default:
+// ToolChoiceAuto, the model decides). With ToolChoiceRequired, tools
+// restricts the call to the named tools.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, "What's the weather in Paris?",
+//	    llm.WithProvider("anthropic"),
+//	    llm.WithModel("claude-sonnet-4-5"),
+//	    llm.WithTools(weatherTool),
+//	    llm.WithToolChoice(llm.ToolChoiceRequired, "get_weather"),
+//	)
+func WithToolChoice(mode ToolChoiceMode, tools ...string) Option {
+	return func(c *callConfig) {
+		c.toolChoice = &provider.ToolChoice{Mode: mode, Tools: tools}
+	}
+}
+
 // WithMessages sets the conversation history.
 // This is useful for multi-turn conversations with Call.
 func WithMessages(msgs ...Message) Option {
@@ -118,6 +174,112 @@ func WithMessages(msgs ...Message) Option {
 	}
 }
 
+// WithHeader adds an HTTP header to the provider API request, such as the
+// organization, project, or trace headers required by API gateways. It
+// replaces the provider's own header of the same name.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, "Hello",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithHeader("OpenAI-Project", "proj_123"),
+//	    llm.WithHeader("X-Trace-Id", traceID),
+//	)
+func WithHeader(key, value string) Option {
+	return func(c *callConfig) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Add(key, value)
+	}
+}
+
+// WithUser sets the ID of the end user the call is made for, so providers
+// can attribute usage and monitor abuse per user: it is sent as "user" to
+// OpenAI and as "metadata.user_id" to Anthropic (Gemini has no such field).
+// It is also logged with the call and passed to the MetricsRecorder, which
+// aggregates usage by user (see MetricSet.UsageByUser). Use an opaque ID,
+// such as a hash of the account ID, rather than an email address.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, question,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithUser(userID),
+//	)
+func WithUser(id string) Option {
+	return func(c *callConfig) {
+		c.user = id
+	}
+}
+
+// WithRawMessages sends the messages to the provider as they are. By
+// default, providers fix common problems of hand-built histories that their
+// APIs reject: empty assistant messages are dropped, and Anthropic and
+// Gemini merge consecutive messages of the same role so that user and
+// assistant turns alternate.
+func WithRawMessages() Option {
+	return func(c *callConfig) {
+		c.rawMessages = true
+	}
+}
+
+// SchemaOption configures how the structured output schema is sent to the
+// provider. See WithSchemaOptions.
+type SchemaOption func(*provider.JSONSchema)
+
+// SchemaStrict sets whether providers that support it (OpenAI) enforce the
+// schema strictly (default: true). Strict mode makes every property required
+// and forbids additional properties; turn it off for schemas with optional
+// properties or maps.
+func SchemaStrict(strict bool) SchemaOption {
+	return func(s *provider.JSONSchema) {
+		s.Strict = strict
+	}
+}
+
+// SchemaAdditionalProperties sets how additionalProperties is sent on the
+// schema's objects (default: provider.AdditionalPropertiesAuto, what the
+// provider requires).
+func SchemaAdditionalProperties(mode provider.AdditionalProperties) SchemaOption {
+	return func(s *provider.JSONSchema) {
+		s.AdditionalProperties = mode
+	}
+}
+
+// WithSchemaOptions configures the structured output schema of CallParse,
+// CallMessagesParse, and CallParseDynamic.
+//
+// Example:
+//
+//	resp, err := llm.CallParse[Profile](ctx, "Describe the user",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithSchemaOptions(
+//	        llm.SchemaStrict(false),
+//	        llm.SchemaAdditionalProperties(provider.AdditionalPropertiesOmit),
+//	    ),
+//	)
+func WithSchemaOptions(opts ...SchemaOption) Option {
+	return func(c *callConfig) {
+		c.schemaOptions = append(c.schemaOptions, opts...)
+	}
+}
+
+// setJSONSchema requests structured output matching jsonSchema.
+func (c *callConfig) setJSONSchema(name string, jsonSchema json.RawMessage) {
+	c.jsonSchema = &provider.JSONSchema{
+		Name:   name,
+		Strict: true,
+		Schema: jsonSchema,
+	}
+	for _, opt := range c.schemaOptions {
+		opt(c.jsonSchema)
+	}
+}
+
 // buildRequest creates a provider.Request from the config and prompt.
 func (c *callConfig) buildRequest(prompt string) *provider.Request {
 	req := &provider.Request{
@@ -129,15 +291,14 @@ func (c *callConfig) buildRequest(prompt string) *provider.Request {
 		Seed:          c.seed,
 		StopSequences: c.stopSequences,
 		JSONSchema:    c.jsonSchema,
+		ToolChoice:    c.toolChoice,
+		Headers:       c.headers,
+		User:          c.user,
+		RawMessages:   c.rawMessages,
 	}
 
-	// Add system message if present
-	if c.systemMessage != "" {
-		req.Messages = append(req.Messages, provider.Message{
-			Role:    provider.RoleSystem,
-			Content: c.systemMessage,
-		})
-	}
+	// Add the system messages, if any
+	req.Messages = append(req.Messages, c.systemMessages()...)
 
 	// Add conversation history
 	req.Messages = append(req.Messages, c.messages...)
@@ -174,9 +335,17 @@ func (c *callConfig) buildRequestFromMessages(messages []Message) *provider.Requ
 		Seed:          c.seed,
 		StopSequences: c.stopSequences,
 		JSONSchema:    c.jsonSchema,
-		Messages:      messages,
+		ToolChoice:    c.toolChoice,
+		Headers:       c.headers,
+		User:          c.user,
+		RawMessages:   c.rawMessages,
 	}
 
+	// Add the system messages, if any
+	req.Messages = append(req.Messages, c.systemMessages()...)
+
+	req.Messages = append(req.Messages, messages...)
+
 	// Add tools
 	for _, tool := range c.tools {
 		params, _ := json.Marshal(tool.Parameters())
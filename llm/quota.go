@@ -0,0 +1,296 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a call would exceed a Quota.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// quotaWindow is the window of the per-minute limits.
+const quotaWindow = time.Minute
+
+// CostFunc returns the cost in USD of usage with model.
+type CostFunc func(model string, usage Usage) float64
+
+// Quota limits the requests, tokens, and cost of LLM calls that share it.
+// A single Quota can be used from many goroutines, runners, and call sites,
+// e.g. by all agents of a multi-user server running on one API key.
+//
+// Per-minute limits are enforced over a sliding window. By default, a call
+// that would exceed them waits until enough budget is free (or its context
+// is done); with WithQuotaNoWait it fails with ErrQuotaExceeded instead.
+// Calls after the daily cost cap is reached always fail with ErrQuotaExceeded.
+//
+// Example:
+//
+//	quota := llm.NewQuota(
+//	    llm.WithRequestsPerMinute(50),
+//	    llm.WithTokensPerMinute(40000),
+//	)
+//
+//	resp, err := llm.Call(ctx, "Hello",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithQuota(quota),
+//	)
+type Quota struct {
+	mu sync.Mutex
+
+	requestsPerMinute int
+	tokensPerMinute   int
+	dailyCostLimit    float64
+	cost              CostFunc
+	noWait            bool
+
+	requests  []time.Time   // Start times of requests in the window
+	tokens    []quotaTokens // Tokens used in the window
+	nextID    int
+	day       string // Day of dailyCost (YYYY-MM-DD, local time)
+	dailyCost float64
+
+	now func() time.Time
+}
+
+// quotaTokens records tokens used at a point in time.
+type quotaTokens struct {
+	id int
+	at time.Time
+	n  int
+}
+
+// QuotaOption configures a Quota.
+type QuotaOption func(*Quota)
+
+// WithRequestsPerMinute limits the number of calls per minute.
+func WithRequestsPerMinute(n int) QuotaOption {
+	return func(q *Quota) {
+		q.requestsPerMinute = n
+	}
+}
+
+// WithTokensPerMinute limits the number of tokens (prompt and completion) per minute.
+// Before a call, its prompt tokens are estimated with EstimateMessagesTokens;
+// after the call, the estimate is replaced by the usage reported by the provider.
+func WithTokensPerMinute(n int) QuotaOption {
+	return func(q *Quota) {
+		q.tokensPerMinute = n
+	}
+}
+
+// WithDailyCostLimit limits the cost in USD per calendar day (local time).
+// cost computes the cost of each call from its model and usage.
+func WithDailyCostLimit(usd float64, cost CostFunc) QuotaOption {
+	return func(q *Quota) {
+		q.dailyCostLimit = usd
+		q.cost = cost
+	}
+}
+
+// WithQuotaNoWait makes calls that would exceed a per-minute limit fail
+// with ErrQuotaExceeded instead of waiting.
+func WithQuotaNoWait() QuotaOption {
+	return func(q *Quota) {
+		q.noWait = true
+	}
+}
+
+// NewQuota creates a quota. Limits that are not set are not enforced.
+func NewQuota(opts ...QuotaOption) *Quota {
+	q := &Quota{now: time.Now}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// WithQuota makes the call wait for (or fail on) the limits of q and record its usage in q.
+func WithQuota(q *Quota) Option {
+	return func(c *callConfig) {
+		c.quota = q
+	}
+}
+
+// QuotaUsage is a snapshot of the usage of a Quota.
+type QuotaUsage struct {
+	Requests  int     // Requests in the last minute
+	Tokens    int     // Tokens in the last minute
+	DailyCost float64 // Cost in USD today
+}
+
+// Usage returns the current usage of the quota.
+func (q *Quota) Usage() QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.expire(now)
+	return QuotaUsage{
+		Requests:  len(q.requests),
+		Tokens:    q.windowTokens(),
+		DailyCost: q.dailyCost,
+	}
+}
+
+// Acquire reserves one request and tokens estimated tokens, waiting until
+// they fit in the per-minute limits. It returns a reservation that must be
+// completed with Release once the actual usage is known.
+func (q *Quota) Acquire(ctx context.Context, model string, tokens int) (*QuotaReservation, error) {
+	for {
+		q.mu.Lock()
+		now := q.now()
+		q.expire(now)
+
+		if err := q.checkCost(now); err != nil {
+			q.mu.Unlock()
+			return nil, err
+		}
+
+		wait := q.wait(now, tokens)
+		if wait == 0 {
+			q.requests = append(q.requests, now)
+			r := &QuotaReservation{quota: q, model: model}
+			if tokens > 0 {
+				q.nextID++
+				r.id = q.nextID
+				q.tokens = append(q.tokens, quotaTokens{id: r.id, at: now, n: tokens})
+			}
+			q.mu.Unlock()
+			return r, nil
+		}
+		q.mu.Unlock()
+
+		if q.noWait {
+			return nil, fmt.Errorf("%w: per-minute limit reached (retry in %s)", ErrQuotaExceeded, wait.Round(time.Second))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// QuotaReservation is the budget reserved for a single call.
+type QuotaReservation struct {
+	quota *Quota
+	model string
+	id    int // ID of the estimated token entry, or 0 if none
+	done  bool
+}
+
+// Release replaces the estimated tokens of the reservation with the actual
+// usage of the call and adds its cost to the daily cost.
+// Pass a zero Usage if the call failed.
+func (r *QuotaReservation) Release(usage Usage) {
+	q := r.quota
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if r.done {
+		return
+	}
+	r.done = true
+
+	if r.id != 0 {
+		q.tokens = slices.DeleteFunc(q.tokens, func(t quotaTokens) bool { return t.id == r.id })
+	}
+
+	now := q.now()
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	if total > 0 {
+		q.nextID++
+		q.tokens = append(q.tokens, quotaTokens{id: q.nextID, at: now, n: total})
+	}
+
+	if q.cost != nil {
+		q.resetDay(now)
+		q.dailyCost += q.cost(r.model, usage)
+	}
+}
+
+// expire removes entries older than the window.
+func (q *Quota) expire(now time.Time) {
+	cutoff := now.Add(-quotaWindow)
+	i := 0
+	for i < len(q.requests) && !q.requests[i].After(cutoff) {
+		i++
+	}
+	q.requests = q.requests[i:]
+
+	kept := q.tokens[:0]
+	for _, t := range q.tokens {
+		if t.at.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	q.tokens = kept
+}
+
+// windowTokens returns the tokens used in the window.
+func (q *Quota) windowTokens() int {
+	n := 0
+	for _, t := range q.tokens {
+		n += t.n
+	}
+	return n
+}
+
+// wait returns how long to wait until a request with tokens fits in the
+// per-minute limits, or 0 if it fits now.
+func (q *Quota) wait(now time.Time, tokens int) time.Duration {
+	var wait time.Duration
+	if q.requestsPerMinute > 0 && len(q.requests) >= q.requestsPerMinute {
+		oldest := q.requests[len(q.requests)-q.requestsPerMinute]
+		wait = oldest.Add(quotaWindow).Sub(now)
+	}
+
+	// A call larger than the whole limit is let through once the window is empty.
+	if q.tokensPerMinute > 0 && len(q.tokens) > 0 {
+		used := q.windowTokens()
+		for _, t := range q.tokens {
+			if used+tokens <= q.tokensPerMinute {
+				break
+			}
+			used -= t.n
+			wait = max(wait, t.at.Add(quotaWindow).Sub(now))
+		}
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// checkCost returns ErrQuotaExceeded if the daily cost limit is reached.
+func (q *Quota) checkCost(now time.Time) error {
+	if q.dailyCostLimit <= 0 {
+		return nil
+	}
+	q.resetDay(now)
+	if q.dailyCost >= q.dailyCostLimit {
+		return fmt.Errorf("%w: daily cost limit of $%.2f reached", ErrQuotaExceeded, q.dailyCostLimit)
+	}
+	return nil
+}
+
+// resetDay resets the daily cost when the day changes.
+func (q *Quota) resetDay(now time.Time) {
+	day := now.Format(time.DateOnly)
+	if day != q.day {
+		q.day = day
+		q.dailyCost = 0
+	}
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// fakeClock is a manually advanced clock for quota tests.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestQuota(clock *fakeClock, opts ...QuotaOption) *Quota {
+	q := NewQuota(opts...)
+	q.now = clock.now
+	return q
+}
+
+func TestQuota_RequestsPerMinute(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)}
+	q := newTestQuota(clock, WithRequestsPerMinute(2), WithQuotaNoWait())
+	ctx := context.Background()
+
+	for range 2 {
+		r, err := q.Acquire(ctx, "m", 0)
+		require.NoError(t, err)
+		r.Release(Usage{})
+	}
+
+	_, err := q.Acquire(ctx, "m", 0)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	clock.advance(time.Minute)
+	_, err = q.Acquire(ctx, "m", 0)
+	assert.NoError(t, err)
+}
+
+func TestQuota_TokensPerMinute(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)}
+	q := newTestQuota(clock, WithTokensPerMinute(100), WithQuotaNoWait())
+	ctx := context.Background()
+
+	r, err := q.Acquire(ctx, "m", 30)
+	require.NoError(t, err)
+	assert.Equal(t, 30, q.Usage().Tokens)
+
+	// The estimate is replaced by the actual usage
+	r.Release(Usage{PromptTokens: 50, CompletionTokens: 30, TotalTokens: 80})
+	assert.Equal(t, 80, q.Usage().Tokens)
+
+	_, err = q.Acquire(ctx, "m", 30)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = q.Acquire(ctx, "m", 20)
+	assert.NoError(t, err)
+
+	clock.advance(time.Minute)
+	assert.Equal(t, 0, q.Usage().Tokens)
+
+	// A call larger than the limit is allowed once the window is empty
+	_, err = q.Acquire(ctx, "m", 500)
+	assert.NoError(t, err)
+}
+
+func TestQuota_Waits(t *testing.T) {
+	q := NewQuota(WithRequestsPerMinute(1))
+	ctx := context.Background()
+
+	_, err := q.Acquire(ctx, "m", 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Acquire(ctx, "m", 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestQuota_DailyCostLimit(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)}
+	perToken := func(model string, usage Usage) float64 {
+		return float64(usage.TotalTokens) * 0.01
+	}
+	q := newTestQuota(clock, WithDailyCostLimit(1, perToken))
+	ctx := context.Background()
+
+	r, err := q.Acquire(ctx, "m", 0)
+	require.NoError(t, err)
+	r.Release(Usage{TotalTokens: 100})
+	assert.InDelta(t, 1.0, q.Usage().DailyCost, 1e-9)
+
+	_, err = q.Acquire(ctx, "m", 0)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	clock.advance(24 * time.Hour)
+	_, err = q.Acquire(ctx, "m", 0)
+	assert.NoError(t, err)
+	assert.Zero(t, q.Usage().DailyCost)
+}
+
+// usageProvider returns a fixed response with usage.
+type usageProvider struct{}
+
+func (usageProvider) Name() string { return "usage" }
+
+func (usageProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	return &provider.Response{
+		Content:      "ok",
+		FinishReason: provider.FinishReasonStop,
+		Usage:        provider.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func TestCall_WithQuota(t *testing.T) {
+	provider.Register("quota-test", func() (provider.Provider, error) {
+		return usageProvider{}, nil
+	})
+	q := NewQuota(WithRequestsPerMinute(1), WithQuotaNoWait())
+	opts := []Option{WithProvider("quota-test"), WithModel("m"), WithQuota(q)}
+
+	_, err := Call(context.Background(), "hello", opts...)
+	require.NoError(t, err)
+	assert.Equal(t, QuotaUsage{Requests: 1, Tokens: 15}, q.Usage())
+
+	_, err = Call(context.Background(), "hello", opts...)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+}
@@ -0,0 +1,197 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimit holds the limits set by WithRateLimit.
+type rateLimit struct {
+	rps             float64
+	tokensPerMinute int
+}
+
+// WithRateLimit limits the calls to the provider and model of the call to
+// rps requests per second and tokensPerMinute tokens (prompt and completion)
+// per minute, so bursts of calls, e.g. from agents fanned out over many
+// goroutines, wait on the client instead of being rejected with 429s. A zero
+// limit is not enforced.
+//
+// The limits are token buckets shared by all calls with the same provider
+// and model in the process, whichever goroutine or runner makes them; the
+// limits of the latest call apply. Up to one second of requests (at least
+// one) and one minute of tokens can be sent in a burst. Before a call, its
+// prompt tokens are estimated with EstimateMessagesTokens; after the call,
+// the estimate is replaced by the usage reported by the provider. A call
+// waits until the buckets have enough capacity or its context is done.
+//
+// Unlike WithQuota, which enforces a budget over a sliding window and can
+// cap cost, WithRateLimit only smooths the rate of calls.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, "Hello",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithRateLimit(5, 30000),
+//	)
+func WithRateLimit(rps float64, tokensPerMinute int) Option {
+	return func(c *callConfig) {
+		c.rateLimit = &rateLimit{rps: rps, tokensPerMinute: tokensPerMinute}
+	}
+}
+
+// rateLimiters holds the rate limiter of each provider and model.
+var rateLimiters = struct {
+	sync.Mutex
+	m map[string]*rateLimiter
+}{m: make(map[string]*rateLimiter)}
+
+// rateLimiterFor returns the rate limiter of providerName and model with
+// limits l, creating it if needed.
+func rateLimiterFor(providerName, model string, l rateLimit) *rateLimiter {
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+
+	key := providerName + "/" + model
+	limiter, ok := rateLimiters.m[key]
+	if !ok {
+		limiter = newRateLimiter(l, time.Now)
+		rateLimiters.m[key] = limiter
+		return limiter
+	}
+	limiter.setLimits(l)
+	return limiter
+}
+
+// rateLimiter is a token bucket for requests and one for tokens.
+type rateLimiter struct {
+	mu       sync.Mutex
+	limits   rateLimit
+	requests float64 // Requests available
+	tokens   float64 // Tokens available; negative if calls used more than estimated
+	last     time.Time
+	now      func() time.Time
+}
+
+// newRateLimiter returns a rate limiter with full buckets.
+func newRateLimiter(l rateLimit, now func() time.Time) *rateLimiter {
+	r := &rateLimiter{now: now, last: now()}
+	r.limits = l
+	r.requests = r.requestBurst()
+	r.tokens = float64(l.tokensPerMinute)
+	return r
+}
+
+// setLimits changes the limits, keeping the available capacity within them.
+func (r *rateLimiter) setLimits(l rateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l == r.limits {
+		return
+	}
+	r.refill(r.now())
+	r.limits = l
+	r.requests = min(r.requests, r.requestBurst())
+	r.tokens = min(r.tokens, float64(l.tokensPerMinute))
+}
+
+// requestBurst returns the capacity of the request bucket.
+func (r *rateLimiter) requestBurst() float64 {
+	return max(1, r.limits.rps)
+}
+
+// refill adds the capacity regained since the last refill.
+func (r *rateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(r.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	r.last = now
+	if r.limits.rps > 0 {
+		r.requests = min(r.requestBurst(), r.requests+elapsed*r.limits.rps)
+	}
+	if r.limits.tokensPerMinute > 0 {
+		perMinute := float64(r.limits.tokensPerMinute)
+		r.tokens = min(perMinute, r.tokens+elapsed*perMinute/60)
+	}
+}
+
+// wait returns how long to wait until a request with tokens fits in the
+// buckets, or 0 if it fits now.
+func (r *rateLimiter) wait(tokens float64) time.Duration {
+	var seconds float64
+	if r.limits.rps > 0 && r.requests < 1 {
+		seconds = (1 - r.requests) / r.limits.rps
+	}
+	if r.limits.tokensPerMinute > 0 && r.tokens < tokens {
+		seconds = max(seconds, (tokens-r.tokens)/(float64(r.limits.tokensPerMinute)/60))
+	}
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// acquire takes one request and tokens estimated tokens from the buckets,
+// waiting until they are available. It returns a reservation that must be
+// completed with release once the actual usage is known.
+func (r *rateLimiter) acquire(ctx context.Context, tokens int) (*rateReservation, error) {
+	for {
+		r.mu.Lock()
+		r.refill(r.now())
+		// A call larger than the whole limit is let through once the bucket is full.
+		cost := float64(tokens)
+		if r.limits.tokensPerMinute > 0 {
+			cost = min(cost, float64(r.limits.tokensPerMinute))
+		}
+		wait := r.wait(cost)
+		if wait == 0 {
+			if r.limits.rps > 0 {
+				r.requests--
+			}
+			reservation := &rateReservation{limiter: r}
+			if r.limits.tokensPerMinute > 0 {
+				r.tokens -= cost
+				reservation.tokens = cost
+			}
+			r.mu.Unlock()
+			return reservation, nil
+		}
+		r.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// rateReservation is the capacity taken from a rate limiter for one call.
+type rateReservation struct {
+	limiter *rateLimiter
+	tokens  float64 // Estimated tokens taken
+	done    bool
+}
+
+// release replaces the estimated tokens of the reservation with the actual
+// usage of the call. Pass a zero Usage if the call failed.
+func (r *rateReservation) release(usage Usage) {
+	l := r.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.done || l.limits.tokensPerMinute <= 0 {
+		return
+	}
+	r.done = true
+
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	l.tokens = min(float64(l.limits.tokensPerMinute), l.tokens+r.tokens-float64(total))
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Requests(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)}
+	r := newRateLimiter(rateLimit{rps: 2}, clock.now)
+	ctx := context.Background()
+
+	// A burst of one second of requests
+	for range 2 {
+		_, err := r.acquire(ctx, 0)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 500*time.Millisecond, r.wait(0))
+
+	clock.advance(250 * time.Millisecond)
+	r.refill(clock.now())
+	assert.Equal(t, 250*time.Millisecond, r.wait(0))
+
+	clock.advance(250 * time.Millisecond)
+	_, err := r.acquire(ctx, 0)
+	require.NoError(t, err)
+}
+
+func TestRateLimiter_Tokens(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)}
+	r := newRateLimiter(rateLimit{tokensPerMinute: 600}, clock.now)
+	ctx := context.Background()
+
+	res, err := r.acquire(ctx, 400)
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Second, r.wait(400)) // 200 missing at 10 tokens/s
+
+	// The estimate is replaced by the actual usage
+	res.release(Usage{PromptTokens: 300, CompletionTokens: 200})
+	assert.InDelta(t, 100, r.tokens, 0.001)
+	res.release(Usage{TotalTokens: 1000}) // Released only once
+	assert.InDelta(t, 100, r.tokens, 0.001)
+
+	// A call larger than the limit waits for a full bucket
+	assert.Equal(t, 50*time.Second, r.wait(600))
+	clock.advance(50 * time.Second)
+	_, err = r.acquire(ctx, 5000)
+	require.NoError(t, err)
+}
+
+func TestWithRateLimit_Waits(t *testing.T) {
+	opts := append(registerContent(t, "Hi"), WithRateLimit(0.001, 0))
+
+	_, err := Call(context.Background(), "Hello", opts...)
+	require.NoError(t, err)
+
+	// The bucket is shared by calls to the same provider and model
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = Call(ctx, "Hello", opts...)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
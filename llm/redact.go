@@ -0,0 +1,267 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// RedactionRule replaces the matches of a pattern in redacted text.
+type RedactionRule struct {
+	// Name identifies the rule, e.g. "email". Matches are replaced with
+	// "[REDACTED:<name>]" unless Replacement is set.
+	Name    string
+	Pattern *regexp.Regexp
+
+	// Replacement replaces each match, with $1 etc. expanded to the
+	// submatches as in regexp.Regexp.ReplaceAllString.
+	Replacement string
+}
+
+// DefaultRedactionRules redact common secrets and personal data: API keys
+// of popular services, bearer tokens, email addresses, and US social
+// security numbers.
+var DefaultRedactionRules = []RedactionRule{
+	{
+		Name: "api_key",
+		Pattern: regexp.MustCompile(`\b(?:sk-(?:ant-|proj-)?[A-Za-z0-9_\-]{16,}` + // OpenAI, Anthropic
+			`|AIza[0-9A-Za-z_\-]{35}` + // Google
+			`|AKIA[0-9A-Z]{16}` + // AWS
+			`|gh[pousr]_[A-Za-z0-9]{36,}` + // GitHub
+			`|xox[abprs]-[A-Za-z0-9\-]{10,})`), // Slack
+	},
+	{
+		Name:        "token",
+		Pattern:     regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		Replacement: "${1}[REDACTED:token]",
+	},
+	{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		Name:    "ssn",
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	},
+}
+
+// DefaultRedactedFields are the JSON fields whose values are redacted by
+// default. Field names are matched case-insensitively, ignoring "_" and "-".
+var DefaultRedactedFields = []string{
+	"password", "secret", "api_key", "token", "access_token", "refresh_token", "authorization",
+}
+
+// Redactor removes secrets and personal data from text before it leaves
+// the process through logs, traces, caches, or recordings, so those can be
+// enabled in regulated environments. Calls and agents send the original
+// text to the provider; only what is observed is redacted.
+//
+// A nil *Redactor redacts nothing. A Redactor is safe for concurrent use.
+type Redactor struct {
+	rules  []RedactionRule
+	fields map[string]bool // Normalized field names
+}
+
+// RedactOption configures a Redactor.
+type RedactOption func(*Redactor)
+
+// WithRedactionRules adds rules to the redactor.
+//
+// Example:
+//
+//	llm.WithRedactionRules(llm.RedactionRule{
+//	    Name:    "employee_id",
+//	    Pattern: regexp.MustCompile(`\bEMP-\d{6}\b`),
+//	})
+func WithRedactionRules(rules ...RedactionRule) RedactOption {
+	return func(r *Redactor) {
+		r.rules = append(r.rules, rules...)
+	}
+}
+
+// WithRedactedFields redacts the values of the named fields of JSON text,
+// such as tool arguments and results, and of log attributes.
+func WithRedactedFields(names ...string) RedactOption {
+	return func(r *Redactor) {
+		for _, name := range names {
+			r.fields[normalizeField(name)] = true
+		}
+	}
+}
+
+// WithoutDefaultRedactions removes DefaultRedactionRules and
+// DefaultRedactedFields, so only the rules and fields of other options apply.
+func WithoutDefaultRedactions() RedactOption {
+	return func(r *Redactor) {
+		r.rules = nil
+		clear(r.fields)
+	}
+}
+
+// NewRedactor returns a redactor with DefaultRedactionRules and
+// DefaultRedactedFields, changed by opts in order.
+//
+// Example:
+//
+//	redactor := llm.NewRedactor(llm.WithRedactedFields("customer_id"))
+//	llm.SetRedactor(redactor)
+func NewRedactor(opts ...RedactOption) *Redactor {
+	r := &Redactor{
+		rules:  append([]RedactionRule(nil), DefaultRedactionRules...),
+		fields: make(map[string]bool),
+	}
+	for _, name := range DefaultRedactedFields {
+		r.fields[normalizeField(name)] = true
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// String returns s with redacted fields, if s is JSON, and matches of the
+// rules replaced.
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	s = r.json(s)
+	for _, rule := range r.rules {
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED:" + rule.Name + "]"
+		}
+		s = rule.Pattern.ReplaceAllString(s, replacement)
+	}
+	return s
+}
+
+// Message returns a copy of m with its content and tool call arguments redacted.
+func (r *Redactor) Message(m Message) Message {
+	if r == nil {
+		return m
+	}
+	m.Content = r.String(m.Content)
+	if m.ToolCalls != nil {
+		calls := make([]provider.ToolCall, len(m.ToolCalls))
+		for i, tc := range m.ToolCalls {
+			tc.Arguments = r.String(tc.Arguments)
+			calls[i] = tc
+		}
+		m.ToolCalls = calls
+	}
+	return m
+}
+
+// Messages returns redacted copies of msgs.
+func (r *Redactor) Messages(msgs []Message) []Message {
+	if msgs == nil {
+		return nil
+	}
+	result := make([]Message, len(msgs))
+	for i, m := range msgs {
+		result[i] = r.Message(m)
+	}
+	return result
+}
+
+// ReplaceAttr redacts string attributes and the values of redacted fields,
+// for slog.HandlerOptions.ReplaceAttr.
+//
+// Example:
+//
+//	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{ReplaceAttr: redactor.ReplaceAttr})
+func (r *Redactor) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if r == nil {
+		return a
+	}
+	if r.fields[normalizeField(a.Key)] {
+		return slog.String(a.Key, "[REDACTED]")
+	}
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, r.String(a.Value.String()))
+	}
+	return a
+}
+
+// json redacts the fields of s if it is a JSON object or array.
+func (r *Redactor) json(s string) string {
+	if len(r.fields) == 0 {
+		return s
+	}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return s
+	}
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return s
+	}
+	if !r.redactFields(v) {
+		return s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return s
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactFields replaces the values of redacted fields in v and reports
+// whether it replaced any.
+func (r *Redactor) redactFields(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if r.fields[normalizeField(key)] {
+				v[key] = "[REDACTED]"
+				changed = true
+			} else if r.redactFields(value) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, value := range v {
+			if r.redactFields(value) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// normalizeField lowercases name and removes "_" and "-".
+func normalizeField(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// defaultRedactor is the redactor set by SetRedactor.
+var defaultRedactor atomic.Pointer[Redactor]
+
+// SetRedactor sets the redactor applied to what calls and agents log, trace,
+// cache, or record. By default, nothing is redacted; pass nil to stop
+// redacting.
+func SetRedactor(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// DefaultRedactor returns the redactor set by SetRedactor, or nil if there
+// is none.
+func DefaultRedactor() *Redactor {
+	return defaultRedactor.Load()
+}
+
+// Redact redacts s with the redactor set by SetRedactor.
+func Redact(s string) string {
+	return DefaultRedactor().String(s)
+}
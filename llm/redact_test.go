@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor_String(t *testing.T) {
+	r := NewRedactor()
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{"openai key", "key sk-proj-abcdefghijklmnop1234", "key [REDACTED:api_key]"},
+		{"google key", "AIza" + "0123456789abcdefghijklmnopqrstuvwxy", "[REDACTED:api_key]"},
+		{"bearer", "Authorization: Bearer eyJhbGciOi.J9", "Authorization: Bearer [REDACTED:token]"},
+		{"email", "write to jane.doe+x@example.co.jp", "write to [REDACTED:email]"},
+		{"ssn", "SSN 123-45-6789.", "SSN [REDACTED:ssn]."},
+		{"plain", "nothing to hide", "nothing to hide"},
+		{"json fields", `{"user":"jane","apiKey":"abc","nested":[{"Refresh-Token":"x"}]}`,
+			`{"apiKey":"[REDACTED]","nested":[{"Refresh-Token":"[REDACTED]"}],"user":"jane"}`},
+		{"json without fields", `{"b":1, "a":2}`, `{"b":1, "a":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.String(tt.in))
+		})
+	}
+}
+
+func TestRedactor_Options(t *testing.T) {
+	r := NewRedactor(
+		WithoutDefaultRedactions(),
+		WithRedactionRules(RedactionRule{Name: "employee_id", Pattern: regexp.MustCompile(`\bEMP-\d{6}\b`)}),
+		WithRedactedFields("customer_id"),
+	)
+	assert.Equal(t, "[REDACTED:employee_id] jane@example.com", r.String("EMP-123456 jane@example.com"))
+	assert.Equal(t, `{"customer_id":"[REDACTED]","password":"p"}`, r.String(`{"customer_id":"c1","password":"p"}`))
+
+	var nilRedactor *Redactor
+	assert.Equal(t, "jane@example.com", nilRedactor.String("jane@example.com"))
+}
+
+func TestRedactor_Message(t *testing.T) {
+	r := NewRedactor()
+	msg := AssistantMessageWithToolCalls("mail jane@example.com", []ToolCall{
+		{ID: "1", Name: "send", Arguments: `{"to":"jane@example.com"}`},
+	})
+
+	redacted := r.Message(msg)
+	assert.Equal(t, "mail [REDACTED:email]", redacted.Content)
+	assert.Equal(t, `{"to":"[REDACTED:email]"}`, redacted.ToolCalls[0].Arguments)
+	assert.Equal(t, `{"to":"jane@example.com"}`, msg.ToolCalls[0].Arguments) // Not modified
+}
+
+func TestRedactor_ReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRedactor()
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: r.ReplaceAttr}))
+
+	logger.Info("login", "user", "jane@example.com", "password", "hunter2", "attempts", 3)
+	assert.Contains(t, buf.String(), "user=[REDACTED:email]")
+	assert.Contains(t, buf.String(), "password=[REDACTED]")
+	assert.Contains(t, buf.String(), "attempts=3")
+	assert.NotContains(t, buf.String(), "hunter2")
+}
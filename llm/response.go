@@ -16,6 +16,9 @@ type Response[T any] struct {
 	parseErr  error
 	messages  []Message       // Full conversation history
 	config    *responseConfig // Provider/model info for Resume
+	variant   string          // The experiment variant of the call, if any
+
+	truncation *Truncation // How the conversation was truncated, if it was (see WithAutoTruncate)
 }
 
 // responseConfig stores the configuration needed to resume a conversation.
@@ -23,6 +26,20 @@ type responseConfig struct {
 	providerName string
 	model        string
 	tools        []Tool
+	variant      string      // The experiment variant of the call, if any
+	truncation   *Truncation // See WithAutoTruncate
+}
+
+// responseConfig returns the configuration to resume the conversation of a
+// call made with c.
+func (c *callConfig) responseConfig() *responseConfig {
+	return &responseConfig{
+		providerName: c.providerName,
+		model:        c.model,
+		tools:        c.tools,
+		variant:      c.variant,
+		truncation:   c.truncation,
+	}
 }
 
 // Text returns the raw text content of the response.
@@ -78,13 +95,19 @@ func (r Response[T]) ToolCalls() []ToolCall {
 
 // Usage returns token usage statistics.
 func (r Response[T]) Usage() Usage {
-	if r.raw == nil {
+	return responseUsage(r.raw)
+}
+
+// responseUsage returns the token usage of a provider response, or a zero
+// Usage if resp is nil.
+func responseUsage(resp *provider.Response) Usage {
+	if resp == nil {
 		return Usage{}
 	}
 	return Usage{
-		PromptTokens:     r.raw.Usage.PromptTokens,
-		CompletionTokens: r.raw.Usage.CompletionTokens,
-		TotalTokens:      r.raw.Usage.TotalTokens,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
 	}
 }
 
@@ -96,12 +119,47 @@ func (r Response[T]) FinishReason() FinishReason {
 	return FinishReason(r.raw.FinishReason)
 }
 
+// ContentFilter returns why the response was blocked or refused, if its
+// finish reason is FinishReasonContentFiltered, and nil otherwise.
+//
+// Example:
+//
+//	if filter := resp.ContentFilter(); filter != nil {
+//	    log.Printf("blocked (%s): %v", filter.Reason, filter.Categories)
+//	}
+func (r Response[T]) ContentFilter() *ContentFilter {
+	if r.raw == nil {
+		return nil
+	}
+	return r.raw.ContentFilter
+}
+
+// Variant returns the name of the experiment variant that the call was
+// routed to by WithExperiment, or "" if there is none.
+func (r Response[T]) Variant() string {
+	return r.variant
+}
+
 // Raw returns the underlying provider response.
 // This can be useful for debugging or accessing provider-specific data.
 func (r Response[T]) Raw() *provider.Response {
 	return r.raw
 }
 
+// HTTPResponse returns the provider's HTTP response, with its status and
+// headers (request IDs, rate limits), for debugging and support requests.
+// It returns nil if the provider does not use HTTP.
+//
+// Example:
+//
+//	log.Printf("request id: %s", resp.HTTPResponse().RequestID())
+func (r Response[T]) HTTPResponse() *provider.HTTPResponse {
+	if r.raw == nil {
+		return nil
+	}
+	return r.raw.HTTP
+}
+
 // Messages returns the full conversation history including the assistant's response.
 func (r Response[T]) Messages() []Message {
 	return r.messages
@@ -188,8 +246,18 @@ const (
 	FinishReasonStop      FinishReason = "stop"
 	FinishReasonToolCalls FinishReason = "tool_calls"
 	FinishReasonLength    FinishReason = "length"
+
+	// FinishReasonContentFiltered means that the provider's safety system
+	// blocked the prompt or the response (Gemini's SAFETY, OpenAI's
+	// content_filter), or that the model refused to answer (Anthropic's
+	// refusal). Response.ContentFilter returns the details.
+	FinishReasonContentFiltered FinishReason = "content_filter"
 )
 
+// ContentFilter describes a response blocked by a provider's safety system
+// or refused by the model.
+type ContentFilter = provider.ContentFilter
+
 // newParsedResponse creates a Response with parsed content.
 func newParsedResponse[T any](raw *provider.Response, parsed T, parseErr error) Response[T] {
 	return Response[T]{
@@ -203,11 +271,13 @@ func newParsedResponse[T any](raw *provider.Response, parsed T, parseErr error)
 // newResponseWithHistory creates a Response with conversation history and config for Resume support.
 func newResponseWithHistory[T any](raw *provider.Response, parsed T, parseErr error, messages []Message, config *responseConfig) Response[T] {
 	return Response[T]{
-		raw:       raw,
-		parsed:    parsed,
-		hasParsed: parseErr == nil,
-		parseErr:  parseErr,
-		messages:  messages,
-		config:    config,
+		raw:        raw,
+		parsed:     parsed,
+		hasParsed:  parseErr == nil,
+		parseErr:   parseErr,
+		messages:   messages,
+		config:     config,
+		variant:    config.variant,
+		truncation: config.truncation,
 	}
 }
@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RouteSpec describes a route of Route.
+type RouteSpec struct {
+	// Description tells the model which inputs the route handles.
+	Description string
+
+	// Examples are inputs that the route handles, if any.
+	Examples []string
+
+	// Args is the JSON schema of an object of arguments that the model
+	// extracts from the input for the route, if any. See schema.Generate.
+	Args json.RawMessage
+}
+
+// RouteResult is the result of Route.
+type RouteResult struct {
+	// Route is the name of the chosen route, or the fallback route set by
+	// RouteFallback.
+	Route string
+
+	// Args are the arguments extracted for the route, a JSON object valid
+	// against its Args schema ("{}" for routes without one).
+	Args json.RawMessage
+
+	// Confidence is the model's confidence in the route, from 0 to 1.
+	Confidence float64
+
+	Usage Usage
+}
+
+// ParseArgs unmarshals the arguments of the route into v.
+func (r RouteResult) ParseArgs(v any) error {
+	return json.Unmarshal(r.Args, v)
+}
+
+// RouteOption configures Route. See WithRouteOptions.
+type RouteOption func(*routeConfig)
+
+// routeConfig holds the configuration of Route.
+type routeConfig struct {
+	fallback      string
+	minConfidence float64
+	instructions  string
+}
+
+// RouteFallback sets the route chosen when the input matches none of the
+// routes, or when the model's confidence is below RouteMinConfidence. The
+// fallback route need not be one of the routes.
+func RouteFallback(name string) RouteOption {
+	return func(c *routeConfig) {
+		c.fallback = name
+	}
+}
+
+// RouteMinConfidence sets the confidence below which the fallback route is
+// chosen instead of the model's choice. It requires RouteFallback.
+func RouteMinConfidence(confidence float64) RouteOption {
+	return func(c *routeConfig) {
+		c.minConfidence = confidence
+	}
+}
+
+// RouteInstructions adds instructions to the routing prompt, e.g. how to
+// break ties between routes.
+func RouteInstructions(instructions string) RouteOption {
+	return func(c *routeConfig) {
+		c.instructions = instructions
+	}
+}
+
+// WithRouteOptions configures Route.
+//
+// Example:
+//
+//	result, err := llm.Route(ctx, input, routes,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o-mini"),
+//	    llm.WithRouteOptions(llm.RouteFallback("chat"), llm.RouteMinConfidence(0.6)),
+//	)
+func WithRouteOptions(opts ...RouteOption) Option {
+	return func(c *callConfig) {
+		c.routeOptions = append(c.routeOptions, opts...)
+	}
+}
+
+// routeDecision is the structured output of a routing call; args holds the
+// arguments as a JSON string so that each route can have its own schema.
+type routeDecision struct {
+	Route      string  `json:"route"`
+	Args       string  `json:"args"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Route classifies input against the descriptions of routes, by name, and
+// returns the chosen route with the arguments extracted for it. The model
+// chooses among the route names with an enum schema, so a small, cheap
+// model is usually enough. Use it to dispatch free-form input to commands,
+// agents, or handlers, beyond literal slash commands.
+//
+// Example:
+//
+//	routes := map[string]llm.RouteSpec{
+//	    "refund": {
+//	        Description: "The user wants their money back for an order",
+//	        Args:        schema.MustGenerate[RefundArgs](),
+//	    },
+//	    "track": {
+//	        Description: "The user asks where their order is",
+//	        Examples:    []string{"Where is my package?"},
+//	    },
+//	}
+//	result, err := llm.Route(ctx, "I want a refund for order 1234", routes,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o-mini"),
+//	    llm.WithRouteOptions(llm.RouteFallback("chat")),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	switch result.Route {
+//	case "refund":
+//	    var args RefundArgs
+//	    if err := result.ParseArgs(&args); err != nil {
+//	        return err
+//	    }
+//	    return refund(ctx, args.OrderID)
+//	...
+//	}
+func Route(ctx context.Context, input string, routes map[string]RouteSpec, opts ...Option) (RouteResult, error) {
+	if len(routes) == 0 {
+		return RouteResult{}, errors.New("no routes")
+	}
+	cfg := newCallConfig()
+	cfg.apply(opts...)
+	rc := &routeConfig{}
+	for _, opt := range cfg.routeOptions {
+		opt(rc)
+	}
+
+	names := make([]string, 0, len(routes)+1)
+	for name := range routes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	choices := names
+	if rc.fallback != "" && !slices.Contains(names, rc.fallback) {
+		choices = append(slices.Clip(names), rc.fallback)
+	}
+
+	resp, err := CallParseDynamic(ctx, routePrompt(input, names, routes, rc), routeSchema(choices), opts...)
+	if err != nil {
+		return RouteResult{}, err
+	}
+	parsed, err := resp.Parsed()
+	if err != nil {
+		return RouteResult{}, err
+	}
+	var decision routeDecision
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		return RouteResult{}, err
+	}
+	if err := json.Unmarshal(data, &decision); err != nil {
+		return RouteResult{}, err
+	}
+
+	result := RouteResult{Route: decision.Route, Args: json.RawMessage("{}"), Confidence: decision.Confidence, Usage: resp.Usage()}
+	if rc.fallback != "" && (decision.Confidence < rc.minConfidence || decision.Route == rc.fallback) {
+		result.Route = rc.fallback
+		return result, nil
+	}
+
+	args := strings.TrimSpace(decision.Args)
+	if args == "" {
+		args = "{}"
+	}
+	var object map[string]any
+	if err := json.Unmarshal([]byte(args), &object); err != nil || object == nil {
+		return result, &ParseError{Content: args, Target: "arguments of route " + decision.Route, Cause: errors.New("not a JSON object")}
+	}
+	if spec := routes[decision.Route]; len(spec.Args) > 0 {
+		if err := parseResponse(args, &object, spec.Args, "arguments of route "+decision.Route); err != nil {
+			return result, err
+		}
+	}
+	result.Args = json.RawMessage(args)
+	return result, nil
+}
+
+// routeSchema returns the schema of the routing decision among choices.
+func routeSchema(choices []string) json.RawMessage {
+	s := map[string]any{
+		"type":  "object",
+		"title": "route",
+		"properties": map[string]any{
+			"route": map[string]any{
+				"type":        "string",
+				"enum":        choices,
+				"description": "Name of the route that best handles the input",
+			},
+			"args": map[string]any{
+				"type":        "string",
+				"description": "JSON object of the arguments of the route, valid against its arguments schema, or {} if it has none",
+			},
+			"confidence": map[string]any{
+				"type":        "number",
+				"minimum":     0,
+				"maximum":     1,
+				"description": "Confidence that the route is right, from 0 (a guess) to 1 (certain)",
+			},
+		},
+		"required":             []string{"route", "args", "confidence"},
+		"additionalProperties": false,
+	}
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// routePrompt returns the prompt to route input among the named routes.
+func routePrompt(input string, names []string, routes map[string]RouteSpec, rc *routeConfig) string {
+	var sb strings.Builder
+	sb.WriteString("Choose the route that best handles the input below, and extract the arguments of the route from the input.")
+	if rc.fallback != "" {
+		fmt.Fprintf(&sb, " If no route fits, choose %q.", rc.fallback)
+	}
+	if rc.instructions != "" {
+		sb.WriteString(" " + rc.instructions)
+	}
+	sb.WriteString("\n\n<routes>\n")
+	for _, name := range names {
+		spec := routes[name]
+		fmt.Fprintf(&sb, "- %s: %s\n", name, spec.Description)
+		for _, example := range spec.Examples {
+			fmt.Fprintf(&sb, "  Example: %s\n", example)
+		}
+		if len(spec.Args) > 0 {
+			fmt.Fprintf(&sb, "  Arguments schema: %s\n", compactJSON(spec.Args))
+		}
+	}
+	sb.WriteString("</routes>\n\n<input>\n")
+	sb.WriteString(input)
+	sb.WriteString("\n</input>")
+	return sb.String()
+}
+
+// compactJSON returns data without insignificant whitespace, or as it is if
+// it is not valid JSON.
+func compactJSON(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRoutes = map[string]RouteSpec{
+	"refund": {
+		Description: "The user wants their money back for an order",
+		Args:        json.RawMessage(`{"type": "object", "properties": {"order_id": {"type": "string"}}, "required": ["order_id"]}`),
+	},
+	"track": {
+		Description: "The user asks where their order is",
+		Examples:    []string{"Where is my package?"},
+	},
+}
+
+func TestRoute(t *testing.T) {
+	var prompt string
+	_, opts := registerPrompt(t, func(p string) string {
+		prompt = p
+		return `{"route": "refund", "args": "{\"order_id\": \"1234\"}", "confidence": 0.9}`
+	})
+
+	result, err := Route(context.Background(), "I want a refund for order 1234", testRoutes, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "refund", result.Route)
+	assert.Equal(t, 0.9, result.Confidence)
+	assert.Equal(t, 15, result.Usage.TotalTokens)
+
+	var args struct {
+		OrderID string `json:"order_id"`
+	}
+	require.NoError(t, result.ParseArgs(&args))
+	assert.Equal(t, "1234", args.OrderID)
+
+	assert.Contains(t, prompt, "- refund: The user wants their money back for an order\n")
+	assert.Contains(t, prompt, `Arguments schema: {"type":"object",`)
+	assert.Contains(t, prompt, "  Example: Where is my package?\n")
+	assert.Contains(t, prompt, "<input>\nI want a refund for order 1234\n</input>")
+	assert.Less(t, strings.Index(prompt, "- refund"), strings.Index(prompt, "- track"))
+}
+
+func TestRoute_Schema(t *testing.T) {
+	var s struct {
+		Properties struct {
+			Route struct {
+				Enum []string `json:"enum"`
+			} `json:"route"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(routeSchema([]string{"refund", "track", "chat"}), &s))
+	assert.Equal(t, []string{"refund", "track", "chat"}, s.Properties.Route.Enum)
+
+	// The model can only choose among the routes
+	_, opts := registerPrompt(t, func(string) string {
+		return `{"route": "cancel", "args": "{}", "confidence": 1}`
+	})
+	_, err := Route(context.Background(), "Cancel my order", testRoutes, opts...)
+	var parseErr *ParseError
+	assert.ErrorAs(t, err, &parseErr)
+}
+
+func TestRoute_Args(t *testing.T) {
+	t.Run("without schema", func(t *testing.T) {
+		_, opts := registerPrompt(t, func(string) string {
+			return `{"route": "track", "args": "", "confidence": 0.8}`
+		})
+		result, err := Route(context.Background(), "Where is my package?", testRoutes, opts...)
+		require.NoError(t, err)
+		assert.Equal(t, "track", result.Route)
+		assert.JSONEq(t, `{}`, string(result.Args))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, opts := registerPrompt(t, func(string) string {
+			return `{"route": "refund", "args": "{}", "confidence": 0.8}`
+		})
+		result, err := Route(context.Background(), "Refund please", testRoutes, opts...)
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, "arguments of route refund", parseErr.Target)
+		assert.Equal(t, "refund", result.Route)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		_, opts := registerPrompt(t, func(string) string {
+			return `{"route": "track", "args": "[1]", "confidence": 0.8}`
+		})
+		_, err := Route(context.Background(), "Where is it?", testRoutes, opts...)
+		assert.ErrorContains(t, err, "not a JSON object")
+	})
+}
+
+func TestRoute_Fallback(t *testing.T) {
+	var prompt string
+	_, opts := registerPrompt(t, func(p string) string {
+		prompt = p
+		if strings.Contains(p, "weather") {
+			return `{"route": "chat", "args": "{}", "confidence": 0.9}`
+		}
+		return `{"route": "track", "args": "{}", "confidence": 0.3}`
+	})
+	opts = append(opts, WithRouteOptions(RouteFallback("chat"), RouteMinConfidence(0.5)))
+
+	result, err := Route(context.Background(), "How is the weather?", testRoutes, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "chat", result.Route)
+	assert.Contains(t, prompt, `If no route fits, choose "chat".`)
+
+	// Below the minimum confidence
+	result, err = Route(context.Background(), "Hmm", testRoutes, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "chat", result.Route)
+	assert.Equal(t, 0.3, result.Confidence)
+	assert.JSONEq(t, `{}`, string(result.Args))
+}
+
+func TestRoute_NoRoutes(t *testing.T) {
+	_, err := Route(context.Background(), "Hello", nil, WithProvider("any"), WithModel("m"))
+	assert.EqualError(t, err, "no routes")
+}
@@ -3,15 +3,25 @@ package llm
 import (
 	"context"
 	"fmt"
+	"io"
 	"iter"
+	"time"
 
 	"github.com/i2y/bucephalus/provider"
 )
 
 // Stream represents a streaming response from an LLM.
 type Stream struct {
-	stream provider.ResponseStream
-	err    error
+	stream  provider.ResponseStream
+	err     error
+	release func(Usage) // Records usage when the stream ends (see WithQuota and WithRateLimit)
+
+	// For logging the end of the stream and recording its metrics
+	ctx        context.Context
+	cfg        *callConfig
+	start      time.Time
+	firstChunk time.Duration
+	ended      bool
 }
 
 // Chunks returns an iterator over the stream chunks.
@@ -31,6 +41,9 @@ type Stream struct {
 func (s *Stream) Chunks() iter.Seq[StreamChunk] {
 	return func(yield func(StreamChunk) bool) {
 		for s.stream.Next() {
+			if s.firstChunk == 0 {
+				s.firstChunk = time.Since(s.start)
+			}
 			current := s.stream.Current()
 			chunk := StreamChunk{
 				Delta:        current.Delta,
@@ -48,6 +61,66 @@ func (s *Stream) Chunks() iter.Seq[StreamChunk] {
 			}
 		}
 		s.err = s.stream.Err()
+		s.end()
+	}
+}
+
+// WriteTo writes the text deltas of the stream to w as they arrive,
+// implementing io.WriterTo. If w has a Flush method, such as
+// http.ResponseWriter (Flush()) or *bufio.Writer (Flush() error), it is
+// flushed after each delta. It returns the number of bytes written and the
+// first write, flush, or stream error. Tool call deltas are not written.
+//
+// Example:
+//
+//	stream, err := llm.CallStream(ctx, "Write a story", opts...)
+//	if err != nil {
+//	    return err
+//	}
+//	defer stream.Close()
+//	if _, err := stream.WriteTo(os.Stdout); err != nil {
+//	    return err
+//	}
+func (s *Stream) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for chunk := range s.Chunks() {
+		if chunk.Delta == "" {
+			continue
+		}
+		n, err := io.WriteString(w, chunk.Delta)
+		written += int64(n)
+		if err == nil {
+			err = flush(w)
+		}
+		if err != nil {
+			return written, fmt.Errorf("writing stream: %w", err)
+		}
+	}
+	return written, s.Err()
+}
+
+// flush flushes w if it has a Flush method.
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+// end logs the end of the stream, records its metrics, and records its
+// usage in its rate limit and quota, if any.
+func (s *Stream) end() {
+	if s.cfg != nil && !s.ended {
+		s.ended = true
+		duration := time.Since(s.start)
+		s.cfg.logResponse(s.ctx, "llm stream", s.stream.Accumulated(), s.err, duration)
+		s.cfg.recordRequest(s.stream.Accumulated(), s.err, true, duration, s.firstChunk)
+	}
+	if s.release != nil {
+		s.release(responseUsage(s.stream.Accumulated()))
 	}
 }
 
@@ -58,6 +131,7 @@ func (s *Stream) Err() error {
 
 // Close closes the stream and releases resources.
 func (s *Stream) Close() error {
+	s.end()
 	return s.stream.Close()
 }
 
@@ -65,7 +139,10 @@ func (s *Stream) Close() error {
 // Should be called after iterating through all chunks.
 func (s *Stream) Response() Response[string] {
 	accumulated := s.stream.Accumulated()
-	return newParsedResponse(accumulated, accumulated.Content, nil)
+	resp := newParsedResponse(accumulated, accumulated.Content, nil)
+	resp.variant = s.cfg.variant
+	resp.truncation = s.cfg.truncation
+	return resp
 }
 
 // StreamChunk represents a single chunk in a streaming response.
@@ -126,12 +203,34 @@ func CallStream(ctx context.Context, prompt string, opts ...Option) (*Stream, er
 
 	req := cfg.buildRequest(prompt)
 
-	stream, err := sp.CallStream(ctx, req)
+	return cfg.callStream(ctx, sp, req)
+}
+
+// CallStreamTo makes a streaming LLM call and writes the text deltas to w as
+// they arrive (see Stream.WriteTo), then returns the complete response. It
+// suits CLIs and HTTP handlers that only pipe the text to a writer.
+//
+// Example:
+//
+//	resp, err := llm.CallStreamTo(ctx, os.Stdout, "Write a haiku about Go",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("o4-mini"),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Printf("\n(%d tokens)\n", resp.Usage().TotalTokens)
+func CallStreamTo(ctx context.Context, w io.Writer, prompt string, opts ...Option) (Response[string], error) {
+	stream, err := CallStream(ctx, prompt, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("starting stream: %w", err)
+		return Response[string]{}, err
 	}
+	defer func() { _ = stream.Close() }()
 
-	return &Stream{stream: stream}, nil
+	if _, err := stream.WriteTo(w); err != nil {
+		return Response[string]{}, err
+	}
+	return stream.Response(), nil
 }
 
 // CallMessagesStream makes a streaming LLM call with message history.
@@ -158,10 +257,35 @@ func CallMessagesStream(ctx context.Context, messages []Message, opts ...Option)
 
 	req := cfg.buildRequestFromMessages(messages)
 
+	return cfg.callStream(ctx, sp, req)
+}
+
+// callStream starts streaming req from sp and logs it, waiting for the
+// call's rate limit and quota, if any.
+func (c *callConfig) callStream(ctx context.Context, sp provider.StreamingProvider, req *provider.Request) (*Stream, error) {
+	if err := c.setLogitBias(req); err != nil {
+		return nil, err
+	}
+	release, err := c.acquire(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	c.logRequest(ctx, "llm stream request", req)
 	stream, err := sp.CallStream(ctx, req)
 	if err != nil {
+		release(Usage{})
+		duration := time.Since(start)
+		c.logResponse(ctx, "llm stream", nil, err, duration)
+		c.recordRequest(nil, err, true, duration, 0)
+		if retry, terr := c.truncate(ctx, req, err); terr != nil {
+			return nil, fmt.Errorf("starting stream: %w (%v)", err, terr)
+		} else if retry {
+			return c.callStream(ctx, sp, req)
+		}
 		return nil, fmt.Errorf("starting stream: %w", err)
 	}
 
-	return &Stream{stream: stream}, nil
+	return &Stream{stream: stream, release: release, ctx: ctx, cfg: c, start: start}, nil
 }
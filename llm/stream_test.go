@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// deltaProvider streams deltas, then fails with err if it is set.
+type deltaProvider struct {
+	contentProvider
+	deltas []string
+	err    error
+}
+
+func (p deltaProvider) CallStream(ctx context.Context, req *provider.Request) (provider.ResponseStream, error) {
+	return &deltaStream{deltas: p.deltas, err: p.err, acc: provider.NewAccumulator(nil)}, nil
+}
+
+type deltaStream struct {
+	deltas  []string
+	current string
+	err     error
+	acc     *provider.Accumulator
+}
+
+func (s *deltaStream) Next() bool {
+	if len(s.deltas) == 0 {
+		return false
+	}
+	s.current, s.deltas = s.deltas[0], s.deltas[1:]
+	s.acc.AddContent(s.current)
+	return true
+}
+
+func (s *deltaStream) Current() *provider.StreamChunk  { return &provider.StreamChunk{Delta: s.current} }
+func (s *deltaStream) Err() error                      { return s.err }
+func (s *deltaStream) Close() error                    { return nil }
+func (s *deltaStream) Accumulated() *provider.Response { return s.acc.Response() }
+
+func registerDeltas(t *testing.T, err error, deltas ...string) []Option {
+	t.Helper()
+	name := "deltas-" + t.Name()
+	provider.RegisterInstance(name, deltaProvider{deltas: deltas, err: err})
+	t.Cleanup(func() { provider.Reset(name) })
+	return []Option{WithProvider(name), WithModel("m")}
+}
+
+// flushCounter counts the flushes of an httptest.ResponseRecorder.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() { f.flushes++ }
+
+func TestCallStreamTo(t *testing.T) {
+	opts := registerDeltas(t, nil, "Hello", "", ", world")
+
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	resp, err := CallStreamTo(context.Background(), w, "Hi", opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, world", w.Body.String())
+	assert.Equal(t, 2, w.flushes)
+	assert.Equal(t, "Hello, world", resp.Text())
+}
+
+func TestStream_WriteTo(t *testing.T) {
+	opts := registerDeltas(t, errors.New("connection reset"), "Hello", " there")
+
+	stream, err := CallStream(context.Background(), "Hi", opts...)
+	require.NoError(t, err)
+	defer func() { _ = stream.Close() }()
+
+	var sb strings.Builder
+	buf := bufio.NewWriter(&sb)
+	n, err := stream.WriteTo(buf)
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, int64(11), n)
+	assert.Equal(t, "Hello there", sb.String()) // Flushed after each delta
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestCallStreamTo_WriteError(t *testing.T) {
+	opts := registerDeltas(t, nil, "Hello", " there")
+	_, err := CallStreamTo(context.Background(), failingWriter{}, "Hi", opts...)
+	assert.EqualError(t, err, "writing stream: broken pipe")
+}
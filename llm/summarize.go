@@ -0,0 +1,315 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SummarizeStrategy is how Summarize combines the summaries of chunks.
+type SummarizeStrategy string
+
+const (
+	// SummarizeMapReduce summarizes the chunks independently and
+	// concurrently, then summarizes the summaries. It is fast and suits
+	// inputs whose parts can be understood on their own.
+	SummarizeMapReduce SummarizeStrategy = "map_reduce"
+
+	// SummarizeRefine summarizes the first chunk, then refines the summary
+	// with each following chunk in order. It is sequential and slower but
+	// keeps the thread of narratives and arguments.
+	SummarizeRefine SummarizeStrategy = "refine"
+)
+
+// Summary is the result of Summarize.
+type Summary struct {
+	Text   string
+	Chunks int   // Number of chunks the input was split into
+	Calls  int   // Number of LLM calls made
+	Usage  Usage // Total usage of all calls
+}
+
+// SummarizeOption configures Summarize. See WithSummarizeOptions.
+type SummarizeOption func(*summarizeConfig)
+
+// summarizeConfig holds the configuration of Summarize.
+type summarizeConfig struct {
+	strategy     SummarizeStrategy
+	chunkTokens  int
+	words        int
+	instructions string
+	concurrency  int
+}
+
+// Defaults of Summarize.
+const (
+	defaultSummarizeChunkTokens = 8000
+	defaultSummarizeWords       = 200
+	defaultSummarizeConcurrency = 4
+)
+
+// SummarizeWith sets the strategy for inputs longer than a chunk
+// (default: SummarizeMapReduce).
+func SummarizeWith(strategy SummarizeStrategy) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.strategy = strategy
+	}
+}
+
+// SummarizeChunkTokens sets the maximum estimated tokens of the chunks sent
+// in each call (default: 8000). Keep it well below the model's context window.
+func SummarizeChunkTokens(n int) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.chunkTokens = n
+	}
+}
+
+// SummarizeWords sets the target length of the summary in words (default: 200).
+func SummarizeWords(n int) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.words = n
+	}
+}
+
+// SummarizeInstructions adds instructions to every summarization prompt,
+// e.g. the audience or what to focus on.
+func SummarizeInstructions(instructions string) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.instructions = instructions
+	}
+}
+
+// SummarizeConcurrency sets the number of chunks summarized at the same
+// time with SummarizeMapReduce (default: 4).
+func SummarizeConcurrency(n int) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithSummarizeOptions configures Summarize.
+//
+// Example:
+//
+//	summary, err := llm.Summarize(ctx, transcript,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o-mini"),
+//	    llm.WithSummarizeOptions(llm.SummarizeWith(llm.SummarizeRefine), llm.SummarizeWords(100)),
+//	)
+func WithSummarizeOptions(opts ...SummarizeOption) Option {
+	return func(c *callConfig) {
+		c.summarizeOptions = append(c.summarizeOptions, opts...)
+	}
+}
+
+// Summarize summarizes text, which may be longer than the model's context
+// window. Text that fits in a chunk (see SummarizeChunkTokens) is summarized
+// with a single call. Longer text is split at paragraphs and summarized with
+// the strategy set by SummarizeWith: map-reduce, which summarizes the chunks
+// concurrently and then combines the summaries (in several rounds if they do
+// not fit in a chunk), or refine, which updates a running summary with each
+// chunk in order.
+//
+// Example:
+//
+//	summary, err := llm.Summarize(ctx, report,
+//	    llm.WithProvider("anthropic"),
+//	    llm.WithModel("claude-haiku-4-5"),
+//	    llm.WithSummarizeOptions(llm.SummarizeWords(150), llm.SummarizeInstructions("Write for executives.")),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(summary.Text)
+func Summarize(ctx context.Context, text string, opts ...Option) (Summary, error) {
+	cfg := newCallConfig()
+	cfg.apply(opts...)
+	sc := &summarizeConfig{
+		strategy:    SummarizeMapReduce,
+		chunkTokens: defaultSummarizeChunkTokens,
+		words:       defaultSummarizeWords,
+		concurrency: defaultSummarizeConcurrency,
+	}
+	for _, opt := range cfg.summarizeOptions {
+		opt(sc)
+	}
+	if sc.chunkTokens <= 0 {
+		sc.chunkTokens = defaultSummarizeChunkTokens
+	}
+	if sc.words <= 0 {
+		sc.words = defaultSummarizeWords
+	}
+
+	s := &summarizer{config: sc, opts: opts}
+	chunks := splitDocument(text, sc.chunkTokens, 0)
+	s.summary.Chunks = len(chunks)
+
+	var err error
+	switch {
+	case len(chunks) == 1:
+		s.summary.Text, err = s.call(ctx, s.prompt("Summarize the text below.", "text", chunks[0], true))
+	case sc.strategy == SummarizeRefine:
+		err = s.refine(ctx, chunks)
+	case sc.strategy == SummarizeMapReduce:
+		err = s.mapReduce(ctx, chunks)
+	default:
+		err = fmt.Errorf("unknown summarize strategy %q", sc.strategy)
+	}
+	if err != nil {
+		return Summary{}, err
+	}
+	return s.summary, nil
+}
+
+// summarizer runs the calls of Summarize.
+type summarizer struct {
+	config  *summarizeConfig
+	opts    []Option
+	summary Summary
+}
+
+// mapReduce summarizes chunks concurrently, then combines the summaries.
+func (s *summarizer) mapReduce(ctx context.Context, chunks []string) error {
+	for round := 1; ; round++ {
+		summaries := make([]string, len(chunks))
+		usages := make([]Usage, len(chunks))
+		errs := forEachChunk(ctx, len(chunks), s.config.concurrency, func(i int) error {
+			task, tag := fmt.Sprintf("Summarize part %d of %d of a longer text. Keep every key fact, decision, and figure; "+
+				"the summaries of all parts will be combined.", i+1, len(chunks)), "text"
+			if round > 1 {
+				task, tag = fmt.Sprintf("Combine the partial summaries below (group %d of %d) into one summary. "+
+					"Keep every key fact, decision, and figure.", i+1, len(chunks)), "summaries"
+			}
+			resp, err := Call(ctx, s.prompt(task, tag, chunks[i], false), s.opts...)
+			if err != nil {
+				return err
+			}
+			summaries[i], usages[i] = strings.TrimSpace(resp.Text()), resp.Usage()
+			return nil
+		})
+		for i, err := range errs {
+			if err != nil {
+				return fmt.Errorf("summarizing chunk %d of %d: %w", i+1, len(chunks), err)
+			}
+			s.summary.Calls++
+			s.summary.Usage = addUsage(s.summary.Usage, usages[i])
+		}
+
+		// Combine the summaries in one call if they fit, or if grouping them
+		// would not reduce their number
+		combined := strings.Join(summaries, "\n\n")
+		groups := splitDocument(combined, s.config.chunkTokens, 0)
+		if len(groups) == 1 || len(groups) >= len(chunks) {
+			text, err := s.call(ctx, s.prompt("Combine the partial summaries below, which cover consecutive parts "+
+				"of one text, into a single coherent summary of the whole text.", "summaries", combined, true))
+			if err != nil {
+				return fmt.Errorf("combining summaries: %w", err)
+			}
+			s.summary.Text = text
+			return nil
+		}
+		chunks = groups
+	}
+}
+
+// refine summarizes the first chunk and refines the summary with the others.
+func (s *summarizer) refine(ctx context.Context, chunks []string) error {
+	for i, chunk := range chunks {
+		var prompt string
+		if i == 0 {
+			prompt = s.prompt(fmt.Sprintf("Summarize part 1 of %d of a longer text; the summary will be "+
+				"refined with the following parts.", len(chunks)), "text", chunk, true)
+		} else {
+			prompt = s.prompt(fmt.Sprintf("Here is a summary of parts 1 to %d of a longer text:\n\n<summary>\n%s\n</summary>\n\n"+
+				"Refine the summary with part %d below so that it covers everything so far. Return only the refined summary.",
+				i, s.summary.Text, i+1), "text", chunk, true)
+		}
+		text, err := s.call(ctx, prompt)
+		if err != nil {
+			return fmt.Errorf("summarizing chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		s.summary.Text = text
+	}
+	return nil
+}
+
+// call makes a call and records its usage.
+func (s *summarizer) call(ctx context.Context, prompt string) (string, error) {
+	resp, err := Call(ctx, prompt, s.opts...)
+	if err != nil {
+		return "", err
+	}
+	s.summary.Calls++
+	s.summary.Usage = addUsage(s.summary.Usage, resp.Usage())
+	return strings.TrimSpace(resp.Text()), nil
+}
+
+// prompt returns a summarization prompt with task, the configured
+// instructions, the target length if final, and content in a tag.
+func (s *summarizer) prompt(task, tag, content string, final bool) string {
+	var sb strings.Builder
+	sb.WriteString(task)
+	if final {
+		fmt.Fprintf(&sb, " Write about %d words.", s.config.words)
+	}
+	if s.config.instructions != "" {
+		sb.WriteString(" ")
+		sb.WriteString(s.config.instructions)
+	}
+	sb.WriteString(" Respond with the summary only.")
+	fmt.Fprintf(&sb, "\n\n<%s>\n%s\n</%s>", tag, content, tag)
+	return sb.String()
+}
+
+// conversationSummaryInstructions is the system message used to summarize
+// conversations.
+const conversationSummaryInstructions = "Summarize the following conversation so it can replace the original messages. " +
+	"Preserve facts, decisions, user preferences, open tasks, and results of tool calls. " +
+	"Be concise and write the summary as plain text."
+
+// SummarizeConversation summarizes msgs with a single LLM call, for a
+// summary that replaces them in a history. The summary keeps the facts,
+// decisions, user preferences, open tasks, and tool results of the
+// conversation. Its instructions replace the system message of opts.
+//
+// Example:
+//
+//	summary, err := llm.SummarizeConversation(ctx, history[:len(history)-10],
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o-mini"),
+//	)
+func SummarizeConversation(ctx context.Context, msgs []Message, opts ...Option) (string, error) {
+	callOpts := make([]Option, 0, len(opts)+1)
+	callOpts = append(callOpts, opts...)
+	callOpts = append(callOpts, WithSystemMessage(conversationSummaryInstructions))
+
+	resp, err := Call(ctx, FormatTranscript(msgs), callOpts...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Text()), nil
+}
+
+// FormatTranscript renders msgs as plain text, one message per paragraph
+// prefixed by its role, with tool calls and results, for prompts that show
+// a conversation to a model.
+func FormatTranscript(msgs []Message) string {
+	var sb strings.Builder
+	for _, msg := range msgs {
+		switch {
+		case msg.Role == RoleTool:
+			fmt.Fprintf(&sb, "tool result (%s): %s\n\n", msg.ToolID, msg.Content)
+		case len(msg.ToolCalls) > 0:
+			if msg.Content != "" {
+				fmt.Fprintf(&sb, "%s: %s\n", msg.Role, msg.Content)
+			}
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&sb, "%s called tool %s(%s)\n", msg.Role, tc.Name, tc.Arguments)
+			}
+			sb.WriteString("\n")
+		default:
+			fmt.Fprintf(&sb, "%s: %s\n\n", msg.Role, msg.Content)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
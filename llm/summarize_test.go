@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fiveParagraphs is split into five chunks of at most 10 tokens.
+const fiveParagraphs = "The first paragraph is about cats.\n\nThe second paragraph is about dogs.\n\n" +
+	"The third paragraph is about fish.\n\nThe fourth paragraph is on birds.\n\nThe fifth paragraph is on mice."
+
+var partNumber = regexp.MustCompile(`part (\d+) of`)
+
+func TestSummarize_Short(t *testing.T) {
+	p, opts := registerPrompt(t, func(prompt string) string {
+		assert.Contains(t, prompt, "Write about 50 words. Focus on pets.")
+		return "  A short summary.\n"
+	})
+	summary, err := Summarize(context.Background(), "Cats and dogs are pets.",
+		append(opts, WithSummarizeOptions(SummarizeWords(50), SummarizeInstructions("Focus on pets.")))...)
+	require.NoError(t, err)
+	assert.Equal(t, "A short summary.", summary.Text)
+	assert.Equal(t, 1, summary.Chunks)
+	assert.Equal(t, 1, summary.Calls)
+	assert.Equal(t, int64(1), p.calls.Load())
+	assert.Equal(t, 15, summary.Usage.TotalTokens)
+}
+
+func TestSummarize_MapReduce(t *testing.T) {
+	_, opts := registerPrompt(t, func(prompt string) string {
+		switch {
+		case strings.HasPrefix(prompt, "Summarize part"):
+			return "summary " + partNumber.FindStringSubmatch(prompt)[1]
+		case strings.HasPrefix(prompt, "Combine the partial summaries below (group"):
+			return "group"
+		default:
+			// The final call combines the two group summaries
+			assert.Contains(t, prompt, "<summaries>\ngroup\n\ngroup\n</summaries>")
+			return "final"
+		}
+	})
+	summary, err := Summarize(context.Background(), fiveParagraphs,
+		append(opts, WithSummarizeOptions(SummarizeChunkTokens(10)))...)
+	require.NoError(t, err)
+	assert.Equal(t, "final", summary.Text)
+	assert.Equal(t, 5, summary.Chunks)
+	assert.Equal(t, 8, summary.Calls) // 5 parts, 2 groups of part summaries, and the final summary
+	assert.Equal(t, 8*15, summary.Usage.TotalTokens)
+}
+
+func TestSummarize_Refine(t *testing.T) {
+	_, opts := registerPrompt(t, func(prompt string) string {
+		if m := regexp.MustCompile(`(?s)<summary>\n(.*)\n</summary>`).FindStringSubmatch(prompt); m != nil {
+			return m[1] + "+" + regexp.MustCompile(`with part (\d+)`).FindStringSubmatch(prompt)[1]
+		}
+		return "1"
+	})
+	summary, err := Summarize(context.Background(), fiveParagraphs,
+		append(opts, WithSummarizeOptions(SummarizeChunkTokens(10), SummarizeWith(SummarizeRefine)))...)
+	require.NoError(t, err)
+	assert.Equal(t, "1+2+3+4+5", summary.Text)
+	assert.Equal(t, 5, summary.Calls)
+
+	_, err = Summarize(context.Background(), fiveParagraphs,
+		append(opts, WithSummarizeOptions(SummarizeChunkTokens(10), SummarizeWith("outline")))...)
+	assert.ErrorContains(t, err, `unknown summarize strategy "outline"`)
+}
+
+func TestSummarizeConversation(t *testing.T) {
+	_, opts := registerPrompt(t, func(prompt string) string {
+		assert.Equal(t, "user: Read main.go\n\n"+
+			"assistant called tool read({\"path\":\"main.go\"})\n\n"+
+			"tool result (call_1): package main\n\n"+
+			"assistant: It is the main package.", prompt)
+		return " The user read main.go. "
+	})
+	summary, err := SummarizeConversation(context.Background(), []Message{
+		UserMessage("Read main.go"),
+		AssistantMessageWithToolCalls("", []ToolCall{{ID: "call_1", Name: "read", Arguments: `{"path":"main.go"}`}}),
+		ToolMessage("call_1", "package main"),
+		AssistantMessage("It is the main package."),
+	}, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "The user read main.go.", summary)
+}
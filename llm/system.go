@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// Orders of the standard layers of a system prompt. Sections are rendered
+// in increasing order; use values in between for custom layers.
+const (
+	SystemOrderBase  = 100 // Persona and general instructions (WithSystemMessage, agents)
+	SystemOrderIndex = 200 // Indexes of the available skills, commands, and agents
+	SystemOrderSkill = 300 // Activated skills and expanded commands
+	SystemOrderRun   = 400 // Addenda for a single run or call
+)
+
+// SystemPriorityRequired is the priority of sections that are never dropped
+// to fit the token budget.
+const SystemPriorityRequired = math.MaxInt
+
+// SystemSection is a layer of a system prompt.
+type SystemSection struct {
+	// Name identifies the section; adding a section replaces the one with
+	// the same name.
+	Name string
+
+	// Text is the content of the section. Empty sections are skipped.
+	Text string
+
+	// Order is the position of the section, e.g. SystemOrderSkill. Sections
+	// of the same order are rendered in the order they were added.
+	Order int
+
+	// Priority decides which sections are dropped first, lowest first, when
+	// the prompt exceeds its token budget.
+	Priority int
+}
+
+// SystemPrompt is a system prompt built from ordered sections, such as a
+// base persona, a plugin index, active skills, and a per-run addendum, with
+// an optional token budget. Providers receive each section as a system
+// message, which they render in their own form (system blocks for
+// Anthropic, parts of the system instruction for Gemini).
+//
+// Example:
+//
+//	prompt := llm.NewSystemPrompt(
+//	    llm.SystemSection{Name: "persona", Text: "You are a support agent.", Order: llm.SystemOrderBase, Priority: llm.SystemPriorityRequired},
+//	    llm.SystemSection{Name: "skills", Text: p.SkillsIndexSystemMessage(), Order: llm.SystemOrderIndex},
+//	).SetMaxTokens(2000)
+//	resp, err := llm.Call(ctx, question, llm.WithSystemPrompt(prompt))
+type SystemPrompt struct {
+	sections  []SystemSection
+	maxTokens int
+}
+
+// NewSystemPrompt creates a system prompt with the given sections.
+func NewSystemPrompt(sections ...SystemSection) *SystemPrompt {
+	p := &SystemPrompt{}
+	for _, s := range sections {
+		p.Add(s)
+	}
+	return p
+}
+
+// Add adds a section, replacing the section with the same name if any, and
+// returns p.
+func (p *SystemPrompt) Add(s SystemSection) *SystemPrompt {
+	for i := range p.sections {
+		if s.Name != "" && p.sections[i].Name == s.Name {
+			p.sections[i] = s
+			return p
+		}
+	}
+	p.sections = append(p.sections, s)
+	return p
+}
+
+// SetMaxTokens sets the token budget of the prompt, estimated with
+// EstimateTokens, and returns p. Zero means no budget.
+func (p *SystemPrompt) SetMaxTokens(n int) *SystemPrompt {
+	p.maxTokens = n
+	return p
+}
+
+// Sections returns the non-empty sections in order, without those dropped
+// to fit the token budget. Sections are dropped by increasing priority, the
+// later ones first among equal priorities, and required sections are kept
+// even if they exceed the budget.
+func (p *SystemPrompt) Sections() []SystemSection {
+	sections := make([]SystemSection, 0, len(p.sections))
+	for _, s := range p.sections {
+		if strings.TrimSpace(s.Text) != "" {
+			sections = append(sections, s)
+		}
+	}
+	slices.SortStableFunc(sections, func(a, b SystemSection) int {
+		return cmp.Compare(a.Order, b.Order)
+	})
+	if p.maxTokens <= 0 {
+		return sections
+	}
+
+	tokens := 0
+	for _, s := range sections {
+		tokens += EstimateTokens(s.Text)
+	}
+	for tokens > p.maxTokens {
+		drop := -1
+		for i, s := range sections {
+			if s.Priority != SystemPriorityRequired && (drop < 0 || s.Priority <= sections[drop].Priority) {
+				drop = i
+			}
+		}
+		if drop < 0 {
+			break
+		}
+		tokens -= EstimateTokens(sections[drop].Text)
+		sections = slices.Delete(sections, drop, drop+1)
+	}
+	return sections
+}
+
+// String returns the sections joined by blank lines.
+func (p *SystemPrompt) String() string {
+	sections := p.Sections()
+	texts := make([]string, len(sections))
+	for i, s := range sections {
+		texts[i] = s.Text
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// WithSystemPrompt adds the sections of p to the system prompt of the call,
+// after the message set by WithSystemMessage (a required section of order
+// SystemOrderBase), and applies its token budget if set.
+func WithSystemPrompt(p *SystemPrompt) Option {
+	return func(c *callConfig) {
+		c.systemSections = append(slices.Clip(c.systemSections), p.sections...)
+		if p.maxTokens > 0 {
+			c.systemMaxTokens = p.maxTokens
+		}
+	}
+}
+
+// WithSystemSection adds a section to the system prompt of the call,
+// replacing the section with the same name. Unlike WithSystemMessage, which
+// replaces the base system message, sections from several options are
+// layered.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, question,
+//	    llm.WithSystemMessage("You are a support agent."),
+//	    llm.WithSystemSection(llm.SystemSection{Name: "billing", Text: billingSkill, Order: llm.SystemOrderSkill}),
+//	)
+func WithSystemSection(s SystemSection) Option {
+	return func(c *callConfig) {
+		c.systemSections = append(slices.Clip(c.systemSections), s)
+	}
+}
+
+// systemMessages returns the system messages of the call: the message set
+// by WithSystemMessage followed by the sections of its system prompt.
+func (c *callConfig) systemMessages() []provider.Message {
+	prompt := NewSystemPrompt(SystemSection{Text: c.systemMessage, Order: SystemOrderBase, Priority: SystemPriorityRequired})
+	for _, s := range c.systemSections {
+		prompt.Add(s)
+	}
+	prompt.SetMaxTokens(c.systemMaxTokens)
+
+	sections := prompt.Sections()
+	msgs := make([]provider.Message, len(sections))
+	for i, s := range sections {
+		msgs[i] = provider.Message{Role: provider.RoleSystem, Content: s.Text}
+	}
+	return msgs
+}
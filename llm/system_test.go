@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemPrompt(t *testing.T) {
+	prompt := NewSystemPrompt(
+		SystemSection{Name: "run", Text: "Answer in French.", Order: SystemOrderRun},
+		SystemSection{Name: "persona", Text: "You are a librarian.", Order: SystemOrderBase},
+		SystemSection{Name: "skill", Text: "Old skill", Order: SystemOrderSkill},
+		SystemSection{Name: "empty", Text: " ", Order: SystemOrderIndex},
+	)
+	prompt.Add(SystemSection{Name: "skill", Text: "Cataloging skill", Order: SystemOrderSkill})
+	assert.Equal(t, "You are a librarian.\n\nCataloging skill\n\nAnswer in French.", prompt.String())
+}
+
+func TestSystemPrompt_Budget(t *testing.T) {
+	long := strings.Repeat("x", 400) // 100 tokens
+	prompt := NewSystemPrompt(
+		SystemSection{Name: "persona", Text: long, Order: SystemOrderBase, Priority: SystemPriorityRequired},
+		SystemSection{Name: "index", Text: long, Order: SystemOrderIndex, Priority: -1},
+		SystemSection{Name: "skill-a", Text: long, Order: SystemOrderSkill},
+		SystemSection{Name: "skill-b", Text: long, Order: SystemOrderSkill},
+	).SetMaxTokens(250)
+
+	var names []string
+	for _, s := range prompt.Sections() {
+		names = append(names, s.Name)
+	}
+	// The lowest priority goes first, then the later of equal priorities
+	assert.Equal(t, []string{"persona", "skill-a"}, names)
+
+	// Required sections are kept over budget
+	prompt.SetMaxTokens(10)
+	require.Len(t, prompt.Sections(), 1)
+	assert.Equal(t, "persona", prompt.Sections()[0].Name)
+}
+
+func TestWithSystemSection(t *testing.T) {
+	c := newCallConfig()
+	for _, opt := range []Option{
+		WithSystemSection(SystemSection{Name: "skill", Text: "Use the skill.", Order: SystemOrderSkill}),
+		WithSystemMessage("You are helpful."),
+		WithSystemPrompt(NewSystemPrompt(SystemSection{Text: "Be brief.", Order: SystemOrderRun})),
+	} {
+		opt(c)
+	}
+
+	assert.Equal(t, []Message{
+		SystemMessage("You are helpful."),
+		SystemMessage("Use the skill."),
+		SystemMessage("Be brief."),
+		UserMessage("Hello"),
+	}, c.buildRequest("Hello").Messages)
+}
@@ -0,0 +1,37 @@
+package llm
+
+// charsPerToken is the average number of characters per token used for estimates.
+// It is a rough approximation that holds reasonably well for English text
+// across the tokenizers of the supported providers.
+const charsPerToken = 4
+
+// messageOverheadTokens approximates the tokens used by a message's role and framing.
+const messageOverheadTokens = 4
+
+// EstimateTokens returns a rough estimate of the number of tokens in text.
+// It does not use a real tokenizer and should only be used for budgeting.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// EstimateMessageTokens returns a rough estimate of the tokens used by a message,
+// including its tool calls.
+func EstimateMessageTokens(msg Message) int {
+	n := messageOverheadTokens + EstimateTokens(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		n += EstimateTokens(tc.Name) + EstimateTokens(tc.Arguments)
+	}
+	return n
+}
+
+// EstimateMessagesTokens returns a rough estimate of the tokens used by messages.
+func EstimateMessagesTokens(msgs []Message) int {
+	n := 0
+	for _, msg := range msgs {
+		n += EstimateMessageTokens(msg)
+	}
+	return n
+}
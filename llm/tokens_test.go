@@ -0,0 +1,21 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 1, EstimateTokens("abc"))
+	assert.Equal(t, 2, EstimateTokens("hello"))
+
+	msgs := []Message{
+		UserMessage("hello"),
+		AssistantMessageWithToolCalls("", []ToolCall{{Name: "echo", Arguments: `{"a":1}`}}),
+	}
+	assert.Equal(t, 6, EstimateMessageTokens(msgs[0]))
+	assert.Equal(t, 4+1+2, EstimateMessageTokens(msgs[1]))
+	assert.Equal(t, 13, EstimateMessagesTokens(msgs))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/invopop/jsonschema"
 
@@ -22,10 +23,18 @@ type Tool interface {
 	// Parameters returns the JSON schema for the tool's parameters.
 	Parameters() *jsonschema.Schema
 
-	// Execute runs the tool with the given JSON arguments.
+	// Execute runs the tool with the given JSON arguments. Tools that block
+	// or take long, such as commands and HTTP requests, must return promptly
+	// with ctx.Err() (or an error wrapping it) when ctx is done.
 	Execute(ctx context.Context, args json.RawMessage) (any, error)
 }
 
+// ToolResultContent is implemented by tool results that provide the content
+// sent to the model themselves. Other non-string results are sent as JSON.
+type ToolResultContent interface {
+	ToolContent() string
+}
+
 // TypedTool provides type-safe tool creation with auto-generated schema.
 // In is the input type, Out is the output type.
 type TypedTool[In any, Out any] struct {
@@ -59,7 +68,10 @@ func NewTool[In any, Out any](
 	fn func(ctx context.Context, in In) (Out, error),
 ) (*TypedTool[In, Out], error) {
 	var zero In
-	paramSchema := schema.Reflector.Reflect(&zero)
+	paramSchema, err := schema.Reflect(&zero)
+	if err != nil {
+		return nil, fmt.Errorf("creating tool %s: %w", name, err)
+	}
 
 	return &TypedTool[In, Out]{
 		name:        name,
@@ -147,7 +159,23 @@ func (r *ToolRegistry) All() []Tool {
 	return tools
 }
 
-// ExecuteToolCalls executes tool calls and returns tool result messages.
+// ExecuteToolCalls executes tool calls in order and returns tool result
+// messages. Tool errors are reported to the model in the results, as JSON
+// ToolErrorResult objects.
+//
+// ExecuteToolCalls checks ctx before each call: if ctx is done, the
+// remaining calls are not started, and it returns the results of the calls
+// that ran with a *ToolCallsCanceledError, which wraps ctx.Err(). A call in
+// progress when ctx is done sees it through its own ctx and is not
+// interrupted otherwise; its result is included.
+//
+// Example:
+//
+//	msgs, err := llm.ExecuteToolCalls(ctx, resp.ToolCalls(), registry)
+//	var canceled *llm.ToolCallsCanceledError
+//	if errors.As(err, &canceled) {
+//	    log.Printf("%d tool calls not run", len(canceled.Pending))
+//	}
 func ExecuteToolCalls(ctx context.Context, toolCalls []ToolCall, registry *ToolRegistry) ([]Message, error) {
 	if len(toolCalls) == 0 {
 		return nil, nil
@@ -155,28 +183,29 @@ func ExecuteToolCalls(ctx context.Context, toolCalls []ToolCall, registry *ToolR
 
 	messages := make([]Message, 0, len(toolCalls))
 
-	for _, tc := range toolCalls {
+	for i, tc := range toolCalls {
+		if err := ctx.Err(); err != nil {
+			return messages, &ToolCallsCanceledError{Completed: i, Pending: toolCalls[i:], Cause: err}
+		}
+
 		tool, ok := registry.Get(tc.Name)
 		if !ok {
 			return nil, &ToolNotFoundError{Name: tc.Name}
 		}
 
+		start := time.Now()
 		result, err := tool.Execute(ctx, json.RawMessage(tc.Arguments))
+		duration := time.Since(start)
+		logToolCall(ctx, tc, duration, err)
+		if m := Metrics(); m != nil {
+			m.RecordToolExecution(tc.Name, duration, err)
+		}
 		var content string
+		if err == nil {
+			content, err = toolResultContent(result)
+		}
 		if err != nil {
-			content = fmt.Sprintf("Error: %v", err)
-		} else {
-			// Marshal result to JSON if it's not already a string
-			if s, ok := result.(string); ok {
-				content = s
-			} else {
-				bytes, err := json.Marshal(result)
-				if err != nil {
-					content = fmt.Sprintf("Error marshaling result: %v", err)
-				} else {
-					content = string(bytes)
-				}
-			}
+			content = NewToolErrorResult(err).ToolContent()
 		}
 
 		messages = append(messages, ToolMessage(tc.ID, content))
@@ -184,3 +213,20 @@ func ExecuteToolCalls(ctx context.Context, toolCalls []ToolCall, registry *ToolR
 
 	return messages, nil
 }
+
+// toolResultContent returns the content sent to the model for a tool
+// result: strings as they are, the content of ToolResultContent results,
+// and other results as JSON.
+func toolResultContent(result any) (string, error) {
+	switch r := result.(type) {
+	case string:
+		return r, nil
+	case ToolResultContent:
+		return r.ToolContent(), nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("marshaling result: %w", err)
+	}
+	return string(data), nil
+}
@@ -335,8 +335,7 @@ func TestExecuteToolCalls(t *testing.T) {
 			wantErr: false,
 			checkMsgs: func(t *testing.T, msgs []Message) {
 				require.Len(t, msgs, 1)
-				assert.Contains(t, msgs[0].Content, "Error:")
-				assert.Contains(t, msgs[0].Content, "tool execution failed")
+				assert.JSONEq(t, `{"error_type": "execution_error", "message": "tool execution failed", "retryable": false}`, msgs[0].Content)
 			},
 		},
 	}
@@ -361,3 +360,36 @@ func TestExecuteToolCalls(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteToolCalls_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ran []string
+	registry := NewToolRegistry()
+	registry.Register(MustNewTool("step", "runs a step",
+		func(ctx context.Context, in TestInput) (string, error) {
+			ran = append(ran, in.Name)
+			if in.Name == "stop" {
+				cancel()
+				return "", ctx.Err()
+			}
+			return "done", nil
+		}))
+
+	calls := []ToolCall{
+		{ID: "1", Name: "step", Arguments: `{"name": "first"}`},
+		{ID: "2", Name: "step", Arguments: `{"name": "stop"}`},
+		{ID: "3", Name: "step", Arguments: `{"name": "third"}`},
+	}
+	msgs, err := ExecuteToolCalls(ctx, calls, registry)
+
+	assert.Equal(t, []string{"first", "stop"}, ran)
+	assert.Equal(t, []Message{ToolMessage("1", "done"), ToolMessage("2", `{"error_type":"canceled","message":"context canceled","retryable":false}`)}, msgs)
+	var canceled *ToolCallsCanceledError
+	require.ErrorAs(t, err, &canceled)
+	assert.Equal(t, 2, canceled.Completed)
+	assert.Equal(t, calls[2:], canceled.Pending)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.EqualError(t, err, "tool calls canceled after 2 of 3: context canceled")
+}
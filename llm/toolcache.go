@@ -0,0 +1,224 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ToolCache caches the results of tools by tool name and arguments, so that
+// expensive idempotent tools (web search, Wikipedia, reads of unchanged
+// files) are not executed again with the same arguments within its TTL.
+// Caching is opt-in: only tools wrapped with Wrap use the cache. Errors are
+// not cached. A ToolCache is safe for concurrent use; use one per session to
+// keep results from leaking between sessions.
+//
+// Example:
+//
+//	cache := llm.NewToolCache(10*time.Minute,
+//	    llm.WithToolCacheKey("read", tools.ReadCacheKey), // Re-read files that changed
+//	)
+//	cached := cache.Wrap(tools.MustWebSearch(), tools.MustWikipedia(), tools.MustRead())
+//	resp, err := llm.Call(ctx, question, llm.WithTools(append(cached, tools.MustBash())...))
+type ToolCache struct {
+	ttl        time.Duration
+	maxEntries int
+	keys       map[string]func(args json.RawMessage) (string, error)
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]toolCacheEntry
+	hits    int
+	misses  int
+}
+
+// toolCacheEntry is a cached tool result.
+type toolCacheEntry struct {
+	tool    string
+	result  any
+	expires time.Time
+}
+
+// ToolCacheOption configures a ToolCache.
+type ToolCacheOption func(*ToolCache)
+
+// WithToolCacheMaxEntries limits the number of cached results; the results
+// closest to expiring are evicted first. The default is 1000.
+func WithToolCacheMaxEntries(n int) ToolCacheOption {
+	return func(c *ToolCache) {
+		c.maxEntries = n
+	}
+}
+
+// WithToolCacheKey adds the string that fn returns for the arguments of the
+// named tool to its cache key, e.g. the modification time of the file a
+// tool reads, so that results are not reused once it changes. If fn fails,
+// the call is not cached.
+func WithToolCacheKey(tool string, fn func(args json.RawMessage) (string, error)) ToolCacheOption {
+	return func(c *ToolCache) {
+		c.keys[tool] = fn
+	}
+}
+
+// NewToolCache creates a tool cache whose results expire after ttl.
+func NewToolCache(ttl time.Duration, opts ...ToolCacheOption) *ToolCache {
+	c := &ToolCache{
+		ttl:        ttl,
+		maxEntries: 1000,
+		keys:       make(map[string]func(json.RawMessage) (string, error)),
+		now:        time.Now,
+		entries:    make(map[string]toolCacheEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Wrap returns tools that execute through the cache.
+func (c *ToolCache) Wrap(tools ...Tool) []Tool {
+	wrapped := make([]Tool, len(tools))
+	for i, t := range tools {
+		wrapped[i] = &cachedTool{Tool: t, cache: c}
+	}
+	return wrapped
+}
+
+// Invalidate removes the cached results of the named tools, or of all tools
+// if no names are given.
+func (c *ToolCache) Invalidate(tools ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(tools) == 0 {
+		clear(c.entries)
+		return
+	}
+	for key, e := range c.entries {
+		for _, name := range tools {
+			if e.tool == name {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
+
+// ToolCacheStats are statistics of a ToolCache.
+type ToolCacheStats struct {
+	Entries int // Cached results, including expired ones not evicted yet
+	Hits    int // Calls answered from the cache
+	Misses  int // Calls executed by the tool
+}
+
+// Stats returns statistics of the cache.
+func (c *ToolCache) Stats() ToolCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ToolCacheStats{Entries: len(c.entries), Hits: c.hits, Misses: c.misses}
+}
+
+// key returns the cache key of a call of tool with args, or false if the
+// call cannot be cached.
+func (c *ToolCache) key(tool string, args json.RawMessage) (string, bool) {
+	normalized, err := normalizeJSON(args)
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s", tool, normalized)
+	if fn, ok := c.keys[tool]; ok {
+		extra, err := fn(args)
+		if err != nil {
+			return "", false
+		}
+		fmt.Fprintf(h, "\x00%s", extra)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// get returns the unexpired result cached under key.
+func (c *ToolCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && c.now().Before(e.expires) {
+		c.hits++
+		return e.result, true
+	}
+	if ok {
+		delete(c.entries, key)
+	}
+	c.misses++
+	return nil, false
+}
+
+// put caches the result of tool under key, evicting results if the cache
+// is full.
+func (c *ToolCache) put(key, tool string, result any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		for len(c.entries) >= c.maxEntries {
+			var oldest string
+			for k, e := range c.entries {
+				if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+					oldest = k
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = toolCacheEntry{tool: tool, result: result, expires: now.Add(c.ttl)}
+}
+
+// cachedTool is a tool that executes through a ToolCache.
+type cachedTool struct {
+	Tool
+	cache *ToolCache
+}
+
+func (t *cachedTool) Execute(ctx context.Context, args json.RawMessage) (any, error) {
+	key, ok := t.cache.key(t.Name(), args)
+	if !ok {
+		return t.Tool.Execute(ctx, args)
+	}
+	if result, ok := t.cache.get(key); ok {
+		if logger := Logger(); logger != nil {
+			logger.LogAttrs(ctx, slog.LevelDebug, "tool cache hit", slog.String("tool", t.Name()))
+		}
+		return result, nil
+	}
+	result, err := t.Tool.Execute(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	t.cache.put(key, t.Name(), result)
+	return result, nil
+}
+
+// normalizeJSON returns data re-encoded with sorted object keys and no
+// insignificant whitespace, so that equivalent arguments have the same key.
+// Empty arguments are normalized to {}.
+func normalizeJSON(data json.RawMessage) ([]byte, error) {
+	if len(data) == 0 {
+		return []byte("{}"), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
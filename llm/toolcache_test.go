@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type searchArgs struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+// countingTool returns a tool that counts its executions and fails for the
+// query "fail".
+func countingTool(t *testing.T, name string, calls *int) Tool {
+	t.Helper()
+	return MustNewTool(name, "Search", func(ctx context.Context, args searchArgs) (string, error) {
+		*calls++
+		if args.Query == "fail" {
+			return "", errors.New("unavailable")
+		}
+		return "results for " + args.Query, nil
+	})
+}
+
+func TestToolCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewToolCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	var calls int
+	search := cache.Wrap(countingTool(t, "search", &calls))[0]
+	assert.Equal(t, "search", search.Name())
+
+	result, err := search.Execute(ctx, json.RawMessage(`{"query": "go", "limit": 5}`))
+	require.NoError(t, err)
+	assert.Equal(t, "results for go", result)
+
+	// Equivalent arguments are served from the cache
+	result, err = search.Execute(ctx, json.RawMessage(`{"limit":5,"query":"go"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "results for go", result)
+	assert.Equal(t, 1, calls)
+
+	// Other arguments are not
+	_, err = search.Execute(ctx, json.RawMessage(`{"query": "rust", "limit": 5}`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// Errors are not cached
+	for range 2 {
+		_, err = search.Execute(ctx, json.RawMessage(`{"query": "fail"}`))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 4, calls)
+
+	// Results expire after the TTL
+	now = now.Add(2 * time.Minute)
+	_, err = search.Execute(ctx, json.RawMessage(`{"query": "go", "limit": 5}`))
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls)
+
+	assert.Equal(t, ToolCacheStats{Entries: 2, Hits: 1, Misses: 5}, cache.Stats())
+}
+
+func TestToolCache_PerToolKeys(t *testing.T) {
+	ctx := context.Background()
+	version := "1"
+	cache := NewToolCache(time.Minute, WithToolCacheKey("read", func(args json.RawMessage) (string, error) {
+		if version == "" {
+			return "", errors.New("no version")
+		}
+		return version, nil
+	}))
+
+	var reads, searches int
+	wrapped := cache.Wrap(countingTool(t, "read", &reads), countingTool(t, "search", &searches))
+	read, search := wrapped[0], wrapped[1]
+	args := json.RawMessage(`{"query": "main.go"}`)
+
+	_, _ = read.Execute(ctx, args)
+	_, _ = read.Execute(ctx, args)
+	assert.Equal(t, 1, reads)
+
+	// The key changes with the version of what the tool reads
+	version = "2"
+	_, _ = read.Execute(ctx, args)
+	assert.Equal(t, 2, reads)
+
+	// Without a key, calls are not cached
+	version = ""
+	_, _ = read.Execute(ctx, args)
+	_, _ = read.Execute(ctx, args)
+	assert.Equal(t, 4, reads)
+
+	_, _ = search.Execute(ctx, args)
+	cache.Invalidate("read")
+	assert.Equal(t, 1, cache.Stats().Entries)
+	cache.Invalidate()
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestToolCache_MaxEntries(t *testing.T) {
+	ctx := context.Background()
+	cache := NewToolCache(time.Minute, WithToolCacheMaxEntries(2))
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	var calls int
+	search := cache.Wrap(countingTool(t, "search", &calls))[0]
+	for _, q := range []string{"a", "b", "c"} {
+		now = now.Add(time.Second)
+		_, err := search.Execute(ctx, json.RawMessage(`{"query": "`+q+`"}`))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, cache.Stats().Entries)
+
+	// The oldest result was evicted
+	_, _ = search.Execute(ctx, json.RawMessage(`{"query": "c"}`))
+	assert.Equal(t, 3, calls)
+	_, _ = search.Execute(ctx, json.RawMessage(`{"query": "a"}`))
+	assert.Equal(t, 4, calls)
+}
+
+func TestToolCache_ExecuteToolCalls(t *testing.T) {
+	var calls int
+	registry := NewToolRegistry()
+	registry.Register(NewToolCache(time.Minute).Wrap(countingTool(t, "search", &calls))...)
+
+	toolCalls := []ToolCall{
+		{ID: "1", Name: "search", Arguments: `{"query": "go"}`},
+		{ID: "2", Name: "search", Arguments: `{"query": "go"}`},
+	}
+	msgs, err := ExecuteToolCalls(context.Background(), toolCalls, registry)
+	require.NoError(t, err)
+	assert.Equal(t, []Message{ToolMessage("1", "results for go"), ToolMessage("2", "results for go")}, msgs)
+	assert.Equal(t, 1, calls)
+}
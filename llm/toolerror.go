@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Types of tool errors, the error_type of ToolErrorResult.
+const (
+	ToolErrorInvalidArguments = "invalid_arguments" // The arguments do not match the tool's parameters
+	ToolErrorNotFound         = "not_found"         // A file or resource does not exist
+	ToolErrorPermission       = "permission_denied" // Access to a file or resource is denied
+	ToolErrorTimeout          = "timeout"           // The tool took too long
+	ToolErrorCanceled         = "canceled"          // The call was canceled
+	ToolErrorRejected         = "rejected"          // The call was vetoed, e.g. by the user
+	ToolErrorNotExecuted      = "not_executed"      // The call was never run, e.g. after an interruption
+	ToolErrorExecution        = "execution_error"   // Any other error
+)
+
+// ToolErrorResult is the tool result sent to the model for a failed tool
+// call: a JSON object that tells the model what went wrong and how to
+// recover, so that it can correct its call instead of guessing from a
+// free-text message.
+//
+//	{"error_type": "not_found", "message": "open main.go: no such file or directory",
+//	 "retryable": false, "suggestion": "Check the name or path, e.g. by listing or searching first."}
+type ToolErrorResult struct {
+	ErrorType  string `json:"error_type"`
+	Message    string `json:"message"`
+	Retryable  bool   `json:"retryable"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// ToolContent returns the result as JSON.
+func (r ToolErrorResult) ToolContent() string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// NewToolErrorResult returns the tool result of err. A *ToolError in the
+// chain of err sets the type, retryability, and suggestion; other errors
+// are classified by their cause: invalid JSON arguments, missing files,
+// denied permissions, timeouts, and cancellations. ExecuteToolCalls sends
+// it for the errors of tools.
+//
+// Example:
+//
+//	// In a tool: let the model know that it may retry
+//	return Out{}, &llm.ToolError{
+//	    ToolName:   "search",
+//	    Cause:      err,
+//	    Type:       llm.ToolErrorExecution,
+//	    Retryable:  true,
+//	    Suggestion: "The search backend is overloaded; retry in a moment.",
+//	}
+func NewToolErrorResult(err error) ToolErrorResult {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		result := classifyToolError(toolErr.Cause)
+		if toolErr.Cause == nil {
+			result.Message = fmt.Sprintf("tool %q failed", toolErr.ToolName)
+		}
+		if toolErr.Type != "" {
+			result.ErrorType = toolErr.Type
+			result.Retryable = toolErr.Retryable
+			result.Suggestion = ""
+		}
+		if toolErr.Suggestion != "" {
+			result.Suggestion = toolErr.Suggestion
+		}
+		return result
+	}
+	return classifyToolError(err)
+}
+
+// classifyToolError returns the tool result of err from its cause.
+func classifyToolError(err error) ToolErrorResult {
+	result := ToolErrorResult{ErrorType: ToolErrorExecution}
+	if err != nil {
+		result.Message = err.Error()
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		result.ErrorType = ToolErrorInvalidArguments
+		result.Suggestion = "Fix the arguments to match the parameters schema of the tool."
+	case errors.Is(err, context.DeadlineExceeded):
+		result.ErrorType = ToolErrorTimeout
+		result.Retryable = true
+		result.Suggestion = "Retry, or make the request smaller."
+	case errors.Is(err, context.Canceled):
+		result.ErrorType = ToolErrorCanceled
+	case errors.Is(err, fs.ErrNotExist):
+		result.ErrorType = ToolErrorNotFound
+		result.Suggestion = "Check the name or path, e.g. by listing or searching first."
+	case errors.Is(err, fs.ErrPermission):
+		result.ErrorType = ToolErrorPermission
+		result.Suggestion = "Do not retry; use another file or resource, or ask the user for access."
+	}
+	return result
+}
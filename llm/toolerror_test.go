@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewToolErrorResult(t *testing.T) {
+	_, notExist := os.Open("/nonexistent/file")
+	tests := []struct {
+		name string
+		err  error
+		want ToolErrorResult
+	}{
+		{
+			name: "other error",
+			err:  errors.New("boom"),
+			want: ToolErrorResult{ErrorType: ToolErrorExecution, Message: "boom"},
+		},
+		{
+			name: "missing file",
+			err:  fmt.Errorf("reading: %w", notExist),
+			want: ToolErrorResult{ErrorType: ToolErrorNotFound, Message: "reading: " + notExist.Error(), Suggestion: "Check the name or path, e.g. by listing or searching first."},
+		},
+		{
+			name: "timeout",
+			err:  context.DeadlineExceeded,
+			want: ToolErrorResult{ErrorType: ToolErrorTimeout, Message: "context deadline exceeded", Retryable: true, Suggestion: "Retry, or make the request smaller."},
+		},
+		{
+			name: "tool error",
+			err: &ToolError{
+				ToolName:   "search",
+				Cause:      errors.New("overloaded"),
+				Type:       ToolErrorExecution,
+				Retryable:  true,
+				Suggestion: "Retry in a moment.",
+			},
+			want: ToolErrorResult{ErrorType: ToolErrorExecution, Message: "overloaded", Retryable: true, Suggestion: "Retry in a moment."},
+		},
+		{
+			name: "tool error classified by its cause",
+			err:  fmt.Errorf("wrapped: %w", &ToolError{ToolName: "sleep", Cause: context.DeadlineExceeded}),
+			want: ToolErrorResult{ErrorType: ToolErrorTimeout, Message: "context deadline exceeded", Retryable: true, Suggestion: "Retry, or make the request smaller."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewToolErrorResult(tt.err))
+		})
+	}
+}
+
+func TestExecuteToolCalls_InvalidArguments(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(MustNewTool("greet", "greets",
+		func(ctx context.Context, in TestInput) (string, error) {
+			return "Hello, " + in.Name, nil
+		}))
+
+	msgs, err := ExecuteToolCalls(context.Background(), []ToolCall{{ID: "1", Name: "greet", Arguments: `{"name": 42}`}}, registry)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.JSONEq(t, `{
+		"error_type": "invalid_arguments",
+		"message": "failed to unmarshal tool arguments: json: cannot unmarshal number into Go struct field TestInput.name of type string",
+		"retryable": false,
+		"suggestion": "Fix the arguments to match the parameters schema of the tool."
+	}`, msgs[0].Content)
+}
@@ -0,0 +1,355 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Translation is the result of Translate.
+type Translation struct {
+	Text     string
+	Segments int   // Number of segments translated
+	Calls    int   // Number of LLM calls made
+	Usage    Usage // Total usage of all calls
+}
+
+// TranslateOption configures Translate. See WithTranslateOptions.
+type TranslateOption func(*translateConfig)
+
+// translateConfig holds the configuration of Translate.
+type translateConfig struct {
+	source       string
+	glossary     map[string]string
+	batchTokens  int
+	concurrency  int
+	instructions string
+}
+
+// Defaults of Translate.
+const (
+	defaultTranslateBatchTokens = 2000
+	defaultTranslateConcurrency = 4
+)
+
+// TranslateFrom sets the language of the text (default: detected by the model).
+func TranslateFrom(language string) TranslateOption {
+	return func(c *translateConfig) {
+		c.source = language
+	}
+}
+
+// TranslateGlossary sets the translations of terms, such as product names
+// and domain vocabulary, that the model must use.
+func TranslateGlossary(glossary map[string]string) TranslateOption {
+	return func(c *translateConfig) {
+		c.glossary = glossary
+	}
+}
+
+// TranslateBatchTokens sets the maximum estimated tokens of the segments
+// translated in one call (default: 2000).
+func TranslateBatchTokens(n int) TranslateOption {
+	return func(c *translateConfig) {
+		c.batchTokens = n
+	}
+}
+
+// TranslateConcurrency sets the number of batches translated at the same
+// time (default: 4).
+func TranslateConcurrency(n int) TranslateOption {
+	return func(c *translateConfig) {
+		c.concurrency = n
+	}
+}
+
+// TranslateInstructions adds instructions to every translation prompt,
+// e.g. the tone or the audience.
+func TranslateInstructions(instructions string) TranslateOption {
+	return func(c *translateConfig) {
+		c.instructions = instructions
+	}
+}
+
+// WithTranslateOptions configures Translate.
+//
+// Example:
+//
+//	translation, err := llm.Translate(ctx, readme, "Japanese",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithTranslateOptions(llm.TranslateGlossary(map[string]string{"agent": "エージェント"})),
+//	)
+func WithTranslateOptions(opts ...TranslateOption) Option {
+	return func(c *callConfig) {
+		c.translateOptions = append(c.translateOptions, opts...)
+	}
+}
+
+// translationBatch is the structured output of a translation call.
+type translationBatch struct {
+	Translations []string `json:"translations" jsonschema:"required,description=The translated segments in the order of the input"`
+}
+
+// Translate translates Markdown or plain text into the target language,
+// preserving its formatting. Fenced and indented code blocks are kept as
+// they are; the other blocks are translated as segments, with inline code,
+// URLs, and HTML tags replaced by placeholders that the model must keep.
+// Segments are translated in batches of up to TranslateBatchTokens, with
+// the glossary set by TranslateGlossary.
+//
+// Example:
+//
+//	translation, err := llm.Translate(ctx, "# Install\n\nRun `go get`.", "French",
+//	    llm.WithProvider("anthropic"),
+//	    llm.WithModel("claude-haiku-4-5"),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(translation.Text) // "# Installation\n\nExécutez `go get`."
+func Translate(ctx context.Context, text, targetLanguage string, opts ...Option) (Translation, error) {
+	cfg := newCallConfig()
+	cfg.apply(opts...)
+	tc := &translateConfig{batchTokens: defaultTranslateBatchTokens, concurrency: defaultTranslateConcurrency}
+	for _, opt := range cfg.translateOptions {
+		opt(tc)
+	}
+	if tc.batchTokens <= 0 {
+		tc.batchTokens = defaultTranslateBatchTokens
+	}
+
+	blocks := splitMarkdownBlocks(text)
+	var segments []*translationSegment
+	for _, block := range blocks {
+		if block.translate {
+			segments = append(segments, protect(block))
+		}
+	}
+
+	// Batch consecutive segments up to the token limit
+	var batches [][]*translationSegment
+	tokens := 0
+	for _, segment := range segments {
+		n := EstimateTokens(segment.text)
+		if len(batches) == 0 || tokens+n > tc.batchTokens {
+			batches = append(batches, nil)
+			tokens = 0
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], segment)
+		tokens += n
+	}
+
+	usages := make([]Usage, len(batches))
+	errs := forEachChunk(ctx, len(batches), tc.concurrency, func(i int) error {
+		var err error
+		usages[i], err = translateBatch(ctx, batches[i], targetLanguage, tc, opts)
+		return err
+	})
+
+	out := Translation{Segments: len(segments), Calls: len(batches)}
+	for i, err := range errs {
+		if err != nil {
+			return Translation{}, fmt.Errorf("translating batch %d of %d: %w", i+1, len(batches), err)
+		}
+		out.Usage = addUsage(out.Usage, usages[i])
+	}
+
+	var sb strings.Builder
+	for _, block := range blocks {
+		sb.WriteString(block.prefix)
+		if block.segment != nil {
+			sb.WriteString(block.segment.translation)
+		} else {
+			sb.WriteString(block.text)
+		}
+		sb.WriteString(block.suffix)
+	}
+	out.Text = sb.String()
+	return out, nil
+}
+
+// translateBatch translates the segments of a batch in place.
+func translateBatch(ctx context.Context, batch []*translationSegment, targetLanguage string, tc *translateConfig, opts []Option) (Usage, error) {
+	texts := make([]string, len(batch))
+	for i, segment := range batch {
+		texts[i] = segment.text
+	}
+	input, err := json.MarshalIndent(texts, "", "  ")
+	if err != nil {
+		return Usage{}, err
+	}
+
+	resp, err := CallParse[translationBatch](ctx, translatePrompt(string(input), targetLanguage, tc), opts...)
+	if err != nil {
+		return Usage{}, err
+	}
+	parsed, err := resp.Parsed()
+	if err != nil {
+		return resp.Usage(), err
+	}
+	if len(parsed.Translations) != len(batch) {
+		return resp.Usage(), fmt.Errorf("got %d translations for %d segments", len(parsed.Translations), len(batch))
+	}
+	for i, segment := range batch {
+		if err := segment.restore(parsed.Translations[i]); err != nil {
+			return resp.Usage(), err
+		}
+	}
+	return resp.Usage(), nil
+}
+
+// translatePrompt returns the prompt to translate the JSON array of segments input.
+func translatePrompt(input, targetLanguage string, tc *translateConfig) string {
+	var sb strings.Builder
+	sb.WriteString("Translate each segment in the JSON array below")
+	if tc.source != "" {
+		sb.WriteString(" from " + tc.source)
+	}
+	sb.WriteString(" into " + targetLanguage + ". ")
+	sb.WriteString("Preserve Markdown syntax, line breaks, and placeholders such as ⟦0⟧ exactly as they are. ")
+	sb.WriteString("Return one translation per segment, in the same order.")
+	if tc.instructions != "" {
+		sb.WriteString(" " + tc.instructions)
+	}
+	if len(tc.glossary) > 0 {
+		sb.WriteString("\n\nUse these translations for the following terms:\n")
+		terms := make([]string, 0, len(tc.glossary))
+		for term := range tc.glossary {
+			terms = append(terms, term)
+		}
+		slices.Sort(terms)
+		for _, term := range terms {
+			fmt.Fprintf(&sb, "- %s → %s\n", term, tc.glossary[term])
+		}
+	}
+	sb.WriteString("\n\n<segments>\n")
+	sb.WriteString(input)
+	sb.WriteString("\n</segments>")
+	return sb.String()
+}
+
+// markdownBlock is a block of a Markdown document: a paragraph, heading,
+// list, or table to translate, or a code block or blank lines to keep.
+type markdownBlock struct {
+	prefix, text, suffix string // Whitespace around text is kept as is
+	translate            bool
+	segment              *translationSegment
+}
+
+// fenceLine matches the opening or closing line of a fenced code block.
+var fenceLine = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+
+// splitMarkdownBlocks splits text into blocks separated by blank lines,
+// keeping fenced code blocks whole. Concatenating the blocks returns text.
+func splitMarkdownBlocks(text string) []*markdownBlock {
+	var blocks []*markdownBlock
+	var current strings.Builder
+	fence := ""
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		raw := current.String()
+		current.Reset()
+		trimmed := strings.TrimSpace(raw)
+		start := strings.Index(raw, trimmed)
+		block := &markdownBlock{
+			prefix: raw[:start],
+			text:   trimmed,
+			suffix: raw[start+len(trimmed):],
+		}
+		block.translate = trimmed != "" && !fenceLine.MatchString(trimmed) && !isIndentedCode(raw)
+		blocks = append(blocks, block)
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if fence != "" {
+			current.WriteString(line)
+			if m := fenceLine.FindStringSubmatch(line); m != nil && strings.HasPrefix(m[1], fence[:1]) && len(m[1]) >= len(fence) &&
+				strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), fence[:1])) == "" {
+				fence = ""
+				flush()
+			}
+			continue
+		}
+		if m := fenceLine.FindStringSubmatch(line); m != nil {
+			flush()
+			fence = m[1]
+			current.WriteString(line)
+			continue
+		}
+		current.WriteString(line)
+		if strings.TrimSpace(line) == "" {
+			flush()
+		}
+	}
+	flush()
+	return blocks
+}
+
+// isIndentedCode reports whether every non-blank line of block is indented
+// by four spaces or a tab.
+func isIndentedCode(block string) bool {
+	indented := false
+	for _, line := range strings.Split(block, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "    ") && !strings.HasPrefix(line, "\t") {
+			return false
+		}
+		indented = true
+	}
+	return indented
+}
+
+// protectedSpan matches inline code, link destinations, autolinks and bare
+// URLs, and HTML tags, which are not translated.
+var protectedSpan = regexp.MustCompile("`+[^`]*`+|\\]\\([^)]*\\)|<https?://[^>]*>|https?://[^\\s)>\\]]+|</?[A-Za-z][^>]*>")
+
+// translationSegment is the text of a block with protected spans replaced
+// by placeholders.
+type translationSegment struct {
+	text        string
+	protected   []string
+	translation string
+}
+
+// protect returns the segment of block and links it to the block.
+func protect(block *markdownBlock) *translationSegment {
+	segment := &translationSegment{}
+	segment.text = protectedSpan.ReplaceAllStringFunc(block.text, func(span string) string {
+		placeholder := fmt.Sprintf("⟦%d⟧", len(segment.protected))
+		segment.protected = append(segment.protected, span)
+		return placeholder
+	})
+	block.segment = segment
+	return segment
+}
+
+// restore sets the translation of the segment, replacing the placeholders
+// in translated with the protected spans.
+func (s *translationSegment) restore(translated string) error {
+	for i, span := range s.protected {
+		placeholder := fmt.Sprintf("⟦%d⟧", i)
+		if !strings.Contains(translated, placeholder) {
+			return fmt.Errorf("translation of %q dropped placeholder %s for %q", truncate(s.text, 40), placeholder, span)
+		}
+		translated = strings.Replace(translated, placeholder, span, 1)
+	}
+	s.translation = strings.TrimSpace(translated)
+	return nil
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
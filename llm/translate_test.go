@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperTranslator returns a prompt function that "translates" the segments
+// of a prompt by upper-casing them, and records them.
+func upperTranslator(t *testing.T, segments *[]string) func(prompt string) string {
+	return func(prompt string) string {
+		start := strings.Index(prompt, "<segments>\n") + len("<segments>\n")
+		end := strings.LastIndex(prompt, "\n</segments>")
+		var in []string
+		require.NoError(t, json.Unmarshal([]byte(prompt[start:end]), &in))
+		out := make([]string, len(in))
+		for i, s := range in {
+			out[i] = strings.ToUpper(s)
+		}
+		if segments != nil {
+			*segments = append(*segments, in...)
+		}
+		b, _ := json.Marshal(map[string][]string{"translations": out})
+		return string(b)
+	}
+}
+
+const translateDocument = "# Getting started\n\nRun `go get` and see [the docs](https://example.com/docs).\n\n" +
+	"```go\nfmt.Println(\"hello\")\n```\n\n    indented code\n\n- first item\n- second item\n"
+
+func TestTranslate_PreservesMarkdown(t *testing.T) {
+	var segments []string
+	p, opts := registerPrompt(t, upperTranslator(t, &segments))
+
+	translation, err := Translate(context.Background(), translateDocument, "Upper", opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "# GETTING STARTED\n\nRUN `go get` AND SEE [THE DOCS](https://example.com/docs).\n\n"+
+		"```go\nfmt.Println(\"hello\")\n```\n\n    indented code\n\n- FIRST ITEM\n- SECOND ITEM\n", translation.Text)
+	assert.Equal(t, []string{"# Getting started", "Run ⟦0⟧ and see [the docs⟦1⟧.", "- first item\n- second item"}, segments)
+	assert.Equal(t, 3, translation.Segments)
+	assert.Equal(t, 1, translation.Calls)
+	assert.Equal(t, int64(1), p.calls.Load())
+	assert.Equal(t, 15, translation.Usage.TotalTokens)
+}
+
+func TestTranslate_Batches(t *testing.T) {
+	p, opts := registerPrompt(t, upperTranslator(t, nil))
+
+	translation, err := Translate(context.Background(), fiveParagraphs, "Upper",
+		append(opts, WithTranslateOptions(TranslateBatchTokens(20)))...)
+	require.NoError(t, err)
+	assert.Equal(t, strings.ToUpper(fiveParagraphs), translation.Text)
+	assert.Equal(t, 5, translation.Segments)
+	assert.Equal(t, 3, translation.Calls)
+	assert.Equal(t, int64(3), p.calls.Load())
+	assert.Equal(t, 45, translation.Usage.TotalTokens)
+}
+
+func TestTranslate_Prompt(t *testing.T) {
+	_, opts := registerPrompt(t, func(prompt string) string {
+		assert.Contains(t, prompt, "from English into French. ")
+		assert.Contains(t, prompt, " Use a formal tone.")
+		assert.Contains(t, prompt, "- agent → agent\n- tool → outil\n")
+		return `{"translations": ["Bonjour"]}`
+	})
+
+	translation, err := Translate(context.Background(), "Hello", "French", append(opts, WithTranslateOptions(
+		TranslateFrom("English"),
+		TranslateInstructions("Use a formal tone."),
+		TranslateGlossary(map[string]string{"tool": "outil", "agent": "agent"}),
+	))...)
+	require.NoError(t, err)
+	assert.Equal(t, "Bonjour", translation.Text)
+}
+
+func TestTranslate_Errors(t *testing.T) {
+	_, opts := registerPrompt(t, func(prompt string) string {
+		return `{"translations": ["Exécutez go get."]}`
+	})
+	_, err := Translate(context.Background(), "Run `go get`.", "French", opts...)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "translating batch 1 of 1")
+	assert.Contains(t, err.Error(), "dropped placeholder ⟦0⟧")
+
+	_, err = Translate(context.Background(), "One.\n\nTwo.", "French", opts...)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "got 1 translations for 2 segments")
+}
+
+func TestSplitMarkdownBlocks(t *testing.T) {
+	text := "\n\nIntro\n\n~~~\ncode\n\nmore ```\n~~~\nAfter\n"
+	blocks := splitMarkdownBlocks(text)
+
+	var joined strings.Builder
+	var translated []string
+	for _, block := range blocks {
+		joined.WriteString(block.prefix + block.text + block.suffix)
+		if block.translate {
+			translated = append(translated, block.text)
+		}
+	}
+	assert.Equal(t, text, joined.String())
+	assert.Equal(t, []string{"Intro", "After"}, translated)
+}
@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// defaultTruncateKeep is the default fraction of the conversation kept by
+// WithAutoTruncate.
+const defaultTruncateKeep = 0.5
+
+// Truncation describes how WithAutoTruncate shortened the conversation of a
+// call that exceeded the model's context window.
+type Truncation struct {
+	Dropped      []Message // The messages dropped, oldest first
+	Summary      string    // The summary that replaced them, if TruncateSummarize is set
+	TokensBefore int       // Estimated tokens of the conversation before
+	TokensAfter  int       // Estimated tokens of the conversation after
+}
+
+// TruncateOption configures WithAutoTruncate.
+type TruncateOption func(*truncateConfig)
+
+// truncateConfig holds the configuration of WithAutoTruncate.
+type truncateConfig struct {
+	keep        float64
+	summarize   bool
+	summaryOpts []Option
+}
+
+// TruncateKeep sets the fraction of the estimated tokens of the non-system
+// messages that is kept (default: 0.5).
+func TruncateKeep(fraction float64) TruncateOption {
+	return func(c *truncateConfig) {
+		c.keep = fraction
+	}
+}
+
+// TruncateSummarize replaces the dropped messages with a system message
+// summarizing them, made with the provider and model of the call unless
+// opts set others, e.g. a cheaper model.
+func TruncateSummarize(opts ...Option) TruncateOption {
+	return func(c *truncateConfig) {
+		c.summarize = true
+		c.summaryOpts = opts
+	}
+}
+
+// WithAutoTruncate retries a call rejected for exceeding the model's
+// context window (see provider.IsContextOverflow) once, after dropping the
+// oldest non-system messages, so that long conversations do not just fail.
+// System messages and the last message are kept, and tool calls are
+// dropped with their results. Response.Truncation reports what was
+// dropped.
+//
+// Example:
+//
+//	resp, err := llm.CallMessages(ctx, history,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithAutoTruncate(llm.TruncateSummarize(llm.WithModel("gpt-4o-mini"))),
+//	)
+//	if t := resp.Truncation(); t != nil {
+//	    log.Printf("dropped %d messages", len(t.Dropped))
+//	}
+func WithAutoTruncate(opts ...TruncateOption) Option {
+	return func(c *callConfig) {
+		tc := &truncateConfig{keep: defaultTruncateKeep}
+		for _, opt := range opts {
+			opt(tc)
+		}
+		c.autoTruncate = tc
+	}
+}
+
+// Truncation returns how the conversation was shortened before the call
+// succeeded, or nil if it was not (see WithAutoTruncate).
+func (r Response[T]) Truncation() *Truncation {
+	return r.truncation
+}
+
+// truncate shortens the messages of req after err, a context overflow, if
+// the call truncates them. It reports whether req should be sent again.
+func (c *callConfig) truncate(ctx context.Context, req *provider.Request, err error) (bool, error) {
+	if c.autoTruncate == nil || c.truncation != nil || !provider.IsContextOverflow(err) {
+		return false, nil
+	}
+	before := EstimateMessagesTokens(req.Messages)
+	kept, dropped := truncateMessages(req.Messages, c.autoTruncate.keep)
+	if len(dropped) == 0 {
+		return false, nil
+	}
+
+	t := &Truncation{Dropped: dropped}
+	if c.autoTruncate.summarize {
+		summary, err := c.summarizeDropped(ctx, dropped)
+		if err != nil {
+			return false, fmt.Errorf("summarizing truncated messages: %w", err)
+		}
+		t.Summary = summary
+		kept = insertSummary(kept, truncationSummaryPrefix+summary)
+	}
+	req.Messages = kept
+	t.TokensBefore = before
+	t.TokensAfter = EstimateMessagesTokens(kept)
+	c.truncation = t
+
+	if logger := c.log(); logger != nil {
+		logger.LogAttrs(ctx, slog.LevelWarn, "llm context truncated", append(c.logAttrs(),
+			slog.Int("dropped", len(dropped)),
+			slog.Int("tokens_before", t.TokensBefore),
+			slog.Int("tokens_after", t.TokensAfter),
+		)...)
+	}
+	return true, nil
+}
+
+// truncateMessages drops the oldest non-system messages of msgs until the
+// estimated tokens of the others are at most keep times what they were, or
+// only the last message remains. An assistant message with tool calls is
+// dropped with its tool results, and the first kept non-system message is a
+// user message if possible.
+func truncateMessages(msgs []Message, keep float64) (kept, dropped []Message) {
+	var rest []Message
+	for _, msg := range msgs {
+		if msg.Role != RoleSystem {
+			rest = append(rest, msg)
+		}
+	}
+	tokens := EstimateMessagesTokens(rest)
+	target := int(float64(tokens) * keep)
+
+	cut := 0
+	for cut < len(rest)-1 {
+		end := groupEnd(rest, cut)
+		if end >= len(rest) {
+			break // Keep the last group
+		}
+		if tokens <= target && rest[cut].Role == RoleUser {
+			break
+		}
+		tokens -= EstimateMessagesTokens(rest[cut:end])
+		cut = end
+	}
+	if cut == 0 {
+		return msgs, nil
+	}
+
+	// Keep the system messages in place
+	for _, msg := range msgs {
+		if msg.Role == RoleSystem || len(dropped) == cut {
+			kept = append(kept, msg)
+		} else {
+			dropped = append(dropped, msg)
+		}
+	}
+	return kept, dropped
+}
+
+// groupEnd returns the end of the group of messages starting at i: an
+// assistant message with tool calls and the tool results that follow it, or
+// a single message.
+func groupEnd(msgs []Message, i int) int {
+	end := i + 1
+	if msgs[i].Role == RoleAssistant && len(msgs[i].ToolCalls) > 0 {
+		for end < len(msgs) && msgs[end].Role == RoleTool {
+			end++
+		}
+	}
+	return end
+}
+
+// truncationSummaryPrefix introduces the summary of the dropped messages.
+const truncationSummaryPrefix = "Summary of the earlier conversation, which was truncated:\n\n"
+
+// summarizeDropped summarizes the dropped messages with an LLM call.
+func (c *callConfig) summarizeDropped(ctx context.Context, dropped []Message) (string, error) {
+	opts := []Option{WithProvider(c.providerName), WithModel(c.model)}
+	if c.user != "" {
+		opts = append(opts, WithUser(c.user))
+	}
+	return SummarizeConversation(ctx, dropped, append(opts, c.autoTruncate.summaryOpts...)...)
+}
+
+// insertSummary inserts a system message with summary after the leading
+// system messages of msgs.
+func insertSummary(msgs []Message, summary string) []Message {
+	i := 0
+	for i < len(msgs) && msgs[i].Role == RoleSystem {
+		i++
+	}
+	result := make([]Message, 0, len(msgs)+1)
+	result = append(result, msgs[:i]...)
+	result = append(result, SystemMessage(summary))
+	return append(result, msgs[i:]...)
+}
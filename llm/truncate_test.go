@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// overflowError is the error of a request exceeding the context window.
+type overflowError struct{}
+
+func (overflowError) Error() string   { return "This model's maximum context length is 100 tokens." }
+func (overflowError) HTTPStatus() int { return 400 }
+
+// windowProvider rejects requests of more than maxTokens estimated tokens.
+type windowProvider struct {
+	maxTokens int
+	mu        sync.Mutex
+	requests  [][]Message
+}
+
+func (p *windowProvider) Name() string { return "window" }
+
+func (p *windowProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req.Messages)
+	p.mu.Unlock()
+	if strings.HasPrefix(req.Messages[0].Content, "Summarize") {
+		return &provider.Response{Content: "The user asked about Go.", FinishReason: provider.FinishReasonStop}, nil
+	}
+	if EstimateMessagesTokens(req.Messages) > p.maxTokens {
+		return nil, fmt.Errorf("calling provider: %w", overflowError{})
+	}
+	return &provider.Response{Content: "answer", FinishReason: provider.FinishReasonStop}, nil
+}
+
+func registerWindow(t *testing.T, maxTokens int) (*windowProvider, []Option) {
+	t.Helper()
+	p := &windowProvider{maxTokens: maxTokens}
+	name := "window-" + t.Name()
+	provider.RegisterInstance(name, p)
+	t.Cleanup(func() { provider.Reset(name) })
+	return p, []Option{WithProvider(name), WithModel("m")}
+}
+
+// longConversation returns a system message and turns of about 20 tokens
+// each, the last of which is a question.
+func longConversation() []Message {
+	filler := strings.Repeat("x", 60)
+	return []Message{
+		SystemMessage("You are helpful."),
+		UserMessage("first " + filler),
+		AssistantMessageWithToolCalls("", []ToolCall{{ID: "1", Name: "search", Arguments: `{"q":"` + filler + `"}`}}),
+		ToolMessage("1", "result "+filler),
+		AssistantMessage("found " + filler),
+		UserMessage("second " + filler),
+		AssistantMessage("reply " + filler),
+		UserMessage("What about Go?"),
+	}
+}
+
+func TestWithAutoTruncate(t *testing.T) {
+	p, opts := registerWindow(t, 100)
+	history := longConversation()
+
+	_, err := CallMessages(context.Background(), history, opts...)
+	assert.True(t, provider.IsContextOverflow(err))
+
+	resp, err := CallMessages(context.Background(), history, append(opts, WithAutoTruncate())...)
+	require.NoError(t, err)
+	assert.Equal(t, "answer", resp.Text())
+
+	truncation := resp.Truncation()
+	require.NotNil(t, truncation)
+	// The tool call is dropped with its result, and the kept messages start with the user
+	require.Len(t, truncation.Dropped, 4)
+	assert.Equal(t, "found "+strings.Repeat("x", 60), truncation.Dropped[3].Content)
+	assert.Less(t, truncation.TokensAfter, truncation.TokensBefore)
+
+	sent := p.requests[len(p.requests)-1]
+	assert.Equal(t, RoleSystem, sent[0].Role)
+	assert.Equal(t, history[5:], sent[1:])
+	assert.Equal(t, sent, resp.Messages()[:len(sent)])
+
+	// Without an overflow, nothing is truncated
+	resp, err = CallMessages(context.Background(), history[len(history)-1:], append(opts, WithAutoTruncate())...)
+	require.NoError(t, err)
+	assert.Nil(t, resp.Truncation())
+}
+
+func TestWithAutoTruncate_Summarize(t *testing.T) {
+	p, opts := registerWindow(t, 100)
+
+	resp, err := CallMessages(context.Background(), longConversation(),
+		append(opts, WithAutoTruncate(TruncateSummarize(), TruncateKeep(0.2)))...)
+	require.NoError(t, err)
+
+	truncation := resp.Truncation()
+	require.NotNil(t, truncation)
+	assert.Equal(t, "The user asked about Go.", truncation.Summary)
+	assert.Len(t, truncation.Dropped, 6)
+
+	sent := p.requests[len(p.requests)-1]
+	require.Len(t, sent, 3)
+	assert.Equal(t, SystemMessage(truncationSummaryPrefix+"The user asked about Go."), sent[1])
+	assert.Equal(t, UserMessage("What about Go?"), sent[2])
+}
+
+func TestWithAutoTruncate_RetriesOnce(t *testing.T) {
+	_, opts := registerWindow(t, 10)
+
+	_, err := CallMessages(context.Background(), longConversation(), append(opts, WithAutoTruncate())...)
+	assert.True(t, provider.IsContextOverflow(err))
+}
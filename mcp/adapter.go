@@ -5,9 +5,14 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/invopop/jsonschema"
@@ -18,19 +23,69 @@ import (
 
 // Client wraps an MCP client for use with Bucephalus.
 type Client struct {
-	mcpClient *mcp.Client
-	session   *mcp.ClientSession
-	timeout   time.Duration
+	mcpClient     *mcp.Client
+	timeout       time.Duration
+	resourceTool  bool
+	retry         RetryPolicy
+	toolTimeouts  map[string]time.Duration // Per-tool timeout overrides
+	timeoutResult bool                     // Return timeouts as tool results
+	allowTools    []string                 // Patterns of exposed tools (all if empty)
+	denyTools     []string                 // Patterns of hidden tools
+	guard         *llm.InjectionGuard      // Checks tool results, if set
+	logger        *slog.Logger             // Receives server log messages
+	logLevel      slog.Level
+
+	mu       sync.RWMutex
+	session  *mcp.ClientSession // Current session; replaced on reconnect
+	state    ConnectionState
+	closed   bool
+	draining bool               // Shutting down; no new calls are accepted
+	inflight sync.WaitGroup     // Tool calls and resource reads in flight
+	aborted  context.Context    // Cancelled when a shutdown times out; parent of in-flight calls
+	abort    context.CancelFunc // Cancels aborted
+
+	newTransport func() mcp.Transport // Creates a transport for each (re)connection
+	reconnect    *ReconnectPolicy     // nil disables reconnecting
+	onState      func(ConnectionState, error)
+	toolsChanged toolListeners // Called when the server's tool list changes
+	onProgress   ProgressHandler
+	onElicit     ElicitHandler
+	roots        []Root             // Filesystem roots; guarded by mu
+	mcpRoots     []*mcp.Root        // roots as MCP roots; guarded by mu
+	progress     progressTokens     // Progress tokens of in-flight tool calls
+	cancel       context.CancelFunc // Stops reconnecting
+	done         chan struct{}      // Closed when the supervisor exits
 }
 
 // Option configures the MCP client.
 type Option func(*clientConfig)
 
 type clientConfig struct {
-	timeout time.Duration
+	timeout       time.Duration
+	resourceTool  bool // Whether Tools includes the read_mcp_resource tool
+	keepAlive     time.Duration
+	reconnect     *ReconnectPolicy
+	onState       func(ConnectionState, error)
+	onProgress    ProgressHandler
+	onElicit      ElicitHandler
+	headers       map[string]string // HTTP transports only
+	bearerToken   string            // HTTP transports only
+	httpClient    *http.Client      // HTTP transports only
+	oauth         *OAuthConfig      // HTTP transports only
+	roots         []Root
+	env           map[string]string // Stdio transport only
+	allowTools    []string
+	denyTools     []string
+	logger        *slog.Logger
+	logLevel      slog.Level
+	retry         RetryPolicy
+	toolTimeouts  map[string]time.Duration
+	timeoutResult bool
+	guard         *llm.InjectionGuard
 }
 
-// WithTimeout sets the timeout for tool execution.
+// WithTimeout sets the timeout for tool execution (default: 30 seconds).
+// See WithToolTimeout to override it for individual tools.
 func WithTimeout(d time.Duration) Option {
 	return func(c *clientConfig) {
 		c.timeout = d
@@ -38,6 +93,7 @@ func WithTimeout(d time.Duration) Option {
 }
 
 // NewStdioClient creates an MCP client that communicates via stdio with a subprocess.
+// Use WithReconnect to restart the subprocess when it crashes.
 //
 // Example:
 //
@@ -49,39 +105,106 @@ func WithTimeout(d time.Duration) Option {
 //
 //	tools, err := client.Tools(ctx)
 func NewStdioClient(ctx context.Context, command string, args []string, opts ...Option) (*Client, error) {
+	cfg := newClientConfig(opts)
+
+	return connect(ctx, func() mcp.Transport {
+		cmd := exec.Command(command, args...)
+		if len(cfg.env) > 0 {
+			cmd.Env = os.Environ()
+			for k, v := range cfg.env {
+				cmd.Env = append(cmd.Env, k+"="+v)
+			}
+		}
+		return &mcp.CommandTransport{Command: cmd}
+	}, cfg)
+}
+
+// newClientConfig returns the client configuration with opts applied.
+func newClientConfig(opts []Option) *clientConfig {
 	cfg := &clientConfig{
 		timeout: 30 * time.Second,
 	}
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	return cfg
+}
+
+// connect creates an MCP client and connects it to a server through a
+// transport created by newTransport.
+func connect(ctx context.Context, newTransport func() mcp.Transport, cfg *clientConfig) (*Client, error) {
+	c := &Client{
+		timeout:       cfg.timeout,
+		resourceTool:  cfg.resourceTool,
+		retry:         cfg.retry,
+		toolTimeouts:  cfg.toolTimeouts,
+		timeoutResult: cfg.timeoutResult,
+		allowTools:    cfg.allowTools,
+		denyTools:     cfg.denyTools,
+		guard:         cfg.guard,
+		logger:        cfg.logger,
+		logLevel:      cfg.logLevel,
+		newTransport:  newTransport,
+		reconnect:     cfg.reconnect,
+		onState:       cfg.onState,
+		onProgress:    cfg.onProgress,
+		onElicit:      cfg.onElicit,
+	}
 
 	// Create the MCP client
-	mcpClient := mcp.NewClient(&mcp.Implementation{
+	c.mcpClient = mcp.NewClient(&mcp.Implementation{
 		Name:    "bucephalus",
 		Version: "0.1.0",
-	}, nil)
+	}, c.clientOptions(cfg))
 
-	// Create command transport
-	cmd := exec.Command(command, args...)
-	transport := &mcp.CommandTransport{
-		Command: cmd,
+	// Declare the roots before connecting, so the server sees them from the start
+	if err := c.SetRoots(cfg.roots...); err != nil {
+		return nil, err
 	}
 
 	// Connect to the server
-	session, err := mcpClient.Connect(ctx, transport, nil)
+	session, err := c.mcpClient.Connect(ctx, newTransport(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("connecting to MCP server: %w", err)
 	}
+	c.session = session
+	c.setLogLevel(ctx, session)
+
+	c.startSupervisor()
+	return c, nil
+}
+
+// clientOptions returns the options of the underlying MCP client, routing
+// server notifications to c.
+func (c *Client) clientOptions(cfg *clientConfig) *mcp.ClientOptions {
+	opts := &mcp.ClientOptions{
+		KeepAlive: cfg.keepAlive,
+		ToolListChangedHandler: func(ctx context.Context, req *mcp.ToolListChangedRequest) {
+			// Listing tools from within a notification handler would block the connection
+			go c.refreshTools()
+		},
+	}
+	if c.onProgress != nil {
+		opts.ProgressNotificationHandler = c.handleProgress
+	}
+	if c.onElicit != nil {
+		opts.ElicitationHandler = c.handleElicit
+	}
+	if c.logger != nil {
+		opts.LoggingMessageHandler = c.handleLog
+	}
+	return opts
+}
 
-	return &Client{
-		mcpClient: mcpClient,
-		session:   session,
-		timeout:   cfg.timeout,
-	}, nil
+// currentSession returns the current session.
+func (c *Client) currentSession() *mcp.ClientSession {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.session
 }
 
-// Tools returns all tools from the MCP server as Bucephalus Tools.
+// Tools returns the tools from the MCP server as Bucephalus Tools.
+// Tools excluded by WithAllowedTools or WithDeniedTools are left out.
 //
 // Example:
 //
@@ -96,25 +219,64 @@ func NewStdioClient(ctx context.Context, command string, args []string, opts ...
 //	    llm.WithTools(tools...),
 //	)
 func (c *Client) Tools(ctx context.Context) ([]llm.Tool, error) {
-	result, err := c.session.ListTools(ctx, &mcp.ListToolsParams{})
+	result, err := c.currentSession().ListTools(ctx, &mcp.ListToolsParams{})
 	if err != nil {
 		return nil, fmt.Errorf("listing MCP tools: %w", err)
 	}
 
 	tools := make([]llm.Tool, 0, len(result.Tools))
 	for i := range result.Tools {
+		if !c.toolAllowed(result.Tools[i].Name) {
+			continue
+		}
 		tools = append(tools, &mcpToolWrapper{
 			client:  c,
 			mcpTool: result.Tools[i],
 		})
 	}
 
+	if c.resourceTool && c.HasResources() {
+		tool, err := c.newResourceTool(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+
+	if c.guard != nil {
+		tools = c.guard.Wrap(tools...)
+	}
 	return tools, nil
 }
 
-// Close closes the MCP client connection.
+// Ping checks that the server is responsive.
+func (c *Client) Ping(ctx context.Context) error {
+	if err := c.currentSession().Ping(ctx, nil); err != nil {
+		return fmt.Errorf("pinging MCP server: %w", err)
+	}
+	return nil
+}
+
+// Close closes the MCP client connection and stops reconnecting.
 func (c *Client) Close() error {
-	return c.session.Close()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	session := c.session
+	c.mu.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+	err := session.Close()
+	if c.done != nil {
+		<-c.done
+	}
+	c.notifyState(StateClosed, nil)
+	return err
 }
 
 // mcpToolWrapper wraps an MCP tool to implement llm.Tool.
@@ -147,32 +309,31 @@ func (t *mcpToolWrapper) Parameters() *jsonschema.Schema {
 }
 
 func (t *mcpToolWrapper) Execute(ctx context.Context, args json.RawMessage) (any, error) {
-	// Apply timeout
-	ctx, cancel := context.WithTimeout(ctx, t.client.timeout)
-	defer cancel()
-
 	// Parse arguments
 	var arguments map[string]any
 	if err := json.Unmarshal(args, &arguments); err != nil {
 		return nil, fmt.Errorf("parsing arguments: %w", err)
 	}
 
-	// Call the MCP tool
-	result, err := t.client.session.CallTool(ctx, &mcp.CallToolParams{
-		Name:      t.mcpTool.Name,
-		Arguments: arguments,
-	})
+	result, err := t.client.CallTool(ctx, t.mcpTool.Name, arguments)
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) && t.client.timeoutResult {
+		return llm.ToolErrorResult{
+			ErrorType:  llm.ToolErrorTimeout,
+			Message:    timeoutErr.Error(),
+			Retryable:  true,
+			Suggestion: "The operation may be too large; try a smaller request.",
+		}, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("calling MCP tool: %w", err)
+		return nil, err
 	}
 
-	combined := processToolResult(result.Content)
-
 	if result.IsError {
-		return nil, fmt.Errorf("MCP tool error: %s", combined)
+		return nil, fmt.Errorf("MCP tool error: %s", result.Text)
 	}
 
-	return combined, nil
+	return result, nil
 }
 
 // processToolResult extracts text content from MCP tool result.
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+// Server transport types of ServerConfig.Type.
+const (
+	TransportStdio = "stdio" // Local server over stdio
+	TransportHTTP  = "http"  // Remote server over Streamable HTTP
+	TransportSSE   = "sse"   // Remote server over HTTP+SSE
+)
+
+// LoadConfig loads MCP server configurations from a JSON file in the
+// standard mcpServers format used by .mcp.json and claude_desktop_config.json.
+// ${VAR} references to environment variables are expanded. The servers are
+// sorted by name and can be passed to NewManager.
+//
+// Example:
+//
+//	servers, err := mcp.LoadConfig(".mcp.json")
+//	if err != nil {
+//	    return err
+//	}
+//	manager := mcp.NewManager(servers)
+func LoadConfig(path string) ([]ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading MCP config: %w", err)
+	}
+	servers, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	for i := range servers {
+		servers[i].expand(os.Getenv)
+	}
+	return servers, nil
+}
+
+// ParseConfig parses MCP server configurations in the mcpServers format:
+//
+//	{
+//	  "mcpServers": {
+//	    "fs": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-filesystem", "."]},
+//	    "github": {"type": "http", "url": "https://api.githubcopilot.com/mcp/", "headers": {"Authorization": "Bearer ..."}}
+//	  }
+//	}
+//
+// Unlike LoadConfig, it does not expand environment variables.
+func ParseConfig(data []byte) ([]ServerConfig, error) {
+	var raw struct {
+		MCPServers map[string]ServerConfig `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing MCP config: %w", err)
+	}
+
+	servers := make([]ServerConfig, 0, len(raw.MCPServers))
+	for _, name := range slices.Sorted(maps.Keys(raw.MCPServers)) {
+		cfg := raw.MCPServers[name]
+		cfg.Name = name
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("MCP server %q: %w", name, err)
+		}
+		servers = append(servers, cfg)
+	}
+	return servers, nil
+}
+
+// Validate checks that the configuration describes a server that can be started.
+func (c *ServerConfig) Validate() error {
+	switch c.Type {
+	case "", TransportStdio, TransportHTTP, TransportSSE:
+	default:
+		return fmt.Errorf("unknown transport type %q", c.Type)
+	}
+	if c.Command == "" && c.URL == "" {
+		return fmt.Errorf("no command or URL configured")
+	}
+	return nil
+}
+
+// expand expands ${VAR} references in the command, arguments, environment,
+// URL, and headers using mapping.
+func (c *ServerConfig) expand(mapping func(string) string) {
+	c.Command = os.Expand(c.Command, mapping)
+	c.Args = slices.Clone(c.Args)
+	for i, arg := range c.Args {
+		c.Args[i] = os.Expand(arg, mapping)
+	}
+	c.Env = maps.Clone(c.Env)
+	for k, v := range c.Env {
+		c.Env[k] = os.Expand(v, mapping)
+	}
+	c.URL = os.Expand(c.URL, mapping)
+	c.Headers = maps.Clone(c.Headers)
+	for k, v := range c.Headers {
+		c.Headers[k] = os.Expand(v, mapping)
+	}
+}
+
+// WithEnv sets environment variables of a local (stdio) server, in addition
+// to the environment of the current process.
+func WithEnv(env map[string]string) Option {
+	return func(c *clientConfig) {
+		if c.env == nil {
+			c.env = make(map[string]string)
+		}
+		maps.Copy(c.env, env)
+	}
+}
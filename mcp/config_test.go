@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_MCP_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), ".mcp.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"mcpServers": {
+			"github": {"type": "http", "url": "https://example.com/mcp", "headers": {"Authorization": "Bearer ${TEST_MCP_TOKEN}"}},
+			"fs": {"command": "npx", "args": ["-y", "server-filesystem", "."], "env": {"TOKEN": "${TEST_MCP_TOKEN}"}}
+		}
+	}`), 0o644))
+
+	servers, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, servers, 2)
+
+	assert.Equal(t, "fs", servers[0].Name)
+	assert.Equal(t, "npx", servers[0].Command)
+	assert.Equal(t, []string{"-y", "server-filesystem", "."}, servers[0].Args)
+	assert.Equal(t, map[string]string{"TOKEN": "secret"}, servers[0].Env)
+
+	assert.Equal(t, "github", servers[1].Name)
+	assert.Equal(t, TransportHTTP, servers[1].Type)
+	assert.Equal(t, "https://example.com/mcp", servers[1].URL)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer secret"}, servers[1].Headers)
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"mcpServers": {"empty": {}}}`))
+	assert.ErrorContains(t, err, `MCP server "empty"`)
+
+	_, err = ParseConfig([]byte(`{"mcpServers": {"ws": {"type": "websocket", "url": "ws://localhost"}}}`))
+	assert.ErrorContains(t, err, "unknown transport type")
+
+	_, err = ParseConfig([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestManager_ConfigHeaders(t *testing.T) {
+	server := newTestServer()
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	ts := httptest.NewServer(requireToken("secret", handler))
+	defer ts.Close()
+
+	servers, err := ParseConfig([]byte(`{"mcpServers": {"remote": {"type": "http", "url": "` + ts.URL + `",
+		"headers": {"Authorization": "Bearer secret", "X-Tenant": "acme"}}}}`))
+	require.NoError(t, err)
+
+	manager := NewManager(servers)
+	defer manager.Close()
+
+	tools, err := manager.Tools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "remote__greet", tools[0].Name())
+}
@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// WithAllowedTools exposes only the tools matching one of the patterns.
+// Patterns are tool names or globs such as "read_*" (see path.Match).
+//
+// Example:
+//
+//	client, err := mcp.NewStdioClient(ctx, "mcp-server-filesystem", []string{"."},
+//	    mcp.WithAllowedTools("read_*", "list_directory", "search_files"),
+//	)
+func WithAllowedTools(patterns ...string) Option {
+	return func(c *clientConfig) {
+		c.allowTools = append(c.allowTools, patterns...)
+	}
+}
+
+// WithDeniedTools hides the tools matching one of the patterns, e.g. to keep
+// destructive tools away from the model. Denied tools are hidden even if
+// they are allowed by WithAllowedTools.
+func WithDeniedTools(patterns ...string) Option {
+	return func(c *clientConfig) {
+		c.denyTools = append(c.denyTools, patterns...)
+	}
+}
+
+// WithInjectionGuard checks the results of the tools of the server,
+// including the read_mcp_resource tool, with guard, so that instructions
+// planted in untrusted content do not reach the model unchecked.
+//
+// Example:
+//
+//	client, err := mcp.NewStreamableHTTPClient(ctx, "https://mcp.example.com/mcp",
+//	    mcp.WithInjectionGuard(llm.NewInjectionGuard(llm.WithInjectionAction(llm.InjectionBlock))),
+//	)
+func WithInjectionGuard(guard *llm.InjectionGuard) Option {
+	return func(c *clientConfig) {
+		c.guard = guard
+	}
+}
+
+// toolAllowed reports whether the named tool is exposed.
+func (c *Client) toolAllowed(name string) bool {
+	if matchAny(c.denyTools, name) {
+		return false
+	}
+	return len(c.allowTools) == 0 || matchAny(c.allowTools, name)
+}
+
+// checkToolAllowed returns an error if the named tool is not exposed.
+func (c *Client) checkToolAllowed(name string) error {
+	if !c.toolAllowed(name) {
+		return fmt.Errorf("MCP tool %q is not allowed", name)
+	}
+	return nil
+}
+
+// matchAny reports whether name matches one of the patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// WithHeader sets an HTTP header sent with every request to a remote MCP server.
+func WithHeader(key, value string) Option {
+	return func(c *clientConfig) {
+		if c.headers == nil {
+			c.headers = make(map[string]string)
+		}
+		c.headers[key] = value
+	}
+}
+
+// WithHeaders sets HTTP headers sent with every request to a remote MCP server.
+func WithHeaders(headers map[string]string) Option {
+	return func(c *clientConfig) {
+		if c.headers == nil {
+			c.headers = make(map[string]string)
+		}
+		maps.Copy(c.headers, headers)
+	}
+}
+
+// WithBearerToken authenticates requests to a remote MCP server with
+// an "Authorization: Bearer <token>" header.
+func WithBearerToken(token string) Option {
+	return func(c *clientConfig) {
+		c.bearerToken = token
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to connect to a remote MCP server.
+// Headers and bearer tokens are added on top of its transport.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *clientConfig) {
+		c.httpClient = client
+	}
+}
+
+// NewStreamableHTTPClient creates an MCP client that connects to a remote
+// server using the Streamable HTTP transport.
+//
+// Example:
+//
+//	client, err := mcp.NewStreamableHTTPClient(ctx, "https://example.com/mcp",
+//	    mcp.WithBearerToken(os.Getenv("MCP_TOKEN")),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	defer client.Close()
+func NewStreamableHTTPClient(ctx context.Context, endpoint string, opts ...Option) (*Client, error) {
+	cfg := newClientConfig(opts)
+	httpClient := cfg.newHTTPClient()
+	return connect(ctx, func() mcp.Transport {
+		return &mcp.StreamableClientTransport{
+			Endpoint:   endpoint,
+			HTTPClient: httpClient,
+		}
+	}, cfg)
+}
+
+// NewHTTPClient creates an MCP client that connects to a remote server over HTTP.
+// It tries the Streamable HTTP transport first and falls back to the legacy
+// HTTP+SSE transport for servers that do not support it.
+//
+// Example:
+//
+//	client, err := mcp.NewHTTPClient(ctx, "https://example.com/mcp",
+//	    mcp.WithHeader("X-API-Key", os.Getenv("API_KEY")),
+//	)
+func NewHTTPClient(ctx context.Context, endpoint string, opts ...Option) (*Client, error) {
+	client, err := NewStreamableHTTPClient(ctx, endpoint, opts...)
+	if err == nil {
+		return client, nil
+	}
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
+	client, sseErr := newSSEClient(ctx, endpoint, newClientConfig(opts))
+	if sseErr != nil {
+		return nil, errors.Join(fmt.Errorf("streamable HTTP: %w", err), fmt.Errorf("HTTP+SSE: %w", sseErr))
+	}
+	return client, nil
+}
+
+// newHTTPClient returns the HTTP client for remote transports, adding the
+// configured headers and OAuth authorization to each request.
+func (c *clientConfig) newHTTPClient() *http.Client {
+	base := c.httpClient
+	if base == nil {
+		base = http.DefaultClient
+	}
+	if c.oauth != nil {
+		client := *base
+		client.Transport = &oauthTransport{base: base.Transport, config: c.oauth}
+		base = &client
+	}
+	if len(c.headers) == 0 && c.bearerToken == "" {
+		return base
+	}
+
+	headers := maps.Clone(c.headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	if c.bearerToken != "" {
+		headers["Authorization"] = "Bearer " + c.bearerToken
+	}
+
+	client := *base
+	client.Transport = &headerTransport{base: base.Transport, headers: headers}
+	return &client
+}
+
+// headerTransport adds headers to every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
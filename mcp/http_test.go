@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+// newTestServer creates an MCP server with a "greet" tool.
+func newTestServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "greet", Description: "Greet someone"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in greetInput) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Hello, " + in.Name}}}, nil, nil
+		})
+	return server
+}
+
+// requireToken rejects requests without the expected bearer token.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token || r.Header.Get("X-Tenant") != "acme" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestNewStreamableHTTPClient(t *testing.T) {
+	server := newTestServer()
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	ts := httptest.NewServer(requireToken("secret", handler))
+	defer ts.Close()
+
+	ctx := context.Background()
+	_, err := NewStreamableHTTPClient(ctx, ts.URL)
+	require.Error(t, err)
+
+	client, err := NewStreamableHTTPClient(ctx, ts.URL,
+		WithBearerToken("secret"),
+		WithHeader("X-Tenant", "acme"),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+
+	result, err := tools[0].Execute(ctx, []byte(`{"name":"Gopher"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Gopher", result.(*ToolResult).Text)
+}
+
+func TestNewHTTPClient_FallsBackToSSE(t *testing.T) {
+	server := newTestServer()
+	handler := mcp.NewSSEHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	ts := httptest.NewServer(requireToken("secret", handler))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewHTTPClient(ctx, ts.URL,
+		WithBearerToken("secret"),
+		WithHeaders(map[string]string{"X-Tenant": "acme"}),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "greet", tools[0].Name())
+}
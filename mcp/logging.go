@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// WithLogger forwards log messages of the server at level or above to
+// logger, so misbehaving servers can be diagnosed from the application's
+// logs. Records carry the server name ("mcp_server") and, if the server sets
+// one, its logger name ("logger").
+//
+// Example:
+//
+//	client, err := mcp.NewStdioClient(ctx, "./my-mcp-server", nil,
+//	    mcp.WithLogger(slog.Default(), slog.LevelWarn),
+//	)
+func WithLogger(logger *slog.Logger, level slog.Level) Option {
+	return func(c *clientConfig) {
+		c.logger = logger
+		c.logLevel = level
+	}
+}
+
+// setLogLevel asks the server of session to send log messages at the
+// configured level, if a logger is set and the server supports logging.
+func (c *Client) setLogLevel(ctx context.Context, session *mcp.ClientSession) {
+	if c.logger == nil {
+		return
+	}
+	if init := session.InitializeResult(); init == nil || init.Capabilities == nil || init.Capabilities.Logging == nil {
+		return
+	}
+	if err := session.SetLoggingLevel(ctx, &mcp.SetLoggingLevelParams{Level: mcpLogLevel(c.logLevel)}); err != nil {
+		c.logger.WarnContext(ctx, "setting MCP server log level", "error", err)
+	}
+}
+
+// handleLog forwards a log message of the server to the logger.
+func (c *Client) handleLog(ctx context.Context, req *mcp.LoggingMessageRequest) {
+	params := req.Params
+	level := slogLevel(params.Level)
+	if level < c.logLevel {
+		return
+	}
+
+	msg, ok := params.Data.(string)
+	if !ok {
+		data, err := json.Marshal(params.Data)
+		if err != nil {
+			return
+		}
+		msg = string(data)
+	}
+
+	var attrs []slog.Attr
+	if init := req.Session.InitializeResult(); init != nil && init.ServerInfo != nil {
+		attrs = append(attrs, slog.String("mcp_server", init.ServerInfo.Name))
+	}
+	if params.Logger != "" {
+		attrs = append(attrs, slog.String("logger", params.Logger))
+	}
+	c.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// mcpLogLevel returns the MCP logging level corresponding to level.
+func mcpLogLevel(level slog.Level) mcp.LoggingLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// slogLevel returns the slog level corresponding to an MCP logging level.
+func slogLevel(level mcp.LoggingLevel) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "info", "notice":
+		return slog.LevelInfo
+	case "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default: // critical, alert, emergency
+		return slog.LevelError + 4
+	}
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWithLogger(t *testing.T) {
+	server := newTestServer()
+	mcp.AddTool(server, &mcp.Tool{Name: "work", Description: "Do some work"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			_ = req.Session.Log(ctx, &mcp.LoggingMessageParams{Level: "debug", Data: "starting"})
+			_ = req.Session.Log(ctx, &mcp.LoggingMessageParams{Level: "warning", Logger: "db", Data: "slow query"})
+			_ = req.Session.Log(ctx, &mcp.LoggingMessageParams{Level: "error", Data: map[string]any{"code": 42}})
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+		})
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer ts.Close()
+
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL, WithLogger(logger, slog.LevelWarn))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.CallTool(ctx, "work", nil)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return strings.Count(buf.String(), "\n") >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+	logs := buf.String()
+	assert.Contains(t, logs, `level=WARN msg="slow query" mcp_server=test logger=db`)
+	assert.Contains(t, logs, `level=ERROR msg="{\"code\":42}" mcp_server=test`)
+	assert.NotContains(t, logs, "starting")
+}
+
+func TestLogLevels(t *testing.T) {
+	assert.Equal(t, mcp.LoggingLevel("debug"), mcpLogLevel(slog.LevelDebug))
+	assert.Equal(t, mcp.LoggingLevel("info"), mcpLogLevel(slog.LevelInfo))
+	assert.Equal(t, mcp.LoggingLevel("warning"), mcpLogLevel(slog.LevelWarn))
+	assert.Equal(t, mcp.LoggingLevel("error"), mcpLogLevel(slog.LevelError))
+
+	assert.Equal(t, slog.LevelInfo, slogLevel("notice"))
+	assert.Equal(t, slog.LevelWarn, slogLevel("warning"))
+	assert.Greater(t, slogLevel("critical"), slog.LevelError)
+}
@@ -0,0 +1,336 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// DefaultToolNameSeparator separates the server name from the tool name in
+// the names of tools returned by Manager.Tools.
+const DefaultToolNameSeparator = "__"
+
+// ServerConfig configures an MCP server of a Manager.
+// Set Command to run a local server over stdio, or URL to connect to a
+// remote server over HTTP (see NewHTTPClient). Configurations in the standard
+// mcpServers JSON format can be loaded with LoadConfig.
+type ServerConfig struct {
+	Name    string            `json:"-"`                 // Server name, used as the tool name prefix
+	Type    string            `json:"type,omitempty"`    // TransportStdio, TransportHTTP, or TransportSSE (default: by Command/URL)
+	Command string            `json:"command,omitempty"` // Command of a local (stdio) server
+	Args    []string          `json:"args,omitempty"`    // Arguments of Command
+	Env     map[string]string `json:"env,omitempty"`     // Environment variables of Command
+	URL     string            `json:"url,omitempty"`     // Endpoint of a remote server
+	Headers map[string]string `json:"headers,omitempty"` // HTTP headers sent to a remote server
+	Options []Option          `json:"-"`                 // Client options (timeout, headers, ...)
+}
+
+// Manager connects to multiple MCP servers and merges their tools.
+// Tool names are prefixed with the server name ("github__create_issue") to
+// avoid collisions. Servers are started lazily on first use and restarted on
+// the next use after they fail a health check or are shut down. Local
+// servers are also restarted with DefaultReconnectPolicy when they crash.
+//
+// Example:
+//
+//	manager := mcp.NewManager([]mcp.ServerConfig{
+//	    {Name: "fs", Command: "mcp-server-filesystem", Args: []string{"."}},
+//	    {Name: "github", URL: "https://api.githubcopilot.com/mcp/", Options: []mcp.Option{
+//	        mcp.WithBearerToken(os.Getenv("GITHUB_TOKEN")),
+//	    }},
+//	})
+//	defer manager.Close()
+//
+//	tools, err := manager.Tools(ctx)
+type Manager struct {
+	mu        sync.Mutex
+	configs   []ServerConfig
+	clients   map[string]*Client
+	starting  map[string]*serverStart // Servers being started, by name
+	separator string
+	shutdown  bool // Set by ShutdownAll; no server is started again
+}
+
+// serverStart is the start of a server, shared by the callers that need it
+// meanwhile.
+type serverStart struct {
+	done    chan struct{} // Closed when the server is started or failed to
+	client  *Client
+	err     error
+	stopped bool // The server was shut down while starting
+}
+
+// ManagerOption configures a Manager.
+type ManagerOption func(*Manager)
+
+// WithToolNameSeparator sets the separator between the server name and the
+// tool name (default: DefaultToolNameSeparator).
+func WithToolNameSeparator(sep string) ManagerOption {
+	return func(m *Manager) {
+		m.separator = sep
+	}
+}
+
+// NewManager creates a manager for the given servers. No server is started
+// until it is used.
+func NewManager(servers []ServerConfig, opts ...ManagerOption) *Manager {
+	m := &Manager{
+		configs:   servers,
+		clients:   make(map[string]*Client),
+		starting:  make(map[string]*serverStart),
+		separator: DefaultToolNameSeparator,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Servers returns the names of the configured servers.
+func (m *Manager) Servers() []string {
+	names := make([]string, len(m.configs))
+	for i, cfg := range m.configs {
+		names[i] = cfg.Name
+	}
+	return names
+}
+
+// Client returns the client of the named server, starting it if needed.
+// Servers are started without holding up the clients of other servers;
+// concurrent callers share the start of a server.
+func (m *Manager) Client(ctx context.Context, name string) (*Client, error) {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
+	var failed *Client
+	if c, ok := m.clients[name]; ok {
+		if c.State() != StateFailed {
+			m.mu.Unlock()
+			return c, nil
+		}
+		// The server could not be restarted; start it anew
+		failed = c
+		delete(m.clients, name)
+	}
+	if start, ok := m.starting[name]; ok {
+		m.mu.Unlock()
+		select {
+		case <-start.done:
+			return start.client, start.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	cfg, ok := m.config(name)
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("unknown MCP server %q", name)
+	}
+	start := &serverStart{done: make(chan struct{})}
+	m.starting[name] = start
+	m.mu.Unlock()
+
+	if failed != nil {
+		_ = failed.Close()
+	}
+
+	// The server outlives the call that starts it
+	c, err := cfg.connect(context.WithoutCancel(ctx))
+
+	m.mu.Lock()
+	delete(m.starting, name)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("starting MCP server %q: %w", name, err)
+	case m.shutdown:
+		err = ErrShuttingDown
+	case start.stopped:
+		err = fmt.Errorf("MCP server %q was shut down while starting", name)
+	default:
+		m.clients[name] = c
+	}
+	m.mu.Unlock()
+
+	if err != nil && c != nil {
+		_ = c.Close()
+		c = nil
+	}
+	start.client, start.err = c, err
+	close(start.done)
+	return c, err
+}
+
+// connect starts the server and connects to it.
+func (cfg ServerConfig) connect(ctx context.Context) (*Client, error) {
+	opts := cfg.Options
+	if len(cfg.Env) > 0 || len(cfg.Headers) > 0 {
+		// Options set explicitly take precedence
+		opts = append([]Option{WithEnv(cfg.Env), WithHeaders(cfg.Headers)}, opts...)
+	}
+
+	if cfg.Command != "" && cfg.Type != TransportSSE && cfg.Type != TransportHTTP {
+		// Restart crashed servers unless the options set another policy
+		opts = append([]Option{WithReconnect(DefaultReconnectPolicy)}, opts...)
+	}
+
+	switch {
+	case cfg.Type == TransportSSE:
+		return NewSSEClient(ctx, cfg.URL, opts...)
+	case cfg.Type == TransportHTTP:
+		return NewStreamableHTTPClient(ctx, cfg.URL, opts...)
+	case cfg.Command != "":
+		return NewStdioClient(ctx, cfg.Command, cfg.Args, opts...)
+	case cfg.URL != "":
+		return NewHTTPClient(ctx, cfg.URL, opts...)
+	default:
+		return nil, errors.New("no command or URL configured")
+	}
+}
+
+// config returns the configuration of the named server.
+func (m *Manager) config(name string) (ServerConfig, bool) {
+	for _, cfg := range m.configs {
+		if cfg.Name == name {
+			return cfg, true
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// Tools returns the tools of all servers, with names prefixed by the server
+// name. Servers are started as needed. Calls to the returned tools restart
+// their server if it was shut down in the meantime.
+func (m *Manager) Tools(ctx context.Context) ([]llm.Tool, error) {
+	var all []llm.Tool
+	for _, cfg := range m.configs {
+		tools, err := m.ServerTools(ctx, cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tools...)
+	}
+	return all, nil
+}
+
+// ServerTools returns the tools of the named server, with names prefixed by
+// the server name.
+func (m *Manager) ServerTools(ctx context.Context, name string) ([]llm.Tool, error) {
+	c, err := m.Client(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := c.Tools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %q: %w", name, err)
+	}
+
+	wrapped := make([]llm.Tool, len(tools))
+	for i, tool := range tools {
+		wrapped[i] = &managedTool{
+			Tool:    tool,
+			client:  c,
+			manager: m,
+			server:  name,
+			name:    name + m.separator + tool.Name(),
+		}
+	}
+	return wrapped, nil
+}
+
+// HealthCheck pings every running server and returns the errors of servers
+// that did not respond, keyed by server name. Unhealthy servers are shut
+// down, so they are restarted on their next use.
+func (m *Manager) HealthCheck(ctx context.Context) map[string]error {
+	m.mu.Lock()
+	clients := make(map[string]*Client, len(m.clients))
+	for name, c := range m.clients {
+		clients[name] = c
+	}
+	m.mu.Unlock()
+
+	failed := make(map[string]error)
+	for name, c := range clients {
+		if err := c.Ping(ctx); err != nil {
+			failed[name] = err
+			_ = m.Shutdown(name)
+		}
+	}
+	return failed
+}
+
+// Shutdown stops the named server. It is started again on its next use.
+func (m *Manager) Shutdown(name string) error {
+	m.mu.Lock()
+	c, ok := m.clients[name]
+	delete(m.clients, name)
+	if start, starting := m.starting[name]; starting {
+		start.stopped = true
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return c.Close()
+}
+
+// Close stops all servers.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*Client)
+	for _, start := range m.starting {
+		start.stopped = true
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for name, c := range clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing MCP server %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// managedTool is a server tool with a namespaced name. It executes through
+// the server's current client, restarting the server if needed.
+type managedTool struct {
+	llm.Tool
+	client  *Client // Client the tool was listed from
+	manager *Manager
+	server  string
+	name    string
+}
+
+func (t *managedTool) Name() string {
+	return t.name
+}
+
+func (t *managedTool) Execute(ctx context.Context, args json.RawMessage) (any, error) {
+	c, err := t.manager.Client(ctx, t.server)
+	if err != nil {
+		return nil, err
+	}
+	if c == t.client {
+		return t.Tool.Execute(ctx, args)
+	}
+
+	// The server was restarted since the tools were listed
+	tools, err := c.Tools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("MCP server %q: %w", t.server, err)
+	}
+	for _, tool := range tools {
+		if tool.Name() == t.Tool.Name() {
+			return tool.Execute(ctx, args)
+		}
+	}
+	return nil, fmt.Errorf("MCP server %q no longer provides tool %q", t.server, t.Tool.Name())
+}
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHTTPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := newTestServer()
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestManager(t *testing.T) {
+	a := newTestHTTPServer(t)
+	b := newTestHTTPServer(t)
+
+	manager := NewManager([]ServerConfig{
+		{Name: "a", URL: a.URL},
+		{Name: "b", URL: b.URL},
+	})
+	defer manager.Close()
+
+	ctx := context.Background()
+	assert.Equal(t, []string{"a", "b"}, manager.Servers())
+
+	tools, err := manager.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	assert.Equal(t, "a__greet", tools[0].Name())
+	assert.Equal(t, "b__greet", tools[1].Name())
+
+	result, err := tools[1].Execute(ctx, []byte(`{"name":"B"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, B", result.(*ToolResult).Text)
+
+	assert.Empty(t, manager.HealthCheck(ctx))
+
+	// A shut down server is restarted on its next use
+	require.NoError(t, manager.Shutdown("a"))
+	result, err = tools[0].Execute(ctx, []byte(`{"name":"A"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, A", result.(*ToolResult).Text)
+
+	_, err = manager.Client(ctx, "missing")
+	assert.ErrorContains(t, err, `unknown MCP server "missing"`)
+}
+
+func TestManager_Client_SlowServer(t *testing.T) {
+	fast := newTestHTTPServer(t)
+	hang := make(chan struct{})
+	release := sync.OnceFunc(func() { close(hang) })
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(release)
+
+	manager := NewManager([]ServerConfig{
+		{Name: "slow", Type: TransportHTTP, URL: slow.URL},
+		{Name: "fast", URL: fast.URL},
+	})
+	defer manager.Close()
+
+	started := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := manager.Client(context.Background(), "slow")
+			started <- err
+		}()
+	}
+	require.Eventually(t, func() bool {
+		manager.mu.Lock()
+		defer manager.mu.Unlock()
+		return manager.starting["slow"] != nil
+	}, 5*time.Second, time.Millisecond)
+
+	// The hanging server does not hold up the clients of the other servers
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := manager.Client(ctx, "fast")
+	require.NoError(t, err)
+	assert.NotNil(t, c)
+
+	// Callers waiting for a server being started give up with their context
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	_, err = manager.Client(waitCtx, "slow")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Callers starting the server at the same time share its start
+	release()
+	assert.Error(t, <-started)
+	assert.Error(t, <-started)
+}
+
+func TestManager_ToolNameSeparator(t *testing.T) {
+	ts := newTestHTTPServer(t)
+	manager := NewManager([]ServerConfig{{Name: "docs", URL: ts.URL}}, WithToolNameSeparator("-"))
+	defer manager.Close()
+
+	tools, err := manager.ServerTools(context.Background(), "docs")
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "docs-greet", tools[0].Name())
+}
@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// Progress is a progress notification for a running tool call.
+type Progress struct {
+	Tool     string  // Name of the tool being called
+	Progress float64 // Progress so far; increases with each notification
+	Total    float64 // Total progress, if known (0 otherwise)
+	Message  string  // Optional human-readable status
+}
+
+// ProgressHandler is called with progress notifications of tool calls.
+type ProgressHandler func(ctx context.Context, p Progress)
+
+// ElicitRequest is a request from the server for information from the user.
+type ElicitRequest struct {
+	Message string         // Message to show the user
+	Schema  map[string]any // JSON schema of the requested information
+}
+
+// Elicitation actions.
+const (
+	ElicitAccept  = "accept"  // The user provided the requested information
+	ElicitDecline = "decline" // The user declined to provide it
+	ElicitCancel  = "cancel"  // The user dismissed the request
+)
+
+// ElicitResult is the answer to an ElicitRequest.
+type ElicitResult struct {
+	Action  string         // ElicitAccept, ElicitDecline, or ElicitCancel
+	Content map[string]any // Requested information (for ElicitAccept), matching the schema
+}
+
+// ElicitHandler answers elicitation requests from the server.
+type ElicitHandler func(ctx context.Context, req ElicitRequest) (ElicitResult, error)
+
+// WithOnProgress sets a handler for progress notifications of tool calls,
+// e.g. to display the progress of long-running tools. Tool calls request
+// progress notifications only if a handler is set.
+func WithOnProgress(fn ProgressHandler) Option {
+	return func(c *clientConfig) {
+		c.onProgress = fn
+	}
+}
+
+// WithOnElicit sets a handler that answers servers asking the user for
+// information during a tool call. Without a handler, the client does not
+// advertise elicitation support.
+//
+// Example:
+//
+//	client, err := mcp.NewStdioClient(ctx, "./deploy-server", nil,
+//	    mcp.WithOnElicit(func(ctx context.Context, req mcp.ElicitRequest) (mcp.ElicitResult, error) {
+//	        fmt.Println(req.Message)
+//	        if !confirm() {
+//	            return mcp.ElicitResult{Action: mcp.ElicitDecline}, nil
+//	        }
+//	        return mcp.ElicitResult{Action: mcp.ElicitAccept, Content: map[string]any{"confirm": true}}, nil
+//	    }),
+//	)
+func WithOnElicit(fn ElicitHandler) Option {
+	return func(c *clientConfig) {
+		c.onElicit = fn
+	}
+}
+
+// progressTokens maps the progress tokens of in-flight tool calls to tool names.
+type progressTokens struct {
+	next  atomic.Int64
+	tools sync.Map // token -> tool name
+}
+
+// add registers a progress token for a call to tool.
+// It returns the token and a function that removes it.
+func (p *progressTokens) add(tool string) (string, func()) {
+	token := fmt.Sprintf("%s-%d", tool, p.next.Add(1))
+	p.tools.Store(token, tool)
+	return token, func() { p.tools.Delete(token) }
+}
+
+// handleProgress forwards a progress notification to the progress handler.
+func (c *Client) handleProgress(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+	tool, ok := c.progress.tools.Load(req.Params.ProgressToken)
+	if !ok {
+		return
+	}
+	c.onProgress(ctx, Progress{
+		Tool:     tool.(string),
+		Progress: req.Params.Progress,
+		Total:    req.Params.Total,
+		Message:  req.Params.Message,
+	})
+}
+
+// handleElicit forwards an elicitation request to the elicitation handler.
+func (c *Client) handleElicit(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+	var schema map[string]any
+	if req.Params.RequestedSchema != nil {
+		data, err := json.Marshal(req.Params.RequestedSchema)
+		if err != nil {
+			return nil, fmt.Errorf("encoding requested schema: %w", err)
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("decoding requested schema: %w", err)
+		}
+	}
+
+	result, err := c.onElicit(ctx, ElicitRequest{Message: req.Params.Message, Schema: schema})
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ElicitResult{Action: result.Action, Content: result.Content}, nil
+}
+
+// toolListeners manages tool list change callbacks.
+type toolListeners struct {
+	mu   sync.Mutex
+	next int
+	fns  map[int]func([]llm.Tool, error)
+}
+
+// OnToolsChanged registers fn to be called when the server notifies that its
+// tool list changed (tools/list_changed). fn receives the refreshed tools, or
+// the error if listing them failed, so long-lived sessions can pick up
+// dynamically added tools without reconnecting. It returns a function that
+// unregisters fn.
+//
+// Example:
+//
+//	client.OnToolsChanged(func(tools []llm.Tool, err error) {
+//	    if err == nil {
+//	        registry.Register(tools...)
+//	    }
+//	})
+func (c *Client) OnToolsChanged(fn func(tools []llm.Tool, err error)) func() {
+	l := &c.toolsChanged
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fns == nil {
+		l.fns = make(map[int]func([]llm.Tool, error))
+	}
+	id := l.next
+	l.next++
+	l.fns[id] = fn
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.fns, id)
+	}
+}
+
+// refreshTools lists the server's tools and notifies the tool list listeners.
+func (c *Client) refreshTools() {
+	l := &c.toolsChanged
+	l.mu.Lock()
+	fns := make([]func([]llm.Tool, error), 0, len(l.fns))
+	for _, fn := range l.fns {
+		fns = append(fns, fn)
+	}
+	l.mu.Unlock()
+
+	if len(fns) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	tools, err := c.Tools(ctx)
+	for _, fn := range fns {
+		fn(tools, err)
+	}
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestClient_OnToolsChanged(t *testing.T) {
+	server := newTestServer()
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL)
+	require.NoError(t, err)
+	defer client.Close()
+
+	changed := make(chan []llm.Tool, 1)
+	client.OnToolsChanged(func(tools []llm.Tool, err error) {
+		require.NoError(t, err)
+		changed <- tools
+	})
+
+	mcp.AddTool(server, &mcp.Tool{Name: "farewell", Description: "Say goodbye"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in greetInput) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Bye, " + in.Name}}}, nil, nil
+		})
+
+	select {
+	case tools := <-changed:
+		require.Len(t, tools, 2)
+		names := []string{tools[0].Name(), tools[1].Name()}
+		assert.ElementsMatch(t, []string{"greet", "farewell"}, names)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tool list change")
+	}
+}
+
+func TestClient_ProgressAndElicitation(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "deploy", Description: "Deploy the app"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			token := req.Params.GetProgressToken()
+			for i := 1; i <= 2; i++ {
+				err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+					ProgressToken: token, Progress: float64(i), Total: 2, Message: "step",
+				})
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+
+			answer, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+				Message: "Deploy to production?",
+				RequestedSchema: &jsonschema.Schema{
+					Type:       "object",
+					Properties: map[string]*jsonschema.Schema{"confirm": {Type: "boolean"}},
+				},
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			text := "cancelled"
+			if answer.Action == ElicitAccept && answer.Content["confirm"] == true {
+				text = "deployed"
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil, nil
+		})
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var progress []Progress
+	var question ElicitRequest
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL,
+		WithOnProgress(func(ctx context.Context, p Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, p)
+		}),
+		WithOnElicit(func(ctx context.Context, req ElicitRequest) (ElicitResult, error) {
+			question = req
+			return ElicitResult{Action: ElicitAccept, Content: map[string]any{"confirm": true}}, nil
+		}),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	result, err := tools[0].Execute(ctx, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "deployed", result.(*ToolResult).Text)
+
+	assert.Equal(t, "Deploy to production?", question.Message)
+	assert.Contains(t, question.Schema, "properties")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, progress, 2)
+	assert.Equal(t, Progress{Tool: "deploy", Progress: 1, Total: 2, Message: "step"}, progress[0])
+}
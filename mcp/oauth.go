@@ -0,0 +1,601 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRedirectURL is the redirect URL of the OAuth authorization code flow
+// when OAuthConfig.RedirectURL is not set.
+const DefaultRedirectURL = "http://127.0.0.1:33418/callback"
+
+// Token is an OAuth token for a remote MCP server. It also records the
+// client it was issued to, so dynamically registered clients are reused.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"`
+	ClientID     string    `json:"client_id,omitempty"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+// Valid reports whether the token has an access token that has not expired.
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+// TokenStore stores OAuth tokens, keyed by server origin ("https://example.com").
+type TokenStore interface {
+	// Load returns the stored token, or nil if there is none.
+	Load(ctx context.Context, key string) (*Token, error)
+	// Save stores the token.
+	Save(ctx context.Context, key string, token *Token) error
+}
+
+// MemoryTokenStore keeps tokens in memory.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*Token
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]*Token)}
+}
+
+func (s *MemoryTokenStore) Load(ctx context.Context, key string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[key], nil
+}
+
+func (s *MemoryTokenStore) Save(ctx context.Context, key string, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = token
+	return nil
+}
+
+// FileTokenStore keeps tokens in a JSON file, so users do not have to
+// authorize again after restarting the application.
+type FileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTokenStore creates a token store backed by the file at path.
+// The file is created with mode 0600 when the first token is saved.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (s *FileTokenStore) Load(ctx context.Context, key string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return tokens[key], nil
+}
+
+func (s *FileTokenStore) Save(ctx context.Context, key string, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[key] = token
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding tokens: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("creating token directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("writing tokens: %w", err)
+	}
+	return nil
+}
+
+// read reads all tokens from the file.
+func (s *FileTokenStore) read() (map[string]*Token, error) {
+	tokens := make(map[string]*Token)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tokens: %w", err)
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Authorizer sends the user to authURL to authorize the client and returns
+// the URL the authorization server redirected back to, which carries the
+// authorization code.
+type Authorizer func(ctx context.Context, authURL string) (*url.URL, error)
+
+// OAuthConfig configures the OAuth 2.1 authorization of a remote MCP server.
+type OAuthConfig struct {
+	ClientID     string     // Pre-registered client; registered dynamically if empty
+	ClientSecret string     // Secret of a pre-registered confidential client
+	ClientName   string     // Client name for dynamic registration (default: "bucephalus")
+	RedirectURL  string     // Redirect URL (default: DefaultRedirectURL)
+	Scopes       []string   // Requested scopes
+	Store        TokenStore // Token storage (default: in memory)
+	Authorize    Authorizer // Authorization step (default: BrowserAuthorizer)
+}
+
+// WithOAuth authorizes requests to a remote MCP server following the MCP
+// authorization spec. When the server responds with 401 Unauthorized, the
+// client discovers the authorization server, registers itself if no client ID
+// is configured, and runs the authorization code flow with PKCE. Tokens are
+// saved to the token store and refreshed when they expire or are rejected.
+//
+// Example:
+//
+//	client, err := mcp.NewHTTPClient(ctx, "https://example.com/mcp",
+//	    mcp.WithOAuth(mcp.OAuthConfig{
+//	        Scopes: []string{"read"},
+//	        Store:  mcp.NewFileTokenStore(filepath.Join(home, ".myapp", "tokens.json")),
+//	    }),
+//	)
+func WithOAuth(cfg OAuthConfig) Option {
+	if cfg.ClientName == "" {
+		cfg.ClientName = "bucephalus"
+	}
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = DefaultRedirectURL
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryTokenStore()
+	}
+	if cfg.Authorize == nil {
+		cfg.Authorize = BrowserAuthorizer(cfg.RedirectURL)
+	}
+	return func(c *clientConfig) {
+		c.oauth = &cfg
+	}
+}
+
+// BrowserAuthorizer returns an Authorizer that opens authURL in the user's
+// browser and receives the redirect on a local server listening at redirectURL.
+func BrowserAuthorizer(redirectURL string) Authorizer {
+	return func(ctx context.Context, authURL string) (*url.URL, error) {
+		redirect, err := url.Parse(redirectURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing redirect URL: %w", err)
+		}
+		listener, err := net.Listen("tcp", redirect.Host)
+		if err != nil {
+			return nil, fmt.Errorf("listening for OAuth redirect: %w", err)
+		}
+
+		callback := make(chan *url.URL, 1)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != redirect.Path {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, "Authorization complete. You can close this window.")
+			select {
+			case callback <- r.URL:
+			default:
+			}
+		})}
+		go func() { _ = server.Serve(listener) }()
+		defer server.Close()
+
+		if err := openBrowser(authURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Open this URL to authorize the MCP client:\n%s\n", authURL)
+		}
+
+		select {
+		case u := <-callback:
+			return u, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// openBrowser opens url in the default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// oauthTransport authorizes requests with OAuth tokens, running the
+// authorization flow when the server requires it.
+type oauthTransport struct {
+	base   http.RoundTripper
+	config *OAuthConfig
+
+	mu       sync.Mutex // Serializes authorization and refresh
+	metadata map[string]*authServerMetadata
+}
+
+// authServerMetadata is the authorization server metadata (RFC 8414).
+type authServerMetadata struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	RegistrationEndpoint  string `json:"registration_endpoint"`
+}
+
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	key := origin(req.URL)
+
+	token, err := t.config.Store.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("loading OAuth token: %w", err)
+	}
+	if token != nil && !token.Valid() && token.RefreshToken != "" {
+		token = t.renew(ctx, req.URL, token, "")
+	}
+
+	resp, err := t.send(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil // The request cannot be sent again
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	_ = resp.Body.Close()
+
+	token, err = t.authorize(ctx, req.URL, token, challenge)
+	if err != nil {
+		return nil, err
+	}
+	return t.send(req, token)
+}
+
+// send sends req with the access token of token, if any.
+func (t *oauthTransport) send(req *http.Request, token *Token) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	if token != nil && token.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	}
+	return t.transport().RoundTrip(req)
+}
+
+func (t *oauthTransport) transport() http.RoundTripper {
+	if t.base == nil {
+		return http.DefaultTransport
+	}
+	return t.base
+}
+
+// renew refreshes token, returning it unchanged if refreshing fails.
+func (t *oauthTransport) renew(ctx context.Context, resource *url.URL, token *Token, challenge string) *Token {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if refreshed, err := t.refresh(ctx, resource, token, challenge); err == nil {
+		return refreshed
+	}
+	return token
+}
+
+// authorize returns a new token after the server rejected rejected, by
+// refreshing it or, failing that, running the authorization flow.
+func (t *oauthTransport) authorize(ctx context.Context, resource *url.URL, rejected *Token, challenge string) (*Token, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Another request may have authorized in the meantime
+	key := origin(resource)
+	token, err := t.config.Store.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("loading OAuth token: %w", err)
+	}
+	if token.Valid() && (rejected == nil || token.AccessToken != rejected.AccessToken) {
+		return token, nil
+	}
+
+	if token != nil && token.RefreshToken != "" {
+		if refreshed, err := t.refresh(ctx, resource, token, challenge); err == nil {
+			return refreshed, nil
+		}
+	}
+	return t.authorizeCode(ctx, resource, token, challenge)
+}
+
+// refresh exchanges the refresh token of token for a new token.
+func (t *oauthTransport) refresh(ctx context.Context, resource *url.URL, token *Token, challenge string) (*Token, error) {
+	meta, err := t.discover(ctx, resource, challenge)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+		"client_id":     {token.ClientID},
+		"resource":      {resourceID(resource)},
+	}
+	return t.requestToken(ctx, resource, meta, form, token)
+}
+
+// authorizeCode runs the authorization code flow with PKCE.
+func (t *oauthTransport) authorizeCode(ctx context.Context, resource *url.URL, previous *Token, challenge string) (*Token, error) {
+	meta, err := t.discover(ctx, resource, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Token{ClientID: t.config.ClientID, ClientSecret: t.config.ClientSecret}
+	if client.ClientID == "" && previous != nil {
+		client.ClientID, client.ClientSecret = previous.ClientID, previous.ClientSecret
+	}
+	if client.ClientID == "" {
+		if client.ClientID, client.ClientSecret, err = t.register(ctx, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	verifier := randomString()
+	sum := sha256.Sum256([]byte(verifier))
+	state := randomString()
+
+	authURL, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", client.ClientID)
+	query.Set("redirect_uri", t.config.RedirectURL)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	query.Set("code_challenge_method", "S256")
+	query.Set("state", state)
+	query.Set("resource", resourceID(resource))
+	if len(t.config.Scopes) > 0 {
+		query.Set("scope", strings.Join(t.config.Scopes, " "))
+	}
+	authURL.RawQuery = query.Encode()
+
+	callback, err := t.config.Authorize(ctx, authURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("authorizing MCP client: %w", err)
+	}
+	params := callback.Query()
+	if e := params.Get("error"); e != "" {
+		return nil, fmt.Errorf("authorizing MCP client: %s %s", e, params.Get("error_description"))
+	}
+	if params.Get("state") != state {
+		return nil, errors.New("authorizing MCP client: state mismatch")
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {params.Get("code")},
+		"redirect_uri":  {t.config.RedirectURL},
+		"client_id":     {client.ClientID},
+		"code_verifier": {verifier},
+		"resource":      {resourceID(resource)},
+	}
+	return t.requestToken(ctx, resource, meta, form, client)
+}
+
+// requestToken requests a token from the token endpoint and saves it.
+// client provides the client credentials, and the refresh token to keep if
+// the response has none.
+func (t *oauthTransport) requestToken(ctx context.Context, resource *url.URL, meta *authServerMetadata, form url.Values, client *Token) (*Token, error) {
+	if client.ClientSecret != "" {
+		form.Set("client_secret", client.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := t.doJSON(req, &result); err != nil {
+		return nil, fmt.Errorf("requesting OAuth token: %w", err)
+	}
+	if result.Error != "" || result.AccessToken == "" {
+		return nil, fmt.Errorf("requesting OAuth token: %s %s", result.Error, result.ErrorDescription)
+	}
+
+	token := &Token{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		ClientID:     client.ClientID,
+		ClientSecret: client.ClientSecret,
+	}
+	if token.RefreshToken == "" && form.Get("grant_type") == "refresh_token" {
+		token.RefreshToken = client.RefreshToken
+	}
+	if result.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+
+	if err := t.config.Store.Save(ctx, origin(resource), token); err != nil {
+		return nil, fmt.Errorf("saving OAuth token: %w", err)
+	}
+	return token, nil
+}
+
+// register registers the client dynamically (RFC 7591).
+func (t *oauthTransport) register(ctx context.Context, meta *authServerMetadata) (clientID, clientSecret string, err error) {
+	if meta.RegistrationEndpoint == "" {
+		return "", "", errors.New("registering MCP client: authorization server does not support dynamic client registration; set OAuthConfig.ClientID")
+	}
+	body, err := json.Marshal(map[string]any{
+		"client_name":                t.config.ClientName,
+		"redirect_uris":              []string{t.config.RedirectURL},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.RegistrationEndpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := t.doJSON(req, &result); err != nil {
+		return "", "", fmt.Errorf("registering MCP client: %w", err)
+	}
+	if result.ClientID == "" {
+		return "", "", errors.New("registering MCP client: no client ID in response")
+	}
+	return result.ClientID, result.ClientSecret, nil
+}
+
+// discover returns the metadata of the authorization server of resource.
+// The authorization server is found from the protected resource metadata
+// (RFC 9728); servers without it are their own authorization server.
+func (t *oauthTransport) discover(ctx context.Context, resource *url.URL, challenge string) (*authServerMetadata, error) {
+	key := origin(resource)
+	if meta, ok := t.metadata[key]; ok {
+		return meta, nil
+	}
+
+	issuer := key
+	metadataURL := key + "/.well-known/oauth-protected-resource"
+	if m := resourceMetadataPattern.FindStringSubmatch(challenge); m != nil {
+		metadataURL = m[1]
+	}
+	var prm struct {
+		AuthorizationServers []string `json:"authorization_servers"`
+	}
+	if err := t.getJSON(ctx, metadataURL, &prm); err == nil && len(prm.AuthorizationServers) > 0 {
+		issuer = strings.TrimSuffix(prm.AuthorizationServers[0], "/")
+	}
+
+	issuerURL, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authorization server URL: %w", err)
+	}
+	var meta authServerMetadata
+	found := false
+	for _, wellKnown := range []string{"/.well-known/oauth-authorization-server", "/.well-known/openid-configuration"} {
+		u := origin(issuerURL) + wellKnown + strings.TrimSuffix(issuerURL.Path, "/")
+		if err := t.getJSON(ctx, u, &meta); err == nil && meta.TokenEndpoint != "" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		// Default endpoints of servers without metadata
+		meta = authServerMetadata{
+			AuthorizationEndpoint: issuer + "/authorize",
+			TokenEndpoint:         issuer + "/token",
+			RegistrationEndpoint:  issuer + "/register",
+		}
+	}
+
+	if t.metadata == nil {
+		t.metadata = make(map[string]*authServerMetadata)
+	}
+	t.metadata[key] = &meta
+	return &meta, nil
+}
+
+var resourceMetadataPattern = regexp.MustCompile(`resource_metadata="([^"]+)"`)
+
+// getJSON fetches u and decodes the JSON response into v.
+func (t *oauthTransport) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return t.doJSON(req, v)
+}
+
+// doJSON sends req without authorization and decodes the JSON response into v.
+// Error responses of the token endpoint are decoded too.
+func (t *oauthTransport) doJSON(req *http.Request, v any) error {
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+	}
+	return nil
+}
+
+// origin returns the scheme and host of u.
+func origin(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// resourceID returns the canonical URI of the MCP server (RFC 8707).
+func resourceID(u *url.URL) string {
+	return origin(u) + strings.TrimSuffix(u.Path, "/")
+}
+
+// randomString returns a random URL-safe string for PKCE verifiers and states.
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
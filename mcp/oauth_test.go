@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oauthTestServer is an MCP server that is its own OAuth authorization server.
+type oauthTestServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	valid     map[string]bool // Accepted access tokens
+	issued    int
+	challenge string // PKCE code challenge of the pending authorization
+	grants    []string
+}
+
+func newOAuthTestServer(t *testing.T) *oauthTestServer {
+	t.Helper()
+	s := &oauthTestServer{valid: make(map[string]bool)}
+	server := newTestServer()
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"authorization_servers": []string{s.URL}})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"registration_endpoint":  s.URL + "/register",
+		})
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"client_id": "client-1"})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		assert.Equal(t, "client-1", r.Form.Get("client_id"))
+		grant := r.Form.Get("grant_type")
+		s.grants = append(s.grants, grant)
+		if grant == "authorization_code" {
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "code-1" || base64.RawURLEncoding.EncodeToString(sum[:]) != s.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_grant"})
+				return
+			}
+		}
+		s.issued++
+		token := fmt.Sprintf("access-%d", s.issued)
+		s.valid[token] = true
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  token,
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+		})
+	})
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		ok := len(r.Header.Get("Authorization")) > 7 && s.valid[r.Header.Get("Authorization")[7:]]
+		s.mu.Unlock()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+s.URL+`/.well-known/oauth-protected-resource"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// revoke invalidates all access tokens.
+func (s *oauthTestServer) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = make(map[string]bool)
+}
+
+func TestWithOAuth(t *testing.T) {
+	ts := newOAuthTestServer(t)
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+
+	authorizations := 0
+	authorize := func(ctx context.Context, authURL string) (*url.URL, error) {
+		authorizations++
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		q := u.Query()
+		assert.Equal(t, "client-1", q.Get("client_id"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.Equal(t, "read", q.Get("scope"))
+
+		ts.mu.Lock()
+		ts.challenge = q.Get("code_challenge")
+		ts.mu.Unlock()
+		return url.Parse(DefaultRedirectURL + "?code=code-1&state=" + url.QueryEscape(q.Get("state")))
+	}
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL+"/mcp", WithOAuth(OAuthConfig{
+		Scopes:    []string{"read"},
+		Store:     store,
+		Authorize: authorize,
+	}))
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, 1, authorizations)
+
+	token, err := store.Load(ctx, ts.URL)
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "client-1", token.ClientID)
+	assert.True(t, token.Valid())
+
+	// Rejected tokens are refreshed without authorizing again
+	ts.revoke()
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tools, 1)
+	assert.Equal(t, 1, authorizations)
+	assert.Equal(t, []string{"authorization_code", "refresh_token"}, ts.grants)
+
+	// Stored tokens are reused by new clients
+	client2, err := NewStreamableHTTPClient(ctx, ts.URL+"/mcp", WithOAuth(OAuthConfig{Store: store, Authorize: authorize}))
+	require.NoError(t, err)
+	defer client2.Close()
+	assert.Equal(t, 1, authorizations)
+}
+
+func TestWithOAuth_StateMismatch(t *testing.T) {
+	ts := newOAuthTestServer(t)
+
+	_, err := NewStreamableHTTPClient(context.Background(), ts.URL+"/mcp", WithOAuth(OAuthConfig{
+		Authorize: func(ctx context.Context, authURL string) (*url.URL, error) {
+			return url.Parse(DefaultRedirectURL + "?code=code-1&state=forged")
+		},
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "state mismatch")
+}
@@ -0,0 +1,232 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ConnectionState is the state of the connection to an MCP server.
+type ConnectionState int
+
+const (
+	// StateConnected means the client is connected (or has reconnected) to the server.
+	StateConnected ConnectionState = iota
+	// StateReconnecting means the connection was lost and the client is reconnecting.
+	StateReconnecting
+	// StateFailed means the client gave up reconnecting.
+	StateFailed
+	// StateClosed means the client was closed.
+	StateClosed
+)
+
+// String returns the name of the state.
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateFailed:
+		return "failed"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrUnavailable is returned by tool calls while the client is not connected,
+// e.g. while a crashed server is being restarted.
+var ErrUnavailable = errors.New("MCP server temporarily unavailable")
+
+// ReconnectPolicy controls how a client reconnects after losing its connection.
+// Delays grow exponentially from InitialDelay up to MaxDelay.
+type ReconnectPolicy struct {
+	MaxAttempts  int           // Attempts per lost connection before giving up (0 = unlimited)
+	InitialDelay time.Duration // Delay before the first attempt
+	MaxDelay     time.Duration // Maximum delay between attempts
+}
+
+// DefaultReconnectPolicy is the reconnect policy of NewSSEClient.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts:  10,
+	InitialDelay: 500 * time.Millisecond,
+	MaxDelay:     30 * time.Second,
+}
+
+// delay returns the delay before the given attempt (starting at 1).
+func (p ReconnectPolicy) delay(attempt int) time.Duration {
+	return backoff(p.InitialDelay, p.MaxDelay, attempt)
+}
+
+// backoff returns the delay before the given attempt (starting at 1),
+// doubling from initial up to max.
+func backoff(initial, max time.Duration, attempt int) time.Duration {
+	d := initial
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if max > 0 && d >= max {
+			return max
+		}
+	}
+	return d
+}
+
+// WithReconnect makes the client reconnect with policy when its connection
+// to the server is lost. Local (stdio) servers are restarted when their
+// process exits. A new session is initialized on each reconnect; tools
+// obtained from the client keep working once it has reconnected, and
+// OnToolsChanged listeners receive the re-listed tools. Calls made while the
+// client is reconnecting fail with ErrUnavailable (see WithRetry).
+func WithReconnect(policy ReconnectPolicy) Option {
+	return func(c *clientConfig) {
+		c.reconnect = &policy
+	}
+}
+
+// WithConnectionStateHandler sets a function called when the connection
+// state changes, e.g. to log or report the health of remote servers.
+// err is the error that caused the change, if any.
+func WithConnectionStateHandler(fn func(state ConnectionState, err error)) Option {
+	return func(c *clientConfig) {
+		c.onState = fn
+	}
+}
+
+// WithKeepAlive pings the server at the given interval and closes the
+// connection if a ping fails, so lost connections are detected (and, with
+// WithReconnect, re-established) even while the client is idle.
+func WithKeepAlive(interval time.Duration) Option {
+	return func(c *clientConfig) {
+		c.keepAlive = interval
+	}
+}
+
+// NewSSEClient creates an MCP client that connects to a remote server using
+// the HTTP+SSE transport. The client reconnects automatically with
+// DefaultReconnectPolicy unless another policy is set with WithReconnect.
+//
+// Example:
+//
+//	client, err := mcp.NewSSEClient(ctx, "https://example.com/sse",
+//	    mcp.WithConnectionStateHandler(func(state mcp.ConnectionState, err error) {
+//	        log.Printf("MCP server %s: %v", state, err)
+//	    }),
+//	)
+func NewSSEClient(ctx context.Context, endpoint string, opts ...Option) (*Client, error) {
+	cfg := newClientConfig(opts)
+	if cfg.reconnect == nil {
+		policy := DefaultReconnectPolicy
+		cfg.reconnect = &policy
+	}
+	return newSSEClient(ctx, endpoint, cfg)
+}
+
+// newSSEClient connects to endpoint using the HTTP+SSE transport.
+func newSSEClient(ctx context.Context, endpoint string, cfg *clientConfig) (*Client, error) {
+	httpClient := cfg.newHTTPClient()
+	return connect(ctx, func() mcp.Transport {
+		return &mcp.SSEClientTransport{
+			Endpoint:   endpoint,
+			HTTPClient: httpClient,
+		}
+	}, cfg)
+}
+
+// startSupervisor starts reconnecting when the session ends, if enabled.
+func (c *Client) startSupervisor() {
+	if c.reconnect == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go c.supervise(ctx)
+}
+
+// supervise waits for the current session to end and reconnects.
+func (c *Client) supervise(ctx context.Context) {
+	defer close(c.done)
+
+	for {
+		err := c.currentSession().Wait()
+		if ctx.Err() != nil {
+			return
+		}
+		c.notifyState(StateReconnecting, err)
+
+		session, err := c.reconnectSession(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.notifyState(StateFailed, err)
+			}
+			return
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			_ = session.Close()
+			return
+		}
+		c.session = session
+		c.mu.Unlock()
+		c.notifyState(StateConnected, nil)
+
+		// The new session may provide different tools
+		c.refreshTools()
+	}
+}
+
+// reconnectSession connects a new session, retrying with backoff.
+func (c *Client) reconnectSession(ctx context.Context) (*mcp.ClientSession, error) {
+	var lastErr error
+	for attempt := 1; c.reconnect.MaxAttempts <= 0 || attempt <= c.reconnect.MaxAttempts; attempt++ {
+		timer := time.NewTimer(c.reconnect.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+
+		session, err := c.mcpClient.Connect(ctx, c.newTransport(), nil)
+		if err == nil {
+			c.setLogLevel(ctx, session)
+			return session, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// State returns the current connection state.
+func (c *Client) State() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+// available returns ErrUnavailable if the client is not connected.
+func (c *Client) available() error {
+	if state := c.State(); state != StateConnected {
+		return fmt.Errorf("%w (server %s)", ErrUnavailable, state)
+	}
+	return nil
+}
+
+// notifyState records the connection state and calls the connection state
+// handler, if any.
+func (c *Client) notifyState(state ConnectionState, err error) {
+	c.mu.Lock()
+	c.state = state
+	c.mu.Unlock()
+
+	if c.onState != nil {
+		c.onState(state, err)
+	}
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconnectPolicy_Delay(t *testing.T) {
+	p := ReconnectPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	assert.Equal(t, 5*time.Second, p.delay(4))
+	assert.Equal(t, 5*time.Second, p.delay(20))
+}
+
+func TestNewSSEClient_Reconnects(t *testing.T) {
+	server := newTestServer()
+	handler := mcp.NewSSEHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	states := make(chan ConnectionState, 10)
+	ctx := context.Background()
+	client, err := NewSSEClient(ctx, ts.URL,
+		WithReconnect(ReconnectPolicy{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}),
+		WithConnectionStateHandler(func(state ConnectionState, err error) {
+			states <- state
+		}),
+	)
+	require.NoError(t, err)
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+
+	// Drop the connection; the client reconnects with a new session
+	ts.CloseClientConnections()
+	assert.Equal(t, StateReconnecting, waitState(t, states))
+	assert.Equal(t, StateConnected, waitState(t, states))
+
+	result, err := tools[0].Execute(ctx, []byte(`{"name":"again"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, again", result.(*ToolResult).Text)
+
+	require.NoError(t, client.Close())
+	assert.Equal(t, StateClosed, waitState(t, states))
+}
+
+func waitState(t *testing.T, states <-chan ConnectionState) ConnectionState {
+	t.Helper()
+	select {
+	case s := <-states:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for connection state")
+		return 0
+	}
+}
@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ReadResourceToolName is the name of the tool added by WithResourceTool.
+const ReadResourceToolName = "read_mcp_resource"
+
+// Resource describes a resource published by an MCP server.
+type Resource struct {
+	URI         string
+	Name        string
+	Title       string
+	Description string
+	MIMEType    string
+	Size        int64 // Size in bytes, if known
+}
+
+// ResourceContent is the content of a resource.
+// Text resources have Text set; binary resources have Blob set.
+type ResourceContent struct {
+	URI      string
+	MIMEType string
+	Text     string
+	Blob     []byte
+}
+
+// WithResourceTool makes Tools also return a read_mcp_resource tool that
+// lets the model read the server's resources by URI. The tool is only added
+// if the server publishes resources.
+func WithResourceTool() Option {
+	return func(c *clientConfig) {
+		c.resourceTool = true
+	}
+}
+
+// HasResources reports whether the server publishes resources.
+func (c *Client) HasResources() bool {
+	res := c.currentSession().InitializeResult()
+	return res != nil && res.Capabilities != nil && res.Capabilities.Resources != nil
+}
+
+// ListResources returns all resources published by the MCP server.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	for r, err := range c.currentSession().Resources(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("listing MCP resources: %w", err)
+		}
+		resources = append(resources, Resource{
+			URI:         r.URI,
+			Name:        r.Name,
+			Title:       r.Title,
+			Description: r.Description,
+			MIMEType:    r.MIMEType,
+			Size:        r.Size,
+		})
+	}
+	return resources, nil
+}
+
+// ReadResource reads the resource with the given URI.
+// A resource may consist of several contents (e.g. the files of a directory).
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContent, error) {
+	ctx, endCall, err := c.beginCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer endCall()
+
+	result, err := c.currentSession().ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, fmt.Errorf("reading MCP resource %q: %w", uri, err)
+	}
+
+	contents := make([]ResourceContent, 0, len(result.Contents))
+	for _, rc := range result.Contents {
+		contents = append(contents, ResourceContent{
+			URI:      rc.URI,
+			MIMEType: rc.MIMEType,
+			Text:     rc.Text,
+			Blob:     rc.Blob,
+		})
+	}
+	return contents, nil
+}
+
+// ResourcesSystemMessage reads the resources with the given URIs and returns
+// a system message that inlines their contents, so the model can use them
+// without calling a tool.
+//
+// Example:
+//
+//	msg, err := client.ResourcesSystemMessage(ctx, "file:///docs/api.md")
+//	if err != nil {
+//	    return err
+//	}
+//	resp, err := llm.Call(ctx, "How do I authenticate?",
+//	    llm.WithSystemMessage(msg),
+//	)
+func (c *Client) ResourcesSystemMessage(ctx context.Context, uris ...string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("# Resources\n\n")
+	for _, uri := range uris {
+		contents, err := c.ReadResource(ctx, uri)
+		if err != nil {
+			return "", err
+		}
+		for _, rc := range contents {
+			sb.WriteString(formatResourceContent(rc))
+			sb.WriteString("\n\n")
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// formatResourceContent formats a resource content for the model.
+// Binary content is described rather than inlined.
+func formatResourceContent(rc ResourceContent) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## %s\n", rc.URI))
+	if rc.MIMEType != "" {
+		sb.WriteString(fmt.Sprintf("MIME type: %s\n", rc.MIMEType))
+	}
+	sb.WriteString("\n")
+	if rc.Blob != nil {
+		sb.WriteString(fmt.Sprintf("[Binary content, %d bytes]", len(rc.Blob)))
+	} else {
+		sb.WriteString(rc.Text)
+	}
+	return sb.String()
+}
+
+// readResourceInput is the input of the read_mcp_resource tool.
+type readResourceInput struct {
+	URI string `json:"uri" jsonschema:"required,description=URI of the resource to read"`
+}
+
+// newResourceTool returns the read_mcp_resource tool, listing the available
+// resources in its description.
+func (c *Client) newResourceTool(ctx context.Context) (llm.Tool, error) {
+	resources, err := c.ListResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Read a resource from the MCP server by URI.")
+	if len(resources) > 0 {
+		sb.WriteString(" Available resources:")
+		for _, r := range resources {
+			sb.WriteString(fmt.Sprintf("\n- %s", r.URI))
+			if r.Description != "" {
+				sb.WriteString(": " + r.Description)
+			} else if r.Name != "" {
+				sb.WriteString(": " + r.Name)
+			}
+		}
+	}
+
+	tool, err := llm.NewTool(ReadResourceToolName, sb.String(),
+		func(ctx context.Context, in readResourceInput) (string, error) {
+			contents, err := c.ReadResource(ctx, in.URI)
+			if err != nil {
+				return "", err
+			}
+			parts := make([]string, 0, len(contents))
+			for _, rc := range contents {
+				parts = append(parts, formatResourceContent(rc))
+			}
+			return strings.Join(parts, "\n\n"), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
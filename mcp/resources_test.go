@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Resources(t *testing.T) {
+	server := newTestServer()
+	server.AddResource(&mcp.Resource{
+		URI:         "file:///docs/auth.md",
+		Name:        "auth",
+		Description: "Authentication guide",
+		MIMEType:    "text/markdown",
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+			{URI: req.Params.URI, MIMEType: "text/markdown", Text: "Use an API key."},
+		}}, nil
+	})
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL, WithResourceTool())
+	require.NoError(t, err)
+	defer client.Close()
+
+	assert.True(t, client.HasResources())
+
+	resources, err := client.ListResources(ctx)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "auth", resources[0].Name)
+	assert.Equal(t, "text/markdown", resources[0].MIMEType)
+
+	contents, err := client.ReadResource(ctx, "file:///docs/auth.md")
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Equal(t, "Use an API key.", contents[0].Text)
+
+	msg, err := client.ResourcesSystemMessage(ctx, "file:///docs/auth.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# Resources\n\n## file:///docs/auth.md\nMIME type: text/markdown\n\nUse an API key.", msg)
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	readTool := tools[1]
+	assert.Equal(t, ReadResourceToolName, readTool.Name())
+	assert.Contains(t, readTool.Description(), "file:///docs/auth.md: Authentication guide")
+
+	result, err := readTool.Execute(ctx, []byte(`{"uri":"file:///docs/auth.md"}`))
+	require.NoError(t, err)
+	assert.Contains(t, result, "Use an API key.")
+}
+
+func TestFormatResourceContent_Binary(t *testing.T) {
+	got := formatResourceContent(ResourceContent{URI: "file:///logo.png", MIMEType: "image/png", Blob: []byte{1, 2, 3}})
+	assert.Equal(t, "## file:///logo.png\nMIME type: image/png\n\n[Binary content, 3 bytes]", got)
+}
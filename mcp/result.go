@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ToolResult is the result of an MCP tool call.
+// It implements llm.ToolResultContent, so tools return it as is and the
+// model receives its text followed by its structured content.
+type ToolResult struct {
+	Text       string            // Text content joined with newlines; other content is described
+	Structured json.RawMessage   // Structured content (structuredContent), if any
+	Images     []Image           // Image content
+	Resources  []ResourceContent // Embedded resources
+	IsError    bool              // Whether the tool reported an error
+}
+
+// Image is image content of a tool result.
+type Image struct {
+	MIMEType string
+	Data     []byte
+}
+
+// ToolContent returns the content sent to the model: the text, followed by
+// the structured content unless the text is already its JSON serialization.
+func (r *ToolResult) ToolContent() string {
+	switch {
+	case len(r.Structured) == 0:
+		return r.Text
+	case r.Text == "":
+		return string(r.Structured)
+	case sameJSON(r.Text, r.Structured):
+		return r.Text
+	default:
+		return r.Text + "\n\n" + string(r.Structured)
+	}
+}
+
+// sameJSON reports whether text is JSON with the same value as data.
+func sameJSON(text string, data json.RawMessage) bool {
+	var a, b any
+	if json.Unmarshal([]byte(text), &a) != nil || json.Unmarshal(data, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// String returns the content sent to the model.
+func (r *ToolResult) String() string {
+	return r.ToolContent()
+}
+
+// Decode unmarshals the structured content into v.
+func (r *ToolResult) Decode(v any) error {
+	if len(r.Structured) == 0 {
+		return fmt.Errorf("tool result has no structured content")
+	}
+	return json.Unmarshal(r.Structured, v)
+}
+
+// CallTool calls the named tool of the server with arguments.
+// Unlike the tools returned by Tools, it returns tool errors as a result
+// with IsError set rather than as an error. Timed-out calls return a
+// *TimeoutError.
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]any) (*ToolResult, error) {
+	if err := c.checkToolAllowed(name); err != nil {
+		return nil, err
+	}
+	ctx, endCall, err := c.beginCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer endCall()
+
+	params := &mcp.CallToolParams{
+		Name:      name,
+		Arguments: arguments,
+	}
+	if c.onProgress != nil {
+		token, done := c.progress.add(name)
+		defer done()
+		params.Meta = mcp.Meta{} // SetProgressToken requires non-nil metadata
+		params.SetProgressToken(token)
+	}
+
+	// Call the MCP tool
+	result, err := c.callTool(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return newToolResult(result)
+}
+
+// newToolResult converts an MCP tool call result.
+func newToolResult(result *mcp.CallToolResult) (*ToolResult, error) {
+	r := &ToolResult{
+		Text:    processToolResult(result.Content),
+		IsError: result.IsError,
+	}
+
+	if result.StructuredContent != nil {
+		data, err := json.Marshal(result.StructuredContent)
+		if err != nil {
+			return nil, fmt.Errorf("encoding structured content: %w", err)
+		}
+		r.Structured = data
+	}
+
+	for _, c := range result.Content {
+		switch item := c.(type) {
+		case *mcp.ImageContent:
+			r.Images = append(r.Images, Image{MIMEType: item.MIMEType, Data: item.Data})
+		case *mcp.EmbeddedResource:
+			if item.Resource != nil {
+				r.Resources = append(r.Resources, ResourceContent{
+					URI:      item.Resource.URI,
+					MIMEType: item.Resource.MIMEType,
+					Text:     item.Resource.Text,
+					Blob:     item.Resource.Blob,
+				})
+			}
+		}
+	}
+	return r, nil
+}
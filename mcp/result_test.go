@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+type forecast struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+}
+
+func TestClient_CallTool_Structured(t *testing.T) {
+	server := newTestServer()
+	mcp.AddTool(server, &mcp.Tool{Name: "forecast", Description: "Get the forecast"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in greetInput) (*mcp.CallToolResult, forecast, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{
+				&mcp.TextContent{Text: "Sunny in " + in.Name},
+				&mcp.ImageContent{MIMEType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}},
+			}}, forecast{City: in.Name, Temperature: 21.5}, nil
+		})
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL)
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.CallTool(ctx, "forecast", map[string]any{"name": "Tokyo"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "Sunny in Tokyo\n[Image: image/png, 4 bytes]", result.Text)
+	require.Len(t, result.Images, 1)
+	assert.Equal(t, "image/png", result.Images[0].MIMEType)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, result.Images[0].Data)
+
+	var got forecast
+	require.NoError(t, result.Decode(&got))
+	assert.Equal(t, forecast{City: "Tokyo", Temperature: 21.5}, got)
+
+	// Tools return the result to the model as its text and structured content
+	registry := llm.NewToolRegistry()
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	registry.Register(tools...)
+	messages, err := llm.ExecuteToolCalls(ctx, []llm.ToolCall{
+		{ID: "call_1", Name: "forecast", Arguments: `{"name":"Osaka"}`},
+	}, registry)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Sunny in Osaka\n[Image: image/png, 4 bytes]\n\n{\"city\":\"Osaka\",\"temperature\":21.5}", messages[0].Content)
+}
+
+func TestNewToolResult(t *testing.T) {
+	t.Run("structured only", func(t *testing.T) {
+		result, err := newToolResult(&mcp.CallToolResult{
+			StructuredContent: map[string]any{"city": "Tokyo"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `{"city":"Tokyo"}`, result.ToolContent())
+	})
+
+	t.Run("text and structured", func(t *testing.T) {
+		result, err := newToolResult(&mcp.CallToolResult{
+			Content:           []mcp.Content{&mcp.TextContent{Text: "Sunny in Tokyo"}},
+			StructuredContent: map[string]any{"city": "Tokyo"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Sunny in Tokyo\n\n{\"city\":\"Tokyo\"}", result.ToolContent())
+	})
+
+	t.Run("structured serialized as text", func(t *testing.T) {
+		result, err := newToolResult(&mcp.CallToolResult{
+			Content:           []mcp.Content{&mcp.TextContent{Text: `{"city": "Tokyo"}`}},
+			StructuredContent: map[string]any{"city": "Tokyo"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `{"city": "Tokyo"}`, result.ToolContent())
+	})
+
+	t.Run("embedded resource", func(t *testing.T) {
+		result, err := newToolResult(&mcp.CallToolResult{Content: []mcp.Content{
+			&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///a.txt", MIMEType: "text/plain", Text: "a"}},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "[Resource: file:///a.txt]", result.ToolContent())
+		require.Len(t, result.Resources, 1)
+		assert.Equal(t, "a", result.Resources[0].Text)
+		assert.Error(t, result.Decode(&struct{}{}))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		result, err := newToolResult(&mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "not found"}},
+			IsError: true,
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "not found", result.Text)
+	})
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RetryPolicy controls how tool calls are retried after transient transport
+// errors, such as a dropped connection. Errors reported by the server and
+// timeouts are not retried. Delays grow exponentially from InitialDelay up
+// to MaxDelay.
+type RetryPolicy struct {
+	MaxRetries   int           // Retries after the first attempt
+	InitialDelay time.Duration // Delay before the first retry
+	MaxDelay     time.Duration // Maximum delay between retries
+}
+
+// WithRetry retries tool calls that fail with transient transport errors.
+// Combined with WithReconnect, calls made while the client is reconnecting
+// succeed once it has reconnected.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *clientConfig) {
+		c.retry = policy
+	}
+}
+
+// WithToolTimeout overrides the timeout (see WithTimeout) of the named tool,
+// e.g. to allow a slow build tool more time than a quick lookup.
+func WithToolTimeout(tool string, d time.Duration) Option {
+	return func(c *clientConfig) {
+		if c.toolTimeouts == nil {
+			c.toolTimeouts = make(map[string]time.Duration)
+		}
+		c.toolTimeouts[tool] = d
+	}
+}
+
+// WithTimeoutResult makes tools return a timeout as a result telling the
+// model that the tool timed out, instead of as an error, so callers that stop
+// on tool errors let the model react to it (e.g. by narrowing its request).
+func WithTimeoutResult() Option {
+	return func(c *clientConfig) {
+		c.timeoutResult = true
+	}
+}
+
+// TimeoutError is returned when a tool call times out.
+type TimeoutError struct {
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("MCP tool %q timed out after %s", e.Tool, e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// toolTimeout returns the timeout of the named tool.
+func (c *Client) toolTimeout(tool string) time.Duration {
+	if d, ok := c.toolTimeouts[tool]; ok {
+		return d
+	}
+	return c.timeout
+}
+
+// callTool calls a tool, retrying after transient errors as configured.
+func (c *Client) callTool(ctx context.Context, params *mcp.CallToolParams) (*mcp.CallToolResult, error) {
+	timeout := c.toolTimeout(params.Name)
+	for attempt := 0; ; attempt++ {
+		result, err := c.callToolOnce(ctx, params, timeout)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return nil, &TimeoutError{Tool: params.Name, Timeout: timeout}
+		}
+		if attempt >= c.retry.MaxRetries || ctx.Err() != nil || !isTransient(err) {
+			return nil, fmt.Errorf("calling MCP tool: %w", err)
+		}
+
+		timer := time.NewTimer(backoff(c.retry.InitialDelay, c.retry.MaxDelay, attempt+1))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("calling MCP tool: %w", err)
+		}
+	}
+}
+
+// callToolOnce calls a tool on the current session with a timeout.
+func (c *Client) callToolOnce(ctx context.Context, params *mcp.CallToolParams, timeout time.Duration) (*mcp.CallToolResult, error) {
+	if err := c.available(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return c.currentSession().CallTool(ctx, params)
+}
+
+// isTransient reports whether err is a transport error that may not recur.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.Is(err, mcp.ErrConnectionClosed) ||
+		errors.Is(err, ErrUnavailable) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.As(err, &netErr) {
+		return true
+	}
+
+	// The HTTP transports do not wrap the errors of failed requests
+	msg := err.Error()
+	for _, s := range transientMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+var transientMessages = []string{
+	": EOF",
+	"unexpected EOF",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+}
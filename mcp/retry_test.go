@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func newSlowTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := newTestServer()
+	mcp.AddTool(server, &mcp.Tool{Name: "slow", Description: "Take a while"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+		})
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestWithToolTimeout(t *testing.T) {
+	ts := newSlowTestServer(t)
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL, WithToolTimeout("slow", 50*time.Millisecond))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.CallTool(ctx, "slow", nil)
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "slow", timeoutErr.Tool)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Other tools keep the client timeout
+	result, err := client.CallTool(ctx, "greet", map[string]any{"name": "Gopher"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Gopher", result.Text)
+}
+
+func TestWithTimeoutResult(t *testing.T) {
+	ts := newSlowTestServer(t)
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL, WithTimeout(50*time.Millisecond), WithTimeoutResult())
+	require.NoError(t, err)
+	defer client.Close()
+
+	tools, err := client.Tools(ctx)
+	require.NoError(t, err)
+	for _, tool := range tools {
+		if tool.Name() == "slow" {
+			result, err := tool.Execute(ctx, []byte(`{}`))
+			require.NoError(t, err)
+			require.IsType(t, llm.ToolErrorResult{}, result)
+			assert.Equal(t, llm.ToolErrorTimeout, result.(llm.ToolErrorResult).ErrorType)
+			assert.Contains(t, result.(llm.ToolErrorResult).ToolContent(), `MCP tool \"slow\" timed out after 50ms`)
+		}
+	}
+}
+
+// dropFirstCall drops the connection of the first tool call.
+type dropFirstCall struct {
+	next    http.Handler
+	dropped atomic.Bool
+}
+
+func (h *dropFirstCall) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if bytes.Contains(body, []byte(`"tools/call"`)) && h.dropped.CompareAndSwap(false, true) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func TestWithRetry(t *testing.T) {
+	server := newTestServer()
+	handler := &dropFirstCall{next: mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL,
+		WithReconnect(ReconnectPolicy{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond}),
+		WithRetry(RetryPolicy{MaxRetries: 5, InitialDelay: 20 * time.Millisecond}),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.CallTool(ctx, "greet", map[string]any{"name": "again"})
+	require.NoError(t, err)
+	assert.True(t, handler.dropped.Load())
+	assert.Equal(t, "Hello, again", result.Text)
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Root is a filesystem root exposed to MCP servers. Filesystem-oriented
+// servers scope their operations to the roots of the client.
+type Root struct {
+	Name string // Optional display name
+	Path string // Directory path; relative paths are resolved against the working directory
+}
+
+// WithRoots sets the filesystem roots exposed to the server, e.g. the
+// workspace of the host application. Roots can be changed later with
+// Client.SetRoots.
+//
+// Example:
+//
+//	client, err := mcp.NewStdioClient(ctx, "mcp-server-filesystem", nil,
+//	    mcp.WithRoots(mcp.Root{Name: "workspace", Path: "."}),
+//	)
+func WithRoots(roots ...Root) Option {
+	return func(c *clientConfig) {
+		c.roots = append(c.roots, roots...)
+	}
+}
+
+// Roots returns the filesystem roots exposed to the server.
+func (c *Client) Roots() []Root {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.roots)
+}
+
+// SetRoots replaces the filesystem roots exposed to the server and notifies
+// it that they changed.
+func (c *Client) SetRoots(roots ...Root) error {
+	mcpRoots, err := toMCPRoots(roots)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var removed []string
+	for _, old := range c.mcpRoots {
+		if !slices.ContainsFunc(mcpRoots, func(r *mcp.Root) bool { return r.URI == old.URI }) {
+			removed = append(removed, old.URI)
+		}
+	}
+	if len(removed) > 0 {
+		c.mcpClient.RemoveRoots(removed...)
+	}
+	if len(mcpRoots) > 0 {
+		c.mcpClient.AddRoots(mcpRoots...)
+	}
+	c.roots = slices.Clone(roots)
+	c.mcpRoots = mcpRoots
+	return nil
+}
+
+// toMCPRoots converts roots to MCP roots with file URIs.
+func toMCPRoots(roots []Root) ([]*mcp.Root, error) {
+	mcpRoots := make([]*mcp.Root, 0, len(roots))
+	for _, r := range roots {
+		path, err := filepath.Abs(r.Path)
+		if err != nil {
+			return nil, fmt.Errorf("resolving root %q: %w", r.Path, err)
+		}
+		u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+		mcpRoots = append(mcpRoots, &mcp.Root{Name: r.Name, URI: u.String()})
+	}
+	return mcpRoots, nil
+}
@@ -0,0 +1,47 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Roots(t *testing.T) {
+	server := newTestServer()
+	mcp.AddTool(server, &mcp.Tool{Name: "roots", Description: "List the client's roots"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			result, err := req.Session.ListRoots(ctx, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			var uris []string
+			for _, r := range result.Roots {
+				uris = append(uris, r.Name+"="+r.URI)
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(uris, ",")}}}, nil, nil
+		})
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, ts.URL, WithRoots(Root{Name: "workspace", Path: "/work/app"}))
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.CallTool(ctx, "roots", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "workspace=file:///work/app", result.Text)
+
+	require.NoError(t, client.SetRoots(Root{Name: "docs", Path: "/work/docs"}, Root{Path: "/work/app"}))
+	assert.Len(t, client.Roots(), 2)
+
+	result, err = client.CallTool(ctx, "roots", nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"docs=file:///work/docs", "=file:///work/app"}, strings.Split(result.Text, ","))
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShuttingDown is returned by calls to a client or manager that is
+// shutting down or has been shut down.
+var ErrShuttingDown = errors.New("MCP client is shutting down")
+
+// beginCall registers an in-flight call with ctx. It returns the context
+// of the call, which is cancelled when a shutdown times out, and a function
+// that unregisters the call. It returns ErrShuttingDown if the client is
+// shutting down or closed.
+func (c *Client) beginCall(ctx context.Context) (context.Context, func(), error) {
+	c.mu.Lock()
+	if c.closed || c.draining {
+		c.mu.Unlock()
+		return nil, nil, ErrShuttingDown
+	}
+	if c.aborted == nil {
+		c.aborted, c.abort = context.WithCancel(context.Background())
+	}
+	aborted := c.aborted
+	c.inflight.Add(1)
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(aborted, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		c.inflight.Done()
+	}, nil
+}
+
+// Shutdown closes the client gracefully: it stops accepting new tool calls
+// and resource reads, waits for the in-flight ones to finish, and closes the
+// connection. If ctx is done first, the calls still in flight are cancelled
+// and ctx's error is returned.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	if err := client.Shutdown(ctx); err != nil {
+//	    log.Printf("MCP client shutdown: %v", err)
+//	}
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.draining = true
+	abort := c.abort
+	c.mu.Unlock()
+
+	waitErr := waitContext(ctx, &c.inflight)
+	if waitErr != nil && abort != nil {
+		abort()
+		c.inflight.Wait()
+	}
+	return errors.Join(waitErr, c.Close())
+}
+
+// ShutdownAll shuts down all servers gracefully, like Client.Shutdown, in
+// parallel. Unlike Close, it is final: the manager starts no server again
+// and its tools fail with ErrShuttingDown.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	err := manager.ShutdownAll(ctx)
+func (m *Manager) ShutdownAll(ctx context.Context) error {
+	m.mu.Lock()
+	m.shutdown = true
+	clients := m.clients
+	m.clients = make(map[string]*Client)
+	m.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for name, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("shutting down MCP server %q: %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// waitContext waits for wg or for ctx to be done, whichever is first.
+func waitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSlowServer returns the URL of a server with a "slow" tool that signals
+// started when called and returns when release is closed.
+func newSlowServer(t *testing.T, started chan<- struct{}, release <-chan struct{}) string {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "slow", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "slow", Description: "Wait"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+			started <- struct{}{}
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+		})
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestClient_Shutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	ctx := context.Background()
+	client, err := NewStreamableHTTPClient(ctx, newSlowServer(t, started, release))
+	require.NoError(t, err)
+
+	type callResult struct {
+		result *ToolResult
+		err    error
+	}
+	call := make(chan callResult)
+	go func() {
+		result, err := client.CallTool(ctx, "slow", nil)
+		call <- callResult{result, err}
+	}()
+	<-started
+
+	shutdown := make(chan error)
+	go func() { shutdown <- client.Shutdown(ctx) }()
+
+	// New calls are refused while the in-flight call finishes
+	require.Eventually(t, func() bool {
+		client.mu.RLock()
+		defer client.mu.RUnlock()
+		return client.draining
+	}, time.Second, time.Millisecond)
+	_, err = client.CallTool(ctx, "slow", nil)
+	assert.ErrorIs(t, err, ErrShuttingDown)
+
+	close(release)
+	res := <-call
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.result.Text)
+	require.NoError(t, <-shutdown)
+	assert.Equal(t, StateClosed, client.State())
+}
+
+func TestClient_Shutdown_Deadline(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	client, err := NewStreamableHTTPClient(context.Background(), newSlowServer(t, started, release))
+	require.NoError(t, err)
+
+	call := make(chan error)
+	go func() {
+		_, err := client.CallTool(context.Background(), "slow", nil)
+		call <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Error(t, <-call) // Cancelled when the shutdown timed out
+}
+
+func TestManager_ShutdownAll(t *testing.T) {
+	manager := NewManager([]ServerConfig{{Name: "a", URL: newTestHTTPServer(t).URL}})
+	ctx := context.Background()
+
+	tools, err := manager.Tools(ctx)
+	require.NoError(t, err)
+	require.NoError(t, manager.ShutdownAll(ctx))
+
+	// Servers are not restarted
+	_, err = tools[0].Execute(ctx, []byte(`{"name":"A"}`))
+	assert.ErrorIs(t, err, ErrShuttingDown)
+	_, err = manager.Tools(ctx)
+	assert.ErrorIs(t, err, ErrShuttingDown)
+}
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// TestMain runs the test binary as a stdio MCP server when asked to.
+func TestMain(m *testing.M) {
+	if os.Getenv("BUCEPHALUS_TEST_MCP_SERVER") == "1" {
+		server := newTestServer()
+		mcp.AddTool(server, &mcp.Tool{Name: "crash", Description: "Exit the server"},
+			func(ctx context.Context, req *mcp.CallToolRequest, in struct{}) (*mcp.CallToolResult, any, error) {
+				os.Exit(1)
+				return nil, nil, nil
+			})
+		_ = server.Run(context.Background(), &mcp.StdioTransport{})
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func TestNewStdioClient_RestartsCrashedServer(t *testing.T) {
+	states := make(chan ConnectionState, 10)
+	ctx := context.Background()
+	client, err := NewStdioClient(ctx, os.Args[0], nil,
+		WithEnv(map[string]string{"BUCEPHALUS_TEST_MCP_SERVER": "1"}),
+		WithReconnect(ReconnectPolicy{InitialDelay: 200 * time.Millisecond}),
+		WithConnectionStateHandler(func(state ConnectionState, err error) {
+			states <- state
+		}),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	relisted := make(chan []llm.Tool, 1)
+	client.OnToolsChanged(func(tools []llm.Tool, err error) {
+		relisted <- tools
+	})
+
+	_, err = client.CallTool(ctx, "crash", nil)
+	require.Error(t, err)
+
+	// Tools are unavailable while the server restarts
+	assert.Equal(t, StateReconnecting, waitState(t, states))
+	_, err = client.CallTool(ctx, "greet", map[string]any{"name": "Gopher"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	assert.Equal(t, StateConnected, waitState(t, states))
+	select {
+	case tools := <-relisted:
+		assert.Len(t, tools, 2)
+	case <-time.After(5 * time.Second):
+		t.Fatal("tools were not re-listed")
+	}
+
+	result, err := client.CallTool(ctx, "greet", map[string]any{"name": "Gopher"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Gopher", result.Text)
+}
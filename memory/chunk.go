@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// Chunk is a piece of a document produced by a Splitter.
+type Chunk struct {
+	Text     string
+	Metadata map[string]string // Set by some splitters, e.g. "heading" by MarkdownSplitter
+}
+
+// Splitter splits a document's text into chunks for embedding.
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+// SplitterFunc adapts a function to the Splitter interface.
+type SplitterFunc func(text string) []Chunk
+
+// Split implements Splitter.
+func (f SplitterFunc) Split(text string) []Chunk {
+	return f(text)
+}
+
+// DefaultChunkTokens is the default maximum chunk size of the splitters, in
+// estimated tokens (see llm.EstimateTokens).
+const DefaultChunkTokens = 512
+
+// TokenSplitter splits text into chunks of at most Size estimated tokens
+// that overlap by Overlap tokens. Chunks end at whitespace; words longer
+// than a chunk form a chunk of their own. Like the other splitters, it keeps
+// the original text, including line breaks, within each chunk.
+type TokenSplitter struct {
+	Size    int // Maximum tokens per chunk (default: DefaultChunkTokens)
+	Overlap int // Tokens repeated from the end of the previous chunk (default: none)
+}
+
+// Split implements Splitter.
+func (s TokenSplitter) Split(text string) []Chunk {
+	size := s.Size
+	if size <= 0 {
+		size = DefaultChunkTokens
+	}
+	overlap := min(max(s.Overlap, 0), size/2)
+
+	var chunks []Chunk
+	for _, span := range tokenSpans(text, wordSpans(text), size, overlap) {
+		chunks = append(chunks, Chunk{Text: text[span[0]:span[1]]})
+	}
+	return chunks
+}
+
+// tokenSpans groups consecutive spans of text into spans of at most size
+// estimated tokens, each starting overlap tokens before the end of the
+// previous one. A span larger than size forms a group of its own.
+func tokenSpans(text string, spans [][2]int, size, overlap int) [][2]int {
+	var groups [][2]int
+	for start := 0; start < len(spans); {
+		end := start + 1
+		for end < len(spans) && llm.EstimateTokens(text[spans[start][0]:spans[end][1]]) <= size {
+			end++
+		}
+		groups = append(groups, [2]int{spans[start][0], spans[end-1][1]})
+		if end == len(spans) {
+			break
+		}
+
+		// Overlap only as far as the next chunk can still take a new span
+		next := end
+		for next > start+1 && llm.EstimateTokens(text[spans[next-1][0]:spans[end-1][1]]) <= overlap &&
+			llm.EstimateTokens(text[spans[next-1][0]:spans[end][1]]) <= size {
+			next--
+		}
+		start = next
+	}
+	return groups
+}
+
+// wordSpans returns the start and end offsets of the words of text.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r) && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// sentenceEnd matches the end of a sentence or paragraph.
+var sentenceEnd = regexp.MustCompile(`[.!?。！？]+["'”’)\]]*\s+|\n\s*\n`)
+
+// SentenceSplitter groups whole sentences into chunks of at most MaxTokens
+// estimated tokens. Paragraph breaks also end sentences. Sentences longer
+// than MaxTokens are split at whitespace as by TokenSplitter.
+type SentenceSplitter struct {
+	MaxTokens int // Maximum tokens per chunk (default: DefaultChunkTokens)
+	Overlap   int // Sentences repeated from the end of the previous chunk (default: none)
+}
+
+// Split implements Splitter.
+func (s SentenceSplitter) Split(text string) []Chunk {
+	maxTokens := s.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultChunkTokens
+	}
+
+	var sentences [][2]int
+	for _, span := range sentenceSpans(text) {
+		sentence := text[span[0]:span[1]]
+		if llm.EstimateTokens(sentence) <= maxTokens {
+			sentences = append(sentences, span)
+			continue
+		}
+		for _, part := range tokenSpans(sentence, wordSpans(sentence), maxTokens, 0) {
+			sentences = append(sentences, [2]int{span[0] + part[0], span[0] + part[1]})
+		}
+	}
+
+	var chunks []Chunk
+	for start := 0; start < len(sentences); {
+		end := start + 1
+		for end < len(sentences) && llm.EstimateTokens(text[sentences[start][0]:sentences[end][1]]) <= maxTokens {
+			end++
+		}
+		chunks = append(chunks, Chunk{Text: text[sentences[start][0]:sentences[end-1][1]]})
+		if end == len(sentences) {
+			break
+		}
+		next := max(end-s.Overlap, start+1)
+		for next < end && llm.EstimateTokens(text[sentences[next][0]:sentences[end][1]]) > maxTokens {
+			next++ // Overlap only as far as the next chunk can still take a new sentence
+		}
+		start = next
+	}
+	return chunks
+}
+
+// sentenceSpans returns the start and end offsets of the sentences of text,
+// without surrounding whitespace.
+func sentenceSpans(text string) [][2]int {
+	var spans [][2]int
+	add := func(start, end int) {
+		sentence := text[start:end]
+		trimmed := strings.TrimLeftFunc(sentence, unicode.IsSpace)
+		start += len(sentence) - len(trimmed)
+		trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+		if trimmed != "" {
+			spans = append(spans, [2]int{start, start + len(trimmed)})
+		}
+	}
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		add(start, loc[1])
+		start = loc[1]
+	}
+	add(start, len(text))
+	return spans
+}
+
+// headingLine matches a Markdown ATX heading.
+var headingLine = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// MarkdownSplitter splits Markdown at headings, so each chunk belongs to one
+// section. The headings leading to a section are recorded in the chunk's
+// "heading" metadata, joined with " > ". Sections longer than MaxTokens are
+// split further with SentenceSplitter. Headings in fenced code blocks are
+// ignored.
+type MarkdownSplitter struct {
+	MaxTokens int // Maximum tokens per chunk (default: DefaultChunkTokens)
+	Level     int // Deepest heading level that starts a section (default: 6)
+}
+
+// Split implements Splitter.
+func (s MarkdownSplitter) Split(text string) []Chunk {
+	level := s.Level
+	if level <= 0 {
+		level = 6
+	}
+
+	var chunks []Chunk
+	var headings [6]string // Headings of the current section, by level
+	var section strings.Builder
+	flush := func() {
+		body := strings.TrimSpace(section.String())
+		section.Reset()
+		if body == "" {
+			return
+		}
+		var metadata map[string]string
+		if heading := strings.Join(compact(headings[:]), " > "); heading != "" {
+			metadata = map[string]string{"heading": heading}
+		}
+		for _, chunk := range (SentenceSplitter{MaxTokens: s.MaxTokens}).Split(body) {
+			chunk.Metadata = metadata
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if m := headingLine.FindStringSubmatch(trimmed); m != nil && !inFence && len(m[1]) <= level {
+			flush()
+			depth := len(m[1])
+			headings[depth-1] = m[2]
+			clear(headings[depth:])
+		}
+		section.WriteString(line)
+	}
+	flush()
+	return chunks
+}
+
+// compact returns the non-empty elements of s.
+func compact(s []string) []string {
+	var out []string
+	for _, v := range s {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
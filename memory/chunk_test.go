@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chunkTexts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	return texts
+}
+
+func sentenceTexts(text string) []string {
+	var texts []string
+	for _, span := range sentenceSpans(text) {
+		texts = append(texts, text[span[0]:span[1]])
+	}
+	return texts
+}
+
+func TestTokenSplitter(t *testing.T) {
+	// Each word is 8 characters, or 2 tokens
+	text := "aaaaaaa1 aaaaaaa2 aaaaaaa3 aaaaaaa4 aaaaaaa5"
+	assert.Equal(t, []string{
+		"aaaaaaa1 aaaaaaa2",
+		"aaaaaaa3 aaaaaaa4",
+		"aaaaaaa5",
+	}, chunkTexts(TokenSplitter{Size: 5}.Split(text)))
+
+	assert.Equal(t, []string{
+		"aaaaaaa1 aaaaaaa2 aaaaaaa3",
+		"aaaaaaa3 aaaaaaa4 aaaaaaa5",
+	}, chunkTexts(TokenSplitter{Size: 7, Overlap: 2}.Split(text)))
+
+	// Overlap never repeats a whole chunk
+	assert.Equal(t, []string{
+		"aaaaaaa1 aaaaaaa2",
+		"bbbbbbbbbbbbbbbbbbbb",
+	}, chunkTexts(TokenSplitter{Size: 5, Overlap: 2}.Split("aaaaaaa1 aaaaaaa2 bbbbbbbbbbbbbbbbbbbb")))
+
+	assert.Empty(t, TokenSplitter{}.Split("  \n "))
+}
+
+func TestSentenceSplitter(t *testing.T) {
+	text := "First sentence here. Second one!\n\nA new paragraph without a stop\nAnd a question? Done."
+	assert.Equal(t, []string{
+		"First sentence here.",
+		"Second one!",
+		"A new paragraph without a stop\nAnd a question?",
+		"Done.",
+	}, sentenceTexts(text))
+
+	assert.Equal(t, []string{
+		"First sentence here. Second one!",
+		"A new paragraph without a stop\nAnd a question?",
+		"Done.",
+	}, chunkTexts(SentenceSplitter{MaxTokens: 12}.Split(text)))
+
+	assert.Equal(t, []string{
+		"One. Two.",
+		"Two. Three.",
+		"Three. Four.",
+	}, chunkTexts(SentenceSplitter{MaxTokens: 3, Overlap: 1}.Split("One. Two. Three. Four.")))
+
+	// Sentences are not repeated if the next one would not fit with them
+	assert.Equal(t, []string{
+		"First sentence here. Second one!",
+		"A new paragraph without a stop\nAnd a question?",
+		"Done.",
+	}, chunkTexts(SentenceSplitter{MaxTokens: 12, Overlap: 1}.Split(text)))
+}
+
+func TestMarkdownSplitter(t *testing.T) {
+	text := `Intro text.
+
+# Install
+
+Run the installer.
+
+## Linux
+
+Use the package manager.
+
+` + "```sh\n# not a heading\napt install tool\n```" + `
+
+# Usage
+
+Run it.
+`
+	chunks := MarkdownSplitter{}.Split(text)
+	require.Len(t, chunks, 4)
+	assert.Equal(t, "Intro text.", chunks[0].Text)
+	assert.Nil(t, chunks[0].Metadata)
+	assert.Equal(t, "Install", chunks[1].Metadata["heading"])
+	assert.Equal(t, "Install > Linux", chunks[2].Metadata["heading"])
+	assert.Contains(t, chunks[2].Text, "# not a heading")
+	assert.Equal(t, "Usage", chunks[3].Metadata["heading"])
+	assert.Equal(t, "# Usage\n\nRun it.", chunks[3].Text)
+
+	// Sections are split only down to Level
+	chunks = MarkdownSplitter{Level: 1}.Split(text)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "Install", chunks[1].Metadata["heading"])
+	assert.Contains(t, chunks[1].Text, "## Linux")
+
+	// Long sections are split into sentences
+	chunks = MarkdownSplitter{MaxTokens: 8}.Split("# Tips\n\nDrink water often. Sleep eight hours.")
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "Sleep eight hours.", chunks[1].Text)
+	assert.Equal(t, "Tips", chunks[1].Metadata["heading"])
+}
+
+func TestPipeline(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "guides"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "guides", "drinks.md"),
+		[]byte("# Coffee\n\nBrew coffee every morning.\n\n# Tea\n\nSteep tea for three minutes.\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "units.md"),
+		[]byte("Always use metric units."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644))
+
+	store := NewInMemoryStore()
+	mem := New(store, wordEmbedder, WithMinScore(0.1))
+	var batches int
+	counting := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float32, error) {
+		batches++
+		return wordEmbedder(ctx, texts)
+	})
+	pipeline := Pipeline{
+		Loader:    FileLoader(filepath.Join(dir, "**", "*.md")),
+		Splitter:  MarkdownSplitter{},
+		Memory:    New(store, counting),
+		BatchSize: 2,
+	}
+	n, err := pipeline.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 3, store.Len())
+	assert.Equal(t, 2, batches)
+
+	matches, err := mem.Search(context.Background(), "tea", 1)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	drinks := filepath.Join(dir, "guides", "drinks.md")
+	assert.Equal(t, drinks+"#1", matches[0].ID)
+	assert.Equal(t, map[string]string{"source": drinks, "heading": "Tea", "chunk": "1"}, matches[0].Metadata)
+
+	// Running again replaces the chunks
+	n, err = pipeline.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 3, store.Len())
+
+	result, err := mem.SearchKnowledgeTool(2).Execute(context.Background(), []byte(`{"query":"tea"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "[1] "+drinks+" (Tea)\n# Tea\n\nSteep tea for three minutes.", result)
+
+	result, err = mem.SearchKnowledgeTool(2).Execute(context.Background(), []byte(`{"query":"metric"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "[1] "+filepath.Join(dir, "units.md")+"\nAlways use metric units.", result)
+
+	_, err = Pipeline{Memory: mem}.Run(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/i2y/bucephalus/llm"
+)
+
+// Loader loads the documents to ingest. Loaded documents need only an ID and
+// a text; embeddings are computed by the pipeline.
+type Loader interface {
+	Load(ctx context.Context) ([]Document, error)
+}
+
+// LoaderFunc adapts a function to the Loader interface.
+type LoaderFunc func(ctx context.Context) ([]Document, error)
+
+// Load implements Loader.
+func (f LoaderFunc) Load(ctx context.Context) ([]Document, error) {
+	return f(ctx)
+}
+
+// FileLoader returns a loader that reads the files matching the glob
+// patterns, which support ** for recursive matching. Each file becomes a
+// document whose ID and "source" metadata are the file's path.
+//
+// Example:
+//
+//	loader := memory.FileLoader("docs/**/*.md", "README.md")
+func FileLoader(patterns ...string) Loader {
+	return LoaderFunc(func(ctx context.Context) ([]Document, error) {
+		var docs []Document
+		seen := make(map[string]bool)
+		for _, pattern := range patterns {
+			paths, err := doublestar.FilepathGlob(pattern, doublestar.WithFilesOnly())
+			if err != nil {
+				return nil, fmt.Errorf("matching %q: %w", pattern, err)
+			}
+			for _, path := range paths {
+				if seen[path] {
+					continue
+				}
+				seen[path] = true
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return nil, fmt.Errorf("loading documents: %w", err)
+				}
+				docs = append(docs, Document{
+					ID:       path,
+					Text:     string(data),
+					Metadata: map[string]string{"source": path},
+				})
+			}
+		}
+		return docs, nil
+	})
+}
+
+// DefaultBatchSize is the default number of chunks a Pipeline embeds per
+// Embedder call.
+const DefaultBatchSize = 64
+
+// Pipeline ingests documents into a memory: it loads documents with Loader,
+// splits them into chunks with Splitter, and embeds and stores the chunks in
+// Memory in batches of BatchSize.
+//
+// Chunk IDs are the document ID followed by "#" and the chunk's index, so
+// running a pipeline again overwrites the chunks it stored before. A chunk's
+// metadata combines the document's metadata, the splitter's metadata, and
+// "source" (the document ID unless set by the loader) and "chunk" (the index).
+//
+// Example:
+//
+//	mem := memory.New(memory.NewInMemoryStore(), embedder)
+//	pipeline := memory.Pipeline{
+//	    Loader:   memory.FileLoader("docs/**/*.md"),
+//	    Splitter: memory.MarkdownSplitter{MaxTokens: 300},
+//	    Memory:   mem,
+//	}
+//	n, err := pipeline.Run(ctx)
+type Pipeline struct {
+	Loader    Loader
+	Splitter  Splitter // Default: SentenceSplitter with DefaultChunkTokens
+	Memory    *Memory
+	BatchSize int // Chunks per Embedder call (default: DefaultBatchSize)
+}
+
+// Run ingests the documents and returns the number of chunks stored.
+func (p Pipeline) Run(ctx context.Context) (int, error) {
+	if p.Loader == nil || p.Memory == nil {
+		return 0, errors.New("pipeline requires a loader and a memory")
+	}
+	splitter := p.Splitter
+	if splitter == nil {
+		splitter = SentenceSplitter{}
+	}
+	batchSize := p.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	docs, err := p.Loader.Load(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var chunks []Document
+	for _, doc := range docs {
+		if doc.ID == "" {
+			doc.ID = newID()
+		}
+		for i, chunk := range splitter.Split(doc.Text) {
+			metadata := maps.Clone(doc.Metadata)
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			maps.Copy(metadata, chunk.Metadata)
+			if metadata["source"] == "" {
+				metadata["source"] = doc.ID
+			}
+			metadata["chunk"] = strconv.Itoa(i)
+			chunks = append(chunks, Document{
+				ID:       doc.ID + "#" + strconv.Itoa(i),
+				Text:     chunk.Text,
+				Metadata: metadata,
+			})
+		}
+	}
+
+	stored := 0
+	for batch := range slices.Chunk(chunks, batchSize) {
+		if err := p.Memory.Add(ctx, batch...); err != nil {
+			return stored, err
+		}
+		stored += len(batch)
+	}
+	return stored, nil
+}
+
+// searchKnowledgeInput is the input of the search_knowledge tool.
+type searchKnowledgeInput struct {
+	Query string `json:"query" jsonschema:"required,description=What to look up in the knowledge base"`
+}
+
+// SearchKnowledgeTool returns the "search_knowledge" tool, which lets an agent
+// search the documents ingested into the memory. It returns up to k passages,
+// each labeled with its source and heading when known.
+//
+// Example:
+//
+//	resp, err := llm.Call(ctx, question,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithTools(mem.SearchKnowledgeTool(5)),
+//	)
+func (m *Memory) SearchKnowledgeTool(k int) llm.Tool {
+	return llm.MustNewTool("search_knowledge", "Search the knowledge base for passages relevant to a query.",
+		func(ctx context.Context, in searchKnowledgeInput) (string, error) {
+			matches, err := m.Search(ctx, in.Query, k)
+			if err != nil {
+				return "", err
+			}
+			if len(matches) == 0 {
+				return "No relevant passages found.", nil
+			}
+
+			var sb strings.Builder
+			for i, match := range matches {
+				if i > 0 {
+					sb.WriteString("\n\n")
+				}
+				fmt.Fprintf(&sb, "[%d]", i+1)
+				if source := match.Metadata["source"]; source != "" {
+					sb.WriteString(" " + source)
+				}
+				if heading := match.Metadata["heading"]; heading != "" {
+					sb.WriteString(" (" + heading + ")")
+				}
+				sb.WriteString("\n")
+				sb.WriteString(strings.TrimSpace(match.Text))
+			}
+			return sb.String(), nil
+		})
+}
@@ -0,0 +1,288 @@
+// Package memory provides semantic memory for RAG and long-term agent memory.
+//
+// A Memory embeds texts with an Embedder and keeps them in a VectorStore:
+// InMemoryStore for tests and small corpora, SQLiteVecStore for SQLite with
+// the sqlite-vec extension, or PgVectorStore for PostgreSQL with pgvector.
+// Recall formats the memories most relevant to a query as a system message
+// section, and Tools lets agents remember and recall facts themselves.
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// Embedder turns texts into embedding vectors, e.g. with a provider's
+// embeddings API. It returns one vector per text, in order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderFunc adapts a function to the Embedder interface.
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// Embed implements Embedder.
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return f(ctx, texts)
+}
+
+// Document is a text stored in a VectorStore.
+type Document struct {
+	ID        string            // Unique ID; generated by Memory.Add if empty
+	Text      string            // Text that was embedded
+	Metadata  map[string]string // Application data, e.g. the source file
+	Embedding []float32         // Embedding of Text
+}
+
+// Match is a document found by a query.
+type Match struct {
+	Document
+	Score float64 // Cosine similarity to the query, from -1 to 1 (higher is more similar)
+}
+
+// VectorStore stores documents and finds those nearest to an embedding.
+// Implementations must be safe for concurrent use.
+type VectorStore interface {
+	// Upsert adds documents, replacing documents with the same IDs.
+	Upsert(ctx context.Context, docs ...Document) error
+
+	// Query returns the k documents most similar to embedding, most similar first.
+	Query(ctx context.Context, embedding []float32, k int) ([]Match, error)
+
+	// Delete removes the documents with the given IDs. Missing IDs are ignored.
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// Memory embeds texts and stores them in a vector store for retrieval.
+type Memory struct {
+	store    VectorStore
+	embedder Embedder
+	minScore float64
+}
+
+// Option configures a Memory.
+type Option func(*Memory)
+
+// WithMinScore ignores matches with a score below min, so that Search and
+// Recall return only relevant memories (default: no minimum).
+func WithMinScore(min float64) Option {
+	return func(m *Memory) {
+		m.minScore = min
+	}
+}
+
+// New creates a memory that embeds texts with embedder and stores them in store.
+//
+// Example:
+//
+//	mem := memory.New(memory.NewInMemoryStore(), embedder, memory.WithMinScore(0.3))
+//	err := mem.Add(ctx, memory.Document{Text: "The user prefers metric units."})
+func New(store VectorStore, embedder Embedder, opts ...Option) *Memory {
+	m := &Memory{store: store, embedder: embedder, minScore: math.Inf(-1)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Store returns the memory's vector store.
+func (m *Memory) Store() VectorStore {
+	return m.store
+}
+
+// Add embeds the documents without an embedding and stores all documents.
+// Documents without an ID get a random one.
+func (m *Memory) Add(ctx context.Context, docs ...Document) error {
+	docs = append([]Document(nil), docs...)
+	var texts []string
+	var missing []int
+	for i := range docs {
+		if docs[i].ID == "" {
+			docs[i].ID = newID()
+		}
+		if docs[i].Embedding == nil {
+			texts = append(texts, docs[i].Text)
+			missing = append(missing, i)
+		}
+	}
+
+	if len(texts) > 0 {
+		embeddings, err := m.embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		for j, i := range missing {
+			docs[i].Embedding = embeddings[j]
+		}
+	}
+
+	if err := m.store.Upsert(ctx, docs...); err != nil {
+		return fmt.Errorf("storing memories: %w", err)
+	}
+	return nil
+}
+
+// Search returns the k memories most relevant to query, most relevant first.
+func (m *Memory) Search(ctx context.Context, query string, k int) ([]Match, error) {
+	embeddings, err := m.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	matches, err := m.store.Query(ctx, embeddings[0], k)
+	if err != nil {
+		return nil, fmt.Errorf("searching memories: %w", err)
+	}
+
+	relevant := matches[:0]
+	for _, match := range matches {
+		if match.Score >= m.minScore {
+			relevant = append(relevant, match)
+		}
+	}
+	return relevant, nil
+}
+
+// Delete removes the memories with the given IDs.
+func (m *Memory) Delete(ctx context.Context, ids ...string) error {
+	if err := m.store.Delete(ctx, ids...); err != nil {
+		return fmt.Errorf("deleting memories: %w", err)
+	}
+	return nil
+}
+
+// Recall returns the k memories most relevant to query formatted as a
+// system message section, or "" if there are none. Pass it to
+// llm.WithSystemMessage or plugin.WithRunSystemMessage to ground a call or
+// agent run in the memories.
+//
+// Example:
+//
+//	memories, err := mem.Recall(ctx, question, 5)
+//	if err != nil {
+//	    return err
+//	}
+//	resp, err := llm.Call(ctx, question,
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	    llm.WithSystemMessage("You are a helpful assistant.\n\n"+memories),
+//	)
+func (m *Memory) Recall(ctx context.Context, query string, k int) (string, error) {
+	matches, err := m.Search(ctx, query, k)
+	if err != nil || len(matches) == 0 {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Relevant memories:\n")
+	for _, match := range matches {
+		sb.WriteString("- ")
+		sb.WriteString(strings.ReplaceAll(strings.TrimSpace(match.Text), "\n", "\n  "))
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// WithMemories returns an option that sets the system message to system
+// followed by the k memories most relevant to query.
+//
+// Example:
+//
+//	opt, err := mem.WithMemories(ctx, question, 5, "You are a helpful assistant.")
+//	if err != nil {
+//	    return err
+//	}
+//	resp, err := llm.Call(ctx, question, opt, llm.WithProvider("openai"), llm.WithModel("gpt-4o"))
+func (m *Memory) WithMemories(ctx context.Context, query string, k int, system string) (llm.Option, error) {
+	memories, err := m.Recall(ctx, query, k)
+	if err != nil {
+		return nil, err
+	}
+	if memories != "" {
+		if system != "" {
+			system += "\n\n"
+		}
+		system += memories
+	}
+	return llm.WithSystemMessage(system), nil
+}
+
+// rememberInput is the input of the remember tool.
+type rememberInput struct {
+	Text string `json:"text" jsonschema:"required,description=Fact to remember phrased so that it is understandable on its own"`
+}
+
+// recallInput is the input of the recall tool.
+type recallInput struct {
+	Query string `json:"query" jsonschema:"required,description=What to recall"`
+}
+
+// Tools returns the "remember" and "recall" tools, which let an agent store
+// facts in the memory and retrieve them in later conversations. recall
+// returns up to k memories.
+func (m *Memory) Tools(k int) []llm.Tool {
+	remember := llm.MustNewTool("remember", "Store a fact in long-term memory for later conversations.",
+		func(ctx context.Context, in rememberInput) (string, error) {
+			if err := m.Add(ctx, Document{Text: in.Text}); err != nil {
+				return "", err
+			}
+			return "Remembered.", nil
+		})
+	recall := llm.MustNewTool("recall", "Search long-term memory for facts relevant to a query.",
+		func(ctx context.Context, in recallInput) (string, error) {
+			memories, err := m.Recall(ctx, in.Query, k)
+			if err != nil {
+				return "", err
+			}
+			if memories == "" {
+				return "No relevant memories.", nil
+			}
+			return memories, nil
+		})
+	return []llm.Tool{remember, recall}
+}
+
+// embed embeds texts and checks that one embedding is returned per text.
+func (m *Memory) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := m.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embedding: %w", err)
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding: got %d embeddings for %d texts", len(embeddings), len(texts))
+	}
+	return embeddings, nil
+}
+
+// newID returns a random document ID.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errDimensions is returned when vectors of different lengths are compared.
+var errDimensions = errors.New("embedding dimensions do not match")
+
+// cosine returns the cosine similarity of a and b.
+func cosine(a, b []float32) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("%w: %d and %d", errDimensions, len(a), len(b))
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0, nil
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb)), nil
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordEmbedder embeds texts as counts of a fixed vocabulary.
+var wordEmbedder = EmbedderFunc(func(ctx context.Context, texts []string) ([][]float32, error) {
+	vocabulary := []string{"metric", "units", "coffee", "tea", "morning"}
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = make([]float32, len(vocabulary))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for j, v := range vocabulary {
+				if strings.Trim(word, ".?") == v {
+					embeddings[i][j]++
+				}
+			}
+		}
+	}
+	return embeddings, nil
+})
+
+func TestMemory_Search(t *testing.T) {
+	store := NewInMemoryStore()
+	mem := New(store, wordEmbedder)
+	ctx := context.Background()
+
+	require.NoError(t, mem.Add(ctx,
+		Document{ID: "units", Text: "The user prefers metric units."},
+		Document{ID: "coffee", Text: "The user drinks coffee every morning.", Metadata: map[string]string{"source": "chat"}},
+		Document{Text: "The user dislikes tea."},
+	))
+	assert.Equal(t, 3, store.Len())
+
+	matches, err := mem.Search(ctx, "coffee in the morning?", 2)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "coffee", matches[0].ID)
+	assert.Equal(t, map[string]string{"source": "chat"}, matches[0].Metadata)
+	assert.InDelta(t, 1.0, matches[0].Score, 1e-9)
+	assert.Greater(t, matches[0].Score, matches[1].Score)
+
+	// Unrelated memories are dropped with a minimum score
+	mem = New(store, wordEmbedder, WithMinScore(0.5))
+	matches, err = mem.Search(ctx, "coffee", 3)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	require.NoError(t, mem.Delete(ctx, "coffee"))
+	matches, err = mem.Search(ctx, "coffee", 3)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestMemory_Recall(t *testing.T) {
+	mem := New(NewInMemoryStore(), wordEmbedder, WithMinScore(0.1))
+	ctx := context.Background()
+
+	recalled, err := mem.Recall(ctx, "units", 3)
+	require.NoError(t, err)
+	assert.Empty(t, recalled)
+
+	require.NoError(t, mem.Add(ctx, Document{Text: "The user prefers metric units.\nAlways convert."}))
+	recalled, err = mem.Recall(ctx, "Which units?", 3)
+	require.NoError(t, err)
+	assert.Equal(t, "Relevant memories:\n- The user prefers metric units.\n  Always convert.", recalled)
+
+	opt, err := mem.WithMemories(ctx, "Which units?", 3, "You are helpful.")
+	require.NoError(t, err)
+	assert.NotNil(t, opt)
+}
+
+func TestMemory_Tools(t *testing.T) {
+	mem := New(NewInMemoryStore(), wordEmbedder, WithMinScore(0.1))
+	tools := mem.Tools(3)
+	require.Len(t, tools, 2)
+	ctx := context.Background()
+
+	result, err := tools[1].Execute(ctx, []byte(`{"query":"tea"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "No relevant memories.", result)
+
+	_, err = tools[0].Execute(ctx, []byte(`{"text":"The user drinks tea."}`))
+	require.NoError(t, err)
+	result, err = tools[1].Execute(ctx, []byte(`{"query":"tea"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Relevant memories:\n- The user drinks tea.", result)
+}
+
+func TestMemory_EmbedErrors(t *testing.T) {
+	ctx := context.Background()
+	failing := New(NewInMemoryStore(), EmbedderFunc(func(ctx context.Context, texts []string) ([][]float32, error) {
+		return nil, errors.New("quota exceeded")
+	}))
+	assert.ErrorContains(t, failing.Add(ctx, Document{Text: "x"}), "embedding: quota exceeded")
+
+	short := New(NewInMemoryStore(), EmbedderFunc(func(ctx context.Context, texts []string) ([][]float32, error) {
+		return [][]float32{{1}}, nil
+	}))
+	assert.ErrorContains(t, short.Add(ctx, Document{Text: "a"}, Document{Text: "b"}), "got 1 embeddings for 2 texts")
+
+	mem := New(NewInMemoryStore(), wordEmbedder)
+	require.NoError(t, mem.Add(ctx, Document{Text: "old", Embedding: []float32{1, 2}}))
+	_, err := mem.Search(ctx, "tea", 1)
+	assert.ErrorIs(t, err, errDimensions)
+}
+
+func TestVectorLiteral(t *testing.T) {
+	v := []float32{0.25, -1, 3e-7}
+	assert.Equal(t, "[0.25,-1,3e-07]", vectorLiteral(v))
+	parsed, err := parseVector(vectorLiteral(v))
+	require.NoError(t, err)
+	assert.Equal(t, v, parsed)
+}
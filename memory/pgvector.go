@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// PgVectorStore is a VectorStore backed by a PostgreSQL table with a column
+// of the pgvector extension's vector type (https://github.com/pgvector/pgvector).
+// It uses database/sql, so any PostgreSQL driver can be used to open the
+// database.
+type PgVectorStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPgVectorStore creates a store backed by db, creating the vector
+// extension, the table for embeddings of the given dimensions, and an HNSW
+// index for cosine distance if needed. If table is empty, "memories" is used;
+// it may be schema-qualified, e.g. "app.memories".
+//
+// Example:
+//
+//	db, _ := sql.Open("pgx", os.Getenv("DATABASE_URL")) // with a PostgreSQL driver imported
+//	store, err := memory.NewPgVectorStore(ctx, db, "", 1536)
+func NewPgVectorStore(ctx context.Context, db *sql.DB, table string, dimensions int) (*PgVectorStore, error) {
+	if table == "" {
+		table = "memories"
+	}
+
+	queries := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	text TEXT NOT NULL,
+	metadata JSONB NOT NULL,
+	embedding vector(%d) NOT NULL
+)`, table, dimensions),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING hnsw (embedding vector_cosine_ops)`, indexName(table), table),
+	}
+	for _, query := range queries {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return nil, fmt.Errorf("creating memory table: %w", err)
+		}
+	}
+	return &PgVectorStore{db: db, table: table}, nil
+}
+
+// indexName returns the name of the embedding index of table. Indexes are
+// created in the schema of their table, so the name is not schema-qualified.
+func indexName(table string) string {
+	name := table[strings.LastIndex(table, ".")+1:]
+	if quoted, ok := strings.CutSuffix(name, `"`); ok && strings.HasPrefix(quoted, `"`) {
+		return quoted + `_embedding_idx"`
+	}
+	return name + "_embedding_idx"
+}
+
+// Upsert implements VectorStore.
+func (s *PgVectorStore) Upsert(ctx context.Context, docs ...Document) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("storing documents: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, text, metadata, embedding) VALUES ($1, $2, $3::jsonb, $4::vector)
+ON CONFLICT (id) DO UPDATE SET text = excluded.text, metadata = excluded.metadata, embedding = excluded.embedding`, s.table)
+	for _, doc := range docs {
+		metadata, err := marshalMetadata(doc.Metadata)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, doc.ID, doc.Text, metadata, vectorLiteral(doc.Embedding)); err != nil {
+			return fmt.Errorf("storing document %q: %w", doc.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storing documents: %w", err)
+	}
+	return nil
+}
+
+// Query implements VectorStore.
+func (s *PgVectorStore) Query(ctx context.Context, embedding []float32, k int) ([]Match, error) {
+	query := fmt.Sprintf(`SELECT id, text, metadata::text, embedding::text, embedding <=> $1::vector AS distance
+FROM %s ORDER BY distance LIMIT $2`, s.table)
+	rows, err := s.db.QueryContext(ctx, query, vectorLiteral(embedding), k)
+	if err != nil {
+		return nil, fmt.Errorf("querying documents: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var match Match
+		var metadata, vector string
+		var distance float64
+		if err := rows.Scan(&match.ID, &match.Text, &metadata, &vector, &distance); err != nil {
+			return nil, fmt.Errorf("querying documents: %w", err)
+		}
+		if match.Metadata, err = unmarshalMetadata(metadata); err != nil {
+			return nil, fmt.Errorf("document %q: %w", match.ID, err)
+		}
+		if match.Embedding, err = parseVector(vector); err != nil {
+			return nil, fmt.Errorf("document %q: %w", match.ID, err)
+		}
+		match.Score = 1 - distance // Cosine distance
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying documents: %w", err)
+	}
+	return matches, nil
+}
+
+// Delete implements VectorStore.
+func (s *PgVectorStore) Delete(ctx context.Context, ids ...string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, s.table)
+	for _, id := range ids {
+		if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("deleting document %q: %w", id, err)
+		}
+	}
+	return nil
+}
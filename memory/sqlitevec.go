@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SQLiteVecStore is a VectorStore backed by a vec0 virtual table of the
+// sqlite-vec extension (https://github.com/asg017/sqlite-vec), version 0.1.6
+// or later. It uses database/sql, so any SQLite driver with the extension
+// loaded can be used to open the database.
+type SQLiteVecStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLiteVecStore creates a store backed by db, creating the vec0 table if
+// needed for embeddings of the given dimensions. If table is empty,
+// "memories" is used.
+//
+// Example:
+//
+//	db, _ := sql.Open("sqlite3", "memory.db") // with sqlite-vec loaded
+//	store, err := memory.NewSQLiteVecStore(ctx, db, "", 1536)
+func NewSQLiteVecStore(ctx context.Context, db *sql.DB, table string, dimensions int) (*SQLiteVecStore, error) {
+	if table == "" {
+		table = "memories"
+	}
+
+	query := fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING vec0(
+	id TEXT PRIMARY KEY,
+	embedding float[%d] distance_metric=cosine,
+	+text TEXT,
+	+metadata TEXT
+)`, table, dimensions)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("creating memory table: %w", err)
+	}
+	return &SQLiteVecStore{db: db, table: table}, nil
+}
+
+// Upsert implements VectorStore.
+// vec0 tables do not support ON CONFLICT, so existing documents are deleted
+// and inserted again in a single transaction.
+func (s *SQLiteVecStore) Upsert(ctx context.Context, docs ...Document) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("storing documents: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	del := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.table)
+	ins := fmt.Sprintf(`INSERT INTO %s (id, embedding, text, metadata) VALUES (?, ?, ?, ?)`, s.table)
+	for _, doc := range docs {
+		metadata, err := marshalMetadata(doc.Metadata)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, del, doc.ID); err != nil {
+			return fmt.Errorf("storing document %q: %w", doc.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, ins, doc.ID, vectorLiteral(doc.Embedding), doc.Text, metadata); err != nil {
+			return fmt.Errorf("storing document %q: %w", doc.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("storing documents: %w", err)
+	}
+	return nil
+}
+
+// Query implements VectorStore.
+func (s *SQLiteVecStore) Query(ctx context.Context, embedding []float32, k int) ([]Match, error) {
+	query := fmt.Sprintf(`SELECT id, text, metadata, vec_to_json(embedding), distance FROM %s
+WHERE embedding MATCH ? AND k = ?
+ORDER BY distance`, s.table)
+	rows, err := s.db.QueryContext(ctx, query, vectorLiteral(embedding), k)
+	if err != nil {
+		return nil, fmt.Errorf("querying documents: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var match Match
+		var metadata, vector string
+		var distance float64
+		if err := rows.Scan(&match.ID, &match.Text, &metadata, &vector, &distance); err != nil {
+			return nil, fmt.Errorf("querying documents: %w", err)
+		}
+		if match.Metadata, err = unmarshalMetadata(metadata); err != nil {
+			return nil, fmt.Errorf("document %q: %w", match.ID, err)
+		}
+		if match.Embedding, err = parseVector(vector); err != nil {
+			return nil, fmt.Errorf("document %q: %w", match.ID, err)
+		}
+		match.Score = 1 - distance // Cosine distance
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying documents: %w", err)
+	}
+	return matches, nil
+}
+
+// Delete implements VectorStore.
+func (s *SQLiteVecStore) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id IN (%s)`, s.table, placeholders(len(ids)))
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("deleting documents: %w", err)
+	}
+	return nil
+}
+
+// placeholders returns n comma-separated "?" placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
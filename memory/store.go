@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// InMemoryStore is a VectorStore that keeps documents in memory and finds
+// matches by exhaustive search. It suits tests and corpora of up to tens of
+// thousands of documents.
+type InMemoryStore struct {
+	mu   sync.RWMutex
+	docs map[string]Document
+}
+
+// NewInMemoryStore creates an empty in-memory store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{docs: make(map[string]Document)}
+}
+
+// Upsert implements VectorStore.
+func (s *InMemoryStore) Upsert(ctx context.Context, docs ...Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, doc := range docs {
+		doc.Metadata = maps.Clone(doc.Metadata)
+		doc.Embedding = slices.Clone(doc.Embedding)
+		s.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// Query implements VectorStore.
+func (s *InMemoryStore) Query(ctx context.Context, embedding []float32, k int) ([]Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make([]Match, 0, len(s.docs))
+	for _, doc := range s.docs {
+		score, err := cosine(embedding, doc.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("document %q: %w", doc.ID, err)
+		}
+		matches = append(matches, Match{Document: doc, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if k >= 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// Delete implements VectorStore.
+func (s *InMemoryStore) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.docs, id)
+	}
+	return nil
+}
+
+// Len returns the number of stored documents.
+func (s *InMemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
+
+// vectorLiteral formats v as "[x,y,...]", the text form of vectors accepted
+// by sqlite-vec and pgvector.
+func vectorLiteral(v []float32) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// parseVector parses the text form of a vector.
+func parseVector(s string) ([]float32, error) {
+	var v []float32
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("parsing vector: %w", err)
+	}
+	return v, nil
+}
+
+// marshalMetadata returns the JSON form of metadata.
+func marshalMetadata(metadata map[string]string) (string, error) {
+	if metadata == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("marshaling metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalMetadata parses the JSON form of metadata.
+func unmarshalMetadata(data string) (map[string]string, error) {
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(data), &metadata); err != nil {
+		return nil, fmt.Errorf("parsing metadata: %w", err)
+	}
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	return metadata, nil
+}
@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVectorDB is a database/sql connector for an in-memory table of
+// documents. It understands the statements of SQLiteVecStore and
+// PgVectorStore: inserts, deletes by ID, and nearest-neighbor selects by
+// cosine distance. Other statements, such as CREATE, are only recorded.
+type fakeVectorDB struct {
+	mu         sync.Mutex
+	rows       map[string]fakeRow
+	statements []string // Statements other than inserts, deletes, and selects
+}
+
+// fakeRow is a stored document, with its columns as the stores write them.
+type fakeRow struct {
+	text, metadata, embedding string
+}
+
+func newFakeVectorDB(t *testing.T) (*fakeVectorDB, *sql.DB) {
+	t.Helper()
+	fake := &fakeVectorDB{rows: map[string]fakeRow{}}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return fake, db
+}
+
+// Connect implements driver.Connector.
+func (f *fakeVectorDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+// Driver implements driver.Connector.
+func (f *fakeVectorDB) Driver() driver.Driver {
+	return nil
+}
+
+var insertColumns = regexp.MustCompile(`^INSERT INTO \S+ \(([^)]*)\)`)
+
+func (f *fakeVectorDB) exec(query string, args []driver.NamedValue) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		m := insertColumns.FindStringSubmatch(query)
+		if m == nil {
+			return fmt.Errorf("unexpected insert: %s", query)
+		}
+		values := map[string]string{}
+		for i, column := range strings.Split(m[1], ",") {
+			values[strings.TrimSpace(column)] = args[i].Value.(string)
+		}
+		id := values["id"]
+		if _, ok := f.rows[id]; ok && !strings.Contains(query, "ON CONFLICT") {
+			return fmt.Errorf("UNIQUE constraint failed: %s", id)
+		}
+		f.rows[id] = fakeRow{text: values["text"], metadata: values["metadata"], embedding: values["embedding"]}
+	case strings.HasPrefix(query, "DELETE"):
+		for _, arg := range args {
+			delete(f.rows, arg.Value.(string))
+		}
+	default:
+		f.statements = append(f.statements, query)
+	}
+	return nil
+}
+
+// query returns the k rows nearest to the embedding in args, with their
+// cosine distance, ordered by distance.
+func (f *fakeVectorDB) query(args []driver.NamedValue) (driver.Rows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target, err := parseVector(args[0].Value.(string))
+	if err != nil {
+		return nil, err
+	}
+	rows := &fakeRows{}
+	for id, row := range f.rows {
+		embedding, err := parseVector(row.embedding)
+		if err != nil {
+			return nil, err
+		}
+		similarity, err := cosine(target, embedding)
+		if err != nil {
+			return nil, err
+		}
+		rows.values = append(rows.values, []driver.Value{id, row.text, row.metadata, row.embedding, 1 - similarity})
+	}
+	sort.Slice(rows.values, func(i, j int) bool {
+		return rows.values[i][4].(float64) < rows.values[j][4].(float64)
+	})
+	if k := int(args[1].Value.(int64)); len(rows.values) > k {
+		rows.values = rows.values[:k]
+	}
+	return rows, nil
+}
+
+// fakeConn is a connection to a fakeVectorDB. Transactions restore the
+// rows of the database when they are rolled back.
+type fakeConn struct {
+	db *fakeVectorDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return &fakeTx{db: c.db, rows: maps.Clone(c.db.rows)}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.db.exec(strings.TrimSpace(query), args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(args)
+}
+
+type fakeTx struct {
+	db   *fakeVectorDB
+	rows map[string]fakeRow // Rows at the start of the transaction
+}
+
+func (tx *fakeTx) Commit() error {
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rows = tx.rows
+	return nil
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "text", "metadata", "embedding", "distance"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSQLiteVecStore(t *testing.T) {
+	fake, db := newFakeVectorDB(t)
+	store, err := NewSQLiteVecStore(context.Background(), db, "", 3)
+	require.NoError(t, err)
+	require.Len(t, fake.statements, 1)
+	assert.Contains(t, fake.statements[0], "CREATE VIRTUAL TABLE IF NOT EXISTS memories USING vec0(")
+	assert.Contains(t, fake.statements[0], "embedding float[3] distance_metric=cosine")
+
+	testVectorStore(t, store)
+}
+
+func TestPgVectorStore(t *testing.T) {
+	fake, db := newFakeVectorDB(t)
+	store, err := NewPgVectorStore(context.Background(), db, "app.memories", 3)
+	require.NoError(t, err)
+	require.Len(t, fake.statements, 3)
+	assert.Contains(t, fake.statements[1], "CREATE TABLE IF NOT EXISTS app.memories (")
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS memories_embedding_idx ON app.memories USING hnsw (embedding vector_cosine_ops)", fake.statements[2])
+
+	testVectorStore(t, store)
+}
+
+func TestIndexName(t *testing.T) {
+	assert.Equal(t, "memories_embedding_idx", indexName("memories"))
+	assert.Equal(t, "docs_embedding_idx", indexName("app.docs"))
+	assert.Equal(t, `"Docs_embedding_idx"`, indexName(`"App"."Docs"`))
+}
+
+// testVectorStore tests the behavior shared by all VectorStores.
+func testVectorStore(t *testing.T, store VectorStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	require.NoError(t, store.Upsert(ctx,
+		Document{ID: "x", Text: "along x", Embedding: []float32{1, 0, 0}, Metadata: map[string]string{"axis": "x"}},
+		Document{ID: "y", Text: "along y", Embedding: []float32{0, 1, 0}},
+		Document{ID: "xy", Text: "between x and y", Embedding: []float32{1, 1, 0}},
+	))
+
+	// Matches are ordered by cosine similarity
+	matches, err := store.Query(ctx, []float32{1, 0, 0}, 3)
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	assert.Equal(t, []string{"x", "xy", "y"}, []string{matches[0].ID, matches[1].ID, matches[2].ID})
+	assert.InDelta(t, 1.0, matches[0].Score, 1e-6)
+	assert.InDelta(t, 0.7071, matches[1].Score, 1e-4)
+	assert.InDelta(t, 0.0, matches[2].Score, 1e-6)
+	assert.Equal(t, "along x", matches[0].Text)
+	assert.Equal(t, map[string]string{"axis": "x"}, matches[0].Metadata)
+	assert.Equal(t, []float32{1, 0, 0}, matches[0].Embedding)
+	assert.Nil(t, matches[2].Metadata)
+
+	matches, err = store.Query(ctx, []float32{1, 0, 0}, 1)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	// Upserting an existing ID replaces the document
+	require.NoError(t, store.Upsert(ctx, Document{ID: "x", Text: "along z", Embedding: []float32{0, 0, 1}}))
+	matches, err = store.Query(ctx, []float32{0, 0, 1}, 10)
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	assert.Equal(t, "x", matches[0].ID)
+	assert.Equal(t, "along z", matches[0].Text)
+	assert.Nil(t, matches[0].Metadata)
+
+	require.NoError(t, store.Delete(ctx, "x", "y"))
+	require.NoError(t, store.Delete(ctx))
+	matches, err = store.Query(ctx, []float32{1, 0, 0}, 10)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "xy", matches[0].ID)
+}
@@ -0,0 +1,193 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// Default audio models and voice.
+const (
+	DefaultTranscriptionModel = "gpt-4o-mini-transcribe"
+	DefaultSpeechModel        = "gpt-4o-mini-tts"
+	DefaultVoice              = "alloy"
+)
+
+// transcriptionResponse is the JSON response of the transcriptions endpoint.
+type transcriptionResponse struct {
+	Text  string `json:"text"`
+	Usage *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage,omitempty"`
+}
+
+// speechRequest is the request of the speech endpoint.
+type speechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format,omitempty"`
+	Speed          float64 `json:"speed,omitempty"`
+	Instructions   string  `json:"instructions,omitempty"`
+}
+
+// audioExtensions maps audio MIME types to the file extensions the
+// transcriptions endpoint uses to detect the format.
+var audioExtensions = map[string]string{
+	"audio/mpeg":   "mp3",
+	"audio/mp3":    "mp3",
+	"audio/mp4":    "m4a",
+	"audio/m4a":    "m4a",
+	"audio/x-m4a":  "m4a",
+	"audio/wav":    "wav",
+	"audio/wave":   "wav",
+	"audio/x-wav":  "wav",
+	"audio/webm":   "webm",
+	"audio/ogg":    "ogg",
+	"audio/flac":   "flac",
+	"audio/x-flac": "flac",
+	"video/mp4":    "mp4",
+	"video/webm":   "webm",
+}
+
+// speechMIMETypes maps the formats of the speech endpoint to MIME types.
+var speechMIMETypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/ogg",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+	"pcm":  "audio/L16;rate=24000",
+}
+
+// Transcribe implements provider.Transcriber with the transcriptions
+// endpoint (Whisper and the GPT-4o transcribe models).
+func (p *Provider) Transcribe(ctx context.Context, req *provider.TranscriptionRequest) (*provider.Transcription, error) {
+	model := req.Model
+	if model == "" {
+		model = DefaultTranscriptionModel
+	}
+	mimeType, _, _ := strings.Cut(req.MIMEType, ";")
+	ext, ok := audioExtensions[strings.TrimSpace(mimeType)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported audio type %q", req.MIMEType)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{{"model", model}, {"response_format", "json"}, {"language", req.Language}, {"prompt", req.Prompt}}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("writing form: %w", err)
+		}
+	}
+	file, err := form.CreateFormFile("file", "audio."+ext)
+	if err != nil {
+		return nil, fmt.Errorf("writing form: %w", err)
+	}
+	if _, err := file.Write(req.Audio); err != nil {
+		return nil, fmt.Errorf("writing form: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("writing form: %w", err)
+	}
+
+	raw, err := p.client.post(ctx, "/audio/transcriptions", form.FormDataContentType(), &body, req.Headers)
+	if err != nil {
+		return nil, err
+	}
+	var resp transcriptionResponse
+	if err := json.Unmarshal(raw.Body, &resp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	out := &provider.Transcription{Text: resp.Text, HTTP: raw}
+	if resp.Usage != nil {
+		out.Usage = provider.Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		}
+	}
+	return out, nil
+}
+
+// Speak implements provider.SpeechSynthesizer with the speech endpoint.
+// Formats are "mp3" (default), "opus", "aac", "flac", "wav", and "pcm".
+func (p *Provider) Speak(ctx context.Context, req *provider.SpeechRequest) (*provider.Speech, error) {
+	apiReq := &speechRequest{
+		Model:          req.Model,
+		Input:          req.Text,
+		Voice:          req.Voice,
+		ResponseFormat: req.Format,
+		Speed:          req.Speed,
+		Instructions:   req.Instructions,
+	}
+	if apiReq.Model == "" {
+		apiReq.Model = DefaultSpeechModel
+	}
+	if apiReq.Voice == "" {
+		apiReq.Voice = DefaultVoice
+	}
+	if apiReq.ResponseFormat == "" {
+		apiReq.ResponseFormat = "mp3"
+	}
+	mimeType, ok := speechMIMETypes[apiReq.ResponseFormat]
+	if !ok {
+		return nil, fmt.Errorf("unsupported speech format %q", req.Format)
+	}
+
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+	raw, err := p.client.post(ctx, "/audio/speech", "application/json", bytes.NewReader(body), req.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	audio := raw.Body
+	raw.Body = nil // Keep the raw response small; the audio is in Speech.Audio
+	return &provider.Speech{Audio: audio, MIMEType: mimeType, HTTP: raw}, nil
+}
+
+// post sends a POST request to path with the additional headers and returns
+// the response, or an *APIError if its status is not 200.
+func (c *client) post(ctx context.Context, path, contentType string, body io.Reader, headers http.Header) (*provider.HTTPResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	setHeaders(httpReq, headers)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	respBody, err := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	raw := &provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, c.parseError(raw)
+	}
+	return raw, nil
+}
@@ -1,7 +1,6 @@
 package openai
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,86 +8,106 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/i2y/bucephalus/provider"
+	"github.com/i2y/bucephalus/provider/sse"
 )
 
 const defaultBaseURL = "https://api.openai.com/v1"
 
 // client wraps the HTTP client for OpenAI API calls.
 type client struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey           string
+	baseURL          string
+	httpClient       *http.Client
+	maxResponseBytes int64
 }
 
 // newClient creates a new OpenAI client.
-func newClient(apiKey, baseURL string, httpClient *http.Client) *client {
+func newClient(apiKey, baseURL string, httpClient *http.Client, maxResponseBytes int64) *client {
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
+	if maxResponseBytes == 0 {
+		maxResponseBytes = provider.DefaultMaxResponseBytes
+	}
 	return &client{
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		httpClient: httpClient,
+		apiKey:           apiKey,
+		baseURL:          baseURL,
+		httpClient:       httpClient,
+		maxResponseBytes: maxResponseBytes,
 	}
 }
 
-// chatCompletion sends a chat completion request.
-func (c *client) chatCompletion(ctx context.Context, req *chatCompletionRequest) (*chatCompletionResponse, error) {
+// chatCompletion sends a chat completion request with the additional headers.
+func (c *client) chatCompletion(ctx context.Context, req *chatCompletionRequest, headers http.Header) (*chatCompletionResponse, *provider.HTTPResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return nil, nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST",
 		c.baseURL+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	setHeaders(httpReq, headers)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
+		return nil, nil, fmt.Errorf("sending request: %w", err)
 	}
 	defer func() { _ = httpResp.Body.Close() }()
 
-	respBody, err := io.ReadAll(httpResp.Body)
+	respBody, err := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, nil, fmt.Errorf("reading response: %w", err)
 	}
 
+	raw := &provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody}
 	if httpResp.StatusCode != http.StatusOK {
-		return nil, c.parseError(httpResp.StatusCode, respBody)
+		return nil, raw, c.parseError(raw)
 	}
 
 	var resp chatCompletionResponse
 	if err := json.Unmarshal(respBody, &resp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+		return nil, raw, fmt.Errorf("parsing response: %w", err)
 	}
 
-	return &resp, nil
+	return &resp, raw, nil
+}
+
+// setHeaders sets the additional headers on the request, replacing the
+// default ones of the same name.
+func setHeaders(httpReq *http.Request, headers http.Header) {
+	for key, values := range headers {
+		httpReq.Header[http.CanonicalHeaderKey(key)] = values
+	}
 }
 
 // parseError parses an error response from the API.
-func (c *client) parseError(statusCode int, body []byte) error {
+func (c *client) parseError(raw *provider.HTTPResponse) error {
 	var errResp errorResponse
-	if err := json.Unmarshal(body, &errResp); err != nil {
+	if err := json.Unmarshal(raw.Body, &errResp); err != nil {
 		return &APIError{
-			StatusCode: statusCode,
-			Message:    string(body),
+			StatusCode: raw.StatusCode,
+			Message:    string(raw.Body),
+			Raw:        raw,
 		}
 	}
 
 	return &APIError{
-		StatusCode: statusCode,
+		StatusCode: raw.StatusCode,
 		Message:    errResp.Error.Message,
 		Type:       errResp.Error.Type,
 		Code:       errResp.Error.Code,
+		Raw:        raw,
 	}
 }
 
@@ -98,6 +117,17 @@ type APIError struct {
 	Message    string
 	Type       string
 	Code       string
+	Raw        *provider.HTTPResponse // The HTTP response, with its headers and body
+}
+
+// HTTPStatus returns the HTTP status code of the error.
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// RawResponse returns the HTTP response of the error.
+func (e *APIError) RawResponse() *provider.HTTPResponse {
+	return e.Raw
 }
 
 func (e *APIError) Error() string {
@@ -108,7 +138,7 @@ func (e *APIError) Error() string {
 }
 
 // chatCompletionStream sends a streaming chat completion request.
-func (c *client) chatCompletionStream(ctx context.Context, req *chatCompletionRequest) (*streamReader, error) {
+func (c *client) chatCompletionStream(ctx context.Context, req *chatCompletionRequest, headers http.Header) (*streamReader, error) {
 	// Create a copy with stream enabled
 	streamReq := *req
 	streamReq.Stream = true
@@ -126,6 +156,7 @@ func (c *client) chatCompletionStream(ctx context.Context, req *chatCompletionRe
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	setHeaders(httpReq, headers)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -134,43 +165,37 @@ func (c *client) chatCompletionStream(ctx context.Context, req *chatCompletionRe
 
 	if httpResp.StatusCode != http.StatusOK {
 		defer func() { _ = httpResp.Body.Close() }()
-		respBody, _ := io.ReadAll(httpResp.Body)
-		return nil, c.parseError(httpResp.StatusCode, respBody)
+		respBody, _ := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
+		return nil, c.parseError(&provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody})
 	}
 
 	return &streamReader{
-		reader: bufio.NewReader(httpResp.Body),
+		events: sse.NewReader(httpResp.Body),
 		closer: httpResp.Body,
+		raw:    &provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header},
 	}, nil
 }
 
 // streamReader reads SSE events from an OpenAI stream.
 type streamReader struct {
-	reader *bufio.Reader
+	events *sse.Reader
 	closer io.Closer
+	raw    *provider.HTTPResponse
 }
 
 // ReadChunk reads the next chunk from the stream.
 // Returns nil, io.EOF when the stream is done.
 func (s *streamReader) ReadChunk() (*streamChunk, error) {
 	for {
-		line, err := s.reader.ReadString('\n')
+		event, err := s.events.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		if !strings.HasPrefix(line, "data:") {
+		data := strings.TrimSpace(event.Data)
+		if data == "" {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data:")
-		data = strings.TrimSpace(data)
-
 		if data == "[DONE]" {
 			return nil, io.EOF
 		}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/i2y/bucephalus/provider"
+)
+
+// modelsResponse is the response of the models endpoint.
+type modelsResponse struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+	} `json:"data"`
+}
+
+// ListModels implements provider.ModelLister. It also lists the models of
+// OpenAI-compatible servers, such as Ollama's (WithBaseURL("http://localhost:11434/v1")).
+func (p *Provider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	resp, err := p.client.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]provider.ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		info := provider.ModelInfo{ID: m.ID}
+		if m.Created != 0 {
+			info.Created = time.Unix(m.Created, 0)
+		}
+		models = append(models, info)
+	}
+	slices.SortFunc(models, func(a, b provider.ModelInfo) int { return strings.Compare(a.ID, b.ID) })
+	return models, nil
+}
+
+// Ping implements provider.HealthChecker by listing the models, which
+// checks the API key without generating tokens.
+func (p *Provider) Ping(ctx context.Context) error {
+	_, err := p.client.listModels(ctx)
+	return err
+}
+
+// listModels lists the available models.
+func (c *client) listModels(ctx context.Context) (*modelsResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	respBody, err := provider.ReadBody(httpResp.Body, c.maxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, c.parseError(&provider.HTTPResponse{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: respBody})
+	}
+
+	var resp modelsResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	return &resp, nil
+}
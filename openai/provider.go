@@ -4,15 +4,32 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"math"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/i2y/bucephalus/provider"
 )
 
 func init() {
-	provider.Register("openai", func() (provider.Provider, error) {
-		return New()
+	Register("openai")
+}
+
+// Register registers an OpenAI provider configured with opts in the provider
+// registry under name, so it can be selected with llm.WithProvider(name).
+// Register the provider under several names to use several configurations.
+//
+// Example:
+//
+//	openai.Register("openai-eu", openai.WithBaseURL("https://eu.example.com/v1"))
+//	resp, err := llm.Call(ctx, "Hello", llm.WithProvider("openai-eu"), ...)
+func Register(name string, opts ...Option) {
+	provider.Register(name, func() (provider.Provider, error) {
+		return New(opts...)
 	})
 }
 
@@ -25,9 +42,11 @@ type Provider struct {
 type Option func(*providerConfig)
 
 type providerConfig struct {
-	apiKey     string
-	baseURL    string
-	httpClient *http.Client
+	apiKey           string
+	baseURL          string
+	httpClient       *http.Client
+	http             provider.HTTPOptions
+	maxResponseBytes int64
 }
 
 // WithAPIKey sets the API key.
@@ -44,13 +63,40 @@ func WithBaseURL(url string) Option {
 	}
 }
 
-// WithHTTPClient sets a custom HTTP client.
+// WithHTTPClient sets a custom HTTP client. It replaces the default client,
+// so WithProxy and WithTimeout have no effect.
 func WithHTTPClient(client *http.Client) Option {
 	return func(c *providerConfig) {
 		c.httpClient = client
 	}
 }
 
+// WithProxy sends requests through the proxy at proxyURL, e.g.
+// "http://proxy.internal:3128". By default, the proxy is taken from the
+// HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) Option {
+	return func(c *providerConfig) {
+		c.http.Proxy = proxyURL
+	}
+}
+
+// WithTimeout limits the time of a whole request, including reading a
+// streamed response (default: no limit).
+func WithTimeout(d time.Duration) Option {
+	return func(c *providerConfig) {
+		c.http.Timeout = d
+	}
+}
+
+// WithMaxResponseBytes limits the size of response bodies (default:
+// provider.DefaultMaxResponseBytes). Streamed responses are not limited.
+// A negative n disables the limit.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *providerConfig) {
+		c.maxResponseBytes = n
+	}
+}
+
 // New creates a new OpenAI provider.
 func New(opts ...Option) (*Provider, error) {
 	cfg := &providerConfig{}
@@ -69,8 +115,16 @@ func New(opts ...Option) (*Provider, error) {
 		}
 	}
 
+	httpClient := cfg.httpClient
+	if httpClient == nil {
+		var err error
+		if httpClient, err = provider.NewHTTPClient(cfg.http); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Provider{
-		client: newClient(cfg.apiKey, cfg.baseURL, cfg.httpClient),
+		client: newClient(cfg.apiKey, cfg.baseURL, httpClient, cfg.maxResponseBytes),
 	}, nil
 }
 
@@ -79,31 +133,40 @@ func (p *Provider) Name() string {
 	return "openai"
 }
 
+// Close closes the idle connections of the provider's HTTP client.
+// provider.Close calls it for registered providers.
+func (p *Provider) Close() error {
+	p.client.httpClient.CloseIdleConnections()
+	return nil
+}
+
 // Call implements provider.Provider.
 func (p *Provider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
 	apiReq := p.buildRequest(req)
 
-	apiResp, err := p.client.chatCompletion(ctx, apiReq)
+	apiResp, raw, err := p.client.chatCompletion(ctx, apiReq, req.Headers)
 	if err != nil {
 		return nil, err
 	}
 
-	return p.convertResponse(apiResp), nil
+	resp := p.convertResponse(apiResp)
+	resp.HTTP = raw
+	return resp, nil
 }
 
 // CallStream implements provider.StreamingProvider.
 func (p *Provider) CallStream(ctx context.Context, req *provider.Request) (provider.ResponseStream, error) {
 	apiReq := p.buildRequest(req)
 
-	stream, err := p.client.chatCompletionStream(ctx, apiReq)
+	stream, err := p.client.chatCompletionStream(ctx, apiReq, req.Headers)
 	if err != nil {
 		return nil, err
 	}
 
 	return &openaiStream{
-		reader:      stream,
-		accumulated: &provider.Response{},
-		toolCalls:   make(map[int]*provider.ToolCall),
+		reader:   stream,
+		acc:      provider.NewAccumulator(stream.raw),
+		filtered: make(map[string]contentFilterResult),
 	}, nil
 }
 
@@ -117,9 +180,21 @@ func (p *Provider) buildRequest(req *provider.Request) *chatCompletionRequest {
 		TopP:        req.TopP,
 		Seed:        req.Seed,
 		Stop:        req.StopSequences,
+		User:        req.User,
+	}
+	if len(req.LogitBias) > 0 {
+		apiReq.LogitBias = make(map[string]int, len(req.LogitBias))
+		for token, bias := range req.LogitBias {
+			apiReq.LogitBias[token] = int(math.Round(max(-100, min(100, bias))))
+		}
 	}
 
 	for _, msg := range req.Messages {
+		// Empty assistant messages are rejected
+		if !req.RawMessages && msg.Role == provider.RoleAssistant && len(msg.ToolCalls) == 0 && strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+
 		apiMsg := message{
 			Role:    string(msg.Role),
 			Content: msg.Content,
@@ -159,15 +234,27 @@ func (p *Provider) buildRequest(req *provider.Request) *chatCompletionRequest {
 			},
 		})
 	}
+	if len(apiReq.Tools) > 0 {
+		apiReq.ToolChoice = convertToolChoice(req.ToolChoice)
+	}
 
 	// Handle JSON Schema for structured output
 	if req.JSONSchema != nil {
+		schema := req.JSONSchema.Schema
+		additional := req.JSONSchema.AdditionalProperties
+		if req.JSONSchema.Strict {
+			// Strict mode requires all properties and additionalProperties: false everywhere
+			schema = makeAllPropertiesRequired(schema)
+			if additional == provider.AdditionalPropertiesAuto {
+				additional = provider.AdditionalPropertiesFalse
+			}
+		}
 		apiReq.ResponseFormat = &responseFormat{
 			Type: "json_schema",
 			JSONSchema: &jsonSchemaFormat{
 				Name:   req.JSONSchema.Name,
 				Strict: req.JSONSchema.Strict,
-				Schema: makeAllPropertiesRequired(req.JSONSchema.Schema),
+				Schema: provider.ApplyAdditionalProperties(schema, additional),
 			},
 		}
 	}
@@ -175,6 +262,19 @@ func (p *Provider) buildRequest(req *provider.Request) *chatCompletionRequest {
 	return apiReq
 }
 
+// convertToolChoice converts a provider.ToolChoice to an OpenAI tool choice.
+func convertToolChoice(choice *provider.ToolChoice) any {
+	if choice == nil || choice.Mode == "" {
+		return nil
+	}
+	if choice.Mode == provider.ToolChoiceRequired && len(choice.Tools) == 1 {
+		named := namedToolChoice{Type: "function"}
+		named.Function.Name = choice.Tools[0]
+		return named
+	}
+	return string(choice.Mode)
+}
+
 // convertResponse converts an OpenAI API response to a provider.Response.
 func (p *Provider) convertResponse(resp *chatCompletionResponse) *provider.Response {
 	if len(resp.Choices) == 0 {
@@ -191,6 +291,10 @@ func (p *Provider) convertResponse(resp *chatCompletionResponse) *provider.Respo
 			TotalTokens:      resp.Usage.TotalTokens,
 		},
 	}
+	if filter := contentFilter(choice.FinishReason, choice.Message.Refusal, choice.ContentFilterResults); filter != nil {
+		result.FinishReason = provider.FinishReasonContentFiltered
+		result.ContentFilter = filter
+	}
 
 	// Convert tool calls
 	for _, tc := range choice.Message.ToolCalls {
@@ -205,7 +309,7 @@ func (p *Provider) convertResponse(resp *chatCompletionResponse) *provider.Respo
 }
 
 // makeAllPropertiesRequired ensures all properties in the schema are required.
-// OpenAI's structured output API requires all properties to be in the 'required' array.
+// OpenAI's strict structured output requires all properties to be in the 'required' array.
 func makeAllPropertiesRequired(schema json.RawMessage) json.RawMessage {
 	if schema == nil {
 		return nil
@@ -226,7 +330,28 @@ func makeAllPropertiesRequired(schema json.RawMessage) json.RawMessage {
 }
 
 // makeRequiredRecursive recursively makes all properties required in the schema.
+// oneOf is rewritten to anyOf, which is what structured outputs support.
 func makeRequiredRecursive(schemaMap map[string]any) {
+	if oneOf, ok := schemaMap["oneOf"]; ok {
+		delete(schemaMap, "oneOf")
+		schemaMap["anyOf"] = oneOf
+	}
+	if anyOf, ok := schemaMap["anyOf"].([]any); ok {
+		for _, alt := range anyOf {
+			if altMap, ok := alt.(map[string]any); ok {
+				makeRequiredRecursive(altMap)
+			}
+		}
+	}
+	// Recursive types are defined in $defs
+	if defs, ok := schemaMap["$defs"].(map[string]any); ok {
+		for _, def := range defs {
+			if defMap, ok := def.(map[string]any); ok {
+				makeRequiredRecursive(defMap)
+			}
+		}
+	}
+
 	// Get all property names and make them required
 	if props, ok := schemaMap["properties"].(map[string]any); ok {
 		required := make([]string, 0, len(props))
@@ -238,13 +363,13 @@ func makeRequiredRecursive(schemaMap map[string]any) {
 		// Recursively process nested objects
 		for _, val := range props {
 			if propMap, ok := val.(map[string]any); ok {
-				// Handle nested object types
-				if propMap["type"] == "object" {
+				// Handle nested object types and unions
+				if propMap["type"] == "object" || propMap["oneOf"] != nil || propMap["anyOf"] != nil {
 					makeRequiredRecursive(propMap)
 				}
 				// Handle array items
 				if items, ok := propMap["items"].(map[string]any); ok {
-					if items["type"] == "object" {
+					if items["type"] == "object" || items["oneOf"] != nil || items["anyOf"] != nil {
 						makeRequiredRecursive(items)
 					}
 				}
@@ -260,19 +385,44 @@ func convertFinishReason(reason string) provider.FinishReason {
 		return provider.FinishReasonToolCalls
 	case "length":
 		return provider.FinishReasonLength
+	case "content_filter":
+		return provider.FinishReasonContentFiltered
 	default:
 		return provider.FinishReasonStop
 	}
 }
 
+// contentFilter returns the details of a response blocked by the content
+// filter (finish reason "content_filter") or refused by the model, or nil.
+func contentFilter(reason, refusal string, results map[string]contentFilterResult) *provider.ContentFilter {
+	switch {
+	case refusal != "":
+		return &provider.ContentFilter{Reason: "refusal", Message: refusal}
+	case reason == "content_filter":
+		filter := &provider.ContentFilter{Reason: reason}
+		for category, result := range results {
+			if result.Filtered {
+				filter.Categories = append(filter.Categories, category)
+			}
+		}
+		slices.Sort(filter.Categories)
+		return filter
+	default:
+		return nil
+	}
+}
+
 // openaiStream implements provider.ResponseStream for OpenAI.
 type openaiStream struct {
-	reader      *streamReader
-	accumulated *provider.Response
-	err         error
-	current     *provider.StreamChunk
-	done        bool
-	toolCalls   map[int]*provider.ToolCall // Track tool calls by index
+	reader    *streamReader
+	acc       *provider.Accumulator
+	err       error
+	chunk     provider.StreamChunk // Reused for every chunk
+	toolDelta provider.ToolCallDelta
+	current   *provider.StreamChunk
+	done      bool
+	refusal   strings.Builder
+	filtered  map[string]contentFilterResult // Filtered categories, for Azure
 }
 
 func (s *openaiStream) Next() bool {
@@ -282,19 +432,16 @@ func (s *openaiStream) Next() bool {
 
 	chunk, err := s.reader.ReadChunk()
 	if err != nil {
-		if err.Error() == "EOF" {
+		if err == io.EOF {
 			s.done = true
-			// Finalize tool calls
-			for _, tc := range s.toolCalls {
-				s.accumulated.ToolCalls = append(s.accumulated.ToolCalls, *tc)
-			}
 			return false
 		}
 		s.err = err
 		return false
 	}
 
-	s.current = &provider.StreamChunk{}
+	s.chunk = provider.StreamChunk{}
+	s.current = &s.chunk
 
 	if len(chunk.Choices) > 0 {
 		choice := chunk.Choices[0]
@@ -303,42 +450,44 @@ func (s *openaiStream) Next() bool {
 		// Handle content delta
 		if delta.Content != "" {
 			s.current.Delta = delta.Content
-			s.accumulated.Content += delta.Content
+			s.acc.AddContent(delta.Content)
 		}
 
 		// Handle tool call deltas
 		for _, tc := range delta.ToolCalls {
-			if _, exists := s.toolCalls[tc.Index]; !exists {
-				s.toolCalls[tc.Index] = &provider.ToolCall{}
-			}
-			toolCall := s.toolCalls[tc.Index]
-
-			if tc.ID != "" {
-				toolCall.ID = tc.ID
-			}
-			if tc.Function.Name != "" {
-				toolCall.Name = tc.Function.Name
-			}
+			id, name := s.acc.AddToolCallDelta(tc.Index, tc.ID, tc.Function.Name, tc.Function.Arguments)
 			if tc.Function.Arguments != "" {
-				toolCall.Arguments += tc.Function.Arguments
-				s.current.ToolCallDelta = &provider.ToolCallDelta{
-					ID:             toolCall.ID,
-					Name:           toolCall.Name,
+				s.toolDelta = provider.ToolCallDelta{
+					ID:             id,
+					Name:           name,
 					ArgumentsDelta: tc.Function.Arguments,
 				}
+				s.current.ToolCallDelta = &s.toolDelta
+			}
+		}
+
+		// Refusals are streamed separately from the content
+		s.refusal.WriteString(delta.Refusal)
+		for category, result := range choice.ContentFilterResults {
+			if result.Filtered {
+				s.filtered[category] = result
 			}
 		}
 
 		// Handle finish reason
 		if choice.FinishReason != nil {
 			s.current.FinishReason = convertFinishReason(*choice.FinishReason)
-			s.accumulated.FinishReason = s.current.FinishReason
+			if filter := contentFilter(*choice.FinishReason, s.refusal.String(), s.filtered); filter != nil {
+				s.current.FinishReason = provider.FinishReasonContentFiltered
+				s.acc.ContentFilter = filter
+			}
+			s.acc.FinishReason = s.current.FinishReason
 		}
 	}
 
 	// Handle usage (sent in final chunk with stream_options)
 	if chunk.Usage != nil {
-		s.accumulated.Usage = provider.Usage{
+		s.acc.Usage = provider.Usage{
 			PromptTokens:     chunk.Usage.PromptTokens,
 			CompletionTokens: chunk.Usage.CompletionTokens,
 			TotalTokens:      chunk.Usage.TotalTokens,
@@ -361,5 +510,5 @@ func (s *openaiStream) Close() error {
 }
 
 func (s *openaiStream) Accumulated() *provider.Response {
-	return s.accumulated
+	return s.acc.Response()
 }
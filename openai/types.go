@@ -12,8 +12,19 @@ type chatCompletionRequest struct {
 	Seed           *int            `json:"seed,omitempty"`
 	Stop           []string        `json:"stop,omitempty"`
 	Tools          []toolDef       `json:"tools,omitempty"`
+	ToolChoice     any             `json:"tool_choice,omitempty"` // A mode string or a namedToolChoice
 	ResponseFormat *responseFormat `json:"response_format,omitempty"`
 	Stream         bool            `json:"stream,omitempty"`
+	User           string          `json:"user,omitempty"`
+	LogitBias      map[string]int  `json:"logit_bias,omitempty"`
+}
+
+// namedToolChoice forces a call of the named function.
+type namedToolChoice struct {
+	Type     string `json:"type"` // "function"
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
 }
 
 // message represents a chat message.
@@ -65,12 +76,22 @@ type choice struct {
 	Index        int             `json:"index"`
 	Message      responseMessage `json:"message"`
 	FinishReason string          `json:"finish_reason"`
+
+	// ContentFilterResults is set by Azure OpenAI.
+	ContentFilterResults map[string]contentFilterResult `json:"content_filter_results,omitempty"`
+}
+
+// contentFilterResult represents the result of a content filter category.
+type contentFilterResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity,omitempty"`
 }
 
 // responseMessage represents the assistant's response message.
 type responseMessage struct {
 	Role      string     `json:"role"`
 	Content   string     `json:"content"`
+	Refusal   string     `json:"refusal,omitempty"`
 	ToolCalls []toolCall `json:"tool_calls,omitempty"`
 }
 
@@ -123,12 +144,15 @@ type streamChoice struct {
 	Index        int         `json:"index"`
 	Delta        streamDelta `json:"delta"`
 	FinishReason *string     `json:"finish_reason"`
+
+	ContentFilterResults map[string]contentFilterResult `json:"content_filter_results,omitempty"`
 }
 
 // streamDelta represents the delta content in a streaming chunk.
 type streamDelta struct {
 	Role      string           `json:"role,omitempty"`
 	Content   string           `json:"content,omitempty"`
+	Refusal   string           `json:"refusal,omitempty"`
 	ToolCalls []streamToolCall `json:"tool_calls,omitempty"`
 }
 
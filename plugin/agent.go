@@ -2,10 +2,22 @@ package plugin
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/tools"
 )
 
+// DefaultAgentMaxTurns is the default maximum number of LLM calls made by a single Run().
+const DefaultAgentMaxTurns = 10
+
+// ErrMaxTurnsExceeded is returned when the agent keeps requesting tool calls
+// after the maximum number of turns has been reached.
+var ErrMaxTurnsExceeded = errors.New("agent exceeded maximum turns")
+
 // AgentRunner provides methods to run an agent as an independent LLM call.
 // It maintains conversation history across multiple Run() calls via AgentContext.
 type AgentRunner struct {
@@ -16,8 +28,31 @@ type AgentRunner struct {
 	filteredTools  []llm.Tool
 	temperature    *float64
 	maxTokens      *int
-	context        *AgentContext // Maintains conversation history and state
-	extraLLMOpts   []llm.Option  // Additional llm.Options to apply on every call
+	context        *AgentContext        // Maintains conversation history and state
+	extraLLMOpts   []llm.Option         // Additional llm.Options to apply on every call
+	maxTurns       int                  // Maximum LLM calls per Run()
+	registry       *llm.ToolRegistry    // Executes tool calls requested by the model
+	store          ContextStore         // Persists the context by session ID (optional)
+	sessionID      string               // Session ID used with store
+	contextLoaded  bool                 // Whether the context has been loaded from store
+	historyPolicy  []HistoryPolicy      // Applied to the history before each Run()
+	hooks          Hooks                // Lifecycle callbacks
+	tokenBudget    int                  // Maximum tokens per Run() (0 = unlimited)
+	deadline       time.Duration        // Maximum wall-clock time per Run() (0 = unlimited)
+	plugin         *Plugin              // Plugin whose agents can be spawned by name (optional)
+	handoffEnabled bool                 // Whether the handoff tool is registered
+	handoff        *Handoff             // Handoff requested during the last run
+	shared         *SharedState         // State shared with cooperating agents
+	pricing        *Pricing             // Prices used to estimate run cost (optional)
+	fallbacks      []ModelRef           // Models to fail over to, in order
+	fallbackOn     func(error) bool     // Decides whether an error fails over (nil = provider.IsTransient)
+	trace          *Trace               // Trace of the most recent run
+	redactor       *llm.Redactor        // Redacts traces (nil = llm.DefaultRedactor)
+	control        runControl           // Pause/stop requests from other goroutines
+	reflections    int                  // Critique rounds per Run() (0 = none)
+	critic         *ModelRef            // Model that critiques answers (nil = the runner's)
+	fileChanges    *tools.FileChangeLog // Changes to files during the last run
+	report         *usageReport         // Usage report of the most recent run
 }
 
 // AgentOption configures an AgentRunner.
@@ -45,6 +80,22 @@ func WithAgentTools(tools ...llm.Tool) AgentOption {
 	}
 }
 
+// WithAgentToolRegistry provides the tools of a registry to the agent.
+// Like WithAgentTools, only tools listed in the agent's Tools field will be available.
+func WithAgentToolRegistry(registry *llm.ToolRegistry) AgentOption {
+	return func(r *AgentRunner) {
+		r.availableTools = append(r.availableTools, registry.All()...)
+	}
+}
+
+// WithAgentMaxTurns sets the maximum number of LLM calls a single Run() may make
+// while executing tool calls. Defaults to DefaultAgentMaxTurns.
+func WithAgentMaxTurns(n int) AgentOption {
+	return func(r *AgentRunner) {
+		r.maxTurns = n
+	}
+}
+
 // WithAgentTemperature sets the temperature for the agent.
 func WithAgentTemperature(t float64) AgentOption {
 	return func(r *AgentRunner) {
@@ -67,9 +118,47 @@ func WithAgentContext(ctx *AgentContext) AgentOption {
 	}
 }
 
+// WithAgentContextStore enables persistence of the runner's context.
+// The context saved for sessionID is loaded before the first Run() and
+// the context is saved after every successful Run().
+//
+// Example:
+//
+//	store, _ := plugin.NewFileContextStore("./sessions")
+//	runner := agent.NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentContextStore(store, "user-123"),
+//	)
+func WithAgentContextStore(store ContextStore, sessionID string) AgentOption {
+	return func(r *AgentRunner) {
+		r.store = store
+		r.sessionID = sessionID
+	}
+}
+
+// WithAgentHistoryPolicy sets policies that trim or compact the conversation
+// history before each Run(). Policies are applied in order.
+//
+// Example:
+//
+//	runner := agent.NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentHistoryPolicy(
+//	        plugin.KeepSystemAndLastNPolicy(50),
+//	        plugin.MaxTokensPolicy(100000),
+//	    ),
+//	)
+func WithAgentHistoryPolicy(policies ...HistoryPolicy) AgentOption {
+	return func(r *AgentRunner) {
+		r.historyPolicy = append(r.historyPolicy, policies...)
+	}
+}
+
 // WithAgentLLMOptions sets additional llm.Options to apply on every Run() call.
 // This allows passing options like WithTopP, WithTopK, WithSeed, WithStopSequences,
-// or additional WithSystemMessage to the agent.
+// or WithSystemSection to layer more instructions over the agent's system message.
 //
 // Example:
 //
@@ -78,7 +167,7 @@ func WithAgentContext(ctx *AgentContext) AgentOption {
 //	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
 //	    plugin.WithAgentLLMOptions(
 //	        llm.WithTopP(0.9),
-//	        llm.WithSystemMessage(p.SkillsIndexSystemMessage()),
+//	        p.SkillsIndexOption(),
 //	    ),
 //	)
 func WithAgentLLMOptions(opts ...llm.Option) AgentOption {
@@ -87,6 +176,13 @@ func WithAgentLLMOptions(opts ...llm.Option) AgentOption {
 	}
 }
 
+// WithAgentQuota makes every LLM call of the runner (and of the sub-agents it
+// spawns) wait for or fail on the limits of q and record its usage in q.
+// Share one Quota between runners to rate limit them together.
+func WithAgentQuota(q *llm.Quota) AgentOption {
+	return WithAgentLLMOptions(llm.WithQuota(q))
+}
+
 // RunOption configures a single Run() call.
 type RunOption func(*runConfig)
 
@@ -94,6 +190,12 @@ type RunOption func(*runConfig)
 type runConfig struct {
 	extraSystemMessage string
 	extraLLMOpts       []llm.Option
+	grantedTools       []llm.Tool
+	deniedTools        []string
+
+	// Resolved by newRunConfig
+	tools    []llm.Tool
+	registry *llm.ToolRegistry
 }
 
 // WithRunSystemMessage adds an additional system message for this Run() call only.
@@ -111,23 +213,102 @@ func WithRunLLMOptions(opts ...llm.Option) RunOption {
 	}
 }
 
+// WithRunTools makes additional tools available for this Run() call only.
+// Granted tools are not filtered by the agent's allowed tools, so they can be
+// used to extend the agent's capabilities temporarily (e.g. grant write access
+// only for a turn the user has confirmed).
+func WithRunTools(tools ...llm.Tool) RunOption {
+	return func(c *runConfig) {
+		c.grantedTools = append(c.grantedTools, tools...)
+	}
+}
+
+// WithRunDeniedTools removes the named tools for this Run() call only.
+// Denied tools take precedence over tools granted with WithRunTools.
+func WithRunDeniedTools(names ...string) RunOption {
+	return func(c *runConfig) {
+		c.deniedTools = append(c.deniedTools, names...)
+	}
+}
+
+// newRunConfig applies run options and resolves the tools available to the run.
+func (r *AgentRunner) newRunConfig(runOpts []RunOption) *runConfig {
+	cfg := &runConfig{}
+	for _, opt := range runOpts {
+		opt(cfg)
+	}
+
+	cfg.tools, cfg.registry = r.filteredTools, r.registry
+	if len(cfg.grantedTools) == 0 && len(cfg.deniedTools) == 0 {
+		return cfg
+	}
+
+	denied := make(map[string]bool, len(cfg.deniedTools))
+	for _, name := range cfg.deniedTools {
+		denied[name] = true
+	}
+
+	var tools []llm.Tool
+	seen := make(map[string]bool)
+	for _, tool := range append(slices.Clone(r.filteredTools), cfg.grantedTools...) {
+		if denied[tool.Name()] || seen[tool.Name()] {
+			continue
+		}
+		seen[tool.Name()] = true
+		tools = append(tools, tool)
+	}
+
+	cfg.tools, cfg.registry = tools, nil
+	if len(tools) > 0 {
+		cfg.registry = llm.NewToolRegistry()
+		cfg.registry.Register(tools...)
+	}
+	return cfg
+}
+
 // NewRunner creates a new AgentRunner for this agent.
 // The runner maintains conversation history across multiple Run() calls.
+// The provider, model, temperature, and max tokens not set by opts default
+// to the LLM defaults of the plugin set with WithAgentPlugin, then of the
+// plugin the agent was loaded from.
 func (a *Agent) NewRunner(opts ...AgentOption) *AgentRunner {
 	runner := &AgentRunner{
-		agent: a,
+		agent:    a,
+		maxTurns: DefaultAgentMaxTurns,
 	}
 
 	for _, opt := range opts {
 		opt(runner)
 	}
 
+	if runner.maxTurns <= 0 {
+		runner.maxTurns = DefaultAgentMaxTurns
+	}
+	if runner.plugin != nil {
+		runner.applyLLMDefaults(runner.plugin.LLM)
+	}
+	if a.plugin != nil {
+		runner.applyLLMDefaults(a.plugin.LLM)
+	}
+
 	// Filter tools based on agent's allowed tools
 	runner.filteredTools = runner.filterTools()
+	if runner.handoffEnabled && runner.plugin != nil {
+		runner.filteredTools = append(runner.filteredTools, runner.handoffTool())
+	}
+	if len(runner.filteredTools) > 0 {
+		runner.registry = llm.NewToolRegistry()
+		runner.registry.Register(runner.filteredTools...)
+	}
 
 	// Initialize context if not provided via options
 	if runner.context == nil {
-		runner.context = NewAgentContext()
+		runner.context = NewAgentContext(WithContextSessionID(runner.sessionID))
+	} else if runner.sessionID != "" && runner.context.SessionID() == "" {
+		runner.context.SetSessionID(runner.sessionID)
+	}
+	if runner.shared == nil {
+		runner.shared = NewSharedState()
 	}
 
 	return runner
@@ -159,6 +340,11 @@ func (r *AgentRunner) filterTools() []llm.Tool {
 // Conversation history is maintained in the runner's context, allowing
 // multi-turn conversations across multiple Run() calls.
 //
+// When the model requests tool calls, Run executes them with the runner's
+// filtered tools, feeds the results back to the model, and repeats until the
+// model produces a final answer or the max-turns limit is reached.
+// The tool calls and tool results are recorded in the context history.
+//
 // Optional RunOption arguments can be passed to customize this specific call:
 //
 //	resp, _ := runner.Run(ctx, "Help me",
@@ -166,80 +352,318 @@ func (r *AgentRunner) filterTools() []llm.Tool {
 //	    plugin.WithRunLLMOptions(llm.WithTopP(0.9)),
 //	)
 func (r *AgentRunner) Run(ctx context.Context, task string, runOpts ...RunOption) (llm.Response[string], error) {
-	// Apply run options
-	cfg := &runConfig{}
-	for _, opt := range runOpts {
-		opt(cfg)
+	return r.run(ctx, []llm.Message{llm.UserMessage(task)}, runOpts)
+}
+
+// RunParse executes the agent like Run, but requests a structured result of type T.
+// The agent's system message and tools are applied; tool calls are executed
+// until the model returns the final structured answer.
+// Go methods cannot have type parameters, so this is a function taking the runner.
+//
+// Example:
+//
+//	type ReviewReport struct {
+//	    Summary string   `json:"summary" jsonschema:"required"`
+//	    Issues  []string `json:"issues"`
+//	}
+//
+//	resp, err := plugin.RunParse[ReviewReport](ctx, runner, "Review main.go")
+//	if err != nil {
+//	    return err
+//	}
+//	report, err := resp.Parsed()
+func RunParse[T any](ctx context.Context, r *AgentRunner, task string, runOpts ...RunOption) (llm.Response[T], error) {
+	return runLoop(ctx, r, []llm.Message{llm.UserMessage(task)}, runOpts, llm.CallMessagesParse[T])
+}
+
+// RunWithMessages executes the agent with custom messages appended to the context history.
+// The provided messages are added to the existing context history before making the call.
+// Like Run, tool calls are executed and recorded in the history.
+// This can also be used to answer tool calls left pending by a previous run by
+// passing the corresponding tool result messages.
+// Optional RunOption arguments can be passed to customize this specific call.
+func (r *AgentRunner) RunWithMessages(ctx context.Context, messages []llm.Message, runOpts ...RunOption) (llm.Response[string], error) {
+	return runLoop(ctx, r, messages, runOpts, llm.CallMessages)
+}
+
+// loadContext loads the runner's context from its store on first use.
+func (r *AgentRunner) loadContext(ctx context.Context) error {
+	if r.store == nil || r.contextLoaded {
+		return nil
 	}
 
-	// Build options
-	opts := make([]llm.Option, 0)
+	loaded, err := r.store.Load(ctx, r.sessionID)
+	if err != nil && !errors.Is(err, ErrContextNotFound) {
+		return fmt.Errorf("loading agent context: %w", err)
+	}
+	if loaded != nil {
+		r.context.replaceWith(loaded)
+	}
+	r.contextLoaded = true
+	return nil
+}
 
-	if r.providerName != "" {
-		opts = append(opts, llm.WithProvider(r.providerName))
+// prepareContext loads the runner's context and applies its history policies.
+func (r *AgentRunner) prepareContext(ctx context.Context) error {
+	if err := r.loadContext(ctx); err != nil {
+		return err
 	}
-	if r.model != "" {
-		opts = append(opts, llm.WithModel(r.model))
+	if err := r.context.ApplyHistoryPolicy(ctx, r.historyPolicy...); err != nil {
+		return fmt.Errorf("applying history policy: %w", err)
 	}
-	if r.temperature != nil {
-		opts = append(opts, llm.WithTemperature(*r.temperature))
+	return nil
+}
+
+// saveContext saves the runner's context to its store, if any.
+func (r *AgentRunner) saveContext(ctx context.Context) error {
+	if r.store == nil {
+		return nil
 	}
-	if r.maxTokens != nil {
-		opts = append(opts, llm.WithMaxTokens(*r.maxTokens))
+	if err := r.store.Save(ctx, r.sessionID, r.context); err != nil {
+		return fmt.Errorf("saving agent context: %w", err)
 	}
+	return nil
+}
 
-	// Add agent's system message
-	opts = append(opts, llm.WithSystemMessage(r.agent.ToSystemMessage()))
+// run executes the agent loop for the given input messages.
+func (r *AgentRunner) run(ctx context.Context, input []llm.Message, runOpts []RunOption) (llm.Response[string], error) {
+	return runLoop(ctx, r, input, runOpts, llm.CallMessages)
+}
 
-	// Add extra system message from run options (if any)
-	if cfg.extraSystemMessage != "" {
-		opts = append(opts, llm.WithSystemMessage(cfg.extraSystemMessage))
+// callFunc makes a single LLM call with a full message history.
+type callFunc[T any] func(ctx context.Context, messages []llm.Message, opts ...llm.Option) (llm.Response[T], error)
+
+// turnFunc makes the LLM call of a single turn of the agent loop, failing
+// over to the fallback models of the runner, and returns the model that answered.
+type turnFunc[T any] func(ctx context.Context, turn int, messages []llm.Message, opts []llm.Option) (llm.Response[T], ModelRef, error)
+
+// runLoop executes the agent loop using call for each LLM call.
+func runLoop[T any](ctx context.Context, r *AgentRunner, input []llm.Message, runOpts []RunOption, call callFunc[T]) (llm.Response[T], error) {
+	turnCall := func(ctx context.Context, turn int, messages []llm.Message, opts []llm.Option) (resp llm.Response[T], model ModelRef, err error) {
+		model, err = r.withFallback(ctx, opts, func(opts []llm.Option) (err error) {
+			resp, err = call(ctx, messages, opts...)
+			return err
+		})
+		return resp, model, err
+	}
+	return agentLoop(ctx, r, input, r.newRunConfig(runOpts), turnCall, nil)
+}
+
+// agentLoop executes the agent loop of runs and streams. Each iteration makes
+// one LLM call with turnCall; tool calls requested by the model are executed
+// and their results are sent back until the model stops calling tools.
+//
+// If emit is not nil, the turns and tool calls are emitted as events; the
+// loop ends without error and without recording the run in the history when
+// emit returns false.
+func agentLoop[T any](ctx context.Context, r *AgentRunner, input []llm.Message, cfg *runConfig, turnCall turnFunc[T], emit func(AgentEvent) bool) (resp llm.Response[T], err error) {
+	if err := r.beginRun(); err != nil {
+		return resp, err
 	}
+	defer r.endRun()
 
-	// Add filtered tools
-	if len(r.filteredTools) > 0 {
-		opts = append(opts, llm.WithTools(r.filteredTools...))
+	r.startTrace(input)
+	ctx = r.startReport(ctx)
+	defer func() {
+		if errors.Is(err, errStopped) {
+			err = nil
+		}
+		r.finishTrace(err)
+		r.finishReport()
+		r.hooks.runError(ctx, err)
+	}()
+	if emit == nil {
+		emit = func(AgentEvent) bool { return true }
 	}
 
-	// Add runner-level extra LLM options
-	opts = append(opts, r.extraLLMOpts...)
+	// Loading and trimming the history may take a while; a run stopped
+	// before it starts does not do it
+	if r.stopRequested() {
+		return resp, ErrRunStopped
+	}
+	if err := r.prepareContext(ctx); err != nil {
+		return resp, err
+	}
+	r.handoff = nil
 
-	// Add run-level extra LLM options
-	opts = append(opts, cfg.extraLLMOpts...)
+	opts := r.buildOptions(cfg)
 
-	// Create user message for this turn
-	userMsg := llm.UserMessage(task)
+	// Answer tool calls left pending by a previous run, then add the new input
+	input = closePendingToolCalls(r.context.History(), input)
 
-	// Build messages: existing history + new user message
+	// Build messages: existing history + new input
 	history := r.context.History()
-	messages := make([]llm.Message, 0, len(history)+1)
+	messages := make([]llm.Message, 0, len(history)+len(input))
 	messages = append(messages, history...)
-	messages = append(messages, userMsg)
+	messages = append(messages, input...)
 
-	// Make the LLM call with full message history
-	resp, err := llm.CallMessages(ctx, messages, opts...)
-	if err != nil {
-		return resp, err
+	// Messages produced during this run, recorded to history on completion
+	transcript := make([]llm.Message, 0, len(input)+1)
+	transcript = append(transcript, input...)
+
+	budget := r.newRunBudget(ctx)
+	defer budget.stop()
+	ctx = r.trackFileChanges(budget.ctx)
+	reflections := 0
+
+	for turn := 1; ; turn++ {
+		if err := r.checkpoint(ctx); err != nil {
+			return resp, r.interrupt(ctx, transcript, err)
+		}
+
+		if budget.deadlineExceeded() {
+			r.context.AddMessages(transcript...)
+			return resp, budget.error(BudgetDeadline, transcript)
+		}
+
+		if err := r.hooks.turnStart(ctx, turn, messages); err != nil {
+			return resp, err
+		}
+
+		start := time.Now()
+		var model ModelRef
+		resp, model, err = turnCall(ctx, turn, messages, opts)
+		if err != nil {
+			if errors.Is(err, errStopped) {
+				return resp, err
+			}
+			if budget.deadlineExceeded() {
+				r.context.AddMessages(transcript...)
+				return resp, budget.error(BudgetDeadline, transcript)
+			}
+			// Keep the tool calls that already ran, so the next run does not repeat them
+			return resp, r.interrupt(ctx, transcript, err)
+		}
+		r.endTurn(ctx, budget, turn, model, messages, resp.FinishReason(), resp.Usage(), assistantMessage(resp), time.Since(start))
+		if !emit(AgentEvent{Type: AgentEventTurnComplete, Turn: turn}) {
+			return resp, errStopped
+		}
+
+		transcript = append(transcript, assistantMessage(resp))
+		if !resp.HasToolCalls() {
+			critique, revise, err := r.reflect(ctx, budget, &reflections, messages, resp.Text())
+			if err != nil {
+				r.context.AddMessages(transcript...)
+				return resp, err
+			}
+			if revise {
+				// Revise the answer in place of the draft, which is left out of the history
+				messages = append(messages, transcript[len(transcript)-1], llm.UserMessage(fmt.Sprintf(revisionPrompt, critique)))
+				transcript = transcript[:len(transcript)-1]
+				continue
+			}
+		}
+		if !resp.HasToolCalls() || cfg.registry == nil {
+			r.context.AddMessages(transcript...)
+			return resp, r.saveContext(ctx)
+		}
+
+		if kind, ok := budget.exceeded(); ok {
+			r.context.AddMessages(transcript...)
+			return resp, budget.error(kind, transcript)
+		}
+
+		messages = append(messages, transcript[len(transcript)-1])
+		for _, tc := range resp.ToolCalls() {
+			if err := r.checkpoint(ctx); err != nil {
+				return resp, r.interrupt(ctx, transcript, err)
+			}
+			if !emit(AgentEvent{Type: AgentEventToolCallStarted, Turn: turn, ToolCall: &tc}) {
+				return resp, errStopped
+			}
+
+			toolMsg, err := r.executeToolCall(ctx, cfg.registry, tc)
+			if err != nil {
+				return resp, r.interrupt(ctx, transcript, err)
+			}
+			messages = append(messages, toolMsg)
+			transcript = append(transcript, toolMsg)
+
+			if !emit(AgentEvent{Type: AgentEventToolResult, Turn: turn, ToolCall: &tc, ToolResult: toolMsg.Content}) {
+				return resp, errStopped
+			}
+		}
+
+		// A handoff ends the run; the next agent continues the conversation
+		if r.handoff != nil {
+			r.context.AddMessages(transcript...)
+			return resp, r.saveContext(ctx)
+		}
 	}
+}
 
-	// Add user message and assistant response to context history
-	r.context.AddMessage(userMsg)
-	r.context.AddMessage(llm.AssistantMessage(resp.Text()))
+// endTurn records a finished LLM call of the agent loop in the budget, the
+// trace, the run report, the hooks, and the metrics.
+func (r *AgentRunner) endTurn(ctx context.Context, budget *runBudget, turn int, model ModelRef, messages []llm.Message, finishReason llm.FinishReason, usage llm.Usage, reply llm.Message, elapsed time.Duration) {
+	budget.record(usage)
+	r.traceTurn(turn, model, messages, reply, finishReason, usage, elapsed)
+	r.reportTurn(turn, model, usage, elapsed)
+	r.hooks.turnEnd(ctx, turn, reply)
+	if m := llm.Metrics(); m != nil {
+		m.RecordAgentTurn(r.agent.Name)
+	}
+}
 
-	return resp, nil
+// assistantMessage returns the assistant message for a response,
+// including its tool calls so the history stays a faithful transcript.
+func assistantMessage[T any](resp llm.Response[T]) llm.Message {
+	if resp.HasToolCalls() {
+		return llm.AssistantMessageWithToolCalls(resp.Text(), resp.ToolCalls())
+	}
+	return llm.AssistantMessage(resp.Text())
 }
 
-// RunWithMessages executes the agent with custom messages appended to the context history.
-// The provided messages are added to the existing context history before making the call.
-// Optional RunOption arguments can be passed to customize this specific call.
-func (r *AgentRunner) RunWithMessages(ctx context.Context, messages []llm.Message, runOpts ...RunOption) (llm.Response[string], error) {
-	// Apply run options
-	cfg := &runConfig{}
-	for _, opt := range runOpts {
-		opt(cfg)
+// pendingToolCallResult is the tool result recorded for a tool call that was never executed.
+var pendingToolCallResult = llm.ToolErrorResult{
+	ErrorType: llm.ToolErrorNotExecuted,
+	Message:   "tool call was not executed",
+	Retryable: true,
+}.ToolContent()
+
+// closePendingToolCalls prepends tool results for tool calls in the last
+// assistant message of history that are not answered by history or input.
+// Providers reject conversations where tool calls have no matching results.
+func closePendingToolCalls(history, input []llm.Message) []llm.Message {
+	// Find the last assistant message with tool calls
+	last := -1
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == llm.RoleAssistant {
+			if len(history[i].ToolCalls) > 0 {
+				last = i
+			}
+			break
+		}
+	}
+	if last < 0 {
+		return input
 	}
 
-	// Build options
+	answered := make(map[string]bool)
+	for _, msg := range history[last+1:] {
+		if msg.Role == llm.RoleTool {
+			answered[msg.ToolID] = true
+		}
+	}
+	for _, msg := range input {
+		if msg.Role == llm.RoleTool {
+			answered[msg.ToolID] = true
+		}
+	}
+
+	var closing []llm.Message
+	for _, tc := range history[last].ToolCalls {
+		if !answered[tc.ID] {
+			closing = append(closing, llm.ToolMessage(tc.ID, pendingToolCallResult))
+		}
+	}
+	if len(closing) == 0 {
+		return input
+	}
+	return append(closing, input...)
+}
+
+// buildOptions builds the llm.Options for a single LLM call of this runner.
+func (r *AgentRunner) buildOptions(cfg *runConfig) []llm.Option {
 	opts := make([]llm.Option, 0)
 
 	if r.providerName != "" {
@@ -254,18 +678,25 @@ func (r *AgentRunner) RunWithMessages(ctx context.Context, messages []llm.Messag
 	if r.maxTokens != nil {
 		opts = append(opts, llm.WithMaxTokens(*r.maxTokens))
 	}
+	if userID := r.context.UserID(); userID != "" {
+		opts = append(opts, llm.WithUser(userID))
+	}
 
-	// Add agent's system message
-	opts = append(opts, llm.WithSystemMessage(r.agent.ToSystemMessage()))
-
-	// Add extra system message from run options (if any)
+	// Add agent's system message, and the extra system message from run
+	// options (if any) as a layer over it
+	opts = append(opts, r.agent.ToOption())
 	if cfg.extraSystemMessage != "" {
-		opts = append(opts, llm.WithSystemMessage(cfg.extraSystemMessage))
+		opts = append(opts, llm.WithSystemSection(llm.SystemSection{
+			Name:     "run",
+			Text:     cfg.extraSystemMessage,
+			Order:    llm.SystemOrderRun,
+			Priority: llm.SystemPriorityRequired,
+		}))
 	}
 
-	// Add filtered tools
-	if len(r.filteredTools) > 0 {
-		opts = append(opts, llm.WithTools(r.filteredTools...))
+	// Add the tools available to this run
+	if len(cfg.tools) > 0 {
+		opts = append(opts, llm.WithTools(cfg.tools...))
 	}
 
 	// Add runner-level extra LLM options
@@ -274,23 +705,7 @@ func (r *AgentRunner) RunWithMessages(ctx context.Context, messages []llm.Messag
 	// Add run-level extra LLM options
 	opts = append(opts, cfg.extraLLMOpts...)
 
-	// Build full message list: existing history + provided messages
-	history := r.context.History()
-	fullMessages := make([]llm.Message, 0, len(history)+len(messages))
-	fullMessages = append(fullMessages, history...)
-	fullMessages = append(fullMessages, messages...)
-
-	// Make the LLM call
-	resp, err := llm.CallMessages(ctx, fullMessages, opts...)
-	if err != nil {
-		return resp, err
-	}
-
-	// Add provided messages and response to context history
-	r.context.AddMessages(messages...)
-	r.context.AddMessage(llm.AssistantMessage(resp.Text()))
-
-	return resp, nil
+	return opts
 }
 
 // Agent returns the underlying agent.
@@ -319,9 +734,15 @@ func (r *AgentRunner) ClearHistory() {
 }
 
 // ToOption converts an Agent to an llm.Option.
-// This adds the agent's system message to the LLM call.
+// This adds the agent's system message to the LLM call as a required section
+// of the base layer of the system prompt.
 func (a *Agent) ToOption() llm.Option {
-	return llm.WithSystemMessage(a.ToSystemMessage())
+	return llm.WithSystemSection(llm.SystemSection{
+		Name:     "agent:" + a.Name,
+		Text:     a.ToSystemMessage(),
+		Order:    llm.SystemOrderBase,
+		Priority: llm.SystemPriorityRequired,
+	})
 }
 
 // FilterTools filters a list of tools to only include those allowed by this agent.
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ErrTokenBudgetExceeded is returned when an agent run uses more tokens than its budget.
+var ErrTokenBudgetExceeded = errors.New("agent exceeded token budget")
+
+// ErrDeadlineExceeded is returned when an agent run takes longer than its deadline.
+var ErrDeadlineExceeded = errors.New("agent exceeded deadline")
+
+// BudgetKind identifies the limit that stopped an agent run.
+type BudgetKind string
+
+const (
+	// BudgetTurns is the maximum number of LLM calls per run.
+	BudgetTurns BudgetKind = "turns"
+	// BudgetTokens is the maximum number of tokens used per run.
+	BudgetTokens BudgetKind = "tokens"
+	// BudgetDeadline is the maximum wall-clock time per run.
+	BudgetDeadline BudgetKind = "deadline"
+)
+
+// BudgetExceededError is returned when an agent run exceeds one of its limits.
+// It carries the messages produced by the run before it was stopped.
+// Use errors.Is with ErrMaxTurnsExceeded, ErrTokenBudgetExceeded, or
+// ErrDeadlineExceeded to check which limit was exceeded.
+type BudgetExceededError struct {
+	Kind       BudgetKind
+	Turns      int           // LLM calls made during the run
+	TokensUsed int           // Total tokens reported by the provider during the run
+	Elapsed    time.Duration // Wall-clock time of the run
+	Transcript []llm.Message // Messages produced during the run, including the input
+}
+
+// Error implements the error interface.
+func (e *BudgetExceededError) Error() string {
+	switch e.Kind {
+	case BudgetTurns:
+		return fmt.Sprintf("%v (%d)", ErrMaxTurnsExceeded, e.Turns)
+	case BudgetTokens:
+		return fmt.Sprintf("%v (%d tokens used)", ErrTokenBudgetExceeded, e.TokensUsed)
+	default:
+		return fmt.Sprintf("%v (after %s)", ErrDeadlineExceeded, e.Elapsed.Round(time.Millisecond))
+	}
+}
+
+// Unwrap returns the sentinel error for the exceeded limit.
+func (e *BudgetExceededError) Unwrap() error {
+	switch e.Kind {
+	case BudgetTurns:
+		return ErrMaxTurnsExceeded
+	case BudgetTokens:
+		return ErrTokenBudgetExceeded
+	default:
+		return ErrDeadlineExceeded
+	}
+}
+
+// WithAgentTokenBudget limits the total tokens (prompt + completion, as reported
+// by the provider) used by a single Run(). The limit is checked after each LLM call.
+func WithAgentTokenBudget(n int) AgentOption {
+	return func(r *AgentRunner) {
+		r.tokenBudget = n
+	}
+}
+
+// WithAgentDeadline limits the wall-clock time of a single Run().
+// The run's context is cancelled when the deadline passes.
+func WithAgentDeadline(d time.Duration) AgentOption {
+	return func(r *AgentRunner) {
+		r.deadline = d
+	}
+}
+
+// runBudget tracks the limits of a single agent run.
+type runBudget struct {
+	runner *AgentRunner
+	parent context.Context
+	ctx    context.Context
+	start  time.Time
+	turns  int
+	tokens int
+	cancel context.CancelFunc
+}
+
+// newRunBudget starts tracking a run. The returned budget's ctx carries the
+// run deadline, if any; call stop when the run ends.
+func (r *AgentRunner) newRunBudget(ctx context.Context) *runBudget {
+	b := &runBudget{runner: r, parent: ctx, ctx: ctx, start: time.Now(), cancel: func() {}}
+	if r.deadline > 0 {
+		b.ctx, b.cancel = context.WithTimeout(ctx, r.deadline)
+	}
+	return b
+}
+
+// stop releases the resources of the budget.
+func (b *runBudget) stop() {
+	b.cancel()
+}
+
+// record accounts for a completed LLM call.
+func (b *runBudget) record(usage llm.Usage) {
+	b.turns++
+	b.tokens += usage.TotalTokens
+}
+
+// exceeded returns the kind of limit the run has exceeded, if any.
+// It is checked before tool calls are executed for another turn.
+func (b *runBudget) exceeded() (BudgetKind, bool) {
+	r := b.runner
+	switch {
+	case r.tokenBudget > 0 && b.tokens > r.tokenBudget:
+		return BudgetTokens, true
+	case b.deadlineExceeded():
+		return BudgetDeadline, true
+	case b.turns >= r.maxTurns:
+		return BudgetTurns, true
+	}
+	return "", false
+}
+
+// deadlineExceeded reports whether the run deadline (not the caller's context) expired.
+func (b *runBudget) deadlineExceeded() bool {
+	return b.runner.deadline > 0 && b.ctx.Err() != nil && b.parent.Err() == nil
+}
+
+// error returns a BudgetExceededError for kind with a copy of transcript.
+func (b *runBudget) error(kind BudgetKind, transcript []llm.Message) *BudgetExceededError {
+	return &BudgetExceededError{
+		Kind:       kind,
+		Turns:      b.turns,
+		TokensUsed: b.tokens,
+		Elapsed:    time.Since(b.start),
+		Transcript: copyMessages(transcript),
+	}
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+func TestAgentRunner_Budget_MaxTurns(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"1"}`),
+		toolCallResponse("call_2", "echo", `{"text":"2"}`),
+	)
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentMaxTurns(2),
+	)
+
+	_, err := runner.Run(context.Background(), "loop")
+	require.ErrorIs(t, err, ErrMaxTurnsExceeded)
+
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, BudgetTurns, budgetErr.Kind)
+	assert.Equal(t, 2, budgetErr.Turns)
+
+	// user, assistant, tool, assistant
+	require.Len(t, budgetErr.Transcript, 4)
+	assert.Equal(t, llm.RoleUser, budgetErr.Transcript[0].Role)
+	assert.Equal(t, "echo: 1", budgetErr.Transcript[2].Content)
+	assert.Equal(t, 4, runner.Context().HistoryLen())
+}
+
+func TestAgentRunner_Budget_Tokens(t *testing.T) {
+	resp := toolCallResponse("call_1", "echo", `{"text":"1"}`)
+	resp.Usage = provider.Usage{PromptTokens: 80, CompletionTokens: 40, TotalTokens: 120}
+	_, name := registerScripted(t, resp, textResponse("never reached"))
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentTokenBudget(100),
+	)
+
+	_, err := runner.Run(context.Background(), "loop")
+	require.ErrorIs(t, err, ErrTokenBudgetExceeded)
+
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, 120, budgetErr.TokensUsed)
+	assert.Len(t, budgetErr.Transcript, 2)
+}
+
+func TestAgentRunner_Budget_Deadline(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "slow", `{}`),
+		textResponse("never reached"),
+	)
+
+	slow := llm.MustNewTool("slow", "Sleep",
+		func(ctx context.Context, in struct{}) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+
+	agent := &Agent{Name: "helper", Tools: []string{"slow"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(slow),
+		WithAgentDeadline(20*time.Millisecond),
+	)
+
+	_, err := runner.Run(context.Background(), "wait")
+	require.ErrorIs(t, err, ErrDeadlineExceeded)
+
+	var budgetErr *BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, BudgetDeadline, budgetErr.Kind)
+	assert.Len(t, budgetErr.Transcript, 3)
+}
@@ -1,7 +1,9 @@
 package plugin
 
 import (
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/i2y/bucephalus/llm"
 )
@@ -9,31 +11,163 @@ import (
 // AgentContext maintains conversation history and state for an agent.
 // It provides thread-safe access to conversation history and arbitrary state storage.
 // Contexts can have parent contexts for inheritance (e.g., sub-agents inheriting from parent).
+//
+// A context also carries session metadata (session ID, user ID, creation
+// time, and tags) for servers that manage many sessions; see ContextRegistry.
 type AgentContext struct {
 	history []llm.Message  // Conversation history
+	times   []time.Time    // When each message of history was added
 	state   map[string]any // Arbitrary state storage
 	parent  *AgentContext  // Parent context (for inheritance)
 	mu      sync.RWMutex   // Thread safety
+
+	listeners stateListeners // State change callbacks
+
+	// Session metadata
+	sessionID string
+	userID    string
+	createdAt time.Time
+	tags      []string
 }
 
-// NewAgentContext creates a new empty context.
-func NewAgentContext() *AgentContext {
-	return &AgentContext{
-		history: make([]llm.Message, 0),
-		state:   make(map[string]any),
+// AgentContextOption configures a new AgentContext.
+type AgentContextOption func(*AgentContext)
+
+// WithContextSessionID sets the session ID of the context.
+func WithContextSessionID(id string) AgentContextOption {
+	return func(c *AgentContext) {
+		c.sessionID = id
+	}
+}
+
+// WithContextUserID sets the ID of the user the context belongs to. Runners
+// with the context pass it to every call with llm.WithUser.
+func WithContextUserID(id string) AgentContextOption {
+	return func(c *AgentContext) {
+		c.userID = id
+	}
+}
+
+// WithContextTags tags the context, e.g. with a tenant or a channel.
+func WithContextTags(tags ...string) AgentContextOption {
+	return func(c *AgentContext) {
+		c.addTags(tags)
+	}
+}
+
+// NewAgentContext creates a new empty context, created now.
+//
+// Example:
+//
+//	agentCtx := plugin.NewAgentContext(
+//	    plugin.WithContextSessionID(sessionID),
+//	    plugin.WithContextUserID(userID),
+//	    plugin.WithContextTags("tenant:acme", "web"),
+//	)
+func NewAgentContext(opts ...AgentContextOption) *AgentContext {
+	c := &AgentContext{
+		history:   make([]llm.Message, 0),
+		state:     make(map[string]any),
+		createdAt: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // NewChildContext creates a child context that inherits state from this context.
 // The child has its own history but can access parent's state through GetState.
+// It has the session ID, user ID, and tags of this context, and is created now.
 func (c *AgentContext) NewChildContext() *AgentContext {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return &AgentContext{
-		history: make([]llm.Message, 0),
-		state:   make(map[string]any),
-		parent:  c,
+		history:   make([]llm.Message, 0),
+		state:     make(map[string]any),
+		parent:    c,
+		sessionID: c.sessionID,
+		userID:    c.userID,
+		createdAt: time.Now(),
+		tags:      slices.Clone(c.tags),
+	}
+}
+
+// SessionID returns the session ID of the context, or "" if it has none.
+func (c *AgentContext) SessionID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionID
+}
+
+// SetSessionID sets the session ID of the context. Change it before
+// registering the context with a ContextRegistry, which indexes it by ID.
+func (c *AgentContext) SetSessionID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionID = id
+}
+
+// UserID returns the ID of the user the context belongs to, or "".
+func (c *AgentContext) UserID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.userID
+}
+
+// SetUserID sets the ID of the user the context belongs to.
+func (c *AgentContext) SetUserID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.userID = id
+}
+
+// CreatedAt returns when the context was created.
+func (c *AgentContext) CreatedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.createdAt
+}
+
+// Tags returns a copy of the tags of the context, in the order they were added.
+func (c *AgentContext) Tags() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.tags)
+}
+
+// HasTag reports whether the context has the tag.
+func (c *AgentContext) HasTag(tag string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Contains(c.tags, tag)
+}
+
+// AddTags adds tags to the context, skipping the ones it already has.
+func (c *AgentContext) AddTags(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addTags(tags)
+}
+
+// addTags adds tags without locking.
+func (c *AgentContext) addTags(tags []string) {
+	for _, tag := range tags {
+		if !slices.Contains(c.tags, tag) {
+			c.tags = append(c.tags, tag)
+		}
 	}
 }
 
+// RemoveTags removes tags from the context.
+func (c *AgentContext) RemoveTags(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tags = slices.DeleteFunc(c.tags, func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+}
+
 // History returns a copy of the conversation history.
 func (c *AgentContext) History() []llm.Message {
 	c.mu.RLock()
@@ -52,25 +186,32 @@ func (c *AgentContext) HistoryLen() int {
 	return len(c.history)
 }
 
-// AddMessage adds a message to the conversation history.
+// AddMessage adds a message to the conversation history, timestamped now.
 func (c *AgentContext) AddMessage(msg llm.Message) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.history = append(c.history, msg)
+	c.AddMessages(msg)
 }
 
-// AddMessages adds multiple messages to the conversation history.
+// AddMessages adds multiple messages to the conversation history,
+// timestamped now.
 func (c *AgentContext) AddMessages(msgs ...llm.Message) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.syncTimes()
+	now := time.Now()
 	c.history = append(c.history, msgs...)
+	for range msgs {
+		c.times = append(c.times, now)
+	}
 }
 
 // SetState stores a value in the context with the given key.
 func (c *AgentContext) SetState(key string, value any) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	old := c.state[key]
 	c.state[key] = value
+	c.mu.Unlock()
+
+	c.listeners.notify(StateChange{Key: key, Old: old, New: value})
 }
 
 // GetState retrieves a value from the context.
@@ -97,8 +238,20 @@ func (c *AgentContext) GetState(key string) (any, bool) {
 // Note: This only removes from this context, not from parent contexts.
 func (c *AgentContext) DeleteState(key string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	old, ok := c.state[key]
 	delete(c.state, key)
+	c.mu.Unlock()
+
+	if ok {
+		c.listeners.notify(StateChange{Key: key, Old: old, Deleted: true})
+	}
+}
+
+// OnStateChange registers fn to be called after each change to this context's state.
+// fn is called synchronously from the goroutine making the change.
+// It returns a function that unregisters fn.
+func (c *AgentContext) OnStateChange(fn func(StateChange)) func() {
+	return c.listeners.add(fn)
 }
 
 // HasState checks if a key exists in this context or its parents.
@@ -123,9 +276,13 @@ func (c *AgentContext) StateKeys() []string {
 // Note: This does not affect the parent context.
 func (c *AgentContext) Clear() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	old := c.state
 	c.history = make([]llm.Message, 0)
+	c.times = nil
 	c.state = make(map[string]any)
+	c.mu.Unlock()
+
+	c.notifyCleared(old)
 }
 
 // ClearHistory resets only the conversation history, keeping state.
@@ -133,13 +290,24 @@ func (c *AgentContext) ClearHistory() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.history = make([]llm.Message, 0)
+	c.times = nil
 }
 
 // ClearState resets only the state, keeping conversation history.
 func (c *AgentContext) ClearState() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	old := c.state
 	c.state = make(map[string]any)
+	c.mu.Unlock()
+
+	c.notifyCleared(old)
+}
+
+// notifyCleared notifies listeners that the keys of old were deleted.
+func (c *AgentContext) notifyCleared(old map[string]any) {
+	for k, v := range old {
+		c.listeners.notify(StateChange{Key: k, Old: v, Deleted: true})
+	}
 }
 
 // Parent returns the parent context, or nil if this is a root context.
@@ -156,19 +324,24 @@ func (c *AgentContext) SetParent(parent *AgentContext) {
 	c.parent = parent
 }
 
-// Clone creates a deep copy of this context including history and state.
-// The clone does not share the same parent reference.
+// Clone creates a deep copy of this context including history, state, and
+// session metadata. The clone does not share the same parent reference.
 func (c *AgentContext) Clone() *AgentContext {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	clone := &AgentContext{
-		history: make([]llm.Message, len(c.history)),
-		state:   make(map[string]any, len(c.state)),
-		parent:  c.parent, // Share parent reference
+		history:   make([]llm.Message, len(c.history)),
+		state:     make(map[string]any, len(c.state)),
+		parent:    c.parent, // Share parent reference
+		sessionID: c.sessionID,
+		userID:    c.userID,
+		createdAt: c.createdAt,
+		tags:      slices.Clone(c.tags),
 	}
 
 	copy(clone.history, c.history)
+	clone.times = slices.Clone(c.times)
 	for k, v := range c.state {
 		clone.state[k] = v
 	}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateSession is returned by ContextRegistry.Register when a context
+// with the same session ID is already registered.
+var ErrDuplicateSession = errors.New("session already registered")
+
+// ContextRegistry indexes live agent contexts by session ID, for servers
+// that manage many concurrent sessions. It is safe for concurrent use.
+//
+// Example:
+//
+//	registry := plugin.NewContextRegistry()
+//
+//	// In a request handler
+//	agentCtx, _ := registry.GetOrCreate(sessionID, func(id string) *plugin.AgentContext {
+//	    return plugin.NewAgentContext(plugin.WithContextUserID(userID), plugin.WithContextTags("web"))
+//	})
+//	runner := agent.NewRunner(plugin.WithAgentContext(agentCtx), ...)
+//
+//	// Periodically drop sessions older than a day
+//	registry.RemoveCreatedBefore(time.Now().Add(-24 * time.Hour))
+type ContextRegistry struct {
+	mu       sync.RWMutex
+	contexts map[string]*AgentContext
+}
+
+// NewContextRegistry creates an empty registry.
+func NewContextRegistry() *ContextRegistry {
+	return &ContextRegistry{contexts: make(map[string]*AgentContext)}
+}
+
+// Register adds c under its session ID. It fails if c has no session ID or
+// if another context is registered under it (ErrDuplicateSession).
+func (r *ContextRegistry) Register(c *AgentContext) error {
+	id := c.SessionID()
+	if id == "" {
+		return errors.New("registering agent context: no session ID")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.contexts[id]; ok && existing != c {
+		return fmt.Errorf("registering agent context: %w: %s", ErrDuplicateSession, id)
+	}
+	r.contexts[id] = c
+	return nil
+}
+
+// Get returns the context registered under the session ID.
+func (r *ContextRegistry) Get(sessionID string) (*AgentContext, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.contexts[sessionID]
+	return c, ok
+}
+
+// GetOrCreate returns the context registered under the session ID, or
+// registers and returns the context that create makes for it. created
+// reports whether create was called. The session ID of the new context is
+// set to sessionID.
+func (r *ContextRegistry) GetOrCreate(sessionID string, create func(sessionID string) *AgentContext) (c *AgentContext, created bool) {
+	if c, ok := r.Get(sessionID); ok {
+		return c, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.contexts[sessionID]; ok {
+		return c, false
+	}
+	c = create(sessionID)
+	c.SetSessionID(sessionID)
+	r.contexts[sessionID] = c
+	return c, true
+}
+
+// Remove unregisters the context under the session ID, returning it.
+func (r *ContextRegistry) Remove(sessionID string) (*AgentContext, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.contexts[sessionID]
+	delete(r.contexts, sessionID)
+	return c, ok
+}
+
+// RemoveCreatedBefore unregisters the contexts created before t and
+// returns how many were removed.
+func (r *ContextRegistry) RemoveCreatedBefore(t time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for id, c := range r.contexts {
+		if c.CreatedAt().Before(t) {
+			delete(r.contexts, id)
+			n++
+		}
+	}
+	return n
+}
+
+// Len returns the number of registered contexts.
+func (r *ContextRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.contexts)
+}
+
+// Find returns the registered contexts for which match returns true,
+// oldest first. match must not call methods of the registry.
+func (r *ContextRegistry) Find(match func(*AgentContext) bool) []*AgentContext {
+	r.mu.RLock()
+	var result []*AgentContext
+	for _, c := range r.contexts {
+		if match(c) {
+			result = append(result, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		ti, tj := result[i].CreatedAt(), result[j].CreatedAt()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return result[i].SessionID() < result[j].SessionID()
+	})
+	return result
+}
+
+// ByUser returns the registered contexts of the user, oldest first.
+func (r *ContextRegistry) ByUser(userID string) []*AgentContext {
+	return r.Find(func(c *AgentContext) bool { return c.UserID() == userID })
+}
+
+// ByTag returns the registered contexts with the tag, oldest first.
+func (r *ContextRegistry) ByTag(tag string) []*AgentContext {
+	return r.Find(func(c *AgentContext) bool { return c.HasTag(tag) })
+}
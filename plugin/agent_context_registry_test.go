@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentContext_Metadata(t *testing.T) {
+	before := time.Now()
+	c := NewAgentContext(
+		WithContextSessionID("s1"),
+		WithContextUserID("u1"),
+		WithContextTags("tenant:acme", "web", "web"),
+	)
+	assert.Equal(t, "s1", c.SessionID())
+	assert.Equal(t, "u1", c.UserID())
+	assert.False(t, c.CreatedAt().Before(before))
+	assert.Equal(t, []string{"tenant:acme", "web"}, c.Tags())
+
+	c.AddTags("beta", "web")
+	c.RemoveTags("tenant:acme")
+	assert.Equal(t, []string{"web", "beta"}, c.Tags())
+	assert.True(t, c.HasTag("beta"))
+	assert.False(t, c.HasTag("tenant:acme"))
+
+	// Children and clones keep the metadata
+	child := c.NewChildContext()
+	assert.Equal(t, "s1", child.SessionID())
+	assert.Equal(t, "u1", child.UserID())
+	assert.Equal(t, c.Tags(), child.Tags())
+	assert.Equal(t, c.CreatedAt(), c.Clone().CreatedAt())
+
+	// The metadata survives a JSON round trip
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	restored := NewAgentContext()
+	require.NoError(t, json.Unmarshal(data, restored))
+	assert.Equal(t, "s1", restored.SessionID())
+	assert.Equal(t, "u1", restored.UserID())
+	assert.Equal(t, []string{"web", "beta"}, restored.Tags())
+	assert.True(t, c.CreatedAt().Equal(restored.CreatedAt()))
+}
+
+func TestContextRegistry(t *testing.T) {
+	registry := NewContextRegistry()
+	old := NewAgentContext(WithContextSessionID("old"), WithContextUserID("alice"), WithContextTags("web"))
+	old.createdAt = time.Now().Add(-48 * time.Hour)
+	require.NoError(t, registry.Register(old))
+	require.NoError(t, registry.Register(old))
+
+	err := registry.Register(NewAgentContext(WithContextSessionID("old")))
+	assert.ErrorIs(t, err, ErrDuplicateSession)
+	assert.Error(t, registry.Register(NewAgentContext()))
+
+	c, created := registry.GetOrCreate("new", func(id string) *AgentContext {
+		return NewAgentContext(WithContextUserID("alice"), WithContextTags("slack"))
+	})
+	assert.True(t, created)
+	assert.Equal(t, "new", c.SessionID())
+	again, created := registry.GetOrCreate("new", func(id string) *AgentContext {
+		t.Fatal("unexpected create")
+		return nil
+	})
+	assert.False(t, created)
+	assert.Same(t, c, again)
+
+	got, ok := registry.Get("old")
+	require.True(t, ok)
+	assert.Same(t, old, got)
+	assert.Equal(t, 2, registry.Len())
+	assert.Equal(t, []*AgentContext{old, c}, registry.ByUser("alice"))
+	assert.Equal(t, []*AgentContext{c}, registry.ByTag("slack"))
+	assert.Empty(t, registry.ByUser("bob"))
+
+	assert.Equal(t, 1, registry.RemoveCreatedBefore(time.Now().Add(-time.Hour)))
+	_, ok = registry.Get("old")
+	assert.False(t, ok)
+
+	removed, ok := registry.Remove("new")
+	assert.True(t, ok)
+	assert.Same(t, c, removed)
+	assert.Equal(t, 0, registry.Len())
+}
+
+func TestContextRegistry_Concurrent(t *testing.T) {
+	registry := NewContextRegistry()
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := fmt.Sprintf("s%d", i%10)
+			registry.GetOrCreate(id, func(string) *AgentContext { return NewAgentContext() })
+			registry.ByTag("none")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, registry.Len())
+}
+
+func TestAgentRunner_ContextUser(t *testing.T) {
+	store, err := NewFileContextStore(t.TempDir())
+	require.NoError(t, err)
+	mock, name := registerScripted(t, textResponse("hi"))
+	agentCtx := NewAgentContext(WithContextUserID("alice"))
+	runner := (&Agent{Name: "assistant"}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentContext(agentCtx),
+		WithAgentContextStore(store, "s1"),
+	)
+	_, err = runner.Run(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", mock.requests[0].User)
+	assert.Equal(t, "s1", agentCtx.SessionID())
+}
@@ -0,0 +1,343 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ErrContextNotFound is returned by a ContextStore when no context is saved for a session.
+var ErrContextNotFound = errors.New("agent context not found")
+
+// agentContextJSON is the serialized form of an AgentContext.
+type agentContextJSON struct {
+	History   []llm.Message  `json:"history"`
+	Times     []time.Time    `json:"times,omitempty"` // When each message was added
+	State     map[string]any `json:"state"`
+	SessionID string         `json:"session_id,omitempty"`
+	UserID    string         `json:"user_id,omitempty"`
+	CreatedAt *time.Time     `json:"created_at,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
+}
+
+// MarshalJSON serializes the context's history, state, and session metadata.
+// The parent context is not included.
+func (c *AgentContext) MarshalJSON() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	raw := agentContextJSON{
+		History:   c.history,
+		Times:     c.times,
+		State:     c.state,
+		SessionID: c.sessionID,
+		UserID:    c.userID,
+		Tags:      c.tags,
+	}
+	if !c.createdAt.IsZero() {
+		raw.CreatedAt = &c.createdAt
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON restores the context's history, state, and session metadata.
+// State values are decoded as generic JSON values (e.g. numbers become float64).
+func (c *AgentContext) UnmarshalJSON(data []byte) error {
+	var raw agentContextJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.history = raw.History
+	if c.history == nil {
+		c.history = make([]llm.Message, 0)
+	}
+	c.times = raw.Times
+	c.state = raw.State
+	if c.state == nil {
+		c.state = make(map[string]any)
+	}
+	c.sessionID = raw.SessionID
+	c.userID = raw.UserID
+	if raw.CreatedAt != nil {
+		c.createdAt = *raw.CreatedAt
+	}
+	c.tags = raw.Tags
+	return nil
+}
+
+// replaceWith replaces this context's history and state with those of other,
+// and its session metadata with the metadata that other has. The parent
+// reference is kept.
+func (c *AgentContext) replaceWith(other *AgentContext) {
+	other.mu.RLock()
+	history := copyMessages(other.history)
+	times := other.syncedTimes()
+	state := make(map[string]any, len(other.state))
+	for k, v := range other.state {
+		state[k] = v
+	}
+	sessionID, userID, createdAt, tags := other.sessionID, other.userID, other.createdAt, slices.Clone(other.tags)
+	other.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = history
+	c.times = times
+	c.state = state
+	if sessionID != "" {
+		c.sessionID = sessionID
+	}
+	if userID != "" {
+		c.userID = userID
+	}
+	if !createdAt.IsZero() {
+		c.createdAt = createdAt
+	}
+	if len(tags) > 0 {
+		c.tags = tags
+	}
+}
+
+// ContextStore persists agent contexts by session ID.
+type ContextStore interface {
+	// Load returns the context saved for the session, or ErrContextNotFound.
+	Load(ctx context.Context, sessionID string) (*AgentContext, error)
+
+	// Save stores the context for the session, replacing any previous value.
+	Save(ctx context.Context, sessionID string, agentCtx *AgentContext) error
+
+	// Delete removes the context saved for the session.
+	Delete(ctx context.Context, sessionID string) error
+}
+
+// FileContextStore stores each session as a JSON file in a directory.
+type FileContextStore struct {
+	dir string
+}
+
+// NewFileContextStore creates a store that keeps contexts in dir as <sessionID>.json.
+// The directory is created if it does not exist.
+func NewFileContextStore(dir string) (*FileContextStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating context store directory: %w", err)
+	}
+	return &FileContextStore{dir: dir}, nil
+}
+
+// Load implements ContextStore.
+func (s *FileContextStore) Load(ctx context.Context, sessionID string) (*AgentContext, error) {
+	path, err := s.path(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrContextNotFound
+		}
+		return nil, fmt.Errorf("reading context: %w", err)
+	}
+
+	agentCtx := NewAgentContext()
+	if err := json.Unmarshal(data, agentCtx); err != nil {
+		return nil, fmt.Errorf("parsing context: %w", err)
+	}
+	return agentCtx, nil
+}
+
+// Save implements ContextStore.
+// The file is written atomically via a temporary file and rename.
+func (s *FileContextStore) Save(ctx context.Context, sessionID string, agentCtx *AgentContext) error {
+	path, err := s.path(sessionID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(agentCtx)
+	if err != nil {
+		return fmt.Errorf("marshaling context: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing context: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing context: %w", err)
+	}
+	return nil
+}
+
+// Delete implements ContextStore.
+func (s *FileContextStore) Delete(ctx context.Context, sessionID string) error {
+	path, err := s.path(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("deleting context: %w", err)
+	}
+	return nil
+}
+
+// path returns the file path for a session, rejecting IDs that could escape the directory.
+func (s *FileContextStore) path(sessionID string) (string, error) {
+	if sessionID == "" || strings.ContainsAny(sessionID, `/\`) || sessionID == "." || sessionID == ".." {
+		return "", fmt.Errorf("invalid session ID: %q", sessionID)
+	}
+	return filepath.Join(s.dir, sessionID+".json"), nil
+}
+
+// SQLiteContextStore stores contexts in a SQLite table.
+// It uses database/sql, so any SQLite driver can be used to open the database.
+type SQLiteContextStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLiteContextStore creates a store backed by db, creating the table if needed.
+// If table is empty, "agent_contexts" is used.
+//
+// Example:
+//
+//	db, _ := sql.Open("sqlite3", "sessions.db") // with a SQLite driver imported
+//	store, err := plugin.NewSQLiteContextStore(ctx, db, "")
+func NewSQLiteContextStore(ctx context.Context, db *sql.DB, table string) (*SQLiteContextStore, error) {
+	if table == "" {
+		table = "agent_contexts"
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	session_id TEXT PRIMARY KEY,
+	data TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`, table)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("creating context table: %w", err)
+	}
+
+	return &SQLiteContextStore{db: db, table: table}, nil
+}
+
+// Load implements ContextStore.
+func (s *SQLiteContextStore) Load(ctx context.Context, sessionID string) (*AgentContext, error) {
+	var data string
+	query := fmt.Sprintf(`SELECT data FROM %s WHERE session_id = ?`, s.table)
+	err := s.db.QueryRowContext(ctx, query, sessionID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrContextNotFound
+		}
+		return nil, fmt.Errorf("loading context: %w", err)
+	}
+
+	agentCtx := NewAgentContext()
+	if err := json.Unmarshal([]byte(data), agentCtx); err != nil {
+		return nil, fmt.Errorf("parsing context: %w", err)
+	}
+	return agentCtx, nil
+}
+
+// Save implements ContextStore.
+func (s *SQLiteContextStore) Save(ctx context.Context, sessionID string, agentCtx *AgentContext) error {
+	data, err := json.Marshal(agentCtx)
+	if err != nil {
+		return fmt.Errorf("marshaling context: %w", err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (session_id, data, updated_at) VALUES (?, ?, ?)
+ON CONFLICT(session_id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, sessionID, string(data), time.Now().UTC()); err != nil {
+		return fmt.Errorf("saving context: %w", err)
+	}
+	return nil
+}
+
+// Delete implements ContextStore.
+func (s *SQLiteContextStore) Delete(ctx context.Context, sessionID string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE session_id = ?`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, sessionID); err != nil {
+		return fmt.Errorf("deleting context: %w", err)
+	}
+	return nil
+}
+
+// RedisClient is the subset of Redis commands used by RedisContextStore.
+// Adapt the Redis client library of your choice (e.g. go-redis) to this interface.
+type RedisClient interface {
+	// Get returns the value for key; found is false if the key does not exist.
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+
+	// Set stores value for key. A zero ttl means no expiration.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Del removes key.
+	Del(ctx context.Context, key string) error
+}
+
+// RedisContextStore stores contexts as JSON values in Redis.
+type RedisContextStore struct {
+	client RedisClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisContextStore creates a store that keeps contexts under <prefix><sessionID>.
+// A zero ttl keeps contexts until they are deleted.
+func NewRedisContextStore(client RedisClient, prefix string, ttl time.Duration) *RedisContextStore {
+	if prefix == "" {
+		prefix = "bucephalus:agent_context:"
+	}
+	return &RedisContextStore{client: client, prefix: prefix, ttl: ttl}
+}
+
+// Load implements ContextStore.
+func (s *RedisContextStore) Load(ctx context.Context, sessionID string) (*AgentContext, error) {
+	data, found, err := s.client.Get(ctx, s.prefix+sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("loading context: %w", err)
+	}
+	if !found {
+		return nil, ErrContextNotFound
+	}
+
+	agentCtx := NewAgentContext()
+	if err := json.Unmarshal(data, agentCtx); err != nil {
+		return nil, fmt.Errorf("parsing context: %w", err)
+	}
+	return agentCtx, nil
+}
+
+// Save implements ContextStore.
+func (s *RedisContextStore) Save(ctx context.Context, sessionID string, agentCtx *AgentContext) error {
+	data, err := json.Marshal(agentCtx)
+	if err != nil {
+		return fmt.Errorf("marshaling context: %w", err)
+	}
+	if err := s.client.Set(ctx, s.prefix+sessionID, data, s.ttl); err != nil {
+		return fmt.Errorf("saving context: %w", err)
+	}
+	return nil
+}
+
+// Delete implements ContextStore.
+func (s *RedisContextStore) Delete(ctx context.Context, sessionID string) error {
+	if err := s.client.Del(ctx, s.prefix+sessionID); err != nil {
+		return fmt.Errorf("deleting context: %w", err)
+	}
+	return nil
+}
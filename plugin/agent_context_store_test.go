@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestAgentContext_JSONRoundTrip(t *testing.T) {
+	c := NewAgentContext()
+	c.AddMessage(llm.UserMessage("hello"))
+	c.AddMessage(llm.AssistantMessageWithToolCalls("", []llm.ToolCall{{ID: "1", Name: "echo", Arguments: `{}`}}))
+	c.SetState("user", "alice")
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	restored := NewAgentContext()
+	require.NoError(t, json.Unmarshal(data, restored))
+
+	assert.Equal(t, c.History(), restored.History())
+	v, ok := restored.GetState("user")
+	require.True(t, ok)
+	assert.Equal(t, "alice", v)
+}
+
+func TestFileContextStore(t *testing.T) {
+	store, err := NewFileContextStore(t.TempDir())
+	require.NoError(t, err)
+	testContextStore(t, store)
+
+	assert.Error(t, store.Save(context.Background(), "../escape", NewAgentContext()))
+}
+
+func TestSQLiteContextStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sessions.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewSQLiteContextStore(ctx, db, "")
+	require.NoError(t, err)
+	testContextStore(t, store)
+
+	// Saving again replaces the stored context
+	c := NewAgentContext()
+	c.AddMessage(llm.UserMessage("first"))
+	require.NoError(t, store.Save(ctx, "session", c))
+	c.AddMessage(llm.AssistantMessage("second"))
+	require.NoError(t, store.Save(ctx, "session", c))
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT COUNT(*) FROM agent_contexts`).Scan(&count))
+	assert.Equal(t, 1, count)
+	loaded, err := store.Load(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, c.History(), loaded.History())
+
+	// Creating the table again keeps the stored contexts
+	store, err = NewSQLiteContextStore(ctx, db, "")
+	require.NoError(t, err)
+	_, err = store.Load(ctx, "session")
+	assert.NoError(t, err)
+}
+
+// fakeRedis is an in-memory RedisClient that records the TTL of each key.
+type fakeRedis struct {
+	values map[string][]byte
+	ttls   map[string]time.Duration
+	err    error // Returned by every command if set
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if r.err != nil {
+		return nil, false, r.err
+	}
+	value, ok := r.values[key]
+	return value, ok, nil
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.values[key] = value
+	r.ttls[key] = ttl
+	return nil
+}
+
+func (r *fakeRedis) Del(ctx context.Context, key string) error {
+	if r.err != nil {
+		return r.err
+	}
+	delete(r.values, key)
+	delete(r.ttls, key)
+	return nil
+}
+
+// expire removes key as Redis does when its TTL runs out.
+func (r *fakeRedis) expire(key string) {
+	delete(r.values, key)
+	delete(r.ttls, key)
+}
+
+func TestRedisContextStore(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeRedis()
+	testContextStore(t, NewRedisContextStore(client, "", 0))
+
+	store := NewRedisContextStore(client, "app:", 30*time.Minute)
+	c := NewAgentContext()
+	c.AddMessage(llm.UserMessage("hello"))
+	require.NoError(t, store.Save(ctx, "session", c))
+	assert.Equal(t, 30*time.Minute, client.ttls["app:session"])
+
+	// An expired context is not found
+	client.expire("app:session")
+	_, err := store.Load(ctx, "session")
+	assert.ErrorIs(t, err, ErrContextNotFound)
+
+	client.err = errors.New("connection refused")
+	_, err = store.Load(ctx, "session")
+	assert.EqualError(t, err, "loading context: connection refused")
+	assert.EqualError(t, store.Save(ctx, "session", c), "saving context: connection refused")
+	assert.EqualError(t, store.Delete(ctx, "session"), "deleting context: connection refused")
+}
+
+// testContextStore tests the behavior shared by all ContextStores.
+func testContextStore(t *testing.T, store ContextStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, "session")
+	assert.ErrorIs(t, err, ErrContextNotFound)
+
+	c := NewAgentContext()
+	c.AddMessage(llm.UserMessage("hello"))
+	c.SetState("user", "alice")
+	require.NoError(t, store.Save(ctx, "session", c))
+
+	loaded, err := store.Load(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, c.History(), loaded.History())
+	v, ok := loaded.GetState("user")
+	require.True(t, ok)
+	assert.Equal(t, "alice", v)
+
+	require.NoError(t, store.Delete(ctx, "session"))
+	require.NoError(t, store.Delete(ctx, "session"))
+	_, err = store.Load(ctx, "session")
+	assert.ErrorIs(t, err, ErrContextNotFound)
+}
+
+func TestAgentRunner_ContextStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileContextStore(t.TempDir())
+	require.NoError(t, err)
+
+	_, name := registerScripted(t, textResponse("first"), textResponse("second"))
+	agent := &Agent{Name: "helper"}
+
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentContextStore(store, "s1"),
+	)
+	_, err = runner.Run(ctx, "one")
+	require.NoError(t, err)
+
+	// A new runner for the same session continues the saved conversation
+	resumed := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentContextStore(store, "s1"),
+	)
+	_, err = resumed.Run(ctx, "two")
+	require.NoError(t, err)
+	assert.Equal(t, 4, resumed.Context().HistoryLen())
+}
@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ErrRunStopped is returned by a run that was stopped with AgentRunner.Stop.
+var ErrRunStopped = errors.New("agent run stopped")
+
+// ErrRunnerShutdown is returned by runs started after AgentRunner.Shutdown.
+var ErrRunnerShutdown = errors.New("agent runner is shut down")
+
+// runControl coordinates pausing, stopping, and shutting down runs from
+// other goroutines.
+type runControl struct {
+	mu       sync.Mutex
+	paused   bool
+	stopped  bool
+	wake     chan struct{} // Closed when a paused run should check again
+	shutdown bool          // No new runs are started
+	running  sync.WaitGroup
+}
+
+// Pause pauses the in-progress run at its next safe point: before the next
+// LLM call or tool call. An in-flight LLM call or tool call is not interrupted.
+// Pausing an idle runner pauses its next run before the first LLM call.
+func (r *AgentRunner) Pause() {
+	c := &r.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		c.paused = true
+		c.wake = make(chan struct{})
+	}
+}
+
+// Resume continues a paused run.
+func (r *AgentRunner) Resume() {
+	c := &r.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		c.paused = false
+		close(c.wake)
+	}
+}
+
+// Paused reports whether the runner is paused.
+func (r *AgentRunner) Paused() bool {
+	c := &r.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Stop asks the in-progress run to stop at its next safe point, after the
+// in-flight LLM call or tool call finishes. Stop also ends a paused run.
+//
+// The stopped run returns ErrRunStopped. The messages produced so far are
+// recorded in the context history (and saved to the context store, if any),
+// so a later Run continues the conversation. Stop has no effect on a runner
+// that is not running.
+func (r *AgentRunner) Stop() {
+	c := &r.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+	if c.paused {
+		c.paused = false
+		close(c.wake)
+	}
+}
+
+// Shutdown shuts the runner down gracefully, e.g. before a service restart:
+// it refuses new runs with ErrRunnerShutdown and waits for the in-progress
+// run, including its streams and tool executions, to finish. If ctx is done
+// first, the run is stopped as with Stop, so it ends with ErrRunStopped
+// after its in-flight LLM call or tool call, and ctx's error is returned.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := runner.Shutdown(ctx); err != nil {
+//	    log.Printf("agent shutdown: %v", err)
+//	}
+func (r *AgentRunner) Shutdown(ctx context.Context) error {
+	c := &r.control
+	c.mu.Lock()
+	c.shutdown = true
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		r.Stop()
+		return ctx.Err()
+	}
+}
+
+// beginRun registers a run, or returns ErrRunnerShutdown after Shutdown.
+// It clears a stop request left over from a previous run. The run must be
+// ended with endRun.
+func (r *AgentRunner) beginRun() error {
+	c := &r.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.shutdown {
+		return ErrRunnerShutdown
+	}
+	c.stopped = false
+	c.running.Add(1)
+	return nil
+}
+
+// endRun unregisters a run started with beginRun.
+func (r *AgentRunner) endRun() {
+	r.control.running.Done()
+}
+
+// stopRequested reports whether the run was stopped. Unlike checkpoint, it
+// does not block while the runner is paused.
+func (r *AgentRunner) stopRequested() bool {
+	c := &r.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+// checkpoint blocks while the runner is paused. It returns ErrRunStopped if
+// the run was stopped, or the context's error if ctx is done while paused.
+func (r *AgentRunner) checkpoint(ctx context.Context) error {
+	c := &r.control
+	for {
+		c.mu.Lock()
+		if c.stopped {
+			c.mu.Unlock()
+			return ErrRunStopped
+		}
+		if !c.paused {
+			c.mu.Unlock()
+			return nil
+		}
+		wake := c.wake
+		c.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// interrupt records the partial transcript of a run that ends with err, e.g.
+// because it was stopped or its LLM call failed, so the context stays
+// resumable: tool calls left without results are closed by the next run.
+func (r *AgentRunner) interrupt(ctx context.Context, transcript []llm.Message, err error) error {
+	r.context.AddMessages(transcript...)
+	if serr := r.saveContext(context.WithoutCancel(ctx)); serr != nil {
+		return errors.Join(err, serr)
+	}
+	return err
+}
@@ -0,0 +1,204 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+func TestAgentRunner_Stop(t *testing.T) {
+	twoCalls := &provider.Response{
+		ToolCalls: []provider.ToolCall{
+			{ID: "call_1", Name: "stop", Arguments: `{}`},
+			{ID: "call_2", Name: "stop", Arguments: `{}`},
+		},
+		FinishReason: provider.FinishReasonToolCalls,
+	}
+	mock, name := registerScripted(t, twoCalls, textResponse("resumed"))
+
+	var runner *AgentRunner
+	calls := 0
+	stop := llm.MustNewTool("stop", "Press the stop button",
+		func(ctx context.Context, in struct{}) (string, error) {
+			calls++
+			runner.Stop()
+			return "finished", nil
+		})
+
+	agent := &Agent{Name: "helper", Tools: []string{"stop"}}
+	runner = agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(stop),
+	)
+	ctx := context.Background()
+
+	_, err := runner.Run(ctx, "work")
+	require.ErrorIs(t, err, ErrRunStopped)
+
+	// The in-flight tool call finished; the next one was not started
+	assert.Equal(t, 1, calls)
+	history := runner.Context().History()
+	require.Len(t, history, 3)
+	assert.Equal(t, "finished", history[2].Content)
+
+	// The context is resumable: the unexecuted tool call is closed on the next run
+	resp, err := runner.Run(ctx, "continue")
+	require.NoError(t, err)
+	assert.Equal(t, "resumed", resp.Text())
+	sent := mock.requests[1].Messages
+	assert.Equal(t, "call_2", sent[len(sent)-2].ToolID)
+}
+
+func TestAgentRunner_Stop_DuringPrepare(t *testing.T) {
+	mock, name := registerScripted(t, textResponse("never"))
+
+	// Stop while the history policy runs, before the first LLM call
+	var runner *AgentRunner
+	policy := HistoryPolicyFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		runner.Stop()
+		return history, nil
+	})
+
+	agent := &Agent{Name: "helper"}
+	runner = agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentHistoryPolicy(policy),
+	)
+
+	_, err := runner.Run(context.Background(), "hi")
+	require.ErrorIs(t, err, ErrRunStopped)
+	assert.Empty(t, mock.requests)
+}
+
+func TestAgentRunner_PauseResume(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("done"),
+	)
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	var runner *AgentRunner
+	runner = agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentHooks(Hooks{
+			OnToolResult: func(ctx context.Context, call llm.ToolCall, result string) {
+				runner.Pause()
+			},
+		}),
+	)
+
+	go func() {
+		for !runner.Paused() {
+			time.Sleep(time.Millisecond)
+		}
+		runner.Resume()
+	}()
+
+	resp, err := runner.Run(context.Background(), "say hi")
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Text())
+	assert.False(t, runner.Paused())
+}
+
+func TestAgentRunner_Pause_ContextCancelled(t *testing.T) {
+	_, name := registerScripted(t, textResponse("never"))
+
+	agent := &Agent{Name: "helper"}
+	runner := agent.NewRunner(WithAgentProvider(name), WithAgentModel("test-model"))
+	runner.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := runner.Run(ctx, "hi")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, runner.Context().HistoryLen())
+}
+
+func TestAgentRunner_Shutdown(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("done"),
+	)
+
+	paused := make(chan struct{})
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	var runner *AgentRunner
+	runner = agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentHooks(Hooks{
+			OnToolResult: func(ctx context.Context, call llm.ToolCall, result string) {
+				runner.Pause()
+				close(paused)
+			},
+		}),
+	)
+	ctx := context.Background()
+
+	run := make(chan error)
+	go func() {
+		resp, err := runner.Run(ctx, "say hi")
+		if err == nil {
+			assert.Equal(t, "done", resp.Text())
+		}
+		run <- err
+	}()
+	<-paused
+
+	shutdown := make(chan error)
+	go func() { shutdown <- runner.Shutdown(ctx) }()
+
+	// The in-progress run finishes; new runs are refused
+	runner.Resume()
+	require.NoError(t, <-run)
+	require.NoError(t, <-shutdown)
+	_, err := runner.Run(ctx, "again")
+	assert.ErrorIs(t, err, ErrRunnerShutdown)
+}
+
+func TestAgentRunner_Shutdown_Deadline(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("never"),
+	)
+
+	paused := make(chan struct{})
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	var runner *AgentRunner
+	runner = agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentHooks(Hooks{
+			OnToolResult: func(ctx context.Context, call llm.ToolCall, result string) {
+				runner.Pause()
+				close(paused)
+			},
+		}),
+	)
+
+	run := make(chan error)
+	go func() {
+		_, err := runner.Run(context.Background(), "say hi")
+		run <- err
+	}()
+	<-paused
+
+	// The paused run does not finish in time and is stopped
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, runner.Shutdown(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-run, ErrRunStopped)
+}
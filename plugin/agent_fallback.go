@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+// ModelRef identifies a model of a provider.
+type ModelRef struct {
+	Provider string
+	Model    string
+}
+
+// String returns the model as "provider/model".
+func (m ModelRef) String() string {
+	return m.Provider + "/" + m.Model
+}
+
+// WithAgentFallbacks sets models to fail over to, in order, when an LLM call
+// to the runner's provider and model fails with a transient error (see
+// WithAgentFallbackOn). Every LLM call tries the primary model first, so the
+// runner returns to it once it recovers. The conversation context is kept
+// across the switch.
+//
+// Example:
+//
+//	runner := agent.NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentFallbacks(
+//	        plugin.ModelRef{Provider: "anthropic", Model: "claude-haiku-4-5"},
+//	        plugin.ModelRef{Provider: "openai", Model: "gpt-4o-mini"},
+//	    ),
+//	)
+func WithAgentFallbacks(models ...ModelRef) AgentOption {
+	return func(r *AgentRunner) {
+		r.fallbacks = append(r.fallbacks, models...)
+	}
+}
+
+// WithAgentFallbackOn sets the function deciding whether a failed LLM call
+// fails over to the next model (default: provider.IsTransient, i.e. rate
+// limits, timeouts, server errors, and transport errors).
+func WithAgentFallbackOn(fn func(err error) bool) AgentOption {
+	return func(r *AgentRunner) {
+		r.fallbackOn = fn
+	}
+}
+
+// withFallback runs call with the runner's model and, while it fails with
+// an error that allows failing over, with each fallback model in turn.
+// It returns the model that succeeded.
+func (r *AgentRunner) withFallback(ctx context.Context, opts []llm.Option, call func(opts []llm.Option) error) (ModelRef, error) {
+	model := ModelRef{Provider: r.providerName, Model: r.model}
+	err := call(opts)
+	if err == nil || len(r.fallbacks) == 0 {
+		return model, err
+	}
+
+	fallbackOn := r.fallbackOn
+	if fallbackOn == nil {
+		fallbackOn = provider.IsTransient
+	}
+
+	errs := []error{fmt.Errorf("%s: %w", model, err)}
+	for _, next := range r.fallbacks {
+		if ctx.Err() != nil || !fallbackOn(err) {
+			break
+		}
+		if r.hooks.OnFallback != nil {
+			r.hooks.OnFallback(ctx, model, next, err)
+		}
+		if logger := llm.Logger(); logger != nil {
+			logger.WarnContext(ctx, "llm fallback", "from", model.String(), "to", next.String(), "error", err)
+		}
+
+		model = next
+		err = call(append(opts[:len(opts):len(opts)], llm.WithProvider(next.Provider), llm.WithModel(next.Model)))
+		if err == nil {
+			return model, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", model, err))
+	}
+	return model, errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusError is an API error with an HTTP status code.
+type statusError int
+
+func (e statusError) Error() string   { return "api error" }
+func (e statusError) HTTPStatus() int { return int(e) }
+
+func TestAgentRunner_Fallbacks(t *testing.T) {
+	down := registerFunc(t, func(last string) (string, error) {
+		return "", statusError(529)
+	})
+	up := registerFunc(t, func(last string) (string, error) {
+		return "answer: " + last, nil
+	})
+
+	var switched []string
+	agent := &Agent{Name: "helper"}
+	runner := agent.NewRunner(
+		WithAgentProvider(down),
+		WithAgentModel("big"),
+		WithAgentFallbacks(ModelRef{Provider: down, Model: "small"}, ModelRef{Provider: up, Model: "mini"}),
+		WithAgentHooks(Hooks{
+			OnFallback: func(ctx context.Context, from, to ModelRef, err error) {
+				switched = append(switched, from.Model+"->"+to.Model)
+			},
+		}),
+	)
+
+	resp, err := runner.Run(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "answer: hello", resp.Text())
+	assert.Equal(t, []string{"big->small", "small->mini"}, switched)
+
+	trace := runner.LastTrace()
+	require.Len(t, trace.Turns, 1)
+	assert.Equal(t, up, trace.Turns[0].Provider)
+	assert.Equal(t, "mini", trace.Turns[0].Model)
+
+	// The conversation continues on the next run
+	_, err = runner.Run(context.Background(), "again")
+	require.NoError(t, err)
+	assert.Equal(t, 4, runner.Context().HistoryLen())
+}
+
+func TestAgentRunner_Fallbacks_NotTransient(t *testing.T) {
+	down := registerFunc(t, func(last string) (string, error) {
+		return "", statusError(401)
+	})
+	up := registerFunc(t, func(last string) (string, error) {
+		return "ok", nil
+	})
+
+	agent := &Agent{Name: "helper"}
+	runner := agent.NewRunner(
+		WithAgentProvider(down),
+		WithAgentModel("big"),
+		WithAgentFallbacks(ModelRef{Provider: up, Model: "mini"}),
+	)
+
+	_, err := runner.Run(context.Background(), "hello")
+	var se statusError
+	require.True(t, errors.As(err, &se))
+	assert.Equal(t, 401, int(se))
+
+	// A custom policy can fail over on any error
+	runner = agent.NewRunner(
+		WithAgentProvider(down),
+		WithAgentModel("big"),
+		WithAgentFallbacks(ModelRef{Provider: up, Model: "mini"}),
+		WithAgentFallbackOn(func(error) bool { return true }),
+	)
+	resp, err := runner.Run(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.Text())
+}
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/i2y/bucephalus/tools"
+)
+
+// FileChanges returns the net changes that the tools of the last run made
+// to files, in the order the files were first changed, with unified diffs.
+// Changes are recorded by the Write tool and by tools that use
+// tools.TrackFileChange, including those of spawned sub-agents.
+//
+// Example:
+//
+//	_, err := runner.Run(ctx, "Rename the config field")
+//	for _, change := range runner.FileChanges() {
+//	    fmt.Print(change.Diff)
+//	}
+func (r *AgentRunner) FileChanges() []tools.FileChange {
+	if r.fileChanges == nil {
+		return nil
+	}
+	return r.fileChanges.Changes()
+}
+
+// trackFileChanges starts recording the file changes of a run in a new log,
+// nested in the log of ctx if any, and returns the context of the run.
+func (r *AgentRunner) trackFileChanges(ctx context.Context) context.Context {
+	if parent := tools.FileChangeLogFrom(ctx); parent != nil {
+		r.fileChanges = parent.NewChild()
+	} else {
+		r.fileChanges = tools.NewFileChangeLog()
+	}
+	return tools.WithFileChangeLog(ctx, r.fileChanges)
+}
+
+// RevertFileChanges restores the files changed by the tools of the last run
+// to their content before the run, removing the files it created. Files
+// modified since, e.g. by the user, are left as they are, with a
+// *tools.FileConflictError each. Reverted files no longer appear in
+// FileChanges.
+//
+// Example:
+//
+//	_, err := runner.Run(ctx, "Refactor the handlers")
+//	if !approved(runner.FileChanges()) {
+//	    if err := runner.RevertFileChanges(); err != nil {
+//	        return err
+//	    }
+//	}
+func (r *AgentRunner) RevertFileChanges() error {
+	if r.fileChanges == nil {
+		return nil
+	}
+	return r.fileChanges.Revert()
+}
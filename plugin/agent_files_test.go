@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/tools"
+)
+
+func TestAgentRunner_FileChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: old\n"), 0o644))
+
+	args, err := json.Marshal(tools.WriteInput{Path: path, Content: "name: new\n"})
+	require.NoError(t, err)
+	_, name := registerScripted(t,
+		toolCallResponse("1", "write", string(args)),
+		textResponse("Renamed."),
+		textResponse("Nothing to do."),
+	)
+	runner := (&Agent{Name: "editor", Tools: []string{"write"}}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(tools.MustWrite()),
+	)
+	assert.Nil(t, runner.FileChanges())
+
+	_, err = runner.Run(context.Background(), "Rename it")
+	require.NoError(t, err)
+	changes := runner.FileChanges()
+	require.Len(t, changes, 1)
+	assert.Equal(t, path, changes[0].Path)
+	assert.Equal(t, "name: old\n", string(changes[0].Before))
+	assert.Contains(t, changes[0].Diff, "-name: old\n+name: new\n")
+
+	// Each run has its own changes
+	_, err = runner.Run(context.Background(), "Anything else?")
+	require.NoError(t, err)
+	assert.Empty(t, runner.FileChanges())
+}
+
+func TestAgentRunner_RevertFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	created := filepath.Join(dir, "util.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+	edit, err := json.Marshal(tools.WriteInput{Path: path, Content: "package broken\n"})
+	require.NoError(t, err)
+	create, err := json.Marshal(tools.WriteInput{Path: created, Content: "package main\n"})
+	require.NoError(t, err)
+	_, name := registerScripted(t,
+		toolCallResponse("1", "write", string(edit)),
+		toolCallResponse("2", "write", string(create)),
+		textResponse("Done."),
+	)
+	runner := (&Agent{Name: "editor", Tools: []string{"write"}}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(tools.MustWrite()),
+	)
+	require.NoError(t, runner.RevertFileChanges())
+
+	_, err = runner.Run(context.Background(), "Refactor")
+	require.NoError(t, err)
+	require.Len(t, runner.FileChanges(), 2)
+
+	require.NoError(t, runner.RevertFileChanges())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(data))
+	assert.NoFileExists(t, created)
+	assert.Empty(t, runner.FileChanges())
+}
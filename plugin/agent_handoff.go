@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// HandoffToolName is the name of the tool registered by WithAgentHandoff.
+const HandoffToolName = "handoff"
+
+// DefaultMaxHandoffs is the default maximum number of handoffs followed by RunWithHandoffs.
+const DefaultMaxHandoffs = 5
+
+// ErrTooManyHandoffs is returned by RunWithHandoffs when agents keep handing off.
+var ErrTooManyHandoffs = errors.New("too many agent handoffs")
+
+// Handoff transfers a conversation from one agent to another.
+type Handoff struct {
+	From      string   // Agent handing off
+	Agent     string   // Agent to continue
+	Task      string   // Task for the next agent
+	Summary   string   // Summary of the conversation so far
+	StateKeys []string // Context state keys to transfer
+}
+
+// handoffInput is the input of the handoff tool.
+type handoffInput struct {
+	Agent     string   `json:"agent" jsonschema:"required,description=Name of the agent to continue"`
+	Task      string   `json:"task" jsonschema:"required,description=What the next agent should do"`
+	Summary   string   `json:"summary" jsonschema:"required,description=Summary of the conversation so far"`
+	StateKeys []string `json:"state_keys,omitempty" jsonschema:"description=Context state keys to transfer"`
+}
+
+// WithAgentHandoff lets the agent hand off the conversation to another agent
+// of the runner's plugin (see WithAgentPlugin) by registering the "handoff" tool.
+// When the model calls the tool, the run ends and the requested handoff is
+// available from LastHandoff.
+func WithAgentHandoff() AgentOption {
+	return func(r *AgentRunner) {
+		r.handoffEnabled = true
+	}
+}
+
+// LastHandoff returns the handoff requested by the agent during the last run,
+// or nil if the agent did not hand off.
+func (r *AgentRunner) LastHandoff() *Handoff {
+	return r.handoff
+}
+
+// Handoff creates a runner for the agent named by h to continue the conversation.
+//
+// The new runner inherits this runner's provider, model, tools, limits, and hooks;
+// opts override them. Its context starts with the session metadata of this
+// runner's context, the state keys listed in h, and a message carrying
+// h.Summary; the rest of this runner's history is not transferred.
+func (r *AgentRunner) Handoff(h Handoff, opts ...AgentOption) (*AgentRunner, error) {
+	target, err := r.lookupAgent(h.Agent)
+	if err != nil {
+		return nil, fmt.Errorf("handoff: %w", err)
+	}
+
+	agentCtx := NewAgentContext(
+		WithContextSessionID(r.context.SessionID()),
+		WithContextUserID(r.context.UserID()),
+		WithContextTags(r.context.Tags()...),
+	)
+	for _, key := range h.StateKeys {
+		if v, ok := r.context.GetState(key); ok {
+			agentCtx.SetState(key, v)
+		}
+	}
+	if h.Summary != "" {
+		from := h.From
+		if from == "" {
+			from = r.agent.Name
+		}
+		agentCtx.AddMessage(llm.UserMessage(fmt.Sprintf("Handoff from agent %q.\n\nSummary of the conversation so far:\n%s", from, h.Summary)))
+	}
+
+	inherited := []AgentOption{WithAgentContext(agentCtx)}
+	if r.handoffEnabled {
+		inherited = append(inherited, WithAgentHandoff())
+	}
+	return r.newChildRunner(target, append(inherited, opts...)...), nil
+}
+
+// RunWithHandoffs runs task and follows handoffs requested by the agents,
+// up to DefaultMaxHandoffs. It returns the final response and the runner of
+// the agent that produced it.
+//
+// Example:
+//
+//	runner := p.GetAgent("triage").NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentPlugin(p),
+//	    plugin.WithAgentHandoff(),
+//	)
+//	resp, last, err := runner.RunWithHandoffs(ctx, "My invoice is wrong")
+//	fmt.Println(last.Agent().Name, resp.Text())
+func (r *AgentRunner) RunWithHandoffs(ctx context.Context, task string, runOpts ...RunOption) (llm.Response[string], *AgentRunner, error) {
+	current := r
+	resp, err := current.Run(ctx, task, runOpts...)
+	for handoffs := 0; err == nil && current.LastHandoff() != nil; handoffs++ {
+		if handoffs >= DefaultMaxHandoffs {
+			return resp, current, fmt.Errorf("%w (%d)", ErrTooManyHandoffs, DefaultMaxHandoffs)
+		}
+
+		h := *current.LastHandoff()
+		next, herr := current.Handoff(h)
+		if herr != nil {
+			return resp, current, herr
+		}
+		current = next
+
+		nextTask := h.Task
+		if nextTask == "" {
+			nextTask = task
+		}
+		resp, err = current.Run(ctx, nextTask, runOpts...)
+	}
+	return resp, current, err
+}
+
+// handoffTool returns the tool that lets the model hand off to another agent.
+func (r *AgentRunner) handoffTool() llm.Tool {
+	var sb strings.Builder
+	sb.WriteString("Hand off the conversation to another agent better suited to continue. ")
+	sb.WriteString("Your turn ends after calling this tool. Available agents:\n")
+	for _, a := range r.plugin.AgentsIndex() {
+		if a.Name == r.agent.Name {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", a.Name, a.Description))
+	}
+
+	return llm.MustNewTool(HandoffToolName, strings.TrimSpace(sb.String()),
+		func(ctx context.Context, in handoffInput) (string, error) {
+			if in.Agent == r.agent.Name {
+				return "", errors.New("cannot hand off to yourself")
+			}
+			if _, err := r.lookupAgent(in.Agent); err != nil {
+				return "", err
+			}
+			r.handoff = &Handoff{
+				From:      r.agent.Name,
+				Agent:     in.Agent,
+				Task:      in.Task,
+				Summary:   in.Summary,
+				StateKeys: in.StateKeys,
+			}
+			return fmt.Sprintf("Handing off to %s.", in.Agent), nil
+		})
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestAgentRunner_RunWithHandoffs(t *testing.T) {
+	mock, name := registerScripted(t,
+		toolCallResponse("call_1", HandoffToolName,
+			`{"agent":"billing","task":"Fix the invoice","summary":"User reports a wrong invoice","state_keys":["customer"]}`),
+		textResponse("invoice fixed"),
+	)
+
+	p := &Plugin{
+		Name: "support",
+		Agents: []Agent{
+			{Name: "triage", Description: "Routes requests"},
+			{Name: "billing", Description: "Handles invoices", Content: "You handle billing."},
+		},
+	}
+	runner := p.GetAgent("triage").NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentPlugin(p),
+		WithAgentHandoff(),
+	)
+	runner.Context().SetState("customer", "c-42")
+	runner.Context().SetState("secret", "x")
+
+	resp, last, err := runner.RunWithHandoffs(context.Background(), "My invoice is wrong")
+	require.NoError(t, err)
+	assert.Equal(t, "invoice fixed", resp.Text())
+	assert.Equal(t, "billing", last.Agent().Name)
+
+	// The triage run ended with the handoff
+	h := runner.LastHandoff()
+	require.NotNil(t, h)
+	assert.Equal(t, "triage", h.From)
+	assert.Equal(t, 3, runner.Context().HistoryLen())
+
+	// The handoff tool is offered to the model and lists the other agents
+	require.Len(t, mock.requests[0].Tools, 1)
+	assert.Contains(t, mock.requests[0].Tools[0].Description, "billing")
+
+	// Only the selected state and the summary are transferred
+	customer, ok := last.Context().GetState("customer")
+	require.True(t, ok)
+	assert.Equal(t, "c-42", customer)
+	assert.False(t, last.Context().HasState("secret"))
+
+	sent := mock.requests[1].Messages
+	assert.Contains(t, sent[0].Content, "You handle billing.")
+	assert.Contains(t, sent[1].Content, "User reports a wrong invoice")
+	assert.Equal(t, llm.UserMessage("Fix the invoice"), sent[2])
+	assert.Nil(t, last.LastHandoff())
+}
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// Hooks are lifecycle callbacks invoked during an agent run.
+// They let hosts log, meter, and veto steps of a run. All fields are optional.
+// Hooks are called synchronously from the goroutine running the agent.
+type Hooks struct {
+	// OnTurnStart is called before each turn (LLM call).
+	// Returning an error aborts the run with that error.
+	OnTurnStart func(ctx context.Context, turn int) error
+
+	// OnLLMRequest is called with the messages about to be sent to the LLM.
+	// Returning an error aborts the run with that error.
+	OnLLMRequest func(ctx context.Context, turn int, messages []llm.Message) error
+
+	// OnToolCall is called before a tool call is executed.
+	// Returning an error vetoes the call; the error is sent to the model as the tool result.
+	OnToolCall func(ctx context.Context, call llm.ToolCall) error
+
+	// OnToolResult is called after a tool call has been executed (or vetoed).
+	OnToolResult func(ctx context.Context, call llm.ToolCall, result string)
+
+	// OnTurnEnd is called after each turn with the assistant message produced by the LLM.
+	OnTurnEnd func(ctx context.Context, turn int, msg llm.Message)
+
+	// OnReflection is called after each critique of WithAgentReflection,
+	// with the critique if the answer is not approved.
+	OnReflection func(ctx context.Context, round int, approved bool, critique string)
+
+	// OnFallback is called when an LLM call to from fails with err and is retried with to.
+	OnFallback func(ctx context.Context, from, to ModelRef, err error)
+
+	// OnError is called when the run fails.
+	OnError func(ctx context.Context, err error)
+}
+
+// WithAgentHooks sets lifecycle callbacks for agent runs.
+//
+// Example:
+//
+//	runner := agent.NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentHooks(plugin.Hooks{
+//	        OnToolCall: func(ctx context.Context, call llm.ToolCall) error {
+//	            if call.Name == "bash" {
+//	                return errors.New("bash is disabled")
+//	            }
+//	            return nil
+//	        },
+//	    }),
+//	)
+func WithAgentHooks(hooks Hooks) AgentOption {
+	return func(r *AgentRunner) {
+		r.hooks = hooks
+	}
+}
+
+// turnStart runs the OnTurnStart and OnLLMRequest hooks.
+func (h *Hooks) turnStart(ctx context.Context, turn int, messages []llm.Message) error {
+	if h.OnTurnStart != nil {
+		if err := h.OnTurnStart(ctx, turn); err != nil {
+			return fmt.Errorf("turn %d vetoed: %w", turn, err)
+		}
+	}
+	if h.OnLLMRequest != nil {
+		if err := h.OnLLMRequest(ctx, turn, messages); err != nil {
+			return fmt.Errorf("LLM request vetoed: %w", err)
+		}
+	}
+	return nil
+}
+
+// turnEnd runs the OnTurnEnd hook.
+func (h *Hooks) turnEnd(ctx context.Context, turn int, msg llm.Message) {
+	if h.OnTurnEnd != nil {
+		h.OnTurnEnd(ctx, turn, msg)
+	}
+}
+
+// runError runs the OnError hook.
+func (h *Hooks) runError(ctx context.Context, err error) {
+	if err != nil && h.OnError != nil {
+		h.OnError(ctx, err)
+	}
+}
+
+// executeToolCall executes a single tool call with registry, running the tool hooks around it.
+func (r *AgentRunner) executeToolCall(ctx context.Context, registry *llm.ToolRegistry, call llm.ToolCall) (llm.Message, error) {
+	var msg llm.Message
+	if r.hooks.OnToolCall != nil {
+		if err := r.hooks.OnToolCall(ctx, call); err != nil {
+			msg = llm.ToolMessage(call.ID, llm.ToolErrorResult{
+				ErrorType:  llm.ToolErrorRejected,
+				Message:    fmt.Sprintf("tool call vetoed: %v", err),
+				Suggestion: "Do not retry this call; continue without it, or ask the user.",
+			}.ToolContent())
+		}
+	}
+
+	start := time.Now()
+	if msg.Role == "" {
+		toolMessages, err := llm.ExecuteToolCalls(ctx, []llm.ToolCall{call}, registry)
+		var canceled *llm.ToolCallsCanceledError
+		switch {
+		case errors.As(err, &canceled):
+			// Not run: ctx is done, which ends the run at the next LLM call
+			msg = llm.ToolMessage(call.ID, llm.NewToolErrorResult(canceled.Cause).ToolContent())
+		case err != nil:
+			return llm.Message{}, fmt.Errorf("executing tool calls: %w", err)
+		default:
+			msg = toolMessages[0]
+		}
+	}
+
+	r.traceToolCall(call, msg.Content, time.Since(start))
+	r.reportToolCall(call.Name, time.Since(start))
+
+	if r.hooks.OnToolResult != nil {
+		r.hooks.OnToolResult(ctx, call, msg.Content)
+	}
+	return msg, nil
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestAgentRunner_Hooks(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("done"),
+	)
+
+	var events []string
+	hooks := Hooks{
+		OnTurnStart: func(ctx context.Context, turn int) error {
+			events = append(events, "turn_start")
+			return nil
+		},
+		OnLLMRequest: func(ctx context.Context, turn int, messages []llm.Message) error {
+			events = append(events, "llm_request")
+			return nil
+		},
+		OnToolCall: func(ctx context.Context, call llm.ToolCall) error {
+			events = append(events, "tool_call:"+call.Name)
+			return nil
+		},
+		OnToolResult: func(ctx context.Context, call llm.ToolCall, result string) {
+			events = append(events, "tool_result:"+result)
+		},
+		OnTurnEnd: func(ctx context.Context, turn int, msg llm.Message) {
+			events = append(events, "turn_end")
+		},
+	}
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentHooks(hooks),
+	)
+
+	_, err := runner.Run(context.Background(), "say hi")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"turn_start", "llm_request", "turn_end",
+		"tool_call:echo", "tool_result:echo: hi",
+		"turn_start", "llm_request", "turn_end",
+	}, events)
+}
+
+func TestAgentRunner_Hooks_Veto(t *testing.T) {
+	t.Run("tool call", func(t *testing.T) {
+		mock, name := registerScripted(t,
+			toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+			textResponse("done"),
+		)
+
+		agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+		runner := agent.NewRunner(
+			WithAgentProvider(name),
+			WithAgentModel("test-model"),
+			WithAgentTools(echoTool()),
+			WithAgentHooks(Hooks{
+				OnToolCall: func(ctx context.Context, call llm.ToolCall) error {
+					return errors.New("not allowed")
+				},
+			}),
+		)
+
+		_, err := runner.Run(context.Background(), "say hi")
+		require.NoError(t, err)
+
+		sent := mock.requests[1].Messages
+		assert.Contains(t, sent[len(sent)-1].Content, "not allowed")
+	})
+
+	t.Run("turn", func(t *testing.T) {
+		_, name := registerScripted(t, textResponse("never"))
+		veto := errors.New("stop")
+
+		var hookErr error
+		agent := &Agent{Name: "helper"}
+		runner := agent.NewRunner(
+			WithAgentProvider(name),
+			WithAgentModel("test-model"),
+			WithAgentHooks(Hooks{
+				OnTurnStart: func(ctx context.Context, turn int) error {
+					return veto
+				},
+				OnError: func(ctx context.Context, err error) {
+					hookErr = err
+				},
+			}),
+		)
+
+		_, err := runner.Run(context.Background(), "hi")
+		assert.ErrorIs(t, err, veto)
+		assert.ErrorIs(t, hookErr, veto)
+		assert.Equal(t, 0, runner.Context().HistoryLen())
+	})
+}
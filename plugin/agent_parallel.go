@@ -0,0 +1,160 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ParallelTask is a task for one sub-agent in RunParallel.
+type ParallelTask struct {
+	Agent   *Agent        // Agent to run
+	Task    string        // Task for the agent
+	Options []AgentOption // Per-agent options, e.g. WithAgentTokenBudget or WithAgentModel
+}
+
+// ParallelResult is the outcome of RunParallel.
+type ParallelResult struct {
+	Results   []*SpawnResult // Results in task order; nil for tasks that did not run
+	Synthesis *SpawnResult   // Result of the synthesizer agent, if configured
+}
+
+// ParallelOption configures RunParallel.
+type ParallelOption func(*parallelConfig)
+
+// parallelConfig holds the configuration of a parallel run.
+type parallelConfig struct {
+	limit       int
+	synthesizer *Agent
+	synthTask   string
+}
+
+// WithParallelLimit limits the number of sub-agents running at the same time.
+// Zero or negative means no limit.
+func WithParallelLimit(n int) ParallelOption {
+	return func(c *parallelConfig) {
+		c.limit = n
+	}
+}
+
+// WithParallelSynthesizer feeds the results of all sub-agents to agent,
+// which combines them into a single answer. task describes what to produce;
+// the sub-agent results are appended to it.
+func WithParallelSynthesizer(agent *Agent, task string) ParallelOption {
+	return func(c *parallelConfig) {
+		c.synthesizer = agent
+		c.synthTask = task
+	}
+}
+
+// RunParallel runs several sub-agents concurrently and collects their results.
+//
+// Each task runs like Spawn, with a child context of this runner. If any
+// sub-agent fails, the remaining ones are cancelled and the first error is
+// returned along with the results collected so far. Reports of successful
+// results are added to this runner's history in task order, unless ctx is
+// the context of a run of this runner (see Spawn).
+//
+// Example:
+//
+//	result, err := runner.RunParallel(ctx, []plugin.ParallelTask{
+//	    {Agent: p.GetAgent("security-reviewer"), Task: "Review auth.go"},
+//	    {Agent: p.GetAgent("style-reviewer"), Task: "Review auth.go"},
+//	}, plugin.WithParallelSynthesizer(p.GetAgent("lead"), "Merge the reviews into one report."))
+func (r *AgentRunner) RunParallel(ctx context.Context, tasks []ParallelTask, opts ...ParallelOption) (*ParallelResult, error) {
+	cfg := &parallelConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	for i, task := range tasks {
+		if task.Agent == nil {
+			return nil, fmt.Errorf("parallel task %d: agent is nil", i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &ParallelResult{Results: make([]*SpawnResult, len(tasks))}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	var sem chan struct{}
+	if cfg.limit > 0 {
+		sem = make(chan struct{}, cfg.limit)
+	}
+
+	for i, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			child := r.newChildRunner(task.Agent, task.Options...)
+			resp, err := child.Run(ctx, task.Task)
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Results[i] = &SpawnResult{
+				Agent:      task.Agent.Name,
+				Task:       task.Task,
+				Response:   resp,
+				Transcript: child.Context().History(),
+				Context:    child.Context(),
+				Trace:      child.LastTrace(),
+				Report:     child.LastReport(),
+			}
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("sub-agent %q: %w", task.Agent.Name, err)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return result, firstErr
+	}
+
+	for _, res := range result.Results {
+		res.Summary = spawnReport(res.Agent, res.Task, res.Response.Text())
+		if !r.inRun(ctx) {
+			r.context.AddMessage(llm.UserMessage(res.Summary))
+		}
+	}
+
+	if cfg.synthesizer != nil {
+		synthesis, err := r.Spawn(ctx, cfg.synthesizer, synthesisTask(cfg.synthTask, result.Results))
+		result.Synthesis = synthesis
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// synthesisTask builds the synthesizer's task from the sub-agent results.
+func synthesisTask(task string, results []*SpawnResult) string {
+	var sb strings.Builder
+	sb.WriteString(task)
+	sb.WriteString("\n\n")
+	for _, res := range results {
+		sb.WriteString(fmt.Sprintf("## Result from %s\n\nTask: %s\n\n%s\n\n", res.Agent, res.Task, res.Response.Text()))
+	}
+	return strings.TrimSpace(sb.String())
+}
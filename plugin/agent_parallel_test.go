@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm/llmtest"
+	"github.com/i2y/bucephalus/provider"
+)
+
+// registerFunc registers a provider that answers each request with a
+// function of its last message, and returns its name.
+func registerFunc(t *testing.T, fn func(last string) (string, error)) string {
+	t.Helper()
+	return llmtest.RegisterFunc(t, func(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+		text, err := fn(req.Messages[len(req.Messages)-1].Content)
+		if err != nil {
+			return nil, err
+		}
+		return textResponse(text), nil
+	})
+}
+
+func TestAgentRunner_RunParallel(t *testing.T) {
+	name := registerFunc(t, func(last string) (string, error) {
+		if strings.HasPrefix(last, "Combine") {
+			return "combined", nil
+		}
+		return "done: " + last, nil
+	})
+
+	lead := &Agent{Name: "lead"}
+	runner := lead.NewRunner(WithAgentProvider(name), WithAgentModel("test-model"))
+
+	result, err := runner.RunParallel(context.Background(), []ParallelTask{
+		{Agent: &Agent{Name: "a"}, Task: "task a"},
+		{Agent: &Agent{Name: "b"}, Task: "task b"},
+		{Agent: &Agent{Name: "c"}, Task: "task c"},
+	},
+		WithParallelLimit(2),
+		WithParallelSynthesizer(&Agent{Name: "synth"}, "Combine the results."),
+	)
+	require.NoError(t, err)
+
+	require.Len(t, result.Results, 3)
+	assert.Equal(t, "done: task a", result.Results[0].Response.Text())
+	assert.Equal(t, "done: task c", result.Results[2].Response.Text())
+	require.NotNil(t, result.Synthesis)
+	assert.Equal(t, "combined", result.Synthesis.Response.Text())
+	assert.Contains(t, result.Synthesis.Task, "done: task b")
+
+	// One report per sub-agent plus the synthesis
+	assert.Equal(t, 4, runner.Context().HistoryLen())
+}
+
+func TestAgentRunner_RunParallel_Error(t *testing.T) {
+	name := registerFunc(t, func(last string) (string, error) {
+		if last == "fail" {
+			return "", errors.New("boom")
+		}
+		return "ok", nil
+	})
+
+	lead := &Agent{Name: "lead"}
+	runner := lead.NewRunner(WithAgentProvider(name), WithAgentModel("test-model"))
+
+	result, err := runner.RunParallel(context.Background(), []ParallelTask{
+		{Agent: &Agent{Name: "a"}, Task: "fine"},
+		{Agent: &Agent{Name: "b"}, Task: "fail"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `sub-agent "b"`)
+	require.NotNil(t, result)
+	assert.Equal(t, 0, runner.Context().HistoryLen())
+}
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// WithAgentReflection adds up to n critique rounds to each Run(): when the
+// model gives a final answer, a critic reviews it against the conversation,
+// and if it finds problems, the model revises the answer (with its tools)
+// before the run returns. Only the final answer is recorded in the history;
+// RunStream streams the drafts as turns before it. The critic is the
+// runner's model unless set with WithAgentCritic.
+//
+// Example:
+//
+//	runner := agent.NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentReflection(2),
+//	    plugin.WithAgentCritic(plugin.ModelRef{Provider: "anthropic", Model: "claude-haiku-4-5"}),
+//	)
+func WithAgentReflection(n int) AgentOption {
+	return func(r *AgentRunner) {
+		r.reflections = n
+	}
+}
+
+// WithAgentCritic sets the model that critiques answers for
+// WithAgentReflection, e.g. a cheaper model than the runner's.
+func WithAgentCritic(model ModelRef) AgentOption {
+	return func(r *AgentRunner) {
+		r.critic = &model
+	}
+}
+
+// reflectionVerdict is the structured output of a critique.
+type reflectionVerdict struct {
+	Approved bool   `json:"approved" jsonschema:"required,description=Whether the answer is correct and complete and needs no revision"`
+	Critique string `json:"critique" jsonschema:"required,description=The specific problems of the answer and how to fix them, or empty if it is approved"`
+}
+
+// reflectionInstructions is the system message of critiques.
+const reflectionInstructions = "You review the final answer of an AI assistant to the conversation below. " +
+	"Check that it is correct, complete, and answers what the user asked, using the tool results as evidence. " +
+	"Approve it unless it has real problems; do not ask for changes of style."
+
+// revisionPrompt asks the model to revise its answer after a critique.
+const revisionPrompt = "A reviewer found problems with your answer:\n\n%s\n\n" +
+	"Revise your answer to address them. Reply with the complete revised answer."
+
+// reflect critiques answer, the final answer of a run to messages, if the
+// run has reflection rounds left (counted by round) and budget. It returns
+// the critique if the answer should be revised.
+func (r *AgentRunner) reflect(ctx context.Context, budget *runBudget, round *int, messages []llm.Message, answer string) (string, bool, error) {
+	if *round >= r.reflections {
+		return "", false, nil
+	}
+	if _, ok := budget.exceeded(); ok {
+		return "", false, nil
+	}
+	*round++
+
+	critic := ModelRef{Provider: r.providerName, Model: r.model}
+	if r.critic != nil {
+		critic = *r.critic
+	}
+	// Critiques count against the quota and use the other runner-level
+	// options of the runner's calls, with the critic's model
+	opts := append(slices.Clone(r.extraLLMOpts),
+		llm.WithProvider(critic.Provider),
+		llm.WithModel(critic.Model),
+		llm.WithSystemMessage(reflectionInstructions),
+	)
+	if userID := r.context.UserID(); userID != "" {
+		opts = append(opts, llm.WithUser(userID))
+	}
+
+	_, conversation := splitSystem(messages)
+	prompt := fmt.Sprintf("<conversation>\n%s\n</conversation>\n\n<answer>\n%s\n</answer>", llm.FormatTranscript(conversation), answer)
+	resp, err := llm.CallParse[reflectionVerdict](ctx, prompt, opts...)
+	if err != nil {
+		return "", false, fmt.Errorf("critiquing answer: %w", err)
+	}
+	budget.tokens += resp.Usage().TotalTokens
+	r.reportCall(resp.Usage())
+
+	verdict, err := resp.Parsed()
+	if err != nil {
+		return "", false, fmt.Errorf("critiquing answer: %w", err)
+	}
+	if r.hooks.OnReflection != nil {
+		r.hooks.OnReflection(ctx, *round, verdict.Approved, verdict.Critique)
+	}
+	if verdict.Approved || verdict.Critique == "" {
+		return "", false, nil
+	}
+	return verdict.Critique, true, nil
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestAgentRunner_Reflection(t *testing.T) {
+	main, mainName := registerScripted(t,
+		textResponse("Paris is the capital of Germany."),
+		textResponse("Berlin is the capital of Germany."),
+	)
+	critic, criticName := registerScripted(t,
+		textResponse(`{"approved": false, "critique": "The capital of Germany is Berlin, not Paris."}`),
+		textResponse(`{"approved": true, "critique": ""}`),
+	)
+
+	var rounds []int
+	runner := (&Agent{Name: "assistant"}).NewRunner(
+		WithAgentProvider(mainName),
+		WithAgentModel("big-model"),
+		WithAgentReflection(2),
+		WithAgentCritic(ModelRef{Provider: criticName, Model: "small-model"}),
+		WithAgentHooks(Hooks{
+			OnReflection: func(ctx context.Context, round int, approved bool, critique string) {
+				rounds = append(rounds, round)
+			},
+		}),
+	)
+
+	resp, err := runner.Run(context.Background(), "What is the capital of Germany?")
+	require.NoError(t, err)
+	assert.Equal(t, "Berlin is the capital of Germany.", resp.Text())
+	assert.Equal(t, []int{1, 2}, rounds)
+
+	// The critic saw the conversation and the draft
+	require.Len(t, critic.requests, 2)
+	assert.Equal(t, "small-model", critic.requests[0].Model)
+	prompt := critic.requests[0].Messages[len(critic.requests[0].Messages)-1].Content
+	assert.Contains(t, prompt, "user: What is the capital of Germany?")
+	assert.Contains(t, prompt, "<answer>\nParis is the capital of Germany.\n</answer>")
+
+	// The revision was asked with the draft and the critique
+	revision := main.requests[1].Messages
+	assert.Equal(t, llm.AssistantMessage("Paris is the capital of Germany."), revision[len(revision)-2])
+	assert.Contains(t, revision[len(revision)-1].Content, "The capital of Germany is Berlin, not Paris.")
+
+	// Only the final answer is recorded
+	assert.Equal(t, []llm.Message{
+		llm.UserMessage("What is the capital of Germany?"),
+		llm.AssistantMessage("Berlin is the capital of Germany."),
+	}, runner.Context().History())
+}
+
+func TestAgentRunner_ReflectionRounds(t *testing.T) {
+	_, name := registerScripted(t,
+		textResponse("draft 1"),
+		textResponse(`{"approved": false, "critique": "Too short"}`),
+		textResponse("draft 2"),
+	)
+	runner := (&Agent{Name: "assistant"}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentReflection(1),
+	)
+
+	// The last revision is returned without another critique
+	resp, err := runner.Run(context.Background(), "Write something")
+	require.NoError(t, err)
+	assert.Equal(t, "draft 2", resp.Text())
+}
+
+func TestAgentRunner_ReflectionError(t *testing.T) {
+	_, name := registerScripted(t, textResponse("answer"), textResponse("not JSON"))
+	runner := (&Agent{Name: "assistant"}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentReflection(1),
+	)
+
+	_, err := runner.Run(context.Background(), "Question")
+	assert.ErrorContains(t, err, "critiquing answer")
+	assert.Equal(t, 2, runner.Context().HistoryLen())
+}
+
+func TestAgentRunner_ReflectionQuota(t *testing.T) {
+	_, name := registerScripted(t,
+		textResponse("answer"),
+		textResponse(`{"approved": true, "critique": ""}`),
+	)
+	quota := llm.NewQuota()
+	runner := (&Agent{Name: "assistant"}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentReflection(1),
+		WithAgentQuota(quota),
+	)
+
+	// The critique is recorded in the quota of the runner
+	_, err := runner.Run(context.Background(), "Question")
+	require.NoError(t, err)
+	assert.Equal(t, 2, quota.Usage().Requests)
+}
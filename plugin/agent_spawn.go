@@ -0,0 +1,142 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// SpawnResult is the outcome of a sub-agent run.
+type SpawnResult struct {
+	Agent      string               // Name of the sub-agent
+	Task       string               // Task given to the sub-agent
+	Response   llm.Response[string] // Final response of the sub-agent
+	Transcript []llm.Message        // Full conversation of the sub-agent
+	Context    *AgentContext        // Child context used by the sub-agent
+	Trace      *Trace               // Trace of the sub-agent run
+	Report     *RunReport           // Usage report of the sub-agent run
+	Summary    string               // Report of the result for the runner's history
+}
+
+// WithAgentPlugin sets the plugin whose agents can be spawned by name
+// with SpawnByName.
+func WithAgentPlugin(p *Plugin) AgentOption {
+	return func(r *AgentRunner) {
+		r.plugin = p
+	}
+}
+
+// Spawn runs agent as a sub-agent of this runner to complete task.
+//
+// The sub-agent gets a child context (see AgentContext.NewChildContext), so it
+// can read this runner's state but keeps its own history. It inherits the
+// runner's provider, model, tools, limits, pricing, and hooks; opts
+// override them. When the sub-agent succeeds, a report of its result
+// (SpawnResult.Summary) is added to this runner's history so later runs can
+// build on it.
+//
+// If ctx is the context of a run of this runner, e.g. in a tool, the report
+// is not added, since it would precede the tool call of the run in the
+// history; return the Summary as the tool result instead. The usage of the
+// sub-agent is recorded in the RunReport of that run.
+//
+// Example:
+//
+//	result, err := runner.Spawn(ctx, p.GetAgent("code-reviewer"), "Review main.go")
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(result.Response.Text())
+func (r *AgentRunner) Spawn(ctx context.Context, agent *Agent, task string, opts ...AgentOption) (*SpawnResult, error) {
+	if agent == nil {
+		return nil, errors.New("spawning sub-agent: agent is nil")
+	}
+
+	child := r.newChildRunner(agent, opts...)
+	resp, err := child.Run(ctx, task)
+
+	result := &SpawnResult{
+		Agent:      agent.Name,
+		Task:       task,
+		Response:   resp,
+		Transcript: child.Context().History(),
+		Context:    child.Context(),
+		Trace:      child.LastTrace(),
+		Report:     child.LastReport(),
+	}
+	if err != nil {
+		return result, fmt.Errorf("sub-agent %q: %w", agent.Name, err)
+	}
+
+	result.Summary = spawnReport(agent.Name, task, resp.Text())
+	if !r.inRun(ctx) {
+		r.context.AddMessage(llm.UserMessage(result.Summary))
+	}
+	return result, nil
+}
+
+// inRun reports whether ctx is the context of the run of this runner.
+func (r *AgentRunner) inRun(ctx context.Context) bool {
+	report, _ := ctx.Value(usageReportKey{}).(*usageReport)
+	return report != nil && report == r.report
+}
+
+// SpawnByName runs the named agent from the runner's plugin as a sub-agent.
+// The plugin must be set with WithAgentPlugin. See Spawn for details.
+func (r *AgentRunner) SpawnByName(ctx context.Context, name, task string, opts ...AgentOption) (*SpawnResult, error) {
+	agent, err := r.lookupAgent(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.Spawn(ctx, agent, task, opts...)
+}
+
+// lookupAgent returns the named agent from the runner's plugin.
+func (r *AgentRunner) lookupAgent(name string) (*Agent, error) {
+	if r.plugin == nil {
+		return nil, fmt.Errorf("agent %q: no plugin configured (use WithAgentPlugin)", name)
+	}
+	agent := r.plugin.GetAgent(name)
+	if agent == nil {
+		return nil, fmt.Errorf("agent %q not found in plugin %q", name, r.plugin.Name)
+	}
+	return agent, nil
+}
+
+// newChildRunner creates a runner for agent that inherits this runner's
+// configuration and uses a child of this runner's context.
+func (r *AgentRunner) newChildRunner(agent *Agent, opts ...AgentOption) *AgentRunner {
+	inherited := []AgentOption{
+		WithAgentProvider(r.providerName),
+		WithAgentModel(r.model),
+		WithAgentTools(r.availableTools...),
+		WithAgentMaxTurns(r.maxTurns),
+		WithAgentTokenBudget(r.tokenBudget),
+		WithAgentDeadline(r.deadline),
+		WithAgentHooks(r.hooks),
+		WithAgentLLMOptions(r.extraLLMOpts...),
+		WithAgentFallbacks(r.fallbacks...),
+		WithAgentFallbackOn(r.fallbackOn),
+		WithAgentPlugin(r.plugin),
+		WithAgentSharedState(r.shared),
+		WithAgentRedactor(r.redactor),
+		WithAgentContext(r.context.NewChildContext()),
+	}
+	if r.temperature != nil {
+		inherited = append(inherited, WithAgentTemperature(*r.temperature))
+	}
+	if r.maxTokens != nil {
+		inherited = append(inherited, WithAgentMaxTokens(*r.maxTokens))
+	}
+	if r.pricing != nil {
+		inherited = append(inherited, WithAgentPricing(*r.pricing))
+	}
+	return agent.NewRunner(append(inherited, opts...)...)
+}
+
+// spawnReport formats the result of a sub-agent for the parent history.
+func spawnReport(agent, task, result string) string {
+	return fmt.Sprintf("Sub-agent %q completed the task: %s\n\nResult:\n%s", agent, task, result)
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestAgentRunner_Spawn(t *testing.T) {
+	mock, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"bug"}`),
+		textResponse("found a bug"),
+	)
+
+	p := &Plugin{
+		Name: "test",
+		Agents: []Agent{
+			{Name: "reviewer", Content: "Review code.", Tools: []string{"echo"}},
+		},
+	}
+	lead := &Agent{Name: "lead"}
+	runner := lead.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentPlugin(p),
+	)
+	runner.Context().SetState("repo", "bucephalus")
+
+	result, err := runner.SpawnByName(context.Background(), "reviewer", "review main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "reviewer", result.Agent)
+	assert.Equal(t, "found a bug", result.Response.Text())
+	assert.Len(t, result.Transcript, 4)
+
+	// The sub-agent uses its own system message and can read the parent's state
+	assert.Contains(t, mock.requests[0].Messages[0].Content, "Review code.")
+	repo, ok := result.Context.GetState("repo")
+	require.True(t, ok)
+	assert.Equal(t, "bucephalus", repo)
+
+	// The parent history only gets a report of the result
+	history := runner.Context().History()
+	require.Len(t, history, 1)
+	assert.Equal(t, llm.RoleUser, history[0].Role)
+	assert.Contains(t, history[0].Content, "found a bug")
+
+	_, err = runner.SpawnByName(context.Background(), "missing", "task")
+	assert.Error(t, err)
+}
+
+func TestAgentRunner_Spawn_FromTool(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "delegate", `{"text":"review main.go"}`),
+		textResponse("found a bug"), // The sub-agent
+		textResponse("The reviewer found a bug."),
+	)
+
+	var runner *AgentRunner
+	delegate := llm.MustNewTool("delegate", "Delegate a task to the reviewer",
+		func(ctx context.Context, in echoInput) (string, error) {
+			result, err := runner.Spawn(ctx, &Agent{Name: "reviewer"}, in.Text)
+			if err != nil {
+				return "", err
+			}
+			return result.Summary, nil
+		})
+	runner = (&Agent{Name: "lead", Tools: []string{"delegate"}}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(delegate),
+	)
+
+	_, err := runner.Run(context.Background(), "Review the code")
+	require.NoError(t, err)
+
+	// The report is the tool result, in the order of the run
+	history := runner.Context().History()
+	require.Len(t, history, 4)
+	assert.Equal(t, llm.RoleUser, history[0].Role)
+	assert.Equal(t, "delegate", history[1].ToolCalls[0].Name)
+	assert.Equal(t, llm.RoleTool, history[2].Role)
+	assert.Contains(t, history[2].Content, "found a bug")
+	assert.Equal(t, "The reviewer found a bug.", history[3].Content)
+}
@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// AgentEventType identifies the kind of an AgentEvent.
+type AgentEventType string
+
+const (
+	// AgentEventTextDelta carries a chunk of assistant text.
+	AgentEventTextDelta AgentEventType = "text_delta"
+	// AgentEventToolCallStarted is emitted before a tool call is executed.
+	AgentEventToolCallStarted AgentEventType = "tool_call_started"
+	// AgentEventToolResult carries the result of an executed tool call.
+	AgentEventToolResult AgentEventType = "tool_result"
+	// AgentEventTurnComplete is emitted when a single LLM call has finished.
+	AgentEventTurnComplete AgentEventType = "turn_complete"
+)
+
+// AgentEvent is a single event emitted by a streaming agent run.
+// Only the fields relevant to the event Type are set.
+type AgentEvent struct {
+	Type AgentEventType
+
+	// Turn is the 1-based index of the LLM call this event belongs to.
+	Turn int
+
+	// Delta is the text chunk (AgentEventTextDelta).
+	Delta string
+
+	// ToolCall is the tool call being executed
+	// (AgentEventToolCallStarted, AgentEventToolResult).
+	ToolCall *llm.ToolCall
+
+	// ToolResult is the tool result content sent back to the model (AgentEventToolResult).
+	ToolResult string
+
+	// Response is the accumulated response of the turn (AgentEventTurnComplete).
+	Response *llm.Response[string]
+}
+
+// AgentStream is a streaming agent run created by AgentRunner.RunStream.
+// The run starts when Events is iterated.
+type AgentStream struct {
+	runner *AgentRunner
+	ctx    context.Context
+	input  []llm.Message
+	cfg    *runConfig
+
+	started  bool
+	response llm.Response[string]
+	err      error
+}
+
+// RunStream executes the agent like Run, but streams the assistant text and
+// tool activity as events. Tool calls are handled transparently mid-stream.
+//
+// Example:
+//
+//	stream := runner.RunStream(ctx, "Summarize README.md")
+//	for ev := range stream.Events() {
+//	    switch ev.Type {
+//	    case plugin.AgentEventTextDelta:
+//	        fmt.Print(ev.Delta)
+//	    case plugin.AgentEventToolCallStarted:
+//	        fmt.Printf("\n[calling %s]\n", ev.ToolCall.Name)
+//	    }
+//	}
+//	if err := stream.Err(); err != nil {
+//	    return err
+//	}
+func (r *AgentRunner) RunStream(ctx context.Context, task string, runOpts ...RunOption) *AgentStream {
+	cfg := r.newRunConfig(runOpts)
+
+	return &AgentStream{
+		runner: r,
+		ctx:    ctx,
+		input:  []llm.Message{llm.UserMessage(task)},
+		cfg:    cfg,
+	}
+}
+
+// Events returns an iterator over the events of the run.
+// The run can only be iterated once; stopping the iteration early aborts the run
+// without recording it in the context history.
+func (s *AgentStream) Events() iter.Seq[AgentEvent] {
+	return func(yield func(AgentEvent) bool) {
+		if s.started {
+			return
+		}
+		s.started = true
+
+		turnCall := func(ctx context.Context, turn int, messages []llm.Message, opts []llm.Option) (llm.Response[string], ModelRef, error) {
+			resp, model, err := s.streamTurn(ctx, turn, messages, opts, yield)
+			if err == nil {
+				s.response = resp
+			}
+			return resp, model, err
+		}
+		_, s.err = agentLoop(s.ctx, s.runner, s.input, s.cfg, turnCall, func(ev AgentEvent) bool {
+			if ev.Type == AgentEventTurnComplete {
+				resp := s.response
+				ev.Response = &resp
+			}
+			return yield(ev)
+		})
+	}
+}
+
+// Err returns any error that occurred during the run.
+func (s *AgentStream) Err() error {
+	return s.err
+}
+
+// Response returns the response of the final turn.
+// Should be called after iterating through all events.
+func (s *AgentStream) Response() llm.Response[string] {
+	return s.response
+}
+
+// errStopped signals that the consumer stopped iterating.
+var errStopped = errors.New("agent stream stopped")
+
+// streamTurn makes a single streaming LLM call and yields its text deltas.
+// Failing over to a fallback model is only possible before the stream starts.
+func (s *AgentStream) streamTurn(ctx context.Context, turn int, messages []llm.Message, opts []llm.Option, yield func(AgentEvent) bool) (llm.Response[string], ModelRef, error) {
+	var stream *llm.Stream
+	model, err := s.runner.withFallback(ctx, opts, func(opts []llm.Option) (err error) {
+		stream, err = llm.CallMessagesStream(ctx, messages, opts...)
+		return err
+	})
+	if err != nil {
+		return llm.Response[string]{}, model, err
+	}
+	defer func() { _ = stream.Close() }()
+
+	for chunk := range stream.Chunks() {
+		if chunk.Delta == "" {
+			continue
+		}
+		if !yield(AgentEvent{Type: AgentEventTextDelta, Turn: turn, Delta: chunk.Delta}) {
+			return llm.Response[string]{}, model, errStopped
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return llm.Response[string]{}, model, err
+	}
+
+	return stream.Response(), model, nil
+}
@@ -0,0 +1,423 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+// scriptedProvider returns pre-defined responses in order and records requests.
+type scriptedProvider struct {
+	mu        sync.Mutex
+	responses []*provider.Response
+	requests  []*provider.Request
+}
+
+func (p *scriptedProvider) Name() string {
+	return "scripted"
+}
+
+func (p *scriptedProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+	if len(p.responses) == 0 {
+		return nil, errors.New("no scripted response left")
+	}
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func (p *scriptedProvider) CallStream(ctx context.Context, req *provider.Request) (provider.ResponseStream, error) {
+	resp, err := p.Call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptedStream{resp: resp, pos: -1}, nil
+}
+
+// scriptedStream streams a response one character at a time.
+type scriptedStream struct {
+	resp    *provider.Response
+	pos     int
+	current *provider.StreamChunk
+}
+
+func (s *scriptedStream) Next() bool {
+	s.pos++
+	if s.pos >= len(s.resp.Content) {
+		return false
+	}
+	s.current = &provider.StreamChunk{Delta: s.resp.Content[s.pos : s.pos+1]}
+	return true
+}
+
+func (s *scriptedStream) Current() *provider.StreamChunk { return s.current }
+func (s *scriptedStream) Err() error                     { return nil }
+func (s *scriptedStream) Close() error                   { return nil }
+func (s *scriptedStream) Accumulated() *provider.Response {
+	return s.resp
+}
+
+var scriptedProviderCount atomic.Int64
+
+// registerScripted registers a scripted provider under a unique name.
+func registerScripted(t *testing.T, responses ...*provider.Response) (*scriptedProvider, string) {
+	t.Helper()
+	p := &scriptedProvider{responses: responses}
+	name := fmt.Sprintf("scripted-%d", scriptedProviderCount.Add(1))
+	provider.Register(name, func() (provider.Provider, error) {
+		return p, nil
+	})
+	t.Cleanup(func() { provider.Reset(name) })
+	return p, name
+}
+
+func toolCallResponse(id, name, args string) *provider.Response {
+	return &provider.Response{
+		ToolCalls:    []provider.ToolCall{{ID: id, Name: name, Arguments: args}},
+		FinishReason: provider.FinishReasonToolCalls,
+	}
+}
+
+func textResponse(text string) *provider.Response {
+	return &provider.Response{
+		Content:      text,
+		FinishReason: provider.FinishReasonStop,
+	}
+}
+
+type echoInput struct {
+	Text string `json:"text"`
+}
+
+func echoTool() llm.Tool {
+	return llm.MustNewTool("echo", "Echo the input",
+		func(ctx context.Context, in echoInput) (string, error) {
+			return "echo: " + in.Text, nil
+		})
+}
+
+func TestAgentRunner_Run_ExecutesTools(t *testing.T) {
+	mock, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("done"),
+	)
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+	)
+
+	resp, err := runner.Run(context.Background(), "say hi")
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Text())
+	require.Len(t, mock.requests, 2)
+
+	// The second request must contain the tool call and its result
+	second := mock.requests[1].Messages
+	last := second[len(second)-1]
+	assert.Equal(t, llm.RoleTool, last.Role)
+	assert.Equal(t, "call_1", last.ToolID)
+	assert.Equal(t, "echo: hi", last.Content)
+
+	history := runner.Context().History()
+	require.Len(t, history, 4)
+	assert.Equal(t, llm.RoleUser, history[0].Role)
+	assert.Equal(t, llm.RoleAssistant, history[1].Role)
+	require.Len(t, history[1].ToolCalls, 1)
+	assert.Equal(t, "echo", history[1].ToolCalls[0].Name)
+	assert.Equal(t, llm.RoleTool, history[2].Role)
+	assert.Equal(t, "done", history[3].Content)
+}
+
+func TestAgentRunner_Run_MaxTurns(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"1"}`),
+		toolCallResponse("call_2", "echo", `{"text":"2"}`),
+		textResponse("never reached"),
+	)
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentMaxTurns(2),
+	)
+
+	_, err := runner.Run(context.Background(), "loop")
+	assert.ErrorIs(t, err, ErrMaxTurnsExceeded)
+}
+
+func TestAgentRunner_Run_SendsSystemMessage(t *testing.T) {
+	mock, name := registerScripted(t, textResponse("hello"))
+
+	agent := &Agent{Name: "helper", Content: "Be helpful."}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+	)
+
+	_, err := runner.Run(context.Background(), "hi")
+	require.NoError(t, err)
+	require.Len(t, mock.requests, 1)
+
+	msgs := mock.requests[0].Messages
+	require.NotEmpty(t, msgs)
+	assert.Equal(t, llm.RoleSystem, msgs[0].Role)
+	assert.Contains(t, msgs[0].Content, "Be helpful.")
+}
+
+func TestAgentRunner_RunStream(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("done"),
+	)
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+	)
+
+	stream := runner.RunStream(context.Background(), "say hi")
+
+	var text string
+	var types []AgentEventType
+	for ev := range stream.Events() {
+		if ev.Type == AgentEventTextDelta {
+			text += ev.Delta
+			continue
+		}
+		types = append(types, ev.Type)
+		if ev.Type == AgentEventToolResult {
+			assert.Equal(t, "echo: hi", ev.ToolResult)
+		}
+	}
+	require.NoError(t, stream.Err())
+
+	assert.Equal(t, "done", text)
+	assert.Equal(t, []AgentEventType{
+		AgentEventTurnComplete,
+		AgentEventToolCallStarted,
+		AgentEventToolResult,
+		AgentEventTurnComplete,
+	}, types)
+	assert.Equal(t, "done", stream.Response().Text())
+	assert.Equal(t, 4, runner.Context().HistoryLen())
+}
+
+func TestAgentRunner_RunStream_Metrics(t *testing.T) {
+	metrics := llm.NewMetricSet()
+	llm.SetMetrics(metrics)
+	t.Cleanup(func() { llm.SetMetrics(nil) })
+
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("done"),
+	)
+
+	agent := &Agent{Name: "streamer", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+	)
+
+	stream := runner.RunStream(context.Background(), "say hi")
+	for range stream.Events() {
+	}
+	require.NoError(t, stream.Err())
+
+	var b bytes.Buffer
+	require.NoError(t, metrics.WritePrometheus(&b))
+	assert.Contains(t, b.String(), `llm_agent_turns_total{agent="streamer"} 2`)
+}
+
+func TestRunParse(t *testing.T) {
+	type report struct {
+		Verdict string `json:"verdict"`
+	}
+
+	mock, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"look"}`),
+		textResponse(`{"verdict":"ok"}`),
+	)
+
+	agent := &Agent{Name: "reviewer", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+	)
+
+	resp, err := RunParse[report](context.Background(), runner, "review")
+	require.NoError(t, err)
+
+	parsed, err := resp.Parsed()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", parsed.Verdict)
+
+	require.Len(t, mock.requests, 2)
+	for _, req := range mock.requests {
+		assert.NotNil(t, req.JSONSchema)
+		assert.Len(t, req.Tools, 1)
+	}
+	assert.Equal(t, 4, runner.Context().HistoryLen())
+}
+
+func TestAgentRunner_History_PendingToolCalls(t *testing.T) {
+	mock, name := registerScripted(t,
+		toolCallResponse("call_1", "external", `{}`),
+		textResponse("ok"),
+		toolCallResponse("call_2", "external", `{}`),
+		textResponse("handled"),
+	)
+
+	// No tools are provided, so tool calls are left for the host
+	agent := &Agent{Name: "helper"}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+	)
+	ctx := context.Background()
+
+	resp, err := runner.Run(ctx, "do it")
+	require.NoError(t, err)
+	assert.True(t, resp.HasToolCalls())
+
+	history := runner.Context().History()
+	require.Len(t, history, 2)
+	require.Len(t, history[1].ToolCalls, 1)
+
+	// A plain follow-up closes the unanswered tool call first
+	_, err = runner.Run(ctx, "never mind")
+	require.NoError(t, err)
+	sent := mock.requests[1].Messages
+	assert.Equal(t, llm.RoleTool, sent[len(sent)-2].Role)
+	assert.Equal(t, "call_1", sent[len(sent)-2].ToolID)
+	assert.Equal(t, llm.RoleUser, sent[len(sent)-1].Role)
+
+	// The host can answer pending tool calls with RunWithMessages
+	_, err = runner.Run(ctx, "again")
+	require.NoError(t, err)
+	resp, err = runner.RunWithMessages(ctx, []llm.Message{llm.ToolMessage("call_2", "result")})
+	require.NoError(t, err)
+	assert.Equal(t, "handled", resp.Text())
+
+	history = runner.Context().History()
+	last := history[len(history)-2]
+	assert.Equal(t, llm.RoleTool, last.Role)
+	assert.Equal(t, "result", last.Content)
+}
+
+func TestAgentRunner_RunTools(t *testing.T) {
+	mock, name := registerScripted(t,
+		textResponse("no tools"),
+		toolCallResponse("call_1", "write", `{"text":"x"}`),
+		textResponse("written"),
+	)
+
+	write := llm.MustNewTool("write", "Write a file",
+		func(ctx context.Context, in echoInput) (string, error) {
+			return "wrote " + in.Text, nil
+		})
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool(), write),
+	)
+	ctx := context.Background()
+
+	// Deny the agent's only tool for this run
+	_, err := runner.Run(ctx, "read", WithRunDeniedTools("echo"))
+	require.NoError(t, err)
+	assert.Empty(t, mock.requests[0].Tools)
+
+	// Grant a tool outside the agent's allowed tools for this run
+	resp, err := runner.Run(ctx, "write it", WithRunTools(write))
+	require.NoError(t, err)
+	assert.Equal(t, "written", resp.Text())
+	assert.Len(t, mock.requests[1].Tools, 2)
+	sent := mock.requests[2].Messages
+	assert.Equal(t, "wrote x", sent[len(sent)-1].Content)
+
+	// The runner's tool set is unchanged
+	assert.Len(t, runner.FilteredTools(), 1)
+}
+
+func TestAgentRunner_CallError_KeepsTranscript(t *testing.T) {
+	// The second LLM call fails: no scripted response is left for it
+	mock, name := registerScripted(t, toolCallResponse("call_1", "echo", `{"text":"hi"}`))
+
+	calls := 0
+	echo := llm.MustNewTool("echo", "Echo the input",
+		func(ctx context.Context, in echoInput) (string, error) {
+			calls++
+			return "echo: " + in.Text, nil
+		})
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echo),
+	)
+	ctx := context.Background()
+
+	_, err := runner.Run(ctx, "say hi")
+	require.ErrorContains(t, err, "no scripted response left")
+
+	// The tool call that ran and its result are kept
+	history := runner.Context().History()
+	require.Len(t, history, 3)
+	assert.Equal(t, "call_1", history[1].ToolCalls[0].ID)
+	assert.Equal(t, "echo: hi", history[2].Content)
+
+	// The next run continues without repeating the tool call
+	mock.mu.Lock()
+	mock.responses = append(mock.responses, textResponse("done"))
+	mock.mu.Unlock()
+	resp, err := runner.Run(ctx, "go on")
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Text())
+	assert.Equal(t, 1, calls)
+	assert.Len(t, runner.Context().History(), 5)
+}
+
+func TestAgentRunner_Quota(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"hi"}`),
+		textResponse("done"),
+	)
+
+	quota := llm.NewQuota(llm.WithRequestsPerMinute(1), llm.WithQuotaNoWait())
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentQuota(quota),
+	)
+
+	_, err := runner.Run(context.Background(), "say hi")
+	require.ErrorIs(t, err, llm.ErrQuotaExceeded)
+	assert.Equal(t, 1, quota.Usage().Requests)
+}
@@ -0,0 +1,257 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ChatSession is an interactive conversation with a plugin agent.
+// Slash commands are dispatched automatically: built-in commands (/help,
+// /clear, /compact, and those added with WithChatCommand) are handled
+// locally, plugin commands are expanded and run through the agent, and any
+// other input is sent to the agent loop.
+//
+// Example:
+//
+//	runner := p.GetAgent("assistant").NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentTools(tools.AllTools()...),
+//	)
+//	session := plugin.NewChatSession(p, runner,
+//	    plugin.WithChatEvents(func(ev plugin.AgentEvent) {
+//	        if ev.Type == plugin.AgentEventTextDelta {
+//	            fmt.Print(ev.Delta)
+//	        }
+//	    }),
+//	)
+//
+//	scanner := bufio.NewScanner(os.Stdin)
+//	for scanner.Scan() {
+//	    reply, err := session.Send(ctx, scanner.Text())
+//	    if err != nil {
+//	        fmt.Println("error:", err)
+//	        continue
+//	    }
+//	    if reply.Response == nil {
+//	        fmt.Print(reply.Text) // Built-in command output
+//	    }
+//	    fmt.Println()
+//	}
+type ChatSession struct {
+	plugin   *Plugin
+	runner   *AgentRunner
+	onEvent  func(AgentEvent)
+	builtins map[string]chatCommand
+	locale   string
+}
+
+// ChatReply is the result of sending input to a ChatSession.
+type ChatReply struct {
+	Text     string                // Reply text
+	Command  string                // Name of the slash command (not the alias), if the input was one
+	Response *llm.Response[string] // Agent response; nil for built-in commands
+
+	// Output is the JSON answer to a plugin command with an output contract
+	// (output-format or output-schema), validated against its schema.
+	Output json.RawMessage
+}
+
+// DecodeOutput unmarshals the output of a command with an output contract
+// into v.
+//
+// Example:
+//
+//	reply, err := session.Send(ctx, "/triage "+issue)
+//	if err != nil {
+//	    return err
+//	}
+//	var triage Triage
+//	if err := reply.DecodeOutput(&triage); err != nil {
+//	    return err
+//	}
+func (r *ChatReply) DecodeOutput(v any) error {
+	if r.Output == nil {
+		return errors.New("the reply has no command output")
+	}
+	return json.Unmarshal(r.Output, v)
+}
+
+// ChatCommandHandler handles a built-in slash command and returns its output.
+type ChatCommandHandler func(ctx context.Context, s *ChatSession, args string) (string, error)
+
+// chatCommand is a built-in slash command.
+type chatCommand struct {
+	description string
+	handler     ChatCommandHandler
+}
+
+// ChatOption configures a ChatSession.
+type ChatOption func(*ChatSession)
+
+// WithChatEvents streams agent runs and calls fn for every event,
+// e.g. to print text deltas as they arrive.
+func WithChatEvents(fn func(AgentEvent)) ChatOption {
+	return func(s *ChatSession) {
+		s.onEvent = fn
+	}
+}
+
+// WithChatCommand adds a built-in slash command handled by the host.
+// Built-in commands take precedence over plugin commands with the same name.
+func WithChatCommand(name, description string, handler ChatCommandHandler) ChatOption {
+	return func(s *ChatSession) {
+		s.builtins[name] = chatCommand{description: description, handler: handler}
+	}
+}
+
+// WithChatLocale lists plugin commands in /help with their descriptions in
+// locale, e.g. "ja" (see WithLocale).
+func WithChatLocale(locale string) ChatOption {
+	return func(s *ChatSession) {
+		s.locale = locale
+	}
+}
+
+// NewChatSession creates a chat session for p that sends input to runner.
+// p may be nil, in which case only built-in commands are available. The
+// LLM defaults of p apply to the settings that runner does not set.
+func NewChatSession(p *Plugin, runner *AgentRunner, opts ...ChatOption) *ChatSession {
+	s := &ChatSession{
+		plugin:   p,
+		runner:   runner,
+		builtins: make(map[string]chatCommand),
+	}
+	s.builtins["help"] = chatCommand{description: "List available commands", handler: chatHelp}
+	s.builtins["clear"] = chatCommand{description: "Clear the conversation history", handler: chatClear}
+	s.builtins["compact"] = chatCommand{description: "Summarize the conversation history to free up context", handler: chatCompact}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if p != nil && runner != nil {
+		runner.applyLLMDefaults(p.LLM)
+	}
+	return s
+}
+
+// Runner returns the session's agent runner.
+func (s *ChatSession) Runner() *AgentRunner {
+	return s.runner
+}
+
+// Plugin returns the session's plugin.
+func (s *ChatSession) Plugin() *Plugin {
+	return s.plugin
+}
+
+// Send handles one line of user input.
+// It returns ErrCommandNotFound (wrapped) for unknown slash commands. The
+// answers to plugin commands with an output contract are parsed into
+// ChatReply.Output; if an answer does not meet the contract, Send returns
+// the reply with a *CommandOutputError.
+func (s *ChatSession) Send(ctx context.Context, input string) (*ChatReply, error) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "/") {
+		return s.run(ctx, "", input)
+	}
+
+	name, args := ParseCommandInput(input)
+	if cmd, ok := s.builtins[name]; ok {
+		text, err := cmd.handler(ctx, s, args)
+		if err != nil {
+			return nil, fmt.Errorf("/%s: %w", name, err)
+		}
+		return &ChatReply{Text: text, Command: name}, nil
+	}
+
+	if s.plugin == nil {
+		return nil, fmt.Errorf("%w: /%s", ErrCommandNotFound, name)
+	}
+	expanded, err := s.plugin.ExpandCommand(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: /%s", err, name)
+	}
+
+	task := expanded.Arguments
+	if task == "" {
+		task = input
+	}
+	reply, err := s.run(ctx, expanded.SystemMessage, task)
+	if err != nil {
+		return nil, err
+	}
+	reply.Command = expanded.Command.Name
+	if expanded.Command.HasOutputContract() {
+		reply.Output, err = expanded.ParseOutput(reply.Text)
+		if err != nil {
+			return reply, err
+		}
+	}
+	return reply, nil
+}
+
+// run sends task to the agent with an optional extra system message.
+func (s *ChatSession) run(ctx context.Context, systemMessage, task string) (*ChatReply, error) {
+	var runOpts []RunOption
+	if systemMessage != "" {
+		runOpts = append(runOpts, WithRunSystemMessage(systemMessage))
+	}
+
+	var resp llm.Response[string]
+	if s.onEvent == nil {
+		var err error
+		resp, err = s.runner.Run(ctx, task, runOpts...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		stream := s.runner.RunStream(ctx, task, runOpts...)
+		for ev := range stream.Events() {
+			s.onEvent(ev)
+		}
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		resp = stream.Response()
+	}
+
+	return &ChatReply{Text: resp.Text(), Response: &resp}, nil
+}
+
+// chatHelp lists built-in and plugin commands.
+func chatHelp(ctx context.Context, s *ChatSession, args string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("Commands:\n")
+
+	names := make([]string, 0, len(s.builtins))
+	for name := range s.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("  /%s - %s\n", name, s.builtins[name].description))
+	}
+
+	if s.plugin != nil {
+		for _, cmd := range s.plugin.CommandsIndex(WithLocale(s.locale)) {
+			if _, ok := s.builtins[cmd.Name]; ok {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("  /%s%s - %s\n", cmd.Name, formatAliases(cmd.Aliases), cmd.Description))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// chatClear clears the conversation history.
+func chatClear(ctx context.Context, s *ChatSession, args string) (string, error) {
+	s.runner.ClearHistory()
+	return "Conversation cleared.", nil
+}
@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestChatSession(t *testing.T) {
+	mock, name := registerScripted(t, textResponse("hello"), textResponse("translated"))
+
+	p := &Plugin{
+		Name: "test",
+		Commands: []Command{
+			{Name: "translate", Description: "Translate text", Aliases: []string{"tr"}, Content: "Translate to French: $ARGUMENTS"},
+			{Name: "secret", Description: "Hidden command", Hidden: true, Content: "Secret"},
+		},
+	}
+	runner := (&Agent{Name: "assistant"}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+	)
+
+	var deltas string
+	session := NewChatSession(p, runner,
+		WithChatEvents(func(ev AgentEvent) {
+			deltas += ev.Delta
+		}),
+		WithChatCommand("ping", "Reply with pong", func(ctx context.Context, s *ChatSession, args string) (string, error) {
+			return "pong " + args, nil
+		}),
+	)
+	ctx := context.Background()
+
+	// Regular input goes to the agent
+	reply, err := session.Send(ctx, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", reply.Text)
+	assert.Equal(t, "hello", deltas)
+	require.NotNil(t, reply.Response)
+
+	// Plugin commands are expanded into a system message layered over the
+	// agent's
+	reply, err = session.Send(ctx, "/tr good morning")
+	require.NoError(t, err)
+	assert.Equal(t, "translate", reply.Command)
+	assert.Equal(t, "translated", reply.Text)
+	sent := mock.requests[1].Messages
+	assert.Contains(t, sent[0].Content, "## Agent: assistant")
+	assert.Equal(t, llm.SystemMessage("Translate to French: good morning"), sent[1])
+	assert.Equal(t, llm.UserMessage("good morning"), sent[len(sent)-1])
+
+	// Built-in commands are handled locally
+	reply, err = session.Send(ctx, "/ping x")
+	require.NoError(t, err)
+	assert.Equal(t, "pong x", reply.Text)
+	assert.Nil(t, reply.Response)
+
+	reply, err = session.Send(ctx, "/help")
+	require.NoError(t, err)
+	assert.Contains(t, reply.Text, "/translate (aliases: /tr) - Translate text")
+	assert.NotContains(t, reply.Text, "/secret")
+	assert.Contains(t, reply.Text, "/ping - Reply with pong")
+
+	_, err = session.Send(ctx, "/clear")
+	require.NoError(t, err)
+	assert.Equal(t, 0, runner.Context().HistoryLen())
+
+	_, err = session.Send(ctx, "/unknown")
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+	assert.Len(t, mock.requests, 2)
+}
+
+func TestChatSession_CommandOutput(t *testing.T) {
+	_, name := registerScripted(t,
+		textResponse("```json\n{\"severity\": \"high\"}\n```"),
+		textResponse("It looks serious."),
+	)
+	p := &Plugin{
+		Name: "test",
+		Commands: []Command{{
+			Name:         "triage",
+			Content:      "Triage the issue.",
+			OutputSchema: json.RawMessage(`{"type": "object", "properties": {"severity": {"type": "string"}}, "required": ["severity"]}`),
+		}},
+	}
+	runner := (&Agent{Name: "assistant"}).NewRunner(WithAgentProvider(name), WithAgentModel("test-model"))
+	session := NewChatSession(p, runner)
+	ctx := context.Background()
+
+	reply, err := session.Send(ctx, "/triage login fails")
+	require.NoError(t, err)
+	var triage struct {
+		Severity string `json:"severity"`
+	}
+	require.NoError(t, reply.DecodeOutput(&triage))
+	assert.Equal(t, "high", triage.Severity)
+
+	reply, err = session.Send(ctx, "/triage again")
+	var outputErr *CommandOutputError
+	require.ErrorAs(t, err, &outputErr)
+	require.NotNil(t, reply)
+	assert.Equal(t, "It looks serious.", reply.Text)
+	assert.Nil(t, reply.Output)
+	assert.Error(t, reply.DecodeOutput(&triage))
+}
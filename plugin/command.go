@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"encoding/json"
 	"errors"
 	"strings"
 
@@ -31,7 +32,8 @@ func (p *Plugin) IsCommand(input string) bool {
 
 // ExpandCommand expands a command from user input.
 // Input: "/greet John" → finds "greet" command, extracts "John" as argument.
-// The command's Content is used as SystemMessage with $ARGUMENTS replaced.
+// The command's Content is used as SystemMessage with $ARGUMENTS replaced,
+// followed by the instructions of its output contract, if any.
 func (p *Plugin) ExpandCommand(input string) (*ExpandedCommand, error) {
 	input = strings.TrimSpace(input)
 
@@ -61,6 +63,7 @@ func (p *Plugin) ExpandCommand(input string) (*ExpandedCommand, error) {
 	if arguments != "" {
 		systemMessage = strings.ReplaceAll(systemMessage, "$ARGUMENTS", arguments)
 	}
+	systemMessage = cmd.withOutputInstructions(systemMessage)
 
 	return &ExpandedCommand{
 		Command:       cmd,
@@ -87,16 +90,27 @@ func ParseCommandInput(input string) (cmdName, arguments string) {
 	return cmdName, arguments
 }
 
+// ParseOutput parses the model's answer to the command according to its
+// output contract (see Command.ParseOutput).
+func (e *ExpandedCommand) ParseOutput(text string) (json.RawMessage, error) {
+	return e.Command.ParseOutput(text)
+}
+
 // ToOption converts an ExpandedCommand to an llm.Option.
-// This adds the expanded command's system message to the LLM call.
+// This adds the expanded command's system message to the LLM call as a
+// section of the skill layer of the system prompt.
 func (e *ExpandedCommand) ToOption() llm.Option {
-	return llm.WithSystemMessage(e.SystemMessage)
+	name := ""
+	if e.Command != nil {
+		name = e.Command.Name
+	}
+	return commandSection(name, e.SystemMessage)
 }
 
 // ToOption converts a Command to an llm.Option.
 // This adds the command's content as system message to the LLM call.
 func (c *Command) ToOption() llm.Option {
-	return llm.WithSystemMessage(c.ToSystemMessage())
+	return commandSection(c.Name, c.ToSystemMessage())
 }
 
 // ToOptionWithArgs converts a Command to an llm.Option with argument substitution.
@@ -106,7 +120,17 @@ func (c *Command) ToOptionWithArgs(arguments string) llm.Option {
 	if arguments != "" {
 		content = strings.ReplaceAll(content, "$ARGUMENTS", arguments)
 	}
-	return llm.WithSystemMessage(content)
+	return commandSection(c.Name, c.withOutputInstructions(content))
+}
+
+// commandSection returns an llm.Option that adds the system message of the
+// named command to the skill layer of the system prompt.
+func commandSection(name, text string) llm.Option {
+	return llm.WithSystemSection(llm.SystemSection{
+		Name:  "command:" + name,
+		Text:  text,
+		Order: llm.SystemOrderSkill,
+	})
 }
 
 // ProcessInput processes user input and returns the appropriate llm.Option.
@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/schema"
+)
+
+// Output formats of commands, set with the output-format frontmatter key.
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
+// CommandOutputError is returned when the answer to a command does not meet
+// its output contract.
+type CommandOutputError struct {
+	Command string
+	Output  string // The model's answer
+	Cause   error
+}
+
+func (e *CommandOutputError) Error() string {
+	return fmt.Sprintf("output of command /%s: %v", e.Command, e.Cause)
+}
+
+func (e *CommandOutputError) Unwrap() error {
+	return e.Cause
+}
+
+// setOutputContract sets the output contract of c from its frontmatter:
+// format is "text" or "json", and outputSchema is an inline schema or the
+// path of a JSON schema file, relative to the command file.
+func (c *Command) setOutputContract(format string, outputSchema any) error {
+	switch format {
+	case "", OutputFormatText, OutputFormatJSON:
+		c.OutputFormat = format
+	default:
+		return fmt.Errorf("unknown output-format %q (want %q or %q)", format, OutputFormatText, OutputFormatJSON)
+	}
+
+	switch v := outputSchema.(type) {
+	case nil:
+		return nil
+	case string:
+		path := v
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(c.FilePath), path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading output-schema: %w", err)
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("output-schema %s is not valid JSON", v)
+		}
+		c.OutputSchema = data
+	case map[string]any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("encoding output-schema: %w", err)
+		}
+		c.OutputSchema = data
+	default:
+		return fmt.Errorf("output-schema must be a mapping or a file path, got %T", outputSchema)
+	}
+	if c.OutputFormat == OutputFormatText {
+		return fmt.Errorf("output-schema requires output-format %q", OutputFormatJSON)
+	}
+	c.OutputFormat = OutputFormatJSON
+	return nil
+}
+
+// HasOutputContract reports whether the command requires a JSON answer.
+func (c *Command) HasOutputContract() bool {
+	return c.OutputFormat == OutputFormatJSON || len(c.OutputSchema) > 0
+}
+
+// OutputInstructions returns the instructions telling the model to answer
+// in the command's output format, or "" for text commands. They are added
+// to the system message of expanded commands.
+func (c *Command) OutputInstructions() string {
+	if !c.HasOutputContract() {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("**Output format:** Answer with a single JSON value and nothing else")
+	if len(c.OutputSchema) > 0 {
+		sb.WriteString(", valid against this JSON schema:\n\n```json\n")
+		sb.Write(c.OutputSchema)
+		sb.WriteString("\n```")
+	} else {
+		sb.WriteString(".")
+	}
+	return sb.String()
+}
+
+// ParseOutput parses the model's answer to the command according to its
+// output contract. For JSON commands, it extracts the JSON value of text,
+// which may be fenced or surrounded with prose (see llm.ExtractJSON), and
+// validates it against the output schema, returning a *CommandOutputError
+// if it is missing or invalid. For text commands, it returns text as a
+// JSON string.
+//
+// Example:
+//
+//	// commands/triage.md:
+//	// ---
+//	// description: Triage an issue
+//	// output-schema:
+//	//   type: object
+//	//   properties:
+//	//     severity: {type: string, enum: [low, medium, high]}
+//	//   required: [severity]
+//	// ---
+//	out, err := cmd.ParseOutput(resp.Text())
+//	if err != nil {
+//	    return err
+//	}
+//	var triage struct{ Severity string `json:"severity"` }
+//	err = json.Unmarshal(out, &triage)
+func (c *Command) ParseOutput(text string) (json.RawMessage, error) {
+	if !c.HasOutputContract() {
+		return json.Marshal(text)
+	}
+	raw, err := llm.ExtractJSON(text)
+	if err != nil {
+		return nil, &CommandOutputError{Command: c.Name, Output: text, Cause: err}
+	}
+	if len(c.OutputSchema) > 0 {
+		if err := schema.Validate(c.OutputSchema, raw); err != nil {
+			return nil, &CommandOutputError{Command: c.Name, Output: text, Cause: err}
+		}
+	}
+	return raw, nil
+}
+
+// withOutputInstructions appends the output instructions of c, if any, to
+// the system message of the command.
+func (c *Command) withOutputInstructions(systemMessage string) string {
+	instructions := c.OutputInstructions()
+	if instructions == "" {
+		return systemMessage
+	}
+	if systemMessage == "" {
+		return instructions
+	}
+	return systemMessage + "\n\n" + instructions
+}
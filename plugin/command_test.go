@@ -1,10 +1,16 @@
 package plugin
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
 )
 
 func TestPlugin_IsCommand(t *testing.T) {
@@ -322,3 +328,121 @@ func TestErrCommandNotFound(t *testing.T) {
 	assert.NotNil(t, ErrCommandNotFound)
 	assert.Contains(t, ErrCommandNotFound.Error(), "not found")
 }
+
+func TestParseCommand_OutputContract(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	t.Run("inline schema", func(t *testing.T) {
+		cmd, err := ParseCommand(write("triage.md", `---
+description: Triage an issue
+output-schema:
+  type: object
+  properties:
+    severity: {type: string, enum: [low, high]}
+  required: [severity]
+---
+Triage $ARGUMENTS`))
+		require.NoError(t, err)
+		assert.Equal(t, OutputFormatJSON, cmd.OutputFormat)
+		assert.JSONEq(t, `{"type": "object", "properties": {"severity": {"type": "string", "enum": ["low", "high"]}}, "required": ["severity"]}`, string(cmd.OutputSchema))
+	})
+
+	t.Run("schema file", func(t *testing.T) {
+		write("labels.schema.json", `{"type": "array", "items": {"type": "string"}}`)
+		cmd, err := ParseCommand(write("labels.md", "---\noutput-schema: labels.schema.json\n---\nLabel it"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type": "array", "items": {"type": "string"}}`, string(cmd.OutputSchema))
+	})
+
+	t.Run("format only", func(t *testing.T) {
+		cmd, err := ParseCommand(write("data.md", "---\noutput-format: json\n---\nData"))
+		require.NoError(t, err)
+		assert.True(t, cmd.HasOutputContract())
+		assert.Nil(t, cmd.OutputSchema)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseCommand(write("bad.md", "---\noutput-format: xml\n---\nBad"))
+		assert.ErrorContains(t, err, `unknown output-format "xml"`)
+
+		_, err = ParseCommand(write("missing.md", "---\noutput-schema: missing.json\n---\nBad"))
+		assert.ErrorContains(t, err, "reading output-schema")
+
+		_, err = ParseCommand(write("text.md", "---\noutput-format: text\noutput-schema: {type: object}\n---\nBad"))
+		assert.ErrorContains(t, err, `output-schema requires output-format "json"`)
+	})
+}
+
+func TestCommand_ParseOutput(t *testing.T) {
+	cmd := &Command{
+		Name:         "triage",
+		Content:      "Triage $ARGUMENTS",
+		OutputSchema: json.RawMessage(`{"type": "object", "properties": {"severity": {"type": "string", "enum": ["low", "high"]}}, "required": ["severity"]}`),
+	}
+
+	out, err := cmd.ParseOutput("Here you go:\n```json\n{\"severity\": \"high\"}\n```")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"severity": "high"}`, string(out))
+
+	_, err = cmd.ParseOutput(`{"severity": "urgent"}`)
+	var outputErr *CommandOutputError
+	require.ErrorAs(t, err, &outputErr)
+	assert.Equal(t, "triage", outputErr.Command)
+	assert.Contains(t, err.Error(), "output of command /triage:")
+
+	_, err = cmd.ParseOutput("It is urgent.")
+	assert.ErrorIs(t, err, llm.ErrNoJSON)
+
+	// Text commands return the text as a JSON string
+	out, err = (&Command{Name: "greet"}).ParseOutput("Hello")
+	require.NoError(t, err)
+	assert.Equal(t, `"Hello"`, string(out))
+
+	// Expanded commands and options carry the output instructions
+	p := &Plugin{Commands: []Command{*cmd}}
+	expanded, err := p.ExpandCommand("/triage login fails")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(expanded.SystemMessage, "Triage login fails\n\n**Output format:** Answer with a single JSON value"))
+	assert.Contains(t, expanded.SystemMessage, `"enum": ["low", "high"]`)
+	assert.Contains(t, cmd.ToSystemMessage(), "**Output format:**")
+}
+
+func TestCommand_AliasesAndHidden(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "commit.md")
+	require.NoError(t, os.WriteFile(path, []byte("---\ndescription: Write a commit message\naliases: [ci, c]\n---\nCommit $ARGUMENTS"), 0o644))
+	cmd, err := ParseCommand(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci", "c"}, cmd.Aliases)
+	assert.False(t, cmd.Hidden)
+
+	p := &Plugin{
+		Commands: []Command{
+			*cmd,
+			{Name: "debug-dump", Description: "Dump internal state", Hidden: true, Content: "Dump"},
+		},
+	}
+
+	// Aliases resolve to the command
+	assert.Equal(t, "commit", p.GetCommand("ci").Name)
+	expanded, err := p.ExpandCommand("/c fix typo")
+	require.NoError(t, err)
+	assert.Equal(t, "commit", expanded.Command.Name)
+	assert.Equal(t, "Commit fix typo", expanded.SystemMessage)
+
+	// Hidden commands are left out of the indexes but can still be invoked
+	index := p.CommandsIndex()
+	require.Len(t, index, 1)
+	assert.Equal(t, []string{"ci", "c"}, index[0].Aliases)
+	assert.Contains(t, p.CommandsIndexSystemMessage(), "- /commit (aliases: /ci, /c): Write a commit message")
+	assert.NotContains(t, p.CommandsIndexSystemMessage(), "debug-dump")
+	assert.NotContains(t, p.CommandsSystemMessage(), "debug-dump")
+	assert.NotContains(t, p.ToSystemMessage(), "debug-dump")
+	_, err = p.ExpandCommand("/debug-dump")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// CompactOption configures AgentRunner.Compact.
+type CompactOption func(*compactConfig)
+
+// compactConfig holds the configuration of Compact.
+type compactConfig struct {
+	instructions string
+	keepLast     int
+	llmOpts      []llm.Option
+}
+
+// CompactInstructions adds instructions for the summary, e.g. "Focus on the
+// API design decisions".
+func CompactInstructions(instructions string) CompactOption {
+	return func(c *compactConfig) {
+		c.instructions = instructions
+	}
+}
+
+// CompactKeepLast keeps the last n messages verbatim after the summary.
+// By default, the whole conversation is summarized.
+func CompactKeepLast(n int) CompactOption {
+	return func(c *compactConfig) {
+		c.keepLast = n
+	}
+}
+
+// CompactLLMOptions sets options of the summarization call, e.g. a cheaper
+// model. They apply over the runner's provider and model.
+func CompactLLMOptions(opts ...llm.Option) CompactOption {
+	return func(c *compactConfig) {
+		c.llmOpts = append(c.llmOpts, opts...)
+	}
+}
+
+// CompactResult describes the effect of AgentRunner.Compact.
+type CompactResult struct {
+	Summary        string // Summary that replaced the messages; "" if there was nothing to compact
+	Messages       int    // Number of messages summarized
+	TokensBefore   int    // Estimated tokens of the history before compaction
+	TokensAfter    int    // Estimated tokens of the history after compaction
+	MessagesBefore int    // Number of messages in the history before compaction
+	MessagesAfter  int    // Number of messages in the history after compaction
+}
+
+// Compact summarizes the conversation history with the LLM and replaces it
+// with the summary as a system message, keeping the state, the system
+// messages of the history, and the last messages set by CompactKeepLast.
+// Summaries of earlier compactions are folded into the new one. The
+// compacted context is saved to the runner's store, if any. Use it to keep
+// long sessions within the model's context window; ChatSession offers it as
+// the built-in /compact command.
+//
+// Example:
+//
+//	result, err := runner.Compact(ctx,
+//	    plugin.CompactInstructions("Keep the list of files changed so far"),
+//	    plugin.CompactKeepLast(4),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Printf("Compacted %d messages (%d -> %d tokens)\n", result.Messages, result.TokensBefore, result.TokensAfter)
+func (r *AgentRunner) Compact(ctx context.Context, opts ...CompactOption) (CompactResult, error) {
+	cfg := &compactConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if err := r.beginRun(); err != nil {
+		return CompactResult{}, err
+	}
+	defer r.endRun()
+	if err := r.loadContext(ctx); err != nil {
+		return CompactResult{}, err
+	}
+
+	var result CompactResult
+	policy := HistoryPolicyFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		result.MessagesBefore = len(history)
+		result.TokensBefore = llm.EstimateMessagesTokens(history)
+
+		var system, summaries, rest []llm.Message
+		for _, msg := range history {
+			switch {
+			case msg.Role == llm.RoleSystem && strings.HasPrefix(msg.Content, summaryPrefix):
+				summaries = append(summaries, msg)
+			case msg.Role == llm.RoleSystem:
+				system = append(system, msg)
+			default:
+				rest = append(rest, msg)
+			}
+		}
+		cut := len(rest)
+		if cfg.keepLast > 0 {
+			cut = safeCut(rest, len(rest)-cfg.keepLast)
+		}
+		if cut == 0 && len(summaries) <= 1 {
+			return history, nil
+		}
+		toSummarize := append(summaries, rest[:cut]...)
+
+		summary, err := llm.SummarizeConversation(ctx, toSummarize, r.compactLLMOptions(cfg)...)
+		if err != nil {
+			return nil, err
+		}
+		result.Summary = summary
+		result.Messages = len(toSummarize)
+
+		compacted := make([]llm.Message, 0, len(system)+1+len(rest)-cut)
+		compacted = append(compacted, system...)
+		compacted = append(compacted, llm.SystemMessage(summaryPrefix+summary))
+		return append(compacted, rest[cut:]...), nil
+	})
+	if err := r.context.ApplyHistoryPolicy(ctx, policy); err != nil {
+		return CompactResult{}, fmt.Errorf("compacting history: %w", err)
+	}
+	result.MessagesAfter = r.context.HistoryLen()
+	result.TokensAfter = r.context.HistoryTokens()
+
+	if result.Summary != "" {
+		if err := r.saveContext(ctx); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// compactLLMOptions returns the options of the summarization call of Compact.
+func (r *AgentRunner) compactLLMOptions(cfg *compactConfig) []llm.Option {
+	var opts []llm.Option
+	if r.providerName != "" {
+		opts = append(opts, llm.WithProvider(r.providerName))
+	}
+	if r.model != "" {
+		opts = append(opts, llm.WithModel(r.model))
+	}
+	if userID := r.context.UserID(); userID != "" {
+		opts = append(opts, llm.WithUser(userID))
+	}
+	if cfg.instructions != "" {
+		opts = append(opts, llm.WithSystemSection(llm.SystemSection{
+			Name:  "compact-instructions",
+			Text:  "Additional instructions for the summary: " + cfg.instructions,
+			Order: llm.SystemOrderRun,
+		}))
+	}
+	return append(opts, cfg.llmOpts...)
+}
+
+// chatCompact summarizes the conversation history; args are instructions
+// for the summary.
+func chatCompact(ctx context.Context, s *ChatSession, args string) (string, error) {
+	var opts []CompactOption
+	if args = strings.TrimSpace(args); args != "" {
+		opts = append(opts, CompactInstructions(args))
+	}
+	result, err := s.runner.Compact(ctx, opts...)
+	if err != nil {
+		return "", err
+	}
+	if result.Summary == "" {
+		return "Nothing to compact.", nil
+	}
+	return fmt.Sprintf("Conversation compacted: %d messages summarized (~%d -> ~%d tokens).",
+		result.Messages, result.TokensBefore, result.TokensAfter), nil
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestAgentRunner_Compact(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileContextStore(t.TempDir())
+	require.NoError(t, err)
+	mock, name := registerScripted(t,
+		textResponse("The user likes Go."),
+		textResponse("The user likes Go and Rust."),
+	)
+
+	runner := (&Agent{Name: "assistant"}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentContextStore(store, "s1"),
+	)
+	agentCtx := runner.Context()
+	agentCtx.SetState("lang", "go")
+	agentCtx.AddMessages(
+		llm.SystemMessage("Pinned note"),
+		llm.UserMessage("I like Go"),
+		llm.AssistantMessage("Noted"),
+	)
+
+	result, err := runner.Compact(ctx, CompactInstructions("Focus on preferences"))
+	require.NoError(t, err)
+	assert.Equal(t, "The user likes Go.", result.Summary)
+	assert.Equal(t, 2, result.Messages)
+	assert.Equal(t, 3, result.MessagesBefore)
+	assert.Equal(t, 2, result.MessagesAfter)
+	assert.Equal(t, []llm.Message{
+		llm.SystemMessage("Pinned note"),
+		llm.SystemMessage(summaryPrefix + "The user likes Go."),
+	}, agentCtx.History())
+	v, _ := agentCtx.GetState("lang")
+	assert.Equal(t, "go", v)
+
+	// The summarization call got the transcript and the instructions
+	req := mock.requests[0]
+	assert.Equal(t, "test-model", req.Model)
+	assert.Contains(t, req.Messages[len(req.Messages)-1].Content, "user: I like Go")
+	var system string
+	for _, msg := range req.Messages {
+		if msg.Role == llm.RoleSystem {
+			system += msg.Content
+		}
+	}
+	assert.Contains(t, system, "Focus on preferences")
+
+	// The compacted context was saved
+	saved, err := store.Load(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, agentCtx.History(), saved.History())
+
+	// A later compaction folds in the earlier summary and keeps the last messages
+	agentCtx.AddMessages(
+		llm.UserMessage("I like Rust too"),
+		llm.AssistantMessage("Noted"),
+		llm.UserMessage("What now?"),
+	)
+	result, err = runner.Compact(ctx, CompactKeepLast(1))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Messages)
+	assert.Contains(t, mock.requests[1].Messages[len(mock.requests[1].Messages)-1].Content, "The user likes Go.")
+	assert.Equal(t, []llm.Message{
+		llm.SystemMessage("Pinned note"),
+		llm.SystemMessage(summaryPrefix + "The user likes Go and Rust."),
+		llm.UserMessage("What now?"),
+	}, agentCtx.History())
+
+	// Nothing left to compact
+	result, err = runner.Compact(ctx, CompactKeepLast(1))
+	require.NoError(t, err)
+	assert.Empty(t, result.Summary)
+	assert.Len(t, mock.requests, 2)
+}
+
+func TestChatSession_Compact(t *testing.T) {
+	_, name := registerScripted(t, textResponse("hello"), textResponse("Greetings were exchanged."))
+	runner := (&Agent{Name: "assistant"}).NewRunner(WithAgentProvider(name), WithAgentModel("test-model"))
+	session := NewChatSession(nil, runner)
+	ctx := context.Background()
+
+	reply, err := session.Send(ctx, "/compact")
+	require.NoError(t, err)
+	assert.Equal(t, "Nothing to compact.", reply.Text)
+
+	_, err = session.Send(ctx, "hi")
+	require.NoError(t, err)
+	reply, err = session.Send(ctx, "/compact")
+	require.NoError(t, err)
+	assert.Contains(t, reply.Text, "Conversation compacted: 2 messages summarized")
+	assert.Equal(t, 1, runner.Context().HistoryLen())
+
+	reply, err = session.Send(ctx, "/help")
+	require.NoError(t, err)
+	assert.Contains(t, reply.Text, "/compact - Summarize the conversation history")
+}
@@ -21,6 +21,11 @@ func (c *Command) ToSystemMessage() string {
 		sb.WriteString(c.Content)
 	}
 
+	if instructions := c.OutputInstructions(); instructions != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(instructions)
+	}
+
 	return sb.String()
 }
 
@@ -71,7 +76,7 @@ func (s *Skill) ToSystemMessage() string {
 }
 
 // ToSystemMessage converts the entire Plugin to a comprehensive system message.
-// This includes all commands, agents, and skills defined in the plugin.
+// This includes all commands (except hidden ones), agents, and skills defined in the plugin.
 func (p *Plugin) ToSystemMessage() string {
 	var sb strings.Builder
 
@@ -83,9 +88,9 @@ func (p *Plugin) ToSystemMessage() string {
 	}
 
 	// Commands section
-	if len(p.Commands) > 0 {
+	if commands := p.visibleCommands(); len(commands) > 0 {
 		sb.WriteString("---\n\n# Available Commands\n\n")
-		for _, cmd := range p.Commands {
+		for _, cmd := range commands {
 			sb.WriteString(cmd.ToSystemMessage())
 			sb.WriteString("\n\n")
 		}
@@ -112,15 +117,17 @@ func (p *Plugin) ToSystemMessage() string {
 	return strings.TrimSpace(sb.String())
 }
 
-// CommandsSystemMessage returns a system message with only the commands.
+// CommandsSystemMessage returns a system message with only the commands,
+// except hidden ones.
 func (p *Plugin) CommandsSystemMessage() string {
-	if len(p.Commands) == 0 {
+	commands := p.visibleCommands()
+	if len(commands) == 0 {
 		return ""
 	}
 
 	var sb strings.Builder
 	sb.WriteString("# Available Commands\n\n")
-	for _, cmd := range p.Commands {
+	for _, cmd := range commands {
 		sb.WriteString(cmd.ToSystemMessage())
 		sb.WriteString("\n\n")
 	}
@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// HistoryPolicy trims or compacts conversation history, e.g. to keep it
+// within the model's context window. Policies must not modify the input slice.
+type HistoryPolicy interface {
+	Apply(ctx context.Context, history []llm.Message) ([]llm.Message, error)
+}
+
+// HistoryPolicyFunc adapts a function to the HistoryPolicy interface.
+type HistoryPolicyFunc func(ctx context.Context, history []llm.Message) ([]llm.Message, error)
+
+// Apply implements HistoryPolicy.
+func (f HistoryPolicyFunc) Apply(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+	return f(ctx, history)
+}
+
+// MaxMessagesPolicy keeps only the last n messages.
+func MaxMessagesPolicy(n int) HistoryPolicy {
+	return HistoryPolicyFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		if len(history) <= n {
+			return history, nil
+		}
+		return copyMessages(history[safeCut(history, len(history)-n):]), nil
+	})
+}
+
+// KeepSystemAndLastNPolicy keeps all system messages plus the last n other messages.
+func KeepSystemAndLastNPolicy(n int) HistoryPolicy {
+	return HistoryPolicyFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		system, rest := splitSystem(history)
+		if len(rest) <= n {
+			return history, nil
+		}
+		return append(system, rest[safeCut(rest, len(rest)-n):]...), nil
+	})
+}
+
+// MaxTokensPolicy drops the oldest non-system messages until the estimated
+// token count of the history is at most maxTokens.
+// Tokens are estimated with llm.EstimateMessagesTokens.
+func MaxTokensPolicy(maxTokens int) HistoryPolicy {
+	return HistoryPolicyFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		if llm.EstimateMessagesTokens(history) <= maxTokens {
+			return history, nil
+		}
+
+		system, rest := splitSystem(history)
+		budget := maxTokens - llm.EstimateMessagesTokens(system)
+
+		// Walk backwards keeping as many recent messages as fit the budget
+		start := len(rest)
+		used := 0
+		for start > 0 {
+			n := llm.EstimateMessageTokens(rest[start-1])
+			if used+n > budget {
+				break
+			}
+			used += n
+			start--
+		}
+		return append(system, rest[safeCut(rest, start):]...), nil
+	})
+}
+
+// SummarizeOverflowPolicy summarizes older messages with an LLM call once the
+// estimated token count of the history exceeds maxTokens.
+// The last keepLast messages are kept verbatim; older non-system messages are
+// replaced by a single system message containing the summary.
+// opts configure the summarization call and must include the provider and model.
+//
+// Example:
+//
+//	policy := plugin.SummarizeOverflowPolicy(50000, 10,
+//	    llm.WithProvider("anthropic"),
+//	    llm.WithModel("claude-haiku-4-5"),
+//	)
+func SummarizeOverflowPolicy(maxTokens, keepLast int, opts ...llm.Option) HistoryPolicy {
+	return HistoryPolicyFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		if llm.EstimateMessagesTokens(history) <= maxTokens {
+			return history, nil
+		}
+
+		system, rest := splitSystem(history)
+		if len(rest) <= keepLast {
+			return history, nil
+		}
+		cut := safeCut(rest, len(rest)-keepLast)
+		if cut == 0 {
+			return history, nil
+		}
+
+		summary, err := llm.SummarizeConversation(ctx, rest[:cut], opts...)
+		if err != nil {
+			return nil, fmt.Errorf("summarizing history: %w", err)
+		}
+
+		result := make([]llm.Message, 0, len(system)+1+len(rest)-cut)
+		result = append(result, system...)
+		result = append(result, llm.SystemMessage(summaryPrefix+summary))
+		result = append(result, rest[cut:]...)
+		return result, nil
+	})
+}
+
+// summaryPrefix introduces a summary of earlier conversation in the history.
+const summaryPrefix = "Summary of the earlier conversation:\n\n"
+
+// splitSystem separates system messages from the other messages, preserving order.
+func splitSystem(history []llm.Message) (system, rest []llm.Message) {
+	for _, msg := range history {
+		if msg.Role == llm.RoleSystem {
+			system = append(system, msg)
+		} else {
+			rest = append(rest, msg)
+		}
+	}
+	return system, rest
+}
+
+// safeCut adjusts a cut index so the kept messages do not start with tool
+// results whose tool calls were dropped.
+func safeCut(msgs []llm.Message, start int) int {
+	if start < 0 {
+		start = 0
+	}
+	for start < len(msgs) && msgs[start].Role == llm.RoleTool {
+		start++
+	}
+	return start
+}
+
+// copyMessages returns a copy of msgs.
+func copyMessages(msgs []llm.Message) []llm.Message {
+	result := make([]llm.Message, len(msgs))
+	copy(result, msgs)
+	return result
+}
+
+// ApplyHistoryPolicy applies policies to the conversation history in order
+// and replaces the history with the result.
+func (c *AgentContext) ApplyHistoryPolicy(ctx context.Context, policies ...HistoryPolicy) error {
+	if len(policies) == 0 {
+		return nil
+	}
+
+	history := c.History()
+	for _, policy := range policies {
+		var err error
+		history, err = policy.Apply(ctx, history)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.times = matchTimes(c.history, c.syncedTimes(), history)
+	c.history = copyMessages(history)
+	return nil
+}
@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func testHistory() []llm.Message {
+	return []llm.Message{
+		llm.SystemMessage("system"),
+		llm.UserMessage("one"),
+		llm.AssistantMessageWithToolCalls("", []llm.ToolCall{{ID: "1", Name: "echo", Arguments: `{}`}}),
+		llm.ToolMessage("1", "result"),
+		llm.AssistantMessage("two"),
+		llm.UserMessage("three"),
+		llm.AssistantMessage("four"),
+	}
+}
+
+func TestHistoryPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		policy   HistoryPolicy
+		expected []string
+	}{
+		{
+			name:     "max messages",
+			policy:   MaxMessagesPolicy(3),
+			expected: []string{"two", "three", "four"},
+		},
+		{
+			name:     "max messages skips orphan tool result",
+			policy:   MaxMessagesPolicy(4),
+			expected: []string{"two", "three", "four"},
+		},
+		{
+			name:     "keep system and last n",
+			policy:   KeepSystemAndLastNPolicy(2),
+			expected: []string{"system", "three", "four"},
+		},
+		{
+			name:     "max tokens",
+			policy:   MaxTokensPolicy(22),
+			expected: []string{"system", "two", "three", "four"},
+		},
+		{
+			name:     "under limit",
+			policy:   MaxMessagesPolicy(100),
+			expected: []string{"system", "one", "", "result", "two", "three", "four"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.policy.Apply(ctx, testHistory())
+			require.NoError(t, err)
+
+			var contents []string
+			for _, msg := range result {
+				contents = append(contents, msg.Content)
+			}
+			assert.Equal(t, tt.expected, contents)
+		})
+	}
+}
+
+func TestSummarizeOverflowPolicy(t *testing.T) {
+	mock, name := registerScripted(t, textResponse("they said one"))
+
+	policy := SummarizeOverflowPolicy(10, 2,
+		llm.WithProvider(name),
+		llm.WithModel("test-model"),
+	)
+	result, err := policy.Apply(context.Background(), testHistory())
+	require.NoError(t, err)
+
+	require.Len(t, result, 4)
+	assert.Equal(t, "system", result[0].Content)
+	assert.Equal(t, llm.RoleSystem, result[1].Role)
+	assert.True(t, strings.HasSuffix(result[1].Content, "they said one"))
+	assert.Equal(t, "three", result[2].Content)
+
+	// The summarized messages are sent to the LLM as a transcript
+	require.Len(t, mock.requests, 1)
+	sent := mock.requests[0].Messages
+	assert.Contains(t, sent[len(sent)-1].Content, "user: one")
+	assert.Contains(t, sent[len(sent)-1].Content, "called tool echo")
+}
+
+func TestAgentRunner_HistoryPolicy(t *testing.T) {
+	mock, name := registerScripted(t, textResponse("first"), textResponse("second"))
+
+	agent := &Agent{Name: "helper"}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentHistoryPolicy(MaxMessagesPolicy(1)),
+	)
+	ctx := context.Background()
+
+	_, err := runner.Run(ctx, "one")
+	require.NoError(t, err)
+	_, err = runner.Run(ctx, "two")
+	require.NoError(t, err)
+
+	// Only the last message of the first run is sent with the second
+	sent := mock.requests[1].Messages
+	require.Len(t, sent, 3)
+	assert.Equal(t, llm.RoleSystem, sent[0].Role)
+	assert.Equal(t, "first", sent[1].Content)
+	assert.Equal(t, "two", sent[2].Content)
+}
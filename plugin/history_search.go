@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// HistoryEntry is a message of the conversation history with the time it
+// was added.
+type HistoryEntry struct {
+	Index   int // Position of the message in the history
+	Message llm.Message
+	Time    time.Time // Zero if unknown, e.g. for histories saved before timestamps
+}
+
+// HistoryQuery selects messages of the conversation history. Its zero value
+// matches every message.
+type HistoryQuery struct {
+	// Roles limits the search to messages of these roles.
+	Roles []llm.Role
+
+	// Contains limits the search to messages whose content or tool call
+	// arguments contain this text, ignoring case.
+	Contains string
+
+	// Since and Until limit the search to messages added in [Since, Until).
+	// Messages without a timestamp never match a time range.
+	Since time.Time
+	Until time.Time
+
+	// Limit keeps only the last Limit matches, if positive.
+	Limit int
+}
+
+// matches reports whether e matches the query; contains is q.Contains in
+// lower case.
+func (q *HistoryQuery) matches(e HistoryEntry, contains string) bool {
+	if len(q.Roles) > 0 && !slices.Contains(q.Roles, e.Message.Role) {
+		return false
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		if e.Time.IsZero() || (!q.Since.IsZero() && e.Time.Before(q.Since)) || (!q.Until.IsZero() && !e.Time.Before(q.Until)) {
+			return false
+		}
+	}
+	if contains == "" {
+		return true
+	}
+	if strings.Contains(strings.ToLower(e.Message.Content), contains) {
+		return true
+	}
+	for _, tc := range e.Message.ToolCalls {
+		if strings.Contains(strings.ToLower(tc.Arguments), contains) {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryEntries returns a copy of the conversation history with the time
+// each message was added.
+func (c *AgentContext) HistoryEntries() []HistoryEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	times := c.syncedTimes()
+	entries := make([]HistoryEntry, len(c.history))
+	for i, msg := range c.history {
+		entries[i] = HistoryEntry{Index: i, Message: msg, Time: times[i]}
+	}
+	return entries
+}
+
+// SearchHistory returns the messages of the conversation history that match
+// q, oldest first.
+//
+// Example:
+//
+//	// What did the user ask about invoices in the last hour?
+//	entries := runner.Context().SearchHistory(plugin.HistoryQuery{
+//	    Roles:    []llm.Role{llm.RoleUser},
+//	    Contains: "invoice",
+//	    Since:    time.Now().Add(-time.Hour),
+//	})
+func (c *AgentContext) SearchHistory(q HistoryQuery) []HistoryEntry {
+	contains := strings.ToLower(q.Contains)
+	var result []HistoryEntry
+	for _, e := range c.HistoryEntries() {
+		if q.matches(e, contains) {
+			result = append(result, e)
+		}
+	}
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result
+}
+
+// Turns returns the user and assistant messages of the conversation history
+// that have text, leaving out system messages, tool results, and assistant
+// messages that only call tools.
+func (c *AgentContext) Turns() []llm.Message {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var turns []llm.Message
+	for _, msg := range c.history {
+		if (msg.Role == llm.RoleUser || msg.Role == llm.RoleAssistant) && strings.TrimSpace(msg.Content) != "" {
+			turns = append(turns, msg)
+		}
+	}
+	return turns
+}
+
+// HistoryTokens returns an estimate of the tokens used by the conversation
+// history (see llm.EstimateMessagesTokens).
+func (c *AgentContext) HistoryTokens() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return llm.EstimateMessagesTokens(c.history)
+}
+
+// syncTimes makes the timestamps as long as the history, with zero times
+// for messages without one. c.mu must be held for writing.
+func (c *AgentContext) syncTimes() {
+	c.times = c.syncedTimes()
+}
+
+// syncedTimes returns a copy of the timestamps as long as the history. c.mu
+// must be held.
+func (c *AgentContext) syncedTimes() []time.Time {
+	times := make([]time.Time, len(c.history))
+	copy(times, c.times)
+	return times
+}
+
+// matchTimes returns the timestamps of history, a new version of old (e.g.
+// trimmed by a history policy): messages kept from old, in order, keep their
+// timestamps, and new messages (e.g. summaries) are timestamped now.
+func matchTimes(old []llm.Message, oldTimes []time.Time, history []llm.Message) []time.Time {
+	now := time.Now()
+	times := make([]time.Time, len(history))
+	j := 0
+	for i, msg := range history {
+		times[i] = now
+		for k := j; k < len(old); k++ {
+			if reflect.DeepEqual(old[k], msg) {
+				times[i] = oldTimes[k]
+				j = k + 1
+				break
+			}
+		}
+	}
+	return times
+}
@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestAgentContext_SearchHistory(t *testing.T) {
+	c := NewAgentContext()
+	c.AddMessages(
+		llm.SystemMessage("You are helpful"),
+		llm.UserMessage("Where is my Invoice?"),
+		llm.AssistantMessageWithToolCalls("", []llm.ToolCall{{ID: "1", Name: "find", Arguments: `{"q": "invoice"}`}}),
+		llm.ToolMessage("1", "invoice #42"),
+		llm.AssistantMessage("Here is invoice #42"),
+	)
+	older := time.Now().Add(-2 * time.Hour)
+	c.times[1] = older
+	c.AddMessage(llm.UserMessage("Thanks"))
+
+	entries := c.HistoryEntries()
+	require.Len(t, entries, 6)
+	assert.Equal(t, older, entries[1].Time)
+	assert.Equal(t, 5, entries[5].Index)
+
+	tests := []struct {
+		name  string
+		query HistoryQuery
+		want  []int
+	}{
+		{"all", HistoryQuery{}, []int{0, 1, 2, 3, 4, 5}},
+		{"role", HistoryQuery{Roles: []llm.Role{llm.RoleUser}}, []int{1, 5}},
+		{"substring", HistoryQuery{Contains: "INVOICE"}, []int{1, 2, 3, 4}},
+		{"roles and substring", HistoryQuery{Roles: []llm.Role{llm.RoleUser, llm.RoleAssistant}, Contains: "invoice"}, []int{1, 2, 4}},
+		{"since", HistoryQuery{Since: time.Now().Add(-time.Hour)}, []int{0, 2, 3, 4, 5}},
+		{"until", HistoryQuery{Until: time.Now().Add(-time.Hour)}, []int{1}},
+		{"limit", HistoryQuery{Roles: []llm.Role{llm.RoleUser}, Limit: 1}, []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, e := range c.SearchHistory(tt.query) {
+				got = append(got, e.Index)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAgentContext_Turns(t *testing.T) {
+	c := NewAgentContext()
+	c.AddMessages(
+		llm.SystemMessage("You are helpful"),
+		llm.UserMessage("Add 1 and 2"),
+		llm.AssistantMessageWithToolCalls("", []llm.ToolCall{{ID: "1", Name: "add", Arguments: `{"a": 1, "b": 2}`}}),
+		llm.ToolMessage("1", "3"),
+		llm.AssistantMessage("It is 3"),
+	)
+	assert.Equal(t, []llm.Message{llm.UserMessage("Add 1 and 2"), llm.AssistantMessage("It is 3")}, c.Turns())
+	assert.Equal(t, llm.EstimateMessagesTokens(c.History()), c.HistoryTokens())
+	assert.Positive(t, c.HistoryTokens())
+}
+
+func TestAgentContext_Timestamps(t *testing.T) {
+	c := NewAgentContext()
+	c.AddMessages(llm.UserMessage("one"), llm.AssistantMessage("two"), llm.UserMessage("three"))
+	first := time.Now().Add(-time.Hour)
+	c.times = []time.Time{first, first.Add(time.Minute), first.Add(2 * time.Minute)}
+
+	// Timestamps survive a JSON round trip and cloning
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	restored := NewAgentContext()
+	require.NoError(t, json.Unmarshal(data, restored))
+	assert.True(t, restored.HistoryEntries()[2].Time.Equal(first.Add(2*time.Minute)))
+	assert.Equal(t, c.HistoryEntries(), c.Clone().HistoryEntries())
+
+	// Histories saved without timestamps have zero times
+	legacy := NewAgentContext()
+	require.NoError(t, json.Unmarshal([]byte(`{"history": [{"Role": "user", "Content": "hi"}], "state": {}}`), legacy))
+	legacy.AddMessage(llm.AssistantMessage("hello"))
+	entries := legacy.HistoryEntries()
+	assert.True(t, entries[0].Time.IsZero())
+	assert.False(t, entries[1].Time.IsZero())
+
+	// Messages kept by a history policy keep their timestamps; new ones
+	// (e.g. summaries) are timestamped now
+	require.NoError(t, c.ApplyHistoryPolicy(context.Background(), HistoryPolicyFunc(func(ctx context.Context, history []llm.Message) ([]llm.Message, error) {
+		return append([]llm.Message{llm.SystemMessage("summary")}, history[2:]...), nil
+	})))
+	entries = c.HistoryEntries()
+	require.Len(t, entries, 2)
+	assert.True(t, entries[0].Time.After(first.Add(time.Hour-time.Minute)))
+	assert.Equal(t, first.Add(2*time.Minute), entries[1].Time)
+
+	c.ClearHistory()
+	assert.Empty(t, c.HistoryEntries())
+}
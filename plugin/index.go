@@ -3,6 +3,8 @@ package plugin
 import (
 	"fmt"
 	"strings"
+
+	"github.com/i2y/bucephalus/llm"
 )
 
 // SkillIndex represents a skill's metadata for progressive disclosure.
@@ -16,6 +18,7 @@ type SkillIndex struct {
 type CommandIndex struct {
 	Name        string
 	Description string
+	Aliases     []string
 }
 
 // AgentIndex represents an agent's metadata for progressive disclosure.
@@ -28,29 +31,45 @@ type AgentIndex struct {
 // SkillsIndex returns metadata for all skills in the plugin.
 // Use this for progressive disclosure - present the list first,
 // then load full skill content only when needed.
-func (p *Plugin) SkillsIndex() []SkillIndex {
+// WithLocale selects localized descriptions.
+func (p *Plugin) SkillsIndex(opts ...IndexOption) []SkillIndex {
+	cfg := newIndexConfig(opts)
 	result := make([]SkillIndex, len(p.Skills))
-	for i, s := range p.Skills {
+	for i := range p.Skills {
 		result[i] = SkillIndex{
-			Name:        s.Name,
-			Description: s.Description,
+			Name:        p.Skills[i].Name,
+			Description: p.Skills[i].LocalizedDescription(cfg.locale),
 		}
 	}
 	return result
 }
 
-// CommandsIndex returns metadata for all commands in the plugin.
-func (p *Plugin) CommandsIndex() []CommandIndex {
-	result := make([]CommandIndex, len(p.Commands))
-	for i, c := range p.Commands {
-		result[i] = CommandIndex{
+// CommandsIndex returns metadata for all commands in the plugin, except
+// hidden ones. WithLocale selects localized descriptions.
+func (p *Plugin) CommandsIndex(opts ...IndexOption) []CommandIndex {
+	cfg := newIndexConfig(opts)
+	result := make([]CommandIndex, 0, len(p.Commands))
+	for _, c := range p.visibleCommands() {
+		result = append(result, CommandIndex{
 			Name:        c.Name,
-			Description: c.Description,
-		}
+			Description: c.LocalizedDescription(cfg.locale),
+			Aliases:     c.Aliases,
+		})
 	}
 	return result
 }
 
+// visibleCommands returns the commands that are not hidden.
+func (p *Plugin) visibleCommands() []*Command {
+	var commands []*Command
+	for i := range p.Commands {
+		if !p.Commands[i].Hidden {
+			commands = append(commands, &p.Commands[i])
+		}
+	}
+	return commands
+}
+
 // AgentsIndex returns metadata for all agents in the plugin.
 func (p *Plugin) AgentsIndex() []AgentIndex {
 	result := make([]AgentIndex, len(p.Agents))
@@ -67,20 +86,21 @@ func (p *Plugin) AgentsIndex() []AgentIndex {
 // SkillsIndexSystemMessage returns a compact skills list for system prompt.
 // This follows a progressive disclosure pattern (similar to Claude Code) - include only
 // metadata in the system prompt, load full content when skill is invoked.
+// WithLocale selects localized descriptions.
 //
 // Format:
 //
 //	<available_skills>
 //	- skill-name: Description of the skill
 //	</available_skills>
-func (p *Plugin) SkillsIndexSystemMessage() string {
+func (p *Plugin) SkillsIndexSystemMessage(opts ...IndexOption) string {
 	if len(p.Skills) == 0 {
 		return ""
 	}
 
 	var sb strings.Builder
 	sb.WriteString("<available_skills>\n")
-	for _, s := range p.Skills {
+	for _, s := range p.SkillsIndex(opts...) {
 		sb.WriteString(fmt.Sprintf("- %s: %s\n", s.Name, s.Description))
 	}
 	sb.WriteString("</available_skills>\n\n")
@@ -90,21 +110,24 @@ func (p *Plugin) SkillsIndexSystemMessage() string {
 }
 
 // CommandsIndexSystemMessage returns a compact commands list for system prompt.
+// Hidden commands are not listed. WithLocale selects localized descriptions.
 //
 // Format:
 //
 //	<available_commands>
 //	- /command-name: Description of the command
+//	- /other-command (aliases: /oc): Description of the command
 //	</available_commands>
-func (p *Plugin) CommandsIndexSystemMessage() string {
-	if len(p.Commands) == 0 {
+func (p *Plugin) CommandsIndexSystemMessage(opts ...IndexOption) string {
+	commands := p.CommandsIndex(opts...)
+	if len(commands) == 0 {
 		return ""
 	}
 
 	var sb strings.Builder
 	sb.WriteString("<available_commands>\n")
-	for _, c := range p.Commands {
-		sb.WriteString(fmt.Sprintf("- /%s: %s\n", c.Name, c.Description))
+	for _, c := range commands {
+		sb.WriteString(fmt.Sprintf("- /%s%s: %s\n", c.Name, formatAliases(c.Aliases), c.Description))
 	}
 	sb.WriteString("</available_commands>\n\n")
 	sb.WriteString("Users can invoke these commands by typing /<command-name> followed by any arguments.")
@@ -112,6 +135,14 @@ func (p *Plugin) CommandsIndexSystemMessage() string {
 	return sb.String()
 }
 
+// formatAliases returns " (aliases: /a, /b)", or "" without aliases.
+func formatAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	return " (aliases: /" + strings.Join(aliases, ", /") + ")"
+}
+
 // AgentsIndexSystemMessage returns a compact agents list for system prompt.
 //
 // Format:
@@ -142,13 +173,14 @@ func (p *Plugin) AgentsIndexSystemMessage() string {
 
 // PluginIndexSystemMessage returns a combined index of all plugin components.
 // This is useful for giving the LLM an overview of available capabilities.
-func (p *Plugin) PluginIndexSystemMessage() string {
+// WithLocale selects localized descriptions.
+func (p *Plugin) PluginIndexSystemMessage(opts ...IndexOption) string {
 	var parts []string
 
-	if msg := p.SkillsIndexSystemMessage(); msg != "" {
+	if msg := p.SkillsIndexSystemMessage(opts...); msg != "" {
 		parts = append(parts, msg)
 	}
-	if msg := p.CommandsIndexSystemMessage(); msg != "" {
+	if msg := p.CommandsIndexSystemMessage(opts...); msg != "" {
 		parts = append(parts, msg)
 	}
 	if msg := p.AgentsIndexSystemMessage(); msg != "" {
@@ -167,6 +199,24 @@ func (p *Plugin) PluginIndexSystemMessage() string {
 	return header + strings.Join(parts, "\n")
 }
 
+// SkillsIndexOption returns an llm.Option that adds SkillsIndexSystemMessage
+// to the index layer of the system prompt, below the agent's system message.
+func (p *Plugin) SkillsIndexOption(opts ...IndexOption) llm.Option {
+	return indexSection("skills-index:"+p.Name, p.SkillsIndexSystemMessage(opts...))
+}
+
+// PluginIndexOption returns an llm.Option that adds PluginIndexSystemMessage
+// to the index layer of the system prompt, below the agent's system message.
+func (p *Plugin) PluginIndexOption(opts ...IndexOption) llm.Option {
+	return indexSection("plugin-index:"+p.Name, p.PluginIndexSystemMessage(opts...))
+}
+
+// indexSection returns an llm.Option that adds text to the index layer of
+// the system prompt.
+func indexSection(name, text string) llm.Option {
+	return llm.WithSystemSection(llm.SystemSection{Name: name, Text: text, Order: llm.SystemOrderIndex})
+}
+
 // HasSkill checks if a skill with the given name exists.
 func (p *Plugin) HasSkill(name string) bool {
 	return p.GetSkill(name) != nil
@@ -0,0 +1,76 @@
+package plugin
+
+import "github.com/i2y/bucephalus/llm"
+
+// LLMDefaults are the default LLM settings of a plugin, declared in the
+// "bucephalus" block of plugin.json, so that hosts need not choose models
+// per plugin. Settings of the host (agent options, call options) take
+// precedence.
+//
+// Example plugin.json:
+//
+//	{
+//	  "name": "code-review",
+//	  "bucephalus": {
+//	    "llm": {"provider": "anthropic", "model": "claude-sonnet-4-5-20250929", "temperature": 0.2, "maxTokens": 4096}
+//	  }
+//	}
+type LLMDefaults struct {
+	Provider    string   `json:"provider,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"maxTokens,omitempty"`
+}
+
+// isZero reports whether d sets nothing.
+func (d LLMDefaults) isZero() bool {
+	return d.Provider == "" && d.Model == "" && d.Temperature == nil && d.MaxTokens == nil
+}
+
+// LLMOptions returns the llm.Options of the plugin's LLM defaults, for
+// calls made outside agent runners, such as expanded commands. Put them
+// before the host's options, which override them.
+//
+// Example:
+//
+//	expanded, err := p.ExpandCommand("/review main.go")
+//	if err != nil {
+//	    return err
+//	}
+//	opts := append(p.LLMOptions(), expanded.ToOption())
+//	resp, err := llm.Call(ctx, expanded.Arguments, opts...)
+func (p *Plugin) LLMOptions() []llm.Option {
+	var opts []llm.Option
+	if p.LLM.Provider != "" {
+		opts = append(opts, llm.WithProvider(p.LLM.Provider))
+	}
+	if p.LLM.Model != "" {
+		opts = append(opts, llm.WithModel(p.LLM.Model))
+	}
+	if p.LLM.Temperature != nil {
+		opts = append(opts, llm.WithTemperature(*p.LLM.Temperature))
+	}
+	if p.LLM.MaxTokens != nil {
+		opts = append(opts, llm.WithMaxTokens(*p.LLM.MaxTokens))
+	}
+	return opts
+}
+
+// applyLLMDefaults sets the settings of the runner that are not set yet
+// from d.
+func (r *AgentRunner) applyLLMDefaults(d LLMDefaults) {
+	if r.providerName == "" {
+		r.providerName = d.Provider
+	}
+	if r.model == "" {
+		r.model = d.Model
+	}
+	if r.temperature == nil && d.Temperature != nil {
+		t := *d.Temperature
+		r.temperature = &t
+	}
+	if r.maxTokens == nil && d.MaxTokens != nil {
+		n := *d.MaxTokens
+		r.maxTokens = &n
+	}
+}
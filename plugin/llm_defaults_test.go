@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestLoad_LLMDefaults(t *testing.T) {
+	mock, name := registerScripted(t, textResponse("one"), textResponse("two"), textResponse("three"))
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".claude-plugin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".claude-plugin", "plugin.json"), []byte(fmt.Sprintf(`{
+		"name": "reviews",
+		"bucephalus": {"llm": {"provider": %q, "model": "plugin-model", "temperature": 0.2, "maxTokens": 512}}
+	}`, name)), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "agents"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "agents", "reviewer.md"), []byte("---\ndescription: Reviews\n---\nReview."), 0o644))
+
+	p, err := Load(root)
+	require.NoError(t, err)
+	assert.Equal(t, "plugin-model", p.LLM.Model)
+	ctx := context.Background()
+
+	// Agents of the plugin use its defaults
+	_, err = p.GetAgent("reviewer").NewRunner().Run(ctx, "Review main.go")
+	require.NoError(t, err)
+	req := mock.requests[0]
+	assert.Equal(t, "plugin-model", req.Model)
+	require.NotNil(t, req.Temperature)
+	assert.Equal(t, 0.2, *req.Temperature)
+	require.NotNil(t, req.MaxTokens)
+	assert.Equal(t, 512, *req.MaxTokens)
+
+	// Host settings take precedence
+	_, err = p.GetAgent("reviewer").NewRunner(WithAgentModel("host-model"), WithAgentTemperature(1)).Run(ctx, "Review main.go")
+	require.NoError(t, err)
+	req = mock.requests[1]
+	assert.Equal(t, "host-model", req.Model)
+	assert.Equal(t, 1.0, *req.Temperature)
+	assert.Equal(t, 512, *req.MaxTokens)
+
+	// Calls outside runners use LLMOptions
+	_, err = llm.Call(ctx, "Hello", append(p.LLMOptions(), llm.WithMaxTokens(100))...)
+	require.NoError(t, err)
+	req = mock.requests[2]
+	assert.Equal(t, "plugin-model", req.Model)
+	assert.Equal(t, 100, *req.MaxTokens)
+
+	// The defaults are exported with the manifest
+	data, err := p.ManifestJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"model": "plugin-model"`)
+}
+
+func TestChatSession_LLMDefaults(t *testing.T) {
+	mock, name := registerScripted(t, textResponse("done"))
+	p := &Plugin{
+		Name:     "test",
+		Commands: []Command{{Name: "summarize", Content: "Summarize."}},
+		LLM:      LLMDefaults{Provider: name, Model: "plugin-model"},
+	}
+	runner := (&Agent{Name: "assistant"}).NewRunner()
+	session := NewChatSession(p, runner)
+
+	_, err := session.Send(context.Background(), "/summarize notes")
+	require.NoError(t, err)
+	assert.Equal(t, "plugin-model", mock.requests[0].Model)
+	assert.Empty(t, (&Plugin{}).LLMOptions())
+}
@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/i2y/bucephalus/llm"
 )
 
 // Load loads a Claude Code-style plugin from the given path.
 // The path should point to the plugin root directory containing .claude-plugin/plugin.json.
+// It fails if a skill depends on a missing skill or an incompatible version
+// (see CheckSkillDependencies).
 func Load(path string) (*Plugin, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -36,6 +41,10 @@ func Load(path string) (*Plugin, error) {
 		Name:        manifest.Name,
 		Description: manifest.Description,
 		Version:     manifest.Version,
+		Homepage:    manifest.Homepage,
+		Repository:  manifest.Repository,
+		License:     manifest.License,
+		Keywords:    manifest.Keywords,
 		RootPath:    absPath,
 		MCPServers:  make(map[string]MCPServerConfig),
 	}
@@ -43,6 +52,9 @@ func Load(path string) (*Plugin, error) {
 	if manifest.Author != nil {
 		plugin.Author = *manifest.Author
 	}
+	if manifest.Bucephalus != nil && manifest.Bucephalus.LLM != nil {
+		plugin.LLM = *manifest.Bucephalus.LLM
+	}
 
 	// Load commands
 	commandsDir := filepath.Join(absPath, "commands")
@@ -60,6 +72,9 @@ func Load(path string) (*Plugin, error) {
 	}
 	if agents, err := loadAgents(agentsDir); err == nil {
 		plugin.Agents = agents
+		for i := range plugin.Agents {
+			plugin.Agents[i].plugin = plugin
+		}
 	}
 
 	// Load skills
@@ -70,6 +85,9 @@ func Load(path string) (*Plugin, error) {
 	if skills, err := loadSkills(skillsDir); err == nil {
 		plugin.Skills = skills
 	}
+	if err := plugin.CheckSkillDependencies(); err != nil {
+		return nil, fmt.Errorf("checking skill dependencies: %w", err)
+	}
 
 	// Load MCP servers
 	mcpPath := filepath.Join(absPath, ".mcp.json")
@@ -197,9 +215,18 @@ func loadMCPServers(path, pluginRoot string) (map[string]MCPServerConfig, error)
 		return nil, fmt.Errorf("parsing MCP config: %w", err)
 	}
 
-	// Replace ${CLAUDE_PLUGIN_ROOT} with actual path
 	result := make(map[string]MCPServerConfig)
 	for name, cfg := range raw.MCPServers {
+		// Skip servers that can't be started, keeping the others
+		cfg.Name = name
+		if err := cfg.Validate(); err != nil {
+			if logger := llm.Logger(); logger != nil {
+				logger.Warn("skipping MCP server", "plugin_root", pluginRoot, "server", name, "error", err)
+			}
+			continue
+		}
+
+		// Replace ${CLAUDE_PLUGIN_ROOT} with actual path
 		cfg.Command = expandPluginRoot(cfg.Command, pluginRoot)
 		for i, arg := range cfg.Args {
 			cfg.Args[i] = expandPluginRoot(arg, pluginRoot)
@@ -207,7 +234,8 @@ func loadMCPServers(path, pluginRoot string) (map[string]MCPServerConfig, error)
 		for k, v := range cfg.Env {
 			cfg.Env[k] = expandPluginRoot(v, pluginRoot)
 		}
-		result[name] = cfg
+		cfg.URL = expandPluginRoot(cfg.URL, pluginRoot)
+		result[cfg.Name] = cfg
 	}
 
 	return result, nil
@@ -218,13 +246,19 @@ func expandPluginRoot(s, pluginRoot string) string {
 	return strings.ReplaceAll(s, "${CLAUDE_PLUGIN_ROOT}", pluginRoot)
 }
 
-// GetCommand returns a command by name, or nil if not found.
+// GetCommand returns a command by name or alias, or nil if not found.
+// Names take precedence over aliases.
 func (p *Plugin) GetCommand(name string) *Command {
 	for i := range p.Commands {
 		if p.Commands[i].Name == name {
 			return &p.Commands[i]
 		}
 	}
+	for i := range p.Commands {
+		if slices.Contains(p.Commands[i].Aliases, name) {
+			return &p.Commands[i]
+		}
+	}
 	return nil
 }
 
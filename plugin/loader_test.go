@@ -0,0 +1,32 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_MCPServers(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".claude-plugin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".claude-plugin", "plugin.json"), []byte(`{"name": "tools"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".mcp.json"), []byte(`{
+		"mcpServers": {
+			"fs": {"command": "${CLAUDE_PLUGIN_ROOT}/bin/fs", "args": ["--root", "${CLAUDE_PLUGIN_ROOT}"]},
+			"broken": {},
+			"ws": {"type": "websocket", "url": "ws://localhost"}
+		}
+	}`), 0o644))
+
+	// Invalid servers are skipped; the plugin and its other servers load
+	p, err := Load(root)
+	require.NoError(t, err)
+	require.Len(t, p.MCPServers, 1)
+	fs := p.MCPServers["fs"]
+	assert.Equal(t, "fs", fs.Name)
+	assert.Equal(t, filepath.Join(root, "bin/fs"), fs.Command)
+	assert.Equal(t, []string{"--root", root}, fs.Args)
+}
@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IndexOption configures the index and system message builders of Plugin,
+// such as SkillsIndex and PluginIndexSystemMessage.
+type IndexOption func(*indexConfig)
+
+// indexConfig holds the configuration of the index builders.
+type indexConfig struct {
+	locale string
+}
+
+// WithLocale presents descriptions in the given locale, e.g. "ja" or
+// "ja-JP", where commands and skills have localized descriptions (see
+// LocalizedDescription).
+//
+// Example:
+//
+//	// skills/pdf/SKILL.md:
+//	// ---
+//	// description: Fill in PDF forms
+//	// description.ja: PDFフォームに入力する
+//	// ---
+//	msg := p.PluginIndexSystemMessage(plugin.WithLocale("ja"))
+func WithLocale(locale string) IndexOption {
+	return func(c *indexConfig) {
+		c.locale = locale
+	}
+}
+
+// newIndexConfig returns the configuration of opts.
+func newIndexConfig(opts []IndexOption) *indexConfig {
+	c := &indexConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// LocalizedDescription returns the description of the command in locale,
+// falling back to the description of its language ("ja" for "ja-JP"), and
+// then to Description.
+func (c *Command) LocalizedDescription(locale string) string {
+	return localize(c.Description, c.Descriptions, locale)
+}
+
+// LocalizedDescription returns the description of the skill in locale,
+// falling back to the description of its language ("ja" for "ja-JP"), and
+// then to Description.
+func (s *Skill) LocalizedDescription(locale string) string {
+	return localize(s.Description, s.Descriptions, locale)
+}
+
+// localize returns the description in descriptions for locale, or def.
+func localize(def string, descriptions map[string]string, locale string) string {
+	if locale == "" || len(descriptions) == 0 {
+		return def
+	}
+	locale = normalizeLocale(locale)
+	if d, ok := descriptions[locale]; ok {
+		return d
+	}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		if d, ok := descriptions[lang]; ok {
+			return d
+		}
+	}
+	return def
+}
+
+// normalizeLocale returns locale in lower case with "-" separators, so that
+// "ja_JP" and "ja-JP" match.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// parseLocalizedDescriptions returns the description.<locale> keys of
+// frontmatter, by normalized locale, or nil if there are none.
+func parseLocalizedDescriptions(frontmatter []byte) (map[string]string, error) {
+	var fields map[string]any
+	if err := yaml.Unmarshal(frontmatter, &fields); err != nil {
+		return nil, err
+	}
+	var descriptions map[string]string
+	for key, value := range fields {
+		locale, ok := strings.CutPrefix(key, "description.")
+		if !ok || locale == "" {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if descriptions == nil {
+			descriptions = make(map[string]string)
+		}
+		descriptions[normalizeLocale(locale)] = text
+	}
+	return descriptions, nil
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_LocalizedDescriptions(t *testing.T) {
+	dir := t.TempDir()
+	cmdPath := filepath.Join(dir, "translate.md")
+	require.NoError(t, os.WriteFile(cmdPath, []byte("---\ndescription: Translate text\ndescription.ja: テキストを翻訳する\ndescription.pt_BR: Traduzir texto\n---\nTranslate $ARGUMENTS"), 0o644))
+	cmd, err := ParseCommand(cmdPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Translate text", cmd.Description)
+	assert.Equal(t, map[string]string{"ja": "テキストを翻訳する", "pt-br": "Traduzir texto"}, cmd.Descriptions)
+
+	skillDir := filepath.Join(dir, "pdf")
+	require.NoError(t, os.MkdirAll(skillDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\ndescription: Fill in PDF forms\ndescription.ja: PDFフォームに入力する\n---\nUse pdftk."), 0o644))
+	skill, err := ParseSkill(skillDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ja": "PDFフォームに入力する"}, skill.Descriptions)
+
+	plainPath := filepath.Join(dir, "plain.md")
+	require.NoError(t, os.WriteFile(plainPath, []byte("---\ndescription: Plain\n---\nPlain"), 0o644))
+	plain, err := ParseCommand(plainPath)
+	require.NoError(t, err)
+	assert.Nil(t, plain.Descriptions)
+}
+
+func TestLocalizedDescription(t *testing.T) {
+	cmd := &Command{Description: "Translate text", Descriptions: map[string]string{"ja": "テキストを翻訳する", "pt-br": "Traduzir texto"}}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", "Translate text"},
+		{"ja", "テキストを翻訳する"},
+		{"ja-JP", "テキストを翻訳する"},
+		{"pt_BR", "Traduzir texto"},
+		{"pt", "Translate text"},
+		{"fr", "Translate text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			assert.Equal(t, tt.want, cmd.LocalizedDescription(tt.locale))
+		})
+	}
+}
+
+func TestIndex_WithLocale(t *testing.T) {
+	p := &Plugin{
+		Name: "tools",
+		Commands: []Command{
+			{Name: "translate", Description: "Translate text", Descriptions: map[string]string{"ja": "テキストを翻訳する"}},
+			{Name: "review", Description: "Review code"},
+		},
+		Skills: []Skill{
+			{Name: "pdf", Description: "Fill in PDF forms", Descriptions: map[string]string{"ja": "PDFフォームに入力する"}},
+		},
+	}
+
+	assert.Equal(t, "テキストを翻訳する", p.CommandsIndex(WithLocale("ja"))[0].Description)
+	assert.Equal(t, "Translate text", p.CommandsIndex()[0].Description)
+	assert.Equal(t, "PDFフォームに入力する", p.SkillsIndex(WithLocale("ja-JP"))[0].Description)
+
+	msg := p.PluginIndexSystemMessage(WithLocale("ja"))
+	assert.Contains(t, msg, "- pdf: PDFフォームに入力する")
+	assert.Contains(t, msg, "- /translate: テキストを翻訳する")
+	assert.Contains(t, msg, "- /review: Review code")
+	assert.Contains(t, p.PluginIndexSystemMessage(), "- /translate: Translate text")
+
+	session := NewChatSession(p, (&Agent{Name: "assistant"}).NewRunner(), WithChatLocale("ja"))
+	reply, err := session.Send(context.Background(), "/help")
+	require.NoError(t, err)
+	assert.Contains(t, reply.Text, "/translate - テキストを翻訳する")
+}
@@ -0,0 +1,225 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// kebabCase matches the names Claude Code accepts for plugins and
+// marketplaces.
+var kebabCase = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// semVer matches semantic versions such as "1.2.3" or "1.2.3-beta.1".
+var semVer = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// Marketplace is a Claude Code plugin marketplace: the catalog of plugins
+// in a marketplace.json file, which users add with "/plugin marketplace add".
+//
+// Example:
+//
+//	m := plugin.NewMarketplace("acme-tools", plugin.Author{Name: "ACME", Email: "dev@acme.com"})
+//	entry, err := m.AddPlugin(p, "./plugins/code-review")
+//	if err != nil {
+//	    return err // The plugin's manifest is invalid
+//	}
+//	entry.Category = "development"
+//	err = m.WriteFile(".claude-plugin/marketplace.json")
+type Marketplace struct {
+	Name     string               `json:"name"`
+	Owner    Author               `json:"owner"`
+	Metadata *MarketplaceMetadata `json:"metadata,omitempty"`
+	Plugins  []*MarketplacePlugin `json:"plugins"`
+}
+
+// MarketplaceMetadata is the optional metadata of a marketplace.
+type MarketplaceMetadata struct {
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	PluginRoot  string `json:"pluginRoot,omitempty"` // Base directory of relative plugin sources
+}
+
+// MarketplacePlugin is the entry of a plugin in a marketplace.
+type MarketplacePlugin struct {
+	Name        string   `json:"name"`
+	Source      string   `json:"source"` // Path of the plugin, relative to the marketplace root
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Author      *Author  `json:"author,omitempty"`
+	Homepage    string   `json:"homepage,omitempty"`
+	Repository  string   `json:"repository,omitempty"`
+	License     string   `json:"license,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// NewMarketplace creates an empty marketplace.
+func NewMarketplace(name string, owner Author) *Marketplace {
+	return &Marketplace{Name: name, Owner: owner}
+}
+
+// AddPlugin validates the manifest of p (see ValidateManifest) and adds p
+// to the marketplace with its metadata, from source, the path of the plugin
+// relative to the marketplace root (e.g. "./plugins/code-review"). It
+// returns the entry, whose category and tags can then be set.
+func (m *Marketplace) AddPlugin(p *Plugin, source string) (*MarketplacePlugin, error) {
+	if err := p.ValidateManifest(); err != nil {
+		return nil, fmt.Errorf("adding plugin %q: %w", p.Name, err)
+	}
+	for _, entry := range m.Plugins {
+		if entry.Name == p.Name {
+			return nil, fmt.Errorf("adding plugin %q: duplicate plugin name", p.Name)
+		}
+	}
+	entry := &MarketplacePlugin{
+		Name:        p.Name,
+		Source:      source,
+		Description: p.Description,
+		Version:     p.Version,
+		Homepage:    p.Homepage,
+		Repository:  p.Repository,
+		License:     p.License,
+		Keywords:    p.Keywords,
+	}
+	if p.Author.Name != "" {
+		author := p.Author
+		entry.Author = &author
+	}
+	m.Plugins = append(m.Plugins, entry)
+	return entry, nil
+}
+
+// Validate checks that the marketplace has a kebab-case name, an owner, and
+// plugins with unique kebab-case names and sources.
+func (m *Marketplace) Validate() error {
+	var errs []error
+	if !kebabCase.MatchString(m.Name) {
+		errs = append(errs, fmt.Errorf("marketplace name %q must be kebab-case", m.Name))
+	}
+	if m.Owner.Name == "" {
+		errs = append(errs, errors.New("marketplace owner name is required"))
+	}
+	seen := make(map[string]bool)
+	for _, entry := range m.Plugins {
+		if !kebabCase.MatchString(entry.Name) {
+			errs = append(errs, fmt.Errorf("plugin name %q must be kebab-case", entry.Name))
+		}
+		if seen[entry.Name] {
+			errs = append(errs, fmt.Errorf("duplicate plugin %q", entry.Name))
+		}
+		seen[entry.Name] = true
+		if entry.Source == "" {
+			errs = append(errs, fmt.Errorf("plugin %q has no source", entry.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// JSON validates the marketplace and returns its marketplace.json content.
+func (m *Marketplace) JSON() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteFile validates the marketplace and writes it to path, conventionally
+// .claude-plugin/marketplace.json at the root of the marketplace repository.
+func (m *Marketplace) WriteFile(path string) error {
+	data, err := m.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating marketplace directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing marketplace: %w", err)
+	}
+	return nil
+}
+
+// ValidateManifest checks that p can be published to Claude Code users: a
+// kebab-case name, a semantic version if any, unique component names and
+// command aliases, and descriptions for agents and skills, which Claude Code
+// uses to decide when to invoke them.
+func (p *Plugin) ValidateManifest() error {
+	var errs []error
+	if !kebabCase.MatchString(p.Name) {
+		errs = append(errs, fmt.Errorf("plugin name %q must be kebab-case", p.Name))
+	}
+	if p.Version != "" && !semVer.MatchString(p.Version) {
+		errs = append(errs, fmt.Errorf("plugin version %q is not a semantic version", p.Version))
+	}
+
+	check := func(kind, name, description string, needsDescription bool, seen map[string]bool) {
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("duplicate %s %q", kind, name))
+		}
+		seen[name] = true
+		if needsDescription && description == "" {
+			errs = append(errs, fmt.Errorf("%s %q has no description", kind, name))
+		}
+	}
+	seen := make(map[string]bool)
+	for _, c := range p.Commands {
+		check("command", c.Name, c.Description, false, seen)
+	}
+	for _, c := range p.Commands {
+		for _, alias := range c.Aliases {
+			if seen[alias] {
+				errs = append(errs, fmt.Errorf("alias %q of command %q is taken", alias, c.Name))
+			}
+			seen[alias] = true
+		}
+	}
+	seen = make(map[string]bool)
+	for _, a := range p.Agents {
+		check("agent", a.Name, a.Description, true, seen)
+	}
+	seen = make(map[string]bool)
+	for _, s := range p.Skills {
+		check("skill", s.Name, s.Description, true, seen)
+	}
+	return errors.Join(errs...)
+}
+
+// ManifestJSON validates the manifest of p (see ValidateManifest) and
+// returns its .claude-plugin/plugin.json content, including its LLM
+// defaults. Components are expected in the default directories (commands,
+// agents, skills) and MCP servers in .mcp.json.
+func (p *Plugin) ManifestJSON() ([]byte, error) {
+	if err := p.ValidateManifest(); err != nil {
+		return nil, err
+	}
+	manifest := pluginManifest{
+		Name:        p.Name,
+		Description: p.Description,
+		Version:     p.Version,
+		Homepage:    p.Homepage,
+		Repository:  p.Repository,
+		License:     p.License,
+		Keywords:    p.Keywords,
+	}
+	if p.Author.Name != "" {
+		author := p.Author
+		manifest.Author = &author
+	}
+	if !p.LLM.isZero() {
+		defaults := p.LLM
+		manifest.Bucephalus = &manifestExtension{LLM: &defaults}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
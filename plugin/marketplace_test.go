@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPublishablePlugin() *Plugin {
+	return &Plugin{
+		Name:        "code-review",
+		Description: "Code review tools",
+		Version:     "1.2.0",
+		Author:      Author{Name: "ACME", Email: "dev@acme.com"},
+		License:     "MIT",
+		Keywords:    []string{"review"},
+		Commands:    []Command{{Name: "review"}},
+		Agents:      []Agent{{Name: "reviewer", Description: "Reviews code"}},
+		Skills:      []Skill{{Name: "style", Description: "Style guide"}},
+	}
+}
+
+func TestPlugin_ValidateManifest(t *testing.T) {
+	require.NoError(t, testPublishablePlugin().ValidateManifest())
+
+	p := testPublishablePlugin()
+	p.Name = "Code Review"
+	p.Version = "1.2"
+	p.Commands = append(p.Commands, Command{Name: "review"})
+	p.Commands = append(p.Commands, Command{Name: "rv"}, Command{Name: "review-all", Aliases: []string{"rv"}})
+	p.Skills = append(p.Skills, Skill{Name: "undocumented"})
+	err := p.ValidateManifest()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `plugin name "Code Review" must be kebab-case`)
+	assert.Contains(t, err.Error(), `plugin version "1.2" is not a semantic version`)
+	assert.Contains(t, err.Error(), `duplicate command "review"`)
+	assert.Contains(t, err.Error(), `alias "rv" of command "review-all" is taken`)
+	assert.Contains(t, err.Error(), `skill "undocumented" has no description`)
+}
+
+func TestPlugin_ManifestJSON(t *testing.T) {
+	data, err := testPublishablePlugin().ManifestJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "code-review",
+		"description": "Code review tools",
+		"version": "1.2.0",
+		"author": {"name": "ACME", "email": "dev@acme.com"},
+		"license": "MIT",
+		"keywords": ["review"]
+	}`, string(data))
+
+	// The manifest loads back into the same metadata
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".claude-plugin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".claude-plugin", "plugin.json"), data, 0o644))
+	p, err := Load(root)
+	require.NoError(t, err)
+	assert.Equal(t, "MIT", p.License)
+	assert.Equal(t, []string{"review"}, p.Keywords)
+	assert.Equal(t, "dev@acme.com", p.Author.Email)
+}
+
+func TestMarketplace(t *testing.T) {
+	m := NewMarketplace("acme-tools", Author{Name: "ACME"})
+	entry, err := m.AddPlugin(testPublishablePlugin(), "./plugins/code-review")
+	require.NoError(t, err)
+	entry.Category = "development"
+
+	_, err = m.AddPlugin(testPublishablePlugin(), "./other")
+	assert.ErrorContains(t, err, "duplicate plugin name")
+	_, err = m.AddPlugin(&Plugin{Name: "Bad Name"}, "./bad")
+	assert.ErrorContains(t, err, "must be kebab-case")
+
+	path := filepath.Join(t.TempDir(), ".claude-plugin", "marketplace.json")
+	require.NoError(t, m.WriteFile(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "acme-tools",
+		"owner": {"name": "ACME"},
+		"plugins": [{
+			"name": "code-review",
+			"source": "./plugins/code-review",
+			"description": "Code review tools",
+			"version": "1.2.0",
+			"author": {"name": "ACME", "email": "dev@acme.com"},
+			"license": "MIT",
+			"keywords": ["review"],
+			"category": "development"
+		}]
+	}`, string(data))
+
+	m.Owner.Name = ""
+	m.Plugins[0].Source = ""
+	err = m.WriteFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "marketplace owner name is required")
+	assert.Contains(t, err.Error(), `plugin "code-review" has no source`)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -89,6 +90,14 @@ func ParseCommand(path string) (*Command, error) {
 			return nil, fmt.Errorf("parsing command frontmatter: %w", err)
 		}
 		cmd.Description = meta.Description
+		if cmd.Descriptions, err = parseLocalizedDescriptions(fm); err != nil {
+			return nil, fmt.Errorf("parsing command frontmatter: %w", err)
+		}
+		cmd.Aliases = meta.Aliases
+		cmd.Hidden = meta.Hidden
+		if err := cmd.setOutputContract(meta.OutputFormat, meta.OutputSchema); err != nil {
+			return nil, fmt.Errorf("parsing command file %s: %w", path, err)
+		}
 	}
 
 	return cmd, nil
@@ -141,6 +150,29 @@ func ParseSkill(dirPath string) (*Skill, error) {
 		}
 		skill.Description = meta.Description
 		skill.Tools = meta.Tools
+		if skill.Descriptions, err = parseLocalizedDescriptions(fm); err != nil {
+			return nil, fmt.Errorf("parsing skill frontmatter: %w", err)
+		}
+		skill.Version = meta.Version
+		skill.License = meta.License
+		skill.Metadata = meta.Metadata
+		if skill.Version != "" {
+			if _, err := parseVersion(skill.Version); err != nil {
+				return nil, fmt.Errorf("parsing skill file %s: %w", skillFile, err)
+			}
+		}
+		for _, tool := range meta.Dependencies.Tools {
+			if !slices.Contains(skill.Tools, tool) {
+				skill.Tools = append(skill.Tools, tool)
+			}
+		}
+		for _, spec := range meta.Dependencies.Skills {
+			dep, err := parseSkillDependency(spec)
+			if err != nil {
+				return nil, fmt.Errorf("parsing skill file %s: %w", skillFile, err)
+			}
+			skill.Dependencies = append(skill.Dependencies, dep)
+		}
 	}
 
 	return skill, nil
@@ -0,0 +1,262 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// RunReport accounts for the usage of an agent run: tokens and wall-clock
+// time per turn, per tool, and per agent, including the sub-agents that run
+// within it (agents spawned by its tools with its context), so that the
+// cost of each agent of a multi-agent application is measurable. Costs are
+// estimated with the pricing of each runner (see WithAgentPricing).
+// Durations are encoded in JSON as nanoseconds.
+type RunReport struct {
+	Agent     string                `json:"agent"`
+	StartedAt time.Time             `json:"started_at"`
+	Duration  time.Duration         `json:"duration"`
+	Usage     llm.Usage             `json:"usage"` // All LLM calls, including those of sub-agents and critiques
+	Cost      float64               `json:"cost,omitempty"`
+	Turns     []TurnUsage           `json:"turns"`           // The turns of the agent itself
+	Tools     map[string]ToolUsage  `json:"tools,omitempty"` // By tool name, including the tool calls of sub-agents
+	Agents    map[string]AgentUsage `json:"agents"`          // By agent name, including the agent itself
+}
+
+// TurnUsage is the usage of one turn of an agent run.
+type TurnUsage struct {
+	Turn         int           `json:"turn"`
+	Provider     string        `json:"provider"`
+	Model        string        `json:"model"`
+	Usage        llm.Usage     `json:"usage"`
+	Duration     time.Duration `json:"duration"`       // Of the LLM call
+	ToolDuration time.Duration `json:"tool_duration"`  // Of the tool calls of the turn
+	ToolCalls    int           `json:"tool_calls"`     // Number of tool calls of the turn
+	Cost         float64       `json:"cost,omitempty"` // Estimated cost of the LLM call
+}
+
+// ToolUsage is the usage of a tool during an agent run.
+type ToolUsage struct {
+	Calls    int           `json:"calls"`
+	Duration time.Duration `json:"duration"`
+}
+
+// AgentUsage is the usage of an agent during a run.
+type AgentUsage struct {
+	Runs     int           `json:"runs"`
+	Calls    int           `json:"calls"` // LLM calls, including critiques
+	Usage    llm.Usage     `json:"usage"`
+	Cost     float64       `json:"cost,omitempty"`
+	Duration time.Duration `json:"duration"` // Wall-clock time of the runs of the agent
+}
+
+// LastReport returns the usage report of the most recent run, or nil if the
+// runner has not run yet.
+//
+// Example:
+//
+//	_, err := runner.Run(ctx, "Review the pull request")
+//	report := runner.LastReport()
+//	for name, usage := range report.Agents {
+//	    fmt.Printf("%s: %d tokens, $%.4f\n", name, usage.Usage.TotalTokens, usage.Cost)
+//	}
+func (r *AgentRunner) LastReport() *RunReport {
+	if r.report == nil {
+		return nil
+	}
+	return r.report.snapshot()
+}
+
+// String returns a human-readable summary of the report.
+func (rep *RunReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Run of %s - %d turns, %s\n", rep.Agent, len(rep.Turns), rep.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&sb, "Tokens: %d prompt, %d completion, %d total",
+		rep.Usage.PromptTokens, rep.Usage.CompletionTokens, rep.Usage.TotalTokens)
+	if rep.Cost > 0 {
+		fmt.Fprintf(&sb, ", cost $%.4f", rep.Cost)
+	}
+	sb.WriteString("\n\nAgents:\n")
+	for _, name := range slices.Sorted(maps.Keys(rep.Agents)) {
+		a := rep.Agents[name]
+		fmt.Fprintf(&sb, "  %s: %d runs, %d calls, %d tokens, %s", name, a.Runs, a.Calls, a.Usage.TotalTokens, a.Duration.Round(time.Millisecond))
+		if a.Cost > 0 {
+			fmt.Fprintf(&sb, ", $%.4f", a.Cost)
+		}
+		sb.WriteString("\n")
+	}
+	if len(rep.Tools) > 0 {
+		sb.WriteString("\nTools:\n")
+		for _, name := range slices.Sorted(maps.Keys(rep.Tools)) {
+			t := rep.Tools[name]
+			fmt.Fprintf(&sb, "  %s: %d calls, %s\n", name, t.Calls, t.Duration.Round(time.Millisecond))
+		}
+	}
+	return sb.String()
+}
+
+// usageReport builds the RunReport of a run. The reports of sub-agent runs
+// also record their usage in the report of the parent run. It is safe for
+// concurrent use, as sub-agents may run in parallel.
+type usageReport struct {
+	mu     sync.Mutex
+	report RunReport
+	parent *usageReport
+}
+
+// usageReportKey is the context key of the usageReport of a run.
+type usageReportKey struct{}
+
+// startReport starts the usage report of a run, nested in the report of the
+// run of ctx if any, and returns the context of the run.
+func (r *AgentRunner) startReport(ctx context.Context) context.Context {
+	parent, _ := ctx.Value(usageReportKey{}).(*usageReport)
+	r.report = &usageReport{
+		report: RunReport{
+			Agent:     r.agent.Name,
+			StartedAt: time.Now(),
+			Tools:     make(map[string]ToolUsage),
+			Agents:    make(map[string]AgentUsage),
+		},
+		parent: parent,
+	}
+	r.report.addRun(r.agent.Name)
+	return context.WithValue(ctx, usageReportKey{}, r.report)
+}
+
+// finishReport completes the report of the current run.
+func (r *AgentRunner) finishReport() {
+	if r.report == nil {
+		return
+	}
+	r.report.mu.Lock()
+	r.report.report.Duration = time.Since(r.report.report.StartedAt)
+	d := r.report.report.Duration
+	r.report.mu.Unlock()
+	r.report.addRunDuration(r.agent.Name, d)
+}
+
+// reportTurn records a turn of the current run.
+func (r *AgentRunner) reportTurn(turn int, model ModelRef, usage llm.Usage, d time.Duration) {
+	if r.report == nil {
+		return
+	}
+	cost := r.cost(usage)
+	r.report.mu.Lock()
+	r.report.report.Turns = append(r.report.report.Turns, TurnUsage{
+		Turn:     turn,
+		Provider: model.Provider,
+		Model:    model.Model,
+		Usage:    usage,
+		Duration: d,
+		Cost:     cost,
+	})
+	r.report.mu.Unlock()
+	r.report.addCall(r.agent.Name, usage, cost)
+}
+
+// reportCall records an LLM call of the current run that is not a turn,
+// such as a critique.
+func (r *AgentRunner) reportCall(usage llm.Usage) {
+	if r.report != nil {
+		r.report.addCall(r.agent.Name, usage, r.cost(usage))
+	}
+}
+
+// reportToolCall records a tool call of the current turn.
+func (r *AgentRunner) reportToolCall(name string, d time.Duration) {
+	if r.report == nil {
+		return
+	}
+	r.report.mu.Lock()
+	if turns := r.report.report.Turns; len(turns) > 0 {
+		turns[len(turns)-1].ToolCalls++
+		turns[len(turns)-1].ToolDuration += d
+	}
+	r.report.mu.Unlock()
+	r.report.addToolCall(name, d)
+}
+
+// cost returns the estimated cost of usage, or 0 without pricing.
+func (r *AgentRunner) cost(usage llm.Usage) float64 {
+	if r.pricing == nil {
+		return 0
+	}
+	return r.pricing.Cost(usage)
+}
+
+// addRun records a run of agent in u and its parents.
+func (u *usageReport) addRun(agent string) {
+	for ; u != nil; u = u.parent {
+		u.mu.Lock()
+		a := u.report.Agents[agent]
+		a.Runs++
+		u.report.Agents[agent] = a
+		u.mu.Unlock()
+	}
+}
+
+// addRunDuration records the duration of a run of agent in u and its
+// parents.
+func (u *usageReport) addRunDuration(agent string, d time.Duration) {
+	for ; u != nil; u = u.parent {
+		u.mu.Lock()
+		a := u.report.Agents[agent]
+		a.Duration += d
+		u.report.Agents[agent] = a
+		u.mu.Unlock()
+	}
+}
+
+// addCall records an LLM call of agent in u and its parents.
+func (u *usageReport) addCall(agent string, usage llm.Usage, cost float64) {
+	for ; u != nil; u = u.parent {
+		u.mu.Lock()
+		a := u.report.Agents[agent]
+		a.Calls++
+		a.Usage = addUsage(a.Usage, usage)
+		a.Cost += cost
+		u.report.Agents[agent] = a
+		u.report.Usage = addUsage(u.report.Usage, usage)
+		u.report.Cost += cost
+		u.mu.Unlock()
+	}
+}
+
+// addToolCall records a tool call in u and its parents.
+func (u *usageReport) addToolCall(name string, d time.Duration) {
+	for ; u != nil; u = u.parent {
+		u.mu.Lock()
+		t := u.report.Tools[name]
+		t.Calls++
+		t.Duration += d
+		u.report.Tools[name] = t
+		u.mu.Unlock()
+	}
+}
+
+// snapshot returns a copy of the report.
+func (u *usageReport) snapshot() *RunReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	rep := u.report
+	rep.Turns = slices.Clone(rep.Turns)
+	rep.Tools = maps.Clone(rep.Tools)
+	rep.Agents = maps.Clone(rep.Agents)
+	return &rep
+}
+
+// addUsage returns the sum of a and b.
+func addUsage(a, b llm.Usage) llm.Usage {
+	return llm.Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
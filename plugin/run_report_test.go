@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+func TestAgentRunner_LastReport(t *testing.T) {
+	delegate := toolCallResponse("call_1", "delegate", `{"text":"Review main.go"}`)
+	delegate.Usage = provider.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110}
+	review := textResponse("LGTM")
+	review.Usage = provider.Usage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50}
+	final := textResponse("The reviewer approved.")
+	final.Usage = provider.Usage{PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220}
+	_, name := registerScripted(t, delegate, review, final)
+
+	var runner *AgentRunner
+	var spawned *SpawnResult
+	delegateTool := llm.MustNewTool("delegate", "Delegates a review",
+		func(ctx context.Context, in echoInput) (string, error) {
+			result, err := runner.Spawn(ctx, &Agent{Name: "reviewer"}, in.Text)
+			if err != nil {
+				return "", err
+			}
+			spawned = result
+			return result.Response.Text(), nil
+		})
+	runner = (&Agent{Name: "lead", Tools: []string{"delegate"}}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(delegateTool),
+		WithAgentPricing(Pricing{InputPerMillion: 1, OutputPerMillion: 10}),
+	)
+	assert.Nil(t, runner.LastReport())
+
+	_, err := runner.Run(context.Background(), "Get main.go reviewed")
+	require.NoError(t, err)
+
+	report := runner.LastReport()
+	require.NotNil(t, report)
+	assert.Equal(t, "lead", report.Agent)
+	assert.Equal(t, 380, report.Usage.TotalTokens)
+	assert.InDelta(t, 340/1e6+40*10/1e6, report.Cost, 1e-9)
+	require.Len(t, report.Turns, 2)
+	assert.Equal(t, 110, report.Turns[0].Usage.TotalTokens)
+	assert.Equal(t, 1, report.Turns[0].ToolCalls)
+	assert.Positive(t, report.Turns[0].ToolDuration)
+	assert.Equal(t, 0, report.Turns[1].ToolCalls)
+	assert.Equal(t, 1, report.Tools["delegate"].Calls)
+
+	// The usage of the sub-agent is attributed to it
+	lead, reviewer := report.Agents["lead"], report.Agents["reviewer"]
+	assert.Equal(t, 1, lead.Runs)
+	assert.Equal(t, 2, lead.Calls)
+	assert.Equal(t, 330, lead.Usage.TotalTokens)
+	assert.Equal(t, 1, reviewer.Runs)
+	assert.Equal(t, 1, reviewer.Calls)
+	assert.Equal(t, 50, reviewer.Usage.TotalTokens)
+	assert.InDelta(t, 40/1e6+10*10/1e6, reviewer.Cost, 1e-9) // With the inherited pricing
+	assert.Positive(t, reviewer.Duration)
+	assert.GreaterOrEqual(t, lead.Duration, reviewer.Duration)
+
+	require.NotNil(t, spawned.Report)
+	assert.Equal(t, 50, spawned.Report.Usage.TotalTokens)
+	assert.Len(t, spawned.Report.Agents, 1)
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	var decoded RunReport
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, report.Agents, decoded.Agents)
+
+	pretty := report.String()
+	assert.Contains(t, pretty, "Run of lead - 2 turns")
+	assert.Contains(t, pretty, "reviewer: 1 runs, 1 calls, 50 tokens")
+	assert.Contains(t, pretty, "delegate: 1 calls")
+}
+
+func TestAgentRunner_LastReport_Reflection(t *testing.T) {
+	answer := textResponse("42")
+	answer.Usage = provider.Usage{TotalTokens: 10}
+	verdict := textResponse(`{"approved": true, "critique": ""}`)
+	verdict.Usage = provider.Usage{TotalTokens: 5}
+	_, name := registerScripted(t, answer, verdict)
+
+	runner := (&Agent{Name: "solver"}).NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentReflection(1),
+	)
+	_, err := runner.Run(context.Background(), "What is 6*7?")
+	require.NoError(t, err)
+
+	report := runner.LastReport()
+	assert.Len(t, report.Turns, 1)
+	assert.Equal(t, 2, report.Agents["solver"].Calls)
+	assert.Equal(t, 15, report.Usage.TotalTokens)
+}
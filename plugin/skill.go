@@ -5,9 +5,21 @@ import (
 )
 
 // ToOption converts a Skill to an llm.Option.
-// This adds the skill's system message to the LLM call.
+// This adds the skill's system message to the LLM call as a section of the
+// skill layer of the system prompt.
+// It does not check the skill's dependencies; see Plugin.ActivateSkill.
 func (s *Skill) ToOption() llm.Option {
-	return llm.WithSystemMessage(s.ToSystemMessage())
+	return llm.WithSystemSection(s.systemSection())
+}
+
+// systemSection returns the section of the skill in the skill layer of the
+// system prompt.
+func (s *Skill) systemSection() llm.SystemSection {
+	return llm.SystemSection{
+		Name:  "skill:" + s.Name,
+		Text:  s.ToSystemMessage(),
+		Order: llm.SystemOrderSkill,
+	}
 }
 
 // FilterTools filters a list of tools to only include those required by this skill.
@@ -0,0 +1,239 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ErrSkillNotFound is returned when a skill doesn't exist in the plugin.
+var ErrSkillNotFound = errors.New("skill not found")
+
+// SkillDependencyError is returned when a dependency of a skill is missing
+// or does not satisfy its version constraint.
+type SkillDependencyError struct {
+	Skill      string
+	Dependency SkillDependency
+	Reason     string // E.g. "not found" or "version 1.0.0 does not satisfy >=2"
+}
+
+func (e *SkillDependencyError) Error() string {
+	return fmt.Sprintf("skill %q depends on %q: %s", e.Skill, e.Dependency.Name, e.Reason)
+}
+
+// MissingToolsError is returned when tools required by a skill are not
+// available.
+type MissingToolsError struct {
+	Skill string
+	Tools []string
+}
+
+func (e *MissingToolsError) Error() string {
+	return fmt.Sprintf("skill %q requires unavailable tools: %s", e.Skill, strings.Join(e.Tools, ", "))
+}
+
+// CheckSkillDependencies checks that the skill dependencies of every skill
+// are skills of the plugin that satisfy their version constraints. It
+// returns the *SkillDependencyError of each unmet dependency, joined. Load
+// fails with this error, so that a plugin with a broken skill library is
+// not used.
+func (p *Plugin) CheckSkillDependencies() error {
+	var errs []error
+	for i := range p.Skills {
+		for _, dep := range p.Skills[i].Dependencies {
+			if err := p.checkSkillDependency(p.Skills[i].Name, dep); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkSkillDependency checks a dependency of the named skill.
+func (p *Plugin) checkSkillDependency(skill string, dep SkillDependency) error {
+	target := p.GetSkill(dep.Name)
+	if target == nil {
+		return &SkillDependencyError{Skill: skill, Dependency: dep, Reason: "not found"}
+	}
+	if dep.Constraint == "" {
+		return nil
+	}
+	if target.Version == "" {
+		return &SkillDependencyError{Skill: skill, Dependency: dep, Reason: fmt.Sprintf("no version to check against %s", dep.Constraint)}
+	}
+	ok, err := satisfies(target.Version, dep.Constraint)
+	if err != nil {
+		return &SkillDependencyError{Skill: skill, Dependency: dep, Reason: err.Error()}
+	}
+	if !ok {
+		return &SkillDependencyError{Skill: skill, Dependency: dep, Reason: fmt.Sprintf("version %s does not satisfy %s", target.Version, dep.Constraint)}
+	}
+	return nil
+}
+
+// ActivateSkill returns an llm.Option that adds the named skill and its
+// dependencies, transitively and dependencies first, to the skill layer of
+// the system prompt. It fails if a dependency is unmet or if tools, the
+// tools available to the call, lack a tool required by one of the skills.
+//
+// Example:
+//
+//	opt, err := p.ActivateSkill("pdf-forms", tools.AllTools())
+//	if err != nil {
+//	    return err // e.g. *plugin.MissingToolsError
+//	}
+//	resp, err := llm.Call(ctx, "Fill in form.pdf", opt, llm.WithTools(tools.AllTools()...))
+func (p *Plugin) ActivateSkill(name string, tools []llm.Tool) (llm.Option, error) {
+	skills, err := p.resolveSkill(name, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	prompt := llm.NewSystemPrompt()
+	for _, s := range skills {
+		if missing := s.MissingTools(tools); len(missing) > 0 {
+			return nil, &MissingToolsError{Skill: s.Name, Tools: missing}
+		}
+		prompt.Add(s.systemSection())
+	}
+	return llm.WithSystemPrompt(prompt), nil
+}
+
+// resolveSkill returns the named skill after its dependencies, skipping the
+// skills in seen, to which it adds them.
+func (p *Plugin) resolveSkill(name string, seen map[string]bool) ([]*Skill, error) {
+	skill := p.GetSkill(name)
+	if skill == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSkillNotFound, name)
+	}
+	seen[name] = true
+	var skills []*Skill
+	for _, dep := range skill.Dependencies {
+		if err := p.checkSkillDependency(name, dep); err != nil {
+			return nil, err
+		}
+		if seen[dep.Name] {
+			continue
+		}
+		deps, err := p.resolveSkill(dep.Name, seen)
+		if err != nil {
+			return nil, err
+		}
+		skills = append(skills, deps...)
+	}
+	return append(skills, skill), nil
+}
+
+// parseSkillDependency parses a dependency of the form "name" or
+// "name <constraint>", e.g. "pdf-basics >=1.2, <2".
+func parseSkillDependency(spec string) (SkillDependency, error) {
+	spec = strings.TrimSpace(spec)
+	i := strings.IndexAny(spec, " <>=^~")
+	if i < 0 {
+		return SkillDependency{Name: spec}, nil
+	}
+	dep := SkillDependency{Name: spec[:i], Constraint: strings.TrimSpace(spec[i:])}
+	if dep.Name == "" {
+		return SkillDependency{}, fmt.Errorf("skill dependency %q has no name", spec)
+	}
+	if _, err := satisfies("0.0.0", dep.Constraint); err != nil {
+		return SkillDependency{}, fmt.Errorf("skill dependency %q: %w", spec, err)
+	}
+	return dep, nil
+}
+
+// version is a semantic version without pre-release or build metadata.
+type version [3]int
+
+// parseVersion parses a version such as "1.2.3", "v1.2", or "1.2.3-beta"
+// (pre-release and build metadata are ignored). Missing parts are zero.
+func parseVersion(s string) (version, error) {
+	var v version
+	core := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// compare returns -1, 0, or 1 as v is less than, equal to, or greater than w.
+func (v version) compare(w version) int {
+	for i := range v {
+		if v[i] != w[i] {
+			if v[i] < w[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// satisfies reports whether version s satisfies constraint, a comma- or
+// space-separated list of comparisons that must all hold: "=1.2", "1.2"
+// (equal), ">1.2", ">=1.2", "<2", "<=2", "^1.2" (>=1.2 with the same major
+// version), and "~1.2" (>=1.2 with the same minor version).
+func satisfies(s, constraint string) (bool, error) {
+	v, err := parseVersion(s)
+	if err != nil {
+		return false, err
+	}
+	var fields []string
+	pending := "" // An operator separated from its version by a space
+	for _, field := range strings.FieldsFunc(constraint, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if strings.Trim(field, "<>=^~") == "" {
+			pending += field
+			continue
+		}
+		fields = append(fields, pending+field)
+		pending = ""
+	}
+	if len(fields) == 0 || pending != "" {
+		return false, fmt.Errorf("invalid version constraint %q", constraint)
+	}
+	for _, field := range fields {
+		rest := strings.TrimLeft(field, "<>=^~")
+		op := field[:len(field)-len(rest)]
+		w, err := parseVersion(rest)
+		if err != nil {
+			return false, fmt.Errorf("invalid version constraint %q", constraint)
+		}
+		c := v.compare(w)
+		var ok bool
+		switch op {
+		case "", "=":
+			ok = c == 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		case "^":
+			ok = c >= 0 && v[0] == w[0]
+		case "~":
+			ok = c >= 0 && v[0] == w[0] && v[1] == w[1]
+		default:
+			return false, fmt.Errorf("invalid version constraint %q", constraint)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+func TestParseSkill_Dependencies(t *testing.T) {
+	skillDir := filepath.Join(t.TempDir(), "pdf-forms")
+	require.NoError(t, os.MkdirAll(skillDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte(`---
+description: Fill in PDF forms
+version: 1.4.0
+license: MIT
+tools: [read]
+dependencies:
+  skills:
+    - pdf-basics >= 1.2, <2
+    - ocr
+  tools: [read, bash]
+metadata:
+  author: docs-team
+---
+Fill in the form.`), 0o644))
+
+	skill, err := ParseSkill(skillDir)
+	require.NoError(t, err)
+	assert.Equal(t, "1.4.0", skill.Version)
+	assert.Equal(t, "MIT", skill.License)
+	assert.Equal(t, []string{"read", "bash"}, skill.Tools)
+	assert.Equal(t, []SkillDependency{{Name: "pdf-basics", Constraint: ">= 1.2, <2"}, {Name: "ocr"}}, skill.Dependencies)
+	assert.Equal(t, map[string]string{"author": "docs-team"}, skill.Metadata)
+
+	require.NoError(t, os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\ndependencies:\n  skills: [pdf-basics >=x]\n---\n"), 0o644))
+	_, err = ParseSkill(skillDir)
+	assert.ErrorContains(t, err, `invalid version constraint ">=x"`)
+
+	require.NoError(t, os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nversion: one\n---\n"), 0o644))
+	_, err = ParseSkill(skillDir)
+	assert.ErrorContains(t, err, `invalid version "one"`)
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.2.0", "1.2", true},
+		{"1.2.1", "=1.2", false},
+		{"1.2.1", ">1.2", true},
+		{"1.2.0", ">= 1.2", true},
+		{"2.0.0", ">=1.2, <2", false},
+		{"1.9.9", ">=1.2 <2", true},
+		{"1.5.0", "^1.2", true},
+		{"2.1.0", "^1.2", false},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"v1.2.3-beta", "<=1.2.3", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.constraint, func(t *testing.T) {
+			got, err := satisfies(tt.version, tt.constraint)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := satisfies("1.0.0", ">=")
+	assert.Error(t, err)
+	_, err = satisfies("1.0.0", "!1.0")
+	assert.Error(t, err)
+}
+
+func TestPlugin_CheckSkillDependencies(t *testing.T) {
+	p := &Plugin{Skills: []Skill{
+		{Name: "pdf-basics", Version: "1.1.0"},
+		{Name: "pdf-forms", Dependencies: []SkillDependency{{Name: "pdf-basics", Constraint: ">=1.2"}, {Name: "ocr"}}},
+		{Name: "unversioned-dep", Dependencies: []SkillDependency{{Name: "pdf-forms", Constraint: "^1"}}},
+	}}
+
+	err := p.CheckSkillDependencies()
+	require.Error(t, err)
+	var depErr *SkillDependencyError
+	require.ErrorAs(t, err, &depErr)
+	assert.Equal(t, "pdf-forms", depErr.Skill)
+	assert.Contains(t, err.Error(), `skill "pdf-forms" depends on "pdf-basics": version 1.1.0 does not satisfy >=1.2`)
+	assert.Contains(t, err.Error(), `skill "pdf-forms" depends on "ocr": not found`)
+	assert.Contains(t, err.Error(), `skill "unversioned-dep" depends on "pdf-forms": no version to check against ^1`)
+
+	p.Skills[0].Version = "1.3.0"
+	p.Skills = append(p.Skills[:2], Skill{Name: "ocr"})
+	assert.NoError(t, p.CheckSkillDependencies())
+}
+
+func TestLoad_SkillDependencies(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".claude-plugin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".claude-plugin", "plugin.json"), []byte(`{"name": "docs"}`), 0o644))
+	skillDir := filepath.Join(root, "skills", "pdf-forms")
+	require.NoError(t, os.MkdirAll(skillDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\ndependencies:\n  skills: [pdf-basics]\n---\nFill."), 0o644))
+
+	_, err := Load(root)
+	var depErr *SkillDependencyError
+	assert.ErrorAs(t, err, &depErr)
+}
+
+func TestPlugin_ActivateSkill(t *testing.T) {
+	p := &Plugin{Skills: []Skill{
+		{Name: "pdf-forms", Content: "Fill forms.", Dependencies: []SkillDependency{{Name: "pdf-basics"}, {Name: "ocr"}}},
+		{Name: "pdf-basics", Content: "Read PDFs.", Tools: []string{"read"}, Dependencies: []SkillDependency{{Name: "ocr"}}},
+		{Name: "ocr", Content: "Recognize text.", Dependencies: []SkillDependency{{Name: "pdf-forms"}}}, // Cycle
+	}}
+	read := llm.MustNewTool("read", "Read a file", func(ctx context.Context, args struct{}) (string, error) { return "", nil })
+
+	opt, err := p.ActivateSkill("pdf-forms", []llm.Tool{read})
+	require.NoError(t, err)
+	mock, name := registerScripted(t, textResponse("ok"))
+	_, err = llm.Call(context.Background(), "Fill in form.pdf", opt, llm.WithProvider(name), llm.WithModel("test-model"))
+	require.NoError(t, err)
+	msgs := mock.requests[0].Messages
+	require.Len(t, msgs, 4)
+	assert.Contains(t, msgs[0].Content, "## Skill: ocr")
+	assert.Contains(t, msgs[1].Content, "## Skill: pdf-basics")
+	assert.Contains(t, msgs[2].Content, "## Skill: pdf-forms")
+
+	_, err = p.ActivateSkill("pdf-forms", nil)
+	var toolsErr *MissingToolsError
+	require.ErrorAs(t, err, &toolsErr)
+	assert.Equal(t, "pdf-basics", toolsErr.Skill)
+	assert.Equal(t, []string{"read"}, toolsErr.Tools)
+
+	_, err = p.ActivateSkill("missing", nil)
+	assert.ErrorIs(t, err, ErrSkillNotFound)
+
+	p.Skills[1].Dependencies = []SkillDependency{{Name: "gone"}}
+	_, err = p.ActivateSkill("pdf-forms", []llm.Tool{read})
+	assert.ErrorContains(t, err, `skill "pdf-basics" depends on "gone": not found`)
+}
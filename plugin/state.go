@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// StateChange describes a change to a state value.
+type StateChange struct {
+	Key     string
+	Old     any  // Previous value, or nil if the key was not set
+	New     any  // New value, or nil if the key was deleted
+	Deleted bool // Whether the key was deleted
+}
+
+// StateReader is implemented by AgentContext and SharedState.
+type StateReader interface {
+	GetState(key string) (any, bool)
+}
+
+// StateWriter is implemented by AgentContext and SharedState.
+type StateWriter interface {
+	SetState(key string, value any)
+}
+
+// StateKey is a typed key for values stored in an AgentContext or SharedState.
+//
+// Example:
+//
+//	var findings = plugin.StateKey[[]Finding]("findings")
+//
+//	findings.Set(runner.Context(), []Finding{{File: "main.go"}})
+//	list, ok := findings.Get(runner.Context())
+type StateKey[T any] string
+
+// Get returns the value stored under the key. See GetStateAs for conversion rules.
+func (k StateKey[T]) Get(s StateReader) (T, bool) {
+	return GetStateAs[T](s, string(k))
+}
+
+// Set stores value under the key.
+func (k StateKey[T]) Set(s StateWriter, value T) {
+	s.SetState(string(k), value)
+}
+
+// GetStateAs returns the value stored under key as a T.
+// Values of a different type (e.g. generic JSON values restored from a
+// ContextStore) are converted through JSON. It returns false if the key is
+// not set or the value cannot be converted.
+func GetStateAs[T any](s StateReader, key string) (T, bool) {
+	var zero T
+	v, ok := s.GetState(key)
+	if !ok {
+		return zero, false
+	}
+	if typed, ok := v.(T); ok {
+		return typed, true
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return zero, false
+	}
+	var converted T
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return zero, false
+	}
+	return converted, true
+}
+
+// stateListeners manages state change callbacks.
+type stateListeners struct {
+	mu   sync.Mutex
+	next int
+	fns  map[int]func(StateChange)
+}
+
+// add registers fn and returns a function that unregisters it.
+func (l *stateListeners) add(fn func(StateChange)) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fns == nil {
+		l.fns = make(map[int]func(StateChange))
+	}
+	id := l.next
+	l.next++
+	l.fns[id] = fn
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.fns, id)
+	}
+}
+
+// notify calls all registered callbacks with change.
+func (l *stateListeners) notify(change StateChange) {
+	l.mu.Lock()
+	fns := make([]func(StateChange), 0, len(l.fns))
+	for _, fn := range l.fns {
+		fns = append(fns, fn)
+	}
+	l.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(change)
+	}
+}
+
+// SharedState is a thread-safe key-value store shared by cooperating agents.
+// Unlike AgentContext state, it is independent of any conversation, so several
+// runners (including concurrently running sub-agents) can exchange
+// intermediate artifacts through it.
+type SharedState struct {
+	mu        sync.RWMutex
+	values    map[string]any
+	listeners stateListeners
+}
+
+// NewSharedState creates an empty shared state.
+func NewSharedState() *SharedState {
+	return &SharedState{values: make(map[string]any)}
+}
+
+// GetState returns the value stored under key.
+func (s *SharedState) GetState(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// SetState stores value under key and notifies listeners.
+func (s *SharedState) SetState(key string, value any) {
+	s.mu.Lock()
+	old := s.values[key]
+	s.values[key] = value
+	s.mu.Unlock()
+
+	s.listeners.notify(StateChange{Key: key, Old: old, New: value})
+}
+
+// DeleteState removes key and notifies listeners if it was set.
+func (s *SharedState) DeleteState(key string) {
+	s.mu.Lock()
+	old, ok := s.values[key]
+	delete(s.values, key)
+	s.mu.Unlock()
+
+	if ok {
+		s.listeners.notify(StateChange{Key: key, Old: old, Deleted: true})
+	}
+}
+
+// UpdateState atomically replaces the value under key with the result of fn,
+// which receives the current value and whether it is set.
+// This is useful for appending to collections from concurrent agents.
+func (s *SharedState) UpdateState(key string, fn func(current any, ok bool) any) {
+	s.mu.Lock()
+	old, ok := s.values[key]
+	value := fn(old, ok)
+	s.values[key] = value
+	s.mu.Unlock()
+
+	s.listeners.notify(StateChange{Key: key, Old: old, New: value})
+}
+
+// StateKeys returns all keys in the shared state.
+func (s *SharedState) StateKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// OnStateChange registers fn to be called after each change.
+// fn is called synchronously from the goroutine making the change.
+// It returns a function that unregisters fn.
+func (s *SharedState) OnStateChange(fn func(StateChange)) func() {
+	return s.listeners.add(fn)
+}
+
+// WithAgentSharedState sets the shared state available to the runner and the
+// sub-agents it spawns.
+func WithAgentSharedState(s *SharedState) AgentOption {
+	return func(r *AgentRunner) {
+		r.shared = s
+	}
+}
+
+// SharedState returns the runner's shared state.
+// If none was set with WithAgentSharedState, the runner has its own empty one.
+func (r *AgentRunner) SharedState() *SharedState {
+	return r.shared
+}
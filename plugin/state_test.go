@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type finding struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func TestStateKey(t *testing.T) {
+	key := StateKey[[]finding]("findings")
+
+	c := NewAgentContext()
+	_, ok := key.Get(c)
+	assert.False(t, ok)
+
+	key.Set(c, []finding{{File: "main.go", Line: 3}})
+	got, ok := key.Get(c)
+	require.True(t, ok)
+	assert.Equal(t, []finding{{File: "main.go", Line: 3}}, got)
+
+	// Values restored from JSON are converted
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	restored := NewAgentContext()
+	require.NoError(t, json.Unmarshal(data, restored))
+	got, ok = key.Get(restored)
+	require.True(t, ok)
+	assert.Equal(t, []finding{{File: "main.go", Line: 3}}, got)
+
+	c.SetState("count", "not a number")
+	_, ok = GetStateAs[int](c, "count")
+	assert.False(t, ok)
+}
+
+func TestAgentContext_OnStateChange(t *testing.T) {
+	c := NewAgentContext()
+
+	var changes []StateChange
+	unsubscribe := c.OnStateChange(func(change StateChange) {
+		changes = append(changes, change)
+	})
+
+	c.SetState("a", 1)
+	c.SetState("a", 2)
+	c.DeleteState("a")
+	c.DeleteState("missing")
+	unsubscribe()
+	c.SetState("b", 3)
+
+	assert.Equal(t, []StateChange{
+		{Key: "a", New: 1},
+		{Key: "a", Old: 1, New: 2},
+		{Key: "a", Old: 2, Deleted: true},
+	}, changes)
+}
+
+func TestSharedState_Concurrent(t *testing.T) {
+	s := NewSharedState()
+
+	var mu sync.Mutex
+	notified := 0
+	s.OnStateChange(func(StateChange) {
+		mu.Lock()
+		notified++
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.UpdateState("count", func(current any, ok bool) any {
+				if !ok {
+					return 1
+				}
+				return current.(int) + 1
+			})
+		}()
+	}
+	wg.Wait()
+
+	count, ok := GetStateAs[int](s, "count")
+	require.True(t, ok)
+	assert.Equal(t, 50, count)
+	assert.Equal(t, 50, notified)
+}
+
+func TestAgentRunner_SharedState(t *testing.T) {
+	shared := NewSharedState()
+	lead := &Agent{Name: "lead"}
+	runner := lead.NewRunner(WithAgentSharedState(shared))
+
+	child := runner.newChildRunner(&Agent{Name: "worker"})
+	child.SharedState().SetState("artifact", "report.md")
+
+	v, ok := runner.SharedState().GetState("artifact")
+	require.True(t, ok)
+	assert.Equal(t, "report.md", v)
+}
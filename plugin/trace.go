@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// Trace is a structured record of a single agent run, for debugging and audit.
+// Its messages, tool arguments, tool results, and error are redacted with
+// the redactor of the runner (see WithAgentRedactor and llm.SetRedactor).
+// Durations are encoded in JSON as nanoseconds.
+type Trace struct {
+	Agent     string        `json:"agent"`
+	Provider  string        `json:"provider"`
+	Model     string        `json:"model"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Input     []llm.Message `json:"input"`
+	Turns     []TraceTurn   `json:"turns"`
+	Usage     llm.Usage     `json:"usage"`
+	Cost      float64       `json:"cost,omitempty"` // Estimated cost in USD (see WithAgentPricing)
+	Error     string        `json:"error,omitempty"`
+}
+
+// TraceTurn records one LLM call of an agent run and the tool calls it requested.
+type TraceTurn struct {
+	Turn         int              `json:"turn"`
+	Provider     string           `json:"provider"`
+	Model        string           `json:"model"`
+	Prompt       []llm.Message    `json:"prompt"`
+	Response     llm.Message      `json:"response"`
+	FinishReason llm.FinishReason `json:"finish_reason"`
+	Usage        llm.Usage        `json:"usage"`
+	Duration     time.Duration    `json:"duration"`
+	ToolCalls    []TraceToolCall  `json:"tool_calls,omitempty"`
+}
+
+// TraceToolCall records the execution of a tool call.
+type TraceToolCall struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Arguments string        `json:"arguments"`
+	Result    string        `json:"result"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Pricing holds per-token prices used to estimate the cost of agent runs.
+type Pricing struct {
+	InputPerMillion  float64 // USD per million prompt tokens
+	OutputPerMillion float64 // USD per million completion tokens
+}
+
+// Cost returns the estimated cost of usage in USD.
+func (p Pricing) Cost(usage llm.Usage) float64 {
+	return float64(usage.PromptTokens)*p.InputPerMillion/1e6 +
+		float64(usage.CompletionTokens)*p.OutputPerMillion/1e6
+}
+
+// WithAgentPricing sets the prices used to estimate the cost recorded in traces.
+func WithAgentPricing(p Pricing) AgentOption {
+	return func(r *AgentRunner) {
+		r.pricing = &p
+	}
+}
+
+// WithAgentRedactor redacts the messages, tool arguments, tool results, and
+// errors recorded in traces with redactor instead of the one set by
+// llm.SetRedactor.
+func WithAgentRedactor(redactor *llm.Redactor) AgentOption {
+	return func(r *AgentRunner) {
+		r.redactor = redactor
+	}
+}
+
+// traceRedactor returns the redactor of traces, or nil if nothing is redacted.
+func (r *AgentRunner) traceRedactor() *llm.Redactor {
+	if r.redactor != nil {
+		return r.redactor
+	}
+	return llm.DefaultRedactor()
+}
+
+// LastTrace returns the trace of the most recent run, or nil if the runner has not run yet.
+func (r *AgentRunner) LastTrace() *Trace {
+	return r.trace
+}
+
+// JSON returns the trace as indented JSON.
+func (t *Trace) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// String returns a human-readable summary of the trace.
+func (t *Trace) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Agent %s (%s/%s) - %d turns, %s\n",
+		t.Agent, t.Provider, t.Model, len(t.Turns), t.Duration.Round(time.Millisecond)))
+	sb.WriteString(fmt.Sprintf("Tokens: %d prompt, %d completion, %d total",
+		t.Usage.PromptTokens, t.Usage.CompletionTokens, t.Usage.TotalTokens))
+	if t.Cost > 0 {
+		sb.WriteString(fmt.Sprintf(", cost $%.4f", t.Cost))
+	}
+	sb.WriteString("\n")
+
+	for _, turn := range t.Turns {
+		sb.WriteString(fmt.Sprintf("\n[turn %d] %s, %d tokens, finish: %s",
+			turn.Turn, turn.Duration.Round(time.Millisecond), turn.Usage.TotalTokens, turn.FinishReason))
+		if turn.Provider != t.Provider || turn.Model != t.Model {
+			sb.WriteString(fmt.Sprintf(", model: %s/%s", turn.Provider, turn.Model))
+		}
+		sb.WriteString("\n")
+		if turn.Response.Content != "" {
+			sb.WriteString(fmt.Sprintf("  assistant: %s\n", truncateTrace(turn.Response.Content)))
+		}
+		for _, tc := range turn.ToolCalls {
+			sb.WriteString(fmt.Sprintf("  tool %s(%s) %s\n    -> %s\n",
+				tc.Name, truncateTrace(tc.Arguments), tc.Duration.Round(time.Millisecond), truncateTrace(tc.Result)))
+		}
+	}
+
+	if t.Error != "" {
+		sb.WriteString(fmt.Sprintf("\nerror: %s\n", t.Error))
+	}
+	return sb.String()
+}
+
+// truncateTrace shortens long text for the pretty-printed trace.
+func truncateTrace(s string) string {
+	const maxLen = 200
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) > maxLen {
+		return s[:maxLen] + "..."
+	}
+	return s
+}
+
+// startTrace starts recording a trace for a run with the given input.
+func (r *AgentRunner) startTrace(input []llm.Message) {
+	r.trace = &Trace{
+		Agent:     r.agent.Name,
+		Provider:  r.providerName,
+		Model:     r.model,
+		StartedAt: time.Now(),
+		Input:     r.traceRedactor().Messages(input),
+	}
+}
+
+// traceTurn records a completed LLM call.
+func (r *AgentRunner) traceTurn(turn int, model ModelRef, prompt []llm.Message, msg llm.Message, reason llm.FinishReason, usage llm.Usage, d time.Duration) {
+	t := r.trace
+	t.Turns = append(t.Turns, TraceTurn{
+		Turn:         turn,
+		Provider:     model.Provider,
+		Model:        model.Model,
+		Prompt:       r.traceRedactor().Messages(prompt),
+		Response:     r.traceRedactor().Message(msg),
+		FinishReason: reason,
+		Usage:        usage,
+		Duration:     d,
+	})
+	t.Usage.PromptTokens += usage.PromptTokens
+	t.Usage.CompletionTokens += usage.CompletionTokens
+	t.Usage.TotalTokens += usage.TotalTokens
+	if r.pricing != nil {
+		t.Cost = r.pricing.Cost(t.Usage)
+	}
+}
+
+// traceToolCall records an executed tool call in the current turn.
+func (r *AgentRunner) traceToolCall(call llm.ToolCall, result string, d time.Duration) {
+	t := r.trace
+	if t == nil || len(t.Turns) == 0 {
+		return
+	}
+	redactor := r.traceRedactor()
+	turn := &t.Turns[len(t.Turns)-1]
+	turn.ToolCalls = append(turn.ToolCalls, TraceToolCall{
+		ID:        call.ID,
+		Name:      call.Name,
+		Arguments: redactor.String(call.Arguments),
+		Result:    redactor.String(result),
+		Duration:  d,
+	})
+}
+
+// finishTrace completes the trace of the current run.
+func (r *AgentRunner) finishTrace(err error) {
+	t := r.trace
+	if t == nil {
+		return
+	}
+	t.Duration = time.Since(t.StartedAt)
+	if err != nil {
+		t.Error = r.traceRedactor().String(err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/provider"
+)
+
+func TestAgentRunner_LastTrace(t *testing.T) {
+	first := toolCallResponse("call_1", "echo", `{"text":"hi"}`)
+	first.Usage = provider.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110}
+	second := textResponse("done")
+	second.Usage = provider.Usage{PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220}
+	_, name := registerScripted(t, first, second)
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentPricing(Pricing{InputPerMillion: 3, OutputPerMillion: 15}),
+	)
+	assert.Nil(t, runner.LastTrace())
+
+	_, err := runner.Run(context.Background(), "say hi")
+	require.NoError(t, err)
+
+	trace := runner.LastTrace()
+	require.NotNil(t, trace)
+	assert.Equal(t, "helper", trace.Agent)
+	assert.Equal(t, "test-model", trace.Model)
+	require.Len(t, trace.Turns, 2)
+	assert.Len(t, trace.Turns[1].Prompt, 3) // user, assistant, tool
+	require.Len(t, trace.Turns[0].ToolCalls, 1)
+	assert.Equal(t, "echo: hi", trace.Turns[0].ToolCalls[0].Result)
+	assert.Equal(t, 330, trace.Usage.TotalTokens)
+	assert.InDelta(t, 300*3/1e6+30*15/1e6, trace.Cost, 1e-9)
+	assert.Empty(t, trace.Error)
+
+	data, err := trace.JSON()
+	require.NoError(t, err)
+	var decoded Trace
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, trace.Usage, decoded.Usage)
+
+	pretty := trace.String()
+	assert.Contains(t, pretty, "2 turns")
+	assert.Contains(t, pretty, "tool echo")
+}
+
+func TestWithAgentRedactor(t *testing.T) {
+	_, name := registerScripted(t,
+		toolCallResponse("call_1", "echo", `{"text":"mail bob@example.com","password":"hunter2"}`),
+		textResponse("done"),
+	)
+
+	agent := &Agent{Name: "helper", Tools: []string{"echo"}}
+	runner := agent.NewRunner(
+		WithAgentProvider(name),
+		WithAgentModel("test-model"),
+		WithAgentTools(echoTool()),
+		WithAgentRedactor(llm.NewRedactor()),
+	)
+	_, err := runner.Run(context.Background(), "my SSN is 123-45-6789")
+	require.NoError(t, err)
+
+	trace := runner.LastTrace()
+	assert.Equal(t, "my SSN is [REDACTED:ssn]", trace.Input[0].Content)
+	call := trace.Turns[0].ToolCalls[0]
+	assert.JSONEq(t, `{"text":"mail [REDACTED:email]","password":"[REDACTED]"}`, call.Arguments)
+	assert.Equal(t, "echo: mail [REDACTED:email]", call.Result)
+	assert.NotContains(t, trace.Turns[0].Response.ToolCalls[0].Arguments, "hunter2")
+
+	// The conversation keeps the original text
+	assert.Contains(t, runner.Context().History()[0].Content, "123-45-6789")
+}
@@ -1,6 +1,12 @@
 // Package plugin provides support for loading and using Claude Code-style plugins.
 package plugin
 
+import (
+	"encoding/json"
+
+	"github.com/i2y/bucephalus/mcp"
+)
+
 // Plugin represents a loaded Claude Code-style plugin.
 type Plugin struct {
 	// Metadata from plugin.json
@@ -8,6 +14,10 @@ type Plugin struct {
 	Description string
 	Version     string
 	Author      Author
+	Homepage    string
+	Repository  string
+	License     string
+	Keywords    []string
 
 	// Components
 	Commands []Command
@@ -17,6 +27,10 @@ type Plugin struct {
 	// MCP servers configuration
 	MCPServers map[string]MCPServerConfig
 
+	// Default LLM settings of the plugin's agents and commands, from the
+	// "bucephalus" block of plugin.json
+	LLM LLMDefaults
+
 	// Root path of the plugin
 	RootPath string
 }
@@ -34,6 +48,17 @@ type Command struct {
 	Description string // From frontmatter
 	Content     string // Markdown content (the prompt)
 	FilePath    string // Original file path
+
+	// Localized descriptions by lower-case locale, e.g. "ja" or "pt-br",
+	// from description.<locale> frontmatter keys (see LocalizedDescription)
+	Descriptions map[string]string
+
+	Aliases []string // Other names the command can be invoked by, from frontmatter
+	Hidden  bool     // Excluded from indexes and help but still invocable, from frontmatter
+
+	// Output contract, from frontmatter (see ParseOutput)
+	OutputFormat string          // OutputFormatText (default) or OutputFormatJSON
+	OutputSchema json.RawMessage // JSON schema of the output; implies OutputFormatJSON
 }
 
 // Agent represents a subagent defined in a plugin.
@@ -43,6 +68,8 @@ type Agent struct {
 	Tools       []string // Tools this agent can use
 	Content     string   // Markdown content (agent instructions)
 	FilePath    string   // Original file path
+
+	plugin *Plugin // Plugin the agent was loaded from, for its LLM defaults
 }
 
 // Skill represents an agent skill defined in a plugin.
@@ -52,21 +79,37 @@ type Skill struct {
 	Tools       []string // Tools this skill requires
 	Content     string   // Markdown content (skill instructions)
 	FilePath    string   // Original file path
+
+	// From frontmatter
+	Version      string            // Semantic version, e.g. "1.2.0"
+	License      string            // License name or file, e.g. "MIT"
+	Dependencies []SkillDependency // Skills this skill builds on
+	Metadata     map[string]string // Arbitrary key-value metadata
+	Descriptions map[string]string // Localized descriptions (see LocalizedDescription)
 }
 
-// MCPServerConfig represents an MCP server configuration.
-type MCPServerConfig struct {
-	Command string            `json:"command"`
-	Args    []string          `json:"args,omitempty"`
-	Env     map[string]string `json:"env,omitempty"`
+// SkillDependency is a dependency of a skill on another skill of the
+// plugin, with an optional version constraint such as ">=1.2, <2".
+type SkillDependency struct {
+	Name       string
+	Constraint string
 }
 
+// MCPServerConfig represents an MCP server configuration.
+// It is the configuration used by mcp.Manager, so plugin servers can be
+// passed to mcp.NewManager directly.
+type MCPServerConfig = mcp.ServerConfig
+
 // pluginManifest represents the plugin.json structure.
 type pluginManifest struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description,omitempty"`
-	Version     string  `json:"version,omitempty"`
-	Author      *Author `json:"author,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Author      *Author  `json:"author,omitempty"`
+	Homepage    string   `json:"homepage,omitempty"`
+	Repository  string   `json:"repository,omitempty"`
+	License     string   `json:"license,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
 
 	// Custom paths for components
 	Commands string `json:"commands,omitempty"`
@@ -76,12 +119,24 @@ type pluginManifest struct {
 	// Inline or path to hooks/mcp config
 	Hooks      any `json:"hooks,omitempty"`
 	MCPServers any `json:"mcpServers,omitempty"`
+
+	// Settings specific to this library, ignored by Claude Code
+	Bucephalus *manifestExtension `json:"bucephalus,omitempty"`
+}
+
+// manifestExtension represents the "bucephalus" block of plugin.json.
+type manifestExtension struct {
+	LLM *LLMDefaults `json:"llm,omitempty"`
 }
 
 // commandFrontmatter represents the YAML frontmatter in command files.
 type commandFrontmatter struct {
-	Description string   `yaml:"description"`
-	Allowed     []string `yaml:"allowed,omitempty"` // Allowed tools/contexts
+	Description  string   `yaml:"description"`
+	Allowed      []string `yaml:"allowed,omitempty"`       // Allowed tools/contexts
+	Aliases      []string `yaml:"aliases,omitempty"`       // Other names of the command
+	Hidden       bool     `yaml:"hidden,omitempty"`        // Excluded from indexes
+	OutputFormat string   `yaml:"output-format,omitempty"` // "text" or "json"
+	OutputSchema any      `yaml:"output-schema,omitempty"` // Inline schema, or path to a JSON schema file
 }
 
 // agentFrontmatter represents the YAML frontmatter in agent files.
@@ -92,6 +147,13 @@ type agentFrontmatter struct {
 
 // skillFrontmatter represents the YAML frontmatter in SKILL.md files.
 type skillFrontmatter struct {
-	Description string   `yaml:"description"`
-	Tools       []string `yaml:"tools,omitempty"`
+	Description  string            `yaml:"description"`
+	Tools        []string          `yaml:"tools,omitempty"`
+	Version      string            `yaml:"version,omitempty"`
+	License      string            `yaml:"license,omitempty"`
+	Metadata     map[string]string `yaml:"metadata,omitempty"`
+	Dependencies struct {
+		Skills []string `yaml:"skills,omitempty"` // "name" or "name <constraint>"
+		Tools  []string `yaml:"tools,omitempty"`  // Added to Tools
+	} `yaml:"dependencies,omitempty"`
 }
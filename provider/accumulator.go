@@ -0,0 +1,92 @@
+package provider
+
+import "strings"
+
+// Accumulator accumulates the chunks of a stream into a Response, for
+// implementing ResponseStream.Accumulated. Content and tool call arguments
+// are appended to buffers rather than concatenated, so accumulating a long
+// generation takes linear time and few allocations.
+//
+// Example:
+//
+//	acc := provider.NewAccumulator(raw)
+//	acc.AddContent("Hello")
+//	acc.AddToolCallDelta(0, "call_1", "get_weather", `{"city":`)
+//	acc.AddToolCallDelta(0, "", "", `"Tokyo"}`)
+//	acc.FinishReason = provider.FinishReasonToolCalls
+//	resp := acc.Response()
+type Accumulator struct {
+	FinishReason  FinishReason
+	Usage         Usage
+	ContentFilter *ContentFilter
+
+	resp      Response
+	content   strings.Builder
+	toolCalls []*toolCallBuffer
+	byIndex   map[int]*toolCallBuffer
+}
+
+// toolCallBuffer accumulates a tool call.
+type toolCallBuffer struct {
+	id, name  string
+	arguments strings.Builder
+}
+
+// NewAccumulator returns an empty accumulator of a stream with the HTTP
+// response raw, which may be nil.
+func NewAccumulator(raw *HTTPResponse) *Accumulator {
+	return &Accumulator{resp: Response{HTTP: raw}}
+}
+
+// AddContent appends delta to the content.
+func (a *Accumulator) AddContent(delta string) {
+	a.content.WriteString(delta)
+}
+
+// AddToolCallDelta appends argumentsDelta to the arguments of the tool call
+// with index, starting the call if it is new, and returns the ID and name of
+// the call. Non-empty id and name set those of the call. Tool calls are
+// accumulated in the order they start.
+func (a *Accumulator) AddToolCallDelta(index int, id, name, argumentsDelta string) (callID, callName string) {
+	tc, ok := a.byIndex[index]
+	if !ok {
+		if a.byIndex == nil {
+			a.byIndex = make(map[int]*toolCallBuffer)
+		}
+		tc = &toolCallBuffer{}
+		a.byIndex[index] = tc
+		a.toolCalls = append(a.toolCalls, tc)
+	}
+	if id != "" {
+		tc.id = id
+	}
+	if name != "" {
+		tc.name = name
+	}
+	tc.arguments.WriteString(argumentsDelta)
+	return tc.id, tc.name
+}
+
+// AddToolCall adds a complete tool call.
+func (a *Accumulator) AddToolCall(tc ToolCall) {
+	buf := &toolCallBuffer{id: tc.ID, name: tc.Name}
+	buf.arguments.WriteString(tc.Arguments)
+	a.toolCalls = append(a.toolCalls, buf)
+}
+
+// Response returns the response accumulated so far. It returns the same
+// *Response on every call, updated with the chunks added since.
+func (a *Accumulator) Response() *Response {
+	a.resp.Content = a.content.String()
+	a.resp.FinishReason = a.FinishReason
+	a.resp.Usage = a.Usage
+	a.resp.ContentFilter = a.ContentFilter
+	if len(a.toolCalls) > 0 {
+		calls := make([]ToolCall, len(a.toolCalls))
+		for i, tc := range a.toolCalls {
+			calls[i] = ToolCall{ID: tc.id, Name: tc.name, Arguments: tc.arguments.String()}
+		}
+		a.resp.ToolCalls = calls
+	}
+	return &a.resp
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccumulator(t *testing.T) {
+	raw := &HTTPResponse{StatusCode: 200}
+	acc := NewAccumulator(raw)
+	acc.AddContent("Hello, ")
+	acc.AddContent("world")
+
+	id, name := acc.AddToolCallDelta(1, "call_b", "search", `{"q":`)
+	assert.Equal(t, "call_b", id)
+	assert.Equal(t, "search", name)
+	acc.AddToolCallDelta(0, "call_a", "lookup", `{}`)
+	id, name = acc.AddToolCallDelta(1, "", "", `"go"}`)
+	assert.Equal(t, "call_b", id)
+	assert.Equal(t, "search", name)
+	acc.AddToolCall(ToolCall{ID: "call_c", Name: "done", Arguments: `{}`})
+	acc.FinishReason = FinishReasonToolCalls
+	acc.Usage = Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7}
+
+	resp := acc.Response()
+	assert.Equal(t, "Hello, world", resp.Content)
+	assert.Equal(t, []ToolCall{ // In the order they started
+		{ID: "call_b", Name: "search", Arguments: `{"q":"go"}`},
+		{ID: "call_a", Name: "lookup", Arguments: `{}`},
+		{ID: "call_c", Name: "done", Arguments: `{}`},
+	}, resp.ToolCalls)
+	assert.Equal(t, FinishReasonToolCalls, resp.FinishReason)
+	assert.Equal(t, 7, resp.Usage.TotalTokens)
+	assert.Same(t, raw, resp.HTTP)
+
+	acc.AddContent("!")
+	assert.Same(t, resp, acc.Response())
+	assert.Equal(t, "Hello, world!", resp.Content)
+}
+
+// BenchmarkAccumulator compares accumulating the content of a long
+// generation with the Accumulator and with string concatenation.
+func BenchmarkAccumulator(b *testing.B) {
+	const chunks = 10000
+	delta := strings.Repeat("x", 16)
+
+	b.Run("Accumulator", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			acc := NewAccumulator(nil)
+			for range chunks {
+				acc.AddContent(delta)
+			}
+			_ = acc.Response()
+		}
+	})
+
+	b.Run("Concatenation", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var content string
+			for range chunks {
+				content += delta
+			}
+			_ = content
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+)
+
+// Transcriber is implemented by providers that can transcribe speech to text.
+type Transcriber interface {
+	// Transcribe returns the transcript of the audio in req.
+	Transcribe(ctx context.Context, req *TranscriptionRequest) (*Transcription, error)
+}
+
+// SpeechSynthesizer is implemented by providers that can synthesize speech
+// from text.
+type SpeechSynthesizer interface {
+	// Speak returns the audio of the text in req spoken aloud.
+	Speak(ctx context.Context, req *SpeechRequest) (*Speech, error)
+}
+
+// TranscriptionRequest is a provider-agnostic speech-to-text request.
+type TranscriptionRequest struct {
+	Model    string
+	Audio    []byte
+	MIMEType string // e.g. "audio/mpeg" or "audio/wav"
+	Language string // ISO-639-1 code of the spoken language, if known
+	Prompt   string // Context that improves accuracy, such as vocabulary
+	Headers  http.Header
+}
+
+// Transcription is the result of a TranscriptionRequest.
+type Transcription struct {
+	Text  string
+	Usage Usage         // Token usage, if the provider reports it
+	HTTP  *HTTPResponse // Raw HTTP response, if the provider uses HTTP
+}
+
+// SpeechRequest is a provider-agnostic text-to-speech request.
+type SpeechRequest struct {
+	Model        string
+	Text         string
+	Voice        string  // Provider-specific voice name; empty for the provider's default
+	Format       string  // Audio format, e.g. "mp3" or "wav"; empty for the provider's default
+	Speed        float64 // Speaking rate, where 1 is normal; 0 for the default
+	Instructions string  // How to speak, e.g. the tone or accent
+	Headers      http.Header
+}
+
+// Speech is the result of a SpeechRequest.
+type Speech struct {
+	Audio    []byte
+	MIMEType string
+	Usage    Usage         // Token usage, if the provider reports it
+	HTTP     *HTTPResponse // Raw HTTP response, if the provider uses HTTP
+}
@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// StatusError is implemented by provider API errors that carry an HTTP status code.
+type StatusError interface {
+	error
+	HTTPStatus() int
+}
+
+// HTTPResponse is the raw HTTP response of a provider API call, for
+// debugging and support requests.
+type HTTPResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte // nil for streamed responses
+}
+
+// RequestID returns the provider's ID of the request from the response
+// headers, or "" if there is none. It is safe to call on a nil response.
+func (r *HTTPResponse) RequestID() string {
+	if r == nil {
+		return ""
+	}
+	for _, key := range []string{"X-Request-Id", "Request-Id", "X-Goog-Request-Id"} {
+		if id := r.Header.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// RawResponseError is implemented by provider API errors that carry the raw
+// HTTP response.
+type RawResponseError interface {
+	error
+	RawResponse() *HTTPResponse
+}
+
+// RawResponse returns the raw HTTP response of a provider API error in err's
+// chain, or nil if there is none.
+//
+// Example:
+//
+//	if raw := provider.RawResponse(err); raw != nil {
+//	    log.Printf("request %s failed: %s", raw.RequestID(), raw.Body)
+//	}
+func RawResponse(err error) *HTTPResponse {
+	var re RawResponseError
+	if errors.As(err, &re) {
+		return re.RawResponse()
+	}
+	return nil
+}
+
+// IsTransient reports whether err is likely temporary, so the request may
+// succeed later or with another provider: rate limits (429), timeouts (408),
+// server errors and overload (5xx), and transport errors without a status code
+// (network errors, connection resets and refusals, and truncated responses).
+// Context cancellation, other client errors (4xx), and local errors such as
+// configuration or marshaling errors are not transient.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var se StatusError
+	if !errors.As(err, &se) {
+		return isTransportError(err)
+	}
+	status := se.HTTPStatus()
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusRequestTimeout ||
+		status >= http.StatusInternalServerError
+}
+
+// isTransportError reports whether err is a failure to reach the provider or
+// to read its response.
+func isTransportError(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// contextOverflowPhrases are the phrases of the errors of the providers for
+// requests that exceed the context window of the model, in lowercase.
+var contextOverflowPhrases = []string{
+	"context_length_exceeded",              // OpenAI
+	"maximum context length",               // OpenAI
+	"prompt is too long",                   // Anthropic
+	"exceeds the maximum number of tokens", // Gemini
+	"context window",
+	"too many tokens",
+}
+
+// IsContextOverflow reports whether err is the rejection of a request that
+// exceeds the context window of the model. Provider errors with a status
+// code other than 400 (Bad Request) or 413 (Content Too Large) are not
+// overflows.
+func IsContextOverflow(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se StatusError
+	if errors.As(err, &se) && se.HTTPStatus() != http.StatusBadRequest && se.HTTPStatus() != http.StatusRequestEntityTooLarge {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, phrase := range contextOverflowPhrases {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) HTTPStatus() int { return int(e) }
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"rate limit", statusError(429), true},
+		{"overloaded", fmt.Errorf("calling provider: %w", statusError(529)), true},
+		{"server error", statusError(503), true},
+		{"bad request", statusError(400), false},
+		{"unauthorized", statusError(401), false},
+		{"network error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}, true},
+		{"connection refused", fmt.Errorf("sending request: %w", syscall.ECONNREFUSED), true},
+		{"connection reset", fmt.Errorf("reading response: %w", syscall.ECONNRESET), true},
+		{"truncated response", fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF), true},
+		{"local error", errors.New("model is required"), false},
+		{"marshal error", fmt.Errorf("marshaling request: %w", &json.UnsupportedValueError{Str: "NaN"}), false},
+		{"canceled", fmt.Errorf("sending request: %w", context.Canceled), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransient(tt.err))
+		})
+	}
+}
+
+type rawError struct{ raw *HTTPResponse }
+
+func (e rawError) Error() string              { return "raw" }
+func (e rawError) RawResponse() *HTTPResponse { return e.raw }
+
+func TestRawResponse(t *testing.T) {
+	raw := &HTTPResponse{StatusCode: 429, Header: http.Header{"Request-Id": {"req_1"}}, Body: []byte("slow down")}
+
+	got := RawResponse(fmt.Errorf("calling provider: %w", rawError{raw}))
+	assert.Same(t, raw, got)
+	assert.Equal(t, "req_1", got.RequestID())
+
+	assert.Nil(t, RawResponse(errors.New("connection refused")))
+	assert.Empty(t, (*HTTPResponse)(nil).RequestID())
+}
+
+// messageError is a status error with a message.
+type messageError struct {
+	status  int
+	message string
+}
+
+func (e messageError) Error() string   { return e.message }
+func (e messageError) HTTPStatus() int { return e.status }
+
+func TestIsContextOverflow(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"openai", fmt.Errorf("calling provider: %w", messageError{400, "openai API error (status 400, type invalid_request_error): This model's maximum context length is 8192 tokens."}), true},
+		{"anthropic", messageError{400, "anthropic API error (status 400, type invalid_request_error): prompt is too long: 210000 tokens > 200000 maximum"}, true},
+		{"gemini", messageError{400, "gemini API error (status 400): The input token count (1200000) exceeds the maximum number of tokens allowed (1048576)."}, true},
+		{"other bad request", messageError{400, "invalid model"}, false},
+		{"rate limit", messageError{429, "too many tokens per minute"}, false},
+		{"transport error", errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsContextOverflow(tt.err))
+		})
+	}
+}
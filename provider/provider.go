@@ -21,12 +21,28 @@ type StreamingProvider interface {
 	CallStream(ctx context.Context, req *Request) (ResponseStream, error)
 }
 
+// ModelLister is implemented by providers that can list the models available
+// to the configured account.
+type ModelLister interface {
+	// ListModels returns the available models, sorted by ID.
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// HealthChecker is implemented by providers that can check their
+// configuration and connectivity with a cheap request that generates nothing.
+type HealthChecker interface {
+	// Ping returns an error if the provider API cannot be reached or rejects
+	// the credentials.
+	Ping(ctx context.Context) error
+}
+
 // ResponseStream represents a streaming response.
 type ResponseStream interface {
 	// Next advances to the next chunk, returns false when done.
 	Next() bool
 
-	// Current returns the current chunk.
+	// Current returns the current chunk. It is only valid until the next
+	// call to Next, which may reuse it.
 	Current() *StreamChunk
 
 	// Err returns any error that occurred during streaming.
@@ -1,35 +1,150 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"reflect"
 	"sync"
 )
 
 var (
-	registry = make(map[string]func() (Provider, error))
-	mu       sync.RWMutex
+	registry    = make(map[string]*entry)
+	defaultName string
+	mu          sync.RWMutex
 )
 
+// entry is a registered provider factory and the provider it created.
+// The factory runs once, on the first Get, and its result (provider or
+// error) is cached until Reset or Close.
+type entry struct {
+	factory func() (Provider, error)
+	once    sync.Once
+	p       Provider
+	err     error
+}
+
+func (e *entry) get(name string) (Provider, error) {
+	e.once.Do(func() {
+		e.p, e.err = e.factory()
+		if e.err != nil {
+			e.err = fmt.Errorf("creating provider %q: %w", name, e.err)
+		}
+	})
+	return e.p, e.err
+}
+
 // Register adds a provider factory to the registry.
 // This is typically called from a provider package's init() function.
+// The factory runs on the first Get of the provider, which then returns the
+// same provider, or the same error, until Reset.
 func Register(name string, factory func() (Provider, error)) {
 	mu.Lock()
 	defer mu.Unlock()
-	registry[name] = factory
+	registry[name] = &entry{factory: factory}
 }
 
-// Get retrieves a provider by name.
-// Returns an error if the provider is not registered.
+// RegisterInstance adds a pre-configured provider to the registry under
+// name. The same implementation can be registered under several names with
+// different configurations, e.g. one per region or endpoint.
+//
+// Example:
+//
+//	eu, err := openai.New(openai.WithBaseURL("https://eu.example.com/v1"))
+//	if err != nil {
+//	    return err
+//	}
+//	provider.RegisterInstance("openai-eu", eu)
+func RegisterInstance(name string, p Provider) {
+	Register(name, func() (Provider, error) { return p, nil })
+}
+
+// Get retrieves a provider by name, creating it on first use.
+// Returns an error if the provider is not registered or cannot be created.
 func Get(name string) (Provider, error) {
 	mu.RLock()
-	factory, ok := registry[name]
+	e, ok := registry[name]
 	mu.RUnlock()
 
 	if !ok {
 		return nil, fmt.Errorf("unknown provider: %q (available: %v)", name, Available())
 	}
 
-	return factory()
+	return e.get(name)
+}
+
+// Reset discards the provider created for name, or its creation error, so
+// the next Get creates it again, e.g. after its API key environment variable
+// is set. It does not close the provider.
+func Reset(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if e, ok := registry[name]; ok {
+		registry[name] = &entry{factory: e.factory}
+	}
+}
+
+// Close closes the created providers that implement io.Closer and discards
+// all created providers, so the next Get creates them again. It returns the
+// errors of the providers that fail to close.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var errs []error
+	closed := make(map[Provider]bool)
+	for name, e := range registry {
+		registry[name] = &entry{factory: e.factory}
+		e.once.Do(func() {}) // Do not create providers that were never used
+		closer, ok := e.p.(io.Closer)
+		if !ok {
+			continue
+		}
+		if reflect.TypeOf(e.p).Comparable() { // Close instances registered under several names once
+			if closed[e.p] {
+				continue
+			}
+			closed[e.p] = true
+		}
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing provider %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MustGet is like Get but panics if the provider is not registered or cannot
+// be created. It is intended for program initialization.
+func MustGet(name string) Provider {
+	p, err := Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// SetDefault sets the provider used by calls that do not name one.
+// The provider does not need to be registered yet.
+func SetDefault(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultName = name
+}
+
+// DefaultName returns the name set by SetDefault, or "" if there is none.
+func DefaultName() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultName
+}
+
+// Default retrieves the provider set by SetDefault.
+func Default() (Provider, error) {
+	name := DefaultName()
+	if name == "" {
+		return nil, errors.New("no default provider: use SetDefault")
+	}
+	return Get(name)
 }
 
 // Available returns the names of all registered providers.
@@ -27,7 +27,8 @@ func (m *mockProvider) Call(ctx context.Context, req *Request) (*Response, error
 func clearRegistry() {
 	mu.Lock()
 	defer mu.Unlock()
-	registry = make(map[string]func() (Provider, error))
+	registry = make(map[string]*entry)
+	defaultName = ""
 }
 
 func TestRegister(t *testing.T) {
@@ -136,6 +137,106 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestRegisterInstance(t *testing.T) {
+	clearRegistry()
+
+	eu := &mockProvider{name: "mock"}
+	us := &mockProvider{name: "mock"}
+	RegisterInstance("mock-eu", eu)
+	RegisterInstance("mock-us", us)
+
+	p, err := Get("mock-eu")
+	require.NoError(t, err)
+	assert.Same(t, eu, p)
+	assert.Same(t, us, MustGet("mock-us"))
+	assert.Panics(t, func() { MustGet("unknown") })
+}
+
+func TestDefault(t *testing.T) {
+	clearRegistry()
+
+	_, err := Default()
+	assert.Error(t, err)
+
+	RegisterInstance("mock", &mockProvider{name: "mock"})
+	SetDefault("mock")
+	assert.Equal(t, "mock", DefaultName())
+
+	p, err := Default()
+	require.NoError(t, err)
+	assert.Equal(t, "mock", p.Name())
+}
+
+// closingProvider counts its Close calls.
+type closingProvider struct {
+	mockProvider
+	closed int
+}
+
+func (p *closingProvider) Close() error {
+	p.closed++
+	return nil
+}
+
+func TestGet_CachesProvider(t *testing.T) {
+	clearRegistry()
+
+	created := 0
+	Register("cached", func() (Provider, error) {
+		created++
+		return &mockProvider{name: "cached"}, nil
+	})
+
+	first, err := Get("cached")
+	require.NoError(t, err)
+	second, err := Get("cached")
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, created)
+
+	Reset("cached")
+	third, err := Get("cached")
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+	assert.Equal(t, 2, created)
+}
+
+func TestGet_CachesError(t *testing.T) {
+	clearRegistry()
+
+	attempts := 0
+	Register("broken", func() (Provider, error) {
+		attempts++
+		return nil, errors.New("API key required")
+	})
+
+	for range 3 {
+		_, err := Get("broken")
+		require.Error(t, err)
+		assert.Equal(t, `creating provider "broken": API key required`, err.Error())
+	}
+	assert.Equal(t, 1, attempts)
+}
+
+func TestClose(t *testing.T) {
+	clearRegistry()
+
+	shared := &closingProvider{}
+	RegisterInstance("a", shared)
+	RegisterInstance("b", shared)
+	unused := &closingProvider{}
+	RegisterInstance("unused", unused)
+
+	MustGet("a")
+	MustGet("b")
+	require.NoError(t, Close())
+	assert.Equal(t, 1, shared.closed)
+	assert.Equal(t, 0, unused.closed)
+
+	// Providers are created again after Close
+	assert.Same(t, shared, MustGet("a"))
+}
+
 func TestGet_ErrorIncludesAvailable(t *testing.T) {
 	clearRegistry()
 
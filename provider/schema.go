@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// ApplyAdditionalProperties returns schema with additionalProperties set on
+// its objects as mode requires. Map schemas, whose additionalProperties is a
+// schema, are left as they are.
+func ApplyAdditionalProperties(schema json.RawMessage, mode AdditionalProperties) json.RawMessage {
+	if schema == nil || mode == AdditionalPropertiesAuto {
+		return schema
+	}
+
+	var schemaMap map[string]any
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		return schema
+	}
+
+	applyAdditionalProperties(schemaMap, mode)
+
+	result, err := json.Marshal(schemaMap)
+	if err != nil {
+		return schema
+	}
+	return result
+}
+
+func applyAdditionalProperties(schemaMap map[string]any, mode AdditionalProperties) {
+	if _, isBool := schemaMap["additionalProperties"].(bool); isBool || schemaMap["additionalProperties"] == nil {
+		_, hasProps := schemaMap["properties"]
+		switch {
+		case mode == AdditionalPropertiesOmit:
+			delete(schemaMap, "additionalProperties")
+		case mode == AdditionalPropertiesFalse && (hasProps || schemaMap["type"] == "object"):
+			schemaMap["additionalProperties"] = false
+		}
+	}
+
+	// Recursively process subschemas
+	for _, key := range []string{"properties", "$defs", "definitions"} {
+		if subs, ok := schemaMap[key].(map[string]any); ok {
+			for _, sub := range subs {
+				if subMap, ok := sub.(map[string]any); ok {
+					applyAdditionalProperties(subMap, mode)
+				}
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if subs, ok := schemaMap[key].([]any); ok {
+			for _, sub := range subs {
+				if subMap, ok := sub.(map[string]any); ok {
+					applyAdditionalProperties(subMap, mode)
+				}
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if subMap, ok := schemaMap[key].(map[string]any); ok {
+			applyAdditionalProperties(subMap, mode)
+		}
+	}
+}
+
+// DefaultRefDepth is how many times InlineRefs expands each recursive
+// reference by default.
+const DefaultRefDepth = 4
+
+// InlineRefs returns schema with its local references ($ref) replaced by the
+// schemas they refer to, for providers that do not support references.
+// Recursive references are expanded at most maxDepth times along any path;
+// the properties, items, and alternatives that would nest deeper are
+// dropped.
+func InlineRefs(schema json.RawMessage, maxDepth int) json.RawMessage {
+	if schema == nil || !bytes.Contains(schema, []byte(`"$ref"`)) {
+		return schema
+	}
+
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return schema
+	}
+
+	inlined := inlineRefs(root, root, make(map[string]int), maxDepth)
+	if inlined == nil {
+		return schema
+	}
+	delete(inlined, "$defs")
+	delete(inlined, "definitions")
+
+	result, err := json.Marshal(inlined)
+	if err != nil {
+		return schema
+	}
+	return result
+}
+
+// inlineRefs returns a copy of node with its references inlined, or nil if
+// node nests deeper than allowed. expanded counts the expansions of each
+// reference on the current path.
+func inlineRefs(node, root map[string]any, expanded map[string]int, maxDepth int) map[string]any {
+	if ref, ok := node["$ref"].(string); ok {
+		target, ok := resolvePointer(root, ref)
+		if !ok {
+			return node
+		}
+		if expanded[ref] >= maxDepth {
+			return nil
+		}
+
+		// Keywords next to the reference, such as description, take precedence
+		merged := make(map[string]any, len(target)+len(node))
+		for k, v := range target {
+			merged[k] = v
+		}
+		for k, v := range node {
+			if k != "$ref" {
+				merged[k] = v
+			}
+		}
+		delete(merged, "$defs")
+		delete(merged, "definitions")
+
+		expanded[ref]++
+		defer func() { expanded[ref]-- }()
+		return inlineRefs(merged, root, expanded, maxDepth)
+	}
+
+	out := make(map[string]any, len(node))
+	for k, v := range node {
+		out[k] = v
+	}
+
+	if props, ok := node["properties"].(map[string]any); ok {
+		inlinedProps := make(map[string]any, len(props))
+		for name, prop := range props {
+			propMap, ok := prop.(map[string]any)
+			if !ok {
+				inlinedProps[name] = prop
+				continue
+			}
+			if inlined := inlineRefs(propMap, root, expanded, maxDepth); inlined != nil {
+				inlinedProps[name] = inlined
+			}
+		}
+		out["properties"] = inlinedProps
+		if required, ok := node["required"].([]any); ok {
+			kept := make([]any, 0, len(required))
+			for _, name := range required {
+				if n, ok := name.(string); ok {
+					if _, present := inlinedProps[n]; !present {
+						continue
+					}
+				}
+				kept = append(kept, name)
+			}
+			out["required"] = kept
+		}
+	}
+	if items, ok := node["items"].(map[string]any); ok {
+		inlined := inlineRefs(items, root, expanded, maxDepth)
+		if inlined == nil {
+			return nil
+		}
+		out["items"] = inlined
+	}
+	if additional, ok := node["additionalProperties"].(map[string]any); ok {
+		if inlined := inlineRefs(additional, root, expanded, maxDepth); inlined != nil {
+			out["additionalProperties"] = inlined
+		} else {
+			out["additionalProperties"] = false
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		alts, ok := node[key].([]any)
+		if !ok {
+			continue
+		}
+		inlinedAlts := make([]any, 0, len(alts))
+		for _, alt := range alts {
+			altMap, ok := alt.(map[string]any)
+			if !ok {
+				inlinedAlts = append(inlinedAlts, alt)
+				continue
+			}
+			inlined := inlineRefs(altMap, root, expanded, maxDepth)
+			if inlined == nil {
+				if key == "allOf" {
+					return nil
+				}
+				continue
+			}
+			inlinedAlts = append(inlinedAlts, inlined)
+		}
+		if len(inlinedAlts) == 0 {
+			return nil
+		}
+		out[key] = inlinedAlts
+	}
+	return out
+}
+
+// resolvePointer returns the schema in root that a local reference such as
+// "#/$defs/Node" points to.
+func resolvePointer(root map[string]any, ref string) (map[string]any, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, false
+	}
+	current := root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		next, ok := current[token].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyAdditionalProperties(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"tags": {"type": "array", "items": {"type": "object", "properties": {"name": {"type": "string"}}}},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"shape": {"anyOf": [{"type": "object", "properties": {"r": {"type": "number"}}, "additionalProperties": false}]}
+		},
+		"additionalProperties": false
+	}`)
+
+	decode := func(data json.RawMessage) map[string]any {
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(data, &m))
+		return m
+	}
+
+	t.Run("auto", func(t *testing.T) {
+		assert.JSONEq(t, string(schema), string(ApplyAdditionalProperties(schema, AdditionalPropertiesAuto)))
+	})
+
+	t.Run("false", func(t *testing.T) {
+		m := decode(ApplyAdditionalProperties(schema, AdditionalPropertiesFalse))
+		props := m["properties"].(map[string]any)
+		assert.Equal(t, false, m["additionalProperties"])
+		assert.Equal(t, false, props["tags"].(map[string]any)["items"].(map[string]any)["additionalProperties"])
+		assert.Equal(t, map[string]any{"type": "string"}, props["labels"].(map[string]any)["additionalProperties"])
+	})
+
+	t.Run("omit", func(t *testing.T) {
+		m := decode(ApplyAdditionalProperties(schema, AdditionalPropertiesOmit))
+		props := m["properties"].(map[string]any)
+		assert.NotContains(t, m, "additionalProperties")
+		assert.NotContains(t, props["shape"].(map[string]any)["anyOf"].([]any)[0], "additionalProperties")
+		assert.Equal(t, map[string]any{"type": "string"}, props["labels"].(map[string]any)["additionalProperties"])
+	})
+}
+
+func TestInlineRefs(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"root": {"$ref": "#/$defs/Node", "description": "The root node"},
+			"meta": {"$ref": "#/$defs/Meta"}
+		},
+		"required": ["root", "meta"],
+		"$defs": {
+			"Meta": {"type": "object", "properties": {"n": {"type": "integer"}}},
+			"Node": {
+				"type": "object",
+				"properties": {
+					"label": {"type": "string"},
+					"children": {"type": "array", "items": {"$ref": "#/$defs/Node"}}
+				},
+				"required": ["label", "children"]
+			}
+		}
+	}`)
+
+	var s map[string]any
+	require.NoError(t, json.Unmarshal(InlineRefs(schema, 2), &s))
+	assert.NotContains(t, s, "$defs")
+
+	props := s["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "object", "properties": map[string]any{"n": map[string]any{"type": "integer"}}}, props["meta"])
+
+	// Node is expanded twice; the children of the innermost node are dropped
+	root := props["root"].(map[string]any)
+	assert.Equal(t, "The root node", root["description"])
+	child := root["properties"].(map[string]any)["children"].(map[string]any)["items"].(map[string]any)
+	assert.Equal(t, []any{"label"}, child["required"])
+	assert.NotContains(t, child["properties"], "children")
+	assert.Contains(t, child["properties"], "label")
+}
+
+func TestInlineRefs_NoRefs(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"a":{"type":"string"}}}`)
+	assert.Equal(t, schema, InlineRefs(schema, DefaultRefDepth))
+}
@@ -0,0 +1,203 @@
+// Package sse reads server-sent events, the streaming format of the
+// provider APIs, as specified by the HTML Living Standard
+// (https://html.spec.whatwg.org/multipage/server-sent-events.html).
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxLineSize is the default limit on the size of a line of the
+// stream (16 MiB).
+const DefaultMaxLineSize = 16 << 20
+
+// bufferSize is the initial size of the line buffer of a Reader.
+const bufferSize = 4096
+
+// bufferPool holds the initial line buffers of Readers, returned when they
+// reach the end of their stream, so that the many short streams of a busy
+// process share them.
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, bufferSize)
+		return &buf
+	},
+}
+
+var (
+	bom   = []byte("\ufeff")
+	colon = []byte(":")
+	space = []byte(" ")
+)
+
+// Event is a server-sent event.
+type Event struct {
+	Type  string        // The event field; "message" if the event has none
+	Data  string        // The data fields, joined by newlines
+	ID    string        // The last event ID of the stream
+	Retry time.Duration // The reconnection time of the event, if any
+}
+
+// Reader reads events from a stream.
+//
+// Example:
+//
+//	r := sse.NewReader(resp.Body)
+//	for {
+//	    event, err := r.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Println(event.Type, event.Data)
+//	}
+type Reader struct {
+	scanner  *bufio.Scanner
+	buf      *[]byte // Line buffer from bufferPool, until the end of the stream
+	searched int     // Bytes of the current line searched for its end
+	lastID   string
+	started  bool
+}
+
+// NewReader returns a Reader that reads events from r, with lines of up to
+// DefaultMaxLineSize bytes.
+func NewReader(r io.Reader) *Reader {
+	return NewReaderSize(r, DefaultMaxLineSize)
+}
+
+// NewReaderSize returns a Reader that reads events from r, with lines of up
+// to maxLineSize bytes.
+func NewReaderSize(r io.Reader, maxLineSize int) *Reader {
+	reader := &Reader{scanner: bufio.NewScanner(r)}
+	if maxLineSize >= bufferSize {
+		reader.buf = bufferPool.Get().(*[]byte)
+		reader.scanner.Buffer(*reader.buf, maxLineSize)
+	} else {
+		reader.scanner.Buffer(make([]byte, maxLineSize), maxLineSize)
+	}
+	reader.scanner.Split(reader.scanLines)
+	return reader
+}
+
+// Next returns the next event with data. It returns io.EOF at the end of the
+// stream. Comments and events without data are skipped. Unlike the standard,
+// a last event that is not followed by a blank line is returned rather than
+// discarded, since some servers omit the blank line before closing the
+// stream.
+func (r *Reader) Next() (*Event, error) {
+	var (
+		eventType string
+		data      strings.Builder
+		hasData   bool
+		retry     time.Duration
+	)
+	dispatch := func() *Event {
+		if !hasData {
+			eventType, retry = "", 0
+			return nil
+		}
+		event := &Event{Type: eventType, Data: data.String(), ID: r.lastID, Retry: retry}
+		if event.Type == "" {
+			event.Type = "message"
+		}
+		return event
+	}
+
+	// Lines are handled as bytes, so only event fields are copied.
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if !r.started {
+			r.started = true
+			line = bytes.TrimPrefix(line, bom) // Byte order mark
+		}
+
+		if len(line) == 0 {
+			if event := dispatch(); event != nil {
+				return event, nil
+			}
+			continue
+		}
+		if line[0] == ':' {
+			continue // Comment
+		}
+
+		field, value, _ := bytes.Cut(line, colon)
+		value = bytes.TrimPrefix(value, space)
+		switch string(field) {
+		case "event":
+			eventType = string(value)
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.Write(value)
+			hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				r.lastID = string(value)
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 63); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	// The scanner no longer uses its buffer
+	if r.buf != nil {
+		bufferPool.Put(r.buf)
+		r.buf = nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading event stream: %w", err)
+	}
+	if event := dispatch(); event != nil {
+		return event, nil
+	}
+	return nil, io.EOF
+}
+
+// LastEventID returns the last event ID of the stream, for resuming it.
+func (r *Reader) LastEventID() string {
+	return r.lastID
+}
+
+// scanLines is a bufio.SplitFunc for lines ending in CRLF, LF, or CR. It
+// remembers how much of an unterminated line it has searched, so that long
+// lines arriving in many reads are scanned in linear time.
+func (r *Reader) scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data[r.searched:], "\r\n"); i >= 0 {
+		i += r.searched
+		r.searched = 0
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A CR at the end of the data may be followed by an LF
+		if i+1 == len(data) && !atEOF {
+			r.searched = i
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		r.searched = 0
+		return len(data), data, nil
+	}
+	r.searched = len(data)
+	return 0, nil, nil
+}
@@ -0,0 +1,166 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAll reads all events of the stream.
+func readAll(r *Reader) ([]Event, error) {
+	var events []Event
+	for {
+		event, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, *event)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []Event
+	}{
+		{
+			name:   "data only",
+			stream: "data: {\"a\":1}\n\ndata: [DONE]\n\n",
+			want:   []Event{{Type: "message", Data: `{"a":1}`}, {Type: "message", Data: "[DONE]"}},
+		},
+		{
+			name:   "event and data",
+			stream: "event: message_start\ndata: {}\n\n",
+			want:   []Event{{Type: "message_start", Data: "{}"}},
+		},
+		{
+			name:   "fields in any order",
+			stream: "data: {}\nevent: ping\n\n",
+			want:   []Event{{Type: "ping", Data: "{}"}},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: first\ndata:second\ndata\n\n",
+			want:   []Event{{Type: "message", Data: "first\nsecond\n"}},
+		},
+		{
+			name:   "comments and unknown fields",
+			stream: ": keep-alive\nfoo: bar\ndata: x\n\n:\n\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+		{
+			name:   "event without data",
+			stream: "event: ping\n\ndata: x\n\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+		{
+			name:   "CRLF and CR line endings",
+			stream: "event: a\r\ndata: 1\r\n\r\nevent: b\rdata: 2\r\r",
+			want:   []Event{{Type: "a", Data: "1"}, {Type: "b", Data: "2"}},
+		},
+		{
+			name:   "id and retry",
+			stream: "id: 7\nretry: 1500\ndata: x\n\ndata: y\n\n",
+			want: []Event{
+				{Type: "message", Data: "x", ID: "7", Retry: 1500 * time.Millisecond},
+				{Type: "message", Data: "y", ID: "7"},
+			},
+		},
+		{
+			name:   "byte order mark",
+			stream: "\ufeffdata: x\n\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+		{
+			name:   "no final blank line",
+			stream: "data: x\n\ndata: y",
+			want:   []Event{{Type: "message", Data: "x"}, {Type: "message", Data: "y"}},
+		},
+		{
+			name:   "only one leading space is removed",
+			stream: "data:  x \n\n",
+			want:   []Event{{Type: "message", Data: " x "}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := readAll(NewReader(strings.NewReader(tt.stream)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, events)
+
+			// Reading byte by byte gives the same events
+			events, err = readAll(NewReader(iotest.OneByteReader(strings.NewReader(tt.stream))))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, events)
+		})
+	}
+}
+
+func TestReader_LineTooLong(t *testing.T) {
+	r := NewReaderSize(strings.NewReader("data: "+strings.Repeat("x", 100)+"\n\n"), 64)
+	_, err := r.Next()
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, io.EOF)
+}
+
+func TestReader_ReadError(t *testing.T) {
+	r := NewReader(io.MultiReader(strings.NewReader("data: x\n\n"), iotest.ErrReader(io.ErrUnexpectedEOF)))
+	event, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "x", event.Data)
+
+	_, err = r.Next()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add("event: a\ndata: 1\n\n")
+	f.Add("data: x\r\ndata: y\r\n\r\n: comment\rid: 1\rretry: 10\r\r")
+	f.Add("\ufeffdata\n\ndata: [DONE]")
+
+	f.Fuzz(func(t *testing.T, stream string) {
+		events, err := readAll(NewReader(strings.NewReader(stream)))
+		require.NoError(t, err)
+		for _, event := range events {
+			assert.NotEmpty(t, event.Type)
+			assert.NotContains(t, event.Type, "\n")
+			assert.NotContains(t, event.Data, "\r")
+		}
+
+		// Events do not depend on how the stream is split into reads
+		split, err := readAll(NewReader(iotest.OneByteReader(strings.NewReader(stream))))
+		require.NoError(t, err)
+		assert.Equal(t, events, split)
+	})
+}
+
+func BenchmarkReader(b *testing.B) {
+	var stream strings.Builder
+	for i := range 10000 {
+		fmt.Fprintf(&stream, "event: delta\ndata: {\"index\":%d,\"text\":\"hello world\"}\n\n", i)
+	}
+	data := stream.String()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		r := NewReader(strings.NewReader(data))
+		for {
+			if _, err := r.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultMaxResponseBytes is the default limit on the size of a provider API
+// response body (32 MiB). Streamed responses are not limited.
+const DefaultMaxResponseBytes = 32 << 20
+
+// HTTPOptions configures the HTTP client of a provider.
+type HTTPOptions struct {
+	// Proxy is the URL of the proxy for requests, e.g.
+	// "http://proxy.internal:3128". If empty, the proxy is taken from the
+	// HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string
+
+	// Timeout limits the time of a whole request, including reading the
+	// response body. Zero means no limit, since generations and streams can
+	// take minutes; use the context to bound individual calls.
+	Timeout time.Duration
+}
+
+// sharedTransport is the transport of the providers without a proxy, so they
+// share one connection pool.
+var sharedTransport = NewTransport(nil)
+
+// NewTransport returns an HTTP transport with the defaults used by the
+// providers: pooled keep-alive connections, HTTP/2, and timeouts for
+// connecting and the TLS handshake. A nil proxy uses the proxy environment
+// variables.
+func NewTransport(proxy *url.URL) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if proxy != nil {
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	return t
+}
+
+// NewHTTPClient returns an HTTP client for a provider configured with opts.
+// Clients without a proxy share a transport and its connection pool.
+//
+// Example:
+//
+//	client, err := provider.NewHTTPClient(provider.HTTPOptions{
+//	    Proxy:   "http://proxy.internal:3128",
+//	    Timeout: 5 * time.Minute,
+//	})
+func NewHTTPClient(opts HTTPOptions) (*http.Client, error) {
+	transport := sharedTransport
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy URL: %w", err)
+		}
+		if proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: scheme and host required", opts.Proxy)
+		}
+		transport = NewTransport(proxy)
+	}
+	return &http.Client{Transport: transport, Timeout: opts.Timeout}, nil
+}
+
+// ResponseTooLargeError is returned when a provider API response body exceeds
+// the size limit.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes", e.Limit)
+}
+
+// ReadBody reads r to the end, up to limit bytes. It returns a
+// *ResponseTooLargeError if r is longer. A limit <= 0 means no limit.
+func ReadBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, &ResponseTooLargeError{Limit: limit}
+	}
+	return data, nil
+}
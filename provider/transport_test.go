@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	a, err := NewHTTPClient(HTTPOptions{})
+	require.NoError(t, err)
+	b, err := NewHTTPClient(HTTPOptions{Timeout: time.Minute})
+	require.NoError(t, err)
+	assert.Same(t, a.Transport, b.Transport)
+	assert.Equal(t, time.Minute, b.Timeout)
+
+	proxied, err := NewHTTPClient(HTTPOptions{Proxy: "http://proxy.internal:3128"})
+	require.NoError(t, err)
+	assert.NotSame(t, a.Transport, proxied.Transport)
+	req, _ := http.NewRequest("GET", "https://api.openai.com/v1/models", nil)
+	proxy, err := proxied.Transport.(*http.Transport).Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "proxy.internal:3128", proxy.Host)
+
+	_, err = NewHTTPClient(HTTPOptions{Proxy: "proxy.internal"})
+	assert.Error(t, err)
+}
+
+func TestReadBody(t *testing.T) {
+	data, err := ReadBody(strings.NewReader("hello"), 5)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	_, err = ReadBody(strings.NewReader("hello!"), 5)
+	var tooLarge *ResponseTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	assert.Equal(t, int64(5), tooLarge.Limit)
+
+	data, err = ReadBody(strings.NewReader("hello!"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, "hello!", string(data))
+}
@@ -1,6 +1,10 @@
 package provider
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
 
 // Request represents a provider-agnostic LLM request.
 type Request struct {
@@ -14,6 +18,17 @@ type Request struct {
 	Seed          *int
 	StopSequences []string
 	JSONSchema    *JSONSchema // For structured output
+	ToolChoice    *ToolChoice // Whether and which tools to call; nil lets the model decide
+	Headers       http.Header // Additional HTTP headers, e.g. for gateways
+	User          string      // ID of the end user, for attribution and abuse monitoring
+
+	// LogitBias maps token IDs (decimal strings) to biases from -100 to 100.
+	LogitBias map[string]float64
+
+	// RawMessages sends the messages as they are. By default, providers
+	// normalize them to what their API accepts, e.g. by merging consecutive
+	// messages of the same role and dropping empty ones.
+	RawMessages bool
 }
 
 // Message represents a single message in the conversation.
@@ -36,10 +51,12 @@ const (
 
 // Response contains the LLM's response.
 type Response struct {
-	Content      string
-	ToolCalls    []ToolCall
-	FinishReason FinishReason
-	Usage        Usage
+	Content       string
+	ToolCalls     []ToolCall
+	FinishReason  FinishReason
+	Usage         Usage
+	HTTP          *HTTPResponse  // Raw HTTP response, if the provider uses HTTP
+	ContentFilter *ContentFilter // Why the response was blocked, if FinishReason is FinishReasonContentFiltered
 }
 
 // FinishReason indicates why the model stopped generating.
@@ -49,8 +66,21 @@ const (
 	FinishReasonStop      FinishReason = "stop"
 	FinishReasonToolCalls FinishReason = "tool_calls"
 	FinishReasonLength    FinishReason = "length"
+
+	// FinishReasonContentFiltered means that the provider's safety system
+	// blocked the prompt or the response, or that the model refused to
+	// answer. The content may be empty or cut short.
+	FinishReasonContentFiltered FinishReason = "content_filter"
 )
 
+// ContentFilter describes a response blocked by a provider's safety system
+// or refused by the model.
+type ContentFilter struct {
+	Reason     string   // The provider's reason, e.g. "SAFETY", "refusal", or "content_filter"
+	Categories []string // The flagged categories, if reported, e.g. "HARM_CATEGORY_HATE_SPEECH"
+	Message    string   // The provider's or the model's explanation, if any
+}
+
 // ToolCall represents a tool invocation requested by the model.
 type ToolCall struct {
 	ID        string
@@ -65,16 +95,67 @@ type ToolDef struct {
 	Parameters  json.RawMessage // JSON Schema
 }
 
+// ToolChoice controls whether and which tools the model calls.
+type ToolChoice struct {
+	Mode ToolChoiceMode
+
+	// Tools restricts ToolChoiceRequired to the named tools. Providers that
+	// can only force a single tool force it when Tools has one name, and
+	// allow any tool otherwise.
+	Tools []string
+}
+
+// ToolChoiceMode is the tool calling mode of a ToolChoice.
+type ToolChoiceMode string
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call tools.
+	ToolChoiceAuto ToolChoiceMode = "auto"
+	// ToolChoiceRequired makes the model call at least one tool.
+	ToolChoiceRequired ToolChoiceMode = "required"
+	// ToolChoiceNone prevents the model from calling tools.
+	ToolChoiceNone ToolChoiceMode = "none"
+)
+
 // JSONSchema represents a JSON Schema for structured output.
 type JSONSchema struct {
 	Name   string          `json:"name"`
 	Strict bool            `json:"strict"`
 	Schema json.RawMessage `json:"schema"`
+
+	// AdditionalProperties controls additionalProperties on the schema's objects.
+	AdditionalProperties AdditionalProperties `json:"-"`
 }
 
+// AdditionalProperties controls how additionalProperties is sent on the
+// objects of structured output schemas. Providers that reject the keyword
+// (Gemini) always omit it.
+type AdditionalProperties string
+
+const (
+	// AdditionalPropertiesAuto sends what the provider requires: false on
+	// every object in OpenAI strict mode, the schema as is otherwise.
+	AdditionalPropertiesAuto AdditionalProperties = ""
+	// AdditionalPropertiesFalse forbids additional properties on every object.
+	AdditionalPropertiesFalse AdditionalProperties = "false"
+	// AdditionalPropertiesOmit removes additionalProperties: false from every
+	// object, allowing additional properties.
+	AdditionalPropertiesOmit AdditionalProperties = "omit"
+)
+
 // Usage contains token usage statistics.
 type Usage struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
 }
+
+// ModelInfo describes a model available from a provider. Fields a provider
+// does not report are zero.
+type ModelInfo struct {
+	ID               string // Model name to use with WithModel
+	DisplayName      string
+	Created          time.Time
+	InputTokenLimit  int
+	OutputTokenLimit int
+}
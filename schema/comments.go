@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// LoadGoComments enables doc comments as schema descriptions for the types
+// declared in the Go source files under dir. Type and field doc comments
+// become the descriptions of their schemas, unless a description tag is
+// set. The import path of dir is derived from the enclosing go.mod, so the
+// source must be available at runtime, e.g. when running from the module
+// directory. Call it before generating schemas, e.g. at the start of main.
+//
+// Example:
+//
+//	// Book is a published book.
+//	type Book struct {
+//	    // Title is the title as printed on the cover.
+//	    Title string `json:"title"`
+//	}
+//
+//	if err := schema.LoadGoComments("./models"); err != nil {
+//	    return err
+//	}
+func LoadGoComments(dir string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("loading Go comments: %w", err)
+	}
+	importPath, err := dirImportPath(absDir)
+	if err != nil {
+		return fmt.Errorf("loading Go comments: %w", err)
+	}
+
+	// Comments are keyed by the directory they were found in; key them by
+	// import path instead
+	var extracted jsonschema.Reflector
+	if err := extracted.AddGoComments("", absDir); err != nil {
+		return fmt.Errorf("loading Go comments: %w", err)
+	}
+	prefix := path.Join("", absDir)
+	if Reflector.CommentMap == nil {
+		Reflector.CommentMap = make(map[string]string)
+	}
+	for key, comment := range extracted.CommentMap {
+		if rest, ok := strings.CutPrefix(key, prefix); ok {
+			Reflector.CommentMap[importPath+filepath.ToSlash(rest)] = comment
+		}
+	}
+	return nil
+}
+
+// dirImportPath returns the import path of dir, which must be absolute,
+// from the module declared in the nearest go.mod.
+func dirImportPath(dir string) (string, error) {
+	for root := dir; ; {
+		module, err := readModulePath(filepath.Join(root, "go.mod"))
+		if err == nil {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(module, filepath.ToSlash(rel)), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", fmt.Errorf("no go.mod found for %s", dir)
+		}
+		root = parent
+	}
+}
+
+// readModulePath returns the module path declared in the go.mod file at name.
+func readModulePath(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module declared in %s", name)
+}
@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Article is a published article.
+type Article struct {
+	// Headline is the title shown above the article.
+	Headline string `json:"headline"`
+	Body     string `json:"body"` // Body is the full text.
+	Byline   string `json:"byline" jsonschema:"description=The author's name"`
+	// Section is the newspaper section.
+	Section struct {
+		Name string `json:"name"`
+	} `json:"section"`
+}
+
+func TestLoadGoComments(t *testing.T) {
+	before := MustGenerate[Article]()
+	assert.NotContains(t, string(before), "Headline is the title")
+
+	require.NoError(t, LoadGoComments("."))
+	t.Cleanup(func() { Reflector.CommentMap = nil })
+
+	var s struct {
+		Description string                    `json:"description"`
+		Properties  map[string]map[string]any `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(MustGenerate[Article](), &s))
+
+	assert.Equal(t, "Article is a published article.", s.Description)
+	assert.Equal(t, "Headline is the title shown above the article.", s.Properties["headline"]["description"])
+	assert.Equal(t, "Body is the full text.", s.Properties["body"]["description"])
+	assert.Equal(t, "The author's name", s.Properties["byline"]["description"])
+	assert.Equal(t, "Section is the newspaper section.", s.Properties["section"]["description"])
+}
+
+func TestLoadGoComments_NoModule(t *testing.T) {
+	err := LoadGoComments(t.TempDir())
+	assert.ErrorContains(t, err, "no go.mod found")
+}
@@ -0,0 +1,59 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/invopop/jsonschema"
+)
+
+// enumSchema returns an enum schema for types with a Values method that
+// returns the allowed values as a slice, or nil for other types.
+//
+// Example:
+//
+//	type Sentiment string
+//
+//	const (
+//	    Positive Sentiment = "positive"
+//	    Negative Sentiment = "negative"
+//	)
+//
+//	func (Sentiment) Values() []Sentiment {
+//	    return []Sentiment{Positive, Negative}
+//	}
+func enumSchema(t reflect.Type) *jsonschema.Schema {
+	m, ok := t.MethodByName("Values")
+	if !ok || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 || m.Type.Out(0).Kind() != reflect.Slice {
+		return nil
+	}
+
+	var typ string
+	switch t.Kind() {
+	case reflect.String:
+		typ = "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		typ = "integer"
+	case reflect.Float32, reflect.Float64:
+		typ = "number"
+	default:
+		return nil
+	}
+
+	values := m.Func.Call([]reflect.Value{reflect.Zero(t)})[0]
+	enum := make([]any, 0, values.Len())
+	for i := range values.Len() {
+		// Encode values as they are sent, honoring custom marshalers
+		data, err := json.Marshal(values.Index(i).Interface())
+		if err != nil {
+			return nil
+		}
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil
+		}
+		enum = append(enum, v)
+	}
+	return &jsonschema.Schema{Type: typ, Enum: enum}
+}
@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Sentiment string
+
+const (
+	Positive Sentiment = "positive"
+	Negative Sentiment = "negative"
+	Neutral  Sentiment = "neutral"
+)
+
+func (Sentiment) Values() []Sentiment {
+	return []Sentiment{Positive, Negative, Neutral}
+}
+
+type Priority int
+
+func (Priority) Values() []Priority {
+	return []Priority{1, 2, 3}
+}
+
+type Review struct {
+	Sentiment Sentiment   `json:"sentiment" jsonschema:"description=Overall sentiment"`
+	Priority  Priority    `json:"priority"`
+	Language  string      `json:"language" jsonschema:"enum=en,enum=ja"`
+	Tags      []Sentiment `json:"tags"`
+}
+
+func TestGenerate_Enums(t *testing.T) {
+	data, err := Generate[Review]()
+	require.NoError(t, err)
+
+	var parsed struct {
+		Properties map[string]struct {
+			Type        string `json:"type"`
+			Enum        []any  `json:"enum"`
+			Description string `json:"description"`
+			Items       struct {
+				Enum []any `json:"enum"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(data, &parsed))
+
+	sentiment := parsed.Properties["sentiment"]
+	assert.Equal(t, "string", sentiment.Type)
+	assert.Equal(t, []any{"positive", "negative", "neutral"}, sentiment.Enum)
+	assert.Equal(t, "Overall sentiment", sentiment.Description)
+
+	priority := parsed.Properties["priority"]
+	assert.Equal(t, "integer", priority.Type)
+	assert.Equal(t, []any{1.0, 2.0, 3.0}, priority.Enum)
+
+	assert.Equal(t, []any{"en", "ja"}, parsed.Properties["language"].Enum)
+	assert.Equal(t, []any{"positive", "negative", "neutral"}, parsed.Properties["tags"].Items.Enum)
+}
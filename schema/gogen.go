@@ -0,0 +1,403 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateGo generates Go type definitions for a JSON schema, with json and
+// jsonschema tags such that Generate reproduces the schema closely. It lets
+// schemas defined elsewhere be used with CallParse without porting them by
+// hand. The root schema becomes the type typeName; nested objects and
+// $defs become types named after their title, definition, or property.
+// Required properties become plain fields, optional ones omitempty fields,
+// and alternatives (anyOf, oneOf) fields of type any.
+//
+// Example:
+//
+//	src, err := schema.GenerateGo(invoiceSchema, "invoices", "Invoice")
+//	if err != nil {
+//	    return err
+//	}
+//	os.WriteFile("invoice_gen.go", src, 0o644)
+func GenerateGo(schema json.RawMessage, packageName, typeName string) ([]byte, error) {
+	var root goSchema
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+
+	g := &goGenerator{
+		defs:  make(map[string]*goSchema),
+		names: make(map[string]bool),
+		refs:  make(map[string]string),
+	}
+	for _, defs := range []orderedSchemas{root.Defs, root.Definitions} {
+		for _, def := range defs {
+			g.defs[def.name] = def.schema
+		}
+	}
+
+	// Name definitions first, so references resolve regardless of order
+	g.names[typeName] = true
+	for _, defs := range []struct {
+		prefix  string
+		schemas orderedSchemas
+	}{{"#/$defs/", root.Defs}, {"#/definitions/", root.Definitions}} {
+		for _, def := range defs.schemas {
+			g.refs[defs.prefix+def.name] = g.uniqueName(goName(def.name))
+		}
+	}
+	g.refs["#"] = typeName
+
+	g.declare(typeName, &root)
+	for _, defs := range []struct {
+		prefix  string
+		schemas orderedSchemas
+	}{{"#/$defs/", root.Defs}, {"#/definitions/", root.Definitions}} {
+		for _, def := range defs.schemas {
+			g.declare(g.refs[defs.prefix+def.name], def.schema)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by schema.GenerateGo. DO NOT EDIT.\n\npackage %s\n\n", packageName)
+	if g.usesTime {
+		buf.WriteString("import \"time\"\n\n")
+	}
+	for _, decl := range g.decls {
+		buf.WriteString(decl)
+		buf.WriteString("\n")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// goSchema is the part of a JSON schema used to generate Go types.
+type goSchema struct {
+	Type                 any             `json:"type"`
+	Title                string          `json:"title"`
+	Description          string          `json:"description"`
+	Format               string          `json:"format"`
+	Pattern              string          `json:"pattern"`
+	Enum                 []any           `json:"enum"`
+	Properties           orderedSchemas  `json:"properties"`
+	Required             []string        `json:"required"`
+	Items                *goSchema       `json:"items"`
+	AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	AnyOf                []*goSchema     `json:"anyOf"`
+	OneOf                []*goSchema     `json:"oneOf"`
+	Ref                  string          `json:"$ref"`
+	Defs                 orderedSchemas  `json:"$defs"`
+	Definitions          orderedSchemas  `json:"definitions"`
+	Minimum              *json.Number    `json:"minimum"`
+	Maximum              *json.Number    `json:"maximum"`
+	ExclusiveMinimum     *json.Number    `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *json.Number    `json:"exclusiveMaximum"`
+	MinLength            *int            `json:"minLength"`
+	MaxLength            *int            `json:"maxLength"`
+	MinItems             *int            `json:"minItems"`
+	MaxItems             *int            `json:"maxItems"`
+}
+
+// namedSchema is a property or definition.
+type namedSchema struct {
+	name   string
+	schema *goSchema
+}
+
+// orderedSchemas is a JSON object of schemas that keeps their order.
+type orderedSchemas []namedSchema
+
+func (o *orderedSchemas) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil { // {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var s goSchema
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		*o = append(*o, namedSchema{name: key.(string), schema: &s})
+	}
+	return nil
+}
+
+// types returns the JSON types of s and whether it may be null.
+func (s *goSchema) types() (types []string, nullable bool) {
+	switch t := s.Type.(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				types = append(types, name)
+			}
+		}
+	}
+	if i := slices.Index(types, "null"); i >= 0 {
+		types = slices.Delete(types, i, i+1)
+		nullable = true
+	}
+	return types, nullable
+}
+
+type goGenerator struct {
+	defs     map[string]*goSchema
+	refs     map[string]string // Type names by reference
+	names    map[string]bool   // Declared type names
+	decls    []string
+	usesTime bool
+}
+
+// uniqueName returns name, or name with a number if it is taken, and reserves it.
+func (g *goGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+// declare adds the declaration of the type name for s.
+func (g *goGenerator) declare(name string, s *goSchema) {
+	var buf strings.Builder
+	writeComment(&buf, name, s.Description, "")
+
+	if target, ok := g.refs[s.Ref]; ok && len(s.Properties) == 0 {
+		fmt.Fprintf(&buf, "type %s = %s\n", name, target)
+		g.decls = append(g.decls, buf.String())
+		return
+	}
+	if len(s.Properties) == 0 {
+		fmt.Fprintf(&buf, "type %s %s\n", name, g.goType(name, s))
+		g.decls = append(g.decls, buf.String())
+		return
+	}
+
+	// Reserve the slot, so the type is declared before its nested types
+	i := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	fmt.Fprintf(&buf, "type %s struct {\n", name)
+	fields := make(map[string]bool)
+	for _, prop := range s.Properties {
+		field := goName(prop.name)
+		for j := 2; fields[field]; j++ {
+			field = goName(prop.name) + strconv.Itoa(j)
+		}
+		fields[field] = true
+
+		typ := g.goType(name+field, prop.schema)
+		if prop.schema.Description != "" && strings.Contains(prop.schema.Description, "\n") {
+			writeComment(&buf, field, prop.schema.Description, "\t")
+		}
+
+		jsonTag := prop.name
+		if !slices.Contains(s.Required, prop.name) {
+			jsonTag += ",omitempty"
+		}
+		tag := `json:` + strconv.Quote(jsonTag)
+		if keywords := schemaTag(prop.schema); keywords != "" {
+			tag += ` jsonschema:` + strconv.Quote(keywords)
+		}
+		if strings.Contains(tag, "`") {
+			tag = strconv.Quote(tag)
+		} else {
+			tag = "`" + tag + "`"
+		}
+		fmt.Fprintf(&buf, "\t%s %s %s\n", field, typ, tag)
+	}
+	buf.WriteString("}\n")
+	g.decls[i] = buf.String()
+}
+
+// goType returns the Go type for s, declaring the types it needs, named
+// after name.
+func (g *goGenerator) goType(name string, s *goSchema) string {
+	if s.Ref != "" {
+		if typ, ok := g.refs[s.Ref]; ok {
+			return "*" + typ
+		}
+		return "any"
+	}
+	if len(s.AnyOf) > 0 || len(s.OneOf) > 0 {
+		return "any"
+	}
+
+	types, nullable := s.types()
+	if len(types) != 1 {
+		return "any"
+	}
+	ptr := ""
+	if nullable {
+		ptr = "*"
+	}
+
+	switch types[0] {
+	case "string":
+		if s.Format == "date-time" {
+			g.usesTime = true
+			return ptr + "time.Time"
+		}
+		return ptr + "string"
+	case "integer":
+		return ptr + "int"
+	case "number":
+		return ptr + "float64"
+	case "boolean":
+		return ptr + "bool"
+	case "array":
+		if s.Items == nil {
+			return "[]any"
+		}
+		return "[]" + g.goType(strings.TrimSuffix(name, "s")+"Item", s.Items)
+	case "object":
+		if len(s.Properties) > 0 {
+			typeName := name
+			if s.Title != "" {
+				typeName = goName(s.Title)
+			}
+			typeName = g.uniqueName(typeName)
+			g.declare(typeName, s)
+			return ptr + typeName
+		}
+		var additional goSchema
+		if json.Unmarshal(s.AdditionalProperties, &additional) == nil && additional.Type != nil {
+			return "map[string]" + g.goType(name+"Value", &additional)
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+// schemaTag returns the jsonschema tag reproducing the keywords of s.
+func schemaTag(s *goSchema) string {
+	var parts []string
+	add := func(key string, value any) {
+		v := fmt.Sprint(value)
+		// Commas separate keywords in the tag
+		parts = append(parts, key+"="+strings.ReplaceAll(v, ",", `\,`))
+	}
+
+	if s.Description != "" && !strings.Contains(s.Description, "\n") {
+		add("description", s.Description)
+	}
+	if s.Title != "" {
+		add("title", s.Title)
+	}
+	if types, _ := s.types(); len(types) == 1 && types[0] == "string" && s.Format != "" && s.Format != "date-time" {
+		add("format", s.Format)
+	}
+	if s.Pattern != "" {
+		add("pattern", s.Pattern)
+	}
+	for _, v := range s.Enum {
+		add("enum", v)
+	}
+	for _, bound := range []struct {
+		key   string
+		value *json.Number
+	}{
+		{"minimum", s.Minimum},
+		{"maximum", s.Maximum},
+		{"exclusiveMinimum", s.ExclusiveMinimum},
+		{"exclusiveMaximum", s.ExclusiveMaximum},
+	} {
+		if bound.value != nil {
+			add(bound.key, *bound.value)
+		}
+	}
+	for _, bound := range []struct {
+		key   string
+		value *int
+	}{
+		{"minLength", s.MinLength},
+		{"maxLength", s.MaxLength},
+		{"minItems", s.MinItems},
+		{"maxItems", s.MaxItems},
+	} {
+		if bound.value != nil {
+			add(bound.key, *bound.value)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// writeComment writes a doc comment for name from a description.
+func writeComment(buf *strings.Builder, name, description, indent string) {
+	if description == "" {
+		return
+	}
+	for i, line := range strings.Split(strings.TrimSpace(description), "\n") {
+		if i == 0 && !strings.HasPrefix(line, name+" ") {
+			line = name + ": " + line
+		}
+		fmt.Fprintf(buf, "%s// %s\n", indent, strings.TrimRight(line, " "))
+	}
+}
+
+// commonInitialisms are written in upper case in Go names.
+var commonInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "TCP": true, "TLS": true, "TTL": true, "UI": true, "UID": true,
+	"URI": true, "URL": true, "UTF8": true, "UUID": true, "XML": true,
+}
+
+// goName converts a JSON name such as "line_items" or "userId" to an
+// exported Go identifier such as "LineItems" or "UserID".
+func goName(name string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	ident := b.String()
+	if ident == "" || unicode.IsDigit([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateGo(t *testing.T) {
+	invoice := json.RawMessage(`{
+		"type": "object",
+		"description": "An invoice sent to a customer.",
+		"properties": {
+			"invoice_id": {"type": "string", "pattern": "^INV-[0-9]+$"},
+			"issued_at": {"type": "string", "format": "date-time"},
+			"status": {"type": "string", "enum": ["draft", "sent", "paid"], "description": "Payment status, as of today"},
+			"customer": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"email": {"type": "string", "format": "email"}
+				},
+				"required": ["name"]
+			},
+			"line_items": {
+				"type": "array",
+				"minItems": 1,
+				"items": {"$ref": "#/$defs/LineItem"}
+			},
+			"discount": {"type": ["number", "null"], "minimum": 0, "maximum": 1},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"extra": {}
+		},
+		"required": ["invoice_id", "issued_at", "status", "customer", "line_items"],
+		"$defs": {
+			"LineItem": {
+				"type": "object",
+				"properties": {
+					"sku": {"type": "string"},
+					"quantity": {"type": "integer", "exclusiveMinimum": 0}
+				},
+				"required": ["sku", "quantity"]
+			}
+		}
+	}`)
+
+	src, err := GenerateGo(invoice, "billing", "Invoice")
+	require.NoError(t, err)
+
+	assert.Equal(t, "// Code generated by schema.GenerateGo. DO NOT EDIT.\n\n"+
+		"package billing\n\n"+
+		"import \"time\"\n\n"+
+		"// Invoice: An invoice sent to a customer.\n"+
+		"type Invoice struct {\n"+
+		"\tInvoiceID string            `json:\"invoice_id\" jsonschema:\"pattern=^INV-[0-9]+$\"`\n"+
+		"\tIssuedAt  time.Time         `json:\"issued_at\"`\n"+
+		"\tStatus    string            `json:\"status\" jsonschema:\"description=Payment status\\\\, as of today,enum=draft,enum=sent,enum=paid\"`\n"+
+		"\tCustomer  InvoiceCustomer   `json:\"customer\"`\n"+
+		"\tLineItems []*LineItem       `json:\"line_items\" jsonschema:\"minItems=1\"`\n"+
+		"\tDiscount  *float64          `json:\"discount,omitempty\" jsonschema:\"minimum=0,maximum=1\"`\n"+
+		"\tLabels    map[string]string `json:\"labels,omitempty\"`\n"+
+		"\tExtra     any               `json:\"extra,omitempty\"`\n"+
+		"}\n\n"+
+		"type InvoiceCustomer struct {\n"+
+		"\tName  string `json:\"name\"`\n"+
+		"\tEmail string `json:\"email,omitempty\" jsonschema:\"format=email\"`\n"+
+		"}\n\n"+
+		"type LineItem struct {\n"+
+		"\tSku      string `json:\"sku\"`\n"+
+		"\tQuantity int    `json:\"quantity\" jsonschema:\"exclusiveMinimum=0\"`\n"+
+		"}\n", string(src))
+}
+
+func TestGenerateGo_RoundTrip(t *testing.T) {
+	// Types generated from a schema generated from Go types match the originals
+	src, err := GenerateGo(MustGenerate[Review](), "reviews", "Review")
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "Sentiment string   `json:\"sentiment\" jsonschema:\"description=Overall sentiment,enum=positive,enum=negative,enum=neutral\"`")
+	assert.Contains(t, string(src), "Tags      []string `json:\"tags\"`")
+}
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"name":        "Name",
+		"line_items":  "LineItems",
+		"userId":      "UserID",
+		"HTTPServer":  "HTTPServer",
+		"api-url":     "APIURL",
+		"2fa_enabled": "X2faEnabled",
+	} {
+		assert.Equal(t, want, goName(in), in)
+	}
+}
+
+func TestGenerateGo_RootReference(t *testing.T) {
+	src, err := GenerateGo(json.RawMessage(`{
+		"$ref": "#/$defs/Node",
+		"$defs": {"Node": {"type": "object", "properties": {"next": {"$ref": "#/$defs/Node"}}}}
+	}`), "graph", "Graph")
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "type Graph = Node\n")
+	assert.Contains(t, string(src), "Next *Node `json:\"next,omitempty\"`")
+}
@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+)
+
+// SchemaProvider is implemented by types that provide their own schema
+// instead of the one reflected from the type. To refine the reflected schema
+// instead, implement JSONSchemaExtend(*jsonschema.Schema) or use
+// RegisterOverride.
+//
+// Example:
+//
+//	type Color string
+//
+//	func (Color) JSONSchema() *jsonschema.Schema {
+//	    return &jsonschema.Schema{Type: "string", Pattern: "^#[0-9a-f]{6}$"}
+//	}
+type SchemaProvider interface {
+	JSONSchema() *jsonschema.Schema
+}
+
+var overrides sync.Map // reflect.Type -> func(*jsonschema.Schema)
+
+// RegisterOverride registers fn to refine the schema generated for T,
+// wherever T appears. fn receives a fresh copy of the schema on each
+// generation and may change it in place, including the schemas of T's
+// properties. A later registration for the same type replaces the earlier
+// one. Use it for types you do not own or cannot tag.
+//
+// Example:
+//
+//	schema.RegisterOverride[User](func(s *jsonschema.Schema) {
+//	    s.Description = "A registered user"
+//	    email := s.Properties.Value("email")
+//	    email.Format = "email"
+//	    email.MaxLength = ptr(uint64(254))
+//	})
+func RegisterOverride[T any](fn func(s *jsonschema.Schema)) {
+	overrides.Store(reflect.TypeFor[T](), fn)
+}
+
+// overrideSchema returns the schema of a type with a registered override,
+// or nil for other types.
+func overrideSchema(t reflect.Type) *jsonschema.Schema {
+	v, ok := overrides.Load(t)
+	if !ok {
+		return nil
+	}
+
+	// Reflect t as usual but without its override, which would recurse
+	r := *Reflector
+	r.Mapper = func(mapped reflect.Type) *jsonschema.Schema {
+		if mapped == t {
+			return baseSchema(mapped)
+		}
+		return mapType(mapped)
+	}
+	s := r.ReflectFromType(t)
+	s.Version = ""
+	s.ID = ""
+	s.Definitions = nil
+
+	v.(func(*jsonschema.Schema))(s)
+	return s
+}
+
+// providedSchema returns the schema of a SchemaProvider, or nil for other types.
+func providedSchema(t reflect.Type) *jsonschema.Schema {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	if t.Implements(schemaProviderType) {
+		return reflect.Zero(t).Interface().(SchemaProvider).JSONSchema()
+	}
+	if reflect.PointerTo(t).Implements(schemaProviderType) {
+		return reflect.New(t).Interface().(SchemaProvider).JSONSchema()
+	}
+	return nil
+}
+
+var schemaProviderType = reflect.TypeFor[SchemaProvider]()
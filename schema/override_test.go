@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Color string
+
+func (Color) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "string", Pattern: "^#[0-9a-f]{6}$"}
+}
+
+type Account struct {
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+type Profile struct {
+	Owner    Account   `json:"owner"`
+	Friends  []Account `json:"friends"`
+	Favorite Color     `json:"favorite"`
+}
+
+func TestSchemaProvider(t *testing.T) {
+	var s map[string]any
+	require.NoError(t, json.Unmarshal(MustGenerate[Profile](), &s))
+
+	favorite := s["properties"].(map[string]any)["favorite"].(map[string]any)
+	assert.Equal(t, "string", favorite["type"])
+	assert.Equal(t, "^#[0-9a-f]{6}$", favorite["pattern"])
+}
+
+func TestRegisterOverride(t *testing.T) {
+	RegisterOverride[Account](func(s *jsonschema.Schema) {
+		s.Description = "A user account"
+		s.Properties.Value("email").Format = "email"
+		s.Properties.Value("age").Minimum = json.Number("0")
+	})
+	t.Cleanup(func() { overrides.Delete(reflect.TypeFor[Account]()) })
+
+	var s struct {
+		Properties map[string]struct {
+			Description string `json:"description"`
+			Properties  map[string]map[string]any
+			Items       struct {
+				Description string `json:"description"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(MustGenerate[Profile](), &s))
+
+	owner := s.Properties["owner"]
+	assert.Equal(t, "A user account", owner.Description)
+	assert.Equal(t, "email", owner.Properties["email"]["format"])
+	assert.Equal(t, float64(0), owner.Properties["age"]["minimum"])
+	assert.Equal(t, "A user account", s.Properties["friends"].Items.Description)
+
+	// The override applies to the root type too, without $schema duplicated
+	root := string(MustGenerate[Account]())
+	assert.Contains(t, root, `"description":"A user account"`)
+	assert.Contains(t, root, `"format":"email"`)
+}
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// typeWalker finds the recursive types reachable from a type.
+type typeWalker struct {
+	stack     []reflect.Type
+	done      map[reflect.Type]bool
+	recursive map[reflect.Type]bool
+}
+
+// recursiveTypes returns the types reachable from t that contain
+// themselves, directly or through other types. It returns an error for
+// recursion the schema cannot express: through unions or within types with
+// overrides, which are reflected separately.
+func recursiveTypes(t reflect.Type) (map[reflect.Type]bool, error) {
+	w := &typeWalker{
+		done:      make(map[reflect.Type]bool),
+		recursive: make(map[reflect.Type]bool),
+	}
+	if err := w.walk(t); err != nil {
+		return nil, err
+	}
+	return w.recursive, nil
+}
+
+func (w *typeWalker) walk(t reflect.Type) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if i := slices.Index(w.stack, t); i >= 0 {
+		for _, outer := range w.stack {
+			if err := w.checkNestable(outer, t); err != nil {
+				return err
+			}
+		}
+		w.recursive[t] = true
+		return nil
+	}
+	if w.done[t] || providedSchema(t) != nil || enumSchema(t) != nil || marshalerSchema(t) != nil {
+		return nil
+	}
+
+	w.stack = append(w.stack, t)
+	defer func() {
+		w.stack = w.stack[:len(w.stack)-1]
+		w.done[t] = true
+	}()
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if (!f.IsExported() && !f.Anonymous) || f.Tag.Get("json") == "-" {
+				continue
+			}
+			if err := w.walk(f.Type); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		if v, ok := unions.Load(t); ok {
+			for _, variant := range v.(*union).variants {
+				if err := w.walk(variant); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkNestable returns an error if recursive types cannot be nested in outer.
+func (w *typeWalker) checkNestable(outer, recursive reflect.Type) error {
+	if outer.Kind() == reflect.Interface {
+		if outer == recursive {
+			return fmt.Errorf("recursive union %s is not supported", outer)
+		}
+		return fmt.Errorf("recursive type %s in union %s is not supported", recursive, outer)
+	}
+	if _, ok := overrides.Load(outer); ok {
+		return fmt.Errorf("recursive type %s in type %s with an override is not supported", recursive, outer)
+	}
+	return nil
+}
+
+// reflectRecursive creates the schema of t, which contains the given
+// recursive types. Recursive types are defined in $defs and referenced with
+// $ref; all other types are inlined as usual.
+func reflectRecursive(t reflect.Type, recursive map[reflect.Type]bool) *jsonschema.Schema {
+	r := *Reflector
+	r.DoNotReference = false
+	s := r.ReflectFromType(t)
+
+	keep := make(map[string]bool)
+	for rt := range recursive {
+		if r.Namer != nil {
+			keep[r.Namer(rt)] = true
+		} else {
+			keep[rt.Name()] = true
+		}
+	}
+
+	// The root must be an object, so it is inlined even if it is recursive
+	defs := s.Definitions
+	version, id := s.Version, s.ID
+	if name, ok := strings.CutPrefix(s.Ref, "#/$defs/"); ok && defs[name] != nil {
+		*s = *defs[name]
+	}
+	inlineDefinitions(s, defs, keep, make(map[*jsonschema.Schema]bool))
+	s.Version, s.ID = version, id
+
+	s.Definitions = jsonschema.Definitions{}
+	for name, def := range defs {
+		if keep[name] {
+			inlineDefinitions(def, defs, keep, make(map[*jsonschema.Schema]bool))
+			s.Definitions[name] = def
+		}
+	}
+	return s
+}
+
+// inlineDefinitions replaces the references in s to the definitions not in
+// keep with the definitions themselves.
+func inlineDefinitions(s *jsonschema.Schema, defs jsonschema.Definitions, keep map[string]bool, seen map[*jsonschema.Schema]bool) {
+	if s == nil || seen[s] {
+		return
+	}
+	seen[s] = true
+
+	for {
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if !ok || keep[name] || defs[name] == nil {
+			break
+		}
+		desc := s.Description
+		*s = *defs[name]
+		if desc != "" {
+			s.Description = desc
+		}
+	}
+
+	if s.Properties != nil {
+		for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			inlineDefinitions(pair.Value, defs, keep, seen)
+		}
+	}
+	for _, subs := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf, s.PrefixItems} {
+		for _, sub := range subs {
+			inlineDefinitions(sub, defs, keep, seen)
+		}
+	}
+	inlineDefinitions(s.Items, defs, keep, seen)
+	inlineDefinitions(s.AdditionalProperties, defs, keep, seen)
+	inlineDefinitions(s.Not, defs, keep, seen)
+}
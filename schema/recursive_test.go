@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TreeNode struct {
+	Label    string     `json:"label"`
+	Children []TreeNode `json:"children"`
+	Meta     NodeMeta   `json:"meta"`
+}
+
+type NodeMeta struct {
+	Weight int `json:"weight"`
+}
+
+type Forest struct {
+	Trees []TreeNode `json:"trees"`
+	Owner *Person    `json:"owner"`
+}
+
+type Person struct {
+	Name   string  `json:"name"`
+	Parent *Person `json:"parent,omitempty"`
+}
+
+type Expr interface{ isExpr() }
+
+type Literal struct {
+	Value float64 `json:"value"`
+}
+
+type Binary struct {
+	Op    string `json:"op"`
+	Left  Expr   `json:"left"`
+	Right Expr   `json:"right"`
+}
+
+func (Literal) isExpr() {}
+func (Binary) isExpr()  {}
+
+type Formula struct {
+	Root Expr `json:"root"`
+}
+
+func TestGenerate_Recursive(t *testing.T) {
+	data, err := Generate[Forest]()
+	require.NoError(t, err)
+
+	var s struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]map[string]any  `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(data, &s))
+
+	// Only recursive types are referenced; the rest is inlined
+	assert.ElementsMatch(t, []string{"TreeNode", "Person"}, keys(s.Defs))
+	assert.JSONEq(t, `{"type": "array", "items": {"$ref": "#/$defs/TreeNode"}}`, string(s.Properties["trees"]))
+	tree := s.Defs["TreeNode"]["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/TreeNode"}, tree["children"].(map[string]any)["items"])
+	assert.Equal(t, "object", tree["meta"].(map[string]any)["type"], "non-recursive types are inlined")
+
+	// Values validate against the references
+	require.NoError(t, Validate(data, []byte(`{
+		"trees": [{"label": "a", "meta": {"weight": 1}, "children": [{"label": "b", "meta": {"weight": 2}, "children": []}]}],
+		"owner": {"name": "x", "parent": {"name": "y"}}
+	}`)))
+	err = Validate(data, []byte(`{
+		"trees": [{"label": "a", "meta": {"weight": 1}, "children": [{"label": 2, "meta": {"weight": 2}, "children": []}]}],
+		"owner": {"name": "x"}
+	}`))
+	assert.ErrorContains(t, err, "$.trees[0].children[0].label: expected string, got integer")
+}
+
+func TestGenerate_RecursiveRoot(t *testing.T) {
+	data, err := Generate[TreeNode]()
+	require.NoError(t, err)
+
+	var s map[string]any
+	require.NoError(t, json.Unmarshal(data, &s))
+	assert.Equal(t, "object", s["type"], "the root is inlined")
+	assert.Contains(t, s["$defs"], "TreeNode")
+	assert.NotContains(t, s, "$ref")
+}
+
+func TestGenerate_RecursiveUnion(t *testing.T) {
+	RegisterUnion[Expr]("kind", map[string]Expr{"literal": Literal{}, "binary": Binary{}})
+	t.Cleanup(func() { unions.Delete(reflect.TypeFor[Expr]()) })
+
+	_, err := Generate[Formula]()
+	assert.ErrorContains(t, err, "recursive union schema.Expr is not supported")
+}
+
+func keys[V any](m map[string]V) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
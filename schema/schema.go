@@ -3,16 +3,114 @@ package schema
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/invopop/jsonschema"
 )
 
 // Reflector is configured for LLM tool/response schemas.
 // DoNotReference inlines all definitions to avoid $ref.
+// Its Mapper adds enums for types with a Values method, oneOf schemas for
+// unions registered with RegisterUnion, the schemas of SchemaProvider types,
+// and the overrides registered with RegisterOverride, and it maps time.Time,
+// json.Number, and types with custom JSON or text marshalers to the JSON
+// they encode to. Prefer Reflect over calling it directly.
 var Reflector = &jsonschema.Reflector{
 	DoNotReference: true,
 }
 
+func init() {
+	Reflector.Mapper = mapType
+}
+
+// mapType returns the schema of types with special handling, or nil to
+// reflect the type as usual.
+func mapType(t reflect.Type) *jsonschema.Schema {
+	s := overrideSchema(t)
+	if s == nil {
+		s = baseSchema(t)
+	}
+	if s != nil && s.Description != "" {
+		typeDescriptions.Store(s, s.Description)
+	}
+	return s
+}
+
+// baseSchema is like mapType but ignores overrides.
+func baseSchema(t reflect.Type) *jsonschema.Schema {
+	if s := providedSchema(t); s != nil {
+		return s
+	}
+	if s := unionSchema(t); s != nil {
+		return s
+	}
+	if s := enumSchema(t); s != nil {
+		return s
+	}
+	return marshalerSchema(t)
+}
+
+// typeDescriptions holds the descriptions of mapped schemas. The reflector
+// replaces the description of a struct field's schema with the field's
+// description tag, even when it is empty; Reflect restores them.
+var typeDescriptions sync.Map // *jsonschema.Schema -> string
+
+// Reflect creates the schema of v's type with Reflector. Struct fields
+// without a description tag keep the description of their type's schema.
+//
+// Recursive types, such as tree nodes, are defined in $defs and referenced
+// with $ref, since they cannot be inlined. Providers that do not support
+// references expand them to a limited depth. Recursion through unions is not
+// supported and returns an error.
+func Reflect(v any) (*jsonschema.Schema, error) {
+	t := reflect.TypeOf(v)
+	recursive, err := recursiveTypes(t)
+	if err != nil {
+		return nil, fmt.Errorf("generating schema for %s: %w", t, err)
+	}
+
+	var s *jsonschema.Schema
+	if len(recursive) > 0 && Reflector.DoNotReference {
+		s = reflectRecursive(t, recursive)
+	} else {
+		s = Reflector.Reflect(v)
+	}
+	restoreDescriptions(s, make(map[*jsonschema.Schema]bool))
+	return s, nil
+}
+
+// restoreDescriptions restores the descriptions recorded in typeDescriptions
+// that were cleared in s and its subschemas.
+func restoreDescriptions(s *jsonschema.Schema, seen map[*jsonschema.Schema]bool) {
+	if s == nil || seen[s] {
+		return
+	}
+	seen[s] = true
+
+	if desc, ok := typeDescriptions.LoadAndDelete(s); ok && s.Description == "" {
+		s.Description = desc.(string)
+	}
+
+	if s.Properties != nil {
+		for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			restoreDescriptions(pair.Value, seen)
+		}
+	}
+	for _, sub := range s.Definitions {
+		restoreDescriptions(sub, seen)
+	}
+	for _, subs := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf, s.PrefixItems} {
+		for _, sub := range subs {
+			restoreDescriptions(sub, seen)
+		}
+	}
+	restoreDescriptions(s.Items, seen)
+	restoreDescriptions(s.AdditionalProperties, seen)
+	restoreDescriptions(s.Not, seen)
+}
+
 // Generate creates a JSON Schema from a Go type.
 // The type should be a struct with json and jsonschema tags.
 //
@@ -27,15 +125,17 @@ var Reflector = &jsonschema.Reflector{
 //	schema, err := schema.Generate[Book]()
 func Generate[T any]() (json.RawMessage, error) {
 	var zero T
-	schema := Reflector.Reflect(&zero)
-	return json.Marshal(schema)
+	return GenerateFromValue(&zero)
 }
 
 // GenerateFromValue creates a JSON Schema from a value.
 // This is useful when you have a value instead of a type.
 func GenerateFromValue(v any) (json.RawMessage, error) {
-	schema := Reflector.Reflect(v)
-	return json.Marshal(schema)
+	s, err := Reflect(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
 }
 
 // MustGenerate is like Generate but panics on error.
@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Limits are the limits providers place on structured output schemas.
+// Zero fields are unlimited.
+type Limits struct {
+	MaxProperties int // Object properties in total
+	MaxDepth      int // Levels of object nesting
+	MaxEnumValues int // Enum values in total
+	MaxChars      int // Total length of property names and enum and const values
+}
+
+// DefaultLimits are the limits of OpenAI strict structured output, the
+// strictest of the supported providers.
+var DefaultLimits = Limits{
+	MaxProperties: 5000,
+	MaxDepth:      10,
+	MaxEnumValues: 1000,
+	MaxChars:      120000,
+}
+
+// SizeReport describes the size of a schema.
+type SizeReport struct {
+	Bytes      int    // Serialized size
+	Tokens     int    // Rough estimate of the prompt tokens used by the schema
+	Properties int    // Object properties in total
+	Depth      int    // Levels of object nesting
+	DeepestAt  string // Path of the most deeply nested object, e.g. "$.a.b[]"
+	EnumValues int    // Enum values in total
+	Chars      int    // Total length of property names and enum and const values
+
+	// Warnings lists the limits the schema exceeds.
+	Warnings []string
+}
+
+// OK reports whether the schema is within the limits it was measured against.
+func (r *SizeReport) OK() bool {
+	return len(r.Warnings) == 0
+}
+
+func (r *SizeReport) String() string {
+	s := fmt.Sprintf("%d bytes (~%d tokens), %d properties, depth %d, %d enum values",
+		r.Bytes, r.Tokens, r.Properties, r.Depth, r.EnumValues)
+	if len(r.Warnings) > 0 {
+		s += ": " + strings.Join(r.Warnings, "; ")
+	}
+	return s
+}
+
+// Measure reports the size of a JSON schema and warns about the limits it
+// exceeds. Schemas over the limits are rejected by providers, often with
+// unspecific errors; deeply nested types are the usual cause.
+//
+// Example:
+//
+//	report, err := schema.Measure(schema.MustGenerate[Catalog](), schema.DefaultLimits)
+//	if err != nil {
+//	    return err
+//	}
+//	if !report.OK() {
+//	    log.Println(report)  // ... depth 12 ...: depth 12 exceeds 10 at $.sections[].items[]...
+//	}
+func Measure(schema json.RawMessage, limits Limits) (*SizeReport, error) {
+	var s any
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+
+	r := &SizeReport{
+		Bytes: len(schema),
+		// Same heuristic as llm.EstimateTokens
+		Tokens: (len(schema) + 3) / 4,
+	}
+	r.measure(s, "$", 0)
+
+	if limits.MaxProperties > 0 && r.Properties > limits.MaxProperties {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d properties exceed %d", r.Properties, limits.MaxProperties))
+	}
+	if limits.MaxDepth > 0 && r.Depth > limits.MaxDepth {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("depth %d exceeds %d at %s", r.Depth, limits.MaxDepth, r.DeepestAt))
+	}
+	if limits.MaxEnumValues > 0 && r.EnumValues > limits.MaxEnumValues {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d enum values exceed %d", r.EnumValues, limits.MaxEnumValues))
+	}
+	if limits.MaxChars > 0 && r.Chars > limits.MaxChars {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%d characters of names and values exceed %d", r.Chars, limits.MaxChars))
+	}
+	return r, nil
+}
+
+// measure adds the size of schema s at path, nested in depth objects, to r.
+func (r *SizeReport) measure(s any, path string, depth int) {
+	m, ok := s.(map[string]any)
+	if !ok {
+		return
+	}
+
+	if props, ok := m["properties"].(map[string]any); ok {
+		depth++
+		if depth > r.Depth {
+			r.Depth = depth
+			r.DeepestAt = path
+		}
+		for _, name := range sortedKeys(props) {
+			r.Properties++
+			r.Chars += len(name)
+			r.measure(props[name], path+"."+name, depth)
+		}
+	}
+	if enum, ok := m["enum"].([]any); ok {
+		r.EnumValues += len(enum)
+		for _, v := range enum {
+			r.Chars += len(fmt.Sprint(v))
+		}
+	}
+	if c, ok := m["const"]; ok {
+		r.Chars += len(fmt.Sprint(c))
+	}
+
+	r.measure(m["items"], path+"[]", depth)
+	r.measure(m["additionalProperties"], path+".*", depth)
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if subs, ok := m[key].([]any); ok {
+			for _, sub := range subs {
+				r.measure(sub, path, depth)
+			}
+		}
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := m[key].(map[string]any); ok {
+			for _, name := range sortedKeys(defs) {
+				r.Chars += len(name)
+				r.measure(defs[name], "#/"+key+"/"+name, 0)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Level3 struct {
+	Value string `json:"value"`
+}
+
+type Level2 struct {
+	Items []Level3 `json:"items"`
+}
+
+type Level1 struct {
+	Child     Level2    `json:"child"`
+	Sentiment Sentiment `json:"sentiment"`
+}
+
+func TestMeasure(t *testing.T) {
+	data := MustGenerate[Level1]()
+	report, err := Measure(data, DefaultLimits)
+	require.NoError(t, err)
+
+	assert.True(t, report.OK())
+	assert.Equal(t, len(data), report.Bytes)
+	assert.Positive(t, report.Tokens)
+	assert.Equal(t, 4, report.Properties) // child, sentiment, items, value
+	assert.Equal(t, 3, report.Depth)
+	assert.Equal(t, "$.child.items[]", report.DeepestAt)
+	assert.Equal(t, 3, report.EnumValues)
+	assert.Equal(t, len("childsentimentitemsvalue")+len("positivenegativeneutral"), report.Chars)
+}
+
+func TestMeasure_ExceedsLimits(t *testing.T) {
+	report, err := Measure(MustGenerate[Level1](), Limits{MaxDepth: 2, MaxEnumValues: 2})
+	require.NoError(t, err)
+
+	assert.False(t, report.OK())
+	assert.Equal(t, []string{
+		"depth 3 exceeds 2 at $.child.items[]",
+		"3 enum values exceed 2",
+	}, report.Warnings)
+	assert.Contains(t, report.String(), "depth 3 exceeds 2")
+}
+
+func TestMeasure_InvalidSchema(t *testing.T) {
+	_, err := Measure(json.RawMessage(`{`), DefaultLimits)
+	assert.Error(t, err)
+}
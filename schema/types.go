@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/invopop/jsonschema"
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	numberType          = reflect.TypeFor[json.Number]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// marshalerSchema returns the schema of time.Time, json.Number, and types
+// with custom JSON or text marshalers, whose JSON does not follow their Go
+// structure, or nil for other types.
+//
+// The JSON type of a custom marshaler is that of its zero value's encoding.
+// Types that encode to objects or arrays, or whose zero value cannot be
+// encoded, are reflected as usual.
+func marshalerSchema(t reflect.Type) *jsonschema.Schema {
+	switch t {
+	case timeType:
+		return &jsonschema.Schema{
+			Type:        "string",
+			Format:      "date-time",
+			Description: "RFC 3339 date-time, e.g. 2006-01-02T15:04:05Z or 2006-01-02T15:04:05+09:00",
+		}
+	case numberType:
+		return &jsonschema.Schema{Type: "number"}
+	}
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+
+	switch {
+	case implements(t, jsonMarshalerType) || implements(t, textMarshalerType):
+		typ := encodedType(t)
+		if typ == "" {
+			return nil
+		}
+		return &jsonschema.Schema{Type: typ}
+	case implements(t, jsonUnmarshalerType):
+		return nil
+	case implements(t, textUnmarshalerType):
+		// Text unmarshalers decode from JSON strings
+		return &jsonschema.Schema{Type: "string"}
+	}
+	return nil
+}
+
+// implements reports whether t or *t implements iface.
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// encodedType returns the JSON type of the encoding of t's zero value, or ""
+// if it is an object, an array, or cannot be determined.
+func encodedType(t reflect.Type) (typ string) {
+	defer func() {
+		// Custom marshalers may not expect zero values
+		if recover() != nil {
+			typ = ""
+		}
+	}()
+
+	data, err := json.Marshal(reflect.New(t).Interface())
+	if err != nil {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return ""
+	}
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return "integer"
+		}
+		return "number"
+	}
+	return ""
+}
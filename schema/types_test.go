@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Celsius encodes as a plain number despite being a struct.
+type Celsius struct{ degrees float64 }
+
+func (c Celsius) MarshalJSON() ([]byte, error) { return json.Marshal(c.degrees) }
+
+func (c *Celsius) UnmarshalJSON(data []byte) error { return json.Unmarshal(data, &c.degrees) }
+
+// Point encodes as an object, so it is reflected as usual.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	type point Point
+	return json.Marshal(point(p))
+}
+
+// Secret cannot encode its zero value.
+type Secret struct {
+	Value string `json:"value"`
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	if s.Value == "" {
+		return nil, errors.New("empty secret")
+	}
+	return json.Marshal(s.Value)
+}
+
+type Reading struct {
+	At          time.Time   `json:"at"`
+	Due         *time.Time  `json:"due,omitempty" jsonschema:"description=When the next reading is due"`
+	Value       json.Number `json:"value"`
+	Temperature Celsius     `json:"temperature"`
+	Location    Point       `json:"location"`
+	Total       *big.Int    `json:"total"`
+	Address     netip.Addr  `json:"address"`
+	Secret      Secret      `json:"secret"`
+}
+
+func TestGenerate_MarshalerTypes(t *testing.T) {
+	var s struct {
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(MustGenerate[Reading](), &s))
+	props := s.Properties
+
+	assert.Equal(t, "string", props["at"]["type"])
+	assert.Equal(t, "date-time", props["at"]["format"])
+	assert.Contains(t, props["at"]["description"], "RFC 3339")
+	assert.Equal(t, "When the next reading is due", props["due"]["description"])
+
+	assert.Equal(t, "number", props["value"]["type"])
+	assert.Equal(t, "number", props["temperature"]["type"])
+	assert.Equal(t, "object", props["location"]["type"])
+	assert.Contains(t, props["location"]["properties"], "x")
+	assert.Equal(t, "number", props["total"]["type"])
+	assert.Equal(t, "string", props["address"]["type"])
+	assert.Equal(t, "object", props["secret"]["type"])
+}
@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+)
+
+// union is a registered tagged union.
+type union struct {
+	discriminator string
+	variants      map[string]reflect.Type // Variant types by discriminator value
+}
+
+var unions sync.Map // reflect.Type of the interface -> *union
+
+// RegisterUnion registers the variants of the interface type I as a tagged
+// union. Fields of type I get a oneOf schema with one alternative per
+// variant, each with the discriminator property fixed to its key. Decode
+// values with UnmarshalUnion, e.g. from an UnmarshalJSON method.
+//
+// Example:
+//
+//	type Shape interface{ Area() float64 }
+//
+//	type Circle struct {
+//	    Radius float64 `json:"radius"`
+//	}
+//	type Rect struct {
+//	    Width  float64 `json:"width"`
+//	    Height float64 `json:"height"`
+//	}
+//
+//	schema.RegisterUnion[Shape]("kind", map[string]Shape{
+//	    "circle": Circle{},
+//	    "rect":   Rect{},
+//	})
+func RegisterUnion[I any](discriminator string, variants map[string]I) {
+	u := &union{
+		discriminator: discriminator,
+		variants:      make(map[string]reflect.Type, len(variants)),
+	}
+	for key, v := range variants {
+		u.variants[key] = reflect.TypeOf(v)
+	}
+	unions.Store(reflect.TypeFor[I](), u)
+}
+
+// UnmarshalUnion decodes data into the variant of the registered union I
+// named by its discriminator property.
+func UnmarshalUnion[I any](data []byte) (I, error) {
+	var zero I
+	t := reflect.TypeFor[I]()
+	v, ok := unions.Load(t)
+	if !ok {
+		return zero, fmt.Errorf("no union registered for %s", t)
+	}
+	u := v.(*union)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return zero, err
+	}
+	var key string
+	if err := json.Unmarshal(fields[u.discriminator], &key); err != nil {
+		return zero, fmt.Errorf("reading %s discriminator %q: %w", t, u.discriminator, err)
+	}
+	variant, ok := u.variants[key]
+	if !ok {
+		return zero, fmt.Errorf("unknown %s variant %q", t, key)
+	}
+
+	ptr := variant.Kind() == reflect.Pointer
+	if ptr {
+		variant = variant.Elem()
+	}
+	value := reflect.New(variant)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return zero, err
+	}
+	if !ptr {
+		value = value.Elem()
+	}
+	return value.Interface().(I), nil
+}
+
+// unionSchema returns the oneOf schema of a registered union, or nil for
+// other types.
+func unionSchema(t reflect.Type) *jsonschema.Schema {
+	v, ok := unions.Load(t)
+	if !ok {
+		return nil
+	}
+	u := v.(*union)
+
+	s := &jsonschema.Schema{}
+	for _, key := range slices.Sorted(maps.Keys(u.variants)) {
+		alt := Reflector.ReflectFromType(u.variants[key])
+		alt.Version = ""
+		alt.ID = ""
+		alt.Definitions = nil
+		if alt.Properties == nil {
+			alt.Properties = jsonschema.NewProperties()
+		}
+		alt.Properties.Set(u.discriminator, &jsonschema.Schema{Type: "string", Enum: []any{key}})
+		if !slices.Contains(alt.Required, u.discriminator) {
+			alt.Required = append([]string{u.discriminator}, alt.Required...)
+		}
+		s.OneOf = append(s.OneOf, alt)
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type Rect struct {
+	Kind   string  `json:"kind"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (r *Rect) Area() float64 { return r.Width * r.Height }
+
+type Drawing struct {
+	Shape Shape `json:"shape"`
+}
+
+func init() {
+	RegisterUnion[Shape]("kind", map[string]Shape{
+		"circle": Circle{},
+		"rect":   &Rect{},
+	})
+}
+
+func TestGenerate_Union(t *testing.T) {
+	data, err := Generate[Drawing]()
+	require.NoError(t, err)
+
+	var parsed struct {
+		Properties struct {
+			Shape struct {
+				OneOf []struct {
+					Properties map[string]struct {
+						Enum []any `json:"enum"`
+					} `json:"properties"`
+					Required []string `json:"required"`
+				} `json:"oneOf"`
+			} `json:"shape"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(data, &parsed))
+
+	variants := parsed.Properties.Shape.OneOf
+	require.Len(t, variants, 2)
+	assert.Equal(t, []any{"circle"}, variants[0].Properties["kind"].Enum)
+	assert.Contains(t, variants[0].Properties, "radius")
+	assert.Equal(t, []string{"kind", "radius"}, variants[0].Required)
+	assert.Equal(t, []any{"rect"}, variants[1].Properties["kind"].Enum)
+	assert.Contains(t, variants[1].Properties, "width")
+	assert.Contains(t, variants[1].Required, "kind")
+	assert.Equal(t, 1, strings.Count(string(data), "$schema"), "variants should not be root schemas")
+}
+
+func TestUnmarshalUnion(t *testing.T) {
+	shape, err := UnmarshalUnion[Shape]([]byte(`{"kind":"circle","radius":2}`))
+	require.NoError(t, err)
+	assert.Equal(t, Circle{Radius: 2}, shape)
+
+	shape, err = UnmarshalUnion[Shape]([]byte(`{"kind":"rect","width":2,"height":3}`))
+	require.NoError(t, err)
+	assert.Equal(t, &Rect{Kind: "rect", Width: 2, Height: 3}, shape)
+
+	_, err = UnmarshalUnion[Shape]([]byte(`{"kind":"triangle"}`))
+	assert.ErrorContains(t, err, `unknown schema.Shape variant "triangle"`)
+
+	_, err = UnmarshalUnion[error]([]byte(`{}`))
+	assert.Error(t, err)
+}
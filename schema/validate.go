@@ -0,0 +1,357 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation is a part of a value that does not match its schema.
+type Violation struct {
+	Path    string // Location in the value, e.g. "$.items[0].name"
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ValidationError is returned by Validate when a value does not match its schema.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return "value does not match schema: " + strings.Join(parts, "; ")
+}
+
+// Validate checks that the JSON data matches the JSON schema and returns a
+// *ValidationError listing all violations if it does not. It supports the
+// keywords generated by this package: type, enum, const, properties,
+// required, additionalProperties, items, oneOf, anyOf, allOf, local $ref
+// references, and the string, number, and array bounds.
+//
+// Example:
+//
+//	s := schema.MustGenerate[Book]()
+//	if err := schema.Validate(s, data); err != nil {
+//	    var verr *schema.ValidationError
+//	    if errors.As(err, &verr) {
+//	        for _, v := range verr.Violations {
+//	            fmt.Println(v)
+//	        }
+//	    }
+//	}
+func Validate(schema json.RawMessage, data []byte) error {
+	var s any
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("parsing schema: %w", err)
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("parsing value: %w", err)
+	}
+
+	resolveRefs(s, s, make(map[uintptr]bool))
+	if target := refTarget(s, s); target != nil {
+		s = target
+	}
+
+	var violations []Violation
+	validate(s, v, "$", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// resolveRefs replaces the local references ($ref) in node with the schemas
+// they refer to in root, in place. Recursive references make the schema
+// cyclic, which validation follows only as deep as the value.
+func resolveRefs(node, root any, seen map[uintptr]bool) {
+	switch n := node.(type) {
+	case map[string]any:
+		if seen[reflect.ValueOf(n).Pointer()] {
+			return
+		}
+		seen[reflect.ValueOf(n).Pointer()] = true
+		for key, child := range n {
+			if target := refTarget(child, root); target != nil {
+				n[key] = target
+			}
+			resolveRefs(n[key], root, seen)
+		}
+	case []any:
+		for i, child := range n {
+			if target := refTarget(child, root); target != nil {
+				n[i] = target
+			}
+			resolveRefs(n[i], root, seen)
+		}
+	}
+}
+
+// refTarget returns the schema in root that schema refers to, following
+// references to references, or nil if schema is not a resolvable local
+// reference.
+func refTarget(schema, root any) any {
+	var target any
+	for range 32 { // Bounds chains of references
+		m, ok := schema.(map[string]any)
+		if !ok {
+			return target
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return target
+		}
+		pointer, ok := strings.CutPrefix(ref, "#")
+		if !ok {
+			return target
+		}
+		schema = root
+		for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+			if token == "" {
+				continue
+			}
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			parent, ok := schema.(map[string]any)
+			if !ok {
+				return target
+			}
+			schema = parent[token]
+		}
+		if schema == nil {
+			return target
+		}
+		target = schema
+	}
+	return target
+}
+
+// validate appends the violations of v against schema s to out.
+func validate(s, v any, path string, out *[]Violation) {
+	add := func(format string, args ...any) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if b, ok := s.(bool); ok {
+		if !b {
+			add("no value is allowed")
+		}
+		return
+	}
+	m, ok := s.(map[string]any)
+	if !ok {
+		return
+	}
+
+	if t, ok := m["type"]; ok && !typeMatches(t, v) {
+		add("expected %s, got %s", formatType(t), jsonType(v))
+		return
+	}
+	if enum, ok := m["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		add("must be one of %s", formatValues(enum))
+	}
+	if c, ok := m["const"]; ok && !reflect.DeepEqual(c, v) {
+		add("must be %s", formatValues([]any{c}))
+	}
+
+	switch v := v.(type) {
+	case string:
+		validateString(m, v, add)
+	case float64:
+		validateNumber(m, v, add)
+	case map[string]any:
+		validateObject(m, v, path, out, add)
+	case []any:
+		validateArray(m, v, path, out, add)
+	}
+
+	if all, ok := m["allOf"].([]any); ok {
+		for _, sub := range all {
+			validate(sub, v, path, out)
+		}
+	}
+	if anyOf, ok := m["anyOf"].([]any); ok && countMatches(anyOf, v, path) == 0 {
+		add("must match at least one schema of anyOf")
+	}
+	if oneOf, ok := m["oneOf"].([]any); ok {
+		if n := countMatches(oneOf, v, path); n != 1 {
+			add("must match exactly one schema of oneOf, matched %d", n)
+		}
+	}
+}
+
+func validateString(m map[string]any, v string, add func(string, ...any)) {
+	n := utf8.RuneCountInString(v)
+	if min, ok := m["minLength"].(float64); ok && float64(n) < min {
+		add("must be at least %v characters", min)
+	}
+	if max, ok := m["maxLength"].(float64); ok && float64(n) > max {
+		add("must be at most %v characters", max)
+	}
+	if pattern, ok := m["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+			add("must match pattern %q", pattern)
+		}
+	}
+}
+
+func validateNumber(m map[string]any, v float64, add func(string, ...any)) {
+	if min, ok := m["minimum"].(float64); ok && v < min {
+		add("must be >= %v", min)
+	}
+	if max, ok := m["maximum"].(float64); ok && v > max {
+		add("must be <= %v", max)
+	}
+	if min, ok := m["exclusiveMinimum"].(float64); ok && v <= min {
+		add("must be > %v", min)
+	}
+	if max, ok := m["exclusiveMaximum"].(float64); ok && v >= max {
+		add("must be < %v", max)
+	}
+	if mult, ok := m["multipleOf"].(float64); ok && mult > 0 {
+		if q := v / mult; math.Abs(q-math.Round(q)) > 1e-9 {
+			add("must be a multiple of %v", mult)
+		}
+	}
+}
+
+func validateObject(m map[string]any, v map[string]any, path string, out *[]Violation, add func(string, ...any)) {
+	if required, ok := m["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := v[name]; !present {
+					add("missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	props, _ := m["properties"].(map[string]any)
+	for _, name := range sortedKeys(v) {
+		childPath := path + "." + name
+		if sub, ok := props[name]; ok {
+			validate(sub, v[name], childPath, out)
+			continue
+		}
+		switch additional := m["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				add("unexpected property %q", name)
+			}
+		case map[string]any:
+			validate(additional, v[name], childPath, out)
+		}
+	}
+}
+
+func validateArray(m map[string]any, v []any, path string, out *[]Violation, add func(string, ...any)) {
+	if min, ok := m["minItems"].(float64); ok && float64(len(v)) < min {
+		add("must have at least %v items", min)
+	}
+	if max, ok := m["maxItems"].(float64); ok && float64(len(v)) > max {
+		add("must have at most %v items", max)
+	}
+	if unique, ok := m["uniqueItems"].(bool); ok && unique {
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if reflect.DeepEqual(v[i], v[j]) {
+					add("items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+	if items, ok := m["items"]; ok {
+		for i, item := range v {
+			validate(items, item, fmt.Sprintf("%s[%d]", path, i), out)
+		}
+	}
+}
+
+// countMatches returns the number of schemas v matches.
+func countMatches(schemas []any, v any, path string) int {
+	n := 0
+	for _, s := range schemas {
+		var violations []Violation
+		validate(s, v, path, &violations)
+		if len(violations) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// typeMatches reports whether v has the JSON type t (a type name or a list of them).
+func typeMatches(t, v any) bool {
+	switch t := t.(type) {
+	case string:
+		actual := jsonType(v)
+		return actual == t || (t == "number" && actual == "integer")
+	case []any:
+		return slices.ContainsFunc(t, func(t any) bool { return typeMatches(t, v) })
+	default:
+		return true
+	}
+}
+
+// jsonType returns the JSON type name of a decoded JSON value.
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func formatType(t any) string {
+	if types, ok := t.([]any); ok {
+		parts := make([]string, len(types))
+		for i, t := range types {
+			parts[i] = fmt.Sprint(t)
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func formatValues(values []any) string {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Sprint(values)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
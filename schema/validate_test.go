@@ -0,0 +1,95 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Order struct {
+	ID       string      `json:"id" jsonschema:"minLength=3,pattern=^ord_"`
+	Quantity int         `json:"quantity" jsonschema:"minimum=1,maximum=10"`
+	Status   Sentiment   `json:"status"`
+	Items    []OrderItem `json:"items" jsonschema:"minItems=1"`
+	Note     string      `json:"note,omitempty"`
+}
+
+type OrderItem struct {
+	SKU   string  `json:"sku"`
+	Price float64 `json:"price" jsonschema:"exclusiveMinimum=0"`
+}
+
+func TestValidate(t *testing.T) {
+	s := MustGenerate[Order]()
+
+	tests := []struct {
+		name       string
+		data       string
+		violations []string
+	}{
+		{
+			name: "valid",
+			data: `{"id":"ord_1","quantity":2,"status":"positive","items":[{"sku":"a","price":1.5}]}`,
+		},
+		{
+			name:       "missing required",
+			data:       `{"id":"ord_1","quantity":2,"status":"positive"}`,
+			violations: []string{`$: missing required property "items"`},
+		},
+		{
+			name: "wrong values",
+			data: `{"id":"x","quantity":11,"status":"angry","items":[],"extra":true}`,
+			violations: []string{
+				`$: unexpected property "extra"`,
+				`$.id: must be at least 3 characters`,
+				`$.id: must match pattern "^ord_"`,
+				`$.items: must have at least 1 items`,
+				`$.quantity: must be <= 10`,
+				`$.status: must be one of ["positive","negative","neutral"]`,
+			},
+		},
+		{
+			name: "nested",
+			data: `{"id":"ord_1","quantity":1.5,"status":"neutral","items":[{"sku":1,"price":0}]}`,
+			violations: []string{
+				`$.items[0].price: must be > 0`,
+				`$.items[0].sku: expected string, got integer`,
+				`$.quantity: expected integer, got number`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(s, []byte(tt.data))
+			if tt.violations == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			var got []string
+			for _, v := range verr.Violations {
+				got = append(got, v.String())
+			}
+			assert.ElementsMatch(t, tt.violations, got)
+		})
+	}
+}
+
+func TestValidate_OneOf(t *testing.T) {
+	s := MustGenerate[Drawing]()
+
+	assert.NoError(t, Validate(s, []byte(`{"shape":{"kind":"circle","radius":1}}`)))
+
+	err := Validate(s, []byte(`{"shape":{"kind":"triangle","radius":1}}`))
+	assert.ErrorContains(t, err, "$.shape: must match exactly one schema of oneOf, matched 0")
+}
+
+func TestValidate_InvalidJSON(t *testing.T) {
+	assert.Error(t, Validate(json.RawMessage(`{`), []byte(`{}`)))
+	assert.Error(t, Validate(MustGenerate[Order](), []byte(`not json`)))
+}
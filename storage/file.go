@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// FileStore stores each conversation as a JSON file in a directory.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex // Serializes read-modify-write cycles of the files
+}
+
+// NewFileStore creates a store that keeps conversations in dir as <id>.json.
+// The directory is created if it does not exist.
+//
+// Example:
+//
+//	store, err := storage.NewFileStore("./conversations")
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating conversation directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Create implements ConversationStore.
+func (s *FileStore) Create(ctx context.Context, id string, metadata map[string]string) (*Conversation, error) {
+	if id == "" {
+		id = newID()
+	}
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: %q", ErrConversationExists, id)
+	}
+	now := time.Now().UTC()
+	conv := &Conversation{ID: id, Metadata: metadata, Messages: []llm.Message{}, CreatedAt: now, UpdatedAt: now}
+	if err := s.write(path, conv); err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// AppendMessages implements ConversationStore.
+func (s *FileStore) AppendMessages(ctx context.Context, id string, msgs ...llm.Message) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, err := s.read(path, id)
+	if err != nil {
+		return err
+	}
+	conv.Messages = append(conv.Messages, msgs...)
+	conv.UpdatedAt = time.Now().UTC()
+	return s.write(path, conv)
+}
+
+// ReplaceMessages implements ConversationStore.
+func (s *FileStore) ReplaceMessages(ctx context.Context, id string, msgs ...llm.Message) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, err := s.read(path, id)
+	if err != nil {
+		return err
+	}
+	conv.Messages = append([]llm.Message{}, msgs...)
+	conv.UpdatedAt = time.Now().UTC()
+	return s.write(path, conv)
+}
+
+// Load implements ConversationStore.
+func (s *FileStore) Load(ctx context.Context, id string) (*Conversation, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(path, id)
+}
+
+// List implements ConversationStore.
+func (s *FileStore) List(ctx context.Context) ([]ConversationInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing conversations: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]ConversationInfo, 0, len(paths))
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		conv, err := s.read(path, id)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				continue // Deleted meanwhile
+			}
+			return nil, err
+		}
+		infos = append(infos, ConversationInfo{
+			ID:           conv.ID,
+			Metadata:     conv.Metadata,
+			MessageCount: len(conv.Messages),
+			CreatedAt:    conv.CreatedAt,
+			UpdatedAt:    conv.UpdatedAt,
+		})
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+	})
+	return infos, nil
+}
+
+// Delete implements ConversationStore.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("deleting conversation: %w", err)
+	}
+	return nil
+}
+
+// read reads the conversation file at path.
+func (s *FileStore) read(path, id string) (*Conversation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+		}
+		return nil, fmt.Errorf("reading conversation: %w", err)
+	}
+	conv := &Conversation{}
+	if err := json.Unmarshal(data, conv); err != nil {
+		return nil, fmt.Errorf("parsing conversation %q: %w", id, err)
+	}
+	return conv, nil
+}
+
+// write writes conv to path atomically via a temporary file and rename.
+func (s *FileStore) write(path string, conv *Conversation) error {
+	data, err := json.Marshal(conv)
+	if err != nil {
+		return fmt.Errorf("marshaling conversation: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing conversation: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing conversation: %w", err)
+	}
+	return nil
+}
+
+// path returns the file path of a conversation, rejecting IDs that could
+// escape the directory.
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid conversation ID: %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// SQLiteStore stores conversations in SQLite tables.
+// It uses database/sql, so any SQLite driver can be used to open the database.
+type SQLiteStore struct {
+	db            *sql.DB
+	conversations string // Table of conversations
+	messages      string // Table of messages
+}
+
+// NewSQLiteStore creates a store backed by db, creating its tables if
+// needed: <prefix>conversations and <prefix>messages. If prefix is empty,
+// no prefix is used.
+//
+// Example:
+//
+//	db, _ := sql.Open("sqlite3", "chat.db") // with a SQLite driver imported
+//	store, err := storage.NewSQLiteStore(ctx, db, "")
+func NewSQLiteStore(ctx context.Context, db *sql.DB, prefix string) (*SQLiteStore, error) {
+	s := &SQLiteStore{db: db, conversations: prefix + "conversations", messages: prefix + "messages"}
+
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	metadata TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`, s.conversations),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	conversation_id TEXT NOT NULL REFERENCES %s(id) ON DELETE CASCADE,
+	seq INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (conversation_id, seq)
+)`, s.messages, s.conversations),
+	}
+	for _, query := range queries {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return nil, fmt.Errorf("creating conversation tables: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Create implements ConversationStore.
+func (s *SQLiteStore) Create(ctx context.Context, id string, metadata map[string]string) (*Conversation, error) {
+	if id == "" {
+		id = newID()
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling metadata: %w", err)
+	}
+
+	now := time.Now().UTC()
+	query := fmt.Sprintf(`INSERT INTO %s (id, metadata, created_at, updated_at) VALUES (?, ?, ?, ?)
+ON CONFLICT(id) DO NOTHING`, s.conversations)
+	res, err := s.db.ExecContext(ctx, query, id, string(data), now, now)
+	if err != nil {
+		return nil, fmt.Errorf("creating conversation: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrConversationExists, id)
+	}
+	return &Conversation{ID: id, Metadata: metadata, Messages: []llm.Message{}, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+// AppendMessages implements ConversationStore.
+// The messages are appended in a single transaction.
+func (s *SQLiteStore) AppendMessages(ctx context.Context, id string, msgs ...llm.Message) error {
+	return s.writeMessages(ctx, id, msgs, false)
+}
+
+// ReplaceMessages implements ConversationStore.
+// The messages are deleted and inserted again in a single transaction.
+func (s *SQLiteStore) ReplaceMessages(ctx context.Context, id string, msgs ...llm.Message) error {
+	return s.writeMessages(ctx, id, msgs, true)
+}
+
+// writeMessages appends msgs to the conversation, first deleting its
+// messages if replace is set.
+func (s *SQLiteStore) writeMessages(ctx context.Context, id string, msgs []llm.Message, replace bool) (err error) {
+	op := "appending messages"
+	if replace {
+		op = "replacing messages"
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := fmt.Sprintf(`UPDATE %s SET updated_at = ? WHERE id = ?`, s.conversations)
+	res, err := tx.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+	}
+
+	if replace {
+		query = fmt.Sprintf(`DELETE FROM %s WHERE conversation_id = ?`, s.messages)
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	var seq int
+	query = fmt.Sprintf(`SELECT COALESCE(MAX(seq), 0) FROM %s WHERE conversation_id = ?`, s.messages)
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&seq); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (conversation_id, seq, data) VALUES (?, ?, ?)`, s.messages)
+	for _, msg := range msgs {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("marshaling message: %w", err)
+		}
+		seq++
+		if _, err := tx.ExecContext(ctx, query, id, seq, string(data)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Load implements ConversationStore.
+func (s *SQLiteStore) Load(ctx context.Context, id string) (*Conversation, error) {
+	conv := &Conversation{ID: id, Messages: []llm.Message{}}
+	var metadata string
+	query := fmt.Sprintf(`SELECT metadata, created_at, updated_at FROM %s WHERE id = ?`, s.conversations)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&metadata, &conv.CreatedAt, &conv.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+		}
+		return nil, fmt.Errorf("loading conversation: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metadata), &conv.Metadata); err != nil {
+		return nil, fmt.Errorf("parsing metadata of conversation %q: %w", id, err)
+	}
+
+	query = fmt.Sprintf(`SELECT data FROM %s WHERE conversation_id = ? ORDER BY seq`, s.messages)
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading conversation: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("loading conversation: %w", err)
+		}
+		var msg llm.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, fmt.Errorf("parsing message of conversation %q: %w", id, err)
+		}
+		conv.Messages = append(conv.Messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading conversation: %w", err)
+	}
+	return conv, nil
+}
+
+// List implements ConversationStore.
+func (s *SQLiteStore) List(ctx context.Context) ([]ConversationInfo, error) {
+	query := fmt.Sprintf(`SELECT c.id, c.metadata, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM %s m WHERE m.conversation_id = c.id)
+FROM %s c ORDER BY c.updated_at DESC`, s.messages, s.conversations)
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("listing conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var infos []ConversationInfo
+	for rows.Next() {
+		var info ConversationInfo
+		var metadata string
+		if err := rows.Scan(&info.ID, &metadata, &info.CreatedAt, &info.UpdatedAt, &info.MessageCount); err != nil {
+			return nil, fmt.Errorf("listing conversations: %w", err)
+		}
+		if err := json.Unmarshal([]byte(metadata), &info.Metadata); err != nil {
+			return nil, fmt.Errorf("parsing metadata of conversation %q: %w", info.ID, err)
+		}
+		infos = append(infos, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing conversations: %w", err)
+	}
+	return infos, nil
+}
+
+// Delete implements ConversationStore.
+// Messages are deleted explicitly, so foreign key enforcement is not needed.
+func (s *SQLiteStore) Delete(ctx context.Context, id string) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("deleting conversation: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, query := range []string{
+		fmt.Sprintf(`DELETE FROM %s WHERE conversation_id = ?`, s.messages),
+		fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.conversations),
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("deleting conversation: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("deleting conversation: %w", err)
+	}
+	return nil
+}
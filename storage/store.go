@@ -0,0 +1,194 @@
+// Package storage persists conversations, so chat history survives restarts.
+//
+// A ConversationStore keeps the messages of each conversation, appended as
+// the conversation goes on. FileStore keeps conversations as JSON files;
+// SQLiteStore keeps them in SQLite through database/sql. Send continues a
+// stored conversation with an LLM call, and ContextStore persists the
+// history of agent runners.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/plugin"
+)
+
+// ErrConversationNotFound is returned when no conversation has the given ID.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ErrConversationExists is returned by Create when the ID is already used.
+var ErrConversationExists = errors.New("conversation already exists")
+
+// Conversation is a stored conversation.
+type Conversation struct {
+	ID        string
+	Metadata  map[string]string // Application data, e.g. a title or user ID
+	Messages  []llm.Message
+	CreatedAt time.Time
+	UpdatedAt time.Time // Time of the last appended message
+}
+
+// ConversationInfo describes a stored conversation without its messages.
+type ConversationInfo struct {
+	ID           string
+	Metadata     map[string]string
+	MessageCount int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// ConversationStore persists conversations.
+// Implementations must be safe for concurrent use.
+type ConversationStore interface {
+	// Create creates an empty conversation. If id is empty, a random ID is
+	// generated. It returns ErrConversationExists if id is already used.
+	Create(ctx context.Context, id string, metadata map[string]string) (*Conversation, error)
+
+	// AppendMessages appends messages to the conversation.
+	AppendMessages(ctx context.Context, id string, msgs ...llm.Message) error
+
+	// ReplaceMessages atomically replaces all messages of the conversation,
+	// keeping its metadata.
+	ReplaceMessages(ctx context.Context, id string, msgs ...llm.Message) error
+
+	// Load returns the conversation with all its messages.
+	Load(ctx context.Context, id string) (*Conversation, error)
+
+	// List returns all conversations, most recently updated first.
+	List(ctx context.Context) ([]ConversationInfo, error)
+
+	// Delete removes the conversation. Deleting a missing conversation is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// newID returns a random conversation ID.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Send continues the stored conversation id with content: it calls the LLM
+// with the stored messages and content, and appends content and the reply
+// to the conversation. A missing conversation is created. Messages set with
+// llm.WithSystemMessage are sent but not stored.
+//
+// Example:
+//
+//	store, _ := storage.NewFileStore("./conversations")
+//	resp, err := storage.Send(ctx, store, "user-123", "What did I ask yesterday?",
+//	    llm.WithProvider("openai"),
+//	    llm.WithModel("gpt-4o"),
+//	)
+func Send(ctx context.Context, store ConversationStore, id, content string, opts ...llm.Option) (llm.Response[string], error) {
+	conv, err := store.Load(ctx, id)
+	if errors.Is(err, ErrConversationNotFound) {
+		conv, err = store.Create(ctx, id, nil)
+	}
+	if err != nil {
+		return llm.Response[string]{}, err
+	}
+
+	user := llm.UserMessage(content)
+	messages := append(conv.Messages, user)
+	resp, err := llm.CallMessages(ctx, messages, opts...)
+	if err != nil {
+		return resp, err
+	}
+
+	reply := resp.Messages()[len(resp.Messages())-1]
+	if err := store.AppendMessages(ctx, conv.ID, user, reply); err != nil {
+		return resp, fmt.Errorf("storing conversation: %w", err)
+	}
+	return resp, nil
+}
+
+// ContextStore returns a plugin.ContextStore that keeps the history of agent
+// runners in store, one conversation per session ID, so stored agent
+// sessions can be listed and read like other conversations. When the stored
+// messages begin the history, the messages added since are appended;
+// otherwise, such as after a history policy trimmed the history or Compact
+// summarized it, the stored messages are replaced. Context state is not
+// stored.
+//
+// Example:
+//
+//	store, _ := storage.NewFileStore("./conversations")
+//	runner := agent.NewRunner(
+//	    plugin.WithAgentProvider("anthropic"),
+//	    plugin.WithAgentModel("claude-sonnet-4-5-20250929"),
+//	    plugin.WithAgentContextStore(storage.ContextStore(store), "user-123"),
+//	)
+func ContextStore(store ConversationStore) plugin.ContextStore {
+	return &contextStore{store: store}
+}
+
+// contextStore adapts a ConversationStore to plugin.ContextStore.
+type contextStore struct {
+	store ConversationStore
+}
+
+// Load implements plugin.ContextStore.
+func (s *contextStore) Load(ctx context.Context, sessionID string) (*plugin.AgentContext, error) {
+	conv, err := s.store.Load(ctx, sessionID)
+	if errors.Is(err, ErrConversationNotFound) {
+		return nil, plugin.ErrContextNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	agentCtx := plugin.NewAgentContext()
+	agentCtx.AddMessages(conv.Messages...)
+	return agentCtx, nil
+}
+
+// Save implements plugin.ContextStore.
+func (s *contextStore) Save(ctx context.Context, sessionID string, agentCtx *plugin.AgentContext) error {
+	history := agentCtx.History()
+
+	conv, err := s.store.Load(ctx, sessionID)
+	switch {
+	case errors.Is(err, ErrConversationNotFound):
+		if _, err := s.store.Create(ctx, sessionID, nil); err != nil {
+			return err
+		}
+		return s.store.AppendMessages(ctx, sessionID, history...)
+	case err != nil:
+		return err
+	case hasPrefix(history, conv.Messages):
+		// Append the messages added since the last save
+		return s.store.AppendMessages(ctx, sessionID, history[len(conv.Messages):]...)
+	default:
+		// The history was trimmed or compacted; replace it
+		return s.store.ReplaceMessages(ctx, sessionID, history...)
+	}
+}
+
+// hasPrefix reports whether history begins with the stored messages.
+// Messages are compared by their JSON encoding, as they are stored.
+func hasPrefix(history, stored []llm.Message) bool {
+	if len(stored) > len(history) {
+		return false
+	}
+	for i := range stored {
+		a, errA := json.Marshal(history[i])
+		b, errB := json.Marshal(stored[i])
+		if errA != nil || errB != nil || !bytes.Equal(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// Delete implements plugin.ContextStore.
+func (s *contextStore) Delete(ctx context.Context, sessionID string) error {
+	return s.store.Delete(ctx, sessionID)
+}
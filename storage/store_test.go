@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i2y/bucephalus/llm"
+	"github.com/i2y/bucephalus/plugin"
+	"github.com/i2y/bucephalus/provider"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	testConversationStore(t, store)
+
+	_, err = store.Load(context.Background(), "../etc/passwd")
+	assert.ErrorContains(t, err, "invalid conversation ID")
+}
+
+func TestSQLiteStore(t *testing.T) {
+	store, err := NewSQLiteStore(context.Background(), openSQLite(t), "chat_")
+	require.NoError(t, err)
+	testConversationStore(t, store)
+
+	// Creating the tables again keeps the stored conversations
+	_, err = store.Create(context.Background(), "kept", nil)
+	require.NoError(t, err)
+	store, err = NewSQLiteStore(context.Background(), store.db, "chat_")
+	require.NoError(t, err)
+	_, err = store.Load(context.Background(), "kept")
+	assert.NoError(t, err)
+}
+
+// openSQLite opens a SQLite database in a temporary directory.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newConversationStores returns an empty store of each kind.
+func newConversationStores(t *testing.T) map[string]ConversationStore {
+	t.Helper()
+	file, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	sqlite, err := NewSQLiteStore(context.Background(), openSQLite(t), "")
+	require.NoError(t, err)
+	return map[string]ConversationStore{"file": file, "sqlite": sqlite}
+}
+
+// testConversationStore tests the behavior shared by all ConversationStores.
+func testConversationStore(t *testing.T, store ConversationStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	conv, err := store.Create(ctx, "support-1", map[string]string{"title": "Refund"})
+	require.NoError(t, err)
+	assert.Equal(t, "support-1", conv.ID)
+	_, err = store.Create(ctx, "support-1", nil)
+	assert.ErrorIs(t, err, ErrConversationExists)
+
+	generated, err := store.Create(ctx, "", nil)
+	require.NoError(t, err)
+	assert.Len(t, generated.ID, 32)
+
+	require.NoError(t, store.AppendMessages(ctx, "support-1", llm.UserMessage("I want a refund")))
+	require.NoError(t, store.AppendMessages(ctx, "support-1", llm.AssistantMessage("Sure.")))
+	assert.ErrorIs(t, store.AppendMessages(ctx, "missing", llm.UserMessage("hi")), ErrConversationNotFound)
+
+	loaded, err := store.Load(ctx, "support-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"title": "Refund"}, loaded.Metadata)
+	assert.Equal(t, []llm.Message{llm.UserMessage("I want a refund"), llm.AssistantMessage("Sure.")}, loaded.Messages)
+	assert.False(t, loaded.UpdatedAt.Before(loaded.CreatedAt))
+
+	infos, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "support-1", infos[0].ID) // Most recently updated first
+	assert.Equal(t, 2, infos[0].MessageCount)
+
+	require.NoError(t, store.ReplaceMessages(ctx, "support-1", llm.UserMessage("Refund order 42")))
+	loaded, err = store.Load(ctx, "support-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"title": "Refund"}, loaded.Metadata)
+	assert.Equal(t, []llm.Message{llm.UserMessage("Refund order 42")}, loaded.Messages)
+	require.NoError(t, store.AppendMessages(ctx, "support-1", llm.AssistantMessage("Done.")))
+	loaded, err = store.Load(ctx, "support-1")
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{llm.UserMessage("Refund order 42"), llm.AssistantMessage("Done.")}, loaded.Messages)
+	assert.ErrorIs(t, store.ReplaceMessages(ctx, "missing"), ErrConversationNotFound)
+
+	require.NoError(t, store.Delete(ctx, "support-1"))
+	require.NoError(t, store.Delete(ctx, "support-1"))
+	_, err = store.Load(ctx, "support-1")
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+}
+
+// echoProvider echoes the last message.
+type echoProvider struct{}
+
+func (echoProvider) Name() string { return "echo" }
+
+func (echoProvider) Call(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	last := req.Messages[len(req.Messages)-1]
+	return &provider.Response{Content: "echo: " + last.Content, FinishReason: provider.FinishReasonStop}, nil
+}
+
+func TestSend(t *testing.T) {
+	provider.RegisterInstance("storage-echo", echoProvider{})
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+	opts := []llm.Option{llm.WithProvider("storage-echo"), llm.WithModel("test-model")}
+
+	resp, err := Send(ctx, store, "chat", "hello", opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "echo: hello", resp.Text())
+
+	// The second call sees the stored history
+	resp, err = Send(ctx, store, "chat", "again", opts...)
+	require.NoError(t, err)
+	assert.Len(t, resp.Messages(), 4)
+
+	conv, err := store.Load(ctx, "chat")
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{
+		llm.UserMessage("hello"), llm.AssistantMessage("echo: hello"),
+		llm.UserMessage("again"), llm.AssistantMessage("echo: again"),
+	}, conv.Messages)
+}
+
+func TestContextStore(t *testing.T) {
+	for name, conversations := range newConversationStores(t) {
+		t.Run(name, func(t *testing.T) {
+			testContextStore(t, conversations)
+		})
+	}
+}
+
+func testContextStore(t *testing.T, conversations ConversationStore) {
+	store := ContextStore(conversations)
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, "session")
+	assert.ErrorIs(t, err, plugin.ErrContextNotFound)
+
+	agentCtx := plugin.NewAgentContext()
+	agentCtx.AddMessages(llm.UserMessage("hi"), llm.AssistantMessage("hello"))
+	require.NoError(t, store.Save(ctx, "session", agentCtx))
+	agentCtx.AddMessage(llm.UserMessage("bye"))
+	require.NoError(t, store.Save(ctx, "session", agentCtx))
+
+	loaded, err := store.Load(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, agentCtx.History(), loaded.History())
+
+	// A compacted history replaces the stored one
+	agentCtx.ClearHistory()
+	agentCtx.AddMessage(llm.UserMessage("summary"))
+	require.NoError(t, store.Save(ctx, "session", agentCtx))
+	conv, err := conversations.Load(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{llm.UserMessage("summary")}, conv.Messages)
+
+	require.NoError(t, store.Delete(ctx, "session"))
+	_, err = store.Load(ctx, "session")
+	assert.ErrorIs(t, err, plugin.ErrContextNotFound)
+}
+
+func TestContextStore_TrimmedHistory(t *testing.T) {
+	for name, conversations := range newConversationStores(t) {
+		t.Run(name, func(t *testing.T) {
+			testContextStoreTrimmedHistory(t, conversations)
+		})
+	}
+}
+
+func testContextStoreTrimmedHistory(t *testing.T, conversations ConversationStore) {
+	store := ContextStore(conversations)
+	ctx := context.Background()
+	_, err := conversations.Create(ctx, "session", map[string]string{"title": "Quiz"})
+	require.NoError(t, err)
+
+	agentCtx := plugin.NewAgentContext()
+	agentCtx.AddMessages(
+		llm.UserMessage("q1"), llm.AssistantMessage("a1"),
+		llm.UserMessage("q2"), llm.AssistantMessage("a2"),
+	)
+	require.NoError(t, store.Save(ctx, "session", agentCtx))
+
+	// The trimmed history grows past the stored length before the next save
+	require.NoError(t, agentCtx.ApplyHistoryPolicy(ctx, plugin.MaxMessagesPolicy(2)))
+	agentCtx.AddMessages(
+		llm.UserMessage("q3"), llm.AssistantMessage("a3"),
+		llm.UserMessage("q4"), llm.AssistantMessage("a4"),
+	)
+	require.NoError(t, store.Save(ctx, "session", agentCtx))
+
+	loaded, err := store.Load(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{
+		llm.UserMessage("q2"), llm.AssistantMessage("a2"),
+		llm.UserMessage("q3"), llm.AssistantMessage("a3"),
+		llm.UserMessage("q4"), llm.AssistantMessage("a4"),
+	}, loaded.History())
+
+	// The metadata of the conversation is kept
+	conv, err := conversations.Load(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"title": "Quiz"}, conv.Metadata)
+}
@@ -4,31 +4,55 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/i2y/bucephalus/llm"
 )
 
+// defaultBashOutputLimit is the default maximum size of stdout and stderr,
+// in bytes.
+const defaultBashOutputLimit = 100 * 1024
+
 // BashInput defines the input for the Bash tool.
 type BashInput struct {
-	Command string `json:"command" jsonschema:"required,description=Shell command to execute"`
-	Timeout int    `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds (default: 30)"`
-	WorkDir string `json:"workdir,omitempty" jsonschema:"description=Working directory for the command"`
+	Command    string            `json:"command" jsonschema:"required,description=Shell command to execute"`
+	Timeout    int               `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds (default: 30; none for background commands)"`
+	WorkDir    string            `json:"workdir,omitempty" jsonschema:"description=Working directory for the command"`
+	Env        map[string]string `json:"env,omitempty" jsonschema:"description=Environment variables to set for the command, in addition to the inherited ones"`
+	Stdin      string            `json:"stdin,omitempty" jsonschema:"description=Text to pass to the standard input of the command"`
+	MaxStdout  int               `json:"max_stdout,omitempty" jsonschema:"description=Maximum bytes of stdout to return (default: 102400)"`
+	MaxStderr  int               `json:"max_stderr,omitempty" jsonschema:"description=Maximum bytes of stderr to return (default: 102400)"`
+	Background bool              `json:"background,omitempty" jsonschema:"description=Run the command in the background and return a job ID to poll with bash_output, e.g. for servers and long builds"`
+}
+
+// BashOutputInput defines the input for the BashOutput tool.
+type BashOutputInput struct {
+	JobID string `json:"job_id" jsonschema:"required,description=ID of the background command returned by bash"`
+	Wait  int    `json:"wait,omitempty" jsonschema:"description=Seconds to wait for the command to finish before returning (default: 0)"`
+	Kill  bool   `json:"kill,omitempty" jsonschema:"description=Kill the command"`
 }
 
 // BashOutput defines the output of the Bash tool.
 type BashOutput struct {
-	Stdout   string `json:"stdout"`
-	Stderr   string `json:"stderr"`
-	ExitCode int    `json:"exit_code"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	ExitCode        int    `json:"exit_code"`
+	StdoutTruncated bool   `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool   `json:"stderr_truncated,omitempty"`
+	JobID           string `json:"job_id,omitempty"`  // Background commands only
+	Running         bool   `json:"running,omitempty"` // The background command is still running; ExitCode is not set yet
 }
 
 // BashTool returns the Bash tool.
 func BashTool() (llm.Tool, error) {
 	return llm.NewTool(
 		"bash",
-		"Execute a shell command and return stdout, stderr, and exit code.",
+		"Execute a shell command and return stdout, stderr, and exit code. Set background to run it in the background and poll it with bash_output.",
 		executeBash,
 	)
 }
@@ -42,7 +66,30 @@ func MustBash() llm.Tool {
 	return tool
 }
 
+// BashOutputTool returns the BashOutput tool, which polls and kills the
+// commands that the Bash tool runs in the background.
+func BashOutputTool() (llm.Tool, error) {
+	return llm.NewTool(
+		"bash_output",
+		"Get the new output of a background command started with bash, and its exit code once it has finished. Optionally wait for it to finish, or kill it.",
+		pollBashJob,
+	)
+}
+
+// MustBashOutput returns the BashOutput tool, panicking on error.
+func MustBashOutput() llm.Tool {
+	tool, err := BashOutputTool()
+	if err != nil {
+		panic(err)
+	}
+	return tool
+}
+
 func executeBash(ctx context.Context, input BashInput) (BashOutput, error) {
+	if input.Background {
+		return startBashJob(input)
+	}
+
 	timeout := input.Timeout
 	if timeout <= 0 {
 		timeout = 30
@@ -52,36 +99,151 @@ func executeBash(ctx context.Context, input BashInput) (BashOutput, error) {
 	execCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(execCtx, "bash", "-c", input.Command)
-
-	if input.WorkDir != "" {
-		cmd.Dir = input.WorkDir
-	}
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	cmd := bashCommand(execCtx, input)
+	stdout := newLimitedBuffer(input.MaxStdout)
+	stderr := newLimitedBuffer(input.MaxStderr)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
 	err := cmd.Run()
 
+	// A cancelled or expired ctx kills the command, which then fails with
+	// an ExitError; report the cause instead
+	if ctx.Err() != nil {
+		return BashOutput{}, ctx.Err()
+	}
+
 	exitCode := 0
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else if execCtx.Err() == context.DeadlineExceeded {
+		if execCtx.Err() == context.DeadlineExceeded {
 			return BashOutput{
-				Stdout:   stdout.String(),
-				Stderr:   fmt.Sprintf("command timed out after %d seconds", timeout),
-				ExitCode: -1,
+				Stdout:          stdout.String(),
+				Stderr:          fmt.Sprintf("command timed out after %d seconds", timeout),
+				ExitCode:        -1,
+				StdoutTruncated: stdout.Truncated(),
 			}, nil
+		} else if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
 		} else {
 			return BashOutput{}, fmt.Errorf("failed to execute command: %w", err)
 		}
 	}
 
 	return BashOutput{
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		ExitCode: exitCode,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		ExitCode:        exitCode,
+		StdoutTruncated: stdout.Truncated(),
+		StderrTruncated: stderr.Truncated(),
 	}, nil
 }
+
+// bashCommand returns the command to run input, without its output.
+func bashCommand(ctx context.Context, input BashInput) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "bash", "-c", input.Command)
+	if input.WorkDir != "" {
+		cmd.Dir = input.WorkDir
+	}
+	if len(input.Env) > 0 {
+		keys := make([]string, 0, len(input.Env))
+		for key := range input.Env {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		cmd.Env = os.Environ()
+		for _, key := range keys {
+			cmd.Env = append(cmd.Env, key+"="+input.Env[key])
+		}
+	}
+	if input.Stdin != "" {
+		cmd.Stdin = strings.NewReader(input.Stdin)
+	}
+	return cmd
+}
+
+// startBashJob starts input as a task of the default task supervisor.
+func startBashJob(input BashInput) (BashOutput, error) {
+	status, err := defaultTaskSupervisor.Start(TaskStartInput{
+		Command:   input.Command,
+		WorkDir:   input.WorkDir,
+		Env:       input.Env,
+		Stdin:     input.Stdin,
+		Timeout:   input.Timeout,
+		MaxStdout: input.MaxStdout,
+		MaxStderr: input.MaxStderr,
+	})
+	if err != nil {
+		return BashOutput{}, err
+	}
+	return BashOutput{JobID: status.TaskID, Running: true}, nil
+}
+
+// pollBashJob returns the output of a background command since the last
+// poll.
+func pollBashJob(ctx context.Context, input BashOutputInput) (BashOutput, error) {
+	out, err := defaultTaskSupervisor.Output(ctx, TaskOutputInput{TaskID: input.JobID, Wait: input.Wait, Kill: input.Kill})
+	if err != nil {
+		return BashOutput{}, err
+	}
+	return BashOutput{
+		Stdout:          out.Stdout,
+		Stderr:          out.Stderr,
+		ExitCode:        out.ExitCode,
+		StdoutTruncated: out.StdoutTruncated,
+		StderrTruncated: out.StderrTruncated,
+		JobID:           out.TaskID,
+		Running:         out.Running,
+	}, nil
+}
+
+// limitedBuffer is a buffer that keeps the first bytes written to it, up
+// to a limit. It is safe for concurrent use.
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// newLimitedBuffer creates a buffer of limit bytes, or of the default limit
+// if limit is not positive.
+func newLimitedBuffer(limit int) *limitedBuffer {
+	if limit <= 0 {
+		limit = defaultBashOutputLimit
+	}
+	return &limitedBuffer{limit: limit}
+}
+
+// Write writes what fits of p, and never fails so that the command is not
+// interrupted by a full buffer.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// From returns the content from offset on, and the new offset.
+func (b *limitedBuffer) From(offset int) (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf.Bytes()[offset:]), b.buf.Len()
+}
+
+// Truncated reports whether writes exceeded the limit.
+func (b *limitedBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileChange is the net change of a file during an agent run.
+type FileChange struct {
+	Path       string      // Absolute path of the file
+	Created    bool        // The file did not exist before
+	Deleted    bool        // The file does not exist after
+	BeforeHash string      // SHA-256 of the content before, in hex; "" if created
+	AfterHash  string      // SHA-256 of the content after, in hex; "" if deleted
+	Before     []byte      // Content before; nil if created
+	BeforeMode os.FileMode // Permission bits before; 0 if created
+	Diff       string      // Unified diff of the change
+}
+
+// FileChangeLog collects the changes that tools make to files. Tools find
+// it in their context (see WithFileChangeLog); agent runners give each run
+// its own. It is safe for concurrent use.
+type FileChangeLog struct {
+	mu     sync.Mutex
+	order  []string
+	files  map[string]*fileVersions
+	parent *FileChangeLog // Also records the changes, if any
+}
+
+// fileVersions are the first and last known contents of a file; nil if it
+// did not exist.
+type fileVersions struct {
+	before []byte
+	after  []byte
+	mode   os.FileMode // Permission bits of the first content
+}
+
+// NewFileChangeLog creates an empty log.
+func NewFileChangeLog() *FileChangeLog {
+	return &FileChangeLog{files: make(map[string]*fileVersions)}
+}
+
+// NewChild returns an empty log whose changes are also recorded in l, e.g.
+// for the run of a sub-agent.
+func (l *FileChangeLog) NewChild() *FileChangeLog {
+	child := NewFileChangeLog()
+	child.parent = l
+	return child
+}
+
+// Record records a change of the file at path from before, with the
+// permission bits mode, to after, where nil means that the file did not
+// exist. Successive changes of a file are merged into one.
+func (l *FileChangeLog) Record(path string, before []byte, mode os.FileMode, after []byte) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	l.mu.Lock()
+	v, ok := l.files[path]
+	if !ok {
+		v = &fileVersions{before: before, mode: mode}
+		l.files[path] = v
+		l.order = append(l.order, path)
+	}
+	v.after = after
+	l.mu.Unlock()
+
+	if l.parent != nil {
+		l.parent.Record(path, before, mode, after)
+	}
+}
+
+// Changes returns the net change of each file, in the order the files were
+// first changed. Files changed back to their original content are left out.
+func (l *FileChangeLog) Changes() []FileChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var changes []FileChange
+	for _, path := range l.order {
+		v := l.files[path]
+		diff := UnifiedDiff(path, v.before, v.after)
+		if diff == "" {
+			continue
+		}
+		changes = append(changes, FileChange{
+			Path:       path,
+			Created:    v.before == nil,
+			Deleted:    v.after == nil,
+			BeforeHash: hashContent(v.before),
+			AfterHash:  hashContent(v.after),
+			Before:     v.before,
+			BeforeMode: v.mode,
+			Diff:       diff,
+		})
+	}
+	return changes
+}
+
+// FileConflictError is returned by FileChangeLog.Revert for a file that was
+// modified after its last recorded change, e.g. by the user; it is left as
+// it is.
+type FileConflictError struct {
+	Path string
+}
+
+func (e *FileConflictError) Error() string {
+	return fmt.Sprintf("%s was modified since the change to revert", e.Path)
+}
+
+// Revert restores each changed file to its content before the first change:
+// modified files are rewritten with their permissions, and created files are
+// removed (directories created for them are kept). Files are reverted in the
+// reverse order of their changes. A file whose current content does not
+// match its last recorded change is not reverted, and a *FileConflictError
+// is returned for it; errors of all files are joined. Reverted files no
+// longer appear in Changes, nor in those of the parent logs.
+func (l *FileChangeLog) Revert() error {
+	changes := l.Changes()
+	var errs []error
+	for i := len(changes) - 1; i >= 0; i-- {
+		if err := l.revert(changes[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// revert reverts change and records it as a change back to the content
+// before.
+func (l *FileChangeLog) revert(change FileChange) error {
+	current, mode, err := readIfExists(change.Path)
+	if err != nil {
+		return err
+	}
+	if hashContent(current) != change.AfterHash {
+		return &FileConflictError{Path: change.Path}
+	}
+	if change.Created {
+		err = os.Remove(change.Path)
+	} else {
+		err = restoreFile(change.Path, change.Before, change.BeforeMode)
+	}
+	if err != nil {
+		return fmt.Errorf("reverting %s: %w", change.Path, err)
+	}
+	l.Record(change.Path, current, mode, change.Before)
+	return nil
+}
+
+// restoreFile writes content to the file at path with the permission bits
+// mode, which os.WriteFile only applies to new files.
+func restoreFile(path string, content []byte, mode os.FileMode) error {
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// hashContent returns the SHA-256 of content in hex, or "" for nil.
+func hashContent(content []byte) string {
+	if content == nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileChangeLogKey is the context key of the FileChangeLog.
+type fileChangeLogKey struct{}
+
+// WithFileChangeLog returns a context in which tools record the changes they
+// make to files in log.
+func WithFileChangeLog(ctx context.Context, log *FileChangeLog) context.Context {
+	return context.WithValue(ctx, fileChangeLogKey{}, log)
+}
+
+// FileChangeLogFrom returns the FileChangeLog of ctx, or nil.
+func FileChangeLogFrom(ctx context.Context) *FileChangeLog {
+	log, _ := ctx.Value(fileChangeLogKey{}).(*FileChangeLog)
+	return log
+}
+
+// TrackFileChange runs change, which modifies the file at path, and records
+// the change in the FileChangeLog of ctx, if any. Custom tools that write
+// files use it so that their changes are reported and can be reverted like
+// those of the Write tool.
+//
+// Example:
+//
+//	err := tools.TrackFileChange(ctx, input.Path, func() error {
+//	    return os.WriteFile(input.Path, patched, 0o644)
+//	})
+func TrackFileChange(ctx context.Context, path string, change func() error) error {
+	log := FileChangeLogFrom(ctx)
+	if log == nil {
+		return change()
+	}
+	before, mode, err := readIfExists(path)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, _, err := readIfExists(path)
+	if err != nil {
+		return err
+	}
+	log.Record(path, before, mode, after)
+	return nil
+}
+
+// readIfExists returns the content and permission bits of the file at path,
+// or nil and 0 if it does not exist. Empty files have empty, non-nil content.
+func readIfExists(path string) ([]byte, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, info.Mode().Perm(), nil
+}
@@ -55,6 +55,9 @@ func globFiles(ctx context.Context, input GlobInput) (GlobOutput, error) {
 	if err != nil {
 		return GlobOutput{}, err
 	}
+	if err := ctx.Err(); err != nil {
+		return GlobOutput{}, err
+	}
 
 	// Prepend base path to results if not current directory
 	if basePath != "." {
@@ -106,6 +106,9 @@ func grepFiles(ctx context.Context, input GrepInput) (GrepOutput, error) {
 		if len(matches) >= maxMatches {
 			break
 		}
+		if err := ctx.Err(); err != nil {
+			return GrepOutput{}, err
+		}
 
 		fileMatches, err := searchFile(filePath, re, maxMatches-len(matches))
 		if err != nil {
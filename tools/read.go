@@ -4,6 +4,7 @@ package tools
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -82,3 +83,22 @@ func readFile(ctx context.Context, input ReadInput) (ReadOutput, error) {
 		Truncated: truncated,
 	}, nil
 }
+
+// ReadCacheKey returns the size and modification time of the file that the
+// Read tool reads with args, for llm.WithToolCacheKey, so that cached reads
+// are not reused once the file changes.
+//
+// Example:
+//
+//	cache := llm.NewToolCache(time.Hour, llm.WithToolCacheKey("read", tools.ReadCacheKey))
+func ReadCacheKey(args json.RawMessage) (string, error) {
+	var input ReadInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(input.Path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano()), nil
+}
@@ -4,16 +4,17 @@ import "github.com/i2y/bucephalus/llm"
 
 // AllTools returns all built-in tools.
 func AllTools() []llm.Tool {
-	return []llm.Tool{
+	return append([]llm.Tool{
 		MustRead(),
 		MustWrite(),
 		MustGlob(),
 		MustGrep(),
 		MustBash(),
+		MustBashOutput(),
 		MustWebFetch(),
 		MustWebSearch(),
 		MustWikipedia(),
-	}
+	}, TaskTools()...)
 }
 
 // FileTools returns file-related tools only.
@@ -60,10 +61,11 @@ func ReadOnlyTools() []llm.Tool {
 }
 
 // SystemTools returns tools that can modify the system.
-// Includes: Write, Bash
+// Includes: Write, Bash, BashOutput, TaskStart, TaskStatus, TaskOutput
 func SystemTools() []llm.Tool {
-	return []llm.Tool{
+	return append([]llm.Tool{
 		MustWrite(),
 		MustBash(),
-	}
+		MustBashOutput(),
+	}, TaskTools()...)
 }
@@ -0,0 +1,347 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
+)
+
+// ErrTaskNotFound is returned for an unknown or forgotten task.
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskStartInput defines the input for the TaskStart tool.
+type TaskStartInput struct {
+	Command   string            `json:"command" jsonschema:"required,description=Shell command to run in the background"`
+	Name      string            `json:"name,omitempty" jsonschema:"description=Short label of the task, e.g. build or server"`
+	WorkDir   string            `json:"workdir,omitempty" jsonschema:"description=Working directory for the command"`
+	Env       map[string]string `json:"env,omitempty" jsonschema:"description=Environment variables to set for the command, in addition to the inherited ones"`
+	Stdin     string            `json:"stdin,omitempty" jsonschema:"description=Text to pass to the standard input of the command"`
+	Timeout   int               `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds after which the task is killed (default: none)"`
+	MaxStdout int               `json:"max_stdout,omitempty" jsonschema:"description=Maximum bytes of stdout to keep (default: 102400)"`
+	MaxStderr int               `json:"max_stderr,omitempty" jsonschema:"description=Maximum bytes of stderr to keep (default: 102400)"`
+}
+
+// TaskStatusInput defines the input for the TaskStatus tool.
+type TaskStatusInput struct {
+	TaskID string `json:"task_id,omitempty" jsonschema:"description=ID of the task; all tasks if empty"`
+}
+
+// TaskOutputInput defines the input for the TaskOutput tool.
+type TaskOutputInput struct {
+	TaskID string `json:"task_id" jsonschema:"required,description=ID of the task"`
+	Wait   int    `json:"wait,omitempty" jsonschema:"description=Seconds to wait for the task to finish before returning (default: 0)"`
+	Kill   bool   `json:"kill,omitempty" jsonschema:"description=Kill the task"`
+}
+
+// TaskStatus is the status of a task.
+type TaskStatus struct {
+	TaskID    string    `json:"task_id"`
+	Name      string    `json:"name,omitempty"`
+	Command   string    `json:"command"`
+	Running   bool      `json:"running"`
+	ExitCode  int       `json:"exit_code"` // Set once the task has finished; -1 if killed or timed out
+	TimedOut  bool      `json:"timed_out,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Seconds   float64   `json:"seconds"` // Running time so far, or until the task finished
+}
+
+// TaskStatusOutput defines the output of the TaskStatus tool.
+type TaskStatusOutput struct {
+	Tasks []TaskStatus `json:"tasks"`
+}
+
+// TaskOutput defines the output of the TaskOutput tool: the output of the
+// task since the last call.
+type TaskOutput struct {
+	TaskID          string `json:"task_id"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool   `json:"stderr_truncated,omitempty"`
+	Running         bool   `json:"running"`
+	ExitCode        int    `json:"exit_code"` // Set once the task has finished
+}
+
+// TaskSupervisor runs shell commands in the background as tasks, such as
+// builds, test suites, and servers, so that agents can start long jobs and
+// poll them in later turns instead of blocking until a timeout. Tasks run
+// until they finish, time out, or are killed; they are forgotten once their
+// final output has been read. It is safe for concurrent use.
+type TaskSupervisor struct {
+	mu    sync.Mutex
+	tasks map[string]*task
+	seq   int
+}
+
+// task is a command run by a TaskSupervisor.
+type task struct {
+	seq       int
+	id        string
+	name      string
+	command   string
+	timeout   int // Seconds; 0 if none
+	startedAt time.Time
+	cancel    context.CancelFunc
+	stdout    *limitedBuffer
+	stderr    *limitedBuffer
+	done      chan struct{} // Closed when the command exits
+
+	// Set before done is closed
+	exitCode   int
+	timedOut   bool
+	finishedAt time.Time
+
+	mu        sync.Mutex // Guards the offsets of the output already read
+	stdoutOff int
+	stderrOff int
+}
+
+// NewTaskSupervisor creates a supervisor without tasks.
+func NewTaskSupervisor() *TaskSupervisor {
+	return &TaskSupervisor{tasks: make(map[string]*task)}
+}
+
+var defaultTaskSupervisor = NewTaskSupervisor()
+
+// DefaultTaskSupervisor returns the supervisor of TaskTools and of the
+// background commands of the Bash tool.
+func DefaultTaskSupervisor() *TaskSupervisor {
+	return defaultTaskSupervisor
+}
+
+// Start starts input as a task. The task is not bound to a context: it
+// outlives the tool call that starts it.
+func (s *TaskSupervisor) Start(input TaskStartInput) (TaskStatus, error) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if input.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(input.Timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	cmd := bashCommand(ctx, BashInput{Command: input.Command, WorkDir: input.WorkDir, Env: input.Env, Stdin: input.Stdin})
+	killProcessGroup(cmd)
+	// Processes started by the command may keep its output open after it
+	// is killed
+	cmd.WaitDelay = time.Second
+
+	t := &task{
+		name:      input.Name,
+		command:   input.Command,
+		timeout:   input.Timeout,
+		startedAt: time.Now(),
+		cancel:    cancel,
+		stdout:    newLimitedBuffer(input.MaxStdout),
+		stderr:    newLimitedBuffer(input.MaxStderr),
+		done:      make(chan struct{}),
+	}
+	cmd.Stdout = t.stdout
+	cmd.Stderr = t.stderr
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return TaskStatus{}, fmt.Errorf("failed to execute command: %w", err)
+	}
+
+	go func() {
+		defer cancel()
+		err := cmd.Wait()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			t.exitCode = exitErr.ExitCode()
+		} else if err != nil {
+			t.exitCode = -1
+		}
+		t.timedOut = ctx.Err() == context.DeadlineExceeded
+		if t.timedOut {
+			t.exitCode = -1
+		}
+		t.finishedAt = time.Now()
+		close(t.done)
+	}()
+
+	s.mu.Lock()
+	s.seq++
+	t.seq = s.seq
+	t.id = "task-" + strconv.Itoa(t.seq)
+	s.tasks[t.id] = t
+	s.mu.Unlock()
+	return t.status(), nil
+}
+
+// Status returns the status of the task with the given ID.
+func (s *TaskSupervisor) Status(id string) (TaskStatus, error) {
+	t, err := s.get(id)
+	if err != nil {
+		return TaskStatus{}, err
+	}
+	return t.status(), nil
+}
+
+// Statuses returns the status of each task, in the order they were started.
+func (s *TaskSupervisor) Statuses() []TaskStatus {
+	s.mu.Lock()
+	tasks := make([]*task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, t)
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(tasks, func(a, b *task) int { return a.seq - b.seq })
+	statuses := make([]TaskStatus, len(tasks))
+	for i, t := range tasks {
+		statuses[i] = t.status()
+	}
+	return statuses
+}
+
+// Output returns the output of a task since the last call, after waiting
+// for it to finish for up to input.Wait seconds, or killing it. A finished
+// task is forgotten once its output has been returned.
+func (s *TaskSupervisor) Output(ctx context.Context, input TaskOutputInput) (TaskOutput, error) {
+	t, err := s.get(input.TaskID)
+	if err != nil {
+		return TaskOutput{}, err
+	}
+
+	if input.Kill {
+		t.cancel()
+		<-t.done
+	} else if input.Wait > 0 {
+		timer := time.NewTimer(time.Duration(input.Wait) * time.Second)
+		defer timer.Stop()
+		select {
+		case <-t.done:
+		case <-timer.C:
+		case <-ctx.Done():
+			return TaskOutput{}, ctx.Err()
+		}
+	}
+
+	out := TaskOutput{TaskID: t.id}
+	select {
+	case <-t.done:
+		out.ExitCode = t.exitCode
+		s.mu.Lock()
+		delete(s.tasks, t.id)
+		s.mu.Unlock()
+	default:
+		out.Running = true
+	}
+
+	t.mu.Lock()
+	out.Stdout, t.stdoutOff = t.stdout.From(t.stdoutOff)
+	out.Stderr, t.stderrOff = t.stderr.From(t.stderrOff)
+	t.mu.Unlock()
+	out.StdoutTruncated = t.stdout.Truncated()
+	out.StderrTruncated = t.stderr.Truncated()
+	if !out.Running && t.timedOut {
+		out.Stderr += fmt.Sprintf("command timed out after %d seconds", t.timeout)
+	}
+	return out, nil
+}
+
+// KillAll kills the running tasks and waits for them to exit, e.g. when the
+// host shuts down.
+func (s *TaskSupervisor) KillAll() {
+	s.mu.Lock()
+	tasks := make([]*task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, t)
+	}
+	s.mu.Unlock()
+
+	for _, t := range tasks {
+		t.cancel()
+	}
+	for _, t := range tasks {
+		<-t.done
+	}
+}
+
+// Tools returns the task_start, task_status, and task_output tools of the
+// supervisor.
+//
+// Example:
+//
+//	supervisor := tools.NewTaskSupervisor()
+//	defer supervisor.KillAll()
+//	resp, err := llm.Call(ctx, "Run the test suite and fix the failures",
+//	    llm.WithTools(append(tools.FileTools(), supervisor.Tools()...)...),
+//	)
+func (s *TaskSupervisor) Tools() []llm.Tool {
+	return []llm.Tool{
+		llm.MustNewTool(
+			"task_start",
+			"Start a shell command in the background as a task, e.g. a build, a test suite, or a server, and return its ID at once. Poll it with task_status and task_output.",
+			func(ctx context.Context, input TaskStartInput) (TaskStatus, error) {
+				return s.Start(input)
+			},
+		),
+		llm.MustNewTool(
+			"task_status",
+			"Get the status of a background task, or of all tasks: whether it is running, its exit code, and its running time.",
+			func(ctx context.Context, input TaskStatusInput) (TaskStatusOutput, error) {
+				if input.TaskID == "" {
+					return TaskStatusOutput{Tasks: s.Statuses()}, nil
+				}
+				status, err := s.Status(input.TaskID)
+				if err != nil {
+					return TaskStatusOutput{}, err
+				}
+				return TaskStatusOutput{Tasks: []TaskStatus{status}}, nil
+			},
+		),
+		llm.MustNewTool(
+			"task_output",
+			"Get the new output of a background task since the last call, and its exit code once it has finished. Optionally wait for it to finish, or kill it.",
+			s.Output,
+		),
+	}
+}
+
+// TaskTools returns the tools of the default task supervisor.
+// Includes: TaskStart, TaskStatus, TaskOutput
+func TaskTools() []llm.Tool {
+	return defaultTaskSupervisor.Tools()
+}
+
+// get returns the task with the given ID.
+func (s *TaskSupervisor) get(id string) (*task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	return t, nil
+}
+
+// status returns the status of t.
+func (t *task) status() TaskStatus {
+	status := TaskStatus{
+		TaskID:    t.id,
+		Name:      t.name,
+		Command:   t.command,
+		Running:   true,
+		StartedAt: t.startedAt,
+	}
+	end := time.Now()
+	select {
+	case <-t.done:
+		status.Running = false
+		status.ExitCode = t.exitCode
+		status.TimedOut = t.timedOut
+		end = t.finishedAt
+	default:
+	}
+	status.Seconds = end.Sub(t.startedAt).Seconds()
+	return status
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tools
+
+import "os/exec"
+
+// killProcessGroup does nothing: only the command itself is killed when
+// canceled.
+func killProcessGroup(cmd *exec.Cmd) {}
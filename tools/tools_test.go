@@ -2,10 +2,14 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/i2y/bucephalus/llm"
 )
 
 func TestReadTool(t *testing.T) {
@@ -224,14 +228,228 @@ func TestBashTool(t *testing.T) {
 		if out.ExitCode != -1 {
 			t.Errorf("expected exit code -1 for timeout, got %d", out.ExitCode)
 		}
+		if !strings.Contains(out.Stderr, "timed out") {
+			t.Errorf("expected a timeout message, got %q", out.Stderr)
+		}
 	})
+
+	t.Run("workdir, env, and stdin", func(t *testing.T) {
+		dir := t.TempDir()
+		result, err := executeBash(ctx, BashInput{
+			Command: `pwd; echo "$GREETING"; cat`,
+			WorkDir: dir,
+			Env:     map[string]string{"GREETING": "hi"},
+			Stdin:   "from stdin",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := dir + "\nhi\nfrom stdin"
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && resolved != dir {
+			want = resolved + "\nhi\nfrom stdin"
+		}
+		if result.Stdout != want {
+			t.Errorf("expected %q, got %q", want, result.Stdout)
+		}
+	})
+
+	t.Run("output limits", func(t *testing.T) {
+		result, err := executeBash(ctx, BashInput{
+			Command:   "echo 0123456789; echo abcdef >&2",
+			MaxStdout: 4,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Stdout != "0123" || !result.StdoutTruncated {
+			t.Errorf("expected truncated stdout, got %q (truncated: %v)", result.Stdout, result.StdoutTruncated)
+		}
+		if result.Stderr != "abcdef\n" || result.StderrTruncated {
+			t.Errorf("expected the whole stderr, got %q (truncated: %v)", result.Stderr, result.StderrTruncated)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err := tool.Execute(ctx, []byte(`{"command": "sleep 5"}`))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestBashTool_Background(t *testing.T) {
+	ctx := context.Background()
+
+	started, err := executeBash(ctx, BashInput{Command: "echo started; read line; echo $line; exit 3", Stdin: "next\n", Background: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.JobID == "" || !started.Running {
+		t.Fatalf("expected a running job, got %+v", started)
+	}
+
+	result, err := pollBashJob(ctx, BashOutputInput{JobID: started.JobID, Wait: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Running || result.ExitCode != 3 || result.Stdout != "started\nnext\n" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// Finished jobs are forgotten once polled
+	if _, err := pollBashJob(ctx, BashOutputInput{JobID: started.JobID}); err == nil {
+		t.Error("expected an error for a finished job")
+	}
+
+	t.Run("incremental output and kill", func(t *testing.T) {
+		started, err := executeBash(ctx, BashInput{Command: "echo first; sleep 10; echo second", Background: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result BashOutput
+		for i := 0; i < 50 && result.Stdout == ""; i++ {
+			time.Sleep(20 * time.Millisecond)
+			if result, err = pollBashJob(ctx, BashOutputInput{JobID: started.JobID}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !result.Running || result.Stdout != "first\n" {
+			t.Fatalf("expected the first line of a running job, got %+v", result)
+		}
+
+		result, err = pollBashJob(ctx, BashOutputInput{JobID: started.JobID, Kill: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Running || result.Stdout != "" {
+			t.Errorf("expected a killed job without new output, got %+v", result)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		started, err := executeBash(ctx, BashInput{Command: "sleep 10", Timeout: 1, Background: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := pollBashJob(ctx, BashOutputInput{JobID: started.JobID, Wait: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ExitCode != -1 || !strings.Contains(result.Stderr, "timed out after 1 seconds") {
+			t.Errorf("expected a timeout, got %+v", result)
+		}
+	})
+}
+
+func TestTaskSupervisor(t *testing.T) {
+	ctx := context.Background()
+	s := NewTaskSupervisor()
+	defer s.KillAll()
+
+	build, err := s.Start(TaskStartInput{Command: "echo compiling; echo warning >&2; exit 2", Name: "build"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := s.Start(TaskStartInput{Command: "echo listening; sleep 30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.TaskID == server.TaskID || !build.Running || build.Name != "build" {
+		t.Fatalf("unexpected statuses: %+v, %+v", build, server)
+	}
+
+	out, err := s.Output(ctx, TaskOutputInput{TaskID: build.TaskID, Wait: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Running || out.ExitCode != 2 || out.Stdout != "compiling\n" || out.Stderr != "warning\n" {
+		t.Errorf("unexpected output: %+v", out)
+	}
+
+	// The finished build is forgotten, the server is still running
+	statuses := s.Statuses()
+	if len(statuses) != 1 || statuses[0].TaskID != server.TaskID || !statuses[0].Running {
+		t.Errorf("expected only the running server, got %+v", statuses)
+	}
+	if _, err := s.Status(build.TaskID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+
+	// Output returns what was written since the last read
+	var stdout string
+	for deadline := time.Now().Add(5 * time.Second); stdout != "listening\n" && time.Now().Before(deadline); {
+		out, err = s.Output(ctx, TaskOutputInput{TaskID: server.TaskID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !out.Running {
+			t.Fatalf("expected the server to be running, got %+v", out)
+		}
+		stdout += out.Stdout
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stdout != "listening\n" {
+		t.Fatalf("unexpected server output: %q", stdout)
+	}
+
+	// Killing the shell also kills the processes it started
+	start := time.Now()
+	out, err = s.Output(ctx, TaskOutputInput{TaskID: server.TaskID, Kill: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Running || out.ExitCode != -1 || out.Stdout != "" {
+		t.Errorf("unexpected output: %+v", out)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("expected the kill to be immediate, took %v", elapsed)
+	}
+}
+
+func TestTaskSupervisor_Tools(t *testing.T) {
+	ctx := context.Background()
+	s := NewTaskSupervisor()
+	defer s.KillAll()
+	byName := make(map[string]llm.Tool)
+	for _, tool := range s.Tools() {
+		byName[tool.Name()] = tool
+	}
+
+	result, err := byName["task_start"].Execute(ctx, []byte(`{"command": "sleep 30", "name": "server"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := result.(TaskStatus).TaskID
+
+	result, err = byName["task_status"].Execute(ctx, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks := result.(TaskStatusOutput).Tasks; len(tasks) != 1 || tasks[0].TaskID != id || tasks[0].Name != "server" {
+		t.Errorf("unexpected tasks: %+v", tasks)
+	}
+
+	s.KillAll()
+	result, err = byName["task_status"].Execute(ctx, []byte(`{"task_id": "`+id+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := result.(TaskStatusOutput).Tasks[0]; status.Running || status.ExitCode != -1 {
+		t.Errorf("expected a killed task, got %+v", status)
+	}
+
+	if _, err := byName["task_output"].Execute(ctx, []byte(`{"task_id": "task-99"}`)); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
 }
 
 func TestRegistryFunctions(t *testing.T) {
 	t.Run("AllTools", func(t *testing.T) {
 		tools := AllTools()
-		if len(tools) != 8 {
-			t.Errorf("expected 8 tools, got %d", len(tools))
+		if len(tools) != 12 {
+			t.Errorf("expected 12 tools, got %d", len(tools))
 		}
 	})
 
@@ -265,8 +483,8 @@ func TestRegistryFunctions(t *testing.T) {
 
 	t.Run("SystemTools", func(t *testing.T) {
 		tools := SystemTools()
-		if len(tools) != 2 {
-			t.Errorf("expected 2 tools, got %d", len(tools))
+		if len(tools) != 6 {
+			t.Errorf("expected 6 tools, got %d", len(tools))
 		}
 	})
 }
@@ -288,3 +506,255 @@ func TestToolMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestReadCacheKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := []byte(`{"path": "` + testFile + `"}`)
+
+	key1, err := ReadCacheKey(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("v2 changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key2, err := ReadCacheKey(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key1 == key2 {
+		t.Errorf("expected the key to change with the file, got %q twice", key1)
+	}
+
+	if _, err := ReadCacheKey([]byte(`{"path": "` + filepath.Join(tmpDir, "missing") + `"}`)); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []byte
+		after  []byte
+		want   string
+	}{
+		{
+			name:   "equal",
+			before: []byte("a\n"),
+			after:  []byte("a\n"),
+			want:   "",
+		},
+		{
+			name:   "modified",
+			before: []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"),
+			after:  []byte("1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\neleven\n"),
+			want: "--- a/f.txt\n+++ b/f.txt\n" +
+				"@@ -2,9 +2,10 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n 9\n 10\n+eleven\n",
+		},
+		{
+			name:   "separate hunks",
+			before: []byte("a\n1\n2\n3\n4\n5\n6\n7\nb\n"),
+			after:  []byte("A\n1\n2\n3\n4\n5\n6\n7\nB\n"),
+			want: "--- a/f.txt\n+++ b/f.txt\n" +
+				"@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n" +
+				"@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			name:   "created",
+			before: nil,
+			after:  []byte("hello"),
+			want:   "--- /dev/null\n+++ b/f.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n",
+		},
+		{
+			name:   "deleted",
+			before: []byte("hello\n"),
+			after:  nil,
+			want:   "--- a/f.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-hello\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("f.txt", tt.before, tt.after)
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTool_FileChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "existing.txt")
+	created := filepath.Join(tmpDir, "created.txt")
+	if err := os.WriteFile(existing, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := NewFileChangeLog()
+	parent := NewFileChangeLog()
+	child := parent.NewChild()
+	ctx := WithFileChangeLog(context.Background(), log)
+	for _, input := range []WriteInput{
+		{Path: existing, Content: "middle\n"},
+		{Path: existing, Content: "new\n"},
+		{Path: created, Content: "hello\n"},
+	} {
+		if _, err := writeFile(ctx, input); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := writeFile(WithFileChangeLog(context.Background(), child), WriteInput{Path: created, Content: "x\n"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := log.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	if changes[0].Path != existing || changes[0].Created || string(changes[0].Before) != "old\n" {
+		t.Errorf("unexpected change: %+v", changes[0])
+	}
+	if !strings.Contains(changes[0].Diff, "-old\n+new\n") {
+		t.Errorf("expected the net diff, got:\n%s", changes[0].Diff)
+	}
+	if changes[0].BeforeHash == "" || changes[0].BeforeHash == changes[0].AfterHash {
+		t.Errorf("unexpected hashes: %q, %q", changes[0].BeforeHash, changes[0].AfterHash)
+	}
+	if !changes[1].Created || changes[1].BeforeHash != "" || changes[1].Before != nil {
+		t.Errorf("expected a created file, got %+v", changes[1])
+	}
+
+	// Changes recorded in a child log are also recorded in its parent
+	if len(child.Changes()) != 1 || len(parent.Changes()) != 1 {
+		t.Errorf("expected the change in the child and the parent logs")
+	}
+
+	// Changing a file back to its content leaves it out
+	back := NewFileChangeLog()
+	backCtx := WithFileChangeLog(context.Background(), back)
+	if _, err := writeFile(backCtx, WriteInput{Path: existing, Content: "changed\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeFile(backCtx, WriteInput{Path: existing, Content: "new\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if changes := back.Changes(); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestFileChangeLog_Revert(t *testing.T) {
+	tmpDir := t.TempDir()
+	modified := filepath.Join(tmpDir, "modified.txt")
+	conflicted := filepath.Join(tmpDir, "conflicted.txt")
+	created := filepath.Join(tmpDir, "sub", "created.txt")
+	for _, path := range []string{modified, conflicted} {
+		if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parent := NewFileChangeLog()
+	log := parent.NewChild()
+	ctx := WithFileChangeLog(context.Background(), log)
+	for _, path := range []string{modified, conflicted, created} {
+		if _, err := writeFile(ctx, WriteInput{Path: path, Content: "agent\n"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The user edits a file after the agent
+	if err := os.WriteFile(conflicted, []byte("user\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := log.Revert()
+	var conflict *FileConflictError
+	if !errors.As(err, &conflict) || conflict.Path != conflicted {
+		t.Fatalf("expected a conflict on %s, got %v", conflicted, err)
+	}
+	if data, _ := os.ReadFile(modified); string(data) != "original\n" {
+		t.Errorf("expected the original content, got %q", data)
+	}
+	if data, _ := os.ReadFile(conflicted); string(data) != "user\n" {
+		t.Errorf("expected the conflicted file to be kept, got %q", data)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("expected the created file to be removed, got %v", err)
+	}
+
+	// Only the conflicted file is still changed, in the log and its parent
+	for _, l := range []*FileChangeLog{log, parent} {
+		changes := l.Changes()
+		if len(changes) != 1 || changes[0].Path != conflicted {
+			t.Errorf("expected only the conflicted change, got %+v", changes)
+		}
+	}
+}
+
+func TestFileChangeLog_Revert_Mode(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "build.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nmake\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A tool replaces the script with a new file of default permissions
+	log := NewFileChangeLog()
+	ctx := WithFileChangeLog(context.Background(), log)
+	err := TrackFileChange(ctx, script, func() error {
+		if err := os.Remove(script); err != nil {
+			return err
+		}
+		return os.WriteFile(script, []byte("#!/bin/sh\nmake all\n"), 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := log.Changes(); len(changes) != 1 || changes[0].BeforeMode != 0755 {
+		t.Fatalf("expected the mode before the change, got %+v", changes)
+	}
+
+	if err := log.Revert(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %v", info.Mode().Perm())
+	}
+}
+
+func TestDiffLines_Reconstructs(t *testing.T) {
+	words := []string{"a\n", "b\n", "c\n", "d\n"}
+	seed := uint32(1)
+	random := func(n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			seed = seed*1664525 + 1013904223
+			lines[i] = words[seed>>30]
+		}
+		return lines
+	}
+
+	for i := range 200 {
+		a, b := random(i%13), random(i%7)
+		var gotA, gotB []string
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				gotA = append(gotA, op.text)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.text)
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("edit script of %q -> %q does not reconstruct the inputs", a, b)
+		}
+	}
+}
@@ -49,9 +49,12 @@ func writeFile(ctx context.Context, input WriteInput) (WriteOutput, error) {
 		}
 	}
 
-	// Write the file
+	// Write the file, recording the change for the agent run, if any
 	data := []byte(input.Content)
-	if err := os.WriteFile(input.Path, data, 0o644); err != nil {
+	err := TrackFileChange(ctx, input.Path, func() error {
+		return os.WriteFile(input.Path, data, 0o644)
+	})
+	if err != nil {
 		return WriteOutput{}, fmt.Errorf("failed to write file: %w", err)
 	}
 